@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xDarkicex/libravdb/libravdb"
+	"github.com/xDarkicex/libravdb/pgwire"
+)
+
+const (
+	defaultListenAddr      = "127.0.0.1:5432"
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// Config is the decoded server configuration file. The file is TOML; see
+// docs/configuration/server.md for every key.
+type Config struct {
+	StoragePath          string
+	Durability           libravdb.DurabilityMode
+	MaxCollections       int
+	AsyncIndexQueueDepth int
+	AsyncIndexWorkers    int
+	TemporalRetention    time.Duration
+	TTLReapInterval      time.Duration
+	ttlReapIntervalSet   bool
+
+	HTTPAddr        string
+	Metrics         bool
+	ShutdownTimeout time.Duration
+
+	// Server is handed to pgwire.NewServer. Credentials are loaded from the
+	// [auth] credentials_file.
+	Server pgwire.ServerConfig
+}
+
+// LoadConfig reads and validates the configuration file at path.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := parseTOML(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	config, err := decodeConfig(values)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+func decodeConfig(values map[string]tomlValue) (*Config, error) {
+	d := &configDecoder{values: values, used: make(map[string]bool, len(values))}
+	config := &Config{
+		Durability:      libravdb.DurabilitySynchronous,
+		Metrics:         true,
+		ShutdownTimeout: defaultShutdownTimeout,
+		Server:          pgwire.ServerConfig{Addr: defaultListenAddr},
+	}
+
+	config.StoragePath = d.str("storage.path")
+	switch mode := d.str("storage.durability"); mode {
+	case "", "synchronous":
+	case "unsafe_nosync":
+		config.Durability = libravdb.DurabilityUnsafeNoSync
+	default:
+		d.fail("storage.durability", fmt.Errorf("must be \"synchronous\" or \"unsafe_nosync\", got %q", mode))
+	}
+	config.MaxCollections = d.integer("storage.max_collections")
+
+	config.AsyncIndexQueueDepth = d.integer("indexing.async_queue_depth")
+	config.AsyncIndexWorkers = d.integer("indexing.async_workers")
+	config.TemporalRetention = d.duration("temporal.retention")
+	if d.has("ttl.reap_interval") {
+		config.TTLReapInterval = d.duration("ttl.reap_interval")
+		config.ttlReapIntervalSet = true
+	}
+
+	server := &config.Server
+	if addr := d.str("pgwire.listen"); addr != "" {
+		server.Addr = addr
+	}
+	server.MaxConnections = d.integer("pgwire.max_connections")
+	server.StartupTimeout = d.duration("pgwire.startup_timeout")
+	server.IdleTimeout = d.duration("pgwire.idle_timeout")
+	server.MaxPreparedStatements = d.integer("pgwire.max_prepared_statements")
+	server.MaxPortals = d.integer("pgwire.max_portals")
+	server.MaxPreparedStatementBytes = d.integer("pgwire.max_prepared_statement_bytes")
+	server.MaxPortalBytes = d.integer("pgwire.max_portal_bytes")
+	server.AllowInsecure = d.boolean("pgwire.allow_insecure")
+	server.ProxyProtocol = d.boolean("pgwire.proxy_protocol")
+	server.TrustedProxyCIDRs = d.strings("pgwire.trusted_proxy_cidrs")
+
+	server.TLSCertificateFile = d.str("tls.cert_file")
+	server.TLSKeyFile = d.str("tls.key_file")
+	server.RequireTLS = d.boolean("tls.require")
+
+	if file := d.str("auth.credentials_file"); file != "" {
+		credentials, err := loadCredentials(file)
+		if err != nil {
+			d.fail("auth.credentials_file", err)
+		}
+		server.Credentials = credentials
+		server.RequireAuthentication = true
+	}
+	server.RequireChannelBinding = d.boolean("auth.require_channel_binding")
+	server.AuthFailureThreshold = d.integer("auth.failure_threshold")
+	server.AuthLockoutDuration = d.duration("auth.lockout_duration")
+	server.AuthGlobalFailureLimit = d.integer("auth.global_failure_limit")
+	server.AuthGlobalFailureWindow = d.duration("auth.global_failure_window")
+	server.AuthAttemptBurst = d.integer("auth.attempt_burst")
+	server.AuthAttemptRefill = d.duration("auth.attempt_refill")
+	server.MaxConcurrentAuth = d.integer("auth.max_concurrent")
+
+	config.HTTPAddr = d.str("http.listen")
+	if d.has("http.metrics") {
+		config.Metrics = d.boolean("http.metrics")
+	}
+	if d.has("shutdown.timeout") {
+		config.ShutdownTimeout = d.duration("shutdown.timeout")
+	}
+
+	if err := d.finish(); err != nil {
+		return nil, err
+	}
+	switch {
+	case config.StoragePath == "":
+		return nil, fmt.Errorf("storage.path is required")
+	case (config.AsyncIndexQueueDepth == 0) != (config.AsyncIndexWorkers == 0):
+		return nil, fmt.Errorf("indexing.async_queue_depth and indexing.async_workers must be set together")
+	case (server.TLSCertificateFile == "") != (server.TLSKeyFile == ""):
+		return nil, fmt.Errorf("tls.cert_file and tls.key_file must be set together")
+	case config.ShutdownTimeout <= 0:
+		return nil, fmt.Errorf("shutdown.timeout must be positive")
+	}
+	return config, nil
+}
+
+// DatabaseOptions returns the libravdb.Open options the configuration
+// selects.
+func (c *Config) DatabaseOptions() []libravdb.Option {
+	opts := []libravdb.Option{
+		libravdb.WithStoragePath(c.StoragePath),
+		libravdb.WithDurability(c.Durability),
+		libravdb.WithMetrics(c.Metrics),
+	}
+	if c.MaxCollections > 0 {
+		opts = append(opts, libravdb.WithMaxCollections(c.MaxCollections))
+	}
+	if c.AsyncIndexQueueDepth > 0 {
+		opts = append(opts, libravdb.WithAsyncIndexing(c.AsyncIndexQueueDepth, c.AsyncIndexWorkers))
+	}
+	if c.TemporalRetention > 0 {
+		opts = append(opts, libravdb.WithTemporalRetention(c.TemporalRetention))
+	}
+	if c.ttlReapIntervalSet {
+		opts = append(opts, libravdb.WithTTLReapInterval(c.TTLReapInterval))
+	}
+	return opts
+}
+
+// loadCredentials reads a SCRAM credentials file. Each non-blank line that
+// does not start with # holds a user name and a PostgreSQL SCRAM-SHA-256
+// verifier separated by whitespace; `libravdb-server -scram-verifier` prints
+// such lines. Plaintext passwords are never accepted.
+func loadCredentials(path string) (map[string]pgwire.SCRAMCredential, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	credentials := make(map[string]pgwire.SCRAMCredential)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: want \"<user> <SCRAM verifier>\"", path, line)
+		}
+		if _, ok := credentials[fields[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: duplicate user %q", path, line, fields[0])
+		}
+		credential, err := pgwire.ParseSCRAMVerifier(fields[0], fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		credentials[fields[0]] = credential
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("%s: no credentials", path)
+	}
+	return credentials, nil
+}
+
+// configDecoder reads typed values out of a parsed file, records the first
+// error and tracks which keys were consumed so unknown keys are reported.
+type configDecoder struct {
+	values map[string]tomlValue
+	used   map[string]bool
+	err    error
+}
+
+func (d *configDecoder) fail(key string, err error) {
+	if d.err == nil {
+		d.err = fmt.Errorf("%s: %w", key, err)
+	}
+}
+
+func (d *configDecoder) has(key string) bool {
+	_, ok := d.values[key]
+	return ok
+}
+
+func (d *configDecoder) lookup(key string, kind tomlKind) (tomlValue, bool) {
+	d.used[key] = true
+	value, ok := d.values[key]
+	if !ok {
+		return tomlValue{}, false
+	}
+	if value.kind != kind {
+		d.fail(key, fmt.Errorf("line %d: expected %s, got %s", value.line, kind, value.kind))
+		return tomlValue{}, false
+	}
+	return value, true
+}
+
+func (d *configDecoder) str(key string) string {
+	value, _ := d.lookup(key, tomlString)
+	return value.str
+}
+
+func (d *configDecoder) strings(key string) []string {
+	value, _ := d.lookup(key, tomlArray)
+	return value.list
+}
+
+func (d *configDecoder) boolean(key string) bool {
+	value, _ := d.lookup(key, tomlBool)
+	return value.boolean
+}
+
+func (d *configDecoder) integer(key string) int {
+	value, ok := d.lookup(key, tomlInteger)
+	if ok && value.integer < 0 {
+		d.fail(key, fmt.Errorf("line %d: must not be negative", value.line))
+		return 0
+	}
+	return int(value.integer)
+}
+
+// duration reads a Go duration string such as "30s" or "168h".
+func (d *configDecoder) duration(key string) time.Duration {
+	value, ok := d.lookup(key, tomlString)
+	if !ok {
+		return 0
+	}
+	parsed, err := time.ParseDuration(value.str)
+	if err != nil {
+		d.fail(key, fmt.Errorf("line %d: %w", value.line, err))
+		return 0
+	}
+	if parsed < 0 {
+		d.fail(key, fmt.Errorf("line %d: must not be negative", value.line))
+		return 0
+	}
+	return parsed
+}
+
+func (d *configDecoder) finish() error {
+	if d.err != nil {
+		return d.err
+	}
+	var unknown []string
+	for key := range d.values {
+		if !d.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown configuration keys: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+type tomlKind int
+
+const (
+	tomlString tomlKind = iota
+	tomlInteger
+	tomlBool
+	tomlArray
+)
+
+func (k tomlKind) String() string {
+	switch k {
+	case tomlString:
+		return "string"
+	case tomlInteger:
+		return "integer"
+	case tomlBool:
+		return "boolean"
+	default:
+		return "string array"
+	}
+}
+
+type tomlValue struct {
+	kind    tomlKind
+	str     string
+	integer int64
+	boolean bool
+	list    []string
+	line    int
+}
+
+// parseTOML parses the subset of TOML the configuration uses: [table]
+// headers, key = value pairs, basic and literal strings, integers, booleans,
+// single-line string arrays and # comments. Keys are returned as
+// "table.key".
+func parseTOML(src string) (map[string]tomlValue, error) {
+	values := make(map[string]tomlValue)
+	table := ""
+	for i, raw := range strings.Split(src, "\n") {
+		line := i + 1
+		text := strings.TrimSpace(raw)
+		if text == "" || text[0] == '#' {
+			continue
+		}
+		if text[0] == '[' {
+			end := strings.IndexByte(text, ']')
+			if end < 0 || strings.HasPrefix(text, "[[") {
+				return nil, fmt.Errorf("line %d: malformed table header", line)
+			}
+			if rest := strings.TrimSpace(text[end+1:]); rest != "" && rest[0] != '#' {
+				return nil, fmt.Errorf("line %d: unexpected text after table header", line)
+			}
+			table = strings.TrimSpace(text[1:end])
+			if !validTOMLKey(table) {
+				return nil, fmt.Errorf("line %d: invalid table name %q", line, table)
+			}
+			continue
+		}
+		eq := strings.IndexByte(text, '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", line)
+		}
+		key := strings.TrimSpace(text[:eq])
+		if !validTOMLKey(key) {
+			return nil, fmt.Errorf("line %d: invalid key %q", line, key)
+		}
+		if table != "" {
+			key = table + "." + key
+		}
+		if _, ok := values[key]; ok {
+			return nil, fmt.Errorf("line %d: duplicate key %s", line, key)
+		}
+		value, rest, err := parseTOMLValue(strings.TrimSpace(text[eq+1:]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", line, key, err)
+		}
+		if rest = strings.TrimSpace(rest); rest != "" && rest[0] != '#' {
+			return nil, fmt.Errorf("line %d: unexpected text after value of %s", line, key)
+		}
+		value.line = line
+		values[key] = value
+	}
+	return values, nil
+}
+
+func validTOMLKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// parseTOMLValue parses one value from the start of text and returns the
+// unconsumed remainder.
+func parseTOMLValue(text string) (tomlValue, string, error) {
+	if text == "" {
+		return tomlValue{}, "", fmt.Errorf("missing value")
+	}
+	switch text[0] {
+	case '"', '\'':
+		s, rest, err := parseTOMLString(text)
+		return tomlValue{kind: tomlString, str: s}, rest, err
+	case '[':
+		var list []string
+		rest := strings.TrimSpace(text[1:])
+		for {
+			if rest == "" {
+				return tomlValue{}, "", fmt.Errorf("unterminated array")
+			}
+			if rest[0] == ']' {
+				return tomlValue{kind: tomlArray, list: list}, rest[1:], nil
+			}
+			s, after, err := parseTOMLString(rest)
+			if err != nil {
+				return tomlValue{}, "", fmt.Errorf("array elements must be strings: %w", err)
+			}
+			list = append(list, s)
+			rest = strings.TrimSpace(after)
+			if strings.HasPrefix(rest, ",") {
+				rest = strings.TrimSpace(rest[1:])
+			} else if !strings.HasPrefix(rest, "]") {
+				return tomlValue{}, "", fmt.Errorf("expected , or ] in array")
+			}
+		}
+	}
+	end := strings.IndexAny(text, " \t#")
+	if end < 0 {
+		end = len(text)
+	}
+	word, rest := text[:end], text[end:]
+	switch word {
+	case "true":
+		return tomlValue{kind: tomlBool, boolean: true}, rest, nil
+	case "false":
+		return tomlValue{kind: tomlBool}, rest, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 10, 64)
+	if err != nil {
+		return tomlValue{}, "", fmt.Errorf("unsupported value %q; quote strings and durations", word)
+	}
+	return tomlValue{kind: tomlInteger, integer: n}, rest, nil
+}
+
+// parseTOMLString parses a basic ("...") or literal ('...') string.
+func parseTOMLString(text string) (string, string, error) {
+	if text == "" || (text[0] != '"' && text[0] != '\'') {
+		return "", "", fmt.Errorf("expected a quoted string")
+	}
+	quote := text[0]
+	if quote == '\'' {
+		end := strings.IndexByte(text[1:], '\'')
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string")
+		}
+		return text[1 : end+1], text[end+2:], nil
+	}
+	var b strings.Builder
+	for i := 1; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '"':
+			return b.String(), text[i+1:], nil
+		case '\\':
+			i++
+			if i == len(text) {
+				return "", "", fmt.Errorf("unterminated string")
+			}
+			switch text[i] {
+			case '"', '\\':
+				b.WriteByte(text[i])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				return "", "", fmt.Errorf("unsupported escape \\%c", text[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xDarkicex/libravdb/libravdb"
+	"github.com/xDarkicex/libravdb/pgwire"
+)
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	credential, err := pgwire.NewSCRAMCredential("alice", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	credentials := writeFile(t, dir, "users", "# comment\n\nalice "+credential.Verifier()+"\n")
+	path := writeFile(t, dir, "server.toml", `
+# LibraVDB server
+[storage]
+path = "/var/lib/libravdb/data.libravdb"
+durability = 'unsafe_nosync'
+
+[indexing]
+async_queue_depth = 4_096
+async_workers = 4
+
+[temporal]
+retention = "168h"
+
+[pgwire]
+listen = "0.0.0.0:6432" # all interfaces
+max_connections = 200
+idle_timeout = "15m"
+trusted_proxy_cidrs = ["10.0.0.0/8", "192.168.0.0/16"]
+
+[tls]
+cert_file = "/etc/libravdb/server.crt"
+key_file = "/etc/libravdb/server.key"
+require = true
+
+[auth]
+credentials_file = "`+credentials+`"
+failure_threshold = 5
+
+[http]
+listen = "127.0.0.1:9187"
+metrics = false
+`)
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.StoragePath != "/var/lib/libravdb/data.libravdb" || config.Durability != libravdb.DurabilityUnsafeNoSync {
+		t.Fatalf("storage = %q, %v", config.StoragePath, config.Durability)
+	}
+	if config.AsyncIndexQueueDepth != 4096 || config.AsyncIndexWorkers != 4 || config.TemporalRetention != 168*time.Hour {
+		t.Fatalf("indexing/temporal = %d, %d, %v", config.AsyncIndexQueueDepth, config.AsyncIndexWorkers, config.TemporalRetention)
+	}
+	server := config.Server
+	if server.Addr != "0.0.0.0:6432" || server.MaxConnections != 200 || server.IdleTimeout != 15*time.Minute ||
+		len(server.TrustedProxyCIDRs) != 2 || server.TrustedProxyCIDRs[1] != "192.168.0.0/16" {
+		t.Fatalf("pgwire = %+v", server)
+	}
+	if !server.RequireTLS || server.TLSCertificateFile != "/etc/libravdb/server.crt" || !server.RequireAuthentication || server.AuthFailureThreshold != 5 {
+		t.Fatalf("tls/auth = %+v", server)
+	}
+	if got := server.Credentials["alice"]; got.Verifier() != credential.Verifier() {
+		t.Fatalf("alice credential = %+v", got)
+	}
+	if config.HTTPAddr != "127.0.0.1:9187" || config.Metrics || config.ShutdownTimeout != defaultShutdownTimeout {
+		t.Fatalf("http = %q, metrics %v, shutdown %v", config.HTTPAddr, config.Metrics, config.ShutdownTimeout)
+	}
+	if got := len(config.DatabaseOptions()); got != 5 {
+		t.Fatalf("DatabaseOptions = %d options, want 5", got)
+	}
+}
+
+func TestLoadConfigRejectsMistakes(t *testing.T) {
+	dir := t.TempDir()
+	for _, tc := range []struct {
+		content string
+		want    string
+	}{
+		{"[storage]\ndurability = \"synchronous\"\n", "storage.path is required"},
+		{"[storage]\npath = \"x\"\npaht = \"y\"\n", "unknown configuration keys: storage.paht"},
+		{"[storage]\npath = \"x\"\n[pgwire]\nmax_connections = \"lots\"\n", "expected integer, got string"},
+		{"[storage]\npath = \"x\"\n[pgwire]\nidle_timeout = \"soon\"\n", "pgwire.idle_timeout"},
+		{"[storage]\npath = \"x\"\ndurability = \"fast\"\n", "storage.durability"},
+		{"[storage]\npath = \"x\"\n[indexing]\nasync_workers = 2\n", "must be set together"},
+		{"[storage]\npath = \"x\"\npath = \"y\"\n", "duplicate key storage.path"},
+		{"[storage]\npath = \"x\n", "unterminated string"},
+		{"[storage]\npath = \"x\"\n[auth]\ncredentials_file = \"" + writeFile(t, dir, "plain", "alice hunter2\n") + "\"\n", "SCRAM verifier"},
+	} {
+		_, err := LoadConfig(writeFile(t, dir, "bad.toml", tc.content))
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Fatalf("LoadConfig(%q) error = %v, want %q", tc.content, err, tc.want)
+		}
+	}
+}
+
+func TestRunCheckpointsOnShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.libravdb")
+	config, err := decodeConfig(map[string]tomlValue{
+		"storage.path":  {kind: tomlString, str: path},
+		"pgwire.listen": {kind: tomlString, str: "127.0.0.1:0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- run(ctx, config, log.New(io.Discard, "", 0)) }()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("run did not return after cancellation")
+	}
+	db, err := libravdb.Open(libravdb.WithStoragePath(path), libravdb.WithMetrics(false))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	_ = db.Close()
+}
+
+func TestHTTPHandler(t *testing.T) {
+	db, err := libravdb.Open(libravdb.WithStoragePath(":memory:server_http"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := newHTTPHandler(db, true)
+	get := func(path string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+		return recorder
+	}
+	if res := get("/healthz"); res.Code != http.StatusOK || !strings.Contains(res.Body.String(), `"healthy"`) {
+		t.Fatalf("/healthz = %d %s", res.Code, res.Body)
+	}
+	if res := get("/metrics"); res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "libravdb_") {
+		t.Fatalf("/metrics = %d %s", res.Code, res.Body)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if res := get("/healthz"); res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("/healthz after Close = %d", res.Code)
+	}
+}
+
+func TestPrintSCRAMVerifier(t *testing.T) {
+	var out strings.Builder
+	if err := printSCRAMVerifier(&out, strings.NewReader("correct horse\n"), "alice"); err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Fields(out.String())
+	if len(fields) != 2 || fields[0] != "alice" {
+		t.Fatalf("output = %q", out.String())
+	}
+	if _, err := pgwire.ParseSCRAMVerifier("alice", fields[1]); err != nil {
+		t.Fatalf("printed verifier does not parse: %v", err)
+	}
+	if err := printSCRAMVerifier(&out, strings.NewReader(""), "alice"); err == nil {
+		t.Fatal("empty password accepted")
+	}
+}
@@ -0,0 +1,168 @@
+// Command libravdb-server serves a LibraVDB database over the PostgreSQL wire
+// protocol, configured from a TOML file:
+//
+//	libravdb-server -config /etc/libravdb/server.toml
+//
+// It optionally serves Prometheus metrics on /metrics and a health check on
+// /healthz, and on SIGINT or SIGTERM drains client connections, checkpoints
+// the database and exits. See docs/configuration/server.md.
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/obs"
+	"github.com/xDarkicex/libravdb/libravdb"
+	"github.com/xDarkicex/libravdb/pgwire"
+)
+
+func main() {
+	configPath := flag.String("config", "libravdb.toml", "path to the server configuration file")
+	verifierUser := flag.String("scram-verifier", "", "read a password from stdin, print a credentials file line for this user and exit")
+	flag.Parse()
+
+	if *verifierUser != "" {
+		if err := printSCRAMVerifier(os.Stdout, os.Stdin, *verifierUser); err != nil {
+			log.Fatalf("scram verifier: %v", err)
+		}
+		return
+	}
+
+	config, err := LoadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("load config: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := run(ctx, config, log.Default()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves until ctx is cancelled or a listener fails, then shuts down in
+// order: pgwire connections, the HTTP endpoint, a final checkpoint and the
+// database itself.
+func run(ctx context.Context, config *Config, logger *log.Logger) error {
+	db, err := libravdb.Open(config.DatabaseOptions()...)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+
+	var httpServer *http.Server
+	httpErr := make(chan error, 1)
+	if config.HTTPAddr != "" {
+		ln, err := net.Listen("tcp", config.HTTPAddr)
+		if err != nil {
+			_ = db.Close()
+			return fmt.Errorf("http listen on %s: %w", config.HTTPAddr, err)
+		}
+		httpServer = &http.Server{Handler: newHTTPHandler(db, config.Metrics), ReadHeaderTimeout: 10 * time.Second}
+		go func() {
+			if err := httpServer.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+				httpErr <- fmt.Errorf("http: %w", err)
+			}
+		}()
+		logger.Printf("libravdb-server: http on %s", ln.Addr())
+	}
+
+	serveCtx, cancelServe := context.WithCancel(ctx)
+	defer cancelServe()
+	server := pgwire.NewServer(db, config.Server)
+	pgDone := make(chan error, 1)
+	go func() { pgDone <- server.Serve(serveCtx) }()
+	logger.Printf("libravdb-server: pgwire on %s, storage %s", config.Server.Addr, config.StoragePath)
+
+	var runErr error
+	pgStopped := false
+	select {
+	case <-ctx.Done():
+		logger.Printf("libravdb-server: shutting down")
+	case err := <-pgDone:
+		pgStopped = true
+		runErr = err
+	case err := <-httpErr:
+		runErr = err
+	}
+
+	cancelServe()
+	if !pgStopped {
+		if err := <-pgDone; err != nil && !errors.Is(err, context.Canceled) && runErr == nil {
+			runErr = err
+		}
+	}
+	if httpServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Printf("libravdb-server: http shutdown: %v", err)
+		}
+		cancel()
+	}
+	checkpointCtx, cancel := context.WithTimeout(context.Background(), config.ShutdownTimeout)
+	defer cancel()
+	if err := db.Checkpoint(checkpointCtx); err != nil && runErr == nil {
+		runErr = fmt.Errorf("final checkpoint: %w", err)
+	}
+	if err := db.Close(); err != nil && runErr == nil {
+		runErr = fmt.Errorf("close database: %w", err)
+	}
+	return runErr
+}
+
+// newHTTPHandler serves /healthz and, when enabled, /metrics. /healthz
+// answers 200 with the database health report, or 503 when any check fails.
+func newHTTPHandler(db *libravdb.Database, metrics bool) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		status, err := db.Health(r.Context())
+		if err != nil {
+			status = &obs.HealthStatus{Status: "unhealthy", Checks: map[string]*obs.CheckResult{
+				"health": {Healthy: false, Message: err.Error()},
+			}}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if status.Status != "healthy" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(status)
+	})
+	if metrics {
+		mux.Handle("/metrics", obs.NewMetrics().Handler())
+	}
+	return mux
+}
+
+// printSCRAMVerifier derives a SCRAM verifier from the first line of in and
+// writes a credentials file line for user to out.
+func printSCRAMVerifier(out io.Writer, in io.Reader, user string) error {
+	password, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	password = strings.TrimRight(password, "\r\n")
+	if password == "" {
+		return fmt.Errorf("no password on stdin")
+	}
+	if strings.ContainsAny(user, " \t") {
+		return fmt.Errorf("user names in the credentials file cannot contain whitespace")
+	}
+	credential, err := pgwire.NewSCRAMCredential(user, password)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s %s\n", user, credential.Verifier())
+	return err
+}
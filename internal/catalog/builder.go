@@ -177,6 +177,7 @@ func NewBuilderFrom(cat *Catalog) *Builder {
 	}
 	for _, fk := range cat.foreignKeys {
 		b.foreignKeys = append(b.foreignKeys, fkEntry{
+			name:            cat.ForeignKeyName(fk),
 			nameHash:        fk.NameHash,
 			sourceTableHash: fk.SourceTableHash,
 			targetTableHash: fk.TargetTableHash,
@@ -192,6 +193,7 @@ func NewBuilderFrom(cat *Catalog) *Builder {
 	}
 	for _, chk := range cat.checkConstraints {
 		b.checks = append(b.checks, checkEntry{
+			name:      cat.CheckName(chk),
 			nameHash:  chk.NameHash,
 			tableHash: chk.TableHash,
 			colHash:   chk.ColHash,
@@ -277,9 +279,17 @@ func (b *Builder) AddForeignKey(info ForeignKeyInfo) uint32 {
 
 // AddCheckConstraint registers a CHECK constraint. Returns the assigned OID.
 func (b *Builder) AddCheckConstraint(tableName, expr, colName string) uint32 {
+	return b.AddNamedCheckConstraint(tableName, "", expr, colName)
+}
+
+// AddNamedCheckConstraint registers a CHECK constraint under name, or under
+// a generated name when name is empty. Returns the assigned OID.
+func (b *Builder) AddNamedCheckConstraint(tableName, name, expr, colName string) uint32 {
 	oid := b.nextOID
 	b.nextOID++
-	name := fmt.Sprintf("__chk_%d", oid)
+	if name == "" {
+		name = fmt.Sprintf("__chk_%d", oid)
+	}
 	b.checks = append(b.checks, checkEntry{
 		name:      name,
 		tableName: tableName,
@@ -340,6 +350,69 @@ func (b *Builder) ReplaceJSONIndexesForTable(table string, indexes []JSONIndexIn
 	}
 }
 
+// DropTable removes a table together with the foreign keys it declares and
+// its CHECK constraints, DEFAULT values, and JSON indexes. Foreign keys of
+// other tables that reference it are kept; ALTER TABLE re-adds the table
+// under the same or a new name after dropping it.
+func (b *Builder) DropTable(name string) {
+	tableHash := hashString(name)
+	tables := b.tables[:0]
+	for _, t := range b.tables {
+		if hashOr(t.nameHash, hashString(t.name)) != tableHash {
+			tables = append(tables, t)
+		}
+	}
+	b.tables = tables
+	foreignKeys := b.foreignKeys[:0]
+	for _, fk := range b.foreignKeys {
+		if hashOr(fk.sourceTableHash, hashString(fk.sourceTable)) != tableHash {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+	b.foreignKeys = foreignKeys
+	checks := b.checks[:0]
+	for _, chk := range b.checks {
+		if hashOr(chk.tableHash, hashString(chk.tableName)) != tableHash {
+			checks = append(checks, chk)
+		}
+	}
+	b.checks = checks
+	defaults := b.defaults[:0]
+	for _, def := range b.defaults {
+		if hashOr(def.tableHash, hashString(def.tableName)) != tableHash {
+			defaults = append(defaults, def)
+		}
+	}
+	b.defaults = defaults
+	b.ReplaceJSONIndexesForTable(name, nil)
+}
+
+// RenameForeignKeyTarget points the foreign keys that reference table
+// oldName at newName.
+func (b *Builder) RenameForeignKeyTarget(oldName, newName string) {
+	oldHash := hashString(oldName)
+	for i := range b.foreignKeys {
+		fk := &b.foreignKeys[i]
+		if hashOr(fk.targetTableHash, hashString(fk.targetTable)) == oldHash {
+			fk.targetTable, fk.targetTableHash = newName, 0
+		}
+	}
+}
+
+// RenameForeignKeyTargetColumn points the foreign keys that reference
+// column oldColumn of table at newColumn.
+func (b *Builder) RenameForeignKeyTargetColumn(table, oldColumn, newColumn string) {
+	tableHash := hashString(table)
+	oldHash := hashString(oldColumn)
+	for i := range b.foreignKeys {
+		fk := &b.foreignKeys[i]
+		if hashOr(fk.targetTableHash, hashString(fk.targetTable)) == tableHash &&
+			hashOr(fk.targetColHash, hashString(fk.targetCol)) == oldHash {
+			fk.targetCol, fk.targetColHash = newColumn, 0
+		}
+	}
+}
+
 // AddGraphLabel registers a graph label. Returns the assigned OID.
 func (b *Builder) AddGraphLabel(name string, labelType uint8) uint32 {
 	oid := b.nextOID
@@ -393,7 +466,9 @@ func (b *Builder) Build() []byte {
 	jsonIndexesOffset := defaultsOffset + numDefaults*defDefSize
 
 	// Calculate variable-length data section size and offsets.
-	// Order: column names, CHECK expressions, DEFAULT values.
+	// Order: column names, CHECK expressions, DEFAULT values, JSON index
+	// names and paths, constraint names. Column name offsets are 16-bit, so
+	// they stay first.
 	var dataOff uint32
 	// Column name offsets
 	type nameSlot struct{ off, ln uint32 }
@@ -429,6 +504,16 @@ func (b *Builder) Build() []byte {
 		jsonPathOffsets[i] = dataOff
 		dataOff += uint32(len(idx.path))
 	}
+	fkNameOffsets := make([]uint32, numFKs)
+	for i, fk := range b.foreignKeys {
+		fkNameOffsets[i] = dataOff
+		dataOff += uint32(len(fk.name))
+	}
+	checkNameOffsets := make([]uint32, numChecks)
+	for i, chk := range b.checks {
+		checkNameOffsets[i] = dataOff
+		dataOff += uint32(len(chk.name))
+	}
 
 	dataSectionOffset := jsonIndexesOffset + numJSONIndexes*jsonIndexDefSize
 	totalSize := dataSectionOffset + dataOff
@@ -523,7 +608,7 @@ func (b *Builder) Build() []byte {
 			nameHash = hashString(fk.name)
 		}
 		writeUint32(buf, off, fk.oid)
-		writeUint32(buf, off+4, 0)
+		writeUint32(buf, off+4, dataSectionOffset+fkNameOffsets[i])
 		writeUint64(buf, off+8, nameHash)
 		writeUint64(buf, off+16, hashOr(fk.sourceTableHash, hashString(fk.sourceTable)))
 		writeUint64(buf, off+24, hashOr(fk.targetTableHash, hashString(fk.targetTable)))
@@ -531,20 +616,24 @@ func (b *Builder) Build() []byte {
 		writeUint64(buf, off+40, hashOr(fk.targetColHash, hashString(fk.targetCol)))
 		buf[off+48] = fk.onDelete
 		buf[off+49] = fk.onUpdate
+		writeUint16(buf, off+50, uint16(len(fk.name)))
+		copy(buf[dataSectionOffset+fkNameOffsets[i]:], fk.name)
 	}
 
 	// Write CheckConstraintDef entries
 	for i, chk := range b.checks {
 		off := checksOffset + uint32(i)*chkDefSize
 		writeUint32(buf, off, chk.oid)
-		writeUint32(buf, off+4, 0)
+		writeUint32(buf, off+4, dataSectionOffset+checkNameOffsets[i])
 		writeUint64(buf, off+8, hashOr(chk.nameHash, hashString(chk.name)))
 		writeUint64(buf, off+16, hashOr(chk.tableHash, hashString(chk.tableName)))
 		writeUint64(buf, off+24, hashOr(chk.colHash, hashString(chk.colName)))
 		writeUint32(buf, off+32, dataSectionOffset+exprOffsets[i])
 		writeUint32(buf, off+36, uint32(len(chk.expr)))
-		// copy expression text into data section
+		writeUint32(buf, off+40, uint32(len(chk.name)))
+		// copy expression and name text into data section
 		copy(buf[dataSectionOffset+exprOffsets[i]:], chk.expr)
+		copy(buf[dataSectionOffset+checkNameOffsets[i]:], chk.name)
 	}
 
 	// Write DefaultValueDef entries
@@ -0,0 +1,70 @@
+package catalog
+
+import "testing"
+
+func TestBuilderConstraintNamesSurviveRebuildAndDropTable(t *testing.T) {
+	b := NewBuilder()
+	b.AddTable("authors", []ColumnInfo{{Name: "id", Type: TypeString}})
+	b.AddTable("books", []ColumnInfo{{Name: "id", Type: TypeString}, {Name: "author_id", Type: TypeString}, {Name: "pages", Type: TypeInt}})
+	b.AddForeignKey(ForeignKeyInfo{Name: "books_author_fk", SourceTable: "books", SourceColumn: "author_id", TargetTable: "authors", TargetColumn: "id"})
+	b.AddNamedCheckConstraint("books", "books_pages_positive", "pages > 0", "pages")
+	b.AddDefaultValue("books", "pages", "1")
+	first, err := Load(b.Build(), nil)
+	if err != nil {
+		t.Fatalf("load catalog: %v", err)
+	}
+
+	// A builder seeded from a loaded catalog must carry names forward.
+	rebuilt := NewBuilderFrom(first)
+	rebuilt.RenameForeignKeyTarget("authors", "writers")
+	cat, err := Load(rebuilt.Build(), nil)
+	if err != nil {
+		t.Fatalf("load rebuilt catalog: %v", err)
+	}
+	booksHash := HashIdentifier("books")
+	fks := cat.ForeignKeysForTable(booksHash)
+	if len(fks) != 1 || cat.ForeignKeyName(fks[0]) != "books_author_fk" {
+		t.Fatalf("foreign keys = %v, want books_author_fk", fks)
+	}
+	if fks[0].TargetTableHash != HashIdentifier("writers") {
+		t.Fatalf("foreign key target was not renamed")
+	}
+	checks := cat.CheckConstraintsForTable(booksHash)
+	if len(checks) != 1 || cat.CheckName(checks[0]) != "books_pages_positive" || cat.CheckExpr(checks[0]) != "pages > 0" {
+		t.Fatalf("check constraints did not round-trip")
+	}
+
+	dropped := NewBuilderFrom(cat)
+	dropped.DropTable("books")
+	cat, err = Load(dropped.Build(), nil)
+	if err != nil {
+		t.Fatalf("load catalog after DropTable: %v", err)
+	}
+	if _, err := cat.GetTable(booksHash); err == nil {
+		t.Fatalf("books still present after DropTable")
+	}
+	if _, err := cat.GetTable(HashIdentifier("authors")); err != nil {
+		t.Fatalf("authors dropped with books: %v", err)
+	}
+	if len(cat.AllForeignKeys()) != 0 || len(cat.CheckConstraintsForTable(booksHash)) != 0 || len(cat.DefaultValuesForTable(booksHash)) != 0 {
+		t.Fatalf("constraints of books survived DropTable")
+	}
+}
+
+func TestLegacyConstraintEntriesHaveNoName(t *testing.T) {
+	b := NewBuilder()
+	b.AddTable("t", []ColumnInfo{{Name: "x", Type: TypeInt}})
+	b.AddNamedCheckConstraint("t", "t_x_check", "x > 0", "x")
+	data := b.Build()
+	cat, err := Load(data, nil)
+	if err != nil {
+		t.Fatalf("load catalog: %v", err)
+	}
+	chk := cat.CheckConstraintsForTable(HashIdentifier("t"))[0]
+	// Older v2 catalogs wrote zero padding where the name now lives.
+	legacy := *chk
+	legacy.NameOff, legacy.NameLen = 0, 0
+	if name := cat.CheckName(&legacy); name != "" {
+		t.Fatalf("legacy check name = %q, want empty", name)
+	}
+}
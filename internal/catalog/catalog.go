@@ -390,6 +390,24 @@ func (c *Catalog) CheckExpr(chk *CheckConstraintDef) string {
 	return string(c.data[chk.ExprOff : chk.ExprOff+chk.ExprLen])
 }
 
+// CheckName returns the name of a CHECK constraint, or "" for catalogs
+// written before constraint names were stored.
+func (c *Catalog) CheckName(chk *CheckConstraintDef) string {
+	if chk == nil || chk.NameLen == 0 || int(chk.NameOff)+int(chk.NameLen) > len(c.data) {
+		return ""
+	}
+	return string(c.data[chk.NameOff : chk.NameOff+chk.NameLen])
+}
+
+// ForeignKeyName returns the constraint name of a foreign key pair, or "" for
+// catalogs written before constraint names were stored.
+func (c *Catalog) ForeignKeyName(fk *ForeignKeyDef) string {
+	if fk == nil || fk.NameLen == 0 || int(fk.NameOff)+int(fk.NameLen) > len(c.data) {
+		return ""
+	}
+	return string(c.data[fk.NameOff : fk.NameOff+uint32(fk.NameLen)])
+}
+
 // DefaultValueForColumn returns the DEFAULT value text for a column, or empty
 // string if no default is defined.
 func (c *Catalog) DefaultValueForColumn(tableHash, colHash uint64) string {
@@ -124,10 +124,12 @@ type GraphLabelDef struct {
 }
 
 // ForeignKeyDef defines a foreign key constraint between two tables.
-// Name hashes are case-insensitive FNV-1a.
+// Name hashes are case-insensitive FNV-1a. The constraint name text lives in
+// the trailing data section at NameOff/NameLen; those fields were padding in
+// older v2 catalogs, where NameLen is zero.
 type ForeignKeyDef struct {
 	OID             uint32
-	Padding         uint32
+	NameOff         uint32 // byte offset of constraint name in trailing data section
 	NameHash        uint64 // FNV-1a hash of constraint name (may be auto-generated)
 	SourceTableHash uint64 // FNV-1a hash of source (child) table name
 	TargetTableHash uint64 // FNV-1a hash of target (parent) table name
@@ -135,20 +137,23 @@ type ForeignKeyDef struct {
 	TargetColHash   uint64 // FNV-1a hash of target column name
 	OnDelete        uint8  // OnDeleteAction constant
 	OnUpdate        uint8  // OnDeleteAction constant (reused enum)
-	Padding2        [6]byte
+	NameLen         uint16 // byte length of constraint name
+	Padding2        [4]byte
 }
 
 // CheckConstraintDef defines a CHECK constraint. The expression text is stored
-// in the trailing variable-length data section at offset ExprOff with length ExprLen.
+// in the trailing variable-length data section at offset ExprOff with length
+// ExprLen, and the constraint name at NameOff/NameLen (zero in older v2
+// catalogs, where those fields were padding).
 type CheckConstraintDef struct {
 	OID       uint32
-	Padding   uint32
+	NameOff   uint32 // byte offset of constraint name in trailing data section
 	NameHash  uint64 // FNV-1a hash of constraint name
 	TableHash uint64 // FNV-1a hash of owning table
 	ColHash   uint64 // FNV-1a hash of column (0 for table-level CHECK)
 	ExprOff   uint32 // byte offset into trailing data section
 	ExprLen   uint32 // byte length of expression text
-	Padding2  [4]byte
+	NameLen   uint32 // byte length of constraint name
 }
 
 // DefaultValueDef defines a column DEFAULT value. The value text is stored in
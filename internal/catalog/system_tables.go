@@ -58,6 +58,15 @@ const (
 	sysColOIDIdxTablespace = 63
 	sysColOIDIdxDef        = 64
 
+	// pg_proc column OIDs
+	sysColOIDProOID       = 70
+	sysColOIDProname      = 71
+	sysColOIDPronamespace = 72
+	sysColOIDProkind      = 73
+	sysColOIDPronargs     = 74
+	sysColOIDProrettype   = 75
+	sysColOIDProargtypes  = 76
+
 	// GRAPH_NODES column OIDs
 	sysColOIDGNID         = 20
 	sysColOIDGNCollection = 21
@@ -188,15 +197,47 @@ var systemTables = func() map[uint32]*SystemTableInfo {
 	}
 	m[sysOIDPgNamespace] = pgNs
 
+	// pg_proc lists functions registered from Go. Built-in functions are not
+	// listed; drivers that probe it during type setup only need the relation.
+	pgProc := &SystemTableInfo{
+		Table: TableDef{
+			OID:          sysOIDPgProc,
+			NameHash:     hashString("pg_proc"),
+			ColumnsCount: 7,
+		},
+		Columns: make(map[uint64]*ColumnDef),
+	}
+	pgProc.Columns[hashString("oid")] = &ColumnDef{
+		OID: sysColOIDProOID, NameHash: hashString("oid"), Type: TypeOID,
+	}
+	pgProc.Columns[hashString("proname")] = &ColumnDef{
+		OID: sysColOIDProname, NameHash: hashString("proname"), Type: TypeName,
+	}
+	pgProc.Columns[hashString("pronamespace")] = &ColumnDef{
+		OID: sysColOIDPronamespace, NameHash: hashString("pronamespace"), Type: TypeOID,
+	}
+	pgProc.Columns[hashString("prokind")] = &ColumnDef{
+		OID: sysColOIDProkind, NameHash: hashString("prokind"), Type: TypeChar,
+	}
+	pgProc.Columns[hashString("pronargs")] = &ColumnDef{
+		OID: sysColOIDPronargs, NameHash: hashString("pronargs"), Type: TypeSmallInt,
+	}
+	pgProc.Columns[hashString("prorettype")] = &ColumnDef{
+		OID: sysColOIDProrettype, NameHash: hashString("prorettype"), Type: TypeOID,
+	}
+	pgProc.Columns[hashString("proargtypes")] = &ColumnDef{
+		OID: sysColOIDProargtypes, NameHash: hashString("proargtypes"), Type: TypeString,
+	}
+	m[sysOIDPgProc] = pgProc
+
 	// These relations are intentionally present even when empty. Python
-	// drivers probe pg_range/pg_proc during type setup, while SQLAlchemy uses
+	// drivers probe pg_range during type setup, while SQLAlchemy uses
 	// pg_constraint/pg_index/pg_attrdef during reflection. Their live rows are
 	// populated by the catalog compatibility layer as support is needed; an
 	// empty relation is still the correct answer for unsupported PostgreSQL
-	// features such as ranges, procedures, and generated defaults.
+	// features such as ranges and generated defaults.
 	for oid, name := range map[uint32]string{
 		sysOIDPgRange:      "pg_range",
-		sysOIDPgProc:       "pg_proc",
 		sysOIDPgConstraint: "pg_constraint",
 		sysOIDPgIndex:      "pg_index",
 		sysOIDPgAttrdef:    "pg_attrdef",
@@ -60,6 +60,7 @@ func (g *graphStore) BFS(start uint64, maxDepth int, visit VisitAction, bitset *
 	if maxDepth <= 0 {
 		maxDepth = 1 << 20
 	}
+	cutoff := g.expiryCutoff()
 	bitset.Clear()
 	frontier.Clear()
 
@@ -100,7 +101,7 @@ func (g *graphStore) BFS(start uint64, maxDepth int, visit VisitAction, bitset *
 
 		page := g.index.Lookup(node)
 		if page != nil {
-			gen := g.enumerateTargets(page, 0, step, bitset, frontier, KindSet{}, WeightFilter{}, EdgePredicate{}, nil, 1, false)
+			gen := g.enumerateTargets(page, 0, step, bitset, frontier, KindSet{}, WeightFilter{}, EdgePredicate{}, nil, cutoff, 1, false)
 
 			if atomic.LoadUint32(&page.Header.Generation) != gen {
 				if err := guard.leave(); err != nil {
@@ -128,7 +129,7 @@ func (g *graphStore) BFS(start uint64, maxDepth int, visit VisitAction, bitset *
 		}
 		reversePage := g.reverse.locator.Lookup(node)
 		if reversePage != nil {
-			reverseGen := g.enumerateTargets(reversePage, 0, step, bitset, frontier, KindSet{}, WeightFilter{}, EdgePredicate{}, nil, 1, true)
+			reverseGen := g.enumerateTargets(reversePage, 0, step, bitset, frontier, KindSet{}, WeightFilter{}, EdgePredicate{}, nil, cutoff, 1, true)
 			if atomic.LoadUint32(&reversePage.Header.Generation) != reverseGen {
 				if err := reverseGuard.leave(); err != nil {
 					return err
@@ -182,6 +183,7 @@ func (g *graphStore) BFSPattern(start uint64, edges []EdgePlan, maxDepth int, vi
 			break
 		}
 	}
+	cutoff := g.expiryCutoff()
 
 	bitset.Clear()
 	frontier.Clear()
@@ -229,7 +231,7 @@ func (g *graphStore) BFSPattern(start uint64, edges []EdgePlan, maxDepth int, vi
 				oldTail := frontier.tail
 				page := g.index.Lookup(node)
 				if page != nil {
-					gen := g.enumerateTargets(page, band, step, bitset, frontier, ks, edges[band].Weight, predicate, propertyDecoder, numBands, dir < 0)
+					gen := g.enumerateTargets(page, band, step, bitset, frontier, ks, edges[band].Weight, predicate, propertyDecoder, cutoff, numBands, dir < 0)
 					if atomic.LoadUint32(&page.Header.Generation) != gen {
 						for i := oldTail; i < frontier.tail; i++ {
 							nd := frontier.data[i]
@@ -253,7 +255,7 @@ func (g *graphStore) BFSPattern(start uint64, edges []EdgePlan, maxDepth int, vi
 				oldTail := frontier.tail
 				page := g.reverse.locator.Lookup(node)
 				if page != nil {
-					gen := g.enumerateTargets(page, band, step, bitset, frontier, ks, edges[band].Weight, predicate, propertyDecoder, numBands, dir > 0)
+					gen := g.enumerateTargets(page, band, step, bitset, frontier, ks, edges[band].Weight, predicate, propertyDecoder, cutoff, numBands, dir > 0)
 					if atomic.LoadUint32(&page.Header.Generation) != gen {
 						for i := oldTail; i < frontier.tail; i++ {
 							nd := frontier.data[i]
@@ -289,8 +291,9 @@ func (g *graphStore) BFSPattern(start uint64, edges []EdgePlan, maxDepth int, vi
 // into the frontier. Targets are pushed at (band, step+1) — same band, one
 // step deeper.  The caller must hold HyalineEnter on the appropriate pool.
 // Returns the generation snapshot taken at entry; the caller compares with a
-// re-read to detect concurrent writes.
-func (g *graphStore) enumerateTargets(page *EdgeTablePage, band int, step int, bitset *Bitset, frontier *FrontierBuf, kindFilter KindSet, weightFilter WeightFilter, predicate EdgePredicate, propertyDecoder *apexjson.Decoder, numBands int, onlyUndirected bool) uint32 {
+// re-read to detect concurrent writes. A non-zero cutoff skips edges whose
+// expires_at property is at or before it.
+func (g *graphStore) enumerateTargets(page *EdgeTablePage, band int, step int, bitset *Bitset, frontier *FrontierBuf, kindFilter KindSet, weightFilter WeightFilter, predicate EdgePredicate, propertyDecoder *apexjson.Decoder, cutoff int64, numBands int, onlyUndirected bool) uint32 {
 	gen := atomic.LoadUint32(&page.Header.Generation)
 	totalCount := page.Header.Count
 
@@ -307,7 +310,7 @@ func (g *graphStore) enumerateTargets(page *EdgeTablePage, band int, step int, b
 
 		if pageCount <= EdgePageInlineCapacity {
 			for i := uint16(0); i < pageCount; i++ {
-				if !g.matchesTraversalEdge(currPage.Inline[i], onlyUndirected, filterActive, kindFilter, weightFilter, predicate, propertyDecoder) {
+				if !g.matchesTraversalEdge(currPage.Inline[i], onlyUndirected, filterActive, kindFilter, weightFilter, predicate, propertyDecoder, cutoff) {
 					continue
 				}
 				target := currPage.Inline[i].Target
@@ -320,7 +323,7 @@ func (g *graphStore) enumerateTargets(page *EdgeTablePage, band int, step int, b
 			}
 		} else {
 			for i := uint16(0); i < EdgePageInlineCapacity; i++ {
-				if !g.matchesTraversalEdge(currPage.Inline[i], onlyUndirected, filterActive, kindFilter, weightFilter, predicate, propertyDecoder) {
+				if !g.matchesTraversalEdge(currPage.Inline[i], onlyUndirected, filterActive, kindFilter, weightFilter, predicate, propertyDecoder, cutoff) {
 					continue
 				}
 				target := currPage.Inline[i].Target
@@ -334,7 +337,7 @@ func (g *graphStore) enumerateTargets(page *EdgeTablePage, band int, step int, b
 			extra := unsafe.Slice((*Edge)(unsafe.Pointer(&currPage.Padding[0])), EdgePageOverflowCapacity)
 			extraCount := pageCount - EdgePageInlineCapacity
 			for i := uint16(0); i < extraCount; i++ {
-				if !g.matchesTraversalEdge(extra[i], onlyUndirected, filterActive, kindFilter, weightFilter, predicate, propertyDecoder) {
+				if !g.matchesTraversalEdge(extra[i], onlyUndirected, filterActive, kindFilter, weightFilter, predicate, propertyDecoder, cutoff) {
 					continue
 				}
 				target := extra[i].Target
@@ -359,14 +362,14 @@ func (g *graphStore) enumerateTargets(page *EdgeTablePage, band int, step int, b
 	return gen
 }
 
-func (g *graphStore) matchesTraversalEdge(edge Edge, onlyUndirected, filterActive bool, kindFilter KindSet, weightFilter WeightFilter, predicate EdgePredicate, propertyDecoder *apexjson.Decoder) bool {
+func (g *graphStore) matchesTraversalEdge(edge Edge, onlyUndirected, filterActive bool, kindFilter KindSet, weightFilter WeightFilter, predicate EdgePredicate, propertyDecoder *apexjson.Decoder, cutoff int64) bool {
 	if onlyUndirected && !g.isUndirectedKind(edge.GetKind()) {
 		return false
 	}
-	return g.matchesEdgeFilters(edge, filterActive, kindFilter, weightFilter, predicate, propertyDecoder)
+	return g.matchesEdgeFilters(edge, filterActive, kindFilter, weightFilter, predicate, propertyDecoder, cutoff)
 }
 
-func (g *graphStore) matchesEdgeFilters(edge Edge, filterActive bool, kindFilter KindSet, weightFilter WeightFilter, predicate EdgePredicate, propertyDecoder *apexjson.Decoder) bool {
+func (g *graphStore) matchesEdgeFilters(edge Edge, filterActive bool, kindFilter KindSet, weightFilter WeightFilter, predicate EdgePredicate, propertyDecoder *apexjson.Decoder, cutoff int64) bool {
 	if filterActive && !kindFilter.Has(edge.GetKind()) {
 		return false
 	}
@@ -374,7 +377,7 @@ func (g *graphStore) matchesEdgeFilters(edge Edge, filterActive bool, kindFilter
 		return false
 	}
 	properties, err := g.propertyBytes(edge.PropertyRef)
-	if err != nil {
+	if err != nil || propertiesExpired(properties, cutoff) {
 		return false
 	}
 	return predicate.MatchesWithPropertiesDecoder(edge, properties, propertyDecoder)
@@ -49,6 +49,7 @@ func (g *graphStore) appendPropertyBytes(head *EdgeTablePage, properties []byte,
 	if uint64(len(properties)) > uint64(^uint32(0))-4 {
 		return 0, fmt.Errorf("edge properties exceed uint32 storage limit")
 	}
+	g.noteEdgeExpiry(properties)
 
 	root := head.Header.PropertyRoot
 	var last *EdgePropertyPage
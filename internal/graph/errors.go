@@ -25,4 +25,8 @@ var (
 	// ErrGraphClosed indicates that the graph runtime is no longer available
 	// for traversal or mutation.
 	ErrGraphClosed = errors.New("graph is closed")
+
+	// ErrNoPath indicates that no path satisfying the traversal constraints
+	// connects the requested nodes.
+	ErrNoPath = errors.New("no path")
 )
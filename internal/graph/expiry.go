@@ -0,0 +1,163 @@
+package graph
+
+import (
+	"bytes"
+	"strings"
+	"time"
+)
+
+// EdgeExpiryProperty is the edge property that bounds an edge's lifetime.
+// Its value is either a number of Unix seconds or an RFC 3339 timestamp.
+// Live reads and traversals skip an edge once the instant has passed; the
+// edge stays physically stored until it is removed through a transaction.
+// Snapshot reads (the AtLSN family) are historical and are not filtered.
+const EdgeExpiryProperty = "expires_at"
+
+// edgeExpiryMarker is the property key as it appears in the canonical JSON
+// envelope. A payload without it cannot carry an expiry, which keeps the
+// check free of JSON parsing for ordinary property edges.
+var edgeExpiryMarker = []byte(`"` + EdgeExpiryProperty + `"`)
+
+var edgeExpiryLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// ExpiredEdge identifies a stored edge, in its canonical direction, whose
+// expiry has passed.
+type ExpiredEdge struct {
+	Src  uint64
+	Tgt  uint64
+	Kind uint8
+}
+
+// EdgeExpiry returns the instant held by the expires_at property of an edge
+// property envelope.
+func EdgeExpiry(properties []byte) (time.Time, bool) {
+	if !bytes.Contains(properties, edgeExpiryMarker) {
+		return time.Time{}, false
+	}
+	value, ok := findEdgeProperty(properties, EdgeExpiryProperty)
+	if !ok {
+		return time.Time{}, false
+	}
+	switch value.Kind {
+	case EdgePropertyNumber:
+		seconds := int64(value.Number)
+		nanos := int64((value.Number - float64(seconds)) * float64(time.Second))
+		return time.Unix(seconds, nanos), true
+	case EdgePropertyString:
+		text := strings.TrimSpace(value.String)
+		for _, layout := range edgeExpiryLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+// noteEdgeExpiry arms expiry filtering once any stored edge carries an
+// expires_at property. Every property payload, whether committed, replayed
+// or loaded from a segment, passes through appendPropertyBytes.
+func (g *graphStore) noteEdgeExpiry(properties []byte) {
+	if !g.expiringEdges.Load() && bytes.Contains(properties, edgeExpiryMarker) {
+		g.expiringEdges.Store(true)
+	}
+}
+
+// expiryCutoff returns the instant live reads compare edge expiry against,
+// or 0 when no edge has ever carried an expiry and filtering can be skipped.
+func (g *graphStore) expiryCutoff() int64 {
+	if !g.expiringEdges.Load() {
+		return 0
+	}
+	return time.Now().UnixNano()
+}
+
+func propertiesExpired(properties []byte, cutoff int64) bool {
+	if cutoff == 0 || len(properties) == 0 {
+		return false
+	}
+	expiry, ok := EdgeExpiry(properties)
+	return ok && expiry.UnixNano() <= cutoff
+}
+
+func (g *graphStore) edgeExpired(edge Edge, cutoff int64) bool {
+	if cutoff == 0 || edge.PropertyRef == 0 {
+		return false
+	}
+	properties, err := g.propertyBytes(edge.PropertyRef)
+	if err != nil {
+		return false
+	}
+	return propertiesExpired(properties, cutoff)
+}
+
+// liveEdges drops expired edges from a live neighbor list in place.
+func (g *graphStore) liveEdges(edges []Edge) []Edge {
+	cutoff := g.expiryCutoff()
+	if cutoff == 0 {
+		return edges
+	}
+	live := edges[:0]
+	for _, edge := range edges {
+		if !g.edgeExpired(edge, cutoff) {
+			live = append(live, edge)
+		}
+	}
+	return live
+}
+
+// liveEdgeViews is the EdgeView counterpart of liveEdges.
+func (g *graphStore) liveEdgeViews(views []EdgeView) []EdgeView {
+	cutoff := g.expiryCutoff()
+	if cutoff == 0 {
+		return views
+	}
+	live := views[:0]
+	for _, view := range views {
+		if !propertiesExpired(view.Properties, cutoff) {
+			live = append(live, view)
+		}
+	}
+	return live
+}
+
+// ExpiredEdges lists the stored edges whose expiry is at or before now. It
+// reads the physical forward pages, so edges already hidden from traversal
+// are reported until a transaction removes them.
+func (g *graphStore) ExpiredEdges(now time.Time) ([]ExpiredEdge, error) {
+	if g == nil {
+		return nil, ErrGraphClosed
+	}
+	g.lifecycleMu.RLock()
+	defer g.lifecycleMu.RUnlock()
+	if !g.graphAvailableUnlocked() {
+		return nil, ErrGraphClosed
+	}
+	if !g.expiringEdges.Load() {
+		return nil, nil
+	}
+	var nodeIDs []uint64
+	g.index.Iterate(func(nodeID uint64) {
+		nodeIDs = append(nodeIDs, nodeID)
+	})
+	cutoff := now.UnixNano()
+	var expired []ExpiredEdge
+	for _, nodeID := range nodeIDs {
+		views, err := g.neighborsWithPropertiesFromTable(nodeID, g.index, g.pagePools[0], g.cfg.PageShards)
+		if err != nil {
+			return nil, err
+		}
+		for _, view := range views {
+			if propertiesExpired(view.Properties, cutoff) {
+				expired = append(expired, ExpiredEdge{Src: nodeID, Tgt: view.Edge.Target, Kind: view.Edge.GetKind()})
+			}
+		}
+	}
+	return expired, nil
+}
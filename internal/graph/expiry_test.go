@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestExpiredEdgesAreHiddenUntilRemoved(t *testing.T) {
+	gi, err := NewGraph(DefaultGraphConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := gi.(*graphStore)
+	defer g.Close()
+
+	now := time.Now()
+	txn := g.BeginTxn()
+	for _, edge := range []struct {
+		tgt        uint64
+		properties map[string]interface{}
+	}{
+		{2, map[string]interface{}{EdgeExpiryProperty: float64(now.Add(-time.Minute).Unix())}},
+		{3, map[string]interface{}{EdgeExpiryProperty: now.Add(time.Hour).UTC().Format(time.RFC3339)}},
+		{4, map[string]interface{}{"cost": 1.0}},
+		{5, map[string]interface{}{EdgeExpiryProperty: now.Add(-time.Second).UTC().Format(time.RFC3339Nano)}},
+	} {
+		if err := txn.AddEdgeWithProperties(1, edge.tgt, 1, 7, edge.properties); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := txn.Commit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := func() []uint64 {
+		edges, err := g.Neighbors(1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make([]uint64, 0, len(edges))
+		for _, edge := range edges {
+			out = append(out, edge.Target)
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+		return out
+	}
+	if got := targets(); len(got) != 2 || got[0] != 3 || got[1] != 4 {
+		t.Fatalf("live neighbors = %v, want [3 4]", got)
+	}
+	if inbound, err := g.InboundNeighbors(2); err != nil || len(inbound) != 0 {
+		t.Fatalf("inbound neighbors of an expired edge = %v, %v", inbound, err)
+	}
+
+	bitset, err := g.GetBitset()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.PutBitset(bitset)
+	frontier, err := g.GetFrontierBuf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.PutFrontierBuf(frontier)
+	visited := map[uint64]bool{}
+	if err := g.BFS(1, 1, func(nodeID uint64, band int, step int) bool {
+		visited[nodeID] = true
+		return true
+	}, bitset, frontier); err != nil {
+		t.Fatal(err)
+	}
+	if visited[2] || visited[5] || !visited[3] || !visited[4] {
+		t.Fatalf("BFS visited %v", visited)
+	}
+
+	expired, err := g.ExpiredEdges(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].Tgt < expired[j].Tgt })
+	if len(expired) != 2 || expired[0] != (ExpiredEdge{Src: 1, Tgt: 2, Kind: 7}) || expired[1] != (ExpiredEdge{Src: 1, Tgt: 5, Kind: 7}) {
+		t.Fatalf("ExpiredEdges = %+v", expired)
+	}
+
+	// An expired edge is hidden but still physically present, so a
+	// transaction can remove it.
+	txn = g.BeginTxn()
+	for _, edge := range expired {
+		if err := txn.RemoveEdge(edge.Src, edge.Tgt, edge.Kind); err != nil {
+			t.Fatalf("RemoveEdge(%d->%d): %v", edge.Src, edge.Tgt, err)
+		}
+	}
+	if err := txn.Commit(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if expired, err := g.ExpiredEdges(now); err != nil || len(expired) != 0 {
+		t.Fatalf("ExpiredEdges after removal = %+v, %v", expired, err)
+	}
+	if got := targets(); len(got) != 2 {
+		t.Fatalf("live neighbors after removal = %v", got)
+	}
+}
+
+func TestEdgeExpiryParsesNumbersAndTimestamps(t *testing.T) {
+	for _, tc := range []struct {
+		properties map[string]interface{}
+		want       time.Time
+		ok         bool
+	}{
+		{map[string]interface{}{EdgeExpiryProperty: 1700000000.5}, time.Unix(1700000000, 5e8), true},
+		{map[string]interface{}{EdgeExpiryProperty: "2026-03-01T12:00:00Z"}, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), true},
+		{map[string]interface{}{EdgeExpiryProperty: "2026-03-01"}, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{map[string]interface{}{EdgeExpiryProperty: "soon"}, time.Time{}, false},
+		{map[string]interface{}{EdgeExpiryProperty: true}, time.Time{}, false},
+		{map[string]interface{}{"cost": 3.0}, time.Time{}, false},
+	} {
+		encoded, err := EncodeEdgeProperties(tc.properties)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := EdgeExpiry(encoded)
+		if ok != tc.ok || !got.Equal(tc.want) {
+			t.Fatalf("EdgeExpiry(%v) = %v, %v; want %v, %v", tc.properties, got, ok, tc.want, tc.ok)
+		}
+	}
+}
@@ -19,14 +19,19 @@ type GraphStats struct {
 	// MutationGeneration increments after each successfully published graph
 	// mutation batch. Controllers use it to detect derived-metric staleness.
 	MutationGeneration uint64
-	// PageRank publication metadata is intentionally independent of the
-	// algorithm implementation; a future maintenance job records these values
-	// once it atomically publishes a rank vector.
+	// PageRank publication metadata. RefreshPageRank records these values
+	// when it atomically publishes a rank vector; external jobs can report
+	// their own publications through RecordPageRankPublication.
+	// PageRankStale means the maintainer has not yet republished after a
+	// commit; a centrality read in that window computes the vector itself.
 	LastPageRankGeneration uint64
 	LastPageRankLSN        uint64
 	PageRankDuration       time.Duration
 	PageRankAvailable      bool
 	PageRankStale          bool
+	// PageRankError is the maintainer's last failure. The next publication
+	// of the live vector clears it.
+	PageRankError error
 }
 
 // storeMetrics represents the internal atomic counters.
@@ -45,6 +50,7 @@ type storeMetrics struct {
 	lastPageRankLSN        atomic.Uint64
 	pageRankDuration       atomic.Int64
 	pageRankAvailable      atomic.Bool
+	pageRankError          atomic.Pointer[error]
 }
 
 func (m *storeMetrics) get() GraphStats {
@@ -67,5 +73,14 @@ func (m *storeMetrics) get() GraphStats {
 		PageRankDuration:       time.Duration(m.pageRankDuration.Load()),
 		PageRankAvailable:      pageRankAvailable,
 		PageRankStale:          pageRankAvailable && pageRankGeneration != mutationGeneration,
+		PageRankError:          m.pageRankFailure(),
 	}
 }
+
+// pageRankFailure returns the maintainer's last failure, or nil.
+func (m *storeMetrics) pageRankFailure() error {
+	if err := m.pageRankError.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
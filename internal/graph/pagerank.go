@@ -0,0 +1,512 @@
+package graph
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultPageRankDamping is the probability of following an edge rather
+	// than teleporting.
+	DefaultPageRankDamping = 0.85
+	// DefaultPageRankTolerance is the L1 change between iterations below
+	// which the power iteration stops.
+	DefaultPageRankTolerance = 1e-6
+	// DefaultPageRankMaxIterations caps the power iteration.
+	DefaultPageRankMaxIterations = 100
+
+	// pageRankSnapshotCacheSize bounds the historical vectors kept for
+	// CentralityAtLSN. Each entry covers an LSN interval, so a handful of
+	// entries serves every snapshot a typical AS OF workload reads.
+	pageRankSnapshotCacheSize = 16
+)
+
+// PageRankOptions configures a PageRank computation. Zero values select the
+// defaults.
+type PageRankOptions struct {
+	Damping       float64
+	Tolerance     float64
+	MaxIterations int
+	// Kinds restricts the walk to these edge kinds; the zero set walks every
+	// kind. Undirected kinds are walked in both directions.
+	Kinds KindSet
+	// SnapshotLSN ranks the edges visible at that commit LSN. Zero ranks the
+	// live graph.
+	SnapshotLSN uint64
+}
+
+// PageRankVector is an immutable PageRank result. Scores form a probability
+// distribution over the nodes incident to a walked edge, plus any seeds;
+// every other node scores zero.
+type PageRankVector struct {
+	// SnapshotLSN is the graph commit LSN the vector describes.
+	SnapshotLSN uint64
+	// Generation is the graph mutation generation the live vector was
+	// computed from. It is zero for snapshot and personalized vectors.
+	Generation uint64
+	Iterations int
+	Converged  bool
+
+	scores map[uint64]float64
+	max    float64
+}
+
+// Score returns the stationary probability of nodeID.
+func (v *PageRankVector) Score(nodeID uint64) float64 {
+	if v == nil {
+		return 0
+	}
+	return v.scores[nodeID]
+}
+
+// Centrality returns the score of nodeID normalized so that the top-ranked
+// node scores 1.
+func (v *PageRankVector) Centrality(nodeID uint64) float64 {
+	if v == nil || v.max == 0 {
+		return 0
+	}
+	return v.scores[nodeID] / v.max
+}
+
+// Len returns the number of ranked nodes.
+func (v *PageRankVector) Len() int {
+	if v == nil {
+		return 0
+	}
+	return len(v.scores)
+}
+
+// Scores returns a copy of the ranked nodes and their probabilities.
+func (v *PageRankVector) Scores() map[uint64]float64 {
+	out := make(map[uint64]float64, v.Len())
+	if v != nil {
+		for node, score := range v.scores {
+			out[node] = score
+		}
+	}
+	return out
+}
+
+// pageRankSnapshot is a cached historical vector. The visible edge set, and
+// therefore the vector, is the same for every snapshot LSN in
+// [from, until); until == 0 leaves the interval open until the next commit.
+type pageRankSnapshot struct {
+	from, until uint64
+	vector      *PageRankVector
+}
+
+type pageRankEdge struct {
+	src, tgt uint64
+}
+
+// GraphCentrality returns the PageRank of nodeID normalized so that the
+// top-ranked node scores 1; nodes without edges score 0, as does every node
+// when the vector cannot be computed. NodeCentrality reports that error.
+func (g *graphStore) GraphCentrality(nodeID uint64) float64 {
+	centrality, _ := g.NodeCentrality(nodeID)
+	return centrality
+}
+
+// CentralityAtLSN returns GraphCentrality over the edges visible at
+// snapshotLSN. NodeCentralityAtLSN reports errors.
+func (g *graphStore) CentralityAtLSN(nodeID uint64, snapshotLSN uint64) float64 {
+	centrality, _ := g.NodeCentralityAtLSN(nodeID, snapshotLSN)
+	return centrality
+}
+
+// NodeCentrality is GraphCentrality with the error of computing the vector.
+// It reads the published vector in O(1). Graph commits wake the maintainer,
+// which republishes the vector warm-started from the previous one, so reads
+// normally find it current. A read that finds no vector for the committed
+// graph, before the first publication or while the maintainer catches up,
+// computes or waits for it rather than score against an older graph.
+func (g *graphStore) NodeCentrality(nodeID uint64) (float64, error) {
+	if !g.GraphAvailable() {
+		return 0, ErrGraphClosed
+	}
+	vector := g.pageRank.Load()
+	if vector == nil || vector.Generation != g.metrics.mutationGeneration.Load() {
+		var err error
+		if vector, err = g.RefreshPageRank(); err != nil {
+			return 0, err
+		}
+	}
+	return vector.Centrality(nodeID), nil
+}
+
+// NodeCentralityAtLSN is CentralityAtLSN with the error of computing the
+// vector, read from the vector PageRankAtLSN returns.
+func (g *graphStore) NodeCentralityAtLSN(nodeID uint64, snapshotLSN uint64) (float64, error) {
+	vector, err := g.PageRankAtLSN(snapshotLSN)
+	if err != nil {
+		return 0, err
+	}
+	return vector.Centrality(nodeID), nil
+}
+
+// RefreshPageRank recomputes and publishes the live PageRank vector unless
+// the published vector already reflects every committed mutation. The power
+// iteration starts from the previous vector, so a refresh after a small
+// change converges in a few iterations. The maintainer calls it after each
+// graph commit; maintenance code can call it to publish synchronously.
+func (g *graphStore) RefreshPageRank() (*PageRankVector, error) {
+	if !g.GraphAvailable() {
+		return nil, ErrGraphClosed
+	}
+	g.pageRankMu.Lock()
+	defer g.pageRankMu.Unlock()
+	// The generation is read before the edges: a mutation racing the scan
+	// leaves the vector tagged with the older generation, and the mutation's
+	// own wake refreshes again.
+	generation := g.metrics.mutationGeneration.Load()
+	previous := g.pageRank.Load()
+	if previous != nil && previous.Generation == generation {
+		return previous, nil
+	}
+	started := time.Now()
+	snapshotLSN := g.lastEdgeCommitLSN.Load()
+	vector, err := computePageRank(g.livePageRankEdges(KindSet{}), nil, PageRankOptions{}, previous)
+	if err != nil {
+		return nil, err
+	}
+	vector.SnapshotLSN = snapshotLSN
+	vector.Generation = generation
+	g.pageRank.Store(vector)
+	g.recordPageRankPublication(generation, snapshotLSN, time.Since(started))
+	return vector, nil
+}
+
+// PageRankAtLSN returns the PageRank vector of the edges visible at
+// snapshotLSN. Vectors are cached per interval of unchanged visibility, so
+// every snapshot between two graph commits shares one computation. Those
+// edges never change, so a cached vector is never stale; a snapshot outside
+// every cached interval computes its vector on the first read.
+func (g *graphStore) PageRankAtLSN(snapshotLSN uint64) (*PageRankVector, error) {
+	if !g.GraphAvailable() {
+		return nil, ErrGraphClosed
+	}
+	g.temporalMu.Lock()
+	for _, entry := range g.pageRankSnapshots {
+		if entry.from <= snapshotLSN && (entry.until == 0 || snapshotLSN < entry.until) {
+			g.temporalMu.Unlock()
+			return entry.vector, nil
+		}
+	}
+	edges, from, until := g.snapshotPageRankEdgesLocked(snapshotLSN, KindSet{})
+	epoch := g.temporalEpoch
+	g.temporalMu.Unlock()
+
+	vector, err := computePageRank(edges, nil, PageRankOptions{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	vector.SnapshotLSN = snapshotLSN
+
+	g.temporalMu.Lock()
+	defer g.temporalMu.Unlock()
+	// A commit during the computation may have moved the interval bounds;
+	// the vector is still correct for this snapshot but is not cached.
+	if epoch == g.temporalEpoch {
+		if len(g.pageRankSnapshots) == pageRankSnapshotCacheSize {
+			g.pageRankSnapshots = append(g.pageRankSnapshots[:0], g.pageRankSnapshots[1:]...)
+		}
+		g.pageRankSnapshots = append(g.pageRankSnapshots, pageRankSnapshot{from: from, until: until, vector: vector})
+	}
+	return vector, nil
+}
+
+// wakePageRank asks the maintainer to bring the published vector up to
+// date, starting it on first use. It never blocks.
+func (g *graphStore) wakePageRank() {
+	g.pageRankLifecycleMu.Lock()
+	if g.pageRankDone == nil && !g.pageRankStopped {
+		g.pageRankDone = make(chan struct{})
+		go g.maintainPageRank(g.pageRankDone)
+	}
+	g.pageRankLifecycleMu.Unlock()
+	select {
+	case g.pageRankWake <- struct{}{}:
+	default:
+	}
+}
+
+// stopPageRankMaintainer stops the maintainer and waits for a publication in
+// progress. Later wakes do not restart it.
+func (g *graphStore) stopPageRankMaintainer() {
+	g.pageRankLifecycleMu.Lock()
+	if g.pageRankStopped {
+		g.pageRankLifecycleMu.Unlock()
+		return
+	}
+	g.pageRankStopped = true
+	done := g.pageRankDone
+	close(g.pageRankStop)
+	g.pageRankLifecycleMu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// maintainPageRank publishes PageRank off the query path: each wake
+// republishes the live vector if a commit made it stale. Failures are
+// reported through Stats; readers keep the last published vector.
+func (g *graphStore) maintainPageRank(done chan struct{}) {
+	defer close(done)
+	for {
+		select {
+		case <-g.pageRankStop:
+			return
+		case <-g.pageRankWake:
+		}
+		if _, err := g.RefreshPageRank(); err != nil {
+			g.notePageRankFailure(err)
+		}
+	}
+}
+
+func (g *graphStore) notePageRankFailure(err error) {
+	g.metrics.pageRankError.Store(&err)
+}
+
+// PersonalizedPageRank ranks nodes by a walk that teleports back to seeds
+// instead of to a uniformly random node, scoring proximity to the seed set.
+// It is computed on demand and not published.
+func (g *graphStore) PersonalizedPageRank(seeds []uint64, opts PageRankOptions) (*PageRankVector, error) {
+	if len(seeds) == 0 {
+		return nil, fmt.Errorf("personalized PageRank requires at least one seed")
+	}
+	if !g.GraphAvailable() {
+		return nil, ErrGraphClosed
+	}
+	var edges []pageRankEdge
+	if opts.SnapshotLSN != 0 {
+		g.temporalMu.Lock()
+		edges, _, _ = g.snapshotPageRankEdgesLocked(opts.SnapshotLSN, opts.Kinds)
+		g.temporalMu.Unlock()
+	} else {
+		edges = g.livePageRankEdges(opts.Kinds)
+	}
+	vector, err := computePageRank(edges, seeds, opts, nil)
+	if err != nil {
+		return nil, err
+	}
+	vector.SnapshotLSN = opts.SnapshotLSN
+	return vector, nil
+}
+
+// noteEdgeCommitLocked records a temporal visibility boundary at lsn. Cached
+// snapshot vectors covering lsn are truncated, and those starting at or after
+// it are dropped. The caller holds temporalMu.
+func (g *graphStore) noteEdgeCommitLocked(lsn uint64) {
+	g.temporalEpoch++
+	for {
+		last := g.lastEdgeCommitLSN.Load()
+		if lsn <= last || g.lastEdgeCommitLSN.CompareAndSwap(last, lsn) {
+			break
+		}
+	}
+	kept := g.pageRankSnapshots[:0]
+	for _, entry := range g.pageRankSnapshots {
+		if lsn <= entry.from {
+			continue
+		}
+		if entry.until == 0 || lsn < entry.until {
+			entry.until = lsn
+		}
+		kept = append(kept, entry)
+	}
+	g.pageRankSnapshots = kept
+}
+
+func (g *graphStore) livePageRankEdges(kinds KindSet) []pageRankEdge {
+	var edges []pageRankEdge
+	g.ForEachEdge(func(src, tgt uint64, edge Edge) bool {
+		kind := edge.GetKind()
+		if kinds != (KindSet{}) && !kinds.Has(kind) {
+			return true
+		}
+		// ForEachEdge already reports undirected edges from both endpoints.
+		edges = append(edges, pageRankEdge{src: src, tgt: tgt})
+		return true
+	})
+	return edges
+}
+
+// snapshotPageRankEdgesLocked collects the edges visible at snapshotLSN from
+// the temporal index, with the interval [from, until) of snapshot LSNs that
+// see the same edges. The caller holds temporalMu.
+func (g *graphStore) snapshotPageRankEdgesLocked(snapshotLSN uint64, kinds KindSet) ([]pageRankEdge, uint64, uint64) {
+	var edges []pageRankEdge
+	var from, until uint64
+	boundary := func(lsn uint64) {
+		if lsn == 0 {
+			return
+		}
+		if lsn <= snapshotLSN {
+			if lsn > from {
+				from = lsn
+			}
+		} else if until == 0 || lsn < until {
+			until = lsn
+		}
+	}
+	for key, state := range g.temporalEdges {
+		for _, version := range state.Versions {
+			boundary(version.BeginLSN)
+			boundary(version.EndLSN)
+		}
+		if kinds != (KindSet{}) && !kinds.Has(key.Kind) {
+			continue
+		}
+		if _, visible := visibleEdgeVersion(state, snapshotLSN); !visible {
+			continue
+		}
+		edges = append(edges, pageRankEdge{src: key.Src, tgt: key.Tgt})
+		if g.isUndirectedKind(key.Kind) && key.Src != key.Tgt {
+			edges = append(edges, pageRankEdge{src: key.Tgt, tgt: key.Src})
+		}
+	}
+	return edges, from, until
+}
+
+// computePageRank runs the power iteration over edges. With seeds the
+// teleport distribution is uniform over the seeds (personalized PageRank),
+// otherwise over every node. Dangling nodes redistribute their mass along the
+// teleport distribution. warm, when non-nil, seeds the iteration with a
+// previous result.
+func computePageRank(edges []pageRankEdge, seeds []uint64, opts PageRankOptions, warm *PageRankVector) (*PageRankVector, error) {
+	damping, tolerance, maxIterations := opts.Damping, opts.Tolerance, opts.MaxIterations
+	if damping == 0 {
+		damping = DefaultPageRankDamping
+	}
+	if tolerance == 0 {
+		tolerance = DefaultPageRankTolerance
+	}
+	if maxIterations == 0 {
+		maxIterations = DefaultPageRankMaxIterations
+	}
+	if damping < 0 || damping >= 1 || math.IsNaN(damping) {
+		return nil, fmt.Errorf("PageRank damping must be in [0, 1), got %g", damping)
+	}
+	if tolerance < 0 || math.IsNaN(tolerance) || maxIterations < 0 {
+		return nil, fmt.Errorf("PageRank tolerance and iteration limit must be non-negative")
+	}
+
+	// Index nodes in ascending ID order so results do not depend on map or
+	// edge iteration order.
+	nodeSet := make(map[uint64]struct{}, len(edges))
+	for _, edge := range edges {
+		nodeSet[edge.src] = struct{}{}
+		nodeSet[edge.tgt] = struct{}{}
+	}
+	for _, seed := range seeds {
+		nodeSet[seed] = struct{}{}
+	}
+	vector := &PageRankVector{Converged: true, scores: make(map[uint64]float64, len(nodeSet))}
+	if len(nodeSet) == 0 {
+		return vector, nil
+	}
+	nodes := make([]uint64, 0, len(nodeSet))
+	for node := range nodeSet {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i] < nodes[j] })
+	index := make(map[uint64]int, len(nodes))
+	for i, node := range nodes {
+		index[node] = i
+	}
+
+	// Compressed adjacency: targets[offsets[i]:offsets[i+1]] are i's
+	// out-neighbors.
+	n := len(nodes)
+	offsets := make([]int, n+1)
+	for _, edge := range edges {
+		offsets[index[edge.src]+1]++
+	}
+	for i := 0; i < n; i++ {
+		offsets[i+1] += offsets[i]
+	}
+	targets := make([]int, len(edges))
+	fill := append([]int(nil), offsets[:n]...)
+	for _, edge := range edges {
+		src := index[edge.src]
+		targets[fill[src]] = index[edge.tgt]
+		fill[src]++
+	}
+
+	teleport := make([]float64, n)
+	if len(seeds) > 0 {
+		unique := make(map[int]struct{}, len(seeds))
+		for _, seed := range seeds {
+			unique[index[seed]] = struct{}{}
+		}
+		for i := range unique {
+			teleport[i] = 1 / float64(len(unique))
+		}
+	} else {
+		for i := range teleport {
+			teleport[i] = 1 / float64(n)
+		}
+	}
+
+	rank := make([]float64, n)
+	var warmMass float64
+	if warm != nil {
+		for i, node := range nodes {
+			rank[i] = warm.scores[node]
+			warmMass += rank[i]
+		}
+	}
+	if warmMass > 0 {
+		for i := range rank {
+			rank[i] /= warmMass
+		}
+	} else {
+		copy(rank, teleport)
+	}
+
+	next := make([]float64, n)
+	vector.Converged = false
+	for vector.Iterations < maxIterations {
+		vector.Iterations++
+		var dangling float64
+		for i := range next {
+			next[i] = 0
+		}
+		for i := 0; i < n; i++ {
+			degree := offsets[i+1] - offsets[i]
+			if degree == 0 {
+				dangling += rank[i]
+				continue
+			}
+			share := damping * rank[i] / float64(degree)
+			for _, target := range targets[offsets[i]:offsets[i+1]] {
+				next[target] += share
+			}
+		}
+		restart := (1 - damping) + damping*dangling
+		var delta float64
+		for i := range next {
+			next[i] += restart * teleport[i]
+			delta += math.Abs(next[i] - rank[i])
+		}
+		rank, next = next, rank
+		if delta < tolerance {
+			vector.Converged = true
+			break
+		}
+	}
+
+	for i, node := range nodes {
+		if rank[i] == 0 {
+			continue
+		}
+		vector.scores[node] = rank[i]
+		if rank[i] > vector.max {
+			vector.max = rank[i]
+		}
+	}
+	return vector, nil
+}
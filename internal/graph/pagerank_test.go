@@ -0,0 +1,298 @@
+package graph
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+// densePageRank is a direct transcription of the PageRank recurrence used as
+// an oracle: dangling mass follows the teleport distribution.
+func densePageRank(edges []pageRankEdge, nodes []uint64, seeds []uint64) map[uint64]float64 {
+	const damping = DefaultPageRankDamping
+	out := make(map[uint64][]uint64)
+	for _, edge := range edges {
+		out[edge.src] = append(out[edge.src], edge.tgt)
+	}
+	teleport := make(map[uint64]float64)
+	if len(seeds) > 0 {
+		for _, seed := range seeds {
+			teleport[seed] = 1 / float64(len(seeds))
+		}
+	} else {
+		for _, node := range nodes {
+			teleport[node] = 1 / float64(len(nodes))
+		}
+	}
+	rank := make(map[uint64]float64)
+	for node, p := range teleport {
+		rank[node] = p
+	}
+	for iteration := 0; iteration < 1000; iteration++ {
+		next := make(map[uint64]float64)
+		var dangling float64
+		for _, node := range nodes {
+			if len(out[node]) == 0 {
+				dangling += rank[node]
+				continue
+			}
+			for _, target := range out[node] {
+				next[target] += damping * rank[node] / float64(len(out[node]))
+			}
+		}
+		for _, node := range nodes {
+			next[node] += ((1 - damping) + damping*dangling) * teleport[node]
+		}
+		rank = next
+	}
+	return rank
+}
+
+func TestComputePageRankMatchesDenseIteration(t *testing.T) {
+	edges := []pageRankEdge{{1, 2}, {2, 3}, {3, 1}, {4, 1}, {4, 3}, {5, 5}, {2, 6}}
+	nodes := []uint64{1, 2, 3, 4, 5, 6}
+	check := func(name string, got *PageRankVector, want map[uint64]float64) {
+		t.Helper()
+		if !got.Converged {
+			t.Fatalf("%s did not converge in %d iterations", name, got.Iterations)
+		}
+		var total float64
+		for node, score := range want {
+			if math.Abs(got.Score(node)-score) > 1e-5 {
+				t.Fatalf("%s score(%d)=%v, want %v", name, node, got.Score(node), score)
+			}
+			total += got.Score(node)
+		}
+		if math.Abs(total-1) > 1e-9 {
+			t.Fatalf("%s scores sum to %v", name, total)
+		}
+	}
+
+	global, err := computePageRank(edges, nil, PageRankOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("global", global, densePageRank(edges, nodes, nil))
+	if global.Centrality(5) != 1 || global.Centrality(99) != 0 {
+		t.Fatalf("centrality(5)=%v centrality(99)=%v, want 1 and 0", global.Centrality(5), global.Centrality(99))
+	}
+
+	// A warm start from a nearby vector reaches the same fixed point faster.
+	// The added edge leaves out-degrees positive where they were, so the
+	// dangling mass, and with it most of the vector, is unchanged.
+	changed := append(append([]pageRankEdge(nil), edges...), pageRankEdge{4, 2})
+	cold, err := computePageRank(changed, nil, PageRankOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	warm, err := computePageRank(changed, nil, PageRankOptions{}, global)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("warm", warm, densePageRank(changed, nodes, nil))
+	if warm.Iterations >= cold.Iterations {
+		t.Fatalf("warm start took %d iterations, cold %d", warm.Iterations, cold.Iterations)
+	}
+
+	personalized, err := computePageRank(edges, []uint64{4, 7, 4}, PageRankOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	check("personalized", personalized, densePageRank(edges, append(nodes, 7), []uint64{4, 7}))
+	if personalized.Score(5) != 0 || personalized.Len() != 6 {
+		t.Fatalf("personalized walk reached an unreachable node: %v", personalized.Scores())
+	}
+
+	if _, err := computePageRank(edges, nil, PageRankOptions{Damping: 1}, nil); err == nil {
+		t.Fatal("damping 1 accepted")
+	}
+}
+
+func TestGraphCentralityRefreshesOncePerMutation(t *testing.T) {
+	store, err := NewGraph(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	// Without the maintainer, every publication below is made by a read.
+	store.(*graphStore).stopPageRankMaintainer()
+
+	if store.GraphCentrality(1) != 0 {
+		t.Fatal("empty graph centrality is not zero")
+	}
+	txn := store.BeginTxn()
+	for _, edge := range [][2]uint64{{2, 1}, {3, 1}, {1, 2}} {
+		if err := txn.AddEdge(edge[0], edge[1], 1, 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := txn.ApplyInMemoryAtLSN(10); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Stats().PageRankStale {
+		t.Fatal("empty-graph vector not stale after the first commit")
+	}
+	if got := store.GraphCentrality(1); got != 1 {
+		t.Fatalf("centrality(1)=%v, want 1", got)
+	}
+	if got := store.GraphCentrality(3); got <= 0 || got >= store.GraphCentrality(2) {
+		t.Fatalf("centrality(3)=%v, want below centrality(2)=%v", got, store.GraphCentrality(2))
+	}
+	published, err := store.RefreshPageRank()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stats := store.Stats()
+	if !stats.PageRankAvailable || stats.PageRankStale || stats.LastPageRankLSN != 10 || published.SnapshotLSN != 10 {
+		t.Fatalf("publication stats=%+v vector LSN=%d", stats, published.SnapshotLSN)
+	}
+	if again, _ := store.RefreshPageRank(); again != published {
+		t.Fatal("RefreshPageRank recomputed an up-to-date vector")
+	}
+
+	txn = store.BeginTxn()
+	if err := txn.RemoveEdge(2, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.ApplyInMemoryAtLSN(20); err != nil {
+		t.Fatal(err)
+	}
+	if !store.Stats().PageRankStale {
+		t.Fatal("published vector not stale after a mutation")
+	}
+	if got := store.GraphCentrality(3); got <= 0 || got >= 1 {
+		t.Fatalf("centrality(3) after removal=%v", got)
+	}
+	refreshed, err := store.RefreshPageRank()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if refreshed == published || refreshed.SnapshotLSN != 20 || store.Stats().PageRankStale {
+		t.Fatalf("vector not republished after mutation: LSN %d", refreshed.SnapshotLSN)
+	}
+}
+
+func TestPageRankMaintainerPublishesAfterCommit(t *testing.T) {
+	store, err := NewGraph(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	txn := store.BeginTxn()
+	if err := txn.AddEdge(2, 1, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.ApplyInMemoryAtLSN(10); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		stats := store.Stats()
+		if stats.PageRankAvailable && !stats.PageRankStale && stats.LastPageRankLSN == 10 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("PageRank for LSN 10 not published: %+v", stats)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	published := store.(*graphStore).pageRank.Load()
+	if got := store.GraphCentrality(1); got != 1 {
+		t.Fatalf("centrality(1)=%v, want 1", got)
+	}
+	if store.(*graphStore).pageRank.Load() != published {
+		t.Fatal("read recomputed the vector the maintainer published")
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.NodeCentrality(1); !errors.Is(err, ErrGraphClosed) {
+		t.Fatalf("NodeCentrality after Close err=%v, want ErrGraphClosed", err)
+	}
+}
+
+func TestCentralityAtLSNCachesPerInterval(t *testing.T) {
+	store, err := NewGraph(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	gs := store.(*graphStore)
+
+	first := store.BeginTxn()
+	if err := first.AddEdge(2, 1, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.ApplyInMemoryAtLSN(10); err != nil {
+		t.Fatal(err)
+	}
+	second := store.BeginTxn()
+	if err := second.RemoveEdge(2, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.AddEdge(1, 2, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.ApplyInMemoryAtLSN(20); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := store.CentralityAtLSN(1, 5); got != 0 {
+		t.Fatalf("centrality before any edge=%v", got)
+	}
+	if store.CentralityAtLSN(1, 12) != 1 || store.CentralityAtLSN(2, 12) >= 1 {
+		t.Fatalf("LSN 12: centrality(1)=%v centrality(2)=%v", store.CentralityAtLSN(1, 12), store.CentralityAtLSN(2, 12))
+	}
+	at15, err := store.PageRankAtLSN(15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	at12, _ := store.PageRankAtLSN(12)
+	if at12 != at15 {
+		t.Fatal("snapshots in one visibility interval did not share a vector")
+	}
+	if store.CentralityAtLSN(2, 25) != 1 || store.CentralityAtLSN(1, 25) >= 1 {
+		t.Fatalf("LSN 25: centrality(1)=%v centrality(2)=%v", store.CentralityAtLSN(1, 25), store.CentralityAtLSN(2, 25))
+	}
+
+	// A later commit closes the open interval [20, ∞) without touching
+	// [10, 20).
+	third := store.BeginTxn()
+	if err := third.AddEdge(3, 1, 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := third.ApplyInMemoryAtLSN(30); err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := store.PageRankAtLSN(15); again != at15 {
+		t.Fatal("closed interval was invalidated by a later commit")
+	}
+	gs.temporalMu.Lock()
+	var openEnded int
+	for _, entry := range gs.pageRankSnapshots {
+		if entry.until == 0 {
+			openEnded++
+		}
+	}
+	gs.temporalMu.Unlock()
+	if openEnded != 0 {
+		t.Fatalf("%d cached intervals still open after commit 30", openEnded)
+	}
+	if store.CentralityAtLSN(2, 35) != 1 || store.CentralityAtLSN(1, 35) <= store.CentralityAtLSN(3, 35) {
+		t.Fatalf("LSN 35: centrality(1)=%v centrality(3)=%v", store.CentralityAtLSN(1, 35), store.CentralityAtLSN(3, 35))
+	}
+
+	personalized, err := store.PersonalizedPageRank([]uint64{3}, PageRankOptions{SnapshotLSN: 35})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if personalized.Score(3) <= 0 || personalized.Score(1) <= 0 || personalized.Score(2) <= 0 {
+		t.Fatalf("personalized scores=%v, want 3 -> 1 -> 2 reached", personalized.Scores())
+	}
+	if _, err := store.PersonalizedPageRank(nil, PageRankOptions{}); err == nil {
+		t.Fatal("personalized PageRank accepted no seeds")
+	}
+}
@@ -96,7 +96,7 @@ func (g *graphStore) FlushToSegment(path string) error {
 	var totalEdges uint64
 
 	for _, nodeID := range nodeIDs {
-		edges, err := g.NeighborsWithProperties(nodeID)
+		edges, err := g.storedNeighborsWithProperties(nodeID)
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,272 @@
+package graph
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// NeighborSource returns the edges adjacent to nodeID in the requested
+// direction: 1 outbound, -1 inbound, 0 both. The SQL executor supplies its
+// own source so transaction, epoch, and snapshot visibility stay owned by the
+// caller; graphStore methods use the store's live or temporal adjacency.
+type NeighborSource func(nodeID uint64, dir int8) ([]EdgeView, error)
+
+// WeightedPathOptions configures a weighted shortest-path search.
+type WeightedPathOptions struct {
+	// Edge selects the traversable edges: direction, kind set, weight filter,
+	// and property predicate. Min and Max bound the number of hops; a zero
+	// Max means unbounded.
+	Edge EdgePlan
+	// CostProperty names the numeric edge property used as the hop cost.
+	// Empty or "weight" uses the physical edge weight. Edges whose property
+	// is missing or not a number are not traversed, matching SQL NULL
+	// comparison semantics; negative costs are an error.
+	CostProperty string
+	// SnapshotLSN evaluates the search against the edges visible at that
+	// commit LSN. Zero reads the live graph.
+	SnapshotLSN uint64
+	// Heuristic turns the search into A*. It must be admissible: it may never
+	// overestimate the remaining cost from nodeID to the target. It is only
+	// consulted when a target is given.
+	Heuristic func(nodeID uint64) float64
+}
+
+// WeightedPath is a cheapest path. Nodes lists the visited node IDs in order
+// and Edges[i] is the hop from Nodes[i] to Nodes[i+1]; Cost is the sum of the
+// hop costs.
+type WeightedPath struct {
+	Nodes []uint64
+	Edges []EdgeView
+	Cost  float64
+}
+
+// ShortestWeightedPath returns the cheapest path from start to target. It
+// returns ErrNoPath when target is unreachable under opts.
+func (g *graphStore) ShortestWeightedPath(start, target uint64, opts WeightedPathOptions) (WeightedPath, error) {
+	return ShortestWeightedPathTo(start, target, opts, g.weightedNeighborSource(opts.SnapshotLSN))
+}
+
+// ShortestWeightedPaths returns the cheapest path from start to every node
+// reachable under opts, keyed by terminal node.
+func (g *graphStore) ShortestWeightedPaths(start uint64, opts WeightedPathOptions) (map[uint64]WeightedPath, error) {
+	return ShortestWeightedPaths(start, opts, g.weightedNeighborSource(opts.SnapshotLSN))
+}
+
+func (g *graphStore) weightedNeighborSource(snapshotLSN uint64) NeighborSource {
+	outbound := g.NeighborsWithProperties
+	inbound := g.InboundNeighborsWithProperties
+	if snapshotLSN != 0 {
+		outbound = func(nodeID uint64) ([]EdgeView, error) {
+			return g.NeighborsAtLSNWithProperties(nodeID, snapshotLSN)
+		}
+		inbound = func(nodeID uint64) ([]EdgeView, error) {
+			return g.InboundNeighborsAtLSNWithProperties(nodeID, snapshotLSN)
+		}
+	}
+	return func(nodeID uint64, dir int8) ([]EdgeView, error) {
+		if dir > 0 {
+			return outbound(nodeID)
+		}
+		if dir < 0 {
+			return inbound(nodeID)
+		}
+		out, err := outbound(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		in, err := inbound(nodeID)
+		if err != nil {
+			return nil, err
+		}
+		return append(out, in...), nil
+	}
+}
+
+// ShortestWeightedPathTo runs Dijkstra, or A* when opts.Heuristic is set,
+// from start to target over the edges returned by neighbors.
+func ShortestWeightedPathTo(start, target uint64, opts WeightedPathOptions, neighbors NeighborSource) (WeightedPath, error) {
+	paths, err := searchWeightedPaths(start, target, true, opts, neighbors)
+	if err != nil {
+		return WeightedPath{}, err
+	}
+	path, ok := paths[target]
+	if !ok {
+		return WeightedPath{}, ErrNoPath
+	}
+	return path, nil
+}
+
+// ShortestWeightedPaths runs a single-source Dijkstra search and returns the
+// cheapest path to every reachable node. The start node is included, with an
+// empty path, only when opts.Edge.Min is zero. opts.Heuristic is ignored.
+func ShortestWeightedPaths(start uint64, opts WeightedPathOptions, neighbors NeighborSource) (map[uint64]WeightedPath, error) {
+	return searchWeightedPaths(start, 0, false, opts, neighbors)
+}
+
+// weightedState is one search state. Hop bounds make the hop count part of
+// the state: a pricier path with fewer hops can still be extended where the
+// cheaper one has exhausted Max, and a path below Min is not yet a result.
+type weightedState struct {
+	node uint64
+	hops int
+}
+
+type weightedStep struct {
+	prev weightedState
+	edge EdgeView
+}
+
+type weightedEntry struct {
+	state    weightedState
+	cost     float64
+	priority float64
+}
+
+type weightedQueue []weightedEntry
+
+func (q weightedQueue) Len() int { return len(q) }
+func (q weightedQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority < q[j].priority
+	}
+	if q[i].state.hops != q[j].state.hops {
+		return q[i].state.hops < q[j].state.hops
+	}
+	return q[i].state.node < q[j].state.node
+}
+func (q weightedQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *weightedQueue) Push(x interface{}) {
+	*q = append(*q, x.(weightedEntry))
+}
+func (q *weightedQueue) Pop() interface{} {
+	old := *q
+	entry := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return entry
+}
+
+// weightedFrontier is a settled (hops, cost) pair for one node at or above
+// the minimum hop count. A later state for the same node with at least as
+// many hops and at least the same cost cannot produce a better path.
+type weightedFrontier struct {
+	hops int
+	cost float64
+}
+
+func searchWeightedPaths(start, target uint64, hasTarget bool, opts WeightedPathOptions, neighbors NeighborSource) (map[uint64]WeightedPath, error) {
+	if neighbors == nil {
+		return nil, fmt.Errorf("weighted shortest path requires a neighbor source")
+	}
+	minHops, maxHops := opts.Edge.Min, opts.Edge.Max
+	if minHops < 0 {
+		minHops = 0
+	}
+	if maxHops > 0 && maxHops < minHops {
+		return nil, fmt.Errorf("weighted shortest path: max hops %d is below min hops %d", maxHops, minHops)
+	}
+	heuristic := func(uint64) float64 { return 0 }
+	if hasTarget && opts.Heuristic != nil {
+		heuristic = opts.Heuristic
+	}
+
+	origin := weightedState{node: start}
+	dist := map[weightedState]float64{origin: 0}
+	preds := make(map[weightedState]weightedStep)
+	settled := make(map[uint64][]weightedFrontier)
+	paths := make(map[uint64]WeightedPath)
+	queue := &weightedQueue{{state: origin, priority: heuristic(start)}}
+
+	for queue.Len() > 0 {
+		entry := heap.Pop(queue).(weightedEntry)
+		current := entry.state
+		if best, ok := dist[current]; !ok || entry.cost > best {
+			continue
+		}
+		if current.hops >= minHops {
+			if weightedDominated(settled[current.node], current.hops, entry.cost) {
+				continue
+			}
+			settled[current.node] = append(settled[current.node], weightedFrontier{hops: current.hops, cost: entry.cost})
+			if _, ok := paths[current.node]; !ok {
+				paths[current.node] = buildWeightedPath(current, entry.cost, preds)
+			}
+			if hasTarget && current.node == target {
+				return paths, nil
+			}
+		}
+		if maxHops > 0 && current.hops >= maxHops {
+			continue
+		}
+		views, err := neighbors(current.node, opts.Edge.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, view := range views {
+			if !opts.Edge.MatchesWithProperties(view.Edge, view.Properties) {
+				continue
+			}
+			cost, ok, err := weightedEdgeCost(current.node, view, opts.CostProperty)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			next := weightedState{node: view.Edge.Target, hops: current.hops + 1}
+			total := entry.cost + cost
+			if best, seen := dist[next]; seen && best <= total {
+				continue
+			}
+			dist[next] = total
+			preds[next] = weightedStep{prev: current, edge: view}
+			heap.Push(queue, weightedEntry{state: next, cost: total, priority: total + heuristic(next.node)})
+		}
+	}
+	return paths, nil
+}
+
+func weightedDominated(frontier []weightedFrontier, hops int, cost float64) bool {
+	for _, settled := range frontier {
+		if settled.hops <= hops && settled.cost <= cost {
+			return true
+		}
+	}
+	return false
+}
+
+// weightedEdgeCost resolves the traversal cost of one edge. ok is false when
+// the cost property is absent or not numeric.
+func weightedEdgeCost(source uint64, view EdgeView, property string) (float64, bool, error) {
+	var cost float64
+	if property == "" || strings.EqualFold(property, "weight") {
+		cost = float64(view.Edge.Weight)
+		if math.IsNaN(cost) || math.IsInf(cost, 0) {
+			return 0, false, nil
+		}
+	} else {
+		value, ok := findEdgeProperty(view.Properties, property)
+		if !ok || value.Kind != EdgePropertyNumber {
+			return 0, false, nil
+		}
+		cost = value.Number
+	}
+	if cost < 0 {
+		return 0, false, fmt.Errorf("edge %d->%d has negative cost %g", source, view.Edge.Target, cost)
+	}
+	return cost, true, nil
+}
+
+func buildWeightedPath(end weightedState, cost float64, preds map[weightedState]weightedStep) WeightedPath {
+	path := WeightedPath{Nodes: make([]uint64, end.hops+1), Edges: make([]EdgeView, end.hops), Cost: cost}
+	current := end
+	for i := end.hops; i > 0; i-- {
+		step := preds[current]
+		path.Nodes[i] = current.node
+		path.Edges[i-1] = step.edge
+		current = step.prev
+	}
+	path.Nodes[0] = current.node
+	return path
+}
@@ -0,0 +1,186 @@
+package graph
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestShortestWeightedPathPrefersCheapestRoute(t *testing.T) {
+	store, err := NewGraph(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	const road, rail uint8 = 11, 12
+	// 1 -> 4 directly is one expensive hop; 1 -> 2 -> 3 -> 4 is cheaper by
+	// weight, while the property costs favour 1 -> 5 -> 4.
+	txn := store.BeginTxn()
+	for _, edge := range []struct {
+		src, tgt uint64
+		weight   float32
+		kind     uint8
+		cost     interface{}
+	}{
+		{1, 4, 10, road, 1.0},
+		{1, 2, 1, road, 5.0},
+		{2, 3, 1, road, 5.0},
+		{3, 4, 1, road, 5.0},
+		{1, 5, 4, road, 0.5},
+		{5, 4, 4, road, 0.25},
+		{1, 4, 0.5, rail, nil},
+	} {
+		var properties map[string]interface{}
+		if edge.cost != nil {
+			properties = map[string]interface{}{"cost": edge.cost}
+		}
+		if err := txn.AddEdgeWithProperties(edge.src, edge.tgt, edge.weight, edge.kind, properties); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := txn.ApplyInMemoryAtLSN(10); err != nil {
+		t.Fatal(err)
+	}
+
+	roads := EdgePlan{Dir: 1, KindSet: NewKindSet(road), Min: 1}
+	path, err := store.ShortestWeightedPath(1, 4, WeightedPathOptions{Edge: roads})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path.Nodes, []uint64{1, 2, 3, 4}) || path.Cost != 3 || len(path.Edges) != 3 {
+		t.Fatalf("weight path=%v cost=%v, want [1 2 3 4] cost 3", path.Nodes, path.Cost)
+	}
+
+	path, err = store.ShortestWeightedPath(1, 4, WeightedPathOptions{Edge: roads, CostProperty: "cost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path.Nodes, []uint64{1, 5, 4}) || path.Cost != 0.75 {
+		t.Fatalf("property path=%v cost=%v, want [1 5 4] cost 0.75", path.Nodes, path.Cost)
+	}
+
+	// A hop bound forces the direct edge even though it is more expensive.
+	bounded := roads
+	bounded.Max = 1
+	path, err = store.ShortestWeightedPath(1, 4, WeightedPathOptions{Edge: bounded})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path.Nodes, []uint64{1, 4}) || path.Cost != 10 {
+		t.Fatalf("bounded path=%v cost=%v, want [1 4] cost 10", path.Nodes, path.Cost)
+	}
+
+	// Without a kind filter the rail edge wins.
+	path, err = store.ShortestWeightedPath(1, 4, WeightedPathOptions{Edge: EdgePlan{Dir: 1, Min: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path.Nodes, []uint64{1, 4}) || path.Cost != 0.5 || path.Edges[0].Edge.GetKind() != rail {
+		t.Fatalf("unfiltered path=%v cost=%v, want rail edge", path.Nodes, path.Cost)
+	}
+
+	// A* with an admissible heuristic returns the same path.
+	remaining := map[uint64]float64{1: 3, 2: 2, 3: 1, 4: 0, 5: 2}
+	path, err = store.ShortestWeightedPath(1, 4, WeightedPathOptions{Edge: roads, Heuristic: func(node uint64) float64 {
+		return remaining[node]
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path.Nodes, []uint64{1, 2, 3, 4}) || path.Cost != 3 {
+		t.Fatalf("A* path=%v cost=%v, want [1 2 3 4] cost 3", path.Nodes, path.Cost)
+	}
+
+	// Inbound traversal walks the same edges backwards.
+	path, err = store.ShortestWeightedPath(4, 1, WeightedPathOptions{Edge: EdgePlan{Dir: -1, KindSet: NewKindSet(road), Min: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(path.Nodes, []uint64{4, 3, 2, 1}) || path.Cost != 3 {
+		t.Fatalf("inbound path=%v cost=%v, want [4 3 2 1] cost 3", path.Nodes, path.Cost)
+	}
+
+	if _, err := store.ShortestWeightedPath(4, 1, WeightedPathOptions{Edge: roads}); !errors.Is(err, ErrNoPath) {
+		t.Fatalf("unreachable target err=%v, want ErrNoPath", err)
+	}
+
+	all, err := store.ShortestWeightedPaths(1, WeightedPathOptions{Edge: roads})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || all[2].Cost != 1 || all[3].Cost != 2 || all[4].Cost != 3 || all[5].Cost != 4 {
+		t.Fatalf("single-source costs=%v", all)
+	}
+	if _, ok := all[1]; ok {
+		t.Fatal("start node reported without a zero-hop pattern")
+	}
+}
+
+func TestShortestWeightedPathHonoursSnapshotLSN(t *testing.T) {
+	store, err := NewGraph(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	const kind uint8 = 13
+	first := store.BeginTxn()
+	if err := first.AddEdge(1, 2, 5, kind); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.AddEdge(2, 3, 5, kind); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.ApplyInMemoryAtLSN(10); err != nil {
+		t.Fatal(err)
+	}
+	second := store.BeginTxn()
+	if err := second.AddEdge(1, 3, 1, kind); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.RemoveEdge(2, 3, kind); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.ApplyInMemoryAtLSN(20); err != nil {
+		t.Fatal(err)
+	}
+
+	edge := EdgePlan{Dir: 1, KindSet: NewKindSet(kind), Min: 1}
+	live, err := store.ShortestWeightedPath(1, 3, WeightedPathOptions{Edge: edge})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(live.Nodes, []uint64{1, 3}) || live.Cost != 1 {
+		t.Fatalf("live path=%v cost=%v, want [1 3] cost 1", live.Nodes, live.Cost)
+	}
+	historical, err := store.ShortestWeightedPath(1, 3, WeightedPathOptions{Edge: edge, SnapshotLSN: 15})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(historical.Nodes, []uint64{1, 2, 3}) || historical.Cost != 10 {
+		t.Fatalf("historical path=%v cost=%v, want [1 2 3] cost 10", historical.Nodes, historical.Cost)
+	}
+	if _, err := store.ShortestWeightedPath(1, 3, WeightedPathOptions{Edge: edge, SnapshotLSN: 5}); !errors.Is(err, ErrNoPath) {
+		t.Fatalf("pre-history err=%v, want ErrNoPath", err)
+	}
+}
+
+func TestShortestWeightedPathRejectsNegativeCost(t *testing.T) {
+	store, err := NewGraph(testConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	txn := store.BeginTxn()
+	if err := txn.AddEdge(1, 2, -1, 14); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.ApplyInMemoryAtLSN(10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ShortestWeightedPath(1, 2, WeightedPathOptions{Edge: EdgePlan{Dir: 1, Min: 1}}); err == nil {
+		t.Fatal("negative edge weight was accepted")
+	}
+}
@@ -492,6 +492,7 @@ func (t *Txn) NeighborsOverlay(nodeID uint64) ([]Edge, error) {
 	var err error
 	if t.epochLSN > 0 {
 		base, err = t.store.NeighborsAtLSN(nodeID, t.epochLSN)
+		base = t.store.liveEdges(base)
 	} else {
 		base, err = t.store.Neighbors(nodeID)
 	}
@@ -537,6 +538,7 @@ func (t *Txn) NeighborsOverlayWithProperties(nodeID uint64) ([]EdgeView, error)
 	var err error
 	if t.epochLSN > 0 {
 		base, err = t.store.NeighborsAtLSNWithProperties(nodeID, t.epochLSN)
+		base = t.store.liveEdgeViews(base)
 	} else {
 		base, err = t.store.NeighborsWithProperties(nodeID)
 	}
@@ -643,6 +645,7 @@ func (t *Txn) InboundNeighborsOverlay(nodeID uint64) ([]Edge, error) {
 	var err error
 	if t.epochLSN > 0 {
 		base, err = t.store.InboundNeighborsAtLSN(nodeID, t.epochLSN)
+		base = t.store.liveEdges(base)
 	} else {
 		base, err = t.store.InboundNeighbors(nodeID)
 	}
@@ -684,6 +687,7 @@ func (t *Txn) InboundNeighborsOverlayWithProperties(nodeID uint64) ([]EdgeView,
 	var err error
 	if t.epochLSN > 0 {
 		base, err = t.store.InboundNeighborsAtLSNWithProperties(nodeID, t.epochLSN)
+		base = t.store.liveEdgeViews(base)
 	} else {
 		base, err = t.store.InboundNeighborsWithProperties(nodeID)
 	}
@@ -744,6 +748,8 @@ type Graph interface {
 
 	BFS(start uint64, maxDepth int, visit VisitAction, bitset *Bitset, frontier *FrontierBuf) error
 	BFSPattern(start uint64, edges []EdgePlan, maxDepth int, visit VisitAction, bitset *Bitset, frontier *FrontierBuf) error
+	ShortestWeightedPath(start, target uint64, opts WeightedPathOptions) (WeightedPath, error)
+	ShortestWeightedPaths(start uint64, opts WeightedPathOptions) (map[uint64]WeightedPath, error)
 	GetBitset() (*Bitset, error)
 	PutBitset(b *Bitset)
 	GetFrontierBuf() (*FrontierBuf, error)
@@ -751,6 +757,11 @@ type Graph interface {
 	Stats() GraphStats
 	GraphCentrality(nodeID uint64) float64
 	CentralityAtLSN(nodeID uint64, snapshotLSN uint64) float64
+	NodeCentrality(nodeID uint64) (float64, error)
+	NodeCentralityAtLSN(nodeID uint64, snapshotLSN uint64) (float64, error)
+	RefreshPageRank() (*PageRankVector, error)
+	PageRankAtLSN(snapshotLSN uint64) (*PageRankVector, error)
+	PersonalizedPageRank(seeds []uint64, opts PageRankOptions) (*PageRankVector, error)
 	RecordPageRankPublication(snapshotLSN uint64, duration time.Duration)
 
 	// Vertex label registry (MVP: in-memory only, not persisted).
@@ -796,6 +807,28 @@ type graphStore struct {
 	// and graph queries.
 	temporalMu    sync.Mutex
 	temporalEdges map[edgeTemporalKey]*edgeTemporalState
+	// temporalEpoch counts temporal index mutations and lastEdgeCommitLSN is
+	// the newest LSN recorded in it. pageRankSnapshots caches historical
+	// PageRank vectors; all three are protected by temporalMu except the
+	// atomic LSN.
+	temporalEpoch     uint64
+	lastEdgeCommitLSN atomic.Uint64
+	pageRankSnapshots []pageRankSnapshot
+
+	// pageRank is the published live PageRank vector; pageRankMu serializes
+	// refreshes. The maintainer goroutine, started by the first wake and
+	// stopped by Close, republishes it after graph commits.
+	pageRankMu          sync.Mutex
+	pageRank            atomic.Pointer[PageRankVector]
+	pageRankWake        chan struct{}
+	pageRankStop        chan struct{}
+	pageRankLifecycleMu sync.Mutex
+	pageRankDone        chan struct{}
+	pageRankStopped     bool
+
+	// expiringEdges is set once any stored edge carries an expires_at
+	// property; until then live reads skip expiry checks entirely.
+	expiringEdges atomic.Bool
 
 	// collectionName is the owning collection for WAL frame identity.
 	collectionName string
@@ -900,6 +933,8 @@ func NewGraph(cfg GraphConfig) (Graph, error) {
 		reverse:        revIdx,
 		manifest:       NewDBManifest(),
 		labelToNodes:   make(map[string][]uint64),
+		pageRankWake:   make(chan struct{}, 1),
+		pageRankStop:   make(chan struct{}),
 	}, nil
 }
 
@@ -1413,6 +1448,7 @@ func (g *graphStore) recordEdgeCommitLSN(lsn uint64, adds, removes []storage.Gra
 	if g.temporalEdges == nil {
 		g.temporalEdges = make(map[edgeTemporalKey]*edgeTemporalState)
 	}
+	g.noteEdgeCommitLocked(lsn)
 	for _, add := range adds {
 		key := edgeTemporalKey{Src: add.Src, Tgt: add.Tgt, Kind: add.Kind}
 		state, ok := g.temporalEdges[key]
@@ -1463,6 +1499,7 @@ func (g *graphStore) RecordEdgeAddLSN(src, tgt uint64, weight float32, kind uint
 	if g.temporalEdges == nil {
 		g.temporalEdges = make(map[edgeTemporalKey]*edgeTemporalState)
 	}
+	g.noteEdgeCommitLocked(lsn)
 	key := edgeTemporalKey{Src: src, Tgt: tgt, Kind: kind}
 	state, ok := g.temporalEdges[key]
 	if !ok {
@@ -1487,6 +1524,7 @@ func (g *graphStore) RecordEdgeRemoveLSN(src, tgt uint64, kind uint8, lsn uint64
 	if g.temporalEdges == nil {
 		g.temporalEdges = make(map[edgeTemporalKey]*edgeTemporalState)
 	}
+	g.noteEdgeCommitLocked(lsn)
 	key := edgeTemporalKey{Src: src, Tgt: tgt, Kind: kind}
 	if state, ok := g.temporalEdges[key]; ok {
 		for i := range state.Versions {
@@ -1883,22 +1921,31 @@ func (g *graphStore) applyCommittedOps(adds, removes []storage.GraphEdgeOp, node
 	}
 	if len(adds) > 0 || len(removes) > 0 || len(nodeDrops) > 0 {
 		g.metrics.mutationGeneration.Add(1)
+		g.wakePageRank()
 	}
 	return nil
 }
 
 // RecordPageRankPublication records an atomically published derived PageRank
-// vector. The computation itself is intentionally separate from graph writes;
-// controllers can use Stats to decide when to run it.
+// vector computed outside the store against the current mutation generation.
+// RefreshPageRank records its own publications; controllers can use Stats to
+// decide when to run it.
 func (g *graphStore) RecordPageRankPublication(snapshotLSN uint64, duration time.Duration) {
-	g.metrics.lastPageRankGeneration.Store(g.metrics.mutationGeneration.Load())
+	g.recordPageRankPublication(g.metrics.mutationGeneration.Load(), snapshotLSN, duration)
+}
+
+func (g *graphStore) recordPageRankPublication(generation, snapshotLSN uint64, duration time.Duration) {
+	g.metrics.lastPageRankGeneration.Store(generation)
 	g.metrics.lastPageRankLSN.Store(snapshotLSN)
 	g.metrics.pageRankDuration.Store(duration.Nanoseconds())
 	g.metrics.pageRankAvailable.Store(true)
+	g.metrics.pageRankError.Store(nil)
 }
 
 func (g *graphStore) edge(src, tgt uint64, kind uint8) (Edge, error) {
-	edges, err := g.Neighbors(src)
+	// Removal must still find an expired edge, so this reads the physical
+	// neighbor list rather than the live one.
+	edges, err := g.storedNeighbors(src)
 	if err != nil {
 		return Edge{}, err
 	}
@@ -2023,6 +2070,15 @@ func (g *graphStore) DropNodeEdges(txn *Txn, nodeID uint64) error {
 }
 
 func (g *graphStore) Neighbors(nodeID uint64) ([]Edge, error) {
+	edges, err := g.storedNeighbors(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return g.liveEdges(edges), nil
+}
+
+// storedNeighbors is Neighbors without expiry filtering.
+func (g *graphStore) storedNeighbors(nodeID uint64) ([]Edge, error) {
 	if g == nil {
 		return nil, ErrGraphClosed
 	}
@@ -2055,6 +2111,16 @@ func (g *graphStore) neighborsUnlocked(nodeID uint64) ([]Edge, error) {
 }
 
 func (g *graphStore) NeighborsWithProperties(nodeID uint64) ([]EdgeView, error) {
+	views, err := g.storedNeighborsWithProperties(nodeID)
+	if err != nil {
+		return nil, err
+	}
+	return g.liveEdgeViews(views), nil
+}
+
+// storedNeighborsWithProperties is NeighborsWithProperties without expiry
+// filtering; segment flushes and index rebuilds must see every stored edge.
+func (g *graphStore) storedNeighborsWithProperties(nodeID uint64) ([]EdgeView, error) {
 	if g == nil {
 		return nil, ErrGraphClosed
 	}
@@ -2100,7 +2166,7 @@ func (g *graphStore) InboundNeighbors(nodeID uint64) ([]Edge, error) {
 		return nil, err
 	}
 	if !g.hasUndirectedKinds() {
-		return inbound, nil
+		return g.liveEdges(inbound), nil
 	}
 	outbound, err := g.neighborsFromTable(nodeID, g.index, g.pagePools[0], g.cfg.PageShards)
 	if err != nil {
@@ -2111,7 +2177,7 @@ func (g *graphStore) InboundNeighbors(nodeID uint64) ([]Edge, error) {
 			inbound = append(inbound, edge)
 		}
 	}
-	return inbound, nil
+	return g.liveEdges(inbound), nil
 }
 
 func (g *graphStore) InboundNeighborsWithProperties(nodeID uint64) ([]EdgeView, error) {
@@ -2128,7 +2194,7 @@ func (g *graphStore) InboundNeighborsWithProperties(nodeID uint64) ([]EdgeView,
 		return nil, err
 	}
 	if !g.hasUndirectedKinds() {
-		return inbound, nil
+		return g.liveEdgeViews(inbound), nil
 	}
 	outbound, err := g.neighborsWithPropertiesFromTable(nodeID, g.index, g.pagePools[0], g.cfg.PageShards)
 	if err != nil {
@@ -2139,7 +2205,7 @@ func (g *graphStore) InboundNeighborsWithProperties(nodeID uint64) ([]EdgeView,
 			inbound = append(inbound, view)
 		}
 	}
-	return inbound, nil
+	return g.liveEdgeViews(inbound), nil
 }
 
 func (g *graphStore) Degree(nodeID uint64) (int, error) {
@@ -2152,72 +2218,6 @@ func (g *graphStore) InboundDegree(nodeID uint64) (int, error) {
 	return len(edges), err
 }
 
-// GraphCentrality returns normalized inbound degree centrality for nodeID.
-// Computed as inbound_degree(node) / max_inbound_degree in the graph.
-// Returns 0.0 for zero-degree nodes and empty graphs.
-func (g *graphStore) GraphCentrality(nodeID uint64) float64 {
-	deg, err := g.InboundDegree(nodeID)
-	if err != nil || deg == 0 {
-		return 0.0
-	}
-	stats := g.Stats()
-	if stats.EdgesAdded == 0 {
-		return 0.0
-	}
-	// Use total edges as normalization factor (approximation of max degree).
-	maxDeg := 0
-	g.ForEachEdge(func(src, tgt uint64, e Edge) bool {
-		if d, _ := g.InboundDegree(tgt); d > maxDeg {
-			maxDeg = d
-		}
-		return true
-	})
-	if maxDeg == 0 {
-		return 0.0
-	}
-	return float64(deg) / float64(maxDeg)
-}
-
-// CentralityAtLSN returns normalized inbound degree centrality for nodeID at
-// the given snapshot LSN, using only edges visible at that LSN. Scans the
-// temporal edge index to count inbound edges and find the maximum.
-func (g *graphStore) CentralityAtLSN(nodeID uint64, snapshotLSN uint64) float64 {
-	g.temporalMu.Lock()
-	defer g.temporalMu.Unlock()
-
-	if g.temporalEdges == nil {
-		return 0.0
-	}
-	inbound := 0
-	inboundByNode := make(map[uint64]int)
-	for key, state := range g.temporalEdges {
-		visible := false
-		for i := len(state.Versions) - 1; i >= 0; i-- {
-			v := state.Versions[i]
-			if v.BeginLSN <= snapshotLSN && (v.EndLSN == 0 || snapshotLSN < v.EndLSN) {
-				visible = true
-				break
-			}
-		}
-		if visible {
-			inboundByNode[key.Tgt]++
-			if key.Tgt == nodeID {
-				inbound++
-			}
-		}
-	}
-	maxInbound := 0
-	for _, c := range inboundByNode {
-		if c > maxInbound {
-			maxInbound = c
-		}
-	}
-	if maxInbound == 0 {
-		return 0.0
-	}
-	return float64(inbound) / float64(maxInbound)
-}
-
 func (g *graphStore) ForEachEdge(fn func(src, tgt uint64, edge Edge) bool) {
 	if g == nil || fn == nil {
 		return
@@ -2237,7 +2237,7 @@ func (g *graphStore) ForEachEdge(fn func(src, tgt uint64, edge Edge) bool) {
 		if err != nil {
 			return
 		}
-		for _, e := range edges {
+		for _, e := range g.liveEdges(edges) {
 			records = append(records, edgeRecord{src: nodeID, tgt: e.Target, edge: e})
 		}
 	})
@@ -2419,6 +2419,9 @@ func (g *graphStore) Close() error {
 	if g == nil {
 		return nil
 	}
+	// The PageRank maintainer reads the graph, so it finishes before the
+	// teardown below takes the locks it reads under.
+	g.stopPageRankMaintainer()
 	g.lifecycleMu.Lock()
 	defer g.lifecycleMu.Unlock()
 	// Stop new graph writers, then wait for every reader's Hyaline interval
@@ -2476,7 +2479,7 @@ func (g *graphStore) Close() error {
 
 func (g *graphStore) rebuildReverseIndex() {
 	g.index.Iterate(func(nodeID uint64) {
-		views, _ := g.NeighborsWithProperties(nodeID)
+		views, _ := g.storedNeighborsWithProperties(nodeID)
 		for _, view := range views {
 			e := view.Edge
 			rEdge := Edge{Target: nodeID, Weight: e.Weight}
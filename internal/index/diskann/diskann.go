@@ -0,0 +1,394 @@
+// Package diskann implements a DiskANN index: a Vamana graph whose
+// full-precision vectors and adjacency lists live in page-aligned blocks of
+// a node file, navigated in memory through product-quantized codes.
+//
+// Inserts and deletes follow FreshDiskANN. An insert searches the graph,
+// prunes its visited set with RobustPrune and adds pruned reverse edges.
+// A delete only tombstones the node; once enough tombstones accumulate,
+// consolidation rewires every node that pointed at one and frees the slots.
+package diskann
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+
+	indexmodel "github.com/xDarkicex/libravdb/internal/index/model"
+	"github.com/xDarkicex/libravdb/internal/quant"
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+const (
+	// DefaultR is the default maximum out-degree of a node.
+	DefaultR = 64
+	// DefaultL is the default search list size used by inserts and queries.
+	DefaultL = 100
+	// DefaultAlpha is the default RobustPrune distance ratio.
+	DefaultAlpha = 1.2
+	// DefaultTrainSize is the number of vectors the index holds before it
+	// trains its PQ codebooks and switches navigation to compressed codes.
+	DefaultTrainSize = 1024
+
+	maxTrainSample = 10000
+	// Consolidation runs once tombstones reach this share of the graph, and
+	// never for fewer than minConsolidate of them.
+	consolidateRatio = 0.1
+	minConsolidate   = 32
+)
+
+// VectorEntry is the shared caller-owned vector ingress record.
+type VectorEntry = indexmodel.VectorEntry
+
+// SearchResult is one query result. Score is the full-precision distance.
+type SearchResult struct {
+	ID      string
+	Version uint64
+	Ordinal uint32
+	Score   float32
+}
+
+// Config holds configuration for a DiskANN index.
+type Config struct {
+	// Quantization configures the in-memory PQ codes. Nil selects 8-bit
+	// codes over the largest number of subspaces up to 32 that divides
+	// the dimension.
+	Quantization *quant.QuantizationConfig
+	// Dir is the directory of the node file. Empty uses os.TempDir().
+	Dir       string
+	Dimension int
+	// R bounds the out-degree of every node.
+	R int
+	// L is the search list size. Queries use max(L, k).
+	L int
+	// Alpha is the RobustPrune distance ratio; values above 1 keep
+	// long-range edges.
+	Alpha  float32
+	Metric util.DistanceMetric
+	// TrainSize is the vector count at which PQ codebooks are trained.
+	// Zero selects DefaultTrainSize.
+	TrainSize  int
+	RandomSeed int64
+}
+
+// DefaultConfig returns a DiskANN configuration with the paper's defaults.
+func DefaultConfig(dimension int) *Config {
+	return &Config{
+		Dimension: dimension,
+		R:         DefaultR,
+		L:         DefaultL,
+		Alpha:     DefaultAlpha,
+		Metric:    util.L2Distance,
+		TrainSize: DefaultTrainSize,
+	}
+}
+
+func (c *Config) validate() error {
+	switch {
+	case c.Dimension <= 0:
+		return fmt.Errorf("dimension must be positive, got %d", c.Dimension)
+	case c.R < 2:
+		return fmt.Errorf("DiskANN R must be at least 2, got %d", c.R)
+	case c.L < 1:
+		return fmt.Errorf("DiskANN L must be positive, got %d", c.L)
+	case c.Alpha < 1:
+		return fmt.Errorf("DiskANN alpha must be at least 1, got %v", c.Alpha)
+	case c.TrainSize < 0:
+		return fmt.Errorf("DiskANN train size must be non-negative, got %d", c.TrainSize)
+	}
+	if c.Quantization != nil {
+		if c.Quantization.Type != quant.ProductQuantization {
+			return fmt.Errorf("DiskANN navigation requires product quantization, got %s", c.Quantization.Type)
+		}
+		if err := c.Quantization.Validate(); err != nil {
+			return err
+		}
+		if c.Dimension%c.Quantization.Codebooks != 0 {
+			return fmt.Errorf("dimension %d must be divisible by %d PQ codebooks", c.Dimension, c.Quantization.Codebooks)
+		}
+	}
+	return nil
+}
+
+func defaultQuantization(dimension int) *quant.QuantizationConfig {
+	codebooks := 1
+	for candidate := min(32, max(1, dimension/4)); candidate > 1; candidate-- {
+		if dimension%candidate == 0 {
+			codebooks = candidate
+			break
+		}
+	}
+	return &quant.QuantizationConfig{
+		Type:       quant.ProductQuantization,
+		Codebooks:  codebooks,
+		Bits:       8,
+		TrainRatio: 1,
+	}
+}
+
+type slotState uint8
+
+const (
+	slotFree slotState = iota
+	slotLive
+	slotDeleted
+)
+
+type slotEntry struct {
+	id      string
+	version uint64
+	ordinal uint32
+	state   slotState
+}
+
+// Index is a DiskANN index. Searches run concurrently; mutations are
+// serialized.
+type Index struct {
+	config   Config
+	distance util.DistanceFunc
+	l2       util.DistanceFunc
+	store    *nodeStore
+	pq       *quant.ProductQuantizer
+	rng      *rand.Rand
+
+	mu       sync.RWMutex
+	slots    map[string]uint32
+	entries  []slotEntry
+	free     []uint32
+	codes    []byte
+	codeSize int
+	// start is the medoid every search begins from.
+	start   uint32
+	live    int
+	deleted int
+	closed  bool
+}
+
+// NewDiskANN creates an empty DiskANN index with a fresh node file.
+func NewDiskANN(config *Config) (*Index, error) {
+	if config == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	cfg := *config
+	if cfg.TrainSize == 0 {
+		cfg.TrainSize = DefaultTrainSize
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid DiskANN config: %w", err)
+	}
+	if cfg.Quantization == nil {
+		cfg.Quantization = defaultQuantization(cfg.Dimension)
+	}
+	distance, err := util.GetDistanceFunc(cfg.Metric)
+	if err != nil {
+		return nil, err
+	}
+	l2, err := util.GetDistanceFunc(util.L2Distance)
+	if err != nil {
+		return nil, err
+	}
+	pq := quant.NewProductQuantizer()
+	if err := pq.Configure(cfg.Quantization); err != nil {
+		return nil, fmt.Errorf("configure DiskANN PQ: %w", err)
+	}
+	store, err := newNodeStore(cfg.Dir, cfg.Dimension, cfg.R)
+	if err != nil {
+		return nil, err
+	}
+	return &Index{
+		config:   cfg,
+		distance: distance,
+		l2:       l2,
+		store:    store,
+		pq:       pq,
+		rng:      rand.New(rand.NewSource(cfg.RandomSeed)),
+		slots:    make(map[string]uint32),
+	}, nil
+}
+
+// Config returns the index configuration.
+func (x *Index) Config() Config {
+	return x.config
+}
+
+// Insert adds or replaces one vector.
+func (x *Index) Insert(ctx context.Context, entry *VectorEntry) error {
+	return x.BatchInsert(ctx, []*VectorEntry{entry})
+}
+
+// BatchInsert adds or replaces vectors. A batch loaded into an empty index
+// is built with Vamana's two passes: one with alpha 1 that settles a sparse
+// graph, then one with the configured alpha that adds long-range edges.
+func (x *Index) BatchInsert(ctx context.Context, entries []*VectorEntry) error {
+	for _, entry := range entries {
+		if entry == nil || entry.ID == "" {
+			return fmt.Errorf("DiskANN entry ID cannot be empty")
+		}
+		if len(entry.Vector) != x.config.Dimension {
+			return fmt.Errorf("vector dimension %d does not match index dimension %d: %w", len(entry.Vector), x.config.Dimension, util.ErrDimension)
+		}
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return fmt.Errorf("DiskANN index is closed")
+	}
+	if x.live+x.deleted == 0 && len(entries) > 1 {
+		return x.buildLocked(ctx, entries)
+	}
+	for _, entry := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := x.insertLocked(ctx, entry, x.config.Alpha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Search finds the k nearest neighbors of query.
+func (x *Index) Search(ctx context.Context, query []float32, k int, filter interface{ Test(uint64) bool }) ([]*SearchResult, error) {
+	return x.SearchWithL(ctx, query, k, 0, filter)
+}
+
+// SearchWithL searches with a per-query list size. Values below the
+// configured L or below k are raised to them.
+func (x *Index) SearchWithL(ctx context.Context, query []float32, k, l int, filter interface{ Test(uint64) bool }) ([]*SearchResult, error) {
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d: %w", k, util.ErrInvalidK)
+	}
+	if len(query) != x.config.Dimension {
+		return nil, fmt.Errorf("query dimension %d does not match index dimension %d", len(query), x.config.Dimension)
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.closed {
+		return nil, fmt.Errorf("DiskANN index is closed")
+	}
+	if x.live == 0 {
+		return nil, fmt.Errorf("%w", util.ErrEmptyIndex)
+	}
+	visits, err := x.greedySearch(ctx, query, max(l, x.config.L, k), k, filter)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]*SearchResult, 0, min(k, len(visits)))
+	for _, visit := range visits {
+		entry := x.entries[visit.slot]
+		if entry.state != slotLive || filter != nil && !filter.Test(uint64(entry.ordinal)) {
+			continue
+		}
+		results = append(results, &SearchResult{ID: entry.id, Version: entry.version, Ordinal: entry.ordinal, Score: visit.distance})
+	}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score < results[j].Score })
+	if len(results) > k {
+		results = results[:k]
+	}
+	return results, nil
+}
+
+// Delete tombstones id. Searches skip it immediately; its edges are
+// repaired by the next consolidation.
+func (x *Index) Delete(ctx context.Context, id string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return fmt.Errorf("DiskANN index is closed")
+	}
+	if err := x.deleteLocked(id); err != nil {
+		return err
+	}
+	if x.live == 0 {
+		return x.resetLocked()
+	}
+	if x.deleted >= max(minConsolidate, int(consolidateRatio*float64(x.live+x.deleted))) {
+		return x.consolidateLocked(ctx)
+	}
+	return nil
+}
+
+// Consolidate repairs the edges around every tombstoned node and frees
+// their slots for reuse.
+func (x *Index) Consolidate(ctx context.Context) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return fmt.Errorf("DiskANN index is closed")
+	}
+	if x.deleted == 0 {
+		return nil
+	}
+	return x.consolidateLocked(ctx)
+}
+
+func (x *Index) deleteLocked(id string) error {
+	slot, ok := x.slots[id]
+	if !ok {
+		return fmt.Errorf("node with ID '%s': %w", id, util.ErrNotFound)
+	}
+	delete(x.slots, id)
+	x.entries[slot].state = slotDeleted
+	x.live--
+	x.deleted++
+	return nil
+}
+
+// resetLocked empties the graph once its last live node is deleted. The
+// trained codebooks are kept.
+func (x *Index) resetLocked() error {
+	x.entries = nil
+	x.free = nil
+	x.codes = x.codes[:0]
+	x.deleted = 0
+	x.start = 0
+	return x.store.truncate()
+}
+
+// Size returns the number of live vectors.
+func (x *Index) Size() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.live
+}
+
+// MemoryUsage estimates the resident bytes: PQ codes, codebooks and the
+// slot table. Vectors and adjacency stay in the node file.
+func (x *Index) MemoryUsage() int64 {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	usage := int64(cap(x.codes)) + x.pq.MemoryUsage() + int64(cap(x.entries))*32 + int64(cap(x.free))*4
+	for id := range x.slots {
+		usage += int64(len(id)) + 24
+	}
+	return usage
+}
+
+// DiskUsage returns the bytes of the node file in use.
+func (x *Index) DiskUsage() int64 {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.store.regionBytes(len(x.entries))
+}
+
+// IsTrained reports whether navigation uses PQ codes yet.
+func (x *Index) IsTrained() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.codeSize > 0
+}
+
+// Close releases the node file.
+func (x *Index) Close() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return nil
+	}
+	x.closed = true
+	x.slots = nil
+	x.entries = nil
+	x.codes = nil
+	return x.store.close()
+}
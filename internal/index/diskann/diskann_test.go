@@ -0,0 +1,542 @@
+package diskann
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+func testConfig(t *testing.T, dimension int) *Config {
+	t.Helper()
+	config := DefaultConfig(dimension)
+	config.Dir = t.TempDir()
+	config.R = 24
+	config.L = 64
+	config.TrainSize = 256
+	config.RandomSeed = 7
+	return config
+}
+
+func newTestIndex(t *testing.T, config *Config) *Index {
+	t.Helper()
+	index, err := NewDiskANN(config)
+	if err != nil {
+		t.Fatalf("NewDiskANN: %v", err)
+	}
+	t.Cleanup(func() { index.Close() })
+	return index
+}
+
+// testBasis spans the low-dimensional subspace testEntries draws from.
+// Embeddings have a low intrinsic dimension; isotropic noise in the full
+// space would not exercise the graph the way real data does.
+func testBasis(rng *rand.Rand, dimension int) [][]float32 {
+	basis := make([][]float32, 8)
+	for i := range basis {
+		basis[i] = make([]float32, dimension)
+		for j := range basis[i] {
+			basis[i][j] = float32(rng.NormFloat64())
+		}
+	}
+	return basis
+}
+
+func testEntries(rng *rand.Rand, basis [][]float32, count, offset int) []*VectorEntry {
+	entries := make([]*VectorEntry, count)
+	for i := range entries {
+		vector := make([]float32, len(basis[0]))
+		for _, direction := range basis {
+			weight := rng.Float32()*2 - 1
+			for j := range vector {
+				vector[j] += weight * direction[j]
+			}
+		}
+		for j := range vector {
+			vector[j] += 0.05 * float32(rng.NormFloat64())
+		}
+		entries[i] = &VectorEntry{
+			ID:      fmt.Sprintf("v%d", offset+i),
+			Vector:  vector,
+			Ordinal: uint32(offset + i),
+			Version: 1,
+		}
+	}
+	return entries
+}
+
+func bruteForce(entries map[string]*VectorEntry, query []float32, k int) []string {
+	type scored struct {
+		id       string
+		distance float32
+	}
+	all := make([]scored, 0, len(entries))
+	for id, entry := range entries {
+		all = append(all, scored{id, util.L2Distance_func(query, entry.Vector)})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].distance < all[j].distance })
+	ids := make([]string, 0, k)
+	for i := 0; i < k && i < len(all); i++ {
+		ids = append(ids, all[i].id)
+	}
+	return ids
+}
+
+func recallAt(t *testing.T, index *Index, live map[string]*VectorEntry, queries []*VectorEntry, k int) float64 {
+	t.Helper()
+	hits := 0
+	for _, query := range queries {
+		results, err := index.Search(context.Background(), query.Vector, k, nil)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		want := make(map[string]bool, k)
+		for _, id := range bruteForce(live, query.Vector, k) {
+			want[id] = true
+		}
+		for _, result := range results {
+			if want[result.ID] {
+				hits++
+			}
+		}
+	}
+	return float64(hits) / float64(len(queries)*k)
+}
+
+func TestNewDiskANNValidatesConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+	}{
+		{"zero dimension", func(c *Config) { c.Dimension = 0 }},
+		{"degree below two", func(c *Config) { c.R = 1 }},
+		{"zero list size", func(c *Config) { c.L = 0 }},
+		{"alpha below one", func(c *Config) { c.Alpha = 0.9 }},
+		{"negative train size", func(c *Config) { c.TrainSize = -1 }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := testConfig(t, 16)
+			tt.mutate(config)
+			if index, err := NewDiskANN(config); err == nil {
+				index.Close()
+				t.Fatal("expected an error")
+			}
+		})
+	}
+	if _, err := NewDiskANN(nil); err == nil {
+		t.Fatal("expected an error for a nil config")
+	}
+}
+
+func TestDiskANNRecall(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(1))
+	index := newTestIndex(t, testConfig(t, 32))
+	basis := testBasis(rng, 32)
+
+	entries := testEntries(rng, basis, 2000, 0)
+	if err := index.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	if !index.IsTrained() {
+		t.Fatal("index should train PQ once TrainSize vectors are present")
+	}
+	live := make(map[string]*VectorEntry, len(entries))
+	for _, entry := range entries {
+		live[entry.ID] = entry
+	}
+	queries := testEntries(rng, basis, 50, 1_000_000)
+	if recall := recallAt(t, index, live, queries, 10); recall < 0.9 {
+		t.Fatalf("recall@10 after build = %.3f, want >= 0.9", recall)
+	}
+
+	// Incremental FreshDiskANN inserts on top of the built graph.
+	for _, entry := range testEntries(rng, basis, 500, len(entries)) {
+		if err := index.Insert(ctx, entry); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+		live[entry.ID] = entry
+	}
+	if index.Size() != len(live) {
+		t.Fatalf("Size() = %d, want %d", index.Size(), len(live))
+	}
+	if recall := recallAt(t, index, live, queries, 10); recall < 0.9 {
+		t.Fatalf("recall@10 after inserts = %.3f, want >= 0.9", recall)
+	}
+
+	results, err := index.Search(ctx, entries[0].Vector, 1, nil)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != entries[0].ID || results[0].Score != 0 || results[0].Ordinal != entries[0].Ordinal {
+		t.Fatalf("self query returned %+v", results)
+	}
+}
+
+func TestDiskANNDeleteAndConsolidate(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(2))
+	index := newTestIndex(t, testConfig(t, 16))
+	basis := testBasis(rng, 16)
+
+	entries := testEntries(rng, basis, 600, 0)
+	if err := index.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	diskUsage := index.DiskUsage()
+
+	live := make(map[string]*VectorEntry, len(entries))
+	for _, entry := range entries {
+		live[entry.ID] = entry
+	}
+	for _, entry := range entries[:250] {
+		if err := index.Delete(ctx, entry.ID); err != nil {
+			t.Fatalf("Delete(%s): %v", entry.ID, err)
+		}
+		delete(live, entry.ID)
+	}
+	if err := index.Consolidate(ctx); err != nil {
+		t.Fatalf("Consolidate: %v", err)
+	}
+	if index.deleted != 0 {
+		t.Fatalf("%d tombstones remain after Consolidate", index.deleted)
+	}
+	if err := index.Delete(ctx, entries[0].ID); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("Delete of a deleted ID = %v, want ErrNotFound", err)
+	}
+
+	queries := make([]*VectorEntry, 0, 40)
+	for _, entry := range entries[:40] {
+		queries = append(queries, entry)
+	}
+	for _, query := range queries {
+		results, err := index.Search(ctx, query.Vector, 10, nil)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		for _, result := range results {
+			if live[result.ID] == nil {
+				t.Fatalf("deleted ID %s returned", result.ID)
+			}
+		}
+	}
+	if recall := recallAt(t, index, live, queries, 10); recall < 0.9 {
+		t.Fatalf("recall@10 after deletes = %.3f, want >= 0.9", recall)
+	}
+
+	// Freed slots are reused before the node file grows.
+	if err := index.BatchInsert(ctx, testEntries(rng, basis, 250, len(entries))); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	if got := index.DiskUsage(); got != diskUsage {
+		t.Fatalf("DiskUsage() = %d after reusing slots, want %d", got, diskUsage)
+	}
+
+	for id := range index.slots {
+		if err := index.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete(%s): %v", id, err)
+		}
+	}
+	if _, err := index.Search(ctx, entries[0].Vector, 1, nil); !errors.Is(err, util.ErrEmptyIndex) {
+		t.Fatalf("Search on emptied index = %v, want ErrEmptyIndex", err)
+	}
+	if err := index.Insert(ctx, entries[0]); err != nil {
+		t.Fatalf("Insert after emptying: %v", err)
+	}
+	if results, err := index.Search(ctx, entries[0].Vector, 1, nil); err != nil || len(results) != 1 {
+		t.Fatalf("Search after emptying = %v, %v", results, err)
+	}
+}
+
+func TestDiskANNReplaceAndFilter(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(3))
+	index := newTestIndex(t, testConfig(t, 8))
+
+	entries := testEntries(rng, testBasis(rng, 8), 100, 0)
+	if err := index.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	moved := &VectorEntry{ID: entries[0].ID, Vector: entries[1].Vector, Ordinal: 500, Version: 2}
+	if err := index.Insert(ctx, moved); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	if index.Size() != len(entries) {
+		t.Fatalf("Size() = %d after replace, want %d", index.Size(), len(entries))
+	}
+	results, err := index.Search(ctx, entries[1].Vector, 2, nil)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	for _, result := range results {
+		if result.ID == moved.ID && (result.Version != 2 || result.Ordinal != 500) {
+			t.Fatalf("replaced entry returned stale %+v", result)
+		}
+	}
+
+	odd := ordinalFilter(func(ordinal uint64) bool { return ordinal%2 == 1 })
+	results, err = index.Search(ctx, entries[2].Vector, 10, odd)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("filtered search returned nothing")
+	}
+	for _, result := range results {
+		if result.Ordinal%2 != 1 {
+			t.Fatalf("filter admitted ordinal %d", result.Ordinal)
+		}
+	}
+}
+
+func TestDiskANNSelectiveFilterDuringTraversal(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(13))
+	config := testConfig(t, 16)
+	config.L = 32
+	index := newTestIndex(t, config)
+
+	basis := testBasis(rng, 16)
+	entries := testEntries(rng, basis, 2000, 0)
+	if err := index.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	// One ordinal in fifty qualifies, uncorrelated with the vectors.
+	selective := ordinalFilter(func(ordinal uint64) bool { return ordinal%50 == 7 })
+	qualifying := make(map[string]*VectorEntry)
+	for _, entry := range entries {
+		if selective.Test(uint64(entry.Ordinal)) {
+			qualifying[entry.ID] = entry
+		}
+	}
+
+	const k = 10
+	hits, total := 0, 0
+	for _, query := range testEntries(rng, basis, 20, 5000) {
+		results, err := index.Search(ctx, query.Vector, k, selective)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if len(results) != k {
+			t.Fatalf("selective filter returned %d results, want %d", len(results), k)
+		}
+		want := make(map[string]bool, k)
+		for _, id := range bruteForce(qualifying, query.Vector, k) {
+			want[id] = true
+		}
+		for _, result := range results {
+			if !selective.Test(uint64(result.Ordinal)) {
+				t.Fatalf("filter admitted ordinal %d", result.Ordinal)
+			}
+			if want[result.ID] {
+				hits++
+			}
+		}
+		total += k
+	}
+	if recall := float64(hits) / float64(total); recall < 0.9 {
+		t.Fatalf("filtered recall@%d = %.3f, want at least 0.9", k, recall)
+	}
+}
+
+type ordinalFilter func(uint64) bool
+
+func (f ordinalFilter) Test(ordinal uint64) bool { return f(ordinal) }
+
+func TestDiskANNSnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(4))
+	source := newTestIndex(t, testConfig(t, 16))
+
+	entries := testEntries(rng, testBasis(rng, 16), 400, 0)
+	if err := source.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	for _, entry := range entries[:20] {
+		if err := source.Delete(ctx, entry.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	data, err := source.SerializeToBytes()
+	if err != nil {
+		t.Fatalf("SerializeToBytes: %v", err)
+	}
+
+	restored := newTestIndex(t, DefaultConfig(4))
+	if err := restored.DeserializeFromBytes(ctx, data); err != nil {
+		t.Fatalf("DeserializeFromBytes: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "index.diskann")
+	if err := source.SaveToDisk(ctx, path); err != nil {
+		t.Fatalf("SaveToDisk: %v", err)
+	}
+	loaded := newTestIndex(t, DefaultConfig(4))
+	if err := loaded.LoadFromDisk(ctx, path); err != nil {
+		t.Fatalf("LoadFromDisk: %v", err)
+	}
+
+	for _, index := range []*Index{restored, loaded} {
+		if index.Size() != source.Size() || index.IsTrained() != source.IsTrained() || index.Config().Dimension != 16 {
+			t.Fatalf("restored index size %d trained %v, want %d %v", index.Size(), index.IsTrained(), source.Size(), source.IsTrained())
+		}
+		for _, query := range entries[10:30] {
+			want, err := source.Search(ctx, query.Vector, 5, nil)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			got, err := index.Search(ctx, query.Vector, 5, nil)
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("restored search returned %d results, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if *got[i] != *want[i] {
+					t.Fatalf("result %d = %+v, want %+v", i, got[i], want[i])
+				}
+			}
+		}
+	}
+
+	data[len(data)-1] ^= 0xFF
+	if err := restored.DeserializeFromBytes(ctx, data); err == nil {
+		t.Fatal("expected a checksum error for a corrupted snapshot")
+	}
+	if restored.Size() != source.Size() {
+		t.Fatal("failed restore must leave the index unchanged")
+	}
+}
+
+// extentRegion is a region held outside the index, as a database extent.
+type extentRegion struct {
+	*bytes.Reader
+	closed bool
+}
+
+func (r *extentRegion) Close() error {
+	r.closed = true
+	return nil
+}
+
+// rebasingBuffer records the base SerializeExtent offers it.
+type rebasingBuffer struct {
+	bytes.Buffer
+	base      io.ReaderAt
+	unchanged bool
+}
+
+func (b *rebasingBuffer) RebaseExtent(base io.ReaderAt, unchanged bool) bool {
+	b.base, b.unchanged = base, unchanged
+	return false
+}
+
+func assertSameSearch(t *testing.T, want, got *Index, queries []*VectorEntry) {
+	t.Helper()
+	ctx := context.Background()
+	if got.Size() != want.Size() {
+		t.Fatalf("index size %d, want %d", got.Size(), want.Size())
+	}
+	for _, query := range queries {
+		wantResults, err := want.Search(ctx, query.Vector, 5, nil)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		gotResults, err := got.Search(ctx, query.Vector, 5, nil)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		if len(gotResults) != len(wantResults) {
+			t.Fatalf("search returned %d results, want %d", len(gotResults), len(wantResults))
+		}
+		for i := range wantResults {
+			if *gotResults[i] != *wantResults[i] {
+				t.Fatalf("result %d = %+v, want %+v", i, gotResults[i], wantResults[i])
+			}
+		}
+	}
+}
+
+func TestDiskANNExtentReadsBaseUntilRewritten(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(5))
+	basis := testBasis(rng, 16)
+	source := newTestIndex(t, testConfig(t, 16))
+	entries := testEntries(rng, basis, 400, 0)
+	if err := source.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	var region bytes.Buffer
+	image, err := source.SerializeExtent(&region)
+	if err != nil {
+		t.Fatalf("SerializeExtent: %v", err)
+	}
+	if int64(region.Len()) != source.DiskUsage() {
+		t.Fatalf("region holds %d bytes, want %d", region.Len(), source.DiskUsage())
+	}
+
+	base := &extentRegion{Reader: bytes.NewReader(region.Bytes())}
+	restored := newTestIndex(t, testConfig(t, 4))
+	if err := restored.DeserializeExtent(ctx, image, base, base.Size()); err != nil {
+		t.Fatalf("DeserializeExtent: %v", err)
+	}
+	assertSameSearch(t, source, restored, entries[:20])
+
+	// Untouched, the index offers its base as unchanged and streams the
+	// same region.
+	var again rebasingBuffer
+	if _, err := restored.SerializeExtent(&again); err != nil {
+		t.Fatalf("SerializeExtent: %v", err)
+	}
+	if again.base != io.ReaderAt(base) || !again.unchanged || !bytes.Equal(again.Bytes(), region.Bytes()) {
+		t.Fatalf("unmodified index offered base %v unchanged %v", again.base != nil, again.unchanged)
+	}
+
+	// Rewritten nodes come from the node file, the rest from the base.
+	more := testEntries(rng, basis, 100, 400)
+	if err := restored.BatchInsert(ctx, more); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	for _, entry := range entries[:30] {
+		if err := restored.Delete(ctx, entry.ID); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	var merged rebasingBuffer
+	mergedImage, err := restored.SerializeExtent(&merged)
+	if err != nil {
+		t.Fatalf("SerializeExtent: %v", err)
+	}
+	if merged.unchanged {
+		t.Fatal("modified index reported its base unchanged")
+	}
+	reloaded := newTestIndex(t, testConfig(t, 4))
+	if err := reloaded.DeserializeExtent(ctx, mergedImage, bytes.NewReader(merged.Bytes()), int64(merged.Len())); err != nil {
+		t.Fatalf("DeserializeExtent: %v", err)
+	}
+	assertSameSearch(t, restored, reloaded, append(entries[20:40], more[:20]...))
+
+	if err := restored.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if !base.closed {
+		t.Fatal("closing the index did not release its base region")
+	}
+
+	image[len(image)-1] ^= 0xFF
+	corrupt := &extentRegion{Reader: bytes.NewReader(region.Bytes())}
+	if err := reloaded.DeserializeExtent(ctx, image, corrupt, corrupt.Size()); err == nil {
+		t.Fatal("expected a checksum error for a corrupted extent image")
+	}
+	if !corrupt.closed {
+		t.Fatal("failed restore did not release the region")
+	}
+}
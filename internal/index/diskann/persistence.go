@@ -0,0 +1,509 @@
+package diskann
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/xDarkicex/libravdb/internal/quant"
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+// FormatVersion is the version of the DiskANN snapshot format.
+const FormatVersion uint32 = 1
+
+// MagicBytes opens every DiskANN snapshot.
+var MagicBytes = []byte("LIBRADKN")
+
+// A snapshot is
+//
+//	magic [8] | version uint32 | crc32 uint32 | nodeOffset uint64
+//	metadata | zero padding | node region
+//
+// The CRC covers every byte after the header. nodeOffset is page aligned,
+// so the node region is a verbatim copy of the live node file prefix.
+const snapshotHeaderSize = 24
+
+// ExtentMagicBytes opens every DiskANN extent image.
+var ExtentMagicBytes = []byte("LIBRADKX")
+
+// An extent image is the snapshot with its node region held elsewhere:
+//
+//	magic [8] | version uint32 | crc32 uint32 | regionBytes uint64
+//	metadata
+//
+// The CRC covers regionBytes and the metadata. The region is the verbatim
+// node file prefix; its integrity is up to whoever stores it.
+const extentHeaderSize = 24
+
+// regionRebaser is implemented by a region writer that can take the place
+// of the region an index was restored from. See SerializeExtent.
+type regionRebaser interface {
+	RebaseExtent(base io.ReaderAt, unchanged bool) bool
+}
+
+// SerializeToBytes returns the snapshot in memory. The node region is part
+// of the snapshot, so this is only suited to small indexes; SerializeExtent
+// streams the region instead.
+func (x *Index) SerializeToBytes() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := x.writeSnapshot(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DeserializeFromBytes replaces the index contents with a snapshot.
+func (x *Index) DeserializeFromBytes(ctx context.Context, data []byte) error {
+	return x.loadSnapshot(ctx, bytes.NewReader(data), int64(len(data)))
+}
+
+// SerializeExtent streams the node region to region and returns the extent
+// image describing it. If the index reads from a base region given to
+// DeserializeExtent and region implements RebaseExtent, the base is offered
+// to it first, together with whether the node region still equals it; when
+// RebaseExtent returns true the writer reuses the base and no region bytes
+// are written.
+//
+// The index lock is held only while the image and the region bounds are
+// captured, not while the region streams, so the caller must keep writers
+// out of the index until SerializeExtent returns.
+func (x *Index) SerializeExtent(region io.Writer) ([]byte, error) {
+	x.mu.RLock()
+	if x.closed {
+		x.mu.RUnlock()
+		return nil, fmt.Errorf("DiskANN index is closed")
+	}
+	meta, err := x.encodeMetadataLocked()
+	if err != nil {
+		x.mu.RUnlock()
+		return nil, err
+	}
+	slots := len(x.entries)
+	store := x.store.regionView()
+	unchanged := x.store.unchanged(slots)
+	x.mu.RUnlock()
+
+	image := make([]byte, extentHeaderSize, extentHeaderSize+len(meta))
+	copy(image[0:8], ExtentMagicBytes)
+	binary.LittleEndian.PutUint32(image[8:12], FormatVersion)
+	binary.LittleEndian.PutUint64(image[16:24], uint64(store.regionBytes(slots)))
+	image = append(image, meta...)
+	binary.LittleEndian.PutUint32(image[12:16], crc32.ChecksumIEEE(image[16:]))
+
+	if rebaser, ok := region.(regionRebaser); ok && store.base != nil {
+		if rebaser.RebaseExtent(store.base, unchanged) {
+			return image, nil
+		}
+	}
+	if err := store.writeRegion(region, slots); err != nil {
+		return nil, fmt.Errorf("write DiskANN node region: %w", err)
+	}
+	return image, nil
+}
+
+// DeserializeExtent replaces the index contents with an extent image whose
+// node region is read from region, which holds size bytes. The region is
+// not copied: nodes are read from it until they are rewritten, so it must
+// stay readable until the index is closed or reset. The index closes region
+// then if it implements io.Closer, and also when DeserializeExtent fails.
+func (x *Index) DeserializeExtent(ctx context.Context, image []byte, region io.ReaderAt, size int64) error {
+	loaded, err := x.decodeExtentImage(ctx, image, size)
+	if err != nil {
+		if closer, ok := region.(io.Closer); ok {
+			closer.Close()
+		}
+		return err
+	}
+	loaded.store.setBase(region, len(loaded.entries))
+	defer func() {
+		if loaded.store != nil {
+			loaded.store.close()
+		}
+	}()
+	return x.publish(loaded)
+}
+
+// decodeExtentImage builds an unpublished index from an extent image whose
+// node region holds size bytes.
+func (x *Index) decodeExtentImage(ctx context.Context, image []byte, size int64) (*Index, error) {
+	if len(image) < extentHeaderSize {
+		return nil, fmt.Errorf("invalid DiskANN extent image: too short")
+	}
+	if !bytes.Equal(image[0:8], ExtentMagicBytes) {
+		return nil, fmt.Errorf("invalid DiskANN extent image: incorrect magic bytes")
+	}
+	if version := binary.LittleEndian.Uint32(image[8:12]); version != FormatVersion {
+		return nil, fmt.Errorf("unsupported DiskANN extent image version %d", version)
+	}
+	if crc32.ChecksumIEEE(image[16:]) != binary.LittleEndian.Uint32(image[12:16]) {
+		return nil, fmt.Errorf("DiskANN extent image checksum mismatch")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	loaded, err := x.decodeMetadata(image[extentHeaderSize:])
+	if err != nil {
+		return nil, err
+	}
+	regionBytes := binary.LittleEndian.Uint64(image[16:24])
+	if want := loaded.store.regionBytes(len(loaded.entries)); regionBytes != uint64(want) || want > size {
+		loaded.store.close()
+		return nil, fmt.Errorf("invalid DiskANN extent image: node region of %d bytes, want %d of %d", regionBytes, want, size)
+	}
+	return loaded, nil
+}
+
+// SaveToDisk streams the snapshot to path through a temporary file.
+func (x *Index) SaveToDisk(ctx context.Context, path string) error {
+	tempPath := path + ".tmp"
+	file, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	err = x.writeSnapshot(file)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, path)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to save DiskANN index: %w", err)
+	}
+	return nil
+}
+
+// LoadFromDisk replaces the index contents with the snapshot at path. The
+// node region is copied into a fresh node file; the snapshot itself is
+// never held in memory.
+func (x *Index) LoadFromDisk(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	return x.loadSnapshot(ctx, file, info.Size())
+}
+
+func (x *Index) writeSnapshot(w io.Writer) error {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.closed {
+		return fmt.Errorf("DiskANN index is closed")
+	}
+	meta, err := x.encodeMetadataLocked()
+	if err != nil {
+		return err
+	}
+	nodeOffset := alignPage(int64(snapshotHeaderSize + len(meta)))
+	padding := make([]byte, nodeOffset-int64(snapshotHeaderSize+len(meta)))
+
+	// The node region can exceed memory, so it is read twice: once for the
+	// checksum and once for the copy.
+	crc := crc32.NewIEEE()
+	crc.Write(meta)
+	crc.Write(padding)
+	if err := x.store.writeRegion(crc, len(x.entries)); err != nil {
+		return fmt.Errorf("checksum DiskANN node file: %w", err)
+	}
+
+	header := make([]byte, snapshotHeaderSize)
+	copy(header[0:8], MagicBytes)
+	binary.LittleEndian.PutUint32(header[8:12], FormatVersion)
+	binary.LittleEndian.PutUint32(header[12:16], crc.Sum32())
+	binary.LittleEndian.PutUint64(header[16:24], uint64(nodeOffset))
+	for _, part := range [][]byte{header, meta, padding} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	if err := x.store.writeRegion(w, len(x.entries)); err != nil {
+		return fmt.Errorf("copy DiskANN node file: %w", err)
+	}
+	return nil
+}
+
+func (x *Index) encodeMetadataLocked() ([]byte, error) {
+	enc := util.AcquireBinaryEncoder(256 + len(x.entries)*48 + len(x.codes))
+	defer util.ReleaseBinaryEncoder(enc)
+
+	q := x.config.Quantization
+	enc.WriteUint32(uint32(x.config.Dimension))
+	enc.WriteUint32(uint32(x.config.Metric))
+	enc.WriteUint32(uint32(x.config.R))
+	enc.WriteUint32(uint32(x.config.L))
+	enc.WriteFloat32(x.config.Alpha)
+	enc.WriteUint32(uint32(x.config.TrainSize))
+	enc.WriteUint32(uint32(q.Codebooks))
+	enc.WriteUint32(uint32(q.Bits))
+	enc.WriteFloat64(q.TrainRatio)
+
+	enc.WriteUint32(uint32(len(x.entries)))
+	enc.WriteUint32(x.start)
+	for _, entry := range x.entries {
+		enc.WriteByte(byte(entry.state))
+		enc.WriteString(entry.id)
+		enc.WriteUint64(entry.version)
+		enc.WriteUint32(entry.ordinal)
+	}
+
+	enc.WriteBool(x.codeSize > 0)
+	if x.codeSize > 0 {
+		state, err := x.pq.SerializeState()
+		if err != nil {
+			return nil, fmt.Errorf("serialize DiskANN PQ: %w", err)
+		}
+		writeBlob(enc, state)
+		writeBlob(enc, x.codes)
+	}
+	return enc.DetachBytes(), nil
+}
+
+func (x *Index) loadSnapshot(ctx context.Context, r io.ReaderAt, size int64) error {
+	header := make([]byte, snapshotHeaderSize)
+	if size < snapshotHeaderSize {
+		return fmt.Errorf("invalid DiskANN snapshot: file too short")
+	}
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return fmt.Errorf("read DiskANN snapshot header: %w", err)
+	}
+	if !bytes.Equal(header[0:8], MagicBytes) {
+		return fmt.Errorf("invalid DiskANN snapshot: incorrect magic bytes")
+	}
+	if version := binary.LittleEndian.Uint32(header[8:12]); version != FormatVersion {
+		return fmt.Errorf("unsupported DiskANN snapshot version %d", version)
+	}
+	nodeOffset := int64(binary.LittleEndian.Uint64(header[16:24]))
+	if nodeOffset < snapshotHeaderSize || nodeOffset > size || nodeOffset%PageSize != 0 {
+		return fmt.Errorf("invalid DiskANN snapshot: node offset %d", nodeOffset)
+	}
+	crc := crc32.NewIEEE()
+	if _, err := io.Copy(crc, io.NewSectionReader(r, snapshotHeaderSize, size-snapshotHeaderSize)); err != nil {
+		return fmt.Errorf("checksum DiskANN snapshot: %w", err)
+	}
+	if crc.Sum32() != binary.LittleEndian.Uint32(header[12:16]) {
+		return fmt.Errorf("DiskANN snapshot checksum mismatch")
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	meta := make([]byte, nodeOffset-snapshotHeaderSize)
+	if _, err := r.ReadAt(meta, snapshotHeaderSize); err != nil {
+		return fmt.Errorf("read DiskANN snapshot metadata: %w", err)
+	}
+	loaded, err := x.decodeMetadata(meta)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if loaded.store != nil {
+			loaded.store.close()
+		}
+	}()
+	region := loaded.store.regionBytes(len(loaded.entries))
+	if nodeOffset+region > size {
+		return fmt.Errorf("invalid DiskANN snapshot: node region truncated")
+	}
+	if _, err := io.Copy(io.NewOffsetWriter(loaded.store.file, 0), io.NewSectionReader(r, nodeOffset, region)); err != nil {
+		return fmt.Errorf("restore DiskANN node file: %w", err)
+	}
+
+	return x.publish(loaded)
+}
+
+// publish swaps the contents of loaded into x and leaves x's previous node
+// store in loaded for the caller to close.
+func (x *Index) publish(loaded *Index) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.closed {
+		return fmt.Errorf("DiskANN index is closed")
+	}
+	previous := x.store
+	x.config = loaded.config
+	x.distance = loaded.distance
+	x.store = loaded.store
+	x.pq = loaded.pq
+	x.slots = loaded.slots
+	x.entries = loaded.entries
+	x.free = loaded.free
+	x.codes = loaded.codes
+	x.codeSize = loaded.codeSize
+	x.start = loaded.start
+	x.live = loaded.live
+	x.deleted = loaded.deleted
+	loaded.store = previous
+	return nil
+}
+
+// decodeMetadata builds an unpublished index from snapshot metadata with
+// an empty node file in the current node directory.
+func (x *Index) decodeMetadata(meta []byte) (*Index, error) {
+	dec := &util.BinaryDecoder{Data: meta}
+	var u [7]uint32
+	var err error
+	for i := 0; i < 4; i++ {
+		if u[i], err = dec.ReadUint32(); err != nil {
+			return nil, fmt.Errorf("read DiskANN config: %w", err)
+		}
+	}
+	alpha, err := dec.ReadFloat32()
+	if err != nil {
+		return nil, fmt.Errorf("read DiskANN alpha: %w", err)
+	}
+	for i := 4; i < 7; i++ {
+		if u[i], err = dec.ReadUint32(); err != nil {
+			return nil, fmt.Errorf("read DiskANN config: %w", err)
+		}
+	}
+	trainRatio, err := dec.ReadFloat64()
+	if err != nil {
+		return nil, fmt.Errorf("read DiskANN PQ train ratio: %w", err)
+	}
+	cfg := x.config
+	cfg.Dimension = int(u[0])
+	cfg.Metric = util.DistanceMetric(u[1])
+	cfg.R = int(u[2])
+	cfg.L = int(u[3])
+	cfg.Alpha = alpha
+	cfg.TrainSize = int(u[4])
+	cfg.Quantization = &quant.QuantizationConfig{
+		Type:       quant.ProductQuantization,
+		Codebooks:  int(u[5]),
+		Bits:       int(u[6]),
+		TrainRatio: trainRatio,
+	}
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid DiskANN snapshot config: %w", err)
+	}
+	// TrainSize zero is normalized away on construction; a snapshot that
+	// carries it was not written by this package.
+	if cfg.TrainSize == 0 {
+		return nil, fmt.Errorf("invalid DiskANN snapshot config: train size is zero")
+	}
+
+	count, err := dec.ReadUint32()
+	if err != nil {
+		return nil, fmt.Errorf("read DiskANN slot count: %w", err)
+	}
+	start, err := dec.ReadUint32()
+	if err != nil {
+		return nil, fmt.Errorf("read DiskANN start: %w", err)
+	}
+	// Every slot takes at least 17 metadata bytes, which bounds count
+	// before anything is allocated for it.
+	if int(count) > (len(meta)-dec.Off)/17 || count > 0 && start >= count {
+		return nil, fmt.Errorf("invalid DiskANN snapshot: %d slots, start %d", count, start)
+	}
+	loaded := &Index{
+		config:  cfg,
+		slots:   make(map[string]uint32, count),
+		entries: make([]slotEntry, count),
+		start:   start,
+	}
+	for i := range loaded.entries {
+		state, err := dec.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("read DiskANN slot %d: %w", i, err)
+		}
+		entry := &loaded.entries[i]
+		entry.state = slotState(state)
+		if entry.id, err = dec.ReadString(); err != nil {
+			return nil, fmt.Errorf("read DiskANN slot %d id: %w", i, err)
+		}
+		if entry.version, err = dec.ReadUint64(); err != nil {
+			return nil, fmt.Errorf("read DiskANN slot %d version: %w", i, err)
+		}
+		if entry.ordinal, err = dec.ReadUint32(); err != nil {
+			return nil, fmt.Errorf("read DiskANN slot %d ordinal: %w", i, err)
+		}
+		switch entry.state {
+		case slotFree:
+			loaded.free = append(loaded.free, uint32(i))
+		case slotLive:
+			if _, ok := loaded.slots[entry.id]; ok || entry.id == "" {
+				return nil, fmt.Errorf("invalid DiskANN snapshot: slot %d has duplicate or empty ID", i)
+			}
+			loaded.slots[entry.id] = uint32(i)
+			loaded.live++
+		case slotDeleted:
+			loaded.deleted++
+		default:
+			return nil, fmt.Errorf("invalid DiskANN snapshot: slot %d state %d", i, state)
+		}
+	}
+	if count > 0 && loaded.entries[start].state == slotFree {
+		return nil, fmt.Errorf("invalid DiskANN snapshot: start slot %d is free", start)
+	}
+
+	if loaded.distance, err = util.GetDistanceFunc(cfg.Metric); err != nil {
+		return nil, err
+	}
+	loaded.pq = quant.NewProductQuantizer()
+	if err := loaded.pq.Configure(cfg.Quantization); err != nil {
+		return nil, fmt.Errorf("configure DiskANN PQ: %w", err)
+	}
+	trained, err := dec.ReadBool()
+	if err != nil {
+		return nil, fmt.Errorf("read DiskANN trained flag: %w", err)
+	}
+	if trained {
+		state, err := readBlob(dec)
+		if err != nil {
+			return nil, fmt.Errorf("read DiskANN PQ: %w", err)
+		}
+		if err := loaded.pq.DeserializeState(state); err != nil {
+			return nil, fmt.Errorf("restore DiskANN PQ: %w", err)
+		}
+		if loaded.codes, err = readBlob(dec); err != nil {
+			return nil, fmt.Errorf("read DiskANN codes: %w", err)
+		}
+		loaded.codeSize = loaded.pq.CodeSize()
+		if loaded.codeSize == 0 || len(loaded.codes) != len(loaded.entries)*loaded.codeSize {
+			return nil, fmt.Errorf("invalid DiskANN snapshot: %d code bytes for %d slots", len(loaded.codes), count)
+		}
+	}
+
+	if loaded.store, err = newNodeStore(cfg.Dir, cfg.Dimension, cfg.R); err != nil {
+		return nil, err
+	}
+	return loaded, nil
+}
+
+// writeBlob writes a length-prefixed byte run that, unlike WriteBytes, is
+// not bounded by util.MaxStringLen.
+func writeBlob(enc *util.BinaryEncoder, data []byte) {
+	enc.WriteUint64(uint64(len(data)))
+	enc.Buf = append(enc.Buf, data...)
+}
+
+func readBlob(dec *util.BinaryDecoder) ([]byte, error) {
+	size, err := dec.ReadUint64()
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(len(dec.Data)-dec.Off) {
+		return nil, fmt.Errorf("unexpected end of data")
+	}
+	data := append([]byte(nil), dec.Data[dec.Off:dec.Off+int(size)]...)
+	dec.Off += int(size)
+	return data, nil
+}
+
+func alignPage(n int64) int64 {
+	return (n + PageSize - 1) / PageSize * PageSize
+}
@@ -0,0 +1,286 @@
+package diskann
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+)
+
+// PageSize is the alignment unit of the node file. Nodes never straddle a
+// page boundary, so every node read is a single aligned I/O.
+const PageSize = 4096
+
+// node is one decoded node block: the full-precision vector and the
+// out-neighbors of a slot.
+type node struct {
+	vector    []float32
+	neighbors []uint32
+}
+
+// nodeStore keeps full-precision vectors and adjacency lists in fixed-size
+// blocks of a file. A block is
+//
+//	degree uint32 | neighbors [R]uint32 | vector [dimension]float32
+//
+// with the adjacency first so edge updates rewrite only the block head.
+// Blocks smaller than a page are packed whole into pages; larger blocks
+// start on a page boundary and occupy consecutive pages.
+//
+// A store restored from an extent reads blocks it has not rewritten from
+// that base region, which has the same layout, and keeps only rewritten
+// blocks in its file.
+type nodeStore struct {
+	file         *os.File
+	dimension    int
+	degree       int
+	blockBytes   int
+	nodesPerPage int
+	pagesPerNode int
+
+	base      io.ReaderAt
+	baseSlots int
+	// written marks the base slots whose block lives in file.
+	written  []uint64
+	modified bool
+}
+
+func newNodeStore(dir string, dimension, degree int) (*nodeStore, error) {
+	file, err := os.CreateTemp(dir, "libravdb-diskann-*.vamana")
+	if err != nil {
+		return nil, fmt.Errorf("create DiskANN node file: %w", err)
+	}
+	store := &nodeStore{
+		file:       file,
+		dimension:  dimension,
+		degree:     degree,
+		blockBytes: 4 + 4*degree + 4*dimension,
+	}
+	if store.blockBytes <= PageSize {
+		store.nodesPerPage = PageSize / store.blockBytes
+	} else {
+		store.pagesPerNode = (store.blockBytes + PageSize - 1) / PageSize
+	}
+	return store, nil
+}
+
+func (s *nodeStore) offset(slot uint32) int64 {
+	if s.nodesPerPage > 0 {
+		perPage := uint32(s.nodesPerPage)
+		return int64(slot/perPage)*PageSize + int64(slot%perPage)*int64(s.blockBytes)
+	}
+	return int64(slot) * int64(s.pagesPerNode) * PageSize
+}
+
+// regionBytes is the length of the file prefix holding the first slots
+// blocks.
+func (s *nodeStore) regionBytes(slots int) int64 {
+	if slots == 0 {
+		return 0
+	}
+	return s.offset(uint32(slots-1)) + int64(s.blockBytes)
+}
+
+// setBase makes the first slots blocks of region the store's contents.
+func (s *nodeStore) setBase(region io.ReaderAt, slots int) {
+	s.base = region
+	s.baseSlots = slots
+	s.written = make([]uint64, (slots+63)/64)
+	s.modified = false
+}
+
+// inBase reports whether slot's current block is in the base region.
+func (s *nodeStore) inBase(slot uint32) bool {
+	return int(slot) < s.baseSlots && s.written[slot/64]&(1<<(slot%64)) == 0
+}
+
+func (s *nodeStore) markWritten(slot uint32) {
+	s.modified = true
+	if int(slot) < s.baseSlots {
+		s.written[slot/64] |= 1 << (slot % 64)
+	}
+}
+
+// unchanged reports whether the first slots blocks are exactly the base
+// region.
+func (s *nodeStore) unchanged(slots int) bool {
+	return s.base != nil && !s.modified && slots == s.baseSlots
+}
+
+// regionView returns a copy of the store that writeRegion can stream from
+// after the index lock is released: later writes no longer move blocks
+// between the base region and the file under it.
+func (s *nodeStore) regionView() *nodeStore {
+	view := *s
+	view.written = slices.Clone(s.written)
+	return &view
+}
+
+func (s *nodeStore) readBlock(buf []byte, slot uint32) error {
+	source := io.ReaderAt(s.file)
+	if s.inBase(slot) {
+		source = s.base
+	}
+	_, err := source.ReadAt(buf, s.offset(slot))
+	return err
+}
+
+func (s *nodeStore) read(slot uint32) (*node, error) {
+	buf := make([]byte, s.blockBytes)
+	if err := s.readBlock(buf, slot); err != nil {
+		return nil, fmt.Errorf("read DiskANN node %d: %w", slot, err)
+	}
+	degree := int(binary.LittleEndian.Uint32(buf))
+	if degree > s.degree {
+		return nil, fmt.Errorf("DiskANN node %d has degree %d, limit %d", slot, degree, s.degree)
+	}
+	n := &node{
+		vector:    make([]float32, s.dimension),
+		neighbors: make([]uint32, degree),
+	}
+	for i := range n.neighbors {
+		n.neighbors[i] = binary.LittleEndian.Uint32(buf[4+4*i:])
+	}
+	base := 4 + 4*s.degree
+	for i := range n.vector {
+		n.vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[base+4*i:]))
+	}
+	return n, nil
+}
+
+func (s *nodeStore) write(slot uint32, vector []float32, neighbors []uint32) error {
+	buf := make([]byte, s.blockBytes)
+	s.encodeNeighbors(buf, neighbors)
+	base := 4 + 4*s.degree
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[base+4*i:], math.Float32bits(v))
+	}
+	if _, err := s.file.WriteAt(buf, s.offset(slot)); err != nil {
+		return fmt.Errorf("write DiskANN node %d: %w", slot, err)
+	}
+	s.markWritten(slot)
+	return nil
+}
+
+// writeNeighbors replaces the adjacency of slot without touching its vector.
+// A block still in the base region is copied into the file whole first.
+func (s *nodeStore) writeNeighbors(slot uint32, neighbors []uint32) error {
+	buf := make([]byte, 4+4*s.degree)
+	if s.inBase(slot) {
+		buf = make([]byte, s.blockBytes)
+		if _, err := s.base.ReadAt(buf, s.offset(slot)); err != nil {
+			return fmt.Errorf("read DiskANN node %d: %w", slot, err)
+		}
+	}
+	s.encodeNeighbors(buf, neighbors)
+	if _, err := s.file.WriteAt(buf, s.offset(slot)); err != nil {
+		return fmt.Errorf("write DiskANN node %d: %w", slot, err)
+	}
+	s.markWritten(slot)
+	return nil
+}
+
+func (s *nodeStore) encodeNeighbors(buf []byte, neighbors []uint32) {
+	binary.LittleEndian.PutUint32(buf, uint32(len(neighbors)))
+	for i, neighbor := range neighbors {
+		binary.LittleEndian.PutUint32(buf[4+4*i:], neighbor)
+	}
+}
+
+// regionBatchBytes bounds the buffer writeRegion merges base and file
+// blocks in.
+const regionBatchBytes = 1 << 20
+
+// writeRegion writes the blocks of the first slots slots to w, laid out as
+// in the file, with zeroed padding.
+func (s *nodeStore) writeRegion(w io.Writer, slots int) error {
+	if s.base == nil {
+		_, err := io.Copy(w, io.NewSectionReader(s.file, 0, s.regionBytes(slots)))
+		return err
+	}
+	batch := s.nodesPerPage * (regionBatchBytes / PageSize)
+	if s.nodesPerPage == 0 {
+		batch = max(1, regionBatchBytes/(s.pagesPerNode*PageSize))
+	}
+	var buf []byte
+	for first := 0; first < slots; first += batch {
+		last := min(first+batch, slots)
+		start := s.offset(uint32(first))
+		end := s.offset(uint32(last-1)) + int64(s.blockBytes)
+		if last < slots {
+			end = s.offset(uint32(last))
+		}
+		if int64(cap(buf)) < end-start {
+			buf = make([]byte, end-start)
+		} else {
+			buf = buf[:end-start]
+			clear(buf)
+		}
+		// Copy runs of slots that share a source with one read each.
+		for run := first; run < last; {
+			inBase := s.inBase(uint32(run))
+			next := run + 1
+			for next < last && s.inBase(uint32(next)) == inBase {
+				next++
+			}
+			from := s.offset(uint32(run))
+			to := s.offset(uint32(next-1)) + int64(s.blockBytes)
+			if inBase {
+				if _, err := s.base.ReadAt(buf[from-start:to-start], from); err != nil {
+					return fmt.Errorf("read DiskANN node %d: %w", run, err)
+				}
+			} else if err := s.readFile(buf[from-start:to-start], from); err != nil {
+				return fmt.Errorf("read DiskANN node %d: %w", run, err)
+			}
+			run = next
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFile fills p from the file at off. The file is sparse: bytes past
+// its end read as zero.
+func (s *nodeStore) readFile(p []byte, off int64) error {
+	n, err := s.file.ReadAt(p, off)
+	if errors.Is(err, io.EOF) {
+		clear(p[n:])
+		return nil
+	}
+	return err
+}
+
+func (s *nodeStore) truncate() error {
+	if err := s.dropBase(); err != nil {
+		return err
+	}
+	return s.file.Truncate(0)
+}
+
+// dropBase stops reading from the base region and releases it.
+func (s *nodeStore) dropBase() error {
+	base := s.base
+	s.base = nil
+	s.baseSlots = 0
+	s.written = nil
+	if closer, ok := base.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+func (s *nodeStore) close() error {
+	err := s.dropBase()
+	if closeErr := s.file.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(s.file.Name()); err == nil && removeErr != nil && !os.IsNotExist(removeErr) {
+		err = removeErr
+	}
+	return err
+}
@@ -0,0 +1,553 @@
+package diskann
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// visit is a node expanded by greedySearch with its full-precision
+// distance to the query.
+type visit struct {
+	node     *node
+	distance float32
+	slot     uint32
+}
+
+type beamEntry struct {
+	distance float32
+	slot     uint32
+	expanded bool
+}
+
+// pruneCandidate is a RobustPrune input. distance is the squared Euclidean
+// distance to the node being pruned.
+type pruneCandidate struct {
+	vector   []float32
+	distance float32
+	slot     uint32
+}
+
+// greedySearch is GreedySearch(start, query, l) from the Vamana paper. The
+// beam is ordered by PQ distance once codebooks are trained and by exact
+// distance before; every expanded node is read from the node file and
+// reported with its exact distance, so callers re-rank on full precision.
+//
+// A non-nil filter is tested during traversal, as in the HNSW filtered
+// search: every scored live node that passes it also enters a second list of
+// up to l matches. Non-matching nodes keep routing through the main beam, so
+// a selective filter does not disconnect the graph, and matches are expanded
+// alongside the beam so each one is reported with its exact distance. Beam
+// entries past the first l stay expandable while they are closer than the
+// k-th match found so far, so a selective filter widens the search in
+// distance order until no closer match can be reached.
+func (x *Index) greedySearch(ctx context.Context, query []float32, l, k int, filter interface{ Test(uint64) bool }) ([]visit, error) {
+	cache := make(map[uint32]*node)
+	approximate := x.approximator(query, cache)
+	distance, err := approximate(x.start)
+	if err != nil {
+		return nil, err
+	}
+	beam := make([]beamEntry, 1, l+1)
+	beam[0] = beamEntry{slot: x.start, distance: distance}
+	var matches []beamEntry
+	if filter != nil {
+		matches = make([]beamEntry, 0, l+1)
+		if x.matches(x.start, filter) {
+			matches = append(matches, beam[0])
+		}
+	}
+	// widened reports whether a beam entry past the first l may still lead
+	// to a match closer than the current k-th one.
+	widened := func(distance float32) bool {
+		return filter != nil && (len(matches) < k || distance < matches[k-1].distance)
+	}
+	seen := map[uint32]struct{}{x.start: {}}
+	expanded := make(map[uint32]struct{})
+	var visits []visit
+	for {
+		list, next := beam, firstUnexpanded(beam)
+		if next >= l && !widened(beam[next].distance) {
+			next = -1
+		}
+		if i := firstUnexpanded(matches); i >= 0 && (next < 0 || matches[i].distance < beam[next].distance) {
+			list, next = matches, i
+		}
+		if next < 0 {
+			return visits, nil
+		}
+		list[next].expanded = true
+		slot := list[next].slot
+		if _, ok := expanded[slot]; ok {
+			continue
+		}
+		expanded[slot] = struct{}{}
+		if len(visits)%64 == 0 {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		n, err := x.loadNode(slot, cache)
+		if err != nil {
+			return nil, err
+		}
+		visits = append(visits, visit{slot: slot, node: n, distance: x.distance(query, n.vector)})
+		for _, neighbor := range n.neighbors {
+			if _, ok := seen[neighbor]; ok {
+				continue
+			}
+			seen[neighbor] = struct{}{}
+			if int(neighbor) >= len(x.entries) || x.entries[neighbor].state == slotFree {
+				return nil, fmt.Errorf("DiskANN node %d links to unallocated slot %d", slot, neighbor)
+			}
+			distance, err := approximate(neighbor)
+			if err != nil {
+				return nil, err
+			}
+			candidate := beamEntry{slot: neighbor, distance: distance}
+			if filter == nil {
+				beam = admitBeam(beam, candidate, l)
+				continue
+			}
+			if x.matches(neighbor, filter) {
+				matches = admitBeam(matches, candidate, l)
+			}
+			// The k-th match distance only shrinks, so a candidate that is
+			// neither within the first l nor widened never becomes expandable.
+			if len(beam) < l || distance < beam[l-1].distance || widened(distance) {
+				beam = admitBeam(beam, candidate, len(beam)+1)
+			}
+		}
+	}
+}
+
+// matches reports whether slot holds a live node that passes filter.
+func (x *Index) matches(slot uint32, filter interface{ Test(uint64) bool }) bool {
+	entry := x.entries[slot]
+	return entry.state == slotLive && filter.Test(uint64(entry.ordinal))
+}
+
+func firstUnexpanded(list []beamEntry) int {
+	for i := range list {
+		if !list[i].expanded {
+			return i
+		}
+	}
+	return -1
+}
+
+// admitBeam inserts candidate into the distance-ordered list, keeping at
+// most l entries.
+func admitBeam(list []beamEntry, candidate beamEntry, l int) []beamEntry {
+	if len(list) == l && candidate.distance >= list[l-1].distance {
+		return list
+	}
+	at := sort.Search(len(list), func(i int) bool { return list[i].distance > candidate.distance })
+	list = append(list, beamEntry{})
+	copy(list[at+1:], list[at:])
+	list[at] = candidate
+	if len(list) > l {
+		list = list[:l]
+	}
+	return list
+}
+
+// approximator returns the beam ordering distance: PQ asymmetric distance
+// from the in-memory codes, or the exact distance from the node file while
+// the codebooks are untrained.
+func (x *Index) approximator(query []float32, cache map[uint32]*node) func(uint32) (float32, error) {
+	if x.codeSize > 0 {
+		state := x.pq.PrepareQuery(query)
+		return func(slot uint32) (float32, error) {
+			offset := int(slot) * x.codeSize
+			return x.pq.DistanceToQuery(x.codes[offset:offset+x.codeSize], query, state)
+		}
+	}
+	return func(slot uint32) (float32, error) {
+		n, err := x.loadNode(slot, cache)
+		if err != nil {
+			return 0, err
+		}
+		return x.distance(query, n.vector), nil
+	}
+}
+
+func (x *Index) loadNode(slot uint32, cache map[uint32]*node) (*node, error) {
+	if n, ok := cache[slot]; ok {
+		return n, nil
+	}
+	n, err := x.store.read(slot)
+	if err != nil {
+		return nil, err
+	}
+	cache[slot] = n
+	return n, nil
+}
+
+// collectCandidates gathers the live nodes among visits and extra, minus
+// self, as RobustPrune candidates around origin. Vectors of extra slots
+// that were not visited are read from the node file.
+func (x *Index) collectCandidates(origin []float32, self uint32, visits []visit, extra []uint32) ([]pruneCandidate, error) {
+	seen := map[uint32]struct{}{self: {}}
+	candidates := make([]pruneCandidate, 0, len(visits)+len(extra))
+	for _, v := range visits {
+		if _, ok := seen[v.slot]; ok || x.entries[v.slot].state != slotLive {
+			continue
+		}
+		seen[v.slot] = struct{}{}
+		candidates = append(candidates, pruneCandidate{slot: v.slot, vector: v.node.vector, distance: x.l2(origin, v.node.vector)})
+	}
+	for _, slot := range extra {
+		if _, ok := seen[slot]; ok || int(slot) >= len(x.entries) || x.entries[slot].state != slotLive {
+			continue
+		}
+		seen[slot] = struct{}{}
+		n, err := x.store.read(slot)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, pruneCandidate{slot: slot, vector: n.vector, distance: x.l2(origin, n.vector)})
+	}
+	return candidates, nil
+}
+
+// robustPrune is RobustPrune(p, V, alpha, R): it takes candidates closest
+// first and drops every later candidate that the chosen one covers, that
+// is, one alpha times closer to the chosen neighbor than to p. Pruning is
+// geometric, so it always uses Euclidean distance; distances are squared,
+// hence the squared alpha.
+func (x *Index) robustPrune(candidates []pruneCandidate, alpha float32) []uint32 {
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+	alpha2 := alpha * alpha
+	removed := make([]bool, len(candidates))
+	neighbors := make([]uint32, 0, min(x.config.R, len(candidates)))
+	for i := range candidates {
+		if removed[i] {
+			continue
+		}
+		neighbors = append(neighbors, candidates[i].slot)
+		if len(neighbors) == x.config.R {
+			break
+		}
+		for j := i + 1; j < len(candidates); j++ {
+			if !removed[j] && alpha2*x.l2(candidates[i].vector, candidates[j].vector) <= candidates[j].distance {
+				removed[j] = true
+			}
+		}
+	}
+	return neighbors
+}
+
+// insertLocked is the FreshDiskANN insert. Replacing an existing ID
+// tombstones the old node first.
+func (x *Index) insertLocked(ctx context.Context, entry *VectorEntry, alpha float32) error {
+	if _, ok := x.slots[entry.ID]; ok {
+		if err := x.deleteLocked(entry.ID); err != nil {
+			return err
+		}
+	}
+	vector := append([]float32(nil), entry.Vector...)
+	var neighbors []uint32
+	empty := x.live+x.deleted == 0
+	if !empty {
+		visits, err := x.greedySearch(ctx, vector, x.config.L, 0, nil)
+		if err != nil {
+			return err
+		}
+		candidates, err := x.collectCandidates(vector, ^uint32(0), visits, nil)
+		if err != nil {
+			return err
+		}
+		neighbors = x.robustPrune(candidates, alpha)
+	}
+	slot := x.allocateLocked()
+	if err := x.store.write(slot, vector, neighbors); err != nil {
+		x.free = append(x.free, slot)
+		return err
+	}
+	if err := x.encodeLocked(slot, vector); err != nil {
+		x.free = append(x.free, slot)
+		return err
+	}
+	x.entries[slot] = slotEntry{id: entry.ID, version: entry.Version, ordinal: entry.Ordinal, state: slotLive}
+	x.slots[entry.ID] = slot
+	x.live++
+	if empty {
+		x.start = slot
+	}
+	for _, neighbor := range neighbors {
+		if err := x.addReverseEdgeLocked(neighbor, slot, alpha); err != nil {
+			return err
+		}
+	}
+	return x.maybeTrainLocked(ctx)
+}
+
+// addReverseEdgeLocked links target back to source, re-pruning target's
+// adjacency when it is already full.
+func (x *Index) addReverseEdgeLocked(target, source uint32, alpha float32) error {
+	n, err := x.store.read(target)
+	if err != nil {
+		return err
+	}
+	for _, neighbor := range n.neighbors {
+		if neighbor == source {
+			return nil
+		}
+	}
+	if len(n.neighbors) < x.config.R {
+		return x.store.writeNeighbors(target, append(n.neighbors, source))
+	}
+	candidates, err := x.collectCandidates(n.vector, target, nil, append(n.neighbors, source))
+	if err != nil {
+		return err
+	}
+	return x.store.writeNeighbors(target, x.robustPrune(candidates, alpha))
+}
+
+// buildLocked loads entries into an empty index with Vamana's two passes
+// over a random permutation.
+func (x *Index) buildLocked(ctx context.Context, entries []*VectorEntry) error {
+	sample := make([][]float32, 0, min(len(entries), maxTrainSample))
+	for _, i := range x.rng.Perm(len(entries)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := x.insertLocked(ctx, entries[i], 1); err != nil {
+			return err
+		}
+		if len(sample) < cap(sample) {
+			sample = append(sample, entries[i].Vector)
+		}
+	}
+	if x.codeSize == 0 {
+		if err := x.recenterLocked(ctx, sample); err != nil {
+			return err
+		}
+	}
+	for _, i := range x.rng.Perm(len(x.entries)) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if x.entries[i].state == slotLive {
+			if err := x.refineLocked(ctx, uint32(i), x.config.Alpha); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// refineLocked re-derives the adjacency of slot from a fresh search of its
+// own vector, as in Vamana's second pass.
+func (x *Index) refineLocked(ctx context.Context, slot uint32, alpha float32) error {
+	n, err := x.store.read(slot)
+	if err != nil {
+		return err
+	}
+	visits, err := x.greedySearch(ctx, n.vector, x.config.L, 0, nil)
+	if err != nil {
+		return err
+	}
+	candidates, err := x.collectCandidates(n.vector, slot, visits, n.neighbors)
+	if err != nil {
+		return err
+	}
+	neighbors := x.robustPrune(candidates, alpha)
+	if err := x.store.writeNeighbors(slot, neighbors); err != nil {
+		return err
+	}
+	for _, neighbor := range neighbors {
+		if err := x.addReverseEdgeLocked(neighbor, slot, alpha); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// consolidateLocked is FreshDiskANN's delete consolidation: every live node
+// with a tombstoned neighbor replaces it with that neighbor's own live
+// out-neighbors and re-prunes. Tombstoned slots are then freed.
+func (x *Index) consolidateLocked(ctx context.Context) error {
+	removed := make(map[uint32]*node, x.deleted)
+	removedNode := func(slot uint32) (*node, error) {
+		if n, ok := removed[slot]; ok {
+			return n, nil
+		}
+		n, err := x.store.read(slot)
+		if err != nil {
+			return nil, err
+		}
+		removed[slot] = n
+		return n, nil
+	}
+	for i := range x.entries {
+		if i%256 == 0 {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if x.entries[i].state != slotLive {
+			continue
+		}
+		slot := uint32(i)
+		n, err := x.store.read(slot)
+		if err != nil {
+			return err
+		}
+		affected := false
+		extra := make([]uint32, 0, len(n.neighbors))
+		for _, neighbor := range n.neighbors {
+			if x.entries[neighbor].state != slotDeleted {
+				extra = append(extra, neighbor)
+				continue
+			}
+			affected = true
+			gone, err := removedNode(neighbor)
+			if err != nil {
+				return err
+			}
+			extra = append(extra, gone.neighbors...)
+		}
+		if !affected {
+			continue
+		}
+		candidates, err := x.collectCandidates(n.vector, slot, nil, extra)
+		if err != nil {
+			return err
+		}
+		if err := x.store.writeNeighbors(slot, x.robustPrune(candidates, x.config.Alpha)); err != nil {
+			return err
+		}
+	}
+	if x.entries[x.start].state != slotLive {
+		// Tombstoned nodes keep their adjacency until they are freed below,
+		// so a search for the old start's vector still traverses from it.
+		old, err := removedNode(x.start)
+		if err != nil {
+			return err
+		}
+		if err := x.recenterLocked(ctx, [][]float32{old.vector}); err != nil {
+			return err
+		}
+		if x.entries[x.start].state != slotLive {
+			for i := range x.entries {
+				if x.entries[i].state == slotLive {
+					x.start = uint32(i)
+					break
+				}
+			}
+		}
+	}
+	for i := range x.entries {
+		if x.entries[i].state == slotDeleted {
+			x.entries[i] = slotEntry{}
+			x.free = append(x.free, uint32(i))
+		}
+	}
+	x.deleted = 0
+	return nil
+}
+
+func (x *Index) allocateLocked() uint32 {
+	if n := len(x.free); n > 0 {
+		slot := x.free[n-1]
+		x.free = x.free[:n-1]
+		return slot
+	}
+	x.entries = append(x.entries, slotEntry{})
+	if x.codeSize > 0 {
+		x.codes = append(x.codes, make([]byte, x.codeSize)...)
+	}
+	return uint32(len(x.entries) - 1)
+}
+
+func (x *Index) encodeLocked(slot uint32, vector []float32) error {
+	if x.codeSize == 0 {
+		return nil
+	}
+	code, err := x.pq.Compress(vector)
+	if err != nil {
+		return fmt.Errorf("encode DiskANN node %d: %w", slot, err)
+	}
+	copy(x.codes[int(slot)*x.codeSize:], code)
+	return nil
+}
+
+// maybeTrainLocked trains the PQ codebooks once TrainSize vectors are
+// present, encodes every node and moves the start to the medoid.
+func (x *Index) maybeTrainLocked(ctx context.Context) error {
+	if x.codeSize > 0 || x.live < x.config.TrainSize {
+		return nil
+	}
+	slots := make([]uint32, 0, x.live)
+	for i := range x.entries {
+		if x.entries[i].state == slotLive {
+			slots = append(slots, uint32(i))
+		}
+	}
+	if len(slots) > maxTrainSample {
+		x.rng.Shuffle(len(slots), func(i, j int) { slots[i], slots[j] = slots[j], slots[i] })
+		slots = slots[:maxTrainSample]
+	}
+	sample := make([][]float32, len(slots))
+	for i, slot := range slots {
+		n, err := x.store.read(slot)
+		if err != nil {
+			return err
+		}
+		sample[i] = n.vector
+	}
+	if err := x.pq.Train(ctx, sample); err != nil {
+		return fmt.Errorf("train DiskANN PQ: %w", err)
+	}
+	codeSize := x.pq.CodeSize()
+	codes := make([]byte, len(x.entries)*codeSize)
+	for i := range x.entries {
+		if x.entries[i].state == slotFree {
+			continue
+		}
+		n, err := x.store.read(uint32(i))
+		if err != nil {
+			return err
+		}
+		code, err := x.pq.Compress(n.vector)
+		if err != nil {
+			return fmt.Errorf("encode DiskANN node %d: %w", i, err)
+		}
+		copy(codes[i*codeSize:], code)
+	}
+	x.codes, x.codeSize = codes, codeSize
+	return x.recenterLocked(ctx, sample)
+}
+
+// recenterLocked moves the search start to the live node nearest the
+// centroid of sample, approximating the medoid.
+func (x *Index) recenterLocked(ctx context.Context, sample [][]float32) error {
+	if len(sample) == 0 || x.live == 0 {
+		return nil
+	}
+	centroid := make([]float32, x.config.Dimension)
+	for _, vector := range sample {
+		for i, v := range vector {
+			centroid[i] += v
+		}
+	}
+	for i := range centroid {
+		centroid[i] /= float32(len(sample))
+	}
+	visits, err := x.greedySearch(ctx, centroid, x.config.L, 0, nil)
+	if err != nil {
+		return err
+	}
+	best := -1
+	for i, v := range visits {
+		if x.entries[v.slot].state == slotLive && (best < 0 || v.distance < visits[best].distance) {
+			best = i
+		}
+	}
+	if best >= 0 {
+		x.start = visits[best].slot
+	}
+	return nil
+}
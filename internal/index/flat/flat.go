@@ -1,11 +1,13 @@
 package flat
 
 import (
+	"cmp"
 	"context"
 	"encoding/binary"
 	"fmt"
 	"hash/crc32"
 	"os"
+	"slices"
 	"sync"
 	"time"
 	"unsafe"
@@ -28,6 +30,7 @@ type VectorEntry struct {
 	ID           string                 `json:"id"`
 	Vector       []float32              `json:"vector"`
 	Version      uint64                 `json:"version"`
+	code         []byte
 	metadataSize int64
 }
 
@@ -45,6 +48,10 @@ type Config struct {
 	Quantization *quant.QuantizationConfig `json:"quantization,omitempty"`
 	Dimension    int                       `json:"dimension"`
 	Metric       util.DistanceMetric       `json:"metric"`
+	// RescoreK enables quantized search when Quantization is set: the scan
+	// runs over the compact codes and only the best RescoreK candidates are
+	// rescored with full-precision vectors.
+	RescoreK int `json:"rescore_k,omitempty"`
 }
 
 // PersistenceMetadata holds metadata about persisted flat index
@@ -73,11 +80,19 @@ type Index struct {
 
 const vectorDataOffset = 48
 
+// quantizerTrainThreshold is the number of vectors collected before the
+// quantizer is trained. Until then quantized search falls back to the exact
+// scan.
+const quantizerTrainThreshold = 100
+
 // NewFlat creates a new flat index
 func NewFlat(config *Config) (*Index, error) {
 	if config.Dimension <= 0 {
 		return nil, fmt.Errorf("dimension must be positive, got %d", config.Dimension)
 	}
+	if config.RescoreK < 0 {
+		return nil, fmt.Errorf("rescore k must be non-negative, got %d", config.RescoreK)
+	}
 
 	vectorSlotSize := (vectorDataOffset + config.Dimension*4 + 63) &^ 63
 	sfl, err := memory.NewShardedFreeList(memory.FreeListConfig{
@@ -131,7 +146,7 @@ func (idx *Index) Insert(ctx context.Context, entry *VectorEntry) error {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
-	return idx.insertLocked(entry)
+	return idx.insertLocked(ctx, entry)
 }
 
 // BatchInsert adds multiple vectors to the index under a single lock.
@@ -197,11 +212,11 @@ func (idx *Index) BatchInsert(ctx context.Context, entries []*VectorEntry) error
 		}
 	}
 
-	return nil
+	return idx.syncCodesLocked(ctx, newEntries)
 }
 
 // insertLocked inserts or updates an entry. The caller must hold idx.mu.
-func (idx *Index) insertLocked(entry *VectorEntry) error {
+func (idx *Index) insertLocked(ctx context.Context, entry *VectorEntry) error {
 	// Add new entry
 	slot, err := idx.vectorSFL.Allocate()
 	if err != nil {
@@ -223,12 +238,49 @@ func (idx *Index) insertLocked(entry *VectorEntry) error {
 	if existingIndex, exists := idx.idToIndex[entry.ID]; exists {
 		idx.releaseVector(idx.vectors[existingIndex].Vector)
 		idx.vectors[existingIndex] = newEntry
-		return nil
+	} else {
+		idx.idToIndex[entry.ID] = len(idx.vectors)
+		idx.vectors = append(idx.vectors, newEntry)
 	}
 
-	idx.idToIndex[entry.ID] = len(idx.vectors)
-	idx.vectors = append(idx.vectors, newEntry)
+	return idx.syncCodesLocked(ctx, []*VectorEntry{newEntry})
+}
+
+// quantizedSearch reports whether searches scan quantized codes and rescore
+// the best RescoreK candidates. The caller must hold idx.mu.
+func (idx *Index) quantizedSearch() bool {
+	return idx.quantizer != nil && idx.config.RescoreK > 0
+}
 
+// syncCodesLocked keeps entry codes in step with the quantizer in quantized
+// search mode. The quantizer is trained once the index reaches
+// quantizerTrainThreshold vectors, at which point every stored vector is
+// encoded; afterwards only the given entries are. The caller must hold
+// idx.mu.
+func (idx *Index) syncCodesLocked(ctx context.Context, entries []*VectorEntry) error {
+	if !idx.quantizedSearch() {
+		return nil
+	}
+	if !idx.quantizer.IsTrained() {
+		if len(idx.vectors) < quantizerTrainThreshold {
+			return nil
+		}
+		training := make([][]float32, len(idx.vectors))
+		for i, entry := range idx.vectors {
+			training[i] = entry.Vector
+		}
+		if err := idx.quantizer.Train(ctx, training); err != nil {
+			return fmt.Errorf("failed to train quantizer: %w", err)
+		}
+		entries = idx.vectors
+	}
+	for _, entry := range entries {
+		code, err := idx.quantizer.Compress(entry.Vector)
+		if err != nil {
+			return fmt.Errorf("failed to compress vector %s: %w", entry.ID, err)
+		}
+		entry.code = code
+	}
 	return nil
 }
 
@@ -356,6 +408,10 @@ func (idx *Index) Search(ctx context.Context, query []float32, k int, filter int
 		k = len(idx.vectors)
 	}
 
+	if idx.quantizedSearch() && idx.quantizer.IsTrained() {
+		return idx.searchQuantizedLocked(ctx, query, k, filter)
+	}
+
 	limit := k
 
 	// Acquire off-heap buffer for the heap. Gracefully degrades to arena
@@ -418,21 +474,86 @@ func (idx *Index) Search(ctx context.Context, query []float32, k int, filter int
 		heapBuf[0] = heapBuf[count]
 		downHeap(heapBuf, 0, count)
 
-		entry := idx.vectors[elem.vecIdx]
-		vec := make([]float32, len(entry.Vector))
-		copy(vec, entry.Vector)
-		results[i] = &SearchResult{
-			ID:       entry.ID,
-			Score:    elem.score,
-			Vector:   vec,
-			Metadata: cloneMetadata(entry.Metadata),
-			Version:  entry.Version,
+		results[i] = newSearchResult(idx.vectors[elem.vecIdx], elem.score)
+	}
+
+	return results, nil
+}
+
+// searchQuantizedLocked scans the quantized codes, keeps the best RescoreK
+// candidates by approximate distance, then rescores them against the
+// full-precision vectors and returns the exact top k. The caller must hold
+// idx.mu for reading.
+func (idx *Index) searchQuantizedLocked(ctx context.Context, query []float32, k int, filter interface {
+	Test(idx uint64) bool
+}) ([]*SearchResult, error) {
+	window := min(max(k, idx.config.RescoreK), len(idx.vectors))
+	heapBuf := make([]heapElement, window)
+	queryState := idx.quantizer.PrepareQuery(query)
+
+	count := 0
+	for i, entry := range idx.vectors {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		if filter != nil && !filter.Test(uint64(i)) {
+			continue
+		}
+
+		distance, err := idx.quantizer.DistanceToQuery(entry.code, query, queryState)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute quantized distance: %w", err)
+		}
+
+		if count < window {
+			heapBuf[count] = heapElement{vecIdx: i, score: distance}
+			upHeap(heapBuf, count)
+			count++
+		} else if distance < heapBuf[0].score {
+			heapBuf[0] = heapElement{vecIdx: i, score: distance}
+			downHeap(heapBuf, 0, count)
+		}
+	}
+
+	candidates := heapBuf[:count]
+	for i := range candidates {
+		distance, err := idx.computeDistance(query, idx.vectors[candidates[i].vecIdx].Vector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute distance: %w", err)
 		}
+		candidates[i].score = distance
+	}
+	slices.SortFunc(candidates, func(a, b heapElement) int {
+		return cmp.Compare(a.score, b.score)
+	})
+	if len(candidates) > k {
+		candidates = candidates[:k]
 	}
 
+	results := make([]*SearchResult, len(candidates))
+	for i, candidate := range candidates {
+		results[i] = newSearchResult(idx.vectors[candidate.vecIdx], candidate.score)
+	}
 	return results, nil
 }
 
+// newSearchResult copies entry out of the index so callers never alias
+// off-heap vectors or stored metadata.
+func newSearchResult(entry *VectorEntry, score float32) *SearchResult {
+	vec := make([]float32, len(entry.Vector))
+	copy(vec, entry.Vector)
+	return &SearchResult{
+		ID:       entry.ID,
+		Score:    score,
+		Vector:   vec,
+		Metadata: cloneMetadata(entry.Metadata),
+		Version:  entry.Version,
+	}
+}
+
 // Delete removes a vector from the index
 func (idx *Index) Delete(ctx context.Context, id string) error {
 	idx.mu.Lock()
@@ -481,9 +602,9 @@ func (idx *Index) MemoryUsage() int64 {
 	// Index map overhead (estimate 32 bytes per entry)
 	usage += int64(len(idx.idToIndex)) * 32
 
-	// Metadata storage (cached per-entry, computed at insert)
+	// Metadata storage (cached per-entry, computed at insert) and quantized codes
 	for _, entry := range idx.vectors {
-		usage += entry.metadataSize
+		usage += entry.metadataSize + int64(len(entry.code))
 	}
 
 	return usage
@@ -732,6 +853,13 @@ func (idx *Index) DeserializeFromBytes(ctx context.Context, data []byte) error {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
+	// The snapshot only records the core quantization fields; anything else
+	// comes from the configuration the index was opened with.
+	cfg.RescoreK = idx.config.RescoreK
+	if cfg.Quantization != nil && idx.config.Quantization != nil && idx.config.Quantization.Type == cfg.Quantization.Type {
+		cfg.Quantization = idx.config.Quantization
+	}
+
 	for _, entry := range idx.vectors {
 		idx.releaseVector(entry.Vector)
 	}
@@ -747,7 +875,7 @@ func (idx *Index) DeserializeFromBytes(ctx context.Context, data []byte) error {
 		}
 	}
 
-	return nil
+	return idx.syncCodesLocked(ctx, nil)
 }
 
 // GetConfig returns the index configuration
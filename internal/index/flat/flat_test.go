@@ -4,10 +4,12 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"testing"
 
+	"github.com/xDarkicex/libravdb/internal/quant"
 	"github.com/xDarkicex/libravdb/internal/util"
 )
 
@@ -650,3 +652,114 @@ func TestSearchArenaBacked(t *testing.T) {
 		t.Fatal("search did not initialize the off-heap heap tier")
 	}
 }
+
+func TestFlatBinaryQuantizationRescoring(t *testing.T) {
+	const dim = 64
+	rng := rand.New(rand.NewSource(11))
+	centers := make([][]float32, 16)
+	for i := range centers {
+		centers[i] = make([]float32, dim)
+		for d := range centers[i] {
+			centers[i][d] = float32(rng.NormFloat64())
+		}
+	}
+	entries := make([]*VectorEntry, 400)
+	for i := range entries {
+		center := centers[i%len(centers)]
+		vec := make([]float32, dim)
+		for d := range vec {
+			vec[d] = center[d] + float32(rng.NormFloat64())*0.5
+		}
+		entries[i] = &VectorEntry{ID: fmt.Sprintf("vec_%d", i), Vector: vec}
+	}
+
+	ctx := context.Background()
+	exact, err := NewFlat(&Config{Dimension: dim, Metric: util.L2Distance})
+	if err != nil {
+		t.Fatalf("NewFlat exact: %v", err)
+	}
+	defer exact.Close()
+	config := &Config{
+		Dimension:    dim,
+		Metric:       util.L2Distance,
+		Quantization: quant.DefaultConfig(quant.BinaryQuantization),
+		RescoreK:     100,
+	}
+	idx, err := NewFlat(config)
+	if err != nil {
+		t.Fatalf("NewFlat binary: %v", err)
+	}
+	defer idx.Close()
+	if err := exact.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert exact: %v", err)
+	}
+	if err := idx.BatchInsert(ctx, entries); err != nil {
+		t.Fatalf("BatchInsert binary: %v", err)
+	}
+	if !idx.quantizer.IsTrained() {
+		t.Fatal("quantizer not trained after reaching the training threshold")
+	}
+
+	const k = 10
+	recall := func(idx *Index) float64 {
+		t.Helper()
+		hits := 0
+		for q := 0; q < 20; q++ {
+			query := entries[q*7].Vector
+			want, err := exact.Search(ctx, query, k, nil)
+			if err != nil {
+				t.Fatalf("exact search: %v", err)
+			}
+			got, err := idx.Search(ctx, query, k, nil)
+			if err != nil {
+				t.Fatalf("quantized search: %v", err)
+			}
+			if len(got) != k {
+				t.Fatalf("got %d results, want %d", len(got), k)
+			}
+			truth := make(map[string]bool, k)
+			for _, r := range want {
+				truth[r.ID] = true
+			}
+			for i, r := range got {
+				if i > 0 && got[i-1].Score > r.Score {
+					t.Fatalf("results not sorted: %v > %v", got[i-1].Score, r.Score)
+				}
+				wantScore := util.L2Distance_func(query, r.Vector)
+				if math.Abs(float64(r.Score-wantScore)) > 1e-4 {
+					t.Fatalf("result %s score %v, want full-precision %v", r.ID, r.Score, wantScore)
+				}
+				if truth[r.ID] {
+					hits++
+				}
+			}
+		}
+		return float64(hits) / float64(20*k)
+	}
+	if r := recall(idx); r < 0.9 {
+		t.Fatalf("recall@%d = %.2f, want >= 0.9", k, r)
+	}
+
+	data, err := idx.SerializeToBytes()
+	if err != nil {
+		t.Fatalf("SerializeToBytes: %v", err)
+	}
+	reloaded, err := NewFlat(config)
+	if err != nil {
+		t.Fatalf("NewFlat reload: %v", err)
+	}
+	defer reloaded.Close()
+	if err := reloaded.DeserializeFromBytes(ctx, data); err != nil {
+		t.Fatalf("DeserializeFromBytes: %v", err)
+	}
+	if !reloaded.quantizedSearch() || !reloaded.quantizer.IsTrained() {
+		t.Fatal("reloaded index lost quantized search")
+	}
+	if r := recall(reloaded); r < 0.9 {
+		t.Fatalf("reloaded recall@%d = %.2f, want >= 0.9", k, r)
+	}
+
+	if _, err := NewFlat(&Config{Dimension: dim, RescoreK: -1}); err == nil {
+		t.Fatal("NewFlat accepted a negative RescoreK")
+	}
+}
@@ -0,0 +1,416 @@
+package hnsw
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+// Epoch-batched insertion. A batch is split into bounded epochs. Each epoch
+// registers its nodes in batch order, then plans every node's neighbors in
+// parallel against the graph as it stood when the epoch began: the nodes of
+// the epoch are unreachable until it publishes, so they see each other
+// through exact pairwise distances instead of through half-built adjacency.
+// Reverse edges are then grouped by target and every affected neighborhood is
+// rewritten once. Each planning and pruning decision depends only on the
+// epoch graph and the batch, never on which worker reached it first, so the
+// resulting topology does not depend on worker scheduling.
+
+const (
+	// epochBatchSize bounds the nodes planned against one graph epoch. It
+	// also bounds the pairwise distance work per node inside an epoch.
+	epochBatchSize = 256
+
+	// epochMinBatch is the smallest batch worth an epoch. Smaller batches
+	// use sequential inserts, which are equally deterministic.
+	epochMinBatch = 8
+)
+
+// epochNode is one node of an epoch and its planned links per level.
+type epochNode struct {
+	node   *Node
+	id     string
+	vector []float32
+	links  [][]util.Candidate
+}
+
+// epochTarget collects the edges an epoch adds to one adjacency list.
+type epochTarget struct {
+	incoming []uint32
+	added    []uint32
+	dropped  []uint32
+	fresh    *epochNode
+	id       uint32
+	level    int
+}
+
+// BatchInsert inserts entries in epochs of up to epochBatchSize nodes. The
+// graph topology depends only on the index contents and the order of
+// entries, not on GOMAXPROCS or worker scheduling. A failure is reported as
+// a *util.BatchInsertError; the entries it counts as inserted stay in the
+// index.
+func (h *Index) BatchInsert(ctx context.Context, entries []*VectorEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	h.epochMu.Lock()
+	defer h.epochMu.Unlock()
+
+	start := 0
+	if len(entries) < epochMinBatch || h.size.Load() == 0 {
+		// Seed an empty graph with a sequential entry point so every epoch
+		// has a graph to plan against.
+		serial := len(entries)
+		if len(entries) >= epochMinBatch {
+			serial = 1
+		}
+		for ; start < serial; start++ {
+			if err := ctx.Err(); err != nil {
+				return &util.BatchInsertError{Inserted: start, Err: err}
+			}
+			if err := h.insert(ctx, entries[start]); err != nil {
+				return &util.BatchInsertError{Inserted: start, Err: fmt.Errorf("failed to insert entry at index %d: %w", start, err)}
+			}
+		}
+	}
+
+	for start < len(entries) {
+		end := min(start+epochBatchSize, len(entries))
+		if inserted, err := h.insertEpoch(ctx, entries[start:end], start); err != nil {
+			return &util.BatchInsertError{Inserted: start + inserted, Err: err}
+		}
+		start = end
+	}
+	return nil
+}
+
+// insertEpoch registers, plans, and publishes one epoch and returns how many
+// leading entries it inserted. offset is the batch position of entries[0],
+// used in error messages.
+func (h *Index) insertEpoch(ctx context.Context, entries []*VectorEntry, offset int) (int, error) {
+	if h.reclamation != nil {
+		h.reclamation.tryReclaim(h)
+	}
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	batch := make([]epochNode, 0, len(entries))
+	registered := len(entries)
+	var registerErr error
+	for i, entry := range entries {
+		node, err := h.insertSingleMetadata(ctx, entry)
+		if err != nil {
+			registered = i
+			registerErr = fmt.Errorf("failed to insert entry at index %d: %w", offset+i, err)
+			break
+		}
+		if node == nil {
+			// The node became the entry point of an empty graph.
+			continue
+		}
+		batch = append(batch, epochNode{node: node, id: entry.ID, vector: entry.Vector})
+	}
+	if len(batch) == 0 {
+		return registered, registerErr
+	}
+
+	ep := h.getEntryPoint()
+	maxLevel := h.getMaxLevel()
+	errs := make([]error, len(batch))
+	parallelEpoch(len(batch), func(i int) {
+		errs[i] = h.planEpochNode(ctx, batch, i, ep, maxLevel)
+	})
+	for _, err := range errs {
+		if err != nil {
+			for i := range batch {
+				h.abandonInsert(batch[i].node, batch[i].id)
+			}
+			return registered - len(batch), err
+		}
+	}
+
+	targets := groupEpochTargets(batch)
+	// Complete the adjacency of the epoch's own nodes before any existing
+	// node links to them, so searches never reach a node without links.
+	split := 0
+	for split < len(targets) && targets[split].fresh != nil {
+		split++
+	}
+	parallelEpoch(split, func(i int) {
+		h.rewriteEpochTarget(&targets[i])
+	})
+	parallelEpoch(len(targets)-split, func(i int) {
+		h.rewriteEpochTarget(&targets[split+i])
+	})
+
+	for i := range targets {
+		target := &targets[i]
+		for _, linkID := range target.added {
+			if linked := h.nodes.Get(linkID); linked != nil && target.level <= linked.Level {
+				h.appendWithSpinlock(linked, linked.Backlinks[target.level], target.id, h.config.M, target.level)
+			}
+		}
+		for _, linkID := range target.dropped {
+			h.removeConnection(target.id, linkID, target.level)
+		}
+	}
+	for i := range batch {
+		h.updateEntryPointCAS(batch[i].node)
+	}
+	return registered, registerErr
+}
+
+// planEpochNode selects the links of batch[i] on every level it occupies,
+// from a beam search of the epoch graph merged with the other epoch nodes.
+func (h *Index) planEpochNode(ctx context.Context, batch []epochNode, i int, ep *Node, maxLevel int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	self := &batch[i]
+	query := self.vector
+
+	var queryState any
+	if h.quantizer != nil {
+		queryState = h.quantizer.PrepareQuery(query)
+	} else if q := h.prepareInt8Query(query); q != nil {
+		queryState = q
+	}
+
+	peers := make([]util.Candidate, 0, len(batch)-1)
+	for j := range batch {
+		if j == i {
+			continue
+		}
+		distance, err := h.computeDistanceOptimized(query, batch[j].node, queryState)
+		if err != nil {
+			return err
+		}
+		peers = append(peers, util.Candidate{ID: batch[j].node.Ordinal, Distance: distance})
+	}
+
+	current := ep
+	for level := maxLevel; level > self.node.Level; level-- {
+		candidate, ok, err := h.greedySearchLevelValue(ctx, query, current, level, queryState)
+		if err != nil {
+			return err
+		}
+		if ok {
+			current = h.nodes.Get(candidate.ID)
+		}
+		if current == nil {
+			current = ep
+		}
+	}
+
+	scratch := h.acquireSearchScratchWithEF(h.config.EfConstruction)
+	defer h.releaseSearchScratch(scratch)
+
+	self.links = make([][]util.Candidate, self.node.Level+1)
+	for level := self.node.Level; level >= 0; level-- {
+		var candidates []util.Candidate
+		if level <= maxLevel && current != nil {
+			found, err := h.searchLevelScratchValues(ctx, query, current, h.config.EfConstruction, level, scratch, queryState, nil, false)
+			if err != nil {
+				return err
+			}
+			candidates = make([]util.Candidate, len(found), len(found)+len(peers))
+			copy(candidates, found)
+			if len(found) > 0 {
+				nearest := slices.MinFunc(found, compareCandidateValues)
+				if next := h.nodes.Get(nearest.ID); next != nil {
+					current = next
+				}
+			}
+		}
+		for _, peer := range peers {
+			if node := h.nodes.Get(peer.ID); node != nil && node.Level >= level {
+				candidates = append(candidates, peer)
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		slices.SortFunc(candidates, compareCandidateValues)
+		selected := h.neighborSelector.SelectNeighborsOptimizedValues(query, candidates, level, h)
+		if maxM := levelConstructionMaxLinks(h.config.M, level); len(selected) > maxM {
+			selected = selected[:maxM]
+		}
+		self.links[level] = slices.Clone(selected)
+	}
+	return nil
+}
+
+// groupEpochTargets lists every adjacency the epoch changes: one entry per
+// level of each epoch node, followed by the existing nodes that receive
+// reverse edges. Reverse edges are grouped per target in batch order.
+func groupEpochTargets(batch []epochNode) []epochTarget {
+	targets := make([]epochTarget, 0, len(batch)*2)
+	index := make(map[uint64]int, len(batch)*2)
+	key := func(id uint32, level int) uint64 {
+		return uint64(level)<<32 | uint64(id)
+	}
+	for i := range batch {
+		for level := range batch[i].links {
+			index[key(batch[i].node.Ordinal, level)] = len(targets)
+			targets = append(targets, epochTarget{fresh: &batch[i], id: batch[i].node.Ordinal, level: level})
+		}
+	}
+	for i := range batch {
+		for level, links := range batch[i].links {
+			for _, link := range links {
+				k := key(link.ID, level)
+				at, ok := index[k]
+				if !ok {
+					at = len(targets)
+					index[k] = at
+					targets = append(targets, epochTarget{id: link.ID, level: level})
+				}
+				targets[at].incoming = append(targets[at].incoming, batch[i].node.Ordinal)
+			}
+		}
+	}
+	return targets
+}
+
+// rewriteEpochTarget merges a target's links with its incoming epoch edges
+// and prunes the result at most once. Lists that fit the preallocated slack
+// keep every edge, matching sequential insertion; overflowing lists are
+// reduced with the diversity heuristic.
+func (h *Index) rewriteEpochTarget(target *epochTarget) {
+	node := h.nodes.Get(target.id)
+	if node == nil || target.level > node.Level {
+		return
+	}
+	vector, ok := h.nodeVectorForHeuristic(target.id)
+	if !ok {
+		return
+	}
+
+	maxCapacity := linkArrayCapacity(h.config.M, target.level)
+	maxM := h.neighborSelector.maxConnections
+	if target.level == 0 {
+		maxM = int(float64(maxM) * h.neighborSelector.levelMultiplier)
+	}
+	maxM = min(maxM, maxCapacity)
+
+	for !h.acquirePruneLock(node) {
+		runtime.Gosched()
+	}
+	defer h.releasePruneLock(node)
+	if h.nodes.Get(target.id) != node || node.Links[target.level] == nil {
+		return
+	}
+
+	var original []uint32
+	if target.fresh != nil {
+		for _, link := range target.fresh.links[target.level] {
+			original = append(original, link.ID)
+		}
+	} else {
+		for _, linkID := range h.getNodeLinks(node, target.level) {
+			if linkID != SentinelNodeID && int(linkID) < h.nodes.Len() && h.nodes.Get(linkID) != nil {
+				original = append(original, linkID)
+			}
+		}
+	}
+
+	merged := slices.Clone(original)
+	for _, linkID := range target.incoming {
+		if linkID != target.id && !slices.Contains(merged, linkID) {
+			merged = append(merged, linkID)
+		}
+	}
+
+	final := merged
+	heuristic := 0
+	if target.fresh != nil && len(merged) == len(original) {
+		heuristic = len(merged)
+	}
+	if len(merged) > maxCapacity {
+		_, candidates := h.appendHeuristicCandidatesFromIDs(vector, merged, nil, make([]util.Candidate, 0, len(merged)))
+		selected := h.neighborSelector.SelectNeighborsOptimizedValues(vector, candidates, target.level, h)
+		final = make([]uint32, 0, min(len(selected), maxCapacity))
+		for _, candidate := range selected[:min(len(selected), maxCapacity)] {
+			final = append(final, candidate.ID)
+		}
+		heuristic = len(final)
+	}
+
+	slice := unsafe.Slice(node.Links[target.level], maxCapacity)
+	for i, linkID := range final {
+		atomic.StoreUint32(&slice[i], linkID)
+	}
+	for i := len(final); i < maxCapacity; i++ {
+		if atomic.LoadUint32(&slice[i]) == SentinelNodeID {
+			break
+		}
+		atomic.StoreUint32(&slice[i], SentinelNodeID)
+	}
+	atomic.StoreUint32(&node.LinkCounts[target.level], uint32(len(final)))
+	atomic.StoreUint32(&node.LinkHeuristic[target.level], uint32(heuristic))
+	if len(final) > maxM {
+		h.markRepairDirty(node, target.level)
+	}
+
+	for _, linkID := range final {
+		if target.fresh != nil || !slices.Contains(original, linkID) {
+			target.added = append(target.added, linkID)
+		}
+	}
+	if target.fresh == nil {
+		for _, linkID := range original {
+			if !slices.Contains(final, linkID) {
+				target.dropped = append(target.dropped, linkID)
+			}
+		}
+	}
+}
+
+// abandonInsert unpublishes a registered node that never joined the graph.
+func (h *Index) abandonInsert(node *Node, id string) {
+	// Unpublish before releasing owned vector and link storage. The node slot
+	// itself remains retired until epoch reclamation is available for readers
+	// that may already have captured its address.
+	h.retireNodeStorage(node.Ordinal, node)
+	if id != "" {
+		h.idToIndex.DeleteString(id)
+		h.ordinalToID.Set(node.Ordinal, "")
+	}
+	h.size.Add(-1)
+}
+
+// parallelEpoch runs fn for every index in [0, n) on up to GOMAXPROCS
+// workers. Callers write results by index, so the outcome does not depend on
+// which worker ran which index.
+func parallelEpoch(n int, fn func(i int)) {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,230 @@
+package hnsw
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+func batchTestConfig() *Config {
+	return &Config{
+		Dimension:      32,
+		M:              12,
+		EfConstruction: 64,
+		EfSearch:       64,
+		ML:             1.0 / math.Log(2.0),
+		Metric:         util.L2Distance,
+		RandomSeed:     7,
+	}
+}
+
+func batchTestEntries(vectors [][]float32) []*VectorEntry {
+	entries := make([]*VectorEntry, len(vectors))
+	for i, vec := range vectors {
+		entries[i] = &VectorEntry{ID: fmt.Sprintf("v%d", i), Vector: vec}
+	}
+	return entries
+}
+
+func buildBatchTestIndex(t *testing.T, vectors [][]float32, procs int) *Index {
+	t.Helper()
+	index, err := NewHNSW(batchTestConfig())
+	if err != nil {
+		t.Fatalf("NewHNSW: %v", err)
+	}
+	previous := runtime.GOMAXPROCS(procs)
+	defer runtime.GOMAXPROCS(previous)
+	if err := index.BatchInsert(context.Background(), batchTestEntries(vectors)); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+	return index
+}
+
+func sortedLinks(links []uint32) []uint32 {
+	links = slices.Clone(links)
+	slices.Sort(links)
+	return links
+}
+
+func TestBatchInsertTopologyIndependentOfWorkers(t *testing.T) {
+	vectors := int8TestVectors(1500, 32, 21)
+	serial := buildBatchTestIndex(t, vectors, 1)
+	defer serial.Close()
+
+	for _, procs := range []int{4, 8} {
+		parallel := buildBatchTestIndex(t, vectors, procs)
+		if parallel.getEntryPoint().Ordinal != serial.getEntryPoint().Ordinal {
+			t.Fatalf("procs=%d entry point %d, want %d", procs, parallel.getEntryPoint().Ordinal, serial.getEntryPoint().Ordinal)
+		}
+		for ordinal := 0; ordinal < serial.nodes.Len(); ordinal++ {
+			want := serial.nodes.Get(uint32(ordinal))
+			got := parallel.nodes.Get(uint32(ordinal))
+			if want == nil || got == nil || want.Level != got.Level {
+				t.Fatalf("procs=%d node %d differs", procs, ordinal)
+			}
+			for level := 0; level <= want.Level; level++ {
+				if !slices.Equal(serial.getNodeLinks(want, level), parallel.getNodeLinks(got, level)) {
+					t.Fatalf("procs=%d node %d level %d links = %v, want %v", procs, ordinal, level,
+						parallel.getNodeLinks(got, level), serial.getNodeLinks(want, level))
+				}
+				if !slices.Equal(sortedLinks(serial.getNodeBacklinks(want, level)), sortedLinks(parallel.getNodeBacklinks(got, level))) {
+					t.Fatalf("procs=%d node %d level %d backlinks differ", procs, ordinal, level)
+				}
+			}
+		}
+		parallel.Close()
+	}
+}
+
+func TestBatchInsertRecallMatchesSequential(t *testing.T) {
+	vectors := int8TestVectors(3000, 32, 23)
+	queries := int8TestVectors(100, 32, 29)
+	truth := bruteForceTruth(vectors, queries, 10)
+	ctx := context.Background()
+
+	sequential, err := NewHNSW(batchTestConfig())
+	if err != nil {
+		t.Fatalf("NewHNSW: %v", err)
+	}
+	defer sequential.Close()
+	for _, entry := range batchTestEntries(vectors) {
+		if err := sequential.Insert(ctx, entry); err != nil {
+			t.Fatalf("Insert: %v", err)
+		}
+	}
+	batched := buildBatchTestIndex(t, vectors, 4)
+	defer batched.Close()
+
+	recall := func(index *Index) float64 {
+		var total float64
+		for i, query := range queries {
+			results, err := index.Search(ctx, query, 10, nil)
+			if err != nil {
+				t.Fatalf("Search %d: %v", i, err)
+			}
+			total += recallAtK(results, truth[i], 10)
+		}
+		return total / float64(len(queries))
+	}
+	want := recall(sequential)
+	got := recall(batched)
+	t.Logf("recall@10 sequential=%.4f batched=%.4f", want, got)
+	if got < 0.95 || got < want-0.01 {
+		t.Fatalf("batched recall %.4f, sequential %.4f", got, want)
+	}
+
+	for ordinal := 0; ordinal < batched.nodes.Len(); ordinal++ {
+		node := batched.nodes.Get(uint32(ordinal))
+		if node == nil || len(batched.getNodeLinks(node, 0)) == 0 {
+			t.Fatalf("node %d has no level-0 links", ordinal)
+		}
+	}
+}
+
+func TestBatchInsertKeepsEntriesBeforeFailure(t *testing.T) {
+	vectors := int8TestVectors(400, 32, 31)
+	entries := batchTestEntries(vectors)
+	entries[300] = &VectorEntry{ID: "v10", Vector: vectors[300]}
+
+	index, err := NewHNSW(batchTestConfig())
+	if err != nil {
+		t.Fatalf("NewHNSW: %v", err)
+	}
+	defer index.Close()
+	ctx := context.Background()
+	err = index.BatchInsert(ctx, entries)
+	var batchErr *util.BatchInsertError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("BatchInsert with a duplicate ID returned %v, want a *util.BatchInsertError", err)
+	}
+	if batchErr.Inserted != 300 {
+		t.Fatalf("Inserted = %d, want 300", batchErr.Inserted)
+	}
+	if got := index.Size(); got != 300 {
+		t.Fatalf("size = %d, want 300", got)
+	}
+	for _, i := range []int{0, 150, 299} {
+		results, err := index.Search(ctx, vectors[i], 1, nil)
+		if err != nil {
+			t.Fatalf("Search %d: %v", i, err)
+		}
+		if len(results) == 0 || results[0].ID != fmt.Sprintf("v%d", i) {
+			t.Fatalf("search v%d returned %v", i, results)
+		}
+	}
+}
+
+func TestBatchInsertConcurrentWithInsert(t *testing.T) {
+	vectors := int8TestVectors(2400, 32, 37)
+	entries := batchTestEntries(vectors)
+	ctx := context.Background()
+
+	index, err := NewHNSW(batchTestConfig())
+	if err != nil {
+		t.Fatalf("NewHNSW: %v", err)
+	}
+	defer index.Close()
+	if err := index.BatchInsert(ctx, entries[:200]); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+
+	// Single inserts land between epochs of two concurrent batches. Neither
+	// may drop links the other added.
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for w := 0; w < 2; w++ {
+		batch := entries[200+w*600 : 200+(w+1)*600]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := index.BatchInsert(ctx, batch); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, entry := range entries[1400:] {
+			if err := index.Insert(ctx, entry); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent insert: %v", err)
+	}
+
+	if got := index.Size(); got != len(entries) {
+		t.Fatalf("size = %d, want %d", got, len(entries))
+	}
+	missed := 0
+	for i, vector := range vectors {
+		results, err := index.Search(ctx, vector, 1, nil)
+		if err != nil {
+			t.Fatalf("Search %d: %v", i, err)
+		}
+		if len(results) == 0 || results[0].ID != entries[i].ID {
+			missed++
+		}
+	}
+	if missed > len(vectors)/100 {
+		t.Fatalf("%d of %d vectors do not find themselves", missed, len(vectors))
+	}
+	for ordinal := 0; ordinal < index.nodes.Len(); ordinal++ {
+		node := index.nodes.Get(uint32(ordinal))
+		if node == nil || len(index.getNodeLinks(node, 0)) == 0 {
+			t.Fatalf("node %d has no level-0 links", ordinal)
+		}
+	}
+}
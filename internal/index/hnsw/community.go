@@ -313,3 +313,19 @@ func DeserializeCommunityRegistry(data []byte) (*CommunityRegistry, error) {
 		NodeToComm: nodeToComm,
 	}, nil
 }
+
+// CommunityOf returns the community of the vector stored under id in the
+// registry installed by SetCommunities. It reports false when no registry is
+// installed or the vector was inserted after the registry was computed.
+func (h *Index) CommunityOf(id string) (uint32, bool) {
+	registry := h.GetCommunities()
+	if registry == nil {
+		return 0, false
+	}
+	node, exists := h.idToIndex.GetString(id)
+	if !exists || node.Ordinal >= uint32(len(registry.NodeToComm)) {
+		return 0, false
+	}
+	community := registry.NodeToComm[node.Ordinal]
+	return community, community != 0
+}
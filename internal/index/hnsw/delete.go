@@ -495,6 +495,9 @@ func (h *Index) retireNodeStorage(nodeID uint32, node *Node) {
 	}
 
 	h.nodes.Set(nodeID, nil)
+	if h.int8Codes != nil {
+		h.int8Codes.clear(nodeID)
+	}
 	for !h.acquirePruneLock(node) {
 		runtime.Gosched()
 	}
@@ -177,6 +177,12 @@ type Index struct {
 	repairOverflow        atomic.Bool
 	reclamation           *reclamationDomain
 	memoryMapped          bool
+	int8Codes             *int8Codes
+	// epochMu orders BatchInsert epochs against single-vector mutations.
+	// An epoch holds it exclusively while it plans against the graph and
+	// rewrites whole adjacency lists; Insert and Delete share it, so an
+	// epoch never overwrites links they add or repair.
+	epochMu sync.RWMutex
 }
 
 // Config holds HNSW configuration parameters
@@ -188,6 +194,7 @@ type Config struct {
 	M                    int
 	EfConstruction       int
 	EfSearch             int
+	RescoreK             int
 	ML                   float64
 	Metric               util.DistanceMetric
 	PruneAlpha           float32
@@ -198,6 +205,10 @@ type Config struct {
 	RandomSeed           int64
 	RawStoreCap          int
 	IDMapCapacity        int
+	// Int8Construction screens beam candidates with 8-bit codes and exact
+	// reconstruction-error bounds, computing FP32 distances only for
+	// candidates the bound cannot reject.
+	Int8Construction bool
 }
 
 func (c *Config) idMapCapacity() uint64 {
@@ -412,6 +423,14 @@ func NewHNSW(config *Config) (*Index, error) {
 	if config.Quantization != nil {
 		index.trainingVectors = make([][]float32, index.getTrainingThreshold())
 	}
+	if config.Int8Construction {
+		codes, err := newInt8Codes(registryPool, config.Dimension)
+		if err != nil {
+			_ = index.Close()
+			return nil, fmt.Errorf("failed to create int8 code store: %w", err)
+		}
+		index.int8Codes = codes
+	}
 
 	// Force allocator growth and initialize the reusable search contexts before
 	// the index is published. Inserts and searches must not pay first-use mmap
@@ -457,6 +476,13 @@ func NewHNSW(config *Config) (*Index, error) {
 }
 
 func (h *Index) Insert(ctx context.Context, entry *VectorEntry) error {
+	h.epochMu.RLock()
+	defer h.epochMu.RUnlock()
+	return h.insert(ctx, entry)
+}
+
+// insert adds one vector. Caller must hold epochMu.
+func (h *Index) insert(ctx context.Context, entry *VectorEntry) error {
 	if h.reclamation != nil {
 		h.reclamation.tryReclaim(h)
 	}
@@ -485,15 +511,7 @@ func (h *Index) Insert(ctx context.Context, entry *VectorEntry) error {
 	}
 
 	if err != nil {
-		// Unpublish before releasing owned vector and link storage. The node slot
-		// itself remains retired until epoch reclamation is available for readers
-		// that may already have captured its address.
-		h.retireNodeStorage(node.Ordinal, node)
-		if entry.ID != "" {
-			h.idToIndex.DeleteString(entry.ID)
-			h.ordinalToID.Set(node.Ordinal, "")
-		}
-		h.size.Add(-1)
+		h.abandonInsert(node, entry.ID)
 	} else {
 		// Update entry point atomically if necessary
 		h.updateEntryPointCAS(node)
@@ -536,6 +554,12 @@ func (h *Index) insertSingleMetadata(ctx context.Context, entry *VectorEntry) (*
 		}
 	}
 
+	if h.int8Codes != nil {
+		if err := h.observeInt8Sample(ctx, entry.Vector); err != nil {
+			return nil, fmt.Errorf("failed to train int8 codes: %w", err)
+		}
+	}
+
 	// Create new node with optimized memory allocation
 	var ordinal uint32
 	if h.provider == nil {
@@ -604,6 +628,11 @@ func (h *Index) insertSingleMetadata(ctx context.Context, entry *VectorEntry) (*
 	if entry.ID != "" {
 		h.ordinalToID.Set(nodeID, entry.ID)
 	}
+	// Encode after publishing the node so a concurrent int8 backfill either
+	// sees the node or this insert sees the trained quantizer.
+	if h.int8Codes != nil {
+		h.encodeInt8(nodeID, entry.Vector)
+	}
 
 	// No entry point candidate list needed anymore, we fall back to O(N) scan.
 
@@ -832,95 +861,6 @@ func (h *Index) releasePruneLock(node *Node) {
 	atomic.StoreUint32(&node.PruneLock, 0)
 }
 
-// BatchInsert provides optimized batch insertion for better performance with large datasets
-func (h *Index) BatchInsert(ctx context.Context, entries []*VectorEntry) error {
-	if len(entries) == 0 {
-		return nil
-	}
-
-	// Use optimized batch processing for all batch sizes
-	return h.batchInsertOptimized(ctx, entries)
-}
-
-// batchInsertOptimized handles large batch insertions with memory optimization
-func (h *Index) batchInsertOptimized(ctx context.Context, entries []*VectorEntry) error {
-	workers := min(runtime.GOMAXPROCS(0), len(entries))
-	if workers <= 1 || len(entries) < 8 {
-		for i, entry := range entries {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			default:
-			}
-			if err := h.Insert(ctx, entry); err != nil {
-				return fmt.Errorf("failed to insert entry at index %d: %w", i, err)
-			}
-		}
-		return nil
-	}
-
-	jobs := make(chan int, workers*2)
-	errCh := make(chan error, 1)
-	var stop atomic.Bool
-	var wg sync.WaitGroup
-
-	for worker := 0; worker < workers; worker++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			for idx := range jobs {
-				if stop.Load() {
-					continue
-				}
-				select {
-				case <-ctx.Done():
-					stop.Store(true)
-					select {
-					case errCh <- ctx.Err():
-					default:
-					}
-					return
-				default:
-				}
-				if err := h.Insert(ctx, entries[idx]); err != nil {
-					stop.Store(true)
-					select {
-					case errCh <- fmt.Errorf("failed to insert entry at index %d: %w", idx, err):
-					default:
-					}
-					return
-				}
-			}
-		}()
-	}
-
-sendLoop:
-	for i := range entries {
-		if stop.Load() {
-			break
-		}
-		select {
-		case <-ctx.Done():
-			stop.Store(true)
-			select {
-			case errCh <- ctx.Err():
-			default:
-			}
-			break sendLoop
-		case jobs <- i:
-		}
-	}
-	close(jobs)
-	wg.Wait()
-
-	select {
-	case err := <-errCh:
-		return err
-	default:
-		return nil
-	}
-}
-
 // Search performs a KNN search using the HNSW algorithm.
 func (h *Index) Search(ctx context.Context, query []float32, k int, filter interface {
 	Test(idx uint64) bool
@@ -959,7 +899,7 @@ func (h *Index) search(ctx context.Context, query []float32, k, efOverride int,
 	qualityFloor := h.config.EfConstruction * 2
 	ef := max(h.config.EfSearch, k, qualityFloor, efOverride)
 	if h.quantizer != nil {
-		ef = max(ef, min(int(h.size.Load()), h.config.EfConstruction*2))
+		ef = max(ef, min(int(h.size.Load()), h.config.EfConstruction*2), h.config.RescoreK)
 	}
 	var scratch *searchScratch
 	if filter != nil {
@@ -979,6 +919,8 @@ func (h *Index) search(ctx context.Context, query []float32, k, efOverride int,
 	var queryState any
 	if h.quantizer != nil {
 		queryState = h.quantizer.PrepareQuery(query)
+	} else if q := h.prepareInt8Query(query); q != nil {
+		queryState = q
 	}
 
 	// Phase 1: Search from top level to level 1
@@ -1001,7 +943,7 @@ func (h *Index) search(ctx context.Context, query []float32, k, efOverride int,
 	if err != nil {
 		return nil, err
 	}
-	h.rerankSearchCandidateValues(query, candidates)
+	candidates = h.rerankSearchCandidateValues(query, candidates, k, filter)
 
 	// Convert to results, admitting only matching candidates. Rejected nodes
 	// remain part of graph traversal above so sparse, uncorrelated filters do
@@ -1142,9 +1084,19 @@ func (h *Index) rerankSearchCandidates(query []float32, candidates []*util.Candi
 	slices.SortFunc(candidates, compareCandidatePtrs)
 }
 
-func (h *Index) rerankSearchCandidateValues(query []float32, candidates []util.Candidate) {
+// rerankSearchCandidateValues replaces approximate quantized distances with
+// exact distances from full-precision vectors. When RescoreK is set only the
+// best RescoreK matching candidates (never fewer than k) in approximate order
+// are rescored; the approximate tail is dropped instead of being compared
+// against exact distances.
+func (h *Index) rerankSearchCandidateValues(query []float32, candidates []util.Candidate, k int, filter interface {
+	Test(idx uint64) bool
+}) []util.Candidate {
 	if h.quantizer == nil || len(candidates) == 0 {
-		return
+		return candidates
+	}
+	if h.config.RescoreK > 0 {
+		candidates = h.rescoreWindow(candidates, max(k, h.config.RescoreK), filter)
 	}
 	for i := range candidates {
 		node := h.nodes.Get(candidates[i].ID)
@@ -1158,6 +1110,29 @@ func (h *Index) rerankSearchCandidateValues(query []float32, candidates []util.C
 		candidates[i].Distance = h.distance(query, vec)
 	}
 	slices.SortFunc(candidates, compareCandidateValues)
+	return candidates
+}
+
+// rescoreWindow keeps the first window candidates that pass filter, reusing
+// the candidates backing array.
+func (h *Index) rescoreWindow(candidates []util.Candidate, window int, filter interface {
+	Test(idx uint64) bool
+}) []util.Candidate {
+	if filter == nil {
+		return candidates[:min(window, len(candidates))]
+	}
+	kept := candidates[:0]
+	for _, candidate := range candidates {
+		if len(kept) == window {
+			break
+		}
+		node := h.nodes.Get(candidate.ID)
+		if node == nil || !filter.Test(uint64(node.Ordinal)) {
+			continue
+		}
+		kept = append(kept, candidate)
+	}
+	return kept
 }
 
 // Size returns the number of vectors in the index
@@ -1297,6 +1272,10 @@ func (h *Index) Close() error {
 		_ = h.ordinalToID.Close()
 		h.ordinalToID = nil
 	}
+	if h.int8Codes != nil {
+		h.int8Codes.close()
+		h.int8Codes = nil
+	}
 	if h.registryPool != nil {
 		h.registryPool.Free()
 		h.registryPool = nil
@@ -1387,6 +1366,17 @@ func (c *Config) validate() error {
 	if c.RepairBatchSize < 0 {
 		return fmt.Errorf("RepairBatchSize must be non-negative")
 	}
+	if c.RescoreK < 0 {
+		return fmt.Errorf("RescoreK must be non-negative")
+	}
+	if c.Int8Construction {
+		if c.Quantization != nil {
+			return fmt.Errorf("Int8Construction cannot be combined with quantization")
+		}
+		if c.Metric != util.L2Distance {
+			return fmt.Errorf("Int8Construction requires the L2 metric")
+		}
+	}
 
 	// Validate quantization config if present
 	if c.Quantization != nil {
@@ -1634,6 +1624,8 @@ func (h *Index) computeDistance(vec1, vec2 []float32, node1, node2 *Node) (float
 }
 
 func (h *Index) Delete(ctx context.Context, id string) error {
+	h.epochMu.RLock()
+	defer h.epochMu.RUnlock()
 	if h.reclamation != nil {
 		h.reclamation.tryReclaim(h)
 	}
@@ -1647,6 +1639,8 @@ func (h *Index) Delete(ctx context.Context, id string) error {
 }
 
 func (h *Index) DeleteByOrdinal(ctx context.Context, ordinal uint32) error {
+	h.epochMu.RLock()
+	defer h.epochMu.RUnlock()
 	if h.reclamation != nil {
 		h.reclamation.tryReclaim(h)
 	}
@@ -606,3 +606,48 @@ func TestComputeCommunitiesTotalMapping(t *testing.T) {
 		}
 	}
 }
+
+func TestCommunityOfResolvesIDs(t *testing.T) {
+	ctx := context.Background()
+	index, err := NewHNSW(&Config{
+		Dimension:      4,
+		M:              4,
+		EfConstruction: 16,
+		EfSearch:       8,
+		ML:             1,
+		Metric:         util.L2Distance,
+		RandomSeed:     7,
+	})
+	if err != nil {
+		t.Fatalf("new hnsw: %v", err)
+	}
+	defer index.Close()
+
+	for i := 0; i < 8; i++ {
+		vec := []float32{float32(i % 2), float32(i), 1, 0}
+		if err := index.Insert(ctx, &VectorEntry{ID: fmt.Sprintf("vec_%d", i), Vector: vec}); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	if _, ok := index.CommunityOf("vec_3"); ok {
+		t.Fatal("CommunityOf reported a community before SetCommunities")
+	}
+
+	registry, err := index.ComputeCommunities(ctx, 1000)
+	if err != nil {
+		t.Fatalf("ComputeCommunities: %v", err)
+	}
+	index.SetCommunities(registry)
+	for i := 0; i < 8; i++ {
+		id := fmt.Sprintf("vec_%d", i)
+		node, _ := index.idToIndex.GetString(id)
+		want := registry.NodeToComm[node.Ordinal]
+		got, ok := index.CommunityOf(id)
+		if ok != (want != 0) || got != want {
+			t.Fatalf("CommunityOf(%s) = %d, %v; want %d", id, got, ok, want)
+		}
+	}
+	if _, ok := index.CommunityOf("missing"); ok {
+		t.Fatal("CommunityOf reported a community for an unknown ID")
+	}
+}
@@ -38,6 +38,8 @@ func (h *Index) insertNode(ctx context.Context, node *Node, nodeID uint32, searc
 	var queryState any
 	if h.quantizer != nil {
 		queryState = h.quantizer.PrepareQuery(searchVector)
+	} else if q := h.prepareInt8Query(searchVector); q != nil {
+		queryState = q
 	}
 
 	maxLevel := h.getMaxLevel()
@@ -0,0 +1,358 @@
+package hnsw
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/xDarkicex/libravdb/internal/quant"
+	"github.com/xDarkicex/libravdb/internal/util/simd"
+	"github.com/xDarkicex/memory"
+)
+
+// Int8 construction keeps an 8-bit scalar code and its exact reconstruction
+// error for every node. The quantizer is trained with one shared range, so
+// every dimension reconstructs as offset + step*code and the code distance
+// e = step*||c(q)-c(x)|| equals ||q̂-x̂||. The triangle inequality then gives
+//
+//	||q-x|| >= e - ε_q - ε_x,  ε = ||v-v̂||
+//
+// A beam candidate whose bound already exceeds the worst kept distance could
+// never be admitted, so it is rejected without reading its FP32 vector. Every
+// other candidate falls back to the exact distance, which keeps the graph and
+// the results identical to an FP32-only build.
+
+const (
+	int8MinTrainingSample = 256
+
+	// int8BoundSlack shrinks the squared lower bound to absorb float32
+	// rounding in the exact distance kernels, so a rejection never depends
+	// on the last few ulps of a borderline candidate.
+	int8BoundSlack = 1e-3
+)
+
+// int8Code is the header of an off-heap code record; the code bytes follow
+// it directly.
+type int8Code struct {
+	residual float32
+	_        uint32
+}
+
+const int8CodeHeaderSize = unsafe.Sizeof(int8Code{})
+
+func (c *int8Code) bytes(dimension int) []byte {
+	return unsafe.Slice((*byte)(unsafe.Add(unsafe.Pointer(c), int8CodeHeaderSize)), dimension)
+}
+
+type int8CodeChunk [chunkSize]atomic.Pointer[int8Code]
+type int8CodeDirectory [maxChunks]atomic.Pointer[int8CodeChunk]
+
+type int8Model struct {
+	quantizer *quant.ScalarQuantizer
+	offset    float64
+	step      float64
+}
+
+// int8Codes stores per-node codes off-heap in the index registry pool. Code
+// records are immutable once published; a re-encoded or deleted node swaps
+// its pointer and the old record stays valid until the pool is freed.
+type int8Codes struct {
+	directory *int8CodeDirectory
+	pool      *memory.Pool
+	dimension int
+	model     atomic.Pointer[int8Model]
+
+	sampleMu sync.Mutex
+	sample   [][]float32
+
+	checks atomic.Int64
+	pruned atomic.Int64
+}
+
+// int8Query is the query state used when int8 construction is enabled and
+// no quantizer owns the query state.
+type int8Query struct {
+	model    *int8Model
+	code     []byte
+	residual float64
+}
+
+// Int8ConstructionStats reports how often int8 bounds settled a beam
+// candidate. Checks counts candidates screened once the beam was full;
+// Fallbacks are the checks that still needed an FP32 distance.
+type Int8ConstructionStats struct {
+	Trained      bool
+	Checks       int64
+	Pruned       int64
+	Fallbacks    int64
+	FallbackRate float64
+}
+
+func newInt8Codes(pool *memory.Pool, dimension int) (*int8Codes, error) {
+	directory, err := memory.PoolAlloc[int8CodeDirectory](pool)
+	if err != nil {
+		return nil, err
+	}
+	return &int8Codes{directory: directory, pool: pool, dimension: dimension}, nil
+}
+
+func (c *int8Codes) sampleSize() int {
+	return max(int8MinTrainingSample, c.dimension*2)
+}
+
+func (c *int8Codes) get(id uint32) *int8Code {
+	chunkIdx := id >> chunkSizeBits
+	if c == nil || c.directory == nil || chunkIdx >= maxChunks {
+		return nil
+	}
+	chunk := c.directory[chunkIdx].Load()
+	if chunk == nil {
+		return nil
+	}
+	return chunk[id&chunkMask].Load()
+}
+
+func (c *int8Codes) set(id uint32, code []byte, residual float32) error {
+	chunkIdx := id >> chunkSizeBits
+	if c.directory == nil || chunkIdx >= maxChunks {
+		return fmt.Errorf("int8 code store: ordinal %d out of range or closed", id)
+	}
+	chunk := c.directory[chunkIdx].Load()
+	if chunk == nil {
+		newChunk, err := memory.PoolAlloc[int8CodeChunk](c.pool)
+		if err != nil {
+			return fmt.Errorf("allocate int8 code chunk: %w", err)
+		}
+		if c.directory[chunkIdx].CompareAndSwap(nil, newChunk) {
+			chunk = newChunk
+		} else {
+			chunk = c.directory[chunkIdx].Load()
+		}
+	}
+
+	data, err := c.pool.Allocate(uint64(int8CodeHeaderSize) + uint64(c.dimension))
+	if err != nil {
+		return fmt.Errorf("allocate int8 code: %w", err)
+	}
+	record := (*int8Code)(unsafe.Pointer(&data[0]))
+	record.residual = residual
+	copy(record.bytes(c.dimension), code)
+	chunk[id&chunkMask].Store(record)
+	return nil
+}
+
+func (c *int8Codes) clear(id uint32) {
+	chunkIdx := id >> chunkSizeBits
+	if c.directory == nil || chunkIdx >= maxChunks {
+		return
+	}
+	if chunk := c.directory[chunkIdx].Load(); chunk != nil {
+		chunk[id&chunkMask].Store(nil)
+	}
+}
+
+func (c *int8Codes) reset() {
+	c.model.Store(nil)
+	c.sample = nil
+	if c.directory == nil {
+		return
+	}
+	for i := range c.directory {
+		c.directory[i].Store(nil)
+	}
+}
+
+func (c *int8Codes) close() {
+	c.model.Store(nil)
+	c.sample = nil
+	c.directory = nil
+}
+
+// encode returns the code for vector and its reconstruction error, rounded
+// up so the stored float32 never understates the true error.
+func (m *int8Model) encode(vector []float32) ([]byte, float32, error) {
+	code, err := m.quantizer.Compress(vector)
+	if err != nil {
+		return nil, 0, err
+	}
+	var sum float64
+	for i, v := range vector {
+		diff := float64(v) - (m.offset + m.step*float64(code[i]))
+		sum += diff * diff
+	}
+	residual := math.Sqrt(sum)
+	stored := float32(residual)
+	if float64(stored) < residual {
+		stored = math.Nextafter32(stored, float32(math.Inf(1)))
+	}
+	return code, stored, nil
+}
+
+// observeInt8Sample collects training vectors until the sample is large
+// enough, then trains the shared-range quantizer and backfills every node
+// inserted so far.
+func (h *Index) observeInt8Sample(ctx context.Context, vector []float32) error {
+	codes := h.int8Codes
+	if codes.model.Load() != nil {
+		return nil
+	}
+	codes.sampleMu.Lock()
+	defer codes.sampleMu.Unlock()
+	if codes.model.Load() != nil {
+		return nil
+	}
+	codes.sample = append(codes.sample, slices.Clone(vector))
+	if len(codes.sample) < codes.sampleSize() {
+		return nil
+	}
+	if err := h.trainInt8(ctx, codes.sample); err != nil {
+		return err
+	}
+	codes.sample = nil
+	return nil
+}
+
+func (h *Index) trainInt8(ctx context.Context, sample [][]float32) error {
+	quantizer := quant.NewScalarQuantizer()
+	if err := quantizer.Configure(&quant.QuantizationConfig{
+		Type:        quant.ScalarQuantization,
+		Bits:        8,
+		TrainRatio:  1,
+		SharedRange: true,
+	}); err != nil {
+		return err
+	}
+	if err := quantizer.Train(ctx, sample); err != nil {
+		return err
+	}
+	offset, step, ok := quantizer.UniformRange()
+	if !ok {
+		return fmt.Errorf("int8 quantizer did not train a shared range")
+	}
+	h.int8Codes.model.Store(&int8Model{
+		quantizer: quantizer,
+		offset:    float64(offset),
+		step:      float64(step),
+	})
+
+	for i := 0; i < h.nodes.Len(); i++ {
+		node := h.nodes.Get(uint32(i))
+		if node == nil {
+			continue
+		}
+		if vec, err := h.getNodeVectorLocal(node); err == nil {
+			h.encodeInt8(uint32(i), vec)
+		}
+	}
+	return nil
+}
+
+// encodeInt8 stores the code for a published node. Codes only accelerate
+// candidate screening, so a node that cannot be encoded simply keeps using
+// FP32 distances.
+func (h *Index) encodeInt8(nodeID uint32, vector []float32) {
+	model := h.int8Codes.model.Load()
+	if model == nil {
+		return
+	}
+	code, residual, err := model.encode(vector)
+	if err != nil {
+		return
+	}
+	_ = h.int8Codes.set(nodeID, code, residual)
+}
+
+// rebuildInt8Codes retrains and re-encodes codes from the restored vectors
+// after the graph has been loaded.
+func (h *Index) rebuildInt8Codes() error {
+	codes := h.int8Codes
+	if codes == nil {
+		return nil
+	}
+	codes.sampleMu.Lock()
+	defer codes.sampleMu.Unlock()
+	codes.reset()
+
+	target := codes.sampleSize()
+	sample := make([][]float32, 0, target)
+	for i := 0; i < h.nodes.Len() && len(sample) < target; i++ {
+		node := h.nodes.Get(uint32(i))
+		if node == nil {
+			continue
+		}
+		if vec, err := h.getNodeVectorLocal(node); err == nil {
+			sample = append(sample, slices.Clone(vec))
+		}
+	}
+	if len(sample) < target {
+		codes.sample = sample
+		return nil
+	}
+	if err := h.trainInt8(context.Background(), sample); err != nil {
+		return fmt.Errorf("failed to rebuild int8 codes: %w", err)
+	}
+	return nil
+}
+
+func (h *Index) prepareInt8Query(query []float32) *int8Query {
+	if h.int8Codes == nil {
+		return nil
+	}
+	model := h.int8Codes.model.Load()
+	if model == nil {
+		return nil
+	}
+	code, residual, err := model.encode(query)
+	if err != nil {
+		return nil
+	}
+	return &int8Query{model: model, code: code, residual: float64(residual)}
+}
+
+// int8Rejects reports whether the int8 lower bound proves that the exact
+// distance from q to node id exceeds cutoff.
+func (h *Index) int8Rejects(q *int8Query, id uint32, cutoff float32) bool {
+	record := h.int8Codes.get(id)
+	if record == nil {
+		return false
+	}
+	ssd := simd.L2SquaredUint8(q.code, record.bytes(len(q.code)))
+	lower := q.model.step*math.Sqrt(float64(ssd)) - q.residual - float64(record.residual)
+	if lower <= 0 {
+		return false
+	}
+	return float32(lower*lower*(1-int8BoundSlack)) > cutoff
+}
+
+func (h *Index) recordInt8Checks(checks, pruned int64) {
+	if checks == 0 {
+		return
+	}
+	h.int8Codes.checks.Add(checks)
+	h.int8Codes.pruned.Add(pruned)
+}
+
+// Int8ConstructionStats returns int8 screening counters and false when int8
+// construction is disabled.
+func (h *Index) Int8ConstructionStats() (Int8ConstructionStats, bool) {
+	codes := h.int8Codes
+	if codes == nil {
+		return Int8ConstructionStats{}, false
+	}
+	checks := codes.checks.Load()
+	pruned := codes.pruned.Load()
+	stats := Int8ConstructionStats{
+		Trained:   codes.model.Load() != nil,
+		Checks:    checks,
+		Pruned:    pruned,
+		Fallbacks: checks - pruned,
+	}
+	if checks > 0 {
+		stats.FallbackRate = float64(stats.Fallbacks) / float64(checks)
+	}
+	return stats, true
+}
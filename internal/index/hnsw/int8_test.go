@@ -0,0 +1,189 @@
+package hnsw
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/quant"
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+func int8TestVectors(count, dimension int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	centers := make([][]float32, 16)
+	for i := range centers {
+		centers[i] = make([]float32, dimension)
+		for d := range centers[i] {
+			centers[i][d] = float32(rng.NormFloat64())
+		}
+	}
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		center := centers[rng.Intn(len(centers))]
+		vec := make([]float32, dimension)
+		for d := range vec {
+			vec[d] = center[d] + float32(rng.NormFloat64())*0.3
+		}
+		vectors[i] = vec
+	}
+	return vectors
+}
+
+func int8TestConfig(int8Construction bool) *Config {
+	return &Config{
+		Dimension:        32,
+		M:                12,
+		EfConstruction:   64,
+		EfSearch:         48,
+		ML:               1.0 / math.Log(2.0),
+		Metric:           util.L2Distance,
+		RandomSeed:       7,
+		Int8Construction: int8Construction,
+	}
+}
+
+func buildInt8TestIndex(t *testing.T, config *Config, vectors [][]float32) *Index {
+	t.Helper()
+	index, err := NewHNSW(config)
+	if err != nil {
+		t.Fatalf("NewHNSW: %v", err)
+	}
+	ctx := context.Background()
+	for i, vec := range vectors {
+		if err := index.Insert(ctx, &VectorEntry{ID: fmt.Sprintf("v%d", i), Vector: vec}); err != nil {
+			t.Fatalf("Insert %d: %v", i, err)
+		}
+	}
+	return index
+}
+
+func TestInt8ConstructionMatchesFP32Graph(t *testing.T) {
+	vectors := int8TestVectors(1500, 32, 3)
+	fp32 := buildInt8TestIndex(t, int8TestConfig(false), vectors)
+	defer fp32.Close()
+	coded := buildInt8TestIndex(t, int8TestConfig(true), vectors)
+	defer coded.Close()
+
+	for ordinal := 0; ordinal < fp32.nodes.Len(); ordinal++ {
+		want := fp32.nodes.Get(uint32(ordinal))
+		got := coded.nodes.Get(uint32(ordinal))
+		if want == nil || got == nil || want.Level != got.Level {
+			t.Fatalf("node %d differs: fp32=%v int8=%v", ordinal, want != nil, got != nil)
+		}
+		for level := 0; level <= want.Level; level++ {
+			if !slices.Equal(fp32.getNodeLinks(want, level), coded.getNodeLinks(got, level)) {
+				t.Fatalf("node %d level %d links differ: fp32=%v int8=%v", ordinal, level,
+					fp32.getNodeLinks(want, level), coded.getNodeLinks(got, level))
+			}
+		}
+	}
+
+	ctx := context.Background()
+	queries := int8TestVectors(40, 32, 11)
+	for i, query := range queries {
+		want, err := fp32.Search(ctx, query, 10, nil)
+		if err != nil {
+			t.Fatalf("fp32 search %d: %v", i, err)
+		}
+		got, err := coded.Search(ctx, query, 10, nil)
+		if err != nil {
+			t.Fatalf("int8 search %d: %v", i, err)
+		}
+		if len(got) != len(want) {
+			t.Fatalf("query %d returned %d results, want %d", i, len(got), len(want))
+		}
+		for j := range want {
+			if got[j].ID != want[j].ID || got[j].Score != want[j].Score {
+				t.Fatalf("query %d rank %d = %s/%v, want %s/%v", i, j, got[j].ID, got[j].Score, want[j].ID, want[j].Score)
+			}
+		}
+	}
+
+	stats, ok := coded.Int8ConstructionStats()
+	if !ok || !stats.Trained {
+		t.Fatalf("int8 stats = %+v, %v", stats, ok)
+	}
+	if stats.Checks == 0 || stats.Pruned == 0 || stats.Pruned+stats.Fallbacks != stats.Checks {
+		t.Fatalf("int8 stats did not record screening: %+v", stats)
+	}
+	t.Logf("int8 screening: %+v", stats)
+	if stats.FallbackRate <= 0 || stats.FallbackRate >= 1 {
+		t.Fatalf("fallback rate = %v", stats.FallbackRate)
+	}
+	if _, ok := fp32.Int8ConstructionStats(); ok {
+		t.Fatal("fp32 index reported int8 stats")
+	}
+}
+
+func TestInt8LowerBoundNeverExceedsExactDistance(t *testing.T) {
+	vectors := int8TestVectors(600, 32, 5)
+	index := buildInt8TestIndex(t, int8TestConfig(true), vectors)
+	defer index.Close()
+
+	for _, query := range int8TestVectors(20, 32, 9) {
+		q := index.prepareInt8Query(query)
+		if q == nil {
+			t.Fatal("int8 query state unavailable after training")
+		}
+		for ordinal, vec := range vectors {
+			exact := index.distance(query, vec)
+			if index.int8Rejects(q, uint32(ordinal), exact) {
+				t.Fatalf("bound rejected ordinal %d at its exact distance %v", ordinal, exact)
+			}
+		}
+	}
+}
+
+func TestInt8CodesRebuiltAfterDeserialize(t *testing.T) {
+	vectors := int8TestVectors(800, 32, 13)
+	index := buildInt8TestIndex(t, int8TestConfig(true), vectors)
+	defer index.Close()
+
+	data, err := index.SerializeToBytes()
+	if err != nil {
+		t.Fatalf("SerializeToBytes: %v", err)
+	}
+	restored, err := NewHNSW(int8TestConfig(true))
+	if err != nil {
+		t.Fatalf("NewHNSW: %v", err)
+	}
+	defer restored.Close()
+	ctx := context.Background()
+	if err := restored.DeserializeFromBytes(ctx, data); err != nil {
+		t.Fatalf("DeserializeFromBytes: %v", err)
+	}
+	if stats, ok := restored.Int8ConstructionStats(); !ok || !stats.Trained {
+		t.Fatalf("restored int8 stats = %+v, %v", stats, ok)
+	}
+	for ordinal := range vectors {
+		if restored.int8Codes.get(uint32(ordinal)) == nil {
+			t.Fatalf("ordinal %d has no int8 code after restore", ordinal)
+		}
+	}
+
+	query := vectors[17]
+	results, err := restored.Search(ctx, query, 5, nil)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(results) == 0 || results[0].ID != "v17" {
+		t.Fatalf("restored search returned %v", results)
+	}
+}
+
+func TestInt8ConstructionRejectsIncompatibleConfig(t *testing.T) {
+	quantized := int8TestConfig(true)
+	quantized.Quantization = quant.DefaultConfig(quant.ScalarQuantization)
+	if _, err := NewHNSW(quantized); err == nil {
+		t.Fatal("int8 construction accepted a quantized index")
+	}
+	cosine := int8TestConfig(true)
+	cosine.Metric = util.CosineDistance
+	if _, err := NewHNSW(cosine); err == nil {
+		t.Fatal("int8 construction accepted the cosine metric")
+	}
+}
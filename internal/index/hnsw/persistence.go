@@ -736,5 +736,6 @@ func (h *Index) rebuildIndexState() error {
 		}
 	}
 
-	return nil
+	// Int8 codes are derived state; retrain them from the restored vectors.
+	return h.rebuildInt8Codes()
 }
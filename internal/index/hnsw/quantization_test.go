@@ -1,9 +1,12 @@
 package hnsw
 
 import (
+	"cmp"
 	"context"
 	"fmt"
 	"math"
+	"math/rand"
+	"slices"
 	"testing"
 
 	"github.com/xDarkicex/libravdb/internal/quant"
@@ -197,6 +200,84 @@ func TestHNSWWithQuantization(t *testing.T) {
 		}
 	})
 
+	t.Run("Binary Quantization With Rescoring", func(t *testing.T) {
+		config := &Config{
+			Dimension:      128,
+			M:              16,
+			EfConstruction: 100,
+			EfSearch:       50,
+			RescoreK:       64,
+			ML:             1.0 / math.Log(2.0),
+			Metric:         util.L2Distance,
+			RandomSeed:     42,
+			Quantization:   quant.DefaultConfig(quant.BinaryQuantization),
+		}
+
+		index, err := NewHNSW(config)
+		if err != nil {
+			t.Fatalf("Failed to create HNSW index: %v", err)
+		}
+		defer index.Close()
+
+		rng := rand.New(rand.NewSource(7))
+		centers := generateTestVectors(16, 128)
+		vectors := make([][]float32, 600)
+		for i := range vectors {
+			vec := make([]float32, 128)
+			for j, c := range centers[i%len(centers)] {
+				vec[j] = c + float32(rng.NormFloat64())*0.03
+			}
+			vectors[i] = vec
+			entry := &VectorEntry{ID: fmt.Sprintf("bq_vec_%d", i), Vector: vec}
+			if err := index.Insert(ctx, entry); err != nil {
+				t.Fatalf("Failed to insert vector %d: %v", i, err)
+			}
+		}
+
+		if !index.quantizationTrained.Load() {
+			t.Fatal("Binary quantizer should be marked trained")
+		}
+
+		const k = 10
+		hits := 0
+		for q := 0; q < 20; q++ {
+			query := vectors[q*29]
+			exact := make([]int, len(vectors))
+			for i := range exact {
+				exact[i] = i
+			}
+			slices.SortFunc(exact, func(a, b int) int {
+				return cmp.Compare(util.L2Distance_func(query, vectors[a]), util.L2Distance_func(query, vectors[b]))
+			})
+			truth := make(map[string]bool, k)
+			for _, i := range exact[:k] {
+				truth[fmt.Sprintf("bq_vec_%d", i)] = true
+			}
+
+			results, err := index.Search(ctx, query, k, nil)
+			if err != nil {
+				t.Fatalf("Search failed: %v", err)
+			}
+			if len(results) != k {
+				t.Fatalf("Expected %d results, got %d", k, len(results))
+			}
+			if want := fmt.Sprintf("bq_vec_%d", q*29); results[0].ID != want {
+				t.Fatalf("Expected first result to be %s, got %s", want, results[0].ID)
+			}
+			for i, result := range results {
+				if i > 0 && results[i-1].Score > result.Score {
+					t.Fatalf("Results not sorted by rescored distance: %f > %f", results[i-1].Score, result.Score)
+				}
+				if truth[result.ID] {
+					hits++
+				}
+			}
+		}
+		if recall := float64(hits) / float64(20*k); recall < 0.7 {
+			t.Fatalf("recall@%d = %.2f, want >= 0.7", k, recall)
+		}
+	})
+
 	t.Run("Quantization Training Threshold", func(t *testing.T) {
 		config := &Config{
 			Dimension:      32,
@@ -909,6 +909,14 @@ func (h *Index) greedySearchLevelValue(ctx context.Context, query []float32, ent
 	}
 	visited[currentID] = visitMark
 
+	// A neighbor whose int8 lower bound exceeds the current distance cannot
+	// improve the descent.
+	int8q, _ := queryState.(*int8Query)
+	if h.quantizer != nil {
+		int8q = nil
+	}
+	var int8Checks, int8Pruned int64
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -927,6 +935,13 @@ func (h *Index) greedySearchLevelValue(ctx context.Context, query []float32, ent
 				continue
 			}
 			visited[neighborID] = visitMark
+			if int8q != nil {
+				int8Checks++
+				if h.int8Rejects(int8q, neighborID, currentDistance) {
+					int8Pruned++
+					continue
+				}
+			}
 			neighborNode := h.nodes.Get(neighborID)
 			if neighborNode == nil {
 				continue
@@ -950,6 +965,13 @@ func (h *Index) greedySearchLevelValue(ctx context.Context, query []float32, ent
 				continue
 			}
 			visited[neighborID] = visitMark
+			if int8q != nil {
+				int8Checks++
+				if h.int8Rejects(int8q, neighborID, currentDistance) {
+					int8Pruned++
+					continue
+				}
+			}
 			neighborNode := h.nodes.Get(neighborID)
 			if neighborNode == nil {
 				continue
@@ -972,6 +994,9 @@ func (h *Index) greedySearchLevelValue(ctx context.Context, query []float32, ent
 			break
 		}
 	}
+	if int8q != nil {
+		h.recordInt8Checks(int8Checks, int8Pruned)
+	}
 
 	return util.Candidate{
 		ID:       currentID,
@@ -1137,8 +1162,16 @@ func (h *Index) searchLevelScratchValues(ctx context.Context, query []float32, e
 	if ctx != nil {
 		done = ctx.Done()
 	}
+	// Int8 bounds only reject candidates the full beam would refuse anyway,
+	// which does not hold for the separate filtered result heap.
+	int8q, _ := queryState.(*int8Query)
+	if filteredSearch || h.quantizer != nil {
+		int8q = nil
+	}
+	var int8Checks, int8Pruned int64
+	communityPrune := isQuery && h.communities != nil
 
-	if isQuery && h.communities != nil {
+	if communityPrune {
 		communities := h.communities
 
 		maxCommID := uint32(0)
@@ -1255,7 +1288,7 @@ func (h *Index) searchLevelScratchValues(ctx context.Context, query []float32, e
 
 			var worstDist float32
 			var canPrune bool
-			if isQuery && h.communities != nil {
+			if communityPrune || int8q != nil {
 				if soaMode {
 					if soaCandidates.size >= ef {
 						worstDist = soaCandidates.Worst().Distance
@@ -1288,7 +1321,7 @@ func (h *Index) searchLevelScratchValues(ctx context.Context, query []float32, e
 				if neighborID >= uint32(len(visited)) || neighborID == SentinelNodeID || visited[neighborID] == visitMark || len(scratch.prefetchedIDs) >= cap(scratch.prefetchedIDs) {
 					return false
 				}
-				if applyCommunityPrune && canPrune {
+				if applyCommunityPrune && communityPrune && canPrune {
 					if commID := h.communities.NodeToComm[neighborID]; commID < uint32(len(scratch.commLowerBounds)) {
 						cb := scratch.commLowerBounds[commID]
 						if cb.gen == scratch.commGen && cb.bound > worstDist {
@@ -1296,6 +1329,14 @@ func (h *Index) searchLevelScratchValues(ctx context.Context, query []float32, e
 						}
 					}
 				}
+				if int8q != nil && canPrune {
+					int8Checks++
+					if h.int8Rejects(int8q, neighborID, worstDist) {
+						visited[neighborID] = visitMark
+						int8Pruned++
+						return false
+					}
+				}
 
 				node := h.nodes.Get(neighborID)
 				if node == nil {
@@ -1536,6 +1577,9 @@ func (h *Index) searchLevelScratchValues(ctx context.Context, query []float32, e
 			}
 		}
 	}
+	if int8q != nil {
+		h.recordInt8Checks(int8Checks, int8Pruned)
+	}
 	if filteredSearch {
 		return filteredCandidates.items, nil
 	}
@@ -149,6 +149,9 @@ func semanticExactTruth(vectors, queries [][]float32, k int) [][]int {
 //	LIBRAVDB_SEMANTIC_FIXTURE=/path/to/nomic-longmemeval-50k.semantic.f32 \
 //	  go test ./internal/index/hnsw -run '^$' -bench BenchmarkHNSWSemanticScale \
 //	  -benchtime=1x -count=1
+//
+// Set LIBRAVDB_SEMANTIC_BATCH=1 to build through the epoch-batched BatchInsert
+// path with GOMAXPROCS set to the worker count.
 func BenchmarkHNSWSemanticScale(b *testing.B) {
 	fixturePath := os.Getenv("LIBRAVDB_SEMANTIC_FIXTURE")
 	if fixturePath == "" {
@@ -170,6 +173,14 @@ func BenchmarkHNSWSemanticScale(b *testing.B) {
 	truth := semanticExactTruth(fixture.vectors, fixture.queries, semanticFixtureK)
 	b.Logf("exact truth completed in %s", time.Since(truthStarted))
 	repairFlush := os.Getenv("LIBRAVDB_SEMANTIC_REPAIR") == "1"
+	batchInsert := os.Getenv("LIBRAVDB_SEMANTIC_BATCH") == "1"
+	var batchEntries []*VectorEntry
+	if batchInsert {
+		batchEntries = make([]*VectorEntry, len(entries))
+		for i := range entries {
+			batchEntries[i] = &entries[i]
+		}
+	}
 	graphM := 36
 	if value := os.Getenv("LIBRAVDB_SEMANTIC_M"); value != "" {
 		parsed, err := strconv.Atoi(value)
@@ -237,7 +248,7 @@ func BenchmarkHNSWSemanticScale(b *testing.B) {
 				var wg sync.WaitGroup
 				errCh := make(chan error, workers)
 				start := make(chan struct{})
-				for worker := 0; worker < workers; worker++ {
+				for worker := 0; worker < workers && !batchInsert; worker++ {
 					wg.Add(1)
 					go func() {
 						defer wg.Done()
@@ -268,9 +279,15 @@ func BenchmarkHNSWSemanticScale(b *testing.B) {
 						b.Fatalf("serial prefix insert %d: %v", i, err)
 					}
 				}
-				next.Store(uint64(serialPrefix))
-				close(start)
-				wg.Wait()
+				if batchInsert {
+					if err := index.BatchInsert(ctx, batchEntries[serialPrefix:]); err != nil {
+						errCh <- fmt.Errorf("batch insert: %w", err)
+					}
+				} else {
+					next.Store(uint64(serialPrefix))
+					close(start)
+					wg.Wait()
+				}
 				if repairFlush {
 					totalRepairs += index.FlushRepairs(0)
 				}
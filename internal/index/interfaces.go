@@ -6,10 +6,12 @@ import (
 	"errors"
 	"fmt"
 	"hash/crc32"
+	"io"
 	"os"
 	"time"
 
 	"github.com/xDarkicex/libravdb/internal/index/btree"
+	"github.com/xDarkicex/libravdb/internal/index/diskann"
 	"github.com/xDarkicex/libravdb/internal/index/flat"
 	"github.com/xDarkicex/libravdb/internal/index/hnsw"
 	"github.com/xDarkicex/libravdb/internal/index/ivfpq"
@@ -98,6 +100,7 @@ const (
 	IndexTypeIVFPQ
 	IndexTypeFlat
 	IndexTypeBTree
+	IndexTypeDiskANN
 )
 
 // String returns the string representation of the index type
@@ -111,6 +114,8 @@ func (it IndexType) String() string {
 		return "Flat"
 	case IndexTypeBTree:
 		return "BTree"
+	case IndexTypeDiskANN:
+		return "DiskANN"
 	default:
 		return "Unknown"
 	}
@@ -125,6 +130,7 @@ type HNSWConfig struct {
 	M                    int
 	EfConstruction       int
 	EfSearch             int
+	RescoreK             int
 	ML                   float64
 	Metric               util.DistanceMetric
 	PruneAlpha           float32
@@ -134,6 +140,7 @@ type HNSWConfig struct {
 	RepairBatchSize      int
 	RawStoreCap          int
 	IDMapCapacity        int
+	Int8Construction     bool
 }
 
 // IVFPQConfig holds configuration for IVF-PQ index
@@ -154,11 +161,25 @@ type BTreeConfig struct {
 	PageShards int
 }
 
+// DiskANNConfig holds configuration for DiskANN index
+type DiskANNConfig struct {
+	Quantization *quant.QuantizationConfig
+	Dir          string
+	Dimension    int
+	R            int
+	L            int
+	Alpha        float32
+	Metric       util.DistanceMetric
+	TrainSize    int
+	RandomSeed   int64
+}
+
 // FlatConfig holds configuration for Flat index
 type FlatConfig struct {
 	Quantization *quant.QuantizationConfig
 	Dimension    int
 	Metric       util.DistanceMetric
+	RescoreK     int
 }
 
 // hnswWrapper wraps the HNSW index to adapt between interface types
@@ -170,6 +191,10 @@ func (w *hnswWrapper) RawVectorStoreProfile() map[string]any {
 	return w.index.RawVectorStoreProfile()
 }
 
+func (w *hnswWrapper) Int8ConstructionStats() (hnsw.Int8ConstructionStats, bool) {
+	return w.index.Int8ConstructionStats()
+}
+
 // Insert adapts the interface VectorEntry to HNSW VectorEntry
 func (w *hnswWrapper) Insert(ctx context.Context, entry *VectorEntry) error {
 	return w.index.Insert(ctx, entry)
@@ -244,6 +269,11 @@ func (w *hnswWrapper) SetCommunities(registry *hnsw.CommunityRegistry) {
 	w.index.SetCommunities(registry)
 }
 
+// CommunityOf delegates to the wrapped HNSW index.
+func (w *hnswWrapper) CommunityOf(id string) (uint32, bool) {
+	return w.index.CommunityOf(id)
+}
+
 // NEW: SaveToDisk delegates persistence to the wrapped index
 func (w *hnswWrapper) SaveToDisk(ctx context.Context, path string) error {
 	return w.index.SaveToDisk(ctx, path)
@@ -292,6 +322,7 @@ func NewHNSW(config *HNSWConfig) (Index, error) {
 		M:                    config.M,
 		EfConstruction:       config.EfConstruction,
 		EfSearch:             config.EfSearch,
+		RescoreK:             config.RescoreK,
 		ML:                   config.ML,
 		Metric:               config.Metric,
 		PruneAlpha:           config.PruneAlpha,
@@ -305,6 +336,7 @@ func NewHNSW(config *HNSWConfig) (Index, error) {
 		RawStoreCap:          config.RawStoreCap,
 		IDMapCapacity:        config.IDMapCapacity,
 		Quantization:         config.Quantization,
+		Int8Construction:     config.Int8Construction,
 	}
 
 	hnswIndex, err := hnsw.NewHNSW(hnswConfig)
@@ -328,6 +360,21 @@ func (w *ivfpqWrapper) IsTrained() bool {
 	return w.index.IsTrained()
 }
 
+// Drift delegates drift measurement to the wrapped index
+func (w *ivfpqWrapper) Drift(ctx context.Context, sample [][]float32) (*ivfpq.DriftReport, error) {
+	return w.index.Drift(ctx, sample)
+}
+
+// Retrain delegates online retraining to the wrapped index
+func (w *ivfpqWrapper) Retrain(ctx context.Context, sample [][]float32, lookup ivfpq.VectorLookup) error {
+	return w.index.Retrain(ctx, sample, lookup)
+}
+
+// Rebalance delegates cluster rebalancing to the wrapped index
+func (w *ivfpqWrapper) Rebalance(ctx context.Context, lookup ivfpq.VectorLookup, opts ivfpq.RebalanceOptions) (int, error) {
+	return w.index.Rebalance(ctx, lookup, opts)
+}
+
 // Insert delegates to the underlying IVF-PQ index
 func (w *ivfpqWrapper) Insert(ctx context.Context, entry *VectorEntry) error {
 	return w.index.Insert(ctx, entry)
@@ -866,6 +913,7 @@ func NewFlat(config *FlatConfig) (Index, error) {
 		Dimension:    config.Dimension,
 		Metric:       config.Metric,
 		Quantization: config.Quantization,
+		RescoreK:     config.RescoreK,
 	}
 
 	flatIndex, err := flat.NewCore(flatConfig)
@@ -963,3 +1011,130 @@ func NewBTree(config *BTreeConfig) (Index, error) {
 	}
 	return &btreeWrapper{index: idx}, nil
 }
+
+// diskannWrapper wraps the DiskANN index to adapt between interface types
+type diskannWrapper struct {
+	index *diskann.Index
+}
+
+// Insert delegates to the underlying DiskANN index
+func (w *diskannWrapper) Insert(ctx context.Context, entry *VectorEntry) error {
+	return w.index.Insert(ctx, entry)
+}
+
+// BatchInsert delegates batch insertion to the underlying DiskANN index
+func (w *diskannWrapper) BatchInsert(ctx context.Context, entries []*VectorEntry) error {
+	return w.index.BatchInsert(ctx, entries)
+}
+
+// Search adapts the search results from DiskANN to interface types
+func (w *diskannWrapper) Search(ctx context.Context, query []float32, k int, filter GraphFilter) ([]*SearchResult, error) {
+	return adaptDiskANNSearchResults(w.index.Search(ctx, query, k, filter))
+}
+
+// SearchWithEf maps ef onto the DiskANN search list size L.
+func (w *diskannWrapper) SearchWithEf(ctx context.Context, query []float32, k, ef int, filter GraphFilter) ([]*SearchResult, error) {
+	return adaptDiskANNSearchResults(w.index.SearchWithL(ctx, query, k, ef, filter))
+}
+
+func adaptDiskANNSearchResults(diskannResults []*diskann.SearchResult, err error) ([]*SearchResult, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SearchResult, len(diskannResults))
+	for i, r := range diskannResults {
+		results[i] = &SearchResult{
+			ID:      r.ID,
+			Score:   r.Score,
+			Version: r.Version,
+			Ordinal: r.Ordinal,
+		}
+	}
+	return results, nil
+}
+
+// Delete delegates to the wrapped index
+func (w *diskannWrapper) Delete(ctx context.Context, id string) error {
+	return w.index.Delete(ctx, id)
+}
+
+// Size delegates to the wrapped index
+func (w *diskannWrapper) Size() int {
+	return w.index.Size()
+}
+
+// MemoryUsage delegates to the wrapped index
+func (w *diskannWrapper) MemoryUsage() int64 {
+	return w.index.MemoryUsage()
+}
+
+// Close delegates to the wrapped index
+func (w *diskannWrapper) Close() error {
+	return w.index.Close()
+}
+
+// SaveToDisk delegates persistence to the wrapped index
+func (w *diskannWrapper) SaveToDisk(ctx context.Context, path string) error {
+	return w.index.SaveToDisk(ctx, path)
+}
+
+// LoadFromDisk delegates loading to the wrapped index
+func (w *diskannWrapper) LoadFromDisk(ctx context.Context, path string) error {
+	return w.index.LoadFromDisk(ctx, path)
+}
+
+// SerializeToBytes delegates to the wrapped index
+func (w *diskannWrapper) SerializeToBytes() ([]byte, error) {
+	return w.index.SerializeToBytes()
+}
+
+// DeserializeFromBytes delegates to the wrapped index
+func (w *diskannWrapper) DeserializeFromBytes(ctx context.Context, data []byte) error {
+	return w.index.DeserializeFromBytes(ctx, data)
+}
+
+// SerializeExtent delegates to the wrapped index
+func (w *diskannWrapper) SerializeExtent(region io.Writer) ([]byte, error) {
+	return w.index.SerializeExtent(region)
+}
+
+// DeserializeExtent delegates to the wrapped index
+func (w *diskannWrapper) DeserializeExtent(ctx context.Context, image []byte, region io.ReaderAt, size int64) error {
+	return w.index.DeserializeExtent(ctx, image, region, size)
+}
+
+// GetPersistenceMetadata reports the node file size as FileSize; vectors
+// and adjacency live there rather than in memory.
+func (w *diskannWrapper) GetPersistenceMetadata() *PersistenceMetadata {
+	return &PersistenceMetadata{
+		Version:   diskann.FormatVersion,
+		NodeCount: w.index.Size(),
+		Dimension: w.index.Config().Dimension,
+		FileSize:  w.index.DiskUsage(),
+		IndexType: "DiskANN",
+		CreatedAt: time.Now(),
+	}
+}
+
+// NewDiskANN creates a new DiskANN index
+func NewDiskANN(config *DiskANNConfig) (Index, error) {
+	diskannConfig := &diskann.Config{
+		Quantization: config.Quantization,
+		Dir:          config.Dir,
+		Dimension:    config.Dimension,
+		R:            config.R,
+		L:            config.L,
+		Alpha:        config.Alpha,
+		Metric:       config.Metric,
+		TrainSize:    config.TrainSize,
+		RandomSeed:   config.RandomSeed,
+	}
+
+	diskannIndex, err := diskann.NewDiskANN(diskannConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return &diskannWrapper{index: diskannIndex}, nil
+}
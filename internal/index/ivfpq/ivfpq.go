@@ -331,6 +331,14 @@ type generation struct {
 	retired   atomic.Bool
 	freed     atomic.Bool // set by drainAndFree for test verification
 	id        uint64
+	// journal is non-nil while Retrain or Rebalance stages a replacement
+	// from this generation; writes record themselves for replay.
+	journal atomic.Pointer[retrainJournal]
+	// trainedCoarseError and trainedQuantError are the mean squared
+	// centroid and reconstruction errors measured on the training sample.
+	// Zero means the baseline is unknown.
+	trainedCoarseError float64
+	trainedQuantError  float64
 }
 
 var genIDSeq atomic.Uint64
@@ -361,6 +369,9 @@ func (g *generation) release() {
 type Index struct {
 	gen          *generation
 	distanceFunc util.DistanceFunc
+	// metric mirrors config.Metric, which no generation swap can change, so
+	// staging code can score clusters without holding the index lock.
+	metric       util.DistanceMetric
 	searchStats  *SearchStats
 	config       *Config // convenience mirror of gen.config
 	scratchPool  *sync.Pool
@@ -597,6 +608,7 @@ func NewIVFPQ(config *Config) (*Index, error) {
 		gen:          gen,
 		config:       config,
 		distanceFunc: distanceFunc,
+		metric:       config.Metric,
 		scratchPool:  scratchPool,
 		rand:         rand.New(rand.NewSource(config.RandomSeed)),
 		searchStats: &SearchStats{
@@ -641,7 +653,7 @@ func (idx *Index) Train(ctx context.Context, vectors [][]float32) error {
 	}
 
 	// Perform k-means clustering to train coarse quantizer
-	if err := idx.trainCoarseQuantizer(ctx, vectors); err != nil {
+	if err := idx.trainCoarseQuantizer(ctx, idx.config, idx.gen.clusters, vectors, idx.rand); err != nil {
 		return fmt.Errorf("failed to train coarse quantizer: %w", err)
 	}
 
@@ -662,19 +674,27 @@ func (idx *Index) Train(ctx context.Context, vectors [][]float32) error {
 	}
 	idx.gen.trained = true
 	idx.gen.mutation.Add(1)
+	idx.gen.trainedCoarseError, idx.gen.trainedQuantError = idx.measureError(idx.gen.clusters, idx.gen.quantizer, driftSample(vectors))
+	// Retraining in place invalidates any replacement staged from the
+	// previous centroids.
+	if journal := idx.gen.journal.Load(); journal != nil {
+		journal.invalidate()
+	}
 	return nil
 }
 
-// trainCoarseQuantizer performs k-means clustering to create cluster centroids
-func (idx *Index) trainCoarseQuantizer(ctx context.Context, vectors [][]float32) error {
+// trainCoarseQuantizer performs k-means clustering to create cluster centroids.
+// It trains the supplied clusters so a replacement generation can be trained
+// without touching the live one.
+func (idx *Index) trainCoarseQuantizer(ctx context.Context, config *Config, clusters []*Cluster, vectors [][]float32, rng *rand.Rand) error {
 	// Initialize centroids using k-means++
-	if err := idx.initializeCentroids(vectors); err != nil {
+	if err := idx.initializeCentroids(clusters, vectors, rng); err != nil {
 		return fmt.Errorf("failed to initialize centroids: %w", err)
 	}
 
 	prevInertia := math.Inf(1)
 
-	for iter := 0; iter < idx.config.MaxIterations; iter++ {
+	for iter := 0; iter < config.MaxIterations; iter++ {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -683,19 +703,19 @@ func (idx *Index) trainCoarseQuantizer(ctx context.Context, vectors [][]float32)
 
 		// Assignment step: assign each vector to nearest centroid.
 		assignments := make([]int, len(vectors))
-		totalInertia, err := idx.assignVectorsToClusters(ctx, vectors, assignments)
+		totalInertia, err := idx.assignVectorsToClusters(ctx, clusters, vectors, assignments)
 		if err != nil {
 			return fmt.Errorf("failed during assignment step: %w", err)
 		}
 
 		// Check for convergence
-		if math.Abs(prevInertia-totalInertia)/prevInertia < idx.config.Tolerance {
+		if math.Abs(prevInertia-totalInertia)/prevInertia < config.Tolerance {
 			break
 		}
 		prevInertia = totalInertia
 
 		// Update step: recompute centroids
-		if err := idx.updateCentroids(vectors, assignments); err != nil {
+		if err := idx.updateCentroids(clusters, vectors, assignments, rng); err != nil {
 			return fmt.Errorf("failed to update centroids: %w", err)
 		}
 	}
@@ -703,11 +723,11 @@ func (idx *Index) trainCoarseQuantizer(ctx context.Context, vectors [][]float32)
 	return nil
 }
 
-func (idx *Index) assignVectorsToClusters(ctx context.Context, vectors [][]float32, assignments []int) (float64, error) {
+func (idx *Index) assignVectorsToClusters(ctx context.Context, clusters []*Cluster, vectors [][]float32, assignments []int) (float64, error) {
 	// Precompute norms for fast assignment scores
-	centroidNorm2 := make([]float32, len(idx.gen.clusters))
-	centroidNorm := make([]float32, len(idx.gen.clusters))
-	for j, cluster := range idx.gen.clusters {
+	centroidNorm2 := make([]float32, len(clusters))
+	centroidNorm := make([]float32, len(clusters))
+	for j, cluster := range clusters {
 		var sum float32
 		for _, v := range cluster.Centroid {
 			sum += v * v
@@ -723,7 +743,7 @@ func (idx *Index) assignVectorsToClusters(ctx context.Context, vectors [][]float
 			bestCluster := 0
 			bestScore := float32(math.Inf(-1))
 
-			for j, cluster := range idx.gen.clusters {
+			for j, cluster := range clusters {
 				score := idx.computeAssignmentScore(vec, cluster.Centroid, centroidNorm2[j], centroidNorm[j])
 				if score > bestScore {
 					bestScore = score
@@ -732,7 +752,7 @@ func (idx *Index) assignVectorsToClusters(ctx context.Context, vectors [][]float
 			}
 
 			assignments[i] = bestCluster
-			totalInertia += float64(idx.distanceFunc(vec, idx.gen.clusters[bestCluster].Centroid))
+			totalInertia += float64(idx.distanceFunc(vec, clusters[bestCluster].Centroid))
 		}
 		return totalInertia, nil
 	}
@@ -765,7 +785,7 @@ func (idx *Index) assignVectorsToClusters(ctx context.Context, vectors [][]float
 				vec := vectors[i]
 				bestCluster := 0
 				bestScore := float32(math.Inf(-1))
-				for j, cluster := range idx.gen.clusters {
+				for j, cluster := range clusters {
 					score := idx.computeAssignmentScore(vec, cluster.Centroid, centroidNorm2[j], centroidNorm[j])
 					if score > bestScore {
 						bestScore = score
@@ -774,7 +794,7 @@ func (idx *Index) assignVectorsToClusters(ctx context.Context, vectors [][]float
 				}
 
 				assignments[i] = bestCluster
-				localInertia += float64(idx.distanceFunc(vec, idx.gen.clusters[bestCluster].Centroid))
+				localInertia += float64(idx.distanceFunc(vec, clusters[bestCluster].Centroid))
 			}
 
 			inertias[worker] = localInertia
@@ -799,15 +819,15 @@ func (idx *Index) assignVectorsToClusters(ctx context.Context, vectors [][]float
 
 // initializeCentroids initializes cluster centroids using k-means++
 // with running-min tracking: O(N·k·dim) instead of O(N·k²·dim).
-func (idx *Index) initializeCentroids(vectors [][]float32) error {
-	nClusters := idx.config.NClusters
+func (idx *Index) initializeCentroids(clusters []*Cluster, vectors [][]float32, rng *rand.Rand) error {
+	nClusters := len(clusters)
 	if len(vectors) < nClusters {
 		return fmt.Errorf("not enough vectors for initialization")
 	}
 
 	// Choose first centroid randomly.
-	firstIdx := idx.rand.Intn(len(vectors))
-	copy(idx.gen.clusters[0].Centroid, vectors[firstIdx])
+	firstIdx := rng.Intn(len(vectors))
+	copy(clusters[0].Centroid, vectors[firstIdx])
 
 	// minDist[i] tracks the squared distance from vector i to its nearest
 	// already-chosen centroid. Updated incrementally as each new centroid
@@ -815,7 +835,7 @@ func (idx *Index) initializeCentroids(vectors [][]float32) error {
 	minDist := make([]float64, len(vectors))
 	totalDist := float64(0)
 	for i, vec := range vectors {
-		d := float64(idx.distanceFunc(vec, idx.gen.clusters[0].Centroid))
+		d := float64(idx.distanceFunc(vec, clusters[0].Centroid))
 		minDist[i] = d * d
 		totalDist += minDist[i]
 	}
@@ -823,7 +843,7 @@ func (idx *Index) initializeCentroids(vectors [][]float32) error {
 	// Choose remaining centroids using k-means++ (proportional to squared distance).
 	for k := 1; k < nClusters; k++ {
 		// Select next centroid via roulette-wheel selection.
-		target := idx.rand.Float64() * totalDist
+		target := rng.Float64() * totalDist
 		cumulative := float64(0)
 		chosenIdx := 0
 		for i, d := range minDist {
@@ -833,11 +853,11 @@ func (idx *Index) initializeCentroids(vectors [][]float32) error {
 				break
 			}
 		}
-		copy(idx.gen.clusters[k].Centroid, vectors[chosenIdx])
+		copy(clusters[k].Centroid, vectors[chosenIdx])
 
 		// Update running-min distances: only compare against the new centroid.
 		totalDist = 0
-		newCentroid := idx.gen.clusters[k].Centroid
+		newCentroid := clusters[k].Centroid
 		for i, vec := range vectors {
 			d := float64(idx.distanceFunc(vec, newCentroid))
 			d2 := d * d
@@ -852,16 +872,16 @@ func (idx *Index) initializeCentroids(vectors [][]float32) error {
 }
 
 // updateCentroids recomputes cluster centroids based on current assignments
-func (idx *Index) updateCentroids(vectors [][]float32, assignments []int) error {
+func (idx *Index) updateCentroids(clusters []*Cluster, vectors [][]float32, assignments []int, rng *rand.Rand) error {
 	// Reset centroids
-	for _, cluster := range idx.gen.clusters {
+	for _, cluster := range clusters {
 		for i := range cluster.Centroid {
 			cluster.Centroid[i] = 0
 		}
 	}
 
 	// Count vectors per cluster
-	counts := make([]int, idx.config.NClusters)
+	counts := make([]int, len(clusters))
 
 	// Sum vectors for each cluster
 	for i, vec := range vectors {
@@ -869,19 +889,19 @@ func (idx *Index) updateCentroids(vectors [][]float32, assignments []int) error
 		counts[clusterID]++
 
 		for j, val := range vec {
-			idx.gen.clusters[clusterID].Centroid[j] += val
+			clusters[clusterID].Centroid[j] += val
 		}
 	}
 
 	// Compute averages (avoid division by zero)
-	for i, cluster := range idx.gen.clusters {
+	for i, cluster := range clusters {
 		if counts[i] > 0 {
 			for j := range cluster.Centroid {
 				cluster.Centroid[j] /= float32(counts[i])
 			}
 		} else {
 			// Reinitialize empty clusters randomly
-			randomIdx := idx.rand.Intn(len(vectors))
+			randomIdx := rng.Intn(len(vectors))
 			copy(cluster.Centroid, vectors[randomIdx])
 		}
 		// Precompute norms for fast assignment scores
@@ -899,7 +919,7 @@ func (idx *Index) updateCentroids(vectors [][]float32, assignments []int) error
 // computeAssignmentScore computes a metric-specific score to maximize for cluster assignment.
 // This avoids expensive sqrt or ||x|| computations where possible.
 func (idx *Index) computeAssignmentScore(vec, centroid []float32, norm2, norm float32) float32 {
-	switch util.DistanceMetric(idx.config.Metric) {
+	switch idx.metric {
 	case util.L2Distance:
 		// argmin(||x-c||²) ≡ argmax(dot(x,c) - ||c||²/2)
 		return dotProduct(vec, centroid) - norm2*0.5
@@ -924,11 +944,16 @@ func (idx *Index) assignToCluster(vector []float32) (int, error) {
 	if !idx.gen.trained {
 		return 0, fmt.Errorf("assignToCluster: %w", util.ErrNotTrained)
 	}
+	return idx.nearestCluster(idx.gen.clusters, vector), nil
+}
 
+// nearestCluster returns the position of the cluster in clusters that vector
+// is assigned to.
+func (idx *Index) nearestCluster(clusters []*Cluster, vector []float32) int {
 	bestCluster := 0
 	bestScore := float32(math.Inf(-1))
 
-	for i, cluster := range idx.gen.clusters {
+	for i, cluster := range clusters {
 		score := idx.computeAssignmentScore(vector, cluster.Centroid, cluster.centroidNorm2, cluster.centroidNorm)
 		if score > bestScore {
 			bestScore = score
@@ -936,7 +961,7 @@ func (idx *Index) assignToCluster(vector []float32) (int, error) {
 		}
 	}
 
-	return bestCluster, nil
+	return bestCluster
 }
 
 // findProbeClusters finds the top-k closest clusters for search probing
@@ -1117,6 +1142,9 @@ func (idx *Index) Insert(ctx context.Context, entry *VectorEntry) error {
 
 	gen.size.Add(1)
 	gen.mutation.Add(1)
+	if journal := gen.journal.Load(); journal != nil {
+		journal.insert(entry.Ordinal, entry.Vector)
+	}
 	return nil
 }
 
@@ -1308,6 +1336,11 @@ func (idx *Index) batchInsertChunk(ctx context.Context, entries []*VectorEntry)
 
 	gen.size.Add(int64(len(entries)))
 	gen.mutation.Add(1)
+	if journal := gen.journal.Load(); journal != nil {
+		for _, entry := range entries {
+			journal.insert(entry.Ordinal, entry.Vector)
+		}
+	}
 	return nil
 }
 
@@ -1830,6 +1863,9 @@ func (idx *Index) DeleteByOrdinal(ctx context.Context, ordinal uint32) error {
 			cluster.mutex.Unlock()
 			gen.size.Add(-1)
 			gen.mutation.Add(1)
+			if journal := gen.journal.Load(); journal != nil {
+				journal.delete(ordinal)
+			}
 			return nil
 		}
 		cluster.mutex.Unlock()
@@ -20,6 +20,10 @@ const (
 
 var ivfpqMagicBytes = []byte("LIBRAIVF")
 
+// ivfpqFlagTrainingError marks a v3 payload that carries the training error
+// baselines after the inverted lists.
+const ivfpqFlagTrainingError uint8 = 1 << 0
+
 const (
 	qTagNone uint8 = 0
 	qTagPQ   uint8 = 1
@@ -65,11 +69,15 @@ func (idx *Index) SerializeToBytes() ([]byte, error) {
 	}
 	buf := make([]byte, 0, 64*1024)
 	w := &sliceWriter{buf: buf}
+	g := idx.gen
+	var flags uint8
+	if g.trainedCoarseError > 0 || g.trainedQuantError > 0 {
+		flags |= ivfpqFlagTrainingError
+	}
 	w.bytes(ivfpqMagicBytes)
 	w.u16(ivfpqFormatVersion)
 	w.u8(ivfpqIndexType)
-	w.u8(0)
-	g := idx.gen
+	w.u8(flags)
 	w.u32(uint32(g.config.Dimension))
 	w.u32(uint32(g.config.NClusters))
 	w.u32(uint32(g.config.NProbes))
@@ -122,6 +130,10 @@ func (idx *Index) SerializeToBytes() ([]byte, error) {
 		}
 		c.mutex.RUnlock()
 	}
+	if flags&ivfpqFlagTrainingError != 0 {
+		w.f64(g.trainedCoarseError)
+		w.f64(g.trainedQuantError)
+	}
 	ck := crc32.Checksum(w.buf, crc32.MakeTable(crc32.Castagnoli))
 	w.u32(ck)
 	return w.buf, nil
@@ -144,6 +156,9 @@ type pendingIndex struct {
 	legacySubDim    int
 	centroids       [][]float32
 	records         [][]pendingRecord
+
+	trainedCoarseError float64
+	trainedQuantError  float64
 }
 
 type pendingRecord struct {
@@ -240,7 +255,8 @@ func (idx *Index) DeserializeFromBytes(ctx context.Context, data []byte) error {
 	if it != ivfpqIndexType {
 		return fmt.Errorf("index type mismatch")
 	}
-	if _, err := r.u8(); err != nil {
+	flags, err := r.u8()
+	if err != nil {
 		return fmt.Errorf("flags: %w", err)
 	}
 
@@ -249,7 +265,7 @@ func (idx *Index) DeserializeFromBytes(ctx context.Context, data []byte) error {
 	case ivfpqFormatVersionLegacy:
 		p, err = parsePendingV2(r)
 	case ivfpqFormatVersion:
-		p, err = parsePendingV3(r)
+		p, err = parsePendingV3(r, flags)
 	}
 	if err != nil {
 		return fmt.Errorf("parse: %w", err)
@@ -359,6 +375,8 @@ func (idx *Index) DeserializeFromBytes(ctx context.Context, data []byte) error {
 	}
 
 	replGen := newGeneration(replPool, base.poolCfg, replClusters, q, replConfig, int(totalRecords), true)
+	replGen.trainedCoarseError = p.trainedCoarseError
+	replGen.trainedQuantError = p.trainedQuantError
 	committed = true
 
 	// COMMIT: pointer swap under exclusive lock.
@@ -401,7 +419,7 @@ func cloneConfig(c *Config) *Config {
 
 // --- parsePendingV3 ---
 
-func parsePendingV3(r *sliceReader) (*pendingIndex, error) {
+func parsePendingV3(r *sliceReader, flags uint8) (*pendingIndex, error) {
 	p := &pendingIndex{}
 	dv, err := r.u32()
 	if err != nil {
@@ -551,6 +569,14 @@ func parsePendingV3(r *sliceReader) (*pendingIndex, error) {
 		}
 		p.records[ci] = recs
 	}
+	if flags&ivfpqFlagTrainingError != 0 {
+		if p.trainedCoarseError, err = r.f64(); err != nil {
+			return nil, fmt.Errorf("trained coarse error: %w", err)
+		}
+		if p.trainedQuantError, err = r.f64(); err != nil {
+			return nil, fmt.Errorf("trained quantization error: %w", err)
+		}
+	}
 	if _, err := r.u32(); err != nil {
 		return nil, fmt.Errorf("footer: %w", err)
 	}
@@ -0,0 +1,744 @@
+package ivfpq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"github.com/xDarkicex/libravdb/internal/quant"
+	"github.com/xDarkicex/libravdb/internal/util"
+	"github.com/xDarkicex/memory"
+)
+
+// Online retraining. Train fixes the coarse centroids and the fine quantizer
+// once; Retrain and Rebalance build a replacement generation next to the live
+// one and publish it with a pointer swap. Writes that land while the
+// replacement is staged are journaled on the live generation and replayed
+// onto the replacement before it is published, so searches keep running
+// against the live generation and no write is lost.
+
+const (
+	// driftSampleLimit caps how many vectors are used to measure error.
+	driftSampleLimit = 2048
+
+	// journalCatchUp is the journal backlog that may be replayed under the
+	// exclusive lock. Longer backlogs are drained first while writers and
+	// searches still run.
+	journalCatchUp = 256
+
+	// splitIterations bounds the 2-means refinement of a split cluster.
+	splitIterations = 10
+)
+
+// ErrRetrainInProgress reports that another Retrain or Rebalance is staging a
+// replacement from the live generation.
+var ErrRetrainInProgress = errors.New("IVF-PQ retrain already in progress")
+
+// ErrRetrainConflict reports that the live generation was hydrated or
+// retrained in place while a replacement was staged. The replacement is
+// discarded and the live generation is left untouched.
+var ErrRetrainConflict = errors.New("IVF-PQ retrain conflicts with a concurrent hydration or training")
+
+// VectorLookup returns the canonical vector for an ordinal. A record whose
+// vector cannot be read is re-encoded from its current code instead, so a
+// record deleted concurrently stays indexed until its delete reaches the
+// index.
+type VectorLookup func(ordinal uint32) ([]float32, error)
+
+// DriftReport describes how far the live generation has drifted from the
+// state it was trained on.
+type DriftReport struct {
+	Generation     uint64
+	Clusters       int
+	Vectors        int
+	EmptyClusters  int
+	MinClusterSize int
+	MaxClusterSize int
+	// SizeSkew is the largest cluster size divided by the mean size.
+	SizeSkew float64
+	// CoarseError and QuantizationError are the mean squared distances from
+	// the sample to its assigned centroid and to its reconstruction.
+	CoarseError       float64
+	QuantizationError float64
+	// TrainedCoarseError and TrainedQuantizationError are the same measures
+	// taken on the training sample, or zero when unknown.
+	TrainedCoarseError       float64
+	TrainedQuantizationError float64
+}
+
+// ErrorGrowth returns the larger ratio of sampled to trained error, or zero
+// when no training baseline is known.
+func (r *DriftReport) ErrorGrowth() float64 {
+	var growth float64
+	if r.TrainedCoarseError > 0 {
+		growth = r.CoarseError / r.TrainedCoarseError
+	}
+	if r.TrainedQuantizationError > 0 {
+		growth = math.Max(growth, r.QuantizationError/r.TrainedQuantizationError)
+	}
+	return growth
+}
+
+// RebalanceOptions controls which clusters Rebalance splits and merges.
+type RebalanceOptions struct {
+	// SplitFactor marks clusters larger than SplitFactor times the mean
+	// size as oversized.
+	SplitFactor float64
+	// MergeFactor marks clusters smaller than MergeFactor times the mean
+	// size as undersized.
+	MergeFactor float64
+	// MaxSplits caps the split/merge pairs of one pass. Zero means no cap.
+	MaxSplits int
+}
+
+// DefaultRebalanceOptions returns the thresholds used when a field of
+// RebalanceOptions is left zero.
+func DefaultRebalanceOptions() RebalanceOptions {
+	return RebalanceOptions{SplitFactor: 3, MergeFactor: 0.25}
+}
+
+type journalOp struct {
+	vector  []float32 // nil for a delete
+	ordinal uint32
+}
+
+// retrainJournal records the writes applied to a generation while a
+// replacement is staged from it.
+type retrainJournal struct {
+	mu      sync.Mutex
+	ops     []journalOp
+	invalid bool
+}
+
+func (j *retrainJournal) insert(ordinal uint32, vector []float32) {
+	j.mu.Lock()
+	j.ops = append(j.ops, journalOp{ordinal: ordinal, vector: append([]float32(nil), vector...)})
+	j.mu.Unlock()
+}
+
+func (j *retrainJournal) delete(ordinal uint32) {
+	j.mu.Lock()
+	j.ops = append(j.ops, journalOp{ordinal: ordinal})
+	j.mu.Unlock()
+}
+
+func (j *retrainJournal) invalidate() {
+	j.mu.Lock()
+	j.invalid = true
+	j.mu.Unlock()
+}
+
+// pending returns the operations recorded after the first from.
+func (j *retrainJournal) pending(from int) ([]journalOp, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.ops[from:len(j.ops):len(j.ops)], j.invalid
+}
+
+// stageSnapshot is a consistent copy of the live generation's inverted lists
+// taken at the moment its journal was installed.
+type stageSnapshot struct {
+	base     *generation
+	journal  *retrainJournal
+	ordinals [][]uint32
+	codes    [][]byte
+	width    int
+}
+
+// beginStage pins the live generation, installs its journal, and copies its
+// inverted lists. Writers are excluded only for the copy.
+func (idx *Index) beginStage() (*stageSnapshot, error) {
+	idx.mutex.Lock()
+	defer idx.mutex.Unlock()
+	if idx.gen == nil {
+		return nil, fmt.Errorf("index closed")
+	}
+	gen := idx.gen
+	if !gen.trained {
+		return nil, fmt.Errorf("Retrain: %w", util.ErrNotTrained)
+	}
+	journal := &retrainJournal{}
+	if !gen.journal.CompareAndSwap(nil, journal) {
+		return nil, ErrRetrainInProgress
+	}
+	gen.acquire()
+
+	snap := &stageSnapshot{
+		base:     gen,
+		journal:  journal,
+		ordinals: make([][]uint32, len(gen.clusters)),
+		codes:    make([][]byte, len(gen.clusters)),
+	}
+	if gen.quantizer != nil && gen.quantizer.IsTrained() {
+		snap.width = gen.quantizer.CodeSize()
+	}
+	for i, cluster := range gen.clusters {
+		st := cluster.storage
+		ordinals := make([]uint32, 0, st.count)
+		codes := make([]byte, 0, int(st.count)*snap.width)
+		for _, seg := range st.segments {
+			ordinals = append(ordinals, seg.ordinals[:seg.used]...)
+			if snap.width > 0 {
+				codes = append(codes, seg.codes[:int(seg.used)*snap.width]...)
+			}
+		}
+		snap.ordinals[i] = ordinals
+		snap.codes[i] = codes
+	}
+	return snap, nil
+}
+
+// end removes the journal and unpins the base generation.
+func (s *stageSnapshot) end() {
+	s.base.journal.CompareAndSwap(s.journal, nil)
+	s.base.release()
+}
+
+func (s *stageSnapshot) code(cluster, record int) []byte {
+	if s.width == 0 {
+		return nil
+	}
+	return s.codes[cluster][record*s.width : (record+1)*s.width]
+}
+
+// vector returns the canonical vector of a snapshot record, falling back to
+// its reconstruction, or to its old centroid when the index keeps no codes.
+func (s *stageSnapshot) vector(lookup VectorLookup, cluster, record int) ([]float32, error) {
+	dim := s.base.config.Dimension
+	if lookup != nil {
+		if vector, err := lookup(s.ordinals[cluster][record]); err == nil && len(vector) == dim {
+			return vector, nil
+		}
+	}
+	if code := s.code(cluster, record); code != nil {
+		return s.base.quantizer.Decompress(code)
+	}
+	return s.base.clusters[cluster].Centroid, nil
+}
+
+// newReplacement builds an empty, trained generation around centroids and q.
+// On success the generation owns q; on failure q is closed.
+func newReplacement(base *generation, centroids [][]float32, q quant.Quantizer) (*generation, error) {
+	pool, err := memory.NewPool(base.poolCfg, 64)
+	if err != nil {
+		if q != nil {
+			q.Close()
+		}
+		return nil, fmt.Errorf("create replacement pool: %w", err)
+	}
+	width := 0
+	if q != nil {
+		width = q.CodeSize()
+	}
+	clusters := make([]*Cluster, len(centroids))
+	for i, centroid := range centroids {
+		cluster := &Cluster{
+			ID:       i,
+			Centroid: centroid,
+			storage:  &clusterStorage{segmentCapacity: 1024, codeWidth: uint32(width)},
+		}
+		var norm2 float32
+		for _, v := range centroid {
+			norm2 += v * v
+		}
+		cluster.centroidNorm2 = norm2
+		cluster.centroidNorm = float32(math.Sqrt(float64(norm2)))
+		clusters[i] = cluster
+	}
+	config := cloneConfig(base.config)
+	if q != nil {
+		if cfg := q.Config(); cfg != nil {
+			config.Quantization = cfg
+		}
+	}
+	return newGeneration(pool, base.poolCfg, clusters, q, config, 0, true), nil
+}
+
+func discardGeneration(g *generation) {
+	g.retired.Store(true)
+	g.release()
+}
+
+// appendVector assigns and encodes vector into an unpublished generation.
+func (idx *Index) appendVector(g *generation, ordinal uint32, vector []float32) error {
+	var code []byte
+	if g.quantizer != nil {
+		var err error
+		if code, err = g.quantizer.Compress(vector); err != nil {
+			return fmt.Errorf("failed to compress vector: %w", err)
+		}
+	}
+	clusterID := idx.nearestCluster(g.clusters, vector)
+	if err := g.clusters[clusterID].storage.append(ordinal, code, g.pool); err != nil {
+		return fmt.Errorf("failed to append to cluster %d storage: %w", clusterID, err)
+	}
+	g.size.Add(1)
+	return nil
+}
+
+func (idx *Index) replayJournal(g *generation, ops []journalOp) error {
+	for _, op := range ops {
+		if op.vector != nil {
+			if err := idx.appendVector(g, op.ordinal, op.vector); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, cluster := range g.clusters {
+			if cluster.storage.deleteByOrdinal(op.ordinal) {
+				g.size.Add(-1)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// commitStage replays the journal onto repl and publishes it. The bulk of the
+// backlog is replayed before the exclusive lock is taken; only the tail that
+// arrives while catching up is replayed under it.
+func (idx *Index) commitStage(ctx context.Context, snap *stageSnapshot, repl *generation) error {
+	applied := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		ops, invalid := snap.journal.pending(applied)
+		if invalid {
+			return ErrRetrainConflict
+		}
+		if len(ops) <= journalCatchUp {
+			break
+		}
+		if err := idx.replayJournal(repl, ops); err != nil {
+			return err
+		}
+		applied += len(ops)
+	}
+
+	idx.mutex.Lock()
+	if idx.gen == nil {
+		idx.mutex.Unlock()
+		return fmt.Errorf("index closed during retrain commit")
+	}
+	ops, invalid := snap.journal.pending(applied)
+	if idx.gen != snap.base || invalid {
+		idx.mutex.Unlock()
+		return ErrRetrainConflict
+	}
+	if err := idx.replayJournal(repl, ops); err != nil {
+		idx.mutex.Unlock()
+		return err
+	}
+	previous := idx.gen
+	previous.journal.Store(nil)
+	idx.gen = repl
+	idx.config = repl.config
+	idx.mutex.Unlock()
+
+	previous.retired.Store(true)
+	previous.release()
+	return nil
+}
+
+// Retrain trains new coarse centroids and a new fine quantizer on sample,
+// re-encodes every indexed record from lookup, and publishes the result as a
+// new generation. Searches and writes keep using the live generation while
+// the replacement is built.
+func (idx *Index) Retrain(ctx context.Context, sample [][]float32, lookup VectorLookup) error {
+	snap, err := idx.beginStage()
+	if err != nil {
+		return err
+	}
+	defer snap.end()
+	base := snap.base
+	config := base.config
+
+	if len(sample) < config.NClusters {
+		return fmt.Errorf("need at least %d training vectors for %d clusters, got %d",
+			config.NClusters, config.NClusters, len(sample))
+	}
+	for i, vec := range sample {
+		if len(vec) != config.Dimension {
+			return fmt.Errorf("vector %d has dimension %d, expected %d", i, len(vec), config.Dimension)
+		}
+	}
+
+	training := make([]*Cluster, config.NClusters)
+	for i := range training {
+		training[i] = &Cluster{ID: i, Centroid: make([]float32, config.Dimension)}
+	}
+	rng := rand.New(rand.NewSource(config.RandomSeed))
+	if err := idx.trainCoarseQuantizer(ctx, config, training, sample, rng); err != nil {
+		return fmt.Errorf("failed to train coarse quantizer: %w", err)
+	}
+	centroids := make([][]float32, len(training))
+	for i, cluster := range training {
+		centroids[i] = cluster.Centroid
+	}
+
+	var q quant.Quantizer
+	if config.Quantization != nil {
+		q, err = quant.Create(cloneConfig(config).Quantization)
+		if err != nil {
+			return fmt.Errorf("failed to create quantizer: %w", err)
+		}
+		if err := q.Train(ctx, sample); err != nil {
+			q.Close()
+			return fmt.Errorf("failed to train fine quantizer: %w", err)
+		}
+	}
+	repl, err := newReplacement(base, centroids, q)
+	if err != nil {
+		return err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			discardGeneration(repl)
+		}
+	}()
+
+	for ci, ordinals := range snap.ordinals {
+		for ri, ordinal := range ordinals {
+			if ri%1024 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			vector, err := snap.vector(lookup, ci, ri)
+			if err != nil {
+				return fmt.Errorf("ordinal %d: %w", ordinal, err)
+			}
+			if err := idx.appendVector(repl, ordinal, vector); err != nil {
+				return err
+			}
+		}
+	}
+	repl.trainedCoarseError, repl.trainedQuantError = idx.measureError(repl.clusters, repl.quantizer, driftSample(sample))
+
+	if err := idx.commitStage(ctx, snap, repl); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// Rebalance pairs oversized clusters with undersized ones. Each undersized
+// cluster gives up its slot: its records move to their nearest remaining
+// centroid and the slot receives one half of a 2-means split of the oversized
+// cluster. The cluster count and the fine quantizer are unchanged, so records
+// outside the affected clusters keep their codes and assignments. It returns
+// the number of clusters split.
+func (idx *Index) Rebalance(ctx context.Context, lookup VectorLookup, opts RebalanceOptions) (int, error) {
+	defaults := DefaultRebalanceOptions()
+	if opts.SplitFactor <= 0 {
+		opts.SplitFactor = defaults.SplitFactor
+	}
+	if opts.MergeFactor <= 0 {
+		opts.MergeFactor = defaults.MergeFactor
+	}
+	if opts.MergeFactor >= opts.SplitFactor {
+		return 0, fmt.Errorf("merge factor %v must be below split factor %v", opts.MergeFactor, opts.SplitFactor)
+	}
+
+	snap, err := idx.beginStage()
+	if err != nil {
+		return 0, err
+	}
+	defer snap.end()
+	base := snap.base
+
+	total := 0
+	for _, ordinals := range snap.ordinals {
+		total += len(ordinals)
+	}
+	if total == 0 {
+		return 0, nil
+	}
+	mean := float64(total) / float64(len(snap.ordinals))
+	var oversized, undersized []int
+	for i, ordinals := range snap.ordinals {
+		size := float64(len(ordinals))
+		switch {
+		case size > opts.SplitFactor*mean && len(ordinals) >= 2:
+			oversized = append(oversized, i)
+		case size < opts.MergeFactor*mean:
+			undersized = append(undersized, i)
+		}
+	}
+	sort.SliceStable(oversized, func(a, b int) bool {
+		return len(snap.ordinals[oversized[a]]) > len(snap.ordinals[oversized[b]])
+	})
+	sort.SliceStable(undersized, func(a, b int) bool {
+		return len(snap.ordinals[undersized[a]]) < len(snap.ordinals[undersized[b]])
+	})
+	pairs := min(len(oversized), len(undersized))
+	if opts.MaxSplits > 0 {
+		pairs = min(pairs, opts.MaxSplits)
+	}
+	if pairs == 0 {
+		return 0, nil
+	}
+
+	centroids := make([][]float32, len(base.clusters))
+	for i, cluster := range base.clusters {
+		centroids[i] = append([]float32(nil), cluster.Centroid...)
+	}
+	affected := make([]bool, len(centroids))
+	vectors := make(map[int][][]float32, pairs)
+	splits := 0
+	for p := 0; p < pairs; p++ {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		over, under := oversized[p], undersized[p]
+		members := make([][]float32, len(snap.ordinals[over]))
+		for ri := range members {
+			if members[ri], err = snap.vector(lookup, over, ri); err != nil {
+				return 0, fmt.Errorf("ordinal %d: %w", snap.ordinals[over][ri], err)
+			}
+		}
+		left, right, ok := splitCentroids(members)
+		if !ok {
+			continue
+		}
+		vectors[over] = members
+		centroids[over], centroids[under] = left, right
+		affected[over], affected[under] = true, true
+		splits++
+	}
+	if splits == 0 {
+		return 0, nil
+	}
+
+	q, err := cloneQuantizer(base.quantizer)
+	if err != nil {
+		return 0, err
+	}
+	repl, err := newReplacement(base, centroids, q)
+	if err != nil {
+		return 0, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			discardGeneration(repl)
+		}
+	}()
+
+	for ci, ordinals := range snap.ordinals {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		for ri, ordinal := range ordinals {
+			target := ci
+			if affected[ci] {
+				var vector []float32
+				if cached, ok := vectors[ci]; ok {
+					vector = cached[ri]
+				} else if vector, err = snap.vector(lookup, ci, ri); err != nil {
+					return 0, fmt.Errorf("ordinal %d: %w", ordinal, err)
+				}
+				target = idx.nearestCluster(repl.clusters, vector)
+			}
+			if err := repl.clusters[target].storage.append(ordinal, snap.code(ci, ri), repl.pool); err != nil {
+				return 0, fmt.Errorf("failed to append to cluster %d storage: %w", target, err)
+			}
+			repl.size.Add(1)
+		}
+	}
+	repl.trainedCoarseError, repl.trainedQuantError = base.trainedCoarseError, base.trainedQuantError
+
+	if err := idx.commitStage(ctx, snap, repl); err != nil {
+		return 0, err
+	}
+	committed = true
+	return splits, nil
+}
+
+// Drift measures cluster size skew on the live generation and the coarse and
+// quantization error of sample against it.
+func (idx *Index) Drift(ctx context.Context, sample [][]float32) (*DriftReport, error) {
+	idx.mutex.RLock()
+	if idx.gen == nil {
+		idx.mutex.RUnlock()
+		return nil, fmt.Errorf("index closed")
+	}
+	if !idx.gen.trained {
+		idx.mutex.RUnlock()
+		return nil, fmt.Errorf("Drift: %w", util.ErrNotTrained)
+	}
+	gen := idx.gen
+	gen.acquire()
+	idx.mutex.RUnlock()
+	defer gen.release()
+
+	report := &DriftReport{
+		Generation:               gen.id,
+		Clusters:                 len(gen.clusters),
+		MinClusterSize:           math.MaxInt,
+		TrainedCoarseError:       gen.trainedCoarseError,
+		TrainedQuantizationError: gen.trainedQuantError,
+	}
+	for _, cluster := range gen.clusters {
+		cluster.mutex.RLock()
+		size := int(cluster.storage.count)
+		cluster.mutex.RUnlock()
+		report.Vectors += size
+		report.MinClusterSize = min(report.MinClusterSize, size)
+		report.MaxClusterSize = max(report.MaxClusterSize, size)
+		if size == 0 {
+			report.EmptyClusters++
+		}
+	}
+	if report.Vectors > 0 {
+		report.SizeSkew = float64(report.MaxClusterSize) * float64(report.Clusters) / float64(report.Vectors)
+	}
+
+	for i, vec := range sample {
+		if len(vec) != gen.config.Dimension {
+			return nil, fmt.Errorf("vector %d has dimension %d, expected %d", i, len(vec), gen.config.Dimension)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	report.CoarseError, report.QuantizationError = idx.measureError(gen.clusters, gen.quantizer, driftSample(sample))
+	return report, nil
+}
+
+// measureError returns the mean squared distance from vectors to their
+// assigned centroid and to their reconstruction under q.
+func (idx *Index) measureError(clusters []*Cluster, q quant.Quantizer, vectors [][]float32) (float64, float64) {
+	if len(vectors) == 0 {
+		return 0, 0
+	}
+	var coarse, fine float64
+	for _, vec := range vectors {
+		coarse += squaredDistance(vec, clusters[idx.nearestCluster(clusters, vec)].Centroid)
+		if q == nil || !q.IsTrained() {
+			continue
+		}
+		code, err := q.Compress(vec)
+		if err != nil {
+			continue
+		}
+		if restored, err := q.Decompress(code); err == nil {
+			fine += squaredDistance(vec, restored)
+		}
+	}
+	n := float64(len(vectors))
+	return coarse / n, fine / n
+}
+
+// driftSample returns an evenly strided subset of at most driftSampleLimit
+// vectors.
+func driftSample(vectors [][]float32) [][]float32 {
+	if len(vectors) <= driftSampleLimit {
+		return vectors
+	}
+	sample := make([][]float32, driftSampleLimit)
+	for i := range sample {
+		sample[i] = vectors[i*len(vectors)/driftSampleLimit]
+	}
+	return sample
+}
+
+func squaredDistance(a, b []float32) float64 {
+	var sum float64
+	for i := range a {
+		d := float64(a[i]) - float64(b[i])
+		sum += d * d
+	}
+	return sum
+}
+
+// splitCentroids divides vectors with 2-means seeded on either side of their
+// mean, along the direction of the farthest vector. It reports false when the
+// vectors cannot be separated.
+func splitCentroids(vectors [][]float32) ([]float32, []float32, bool) {
+	mean := make([]float32, len(vectors[0]))
+	for _, vec := range vectors {
+		for d, v := range vec {
+			mean[d] += v
+		}
+	}
+	for d := range mean {
+		mean[d] /= float32(len(vectors))
+	}
+	farthest, farthestDist := vectors[0], -1.0
+	for _, vec := range vectors {
+		if dist := squaredDistance(vec, mean); dist > farthestDist {
+			farthest, farthestDist = vec, dist
+		}
+	}
+	left := make([]float32, len(mean))
+	right := make([]float32, len(mean))
+	for d := range mean {
+		step := (farthest[d] - mean[d]) * 0.1
+		left[d] = mean[d] - step
+		right[d] = mean[d] + step
+	}
+
+	sides := make([]bool, len(vectors))
+	for iter := 0; iter < splitIterations; iter++ {
+		changed := iter == 0
+		leftCount, rightCount := 0, 0
+		for i, vec := range vectors {
+			toRight := squaredDistance(vec, right) < squaredDistance(vec, left)
+			if toRight != sides[i] {
+				changed = true
+			}
+			sides[i] = toRight
+			if toRight {
+				rightCount++
+			} else {
+				leftCount++
+			}
+		}
+		if leftCount == 0 || rightCount == 0 {
+			return nil, nil, false
+		}
+		if !changed {
+			break
+		}
+		clear(left)
+		clear(right)
+		for i, vec := range vectors {
+			target := left
+			if sides[i] {
+				target = right
+			}
+			for d, v := range vec {
+				target[d] += v
+			}
+		}
+		for d := range left {
+			left[d] /= float32(leftCount)
+			right[d] /= float32(rightCount)
+		}
+	}
+	return left, right, true
+}
+
+// cloneQuantizer returns an independent trained copy of q so a replacement
+// generation can own and close it.
+func cloneQuantizer(q quant.Quantizer) (quant.Quantizer, error) {
+	if q == nil || !q.IsTrained() {
+		return nil, nil
+	}
+	state, err := q.SerializeState()
+	if err != nil {
+		return nil, fmt.Errorf("clone quantizer: %w", err)
+	}
+	clone, err := buildQuantizerFromPending(&pendingIndex{quantTag: qTag(q), quantState: state})
+	if err != nil {
+		return nil, fmt.Errorf("clone quantizer: %w", err)
+	}
+	return clone, nil
+}
@@ -0,0 +1,319 @@
+package ivfpq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/quant"
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+// retrainBlobs returns count vectors scattered around blobs centers placed at
+// offset along every axis.
+func retrainBlobs(rng *rand.Rand, count, dim, blobs int, offset float32) [][]float32 {
+	centers := make([][]float32, blobs)
+	for i := range centers {
+		centers[i] = make([]float32, dim)
+		for d := range centers[i] {
+			centers[i][d] = offset + float32(rng.NormFloat64())*4
+		}
+	}
+	vectors := make([][]float32, count)
+	for i := range vectors {
+		center := centers[i%blobs]
+		vec := make([]float32, dim)
+		for d := range vec {
+			vec[d] = center[d] + float32(rng.NormFloat64())*0.5
+		}
+		vectors[i] = vec
+	}
+	return vectors
+}
+
+type retrainFixture struct {
+	idx     *Index
+	vectors map[uint32][]float32
+	next    uint32
+}
+
+func newRetrainFixture(t *testing.T, dim, nClusters int, train [][]float32) *retrainFixture {
+	t.Helper()
+	idx, err := NewIVFPQ(&Config{
+		Dimension:     dim,
+		NClusters:     nClusters,
+		NProbes:       2,
+		Metric:        util.L2Distance,
+		Quantization:  &quant.QuantizationConfig{Type: quant.ProductQuantization, Codebooks: 4, Bits: 6, TrainRatio: 1},
+		MaxIterations: 25,
+		Tolerance:     1e-4,
+		RandomSeed:    3,
+	})
+	if err != nil {
+		t.Fatalf("NewIVFPQ: %v", err)
+	}
+	if err := idx.Train(context.Background(), train); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	return &retrainFixture{idx: idx, vectors: make(map[uint32][]float32)}
+}
+
+func (f *retrainFixture) insert(t *testing.T, vectors [][]float32) {
+	t.Helper()
+	entries := make([]*VectorEntry, len(vectors))
+	for i, vec := range vectors {
+		entries[i] = &VectorEntry{ID: fmt.Sprintf("v%d", f.next), Ordinal: f.next, Vector: vec}
+		f.vectors[f.next] = vec
+		f.next++
+	}
+	if err := f.idx.BatchInsert(context.Background(), entries); err != nil {
+		t.Fatalf("BatchInsert: %v", err)
+	}
+}
+
+func (f *retrainFixture) lookup(ordinal uint32) ([]float32, error) {
+	vec, ok := f.vectors[ordinal]
+	if !ok {
+		return nil, fmt.Errorf("ordinal %d: %w", ordinal, util.ErrNotFound)
+	}
+	return vec, nil
+}
+
+func (f *retrainFixture) sample() [][]float32 {
+	sample := make([][]float32, 0, len(f.vectors))
+	for ordinal := uint32(0); ordinal < f.next; ordinal++ {
+		if vec, ok := f.vectors[ordinal]; ok {
+			sample = append(sample, vec)
+		}
+	}
+	return sample
+}
+
+// selfRecall reports the fraction of records returned among the top 10
+// results for their own vector.
+func (f *retrainFixture) selfRecall(t *testing.T) float64 {
+	t.Helper()
+	found := 0
+	for ordinal, vec := range f.vectors {
+		results, err := f.idx.Search(context.Background(), vec, 10, nil)
+		if err != nil {
+			t.Fatalf("Search: %v", err)
+		}
+		for _, r := range results {
+			if r.Ordinal == ordinal {
+				found++
+				break
+			}
+		}
+	}
+	return float64(found) / float64(len(f.vectors))
+}
+
+func TestRetrainRecoversFromDrift(t *testing.T) {
+	const dim = 16
+	rng := rand.New(rand.NewSource(1))
+	original := retrainBlobs(rng, 400, dim, 8, 0)
+	f := newRetrainFixture(t, dim, 8, original)
+	defer f.idx.Close()
+	f.insert(t, original)
+
+	ctx := context.Background()
+	before, err := f.idx.Drift(ctx, original)
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	if before.TrainedCoarseError <= 0 || before.TrainedQuantizationError <= 0 {
+		t.Fatalf("training baselines not recorded: %+v", before)
+	}
+	if growth := before.ErrorGrowth(); growth > 1.5 {
+		t.Fatalf("error growth on the training distribution = %v", growth)
+	}
+
+	// The corpus grows 10x into a region the frozen centroids never saw.
+	f.insert(t, retrainBlobs(rng, 4000, dim, 8, 25))
+	drifted, err := f.idx.Drift(ctx, f.sample())
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	if drifted.ErrorGrowth() < 2 || drifted.SizeSkew < 2 {
+		t.Fatalf("drift not detected: growth=%v skew=%v", drifted.ErrorGrowth(), drifted.SizeSkew)
+	}
+	recallBefore := f.selfRecall(t)
+
+	if err := f.idx.Retrain(ctx, f.sample(), f.lookup); err != nil {
+		t.Fatalf("Retrain: %v", err)
+	}
+	after, err := f.idx.Drift(ctx, f.sample())
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	if after.Generation == drifted.Generation {
+		t.Fatal("Retrain did not publish a new generation")
+	}
+	if after.Vectors != len(f.vectors) || f.idx.Size() != len(f.vectors) {
+		t.Fatalf("retrained generation holds %d vectors (size %d), want %d", after.Vectors, f.idx.Size(), len(f.vectors))
+	}
+	if growth := after.ErrorGrowth(); growth > 1.2 {
+		t.Fatalf("error growth after retrain = %v", growth)
+	}
+	if after.SizeSkew >= drifted.SizeSkew {
+		t.Fatalf("size skew did not improve: %v -> %v", drifted.SizeSkew, after.SizeSkew)
+	}
+	if recallAfter := f.selfRecall(t); recallAfter < recallBefore || recallAfter < 0.9 {
+		t.Fatalf("self recall %v -> %v", recallBefore, recallAfter)
+	}
+}
+
+func TestRetrainReplaysWritesDuringStaging(t *testing.T) {
+	const dim = 16
+	rng := rand.New(rand.NewSource(2))
+	vectors := retrainBlobs(rng, 600, dim, 8, 0)
+	f := newRetrainFixture(t, dim, 8, vectors)
+	defer f.idx.Close()
+	f.insert(t, vectors)
+
+	ctx := &blockCtx{Context: context.Background(), blocked: make(chan struct{}), unblock: make(chan struct{})}
+	errCh := make(chan error, 1)
+	sample := f.sample()
+	go func() { errCh <- f.idx.Retrain(ctx, sample, f.lookup) }()
+	<-ctx.blocked
+
+	// Searches and writes proceed against the live generation while the
+	// replacement is staged.
+	if _, err := f.idx.Search(context.Background(), vectors[0], 5, nil); err != nil {
+		t.Fatalf("Search during retrain: %v", err)
+	}
+	if err := f.idx.Retrain(context.Background(), sample, f.lookup); !errors.Is(err, ErrRetrainInProgress) {
+		t.Fatalf("second Retrain error = %v, want ErrRetrainInProgress", err)
+	}
+	const inserted uint32 = 900001
+	if err := f.idx.Insert(context.Background(), &VectorEntry{ID: "late", Ordinal: inserted, Vector: vectors[1]}); err != nil {
+		t.Fatalf("Insert during retrain: %v", err)
+	}
+	if err := f.idx.DeleteByOrdinal(context.Background(), 5); err != nil {
+		t.Fatalf("DeleteByOrdinal during retrain: %v", err)
+	}
+	close(ctx.unblock)
+	if err := <-errCh; err != nil {
+		t.Fatalf("Retrain: %v", err)
+	}
+
+	if got, want := f.idx.Size(), len(vectors); got != want {
+		t.Fatalf("Size = %d, want %d", got, want)
+	}
+	if err := f.idx.DeleteByOrdinal(context.Background(), 5); !errors.Is(err, util.ErrNotFound) {
+		t.Fatalf("delete during staging was lost: %v", err)
+	}
+	if err := f.idx.DeleteByOrdinal(context.Background(), inserted); err != nil {
+		t.Fatalf("insert during staging was lost: %v", err)
+	}
+}
+
+func TestRetrainAbortsWhenTrainedInPlace(t *testing.T) {
+	const dim = 16
+	rng := rand.New(rand.NewSource(4))
+	vectors := retrainBlobs(rng, 300, dim, 8, 0)
+	f := newRetrainFixture(t, dim, 8, vectors)
+	defer f.idx.Close()
+	f.insert(t, vectors)
+
+	ctx := &blockCtx{Context: context.Background(), blocked: make(chan struct{}), unblock: make(chan struct{})}
+	errCh := make(chan error, 1)
+	go func() { errCh <- f.idx.Retrain(ctx, vectors, f.lookup) }()
+	<-ctx.blocked
+	before := f.idx.gen.id
+	if err := f.idx.Train(context.Background(), vectors); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	close(ctx.unblock)
+	if err := <-errCh; !errors.Is(err, ErrRetrainConflict) {
+		t.Fatalf("Retrain error = %v, want ErrRetrainConflict", err)
+	}
+	if f.idx.gen.id != before {
+		t.Fatal("conflicting retrain replaced the live generation")
+	}
+}
+
+func TestRebalanceSplitsOversizedClusters(t *testing.T) {
+	const dim = 16
+	rng := rand.New(rand.NewSource(5))
+	train := retrainBlobs(rng, 800, dim, 8, 0)
+	f := newRetrainFixture(t, dim, 8, train)
+	defer f.idx.Close()
+	f.insert(t, train[:80])
+
+	// A tight hot spot lands in a single cluster.
+	hot := make([][]float32, 2000)
+	for i := range hot {
+		vec := append([]float32(nil), train[0]...)
+		for d := range vec {
+			vec[d] += float32(rng.NormFloat64()) * 0.3
+		}
+		hot[i] = vec
+	}
+	f.insert(t, hot)
+
+	ctx := context.Background()
+	before, err := f.idx.Drift(ctx, nil)
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	recallBefore := f.selfRecall(t)
+	splits, err := f.idx.Rebalance(ctx, f.lookup, RebalanceOptions{})
+	if err != nil {
+		t.Fatalf("Rebalance: %v", err)
+	}
+	if splits == 0 {
+		t.Fatalf("no cluster was split at skew %v", before.SizeSkew)
+	}
+	after, err := f.idx.Drift(ctx, nil)
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	if after.Clusters != before.Clusters || after.Vectors != before.Vectors {
+		t.Fatalf("rebalance changed shape: %+v -> %+v", before, after)
+	}
+	if after.MaxClusterSize > before.MaxClusterSize*3/4 {
+		t.Fatalf("largest cluster %d -> %d", before.MaxClusterSize, after.MaxClusterSize)
+	}
+	if after.TrainedQuantizationError != before.TrainedQuantizationError {
+		t.Fatal("rebalance should keep the fine quantizer baseline")
+	}
+	t.Logf("max cluster %d -> %d, splits %d", before.MaxClusterSize, after.MaxClusterSize, splits)
+	if recall := f.selfRecall(t); recall < recallBefore*0.9 {
+		t.Fatalf("self recall %v -> %v", recallBefore, recall)
+	}
+}
+
+func TestTrainingErrorSurvivesPersistence(t *testing.T) {
+	const dim = 16
+	rng := rand.New(rand.NewSource(6))
+	vectors := retrainBlobs(rng, 300, dim, 8, 0)
+	f := newRetrainFixture(t, dim, 8, vectors)
+	defer f.idx.Close()
+	f.insert(t, vectors)
+
+	data, err := f.idx.SerializeToBytes()
+	if err != nil {
+		t.Fatalf("SerializeToBytes: %v", err)
+	}
+	restored, err := NewIVFPQ(f.idx.GetConfig())
+	if err != nil {
+		t.Fatalf("NewIVFPQ: %v", err)
+	}
+	defer restored.Close()
+	if err := restored.DeserializeFromBytes(context.Background(), data); err != nil {
+		t.Fatalf("DeserializeFromBytes: %v", err)
+	}
+	want, _ := f.idx.Drift(context.Background(), nil)
+	got, err := restored.Drift(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("Drift: %v", err)
+	}
+	if got.TrainedCoarseError != want.TrainedCoarseError || got.TrainedQuantizationError != want.TrainedQuantizationError {
+		t.Fatalf("restored baselines %v/%v, want %v/%v", got.TrainedCoarseError, got.TrainedQuantizationError,
+			want.TrainedCoarseError, want.TrainedQuantizationError)
+	}
+}
@@ -36,6 +36,13 @@ func (f *IndexFactory) CreateIndex(indexType IndexType, config interface{}) (Ind
 		}
 		return NewFlat(flatConfig)
 
+	case IndexTypeDiskANN:
+		diskannConfig, ok := config.(*DiskANNConfig)
+		if !ok {
+			return nil, fmt.Errorf("invalid config type for DiskANN index")
+		}
+		return NewDiskANN(diskannConfig)
+
 	default:
 		return nil, fmt.Errorf("unsupported index type: %v", indexType)
 	}
@@ -47,6 +54,7 @@ func (f *IndexFactory) SupportedIndexTypes() []IndexType {
 		IndexTypeHNSW,
 		IndexTypeIVFPQ,
 		IndexTypeFlat,
+		IndexTypeDiskANN,
 	}
 }
 
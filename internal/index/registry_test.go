@@ -86,6 +86,25 @@ func TestIndexFactory_CreateIndex(t *testing.T) {
 			config:      &HNSWConfig{}, // Wrong config type
 			expectError: true,
 		},
+		{
+			name:      "valid DiskANN config",
+			indexType: IndexTypeDiskANN,
+			config: &DiskANNConfig{
+				Dir:       t.TempDir(),
+				Dimension: 128,
+				R:         32,
+				L:         64,
+				Alpha:     1.2,
+				Metric:    util.L2Distance,
+			},
+			expectError: false,
+		},
+		{
+			name:        "invalid config type for DiskANN",
+			indexType:   IndexTypeDiskANN,
+			config:      &FlatConfig{}, // Wrong config type
+			expectError: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -127,7 +146,7 @@ func TestIndexFactory_SupportedIndexTypes(t *testing.T) {
 	factory := NewIndexFactory()
 	supported := factory.SupportedIndexTypes()
 
-	expectedTypes := []IndexType{IndexTypeHNSW, IndexTypeIVFPQ, IndexTypeFlat}
+	expectedTypes := []IndexType{IndexTypeHNSW, IndexTypeIVFPQ, IndexTypeFlat, IndexTypeDiskANN}
 
 	if len(supported) != len(expectedTypes) {
 		t.Errorf("expected %d supported types, got %d", len(expectedTypes), len(supported))
@@ -1,10 +1,12 @@
 package obs
 
 import (
+	"net/http"
 	"sync"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Metrics holds all metrics
@@ -115,6 +117,11 @@ func NewMetrics() *Metrics {
 	return globalMetrics
 }
 
+// Handler serves the metrics in the Prometheus text exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
 // ResetForTesting resets metrics singleton for testing (call in test cleanup)
 func ResetForTesting() {
 	globalMetrics = nil
@@ -0,0 +1,492 @@
+package optimizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xDarkicex/lexer/parser"
+	"github.com/xDarkicex/libravdb/internal/catalog"
+)
+
+// AlterActionKind identifies an ALTER TABLE action planned into
+// PhysicalPlan.DDLAlterActions. ADD/DROP COLUMN keep their dedicated DDL
+// fields.
+type AlterActionKind uint8
+
+const (
+	AlterRenameTable AlterActionKind = iota + 1
+	AlterRenameColumn
+	AlterRenameConstraint
+	AlterColumnType
+	AlterSetNotNull
+	AlterDropNotNull
+	AlterSetDefault
+	AlterDropDefault
+	AlterAddCheck
+	AlterAddUnique
+	AlterAddForeignKey
+	AlterDropConstraint
+)
+
+// AlterTableAction is one planned ALTER TABLE action. Only the fields
+// relevant to Kind are set.
+type AlterTableAction struct {
+	Kind    AlterActionKind
+	Column  string
+	NewName string
+	// Type is the canonical upper-case target type of ALTER COLUMN ... TYPE,
+	// including any modifier list, e.g. "NUMERIC(10,2)".
+	Type string
+	// UsingRoot is the USING expression's root in DDLAlterExprs, or -1.
+	UsingRoot int32
+	// Default is a SET DEFAULT literal in the form CREATE TABLE stores it:
+	// strings decoded, numbers and TRUE/FALSE/NULL as written.
+	Default    string
+	Constraint string
+	// Expression is a CHECK expression as written; Columns are the names it
+	// mentions, or the UNIQUE / FOREIGN KEY column list.
+	Expression string
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+	OnDelete   uint8
+	OnUpdate   uint8
+	IfExists   bool
+}
+
+// optimizeAlterTable plans ALTER TABLE. The grammar models ADD COLUMN and
+// DROP COLUMN; the remaining actions are planned from the statement's tokens
+// by planAlterTable.
+func (o *Optimizer) optimizeAlterTable(doc *parser.QueryDoc, src []byte) (*PhysicalPlan, error) {
+	if plan, handled, err := o.planAlterTable(src); handled {
+		return plan, err
+	}
+	stmt := &doc.AlterTableStmts[0]
+	if stmt.DropColumn {
+		return &PhysicalPlan{
+			Kind:              QueryKindDDL,
+			DDLKind:           4,
+			DDLTableName:      string(src[stmt.TableStart:stmt.TableEnd]),
+			DDLDropColumn:     true,
+			DDLDropColumnName: string(src[stmt.DropColumnStart:stmt.DropColumnEnd]),
+			CollectionName:    string(src[stmt.TableStart:stmt.TableEnd]),
+		}, nil
+	}
+	vectorDimension := stmt.AddColumn.TypeParam
+	typeName := strings.ToUpper(strings.TrimSpace(string(src[stmt.AddColumn.TypeStart:stmt.AddColumn.TypeEnd])))
+	if typeEnd := strings.IndexByte(typeName, '('); typeEnd >= 0 {
+		typeName = strings.TrimSpace(typeName[:typeEnd])
+	}
+	if typeName != "VECTOR" {
+		vectorDimension = 0
+	}
+	return &PhysicalPlan{
+		Kind:         QueryKindDDL,
+		DDLKind:      4,
+		DDLTableName: string(src[stmt.TableStart:stmt.TableEnd]),
+		DDLColumns: []struct {
+			Name            string
+			Type            string
+			VectorDimension uint32
+			Flags           uint16
+		}{{
+			Name:            string(src[stmt.AddColumn.NameStart:stmt.AddColumn.NameEnd]),
+			Type:            string(src[stmt.AddColumn.TypeStart:stmt.AddColumn.TypeEnd]),
+			VectorDimension: vectorDimension,
+			Flags:           stmt.AddColumn.Flags,
+		}},
+		CollectionName: string(src[stmt.TableStart:stmt.TableEnd]),
+	}, nil
+}
+
+// OptimizeAlterTable plans the ALTER TABLE actions the grammar does not
+// model: renames, ALTER COLUMN ... TYPE/NOT NULL/DEFAULT and table
+// constraints. The parser rejects most of these statements outright, so
+// callers use it when parsing fails. handled is false when src is not one of
+// these forms, including a plain ADD/DROP COLUMN.
+func (o *Optimizer) OptimizeAlterTable(src []byte) (*PhysicalPlan, bool, error) {
+	o.src = src
+	return o.planAlterTable(src)
+}
+
+func (o *Optimizer) planAlterTable(src []byte) (*PhysicalPlan, bool, error) {
+	trimmed := strings.TrimSpace(string(src))
+	if len(trimmed) < 5 || !strings.EqualFold(trimmed[:5], "ALTER") {
+		return nil, false, nil
+	}
+	tokens, err := LexSQLTokens(src, true)
+	if err != nil {
+		// Leave malformed text to the parser, which reports it uniformly.
+		return nil, false, nil
+	}
+	p := &tokenParser{src: src, tokens: tokens, end: len(tokens) - 1, statement: "ALTER TABLE"}
+	if !p.keywords("ALTER", "TABLE") {
+		return nil, false, nil
+	}
+	plan := &PhysicalPlan{Kind: QueryKindDDL, DDLKind: 4}
+	plan.DDLIfExists = p.keywords("IF", "EXISTS")
+	p.keywords("ONLY")
+	if plan.DDLTableName, err = p.qualifiedName("a table name"); err != nil {
+		return nil, false, nil
+	}
+	plan.CollectionName = plan.DDLTableName
+
+	// Split the action list on top-level commas and classify each action
+	// before planning any of them.
+	type span struct{ start, end int }
+	var spans []span
+	depth, start, stmtEnd := 0, p.pos, p.end
+	for i := p.pos; i < p.end && stmtEnd == p.end; i++ {
+		token := tokens[i]
+		if token.Kind != SQLTokenPunct {
+			continue
+		}
+		switch token.Text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		case ",":
+			if depth == 0 {
+				spans = append(spans, span{start, i})
+				start = i + 1
+			}
+		case ";":
+			if depth == 0 {
+				stmtEnd = i
+			}
+		}
+	}
+	spans = append(spans, span{start, stmtEnd})
+	for i := stmtEnd + 1; i < p.end; i++ {
+		if !tokens[i].IsPunct(";") {
+			return nil, true, p.errorf("multiple statements are not supported")
+		}
+	}
+
+	legacy := 0
+	for _, s := range spans {
+		p.pos, p.end = s.start, s.end
+		switch {
+		case p.isWordAt(0, "ADD"):
+			if !p.isWordAt(1, "CONSTRAINT") && !p.isWordAt(1, "CHECK") && !p.isWordAt(1, "UNIQUE") &&
+				!p.isWordAt(1, "FOREIGN") && !p.isWordAt(1, "PRIMARY") {
+				legacy++
+			}
+		case p.isWordAt(0, "DROP"):
+			if !p.isWordAt(1, "CONSTRAINT") {
+				legacy++
+			}
+		case p.isWordAt(0, "ALTER"), p.isWordAt(0, "RENAME"):
+		default:
+			if legacy == 0 && len(spans) == 1 {
+				return nil, false, nil
+			}
+			p.pos = s.start
+			return nil, true, p.errorf("unsupported action near %s", p.describe())
+		}
+	}
+	if legacy == len(spans) {
+		return nil, false, nil
+	}
+	if legacy > 0 {
+		return nil, true, p.errorf("ADD/DROP COLUMN cannot be combined with other actions in one statement")
+	}
+
+	for _, s := range spans {
+		p.pos, p.end = s.start, s.end
+		action, err := o.alterAction(p, plan)
+		if err != nil {
+			return nil, true, err
+		}
+		if !p.done() {
+			return nil, true, p.errorf("unexpected %s", p.describe())
+		}
+		plan.DDLAlterActions = append(plan.DDLAlterActions, action)
+	}
+	if len(plan.DDLAlterActions) > 1 {
+		for _, action := range plan.DDLAlterActions {
+			if action.Kind == AlterRenameTable || action.Kind == AlterRenameColumn || action.Kind == AlterRenameConstraint {
+				return nil, true, p.errorf("RENAME cannot be combined with other actions")
+			}
+		}
+	}
+	return plan, true, nil
+}
+
+func (o *Optimizer) alterAction(p *tokenParser, plan *PhysicalPlan) (AlterTableAction, error) {
+	action := AlterTableAction{UsingRoot: -1}
+	var err error
+	switch {
+	case p.keywords("RENAME"):
+		switch {
+		case p.keywords("TO"):
+			action.Kind = AlterRenameTable
+			action.NewName, err = p.identifier("a new table name")
+			return action, err
+		case p.keywords("CONSTRAINT"):
+			action.Kind = AlterRenameConstraint
+			if action.Constraint, err = p.identifier("a constraint name"); err != nil {
+				return action, err
+			}
+		default:
+			p.keywords("COLUMN")
+			action.Kind = AlterRenameColumn
+			if action.Column, err = p.identifier("a column name"); err != nil {
+				return action, err
+			}
+		}
+		if err := p.expectKeywords("TO"); err != nil {
+			return action, err
+		}
+		action.NewName, err = p.identifier("a new name")
+		return action, err
+
+	case p.keywords("ALTER"):
+		p.keywords("COLUMN")
+		if action.Column, err = p.identifier("a column name"); err != nil {
+			return action, err
+		}
+		switch {
+		case p.keywords("SET", "DATA", "TYPE"), p.keywords("TYPE"):
+			action.Kind = AlterColumnType
+			if action.Type, err = p.typeName(); err != nil {
+				return action, err
+			}
+			if p.keywords("USING") {
+				if p.done() {
+					return action, p.errorf("USING requires an expression")
+				}
+				first, last := p.tokens[p.pos], p.tokens[p.end-1]
+				action.UsingRoot, err = o.lowerAlterUsing(string(p.src[first.Start:last.End]), plan)
+				p.pos = p.end
+			}
+			return action, err
+		case p.keywords("SET", "NOT", "NULL"):
+			action.Kind = AlterSetNotNull
+		case p.keywords("DROP", "NOT", "NULL"):
+			action.Kind = AlterDropNotNull
+		case p.keywords("SET", "DEFAULT"):
+			action.Kind = AlterSetDefault
+			action.Default, err = p.defaultLiteral()
+		case p.keywords("DROP", "DEFAULT"):
+			action.Kind = AlterDropDefault
+		default:
+			return action, p.errorf("unsupported ALTER COLUMN action near %s", p.describe())
+		}
+		return action, err
+
+	case p.keywords("DROP", "CONSTRAINT"):
+		action.Kind = AlterDropConstraint
+		action.IfExists = p.keywords("IF", "EXISTS")
+		if action.Constraint, err = p.identifier("a constraint name"); err != nil {
+			return action, err
+		}
+		if !p.keywords("RESTRICT") && p.keywords("CASCADE") {
+			return action, p.errorf("DROP CONSTRAINT ... CASCADE is not supported")
+		}
+		return action, nil
+
+	case p.keywords("ADD"):
+		if p.keywords("CONSTRAINT") {
+			if action.Constraint, err = p.identifier("a constraint name"); err != nil {
+				return action, err
+			}
+		}
+		switch {
+		case p.keywords("CHECK"):
+			action.Kind = AlterAddCheck
+			open := p.peek()
+			if err := p.expectPunct("("); err != nil {
+				return action, err
+			}
+			depth := 1
+			for depth > 0 {
+				token := p.next()
+				switch {
+				case token.Kind == SQLTokenEOF:
+					return action, p.errorf("unterminated CHECK expression")
+				case token.IsPunct("("):
+					depth++
+				case token.IsPunct(")"):
+					depth--
+					if depth == 0 {
+						action.Expression = strings.TrimSpace(string(p.src[open.End:token.Start]))
+					}
+				case token.IsName():
+					action.Columns = append(action.Columns, token.Text)
+				}
+			}
+			if action.Expression == "" {
+				return action, p.errorf("CHECK requires an expression")
+			}
+			p.keywords("NOT", "VALID")
+			return action, nil
+		case p.keywords("UNIQUE"):
+			action.Kind = AlterAddUnique
+			action.Columns, err = p.identifierList("a column name")
+			return action, err
+		case p.keywords("FOREIGN", "KEY"):
+			action.Kind = AlterAddForeignKey
+			if action.Columns, err = p.identifierList("a column name"); err != nil {
+				return action, err
+			}
+			if err := p.expectKeywords("REFERENCES"); err != nil {
+				return action, err
+			}
+			if action.RefTable, err = p.qualifiedName("a referenced table name"); err != nil {
+				return action, err
+			}
+			if p.peek().IsPunct("(") {
+				if action.RefColumns, err = p.identifierList("a referenced column name"); err != nil {
+					return action, err
+				}
+			}
+			for p.keywords("ON") {
+				event := p.next()
+				var target *uint8
+				switch {
+				case event.IsWord("DELETE"):
+					target = &action.OnDelete
+				case event.IsWord("UPDATE"):
+					target = &action.OnUpdate
+				default:
+					return action, p.errorf("expected DELETE or UPDATE after ON")
+				}
+				switch {
+				case p.keywords("CASCADE"):
+					*target = catalog.OnDeleteCascade
+				case p.keywords("RESTRICT"):
+					*target = catalog.OnDeleteRestrict
+				case p.keywords("NO", "ACTION"):
+					*target = catalog.OnDeleteNoAction
+				case p.keywords("SET", "NULL"):
+					*target = catalog.OnDeleteSetNull
+				case p.keywords("SET", "DEFAULT"):
+					*target = catalog.OnDeleteSetDefault
+				default:
+					return action, p.errorf("unsupported referential action near %s", p.describe())
+				}
+			}
+			p.keywords("NOT", "VALID")
+			return action, nil
+		case p.keywords("PRIMARY", "KEY"):
+			return action, p.errorf("adding a PRIMARY KEY to an existing table is not supported")
+		default:
+			return action, p.errorf("unsupported constraint near %s", p.describe())
+		}
+	}
+	return action, p.errorf("unsupported action near %s", p.describe())
+}
+
+// lowerAlterUsing lowers a USING expression into plan.DDLAlterExprs. USING
+// has the semantics of an UPDATE SET assignment evaluated against each row,
+// and the grammar has no standalone expression entry point, so the
+// expression is parsed as the right-hand side of one.
+func (o *Optimizer) lowerAlterUsing(expr string, plan *PhysicalPlan) (int32, error) {
+	src := []byte("UPDATE t SET c = " + expr)
+	doc := &parser.QueryDoc{}
+	if err := parser.Parse(src, doc); err != nil {
+		return -1, fmt.Errorf("ALTER TABLE: invalid USING expression: %w", err)
+	}
+	if len(doc.UpdateStmts) != 1 || len(doc.UpdateStmts[0].SetValues) != 1 ||
+		doc.UpdateStmts[0].WhereExpr.Kind != parser.NodeKindUnknown {
+		return -1, fmt.Errorf("ALTER TABLE: invalid USING expression %q", expr)
+	}
+	first := len(plan.DDLAlterExprs)
+	root, err := o.lowerConflictExpr(doc, src, doc.UpdateStmts[0].SetValues[0], &plan.DDLAlterExprs, &plan.DDLAlterExprCases)
+	if err != nil {
+		return -1, fmt.Errorf("ALTER TABLE: USING expression: %w", err)
+	}
+	for i := first; i < len(plan.DDLAlterExprs); i++ {
+		node := &plan.DDLAlterExprs[i]
+		if node.Kind != ConflictExprColumn {
+			continue
+		}
+		switch name := node.Column; {
+		case strings.EqualFold(name, "TRUE"), strings.EqualFold(name, "FALSE"):
+			*node = ConflictExpr{Kind: ConflictExprLiteral, Literal: []byte(strings.ToLower(name))}
+		case len(name) >= 2 && name[0] == '"' && name[len(name)-1] == '"':
+			node.Column = strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+		}
+	}
+	return root, nil
+}
+
+// typeName reads a SQL type name, including multi-word names and a
+// parenthesized modifier list, and returns it upper-cased in canonical form.
+func (p *tokenParser) typeName() (string, error) {
+	token := p.peek()
+	if token.Kind != SQLTokenWord {
+		return "", p.errorf("expected a type name near %s", p.describe())
+	}
+	p.pos++
+	name := strings.ToUpper(token.Text)
+	switch name {
+	case "DOUBLE":
+		if p.keywords("PRECISION") {
+			name = "DOUBLE PRECISION"
+		}
+	case "CHARACTER":
+		if p.keywords("VARYING") {
+			name = "CHARACTER VARYING"
+		}
+	case "TIMESTAMP", "TIME":
+		if p.keywords("WITH", "TIME", "ZONE") {
+			name += " WITH TIME ZONE"
+		} else {
+			p.keywords("WITHOUT", "TIME", "ZONE")
+		}
+	}
+	if p.punct("(") {
+		var params []string
+		for {
+			param := p.next()
+			if param.Kind != SQLTokenNumber {
+				return "", p.errorf("invalid modifier for type %s", name)
+			}
+			params = append(params, param.Text)
+			if p.punct(")") {
+				break
+			}
+			if err := p.expectPunct(","); err != nil {
+				return "", err
+			}
+		}
+		name += "(" + strings.Join(params, ",") + ")"
+	}
+	if p.punct("[") {
+		return "", p.errorf("array types are not supported")
+	}
+	return name, nil
+}
+
+// defaultLiteral reads a DEFAULT value. Defaults are stored the way CREATE
+// TABLE stores them: string literals decoded, numbers and TRUE/FALSE/NULL as
+// written. A trailing ::type cast, as emitted by ORMs, is accepted.
+func (p *tokenParser) defaultLiteral() (string, error) {
+	negative := p.punct("-")
+	token := p.next()
+	var value string
+	switch {
+	case token.Kind == SQLTokenNumber:
+		value = token.Text
+		if negative {
+			value = "-" + value
+		}
+	case negative:
+		return "", p.errorf("DEFAULT must be a literal value")
+	case token.Kind == SQLTokenString:
+		value = token.Text
+	case token.IsWord("TRUE"), token.IsWord("FALSE"), token.IsWord("NULL"):
+		value = strings.ToUpper(token.Text)
+	default:
+		return "", p.errorf("DEFAULT must be a literal value")
+	}
+	for p.cast() {
+		if _, err := p.typeName(); err != nil {
+			return "", err
+		}
+	}
+	return value, nil
+}
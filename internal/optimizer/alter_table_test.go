@@ -0,0 +1,81 @@
+package optimizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/catalog"
+)
+
+func TestOptimizeAlterTablePlansActions(t *testing.T) {
+	sql := `ALTER TABLE IF EXISTS public.stock ALTER COLUMN qty TYPE NUMERIC(10, 2) USING COALESCE(NULLIF(qty, 'many'), fallback, '0')::integer * 10, ` +
+		`ALTER COLUMN note SET DEFAULT 'n/a'::text, ADD CONSTRAINT positive CHECK (qty > 0), ` +
+		`ADD FOREIGN KEY (team) REFERENCES teams (id) ON DELETE SET NULL`
+	plan, handled, err := NewOptimizer(nil).OptimizeAlterTable([]byte(sql))
+	if !handled || err != nil {
+		t.Fatalf("OptimizeAlterTable: handled=%v err=%v", handled, err)
+	}
+	if plan.Kind != QueryKindDDL || plan.DDLKind != 4 || plan.DDLTableName != "stock" || !plan.DDLIfExists {
+		t.Fatalf("plan = kind %d ddl %d table %q ifExists %v", plan.Kind, plan.DDLKind, plan.DDLTableName, plan.DDLIfExists)
+	}
+	if len(plan.DDLAlterActions) != 4 {
+		t.Fatalf("planned %d actions, want 4", len(plan.DDLAlterActions))
+	}
+
+	typ := plan.DDLAlterActions[0]
+	if typ.Kind != AlterColumnType || typ.Column != "qty" || typ.Type != "NUMERIC(10,2)" || typ.UsingRoot < 0 {
+		t.Fatalf("type action = %+v", typ)
+	}
+	root := plan.DDLAlterExprs[typ.UsingRoot]
+	if root.Kind != ConflictExprBinary {
+		t.Fatalf("USING root kind = %d, want binary", root.Kind)
+	}
+	cast := plan.DDLAlterExprs[root.Left]
+	if cast.Kind != ConflictExprCast || cast.Type != "integer" {
+		t.Fatalf("USING cast = %+v", cast)
+	}
+	// COALESCE(a, b, c) nests as COALESCE(a, COALESCE(b, c)).
+	coalesce := plan.DDLAlterExprs[cast.Left]
+	if coalesce.Kind != ConflictExprFunction || plan.DDLAlterExprs[coalesce.Left].Function != "NULLIF" {
+		t.Fatalf("COALESCE = %+v", coalesce)
+	}
+	tail := plan.DDLAlterExprs[coalesce.Right]
+	if tail.Kind != ConflictExprFunction || plan.DDLAlterExprs[tail.Left].Column != "fallback" ||
+		string(plan.DDLAlterExprs[tail.Right].Literal) != "0" {
+		t.Fatalf("nested COALESCE = %+v", tail)
+	}
+
+	if def := plan.DDLAlterActions[1]; def.Kind != AlterSetDefault || def.Column != "note" || def.Default != "n/a" {
+		t.Fatalf("default action = %+v", def)
+	}
+	if check := plan.DDLAlterActions[2]; check.Kind != AlterAddCheck || check.Constraint != "positive" || check.Expression != "qty > 0" {
+		t.Fatalf("check action = %+v", check)
+	}
+	fk := plan.DDLAlterActions[3]
+	if fk.Kind != AlterAddForeignKey || fk.RefTable != "teams" || len(fk.Columns) != 1 || fk.Columns[0] != "team" ||
+		fk.OnDelete != catalog.OnDeleteSetNull {
+		t.Fatalf("foreign key action = %+v", fk)
+	}
+}
+
+func TestOptimizeAlterTableLeavesColumnDDLToGrammar(t *testing.T) {
+	for _, sql := range []string{
+		`ALTER TABLE t ADD COLUMN c TEXT`,
+		`ALTER TABLE t DROP COLUMN c`,
+		`SELECT 1`,
+	} {
+		if _, handled, err := NewOptimizer(nil).OptimizeAlterTable([]byte(sql)); handled || err != nil {
+			t.Fatalf("%s: handled=%v err=%v", sql, handled, err)
+		}
+	}
+	for sql, want := range map[string]string{
+		`ALTER TABLE t ADD COLUMN c TEXT, RENAME TO u`:    "ADD/DROP COLUMN cannot be combined",
+		`ALTER TABLE t RENAME TO u, ALTER c DROP DEFAULT`: "RENAME cannot be combined",
+		`ALTER TABLE t ALTER c TYPE INT USING`:            "USING requires an expression",
+	} {
+		_, handled, err := NewOptimizer(nil).OptimizeAlterTable([]byte(sql))
+		if !handled || err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: handled=%v err=%v, want %q", sql, handled, err, want)
+		}
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/xDarkicex/lexer/parser"
 	"github.com/xDarkicex/libravdb/internal/catalog"
 	"github.com/xDarkicex/libravdb/internal/graph"
+	"github.com/xDarkicex/libravdb/internal/sparse"
 )
 
 var (
@@ -53,7 +54,8 @@ func resolveVectorParam(src []byte, id *parser.Identifier, params *ParameterSet)
 	return nil, false
 }
 
-func resolveVectorOperand(doc *parser.QueryDoc, src []byte, ref parser.NodeRef, params *ParameterSet) ([]float32, error) {
+func (o *Optimizer) resolveVectorOperand(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) ([]float32, error) {
+	params := o.boundParams
 	switch ref.Kind {
 	case parser.NodeKindCastExpr:
 		// PostgreSQL clients commonly bind pgvector parameters as
@@ -63,7 +65,7 @@ func resolveVectorOperand(doc *parser.QueryDoc, src []byte, ref parser.NodeRef,
 		if ref.ID < 0 || int(ref.ID) >= len(doc.CastExprs) {
 			return nil, fmt.Errorf("vector cast expression is malformed")
 		}
-		return resolveVectorOperand(doc, src, doc.CastExprs[ref.ID].Expr, params)
+		return o.resolveVectorOperand(doc, src, doc.CastExprs[ref.ID].Expr)
 	case parser.NodeKindString:
 		return parseVectorLiteral(doc, src, ref.ID), nil
 	case parser.NodeKindIdentifier:
@@ -74,6 +76,14 @@ func resolveVectorOperand(doc *parser.QueryDoc, src []byte, ref parser.NodeRef,
 		if int(id.Start) < len(src) && (src[id.Start] == '$' || src[id.Start] == '@') {
 			return nil, fmt.Errorf("vector parameter %q is missing or is not []float32", string(src[id.Start:id.End]))
 		}
+	case parser.NodeKindFunctionExpr:
+		if IsEmbedCall(doc, src, ref) {
+			vec, null, err := o.resolveEmbed(doc, src, ref)
+			if err == nil && null {
+				err = fmt.Errorf("EMBED: text of a vector query operand must not be NULL")
+			}
+			return vec, err
+		}
 	}
 	return nil, fmt.Errorf("vector query operand must be a vector literal or named vector parameter")
 }
@@ -176,6 +186,7 @@ type GraphJoinPlan struct {
 	TerminalLabels     []string              // all labels required on the terminal
 	PathAlias          string                // optional Cypher path variable (p = (...))
 	Shortest           bool                  // shortestPath wrapper requested
+	Weighted           *WeightedPathCost     // weightedShortestPath cost model; nil counts hops
 	SourcePredicates   []RelationalPredicate // inline predicates on the anchor vertex
 	TerminalPredicates []RelationalPredicate // WHERE predicates bound to final vertex
 	// PredicateMatch is true when the graph path came from WHERE MATCH
@@ -214,9 +225,23 @@ type RRFComponent struct {
 	Kind        uint8
 	Ascending   bool // lower-is-better, e.g. VECTOR_DISTANCE
 	Vector      []float32
-	TextColumn  string
+	TextColumn  string // FTS_RANK text column or SPARSE_INNER_PRODUCT sparse column
 	TextQuery   string
+	SparseQuery sparse.Vector
+	SourceAlias string
+	// Function and FunctionArgs describe a call to a function registered
+	// with the database; the executor resolves the name at run time.
+	Function     string
+	FunctionArgs []RRFFunctionArg
+}
+
+// RRFFunctionArg is one argument of a registered-function RRF signal: a
+// column of the candidate row, or a constant from a literal or parameter.
+type RRFFunctionArg struct {
+	IsColumn    bool
+	Column      string
 	SourceAlias string
+	Value       ScalarValue
 }
 
 // FTSRankProjection describes a standalone lexical score projection. RRF
@@ -303,6 +328,8 @@ const (
 	RRFComponentVectorDistance uint8 = iota
 	RRFComponentFTSRank
 	RRFComponentGraphCentrality
+	RRFComponentSparseInnerProduct
+	RRFComponentFunction
 )
 
 // GraphEdgePlan is a single edge extracted from the MATCH path,
@@ -450,6 +477,9 @@ type PhysicalPlan struct {
 	IsDesc   bool   // ORDER BY DESC
 	Distinct bool   // SELECT DISTINCT projection deduplication
 	Offset   int    // rows to skip after projection/order, before LIMIT
+	// MMR re-ranks an ORDER BY MMR(...) nearest-neighbour plan by maximal
+	// marginal relevance; nil for every other ordering.
+	MMR *MMRPlan
 
 	// Vector function projections — populated when a SELECT list contains
 	// SIMILARITY(...) or VECTOR_DISTANCE(...). Each entry pairs the projected
@@ -516,7 +546,7 @@ type PhysicalPlan struct {
 	HavingAggregateColumn string   // aggregate argument, empty for COUNT(*)
 
 	// DDL fields — populated when Kind == QueryKindDDL
-	DDLKind                 uint8 // 0=create table, 1=drop table, 2=create index, 3=drop index, 4=alter table, 5=create edge type
+	DDLKind                 uint8 // 0=create table, 1=drop table, 2=create index, 3=drop index, 4=alter table, 5=create edge type, 6=create view, 7=drop view, 8=refresh materialized view
 	DDLTableName            string
 	DDLEdgeTypeName         string
 	DDLEdgeTypeUndirected   bool
@@ -540,6 +570,11 @@ type PhysicalPlan struct {
 	// surrounding SQL quotes.
 	DDLJSONPath string
 	DDLJSONText bool
+	// DDLIndexMethod is the lower-cased CREATE INDEX ... USING access method
+	// ("" when omitted) and DDLIndexOptions its WITH (name = value) storage
+	// parameters, keyed by lower-cased name.
+	DDLIndexMethod  string
+	DDLIndexOptions map[string]string
 
 	// DDLForeignKeys carries parsed FK constraints for CREATE TABLE.
 	DDLForeignKeys []DDLForeignKey
@@ -552,11 +587,31 @@ type PhysicalPlan struct {
 	// DDLColumnDefaults maps column name → default value string for columns
 	// declared with DEFAULT <literal>.
 	DDLColumnDefaults map[string]string
+	// DDLGeneratedVector is the table's GENERATED FROM (...) USING <model>
+	// vector column, when it declares one.
+	DDLGeneratedVector *GeneratedVectorPlan
 
 	// DDLExternalKey is set when a CREATE TABLE FK references GRAPH_NODES,
 	// signalling the optimizer that graph-aware join planning is applicable.
 	DDLExternalKey bool
 
+	// DDLAlterActions carries the ALTER TABLE actions other than ADD/DROP
+	// COLUMN, in statement order. USING expressions are lowered into
+	// DDLAlterExprs/DDLAlterExprCases exactly like UPDATE SET assignments.
+	DDLAlterActions   []AlterTableAction
+	DDLAlterExprs     []ConflictExpr
+	DDLAlterExprCases []ConflictCase
+
+	// View DDL fields. DDLViewNames lists the views a statement names;
+	// DDLViewQuery is the defining SELECT of CREATE VIEW, already checked to
+	// parse. DDLViewWithData is false for WITH NO DATA.
+	DDLViewNames        []string
+	DDLViewQuery        string
+	DDLViewMaterialized bool
+	DDLViewOrReplace    bool
+	DDLViewIfNotExists  bool
+	DDLViewWithData     bool
+
 	// Recall contract for hybrid vector queries. Default is RecallExact.
 	RecallContract uint8 // 0=Exact, 1=Bounded, 2=BestEffort
 }
@@ -574,6 +629,10 @@ type Optimizer struct {
 	src         []byte
 	params      map[string]interface{} // compatibility-only DML boundary
 	boundParams *ParameterSet
+	embed       EmbedFunc
+	// weightedPath is the cost model of the statement's weightedShortestPath
+	// call, attached to the shortest-path join it wraps.
+	weightedPath *WeightedPathCost
 }
 
 func NewOptimizer(cat *catalog.Catalog) *Optimizer {
@@ -614,6 +673,11 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 	o.src = src
 	o.params = legacyParams
 	o.boundParams = params
+	weightedPath, err := weightedPathCost(src)
+	if err != nil {
+		return nil, err
+	}
+	o.weightedPath = weightedPath
 	// DDL statements — dispatched directly
 	if len(doc.CreateEdgeTypeStmts) > 0 {
 		return o.optimizeCreateEdgeType(doc, src)
@@ -768,6 +832,9 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 			// at execution time and uses the normal joined-row projection path.
 			if gt.TableStart == gt.TableEnd {
 				gjp := GraphJoinPlan{GraphEdges: plan.GraphEdges, MaxHops: plan.MaxHops, JoinType: 0, Shortest: mp.Shortest}
+				if mp.Shortest {
+					gjp.Weighted = o.weightedPath
+				}
 				if mp.PathAliasEnd > mp.PathAlias {
 					gjp.PathAlias = string(src[mp.PathAlias:mp.PathAliasEnd])
 				}
@@ -892,7 +959,7 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 					plan.HasVectorSearch = true
 					plan.VectorIndexOID = id.TableOID // For columns, this is the Table OID, which matches CollectionOID
 
-					vec, err := resolveVectorOperand(doc, src, vf.VectorB, o.boundParams)
+					vec, err := o.resolveVectorOperand(doc, src, vf.VectorB)
 					if err != nil {
 						return nil, err
 					}
@@ -914,6 +981,9 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 				JoinType:       uint8(jc.Type),
 				Shortest:       mp.Shortest,
 			}
+			if mp.Shortest {
+				gjp.Weighted = o.weightedPath
+			}
 			if mp.PathAliasEnd > mp.PathAlias {
 				gjp.PathAlias = string(src[mp.PathAlias:mp.PathAliasEnd])
 			}
@@ -1018,7 +1088,7 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 		plan.OrderBy = ""
 	} else if stmt.OrderBy.Kind == parser.NodeKindVectorFunc {
 		vf := &doc.VectorFuncs[stmt.OrderBy.ID]
-		vec, err := resolveVectorOperand(doc, src, vf.VectorB, o.boundParams)
+		vec, err := o.resolveVectorOperand(doc, src, vf.VectorB)
 		if err != nil {
 			return nil, err
 		}
@@ -1026,6 +1096,18 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 		plan.HasVectorSearch = true
 		plan.OrderBy = "vector_distance"
 		plan.IsDesc = stmt.IsDesc
+	} else if IsMMRCall(doc, src, stmt.OrderBy) {
+		if stmt.IsDesc {
+			return nil, fmt.Errorf("MMR ordering cannot be DESC")
+		}
+		mmr, vec, err := o.lowerMMR(doc, src, stmt.OrderBy)
+		if err != nil {
+			return nil, err
+		}
+		plan.QueryVector = vec
+		plan.HasVectorSearch = true
+		plan.OrderBy = "vector_distance"
+		plan.MMR = mmr
 	} else if stmt.OrderBy.Kind == parser.NodeKindIdentifier {
 		id := &doc.Identifiers[stmt.OrderBy.ID]
 		plan.OrderBy = string(src[id.Start:id.End])
@@ -1102,7 +1184,7 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 				} else {
 					vfp.Name = "vector_distance"
 				}
-				vec, err := resolveVectorOperand(doc, src, vf.VectorB, o.boundParams)
+				vec, err := o.resolveVectorOperand(doc, src, vf.VectorB)
 				if err != nil {
 					return nil, err
 				}
@@ -1174,6 +1256,8 @@ func (o *Optimizer) optimize(doc *parser.QueryDoc, src []byte, legacyParams map[
 					plan.Projections = append(plan.Projections, name)
 					plan.Kind = QueryKindRelational
 					plan.HasRelationalQuery = true
+				} else if IsMMRCall(doc, src, proj.Expr) {
+					return nil, ErrMMRPlacement
 				} else {
 					return nil, fmt.Errorf("unsupported function projection %q", string(src[fn.NameStart:fn.NameEnd]))
 				}
@@ -1591,6 +1675,9 @@ func (o *Optimizer) lowerShortestPathProjection(doc *parser.QueryDoc, src []byte
 	if proj == nil || proj.Expr.Kind != parser.NodeKindShortestPath || proj.Expr.ID < 0 || int(proj.Expr.ID) >= len(doc.ShortestPaths) {
 		return GraphPatternProjection{}, fmt.Errorf("invalid shortestPath expression")
 	}
+	if o.weightedPath != nil {
+		return GraphPatternProjection{}, fmt.Errorf("weightedShortestPath must bind a MATCH path, e.g. MATCH p = weightedShortestPath(...)")
+	}
 	sp := &doc.ShortestPaths[proj.Expr.ID]
 	if sp.MatchPath.Kind != parser.NodeKindMatchPath || sp.MatchPath.ID < 0 || int(sp.MatchPath.ID) >= len(doc.MatchPaths) {
 		return GraphPatternProjection{}, fmt.Errorf("shortestPath requires a graph path")
@@ -2110,7 +2197,8 @@ func (o *Optimizer) lowerScoreExprs(doc *parser.QueryDoc, stmt *parser.SelectStm
 }
 
 // lowerRRF validates and lowers RRF(signal, ...) into compact component
-// descriptors. RRF deliberately accepts only database-owned scoring signals;
+// descriptors. RRF deliberately accepts only database-owned scoring signals
+// and direct calls to registered functions, which rank higher results first;
 // arbitrary scalar expressions would make rank direction ambiguous and would
 // turn a deterministic rank fusion operator into raw arithmetic.
 func (o *Optimizer) lowerRRF(doc *parser.QueryDoc, src []byte, ref parser.NodeRef, plan *PhysicalPlan) error {
@@ -2154,7 +2242,7 @@ func (o *Optimizer) lowerRRFComponent(doc *parser.QueryDoc, src []byte, ref pars
 			return RRFComponent{}, fmt.Errorf("vector signal is invalid")
 		}
 		vf := &doc.VectorFuncs[ref.ID]
-		vector, err := resolveVectorOperand(doc, src, vf.VectorB, o.boundParams)
+		vector, err := o.resolveVectorOperand(doc, src, vf.VectorB)
 		if err != nil {
 			return RRFComponent{}, err
 		}
@@ -2184,12 +2272,120 @@ func (o *Optimizer) lowerRRFComponent(doc *parser.QueryDoc, src []byte, ref pars
 		if ref.ID < 0 || int(ref.ID) >= len(doc.FunctionExprs) {
 			return RRFComponent{}, fmt.Errorf("lexical signal is invalid")
 		}
+		fn := &doc.FunctionExprs[ref.ID]
+		if asciiEqualFold(src[fn.NameStart:fn.NameEnd], []byte("SPARSE_INNER_PRODUCT")) {
+			component, err := o.lowerSparseInnerProduct(doc, src, ref)
+			component.Kind = RRFComponentSparseInnerProduct
+			return component, err
+		}
+		if !asciiEqualFold(src[fn.NameStart:fn.NameEnd], []byte("FTS_RANK")) {
+			return o.lowerRRFFunction(doc, src, ref)
+		}
 		component, err := o.lowerFTSRank(doc, src, ref)
 		component.Kind = RRFComponentFTSRank
 		return component, err
 	default:
-		return RRFComponent{}, fmt.Errorf("expected VECTOR_DISTANCE, FTS_RANK, or GRAPH_CENTRALITY")
+		return RRFComponent{}, fmt.Errorf("expected VECTOR_DISTANCE, FTS_RANK, SPARSE_INNER_PRODUCT, GRAPH_CENTRALITY, or a registered function")
+	}
+}
+
+// lowerRRFFunction lowers name(arg, ...) as a registered-function signal.
+// The optimizer has no view of the database's function registry, so an
+// unknown name is reported by the executor. Each argument must be a column,
+// a literal or a bound parameter; parameters are resolved here.
+func (o *Optimizer) lowerRRFFunction(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) (RRFComponent, error) {
+	fn := &doc.FunctionExprs[ref.ID]
+	name := string(src[fn.NameStart:fn.NameEnd])
+	if fn.HasWindow || fn.ArgsStart < 0 || fn.ArgsStart+fn.ArgsCount > int32(len(doc.FunctionArgs)) {
+		return RRFComponent{}, fmt.Errorf("%s is not a valid ranking signal", name)
+	}
+	component := RRFComponent{Kind: RRFComponentFunction, Function: name, FunctionArgs: make([]RRFFunctionArg, 0, fn.ArgsCount)}
+	for i := int32(0); i < fn.ArgsCount; i++ {
+		argRef := doc.FunctionArgs[fn.ArgsStart+i]
+		var arg RRFFunctionArg
+		switch argRef.Kind {
+		case parser.NodeKindString:
+			if argRef.ID < 0 || int(argRef.ID) >= len(doc.Strings) {
+				return RRFComponent{}, fmt.Errorf("%s argument %d is invalid", name, i+1)
+			}
+			arg.Value = StringValue(string(decodeSQLStringLiteral(src, doc.Strings[argRef.ID])))
+		case parser.NodeKindNumber:
+			if argRef.ID < 0 || int(argRef.ID) >= len(doc.Numbers) {
+				return RRFComponent{}, fmt.Errorf("%s argument %d is invalid", name, i+1)
+			}
+			num := &doc.Numbers[argRef.ID]
+			arg.Value = ScalarFromLiteralBytes(src[num.Start:num.End])
+		case parser.NodeKindIdentifier:
+			if argRef.ID < 0 || int(argRef.ID) >= len(doc.Identifiers) {
+				return RRFComponent{}, fmt.Errorf("%s argument %d is invalid", name, i+1)
+			}
+			id := &doc.Identifiers[argRef.ID]
+			text := src[id.Start:id.End]
+			if src[id.Start] == '$' || src[id.Start] == '@' {
+				value, found := o.resolveParamScalar(doc, src, argRef)
+				if !found {
+					return RRFComponent{}, fmt.Errorf("%s argument %d: parameter %s is not bound", name, i+1, text)
+				}
+				arg.Value = value
+				break
+			}
+			if literal := ScalarFromLiteralBytes(text); literal.Kind == ScalarNull || literal.Kind == ScalarBool {
+				arg.Value = literal
+				break
+			}
+			arg.IsColumn = true
+			arg.Column = string(text)
+			if id.QualEnd > id.QualStart {
+				arg.SourceAlias = string(src[id.QualStart:id.QualEnd])
+			}
+		default:
+			return RRFComponent{}, fmt.Errorf("%s argument %d must be a column, literal or parameter", name, i+1)
+		}
+		component.FunctionArgs = append(component.FunctionArgs, arg)
 	}
+	return component, nil
+}
+
+// lowerSparseInnerProduct lowers SPARSE_INNER_PRODUCT(column, query), where
+// query is a sparsevec literal such as '{1:0.5,7:2}/30522' or a bound text
+// parameter. The literal is decoded once here; higher products rank first.
+func (o *Optimizer) lowerSparseInnerProduct(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) (RRFComponent, error) {
+	fn := &doc.FunctionExprs[ref.ID]
+	if fn.HasWindow || fn.ArgsCount != 2 || fn.ArgsStart < 0 || fn.ArgsStart+fn.ArgsCount > int32(len(doc.FunctionArgs)) {
+		return RRFComponent{}, fmt.Errorf("SPARSE_INNER_PRODUCT requires sparse column and query arguments")
+	}
+	columnRef := doc.FunctionArgs[fn.ArgsStart]
+	if columnRef.Kind != parser.NodeKindIdentifier || columnRef.ID < 0 || int(columnRef.ID) >= len(doc.Identifiers) {
+		return RRFComponent{}, fmt.Errorf("SPARSE_INNER_PRODUCT first argument must be a sparse vector column")
+	}
+	column := &doc.Identifiers[columnRef.ID]
+	component := RRFComponent{TextColumn: string(src[column.Start:column.End])}
+	if column.QualEnd > column.QualStart {
+		component.SourceAlias = string(src[column.QualStart:column.QualEnd])
+	}
+	var literal string
+	queryRef := doc.FunctionArgs[fn.ArgsStart+1]
+	switch queryRef.Kind {
+	case parser.NodeKindString:
+		if queryRef.ID < 0 || int(queryRef.ID) >= len(doc.Strings) {
+			return RRFComponent{}, fmt.Errorf("SPARSE_INNER_PRODUCT query literal is invalid")
+		}
+		literal = string(decodeSQLStringLiteral(src, doc.Strings[queryRef.ID]))
+	case parser.NodeKindIdentifier:
+		value, found := o.resolveParamScalar(doc, src, queryRef)
+		if !found || value.IsNull() || (value.Kind != ScalarString && value.Kind != ScalarBytes) {
+			return RRFComponent{}, fmt.Errorf("SPARSE_INNER_PRODUCT query must be a sparsevec literal or bound text parameter")
+		}
+		literal = string(value.BytesData)
+	default:
+		return RRFComponent{}, fmt.Errorf("SPARSE_INNER_PRODUCT query must be a sparsevec literal or bound text parameter")
+	}
+	query, err := sparse.Parse(literal)
+	if err != nil {
+		return RRFComponent{}, fmt.Errorf("SPARSE_INNER_PRODUCT query: %w", err)
+	}
+	component.SparseQuery = query
+	return component, nil
 }
 
 func (o *Optimizer) lowerFTSRank(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) (RRFComponent, error) {
@@ -2454,7 +2650,7 @@ func (o *Optimizer) lowerScoreExpr(doc *parser.QueryDoc, node parser.NodeRef, pl
 	if node.Kind == parser.NodeKindVectorFunc {
 		plan.HasScoreExpr = true
 		vf := &doc.VectorFuncs[node.ID]
-		if vec, err := resolveVectorOperand(doc, o.src, vf.VectorB, o.boundParams); err == nil {
+		if vec, err := o.resolveVectorOperand(doc, o.src, vf.VectorB); err == nil {
 			plan.QueryVector = vec
 			plan.HasVectorSearch = true
 		}
@@ -2840,7 +3036,7 @@ func (o *Optimizer) lowerVectorOperator(doc *parser.QueryDoc, src []byte, ref pa
 	if id.ResolvedKind != parser.ResolvedKindVector && id.ResolvedKind != parser.ResolvedKindColumn {
 		return vectorOperatorInfo{}, fmt.Errorf("vector operator left operand must resolve to a vector column")
 	}
-	vector, err := resolveVectorOperand(doc, src, be.Right, o.boundParams)
+	vector, err := o.resolveVectorOperand(doc, src, be.Right)
 	if err != nil {
 		return vectorOperatorInfo{}, err
 	}
@@ -2915,6 +3111,15 @@ func (o *Optimizer) optimizeInsert(doc *parser.QueryDoc, src []byte) (*PhysicalP
 	}
 	if !stmt.HasSelect {
 		for _, val := range stmt.Values {
+			if IsEmbedCall(doc, src, val) {
+				literal, isNull, err := o.embedLiteral(doc, src, val)
+				if err != nil {
+					return nil, err
+				}
+				plan.InsertValues = append(plan.InsertValues, literal)
+				plan.InsertValueNull = append(plan.InsertValueNull, isNull)
+				continue
+			}
 			literal, isNull, ok := o.lowerDMLLiteral(doc, src, val)
 			if !ok {
 				continue
@@ -3105,6 +3310,15 @@ func (o *Optimizer) lowerConflictExpr(doc *parser.QueryDoc, src []byte, ref pars
 		}
 		fn := doc.FunctionExprs[ref.ID]
 		name := string(src[fn.NameStart:fn.NameEnd])
+		if IsEmbedCall(doc, src, ref) {
+			literal, isNull, err := o.embedLiteral(doc, src, ref)
+			if err != nil {
+				return -1, err
+			}
+			root := int32(len(*out))
+			*out = append(*out, ConflictExpr{Kind: ConflictExprLiteral, Literal: literal, IsNull: isNull})
+			return root, nil
+		}
 		if isJSONMutationFunction(name) {
 			if fn.ArgsStart < 0 || fn.ArgsCount < 3 || fn.ArgsCount > 4 ||
 				fn.ArgsStart+fn.ArgsCount > int32(len(doc.FunctionArgs)) {
@@ -3130,23 +3344,49 @@ func (o *Optimizer) lowerConflictExpr(doc *parser.QueryDoc, src []byte, ref pars
 			*out = append(*out, expr)
 			return root, nil
 		}
-		if !strings.EqualFold(name, "NOW") && !strings.EqualFold(name, "NULLIF") {
+		minArgs, maxArgs := int32(0), int32(0)
+		switch strings.ToUpper(name) {
+		case "NOW":
+		case "NULLIF":
+			minArgs, maxArgs = 2, 2
+		case "LOWER", "UPPER", "TRIM", "LENGTH", "ABS", "FLOOR", "CEIL", "CEILING":
+			minArgs, maxArgs = 1, 1
+		case "ROUND":
+			minArgs, maxArgs = 1, 2
+		case "COALESCE":
+			minArgs, maxArgs = 1, -1
+		default:
 			return -1, fmt.Errorf("unsupported ON CONFLICT function %q", name)
 		}
-		expr := ConflictExpr{Kind: ConflictExprFunction, Function: name, Left: -1, Right: -1}
-		if fn.ArgsCount > 0 {
-			if fn.ArgsCount != 2 || !strings.EqualFold(name, "NULLIF") {
+		if fn.ArgsCount < minArgs || maxArgs >= 0 && fn.ArgsCount > maxArgs ||
+			fn.ArgsCount > 0 && (fn.ArgsStart < 0 || fn.ArgsStart+fn.ArgsCount > int32(len(doc.FunctionArgs))) {
+			if minArgs == 2 && maxArgs == 2 {
 				return -1, fmt.Errorf("function %q requires exactly two arguments", name)
 			}
-			left, err := o.lowerConflictExpr(doc, src, doc.FunctionArgs[fn.ArgsStart], out, cases)
-			if err != nil {
-				return -1, err
-			}
-			right, err := o.lowerConflictExpr(doc, src, doc.FunctionArgs[fn.ArgsStart+1], out, cases)
+			return -1, fmt.Errorf("function %q called with %d arguments", name, fn.ArgsCount)
+		}
+		args := make([]int32, fn.ArgsCount)
+		for i := range args {
+			arg, err := o.lowerConflictExpr(doc, src, doc.FunctionArgs[fn.ArgsStart+int32(i)], out, cases)
 			if err != nil {
 				return -1, err
 			}
-			expr.Left, expr.Right = left, right
+			args[i] = arg
+		}
+		// Function nodes have two operand slots; a variadic COALESCE is
+		// nested right to left, COALESCE(a, COALESCE(b, c)).
+		for len(args) > 2 {
+			last := len(args) - 2
+			nested := int32(len(*out))
+			*out = append(*out, ConflictExpr{Kind: ConflictExprFunction, Function: name, Left: args[last], Right: args[last+1]})
+			args = append(args[:last], nested)
+		}
+		expr := ConflictExpr{Kind: ConflictExprFunction, Function: name, Left: -1, Right: -1}
+		if len(args) > 0 {
+			expr.Left = args[0]
+		}
+		if len(args) > 1 {
+			expr.Right = args[1]
 		}
 		root := int32(len(*out))
 		*out = append(*out, expr)
@@ -3445,6 +3685,11 @@ func (o *Optimizer) optimizeCreateTable(doc *parser.QueryDoc, src []byte) (*Phys
 		colName := string(src[col.NameStart:col.NameEnd])
 		plan.DDLColumnDefaults[colName] = extractDefaultValue(doc, src, col.DefaultExpr)
 	}
+	generated, err := generatedVectorColumn(src)
+	if err != nil {
+		return nil, err
+	}
+	plan.DDLGeneratedVector = generated
 	return plan, nil
 }
 
@@ -3517,55 +3762,102 @@ func (o *Optimizer) optimizeCreateIndex(doc *parser.QueryDoc, src []byte) (*Phys
 	if len(plan.DDLIndexColumns) == 0 && stmt.ColEnd > stmt.ColStart {
 		plan.DDLIndexColumns = append(plan.DDLIndexColumns, string(src[stmt.ColStart:stmt.ColEnd]))
 	}
+	if int(stmt.TableEnd) <= len(src) {
+		plan.DDLIndexMethod, plan.DDLIndexOptions = createIndexClauses(string(src[stmt.TableEnd:]))
+	}
 	return plan, nil
 }
 
-func (o *Optimizer) optimizeDropIndex(doc *parser.QueryDoc, src []byte) (*PhysicalPlan, error) {
-	stmt := &doc.DropIndexStmts[0]
-	return &PhysicalPlan{
-		Kind:         QueryKindDDL,
-		DDLKind:      3,
-		DDLIndexName: string(src[stmt.IndexStart:stmt.IndexEnd]),
-		DDLIfExists:  stmt.IfExists,
-	}, nil
+// createIndexClauses recovers the optional access-method and storage
+// parameter clauses that follow the table name of a CREATE INDEX:
+//
+//	[USING method] (columns) [WITH (name = value [, ...])]
+func createIndexClauses(text string) (string, map[string]string) {
+	var method string
+	rest := strings.TrimSpace(text)
+	if keyword, tail := leadingSQLWord(rest); strings.EqualFold(keyword, "USING") {
+		method, rest = leadingSQLWord(strings.TrimSpace(tail))
+		method = strings.ToLower(method)
+		rest = strings.TrimSpace(rest)
+	}
+	end := closingParen(rest)
+	if end < 0 {
+		return method, nil
+	}
+	rest = strings.TrimSpace(rest[end+1:])
+	keyword, tail := leadingSQLWord(rest)
+	if !strings.EqualFold(keyword, "WITH") {
+		return method, nil
+	}
+	rest = strings.TrimSpace(tail)
+	end = closingParen(rest)
+	if end < 0 {
+		return method, nil
+	}
+	options := make(map[string]string)
+	for _, param := range strings.Split(rest[1:end], ",") {
+		name, value, found := strings.Cut(param, "=")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+			value = strings.ReplaceAll(value[1:len(value)-1], "''", "'")
+		}
+		options[strings.ToLower(strings.TrimSpace(name))] = value
+	}
+	return method, options
 }
 
-func (o *Optimizer) optimizeAlterTable(doc *parser.QueryDoc, src []byte) (*PhysicalPlan, error) {
-	stmt := &doc.AlterTableStmts[0]
-	if stmt.DropColumn {
-		return &PhysicalPlan{
-			Kind:              QueryKindDDL,
-			DDLKind:           4,
-			DDLTableName:      string(src[stmt.TableStart:stmt.TableEnd]),
-			DDLDropColumn:     true,
-			DDLDropColumnName: string(src[stmt.DropColumnStart:stmt.DropColumnEnd]),
-			CollectionName:    string(src[stmt.TableStart:stmt.TableEnd]),
-		}, nil
+// leadingSQLWord splits an unquoted identifier or keyword off text.
+func leadingSQLWord(text string) (string, string) {
+	end := 0
+	for end < len(text) {
+		ch := text[end]
+		if ch != '_' && (ch < '0' || ch > '9') && (ch < 'a' || ch > 'z') && (ch < 'A' || ch > 'Z') {
+			break
+		}
+		end++
 	}
-	vectorDimension := stmt.AddColumn.TypeParam
-	typeName := strings.ToUpper(strings.TrimSpace(string(src[stmt.AddColumn.TypeStart:stmt.AddColumn.TypeEnd])))
-	if typeEnd := strings.IndexByte(typeName, '('); typeEnd >= 0 {
-		typeName = strings.TrimSpace(typeName[:typeEnd])
+	return text[:end], text[end:]
+}
+
+// closingParen returns the index of the parenthesis closing the one that
+// opens text, skipping quoted text, or -1.
+func closingParen(text string) int {
+	if !strings.HasPrefix(text, "(") {
+		return -1
 	}
-	if typeName != "VECTOR" {
-		vectorDimension = 0
+	depth := 0
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case quote != 0:
+			if ch == quote {
+				quote = 0
+			}
+		case ch == '\'' || ch == '"':
+			quote = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
 	}
+	return -1
+}
+
+func (o *Optimizer) optimizeDropIndex(doc *parser.QueryDoc, src []byte) (*PhysicalPlan, error) {
+	stmt := &doc.DropIndexStmts[0]
 	return &PhysicalPlan{
 		Kind:         QueryKindDDL,
-		DDLKind:      4,
-		DDLTableName: string(src[stmt.TableStart:stmt.TableEnd]),
-		DDLColumns: []struct {
-			Name            string
-			Type            string
-			VectorDimension uint32
-			Flags           uint16
-		}{{
-			Name:            string(src[stmt.AddColumn.NameStart:stmt.AddColumn.NameEnd]),
-			Type:            string(src[stmt.AddColumn.TypeStart:stmt.AddColumn.TypeEnd]),
-			VectorDimension: vectorDimension,
-			Flags:           stmt.AddColumn.Flags,
-		}},
-		CollectionName: string(src[stmt.TableStart:stmt.TableEnd]),
+		DDLKind:      3,
+		DDLIndexName: string(src[stmt.IndexStart:stmt.IndexEnd]),
+		DDLIfExists:  stmt.IfExists,
 	}, nil
 }
 
@@ -0,0 +1,267 @@
+package optimizer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xDarkicex/lexer/parser"
+)
+
+// EmbedFunc embeds text with a registered model. The database supplies it so
+// that EMBED('model', text) is evaluated while a statement is planned, with
+// the statement's own parameters, instead of being spliced into its text.
+type EmbedFunc func(model, text string) ([]float32, error)
+
+// SetEmbedder makes EMBED calls resolvable. Planning a statement that calls
+// EMBED without an embedder fails.
+func (o *Optimizer) SetEmbedder(embed EmbedFunc) {
+	o.embed = embed
+}
+
+// IsEmbedCall reports whether ref is a call of the EMBED function.
+func IsEmbedCall(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) bool {
+	if ref.Kind != parser.NodeKindFunctionExpr || ref.ID < 0 || int(ref.ID) >= len(doc.FunctionExprs) {
+		return false
+	}
+	fn := &doc.FunctionExprs[ref.ID]
+	return fn.NameEnd <= uint32(len(src)) && fn.NameStart < fn.NameEnd &&
+		strings.EqualFold(string(src[fn.NameStart:fn.NameEnd]), "embed")
+}
+
+// CheckEmbedCall validates the arguments of an EMBED call. Both are known
+// before the statement runs: a string literal, a parameter or NULL. Column
+// values are embedded by generated vector columns instead, so that a vector
+// is computed once per write rather than once per read.
+func CheckEmbedCall(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) error {
+	fn := &doc.FunctionExprs[ref.ID]
+	if fn.ArgsCount != 2 {
+		return fmt.Errorf("EMBED expects 2 arguments (model, text), got %d", fn.ArgsCount)
+	}
+	if fn.ArgsStart < 0 || int(fn.ArgsStart+fn.ArgsCount) > len(doc.FunctionArgs) {
+		return fmt.Errorf("EMBED: invalid argument reference")
+	}
+	for i := int32(0); i < fn.ArgsCount; i++ {
+		arg := doc.FunctionArgs[fn.ArgsStart+i]
+		switch arg.Kind {
+		case parser.NodeKindString:
+			continue
+		case parser.NodeKindIdentifier:
+			if arg.ID >= 0 && int(arg.ID) < len(doc.Identifiers) {
+				id := &doc.Identifiers[arg.ID]
+				if id.Start < id.End && id.End <= uint32(len(src)) &&
+					(src[id.Start] == '$' || src[id.Start] == '@' || bytesEqualFold(src[id.Start:id.End], []byte("NULL"))) {
+					continue
+				}
+			}
+		}
+		return fmt.Errorf("EMBED: argument %d must be a string literal, a parameter or NULL; use a generated vector column to embed column values", i+1)
+	}
+	return nil
+}
+
+// resolveEmbed evaluates an EMBED call. null reports a NULL text, whose
+// embedding is NULL.
+func (o *Optimizer) resolveEmbed(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) ([]float32, bool, error) {
+	if err := CheckEmbedCall(doc, src, ref); err != nil {
+		return nil, false, err
+	}
+	fn := &doc.FunctionExprs[ref.ID]
+	model, modelNull, err := o.embedArgument(doc, src, doc.FunctionArgs[fn.ArgsStart])
+	if err != nil {
+		return nil, false, err
+	}
+	if modelNull {
+		return nil, false, fmt.Errorf("EMBED: model name must not be NULL")
+	}
+	text, null, err := o.embedArgument(doc, src, doc.FunctionArgs[fn.ArgsStart+1])
+	if err != nil || null {
+		return nil, null, err
+	}
+	if o.embed == nil {
+		return nil, false, fmt.Errorf("EMBED: no embedding models are available")
+	}
+	vector, err := o.embed(model, text)
+	if err != nil {
+		return nil, false, fmt.Errorf("EMBED: %w", err)
+	}
+	return vector, false, nil
+}
+
+// embedLiteral lowers an EMBED call in a written value to the pgvector
+// literal the DML executors already decode.
+func (o *Optimizer) embedLiteral(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) ([]byte, bool, error) {
+	vector, null, err := o.resolveEmbed(doc, src, ref)
+	if err != nil || null {
+		return nil, null, err
+	}
+	literal := make([]byte, 0, 2+len(vector)*10)
+	literal = append(literal, '[')
+	for i, value := range vector {
+		if i > 0 {
+			literal = append(literal, ',')
+		}
+		literal = strconv.AppendFloat(literal, float64(value), 'f', -1, 32)
+	}
+	return append(literal, ']'), false, nil
+}
+
+// embedArgument resolves one argument accepted by CheckEmbedCall.
+func (o *Optimizer) embedArgument(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) (string, bool, error) {
+	if ref.Kind == parser.NodeKindString {
+		return string(decodeSQLStringLiteral(src, doc.Strings[ref.ID])), false, nil
+	}
+	id := &doc.Identifiers[ref.ID]
+	name := string(src[id.Start:id.End])
+	if name[0] != '$' && name[0] != '@' {
+		return "", true, nil
+	}
+	value, ok := o.boundParams.Lookup(src, id.Start, id.End)
+	if !ok {
+		return "", false, fmt.Errorf("EMBED: parameter %s is not bound", name)
+	}
+	switch value.Kind {
+	case ScalarNull:
+		return "", true, nil
+	case ScalarString:
+		return string(value.BytesData), false, nil
+	}
+	return "", false, fmt.Errorf("EMBED: parameter %s must be text", name)
+}
+
+// GeneratedVectorPlan is the GENERATED FROM (<expression>) USING <model>
+// clause of a CREATE TABLE column: the column's vector is the embedding of
+// the expression's text under the model.
+type GeneratedVectorPlan struct {
+	Column     string
+	Expression string
+	Model      string
+}
+
+// generatedVectorClause is a GENERATED FROM clause found in a statement's
+// tokens, with the span it covers.
+type generatedVectorClause struct {
+	start, end int
+	plan       GeneratedVectorPlan
+}
+
+// ParseGeneratedVectorColumn parses a CREATE TABLE statement declaring a
+// generated vector column, whose GENERATED FROM clause the grammar does not
+// model. Like OptimizeView it is used when parsing fails; handled is false
+// for any other statement. doc is parsed with the clause blanked out, and
+// the optimizer attaches the clause to the CREATE TABLE plan from src.
+func ParseGeneratedVectorColumn(src []byte, doc *parser.QueryDoc) (bool, error) {
+	clause, found, err := findGeneratedVectorColumn(src)
+	if !found {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, parseMasked(src, doc, [2]int{clause.start, clause.end})
+}
+
+// generatedVectorColumn returns the generated vector column a CREATE TABLE
+// statement declares, or nil when it declares none.
+func generatedVectorColumn(src []byte) (*GeneratedVectorPlan, error) {
+	clause, found, err := findGeneratedVectorColumn(src)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &clause.plan, nil
+}
+
+// findGeneratedVectorColumn locates the GENERATED FROM clause of a CREATE
+// TABLE column definition. A table may declare one.
+func findGeneratedVectorColumn(src []byte) (generatedVectorClause, bool, error) {
+	var clause generatedVectorClause
+	if trimmed := strings.TrimSpace(string(src)); len(trimmed) < 6 || !strings.EqualFold(trimmed[:6], "CREATE") || !containsFold(src, "GENERATED") {
+		return clause, false, nil
+	}
+	tokens, _ := LexSQLTokens(src, false)
+	if len(tokens) < 3 || !tokens[0].IsWord("CREATE") {
+		return clause, false, nil
+	}
+	isTable := false
+	for _, token := range tokens[1:] {
+		if token.Kind != SQLTokenWord {
+			break
+		}
+		if token.IsWord("TABLE") {
+			isTable = true
+			break
+		}
+	}
+	if !isTable {
+		return clause, false, nil
+	}
+	found := false
+	depth, columnStart := 0, -1
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Kind == SQLTokenPunct {
+			switch token.Text {
+			case "(":
+				depth++
+				if depth == 1 {
+					columnStart = i + 1
+				}
+			case ")":
+				depth--
+			case ",":
+				if depth == 1 {
+					columnStart = i + 1
+				}
+			}
+			continue
+		}
+		if depth != 1 || !token.IsWord("GENERATED") || !tokens[i+1].IsWord("FROM") {
+			continue
+		}
+		if found {
+			return clause, true, fmt.Errorf("a table may declare only one generated vector column")
+		}
+		found = true
+		if columnStart < 0 || columnStart >= i || !tokens[columnStart].IsName() {
+			return clause, true, fmt.Errorf("GENERATED FROM must follow a column definition")
+		}
+		open := i + 2
+		if open >= len(tokens) || !tokens[open].IsPunct("(") {
+			return clause, true, fmt.Errorf("GENERATED FROM requires a parenthesized expression")
+		}
+		closeAt, nested := -1, 0
+		for j := open; j < len(tokens) && tokens[j].Kind != SQLTokenEOF; j++ {
+			if tokens[j].IsPunct("(") {
+				nested++
+			} else if tokens[j].IsPunct(")") {
+				nested--
+				if nested == 0 {
+					closeAt = j
+					break
+				}
+			}
+		}
+		if closeAt < 0 {
+			return clause, true, fmt.Errorf("GENERATED FROM: unterminated expression")
+		}
+		using := closeAt + 1
+		if using+1 >= len(tokens) || !tokens[using].IsWord("USING") {
+			return clause, true, fmt.Errorf("GENERATED FROM (...) requires USING <model>")
+		}
+		model := tokens[using+1]
+		if model.Kind != SQLTokenWord && model.Kind != SQLTokenQuoted && model.Kind != SQLTokenString {
+			return clause, true, fmt.Errorf("GENERATED FROM (...) USING requires a model name")
+		}
+		clause = generatedVectorClause{
+			start: token.Start,
+			end:   model.End,
+			plan: GeneratedVectorPlan{
+				Column:     tokens[columnStart].Text,
+				Expression: strings.TrimSpace(string(src[tokens[open].End:tokens[closeAt].Start])),
+				Model:      model.Text,
+			},
+		}
+		i = using + 1
+	}
+	return clause, found, nil
+}
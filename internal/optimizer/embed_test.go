@@ -0,0 +1,36 @@
+package optimizer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xDarkicex/lexer/parser"
+)
+
+func TestParseGeneratedVectorColumn(t *testing.T) {
+	src := []byte(`CREATE TABLE t (id TEXT PRIMARY KEY, "Body" TEXT, vec VECTOR(4) GENERATED FROM (("Body") || '!') USING "Model-1", n BIGINT)`)
+	doc := &parser.QueryDoc{}
+	handled, err := ParseGeneratedVectorColumn(src, doc)
+	if !handled || err != nil {
+		t.Fatalf("handled=%v err=%v", handled, err)
+	}
+	if len(doc.CreateTableStmts) != 1 || len(doc.CreateTableStmts[0].Columns) != 4 {
+		t.Fatalf("CREATE TABLE did not parse with the clause blanked: %+v", doc.CreateTableStmts)
+	}
+	generated, err := generatedVectorColumn(src)
+	if err != nil || generated == nil || generated.Column != "vec" || generated.Expression != `("Body") || '!'` || generated.Model != "Model-1" {
+		t.Fatalf("generated = %#v, %v", generated, err)
+	}
+
+	if _, err := generatedVectorColumn([]byte(`CREATE TABLE t (v VECTOR(4) GENERATED FROM (a) USING)`)); err == nil || !strings.Contains(err.Error(), "model name") {
+		t.Fatalf("missing model error = %v", err)
+	}
+	for _, sql := range []string{
+		`SELECT 'GENERATED FROM (x) USING m'`,
+		`CREATE TABLE t (id TEXT PRIMARY KEY, v VECTOR(4))`,
+	} {
+		if handled, err := ParseGeneratedVectorColumn([]byte(sql), &parser.QueryDoc{}); handled || err != nil {
+			t.Fatalf("%s: handled=%v err=%v", sql, handled, err)
+		}
+	}
+}
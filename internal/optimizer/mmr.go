@@ -0,0 +1,186 @@
+package optimizer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xDarkicex/lexer/parser"
+)
+
+// MMRPlan is a lowered
+//
+//	ORDER BY MMR(<vector column>, <query vector>, <lambda> [, <fetch_k> [, <communities>]])
+//
+// The statement is otherwise planned as ORDER BY VECTOR_DISTANCE over the
+// same operands; the executor widens that plan to the candidate pool and
+// re-ranks it.
+type MMRPlan struct {
+	Lambda float64
+	// FetchK is the candidate pool size; 0 selects the executor's default.
+	FetchK int
+	// IndexCommunities also diversifies by the HNSW communities computed by
+	// the last maintenance run, selected with the communities argument
+	// 'index'.
+	IndexCommunities bool
+}
+
+// ErrMMRPlacement rejects an MMR call anywhere but the ORDER BY of a
+// nearest-neighbour query.
+var ErrMMRPlacement = errors.New("MMR is only supported as the ORDER BY expression")
+
+// IsMMRCall reports whether ref is a call of the MMR ordering function.
+func IsMMRCall(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) bool {
+	if ref.Kind != parser.NodeKindFunctionExpr || ref.ID < 0 || int(ref.ID) >= len(doc.FunctionExprs) {
+		return false
+	}
+	fn := &doc.FunctionExprs[ref.ID]
+	return fn.NameEnd <= uint32(len(src)) && fn.NameStart < fn.NameEnd &&
+		strings.EqualFold(string(src[fn.NameStart:fn.NameEnd]), "mmr")
+}
+
+// lowerMMR resolves the arguments of an ORDER BY MMR call and returns the
+// re-ranking parameters with the query vector.
+func (o *Optimizer) lowerMMR(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) (*MMRPlan, []float32, error) {
+	fn := &doc.FunctionExprs[ref.ID]
+	if fn.ArgsCount < 3 || fn.ArgsCount > 5 {
+		return nil, nil, fmt.Errorf("MMR expects (vector_column, query_vector, lambda [, fetch_k [, communities]])")
+	}
+	if fn.ArgsStart < 0 || int(fn.ArgsStart+fn.ArgsCount) > len(doc.FunctionArgs) {
+		return nil, nil, fmt.Errorf("MMR: invalid argument reference")
+	}
+	args := doc.FunctionArgs[fn.ArgsStart : fn.ArgsStart+fn.ArgsCount]
+	if column := args[0]; column.Kind != parser.NodeKindIdentifier || isParamIdentifier(doc, src, column) {
+		return nil, nil, fmt.Errorf("MMR: the first argument must be a vector column")
+	}
+	vec, err := o.resolveVectorOperand(doc, src, args[1])
+	if err != nil {
+		return nil, nil, fmt.Errorf("MMR: %w", err)
+	}
+	mmr := &MMRPlan{}
+	lambda, null, err := o.mmrNumber(doc, src, args[2])
+	if err != nil {
+		return nil, nil, fmt.Errorf("MMR lambda: %w", err)
+	}
+	if null {
+		return nil, nil, fmt.Errorf("MMR lambda must not be NULL")
+	}
+	if math.IsNaN(lambda) || lambda < 0 || lambda > 1 {
+		return nil, nil, fmt.Errorf("MMR lambda must be between 0 and 1, got %v", lambda)
+	}
+	mmr.Lambda = lambda
+	if len(args) > 3 {
+		fetchK, null, err := o.mmrNumber(doc, src, args[3])
+		if err != nil {
+			return nil, nil, fmt.Errorf("MMR fetch_k: %w", err)
+		}
+		if !null {
+			if fetchK < 1 || fetchK != math.Trunc(fetchK) || fetchK > math.MaxInt32 {
+				return nil, nil, fmt.Errorf("MMR fetch_k must be a positive integer, got %v", fetchK)
+			}
+			mmr.FetchK = int(fetchK)
+		}
+	}
+	if len(args) > 4 {
+		communities, null, err := o.mmrText(doc, src, args[4])
+		if err != nil {
+			return nil, nil, fmt.Errorf("MMR communities: %w", err)
+		}
+		switch {
+		case null, strings.EqualFold(communities, "none"):
+		case strings.EqualFold(communities, "index"):
+			mmr.IndexCommunities = true
+		default:
+			return nil, nil, fmt.Errorf("MMR communities must be 'index' or 'none', got %q", communities)
+		}
+	}
+	return mmr, vec, nil
+}
+
+// mmrNumber resolves a numeric literal, parameter or NULL argument.
+func (o *Optimizer) mmrNumber(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) (float64, bool, error) {
+	switch ref.Kind {
+	case parser.NodeKindNumber:
+		if ref.ID >= 0 && int(ref.ID) < len(doc.Numbers) {
+			n := doc.Numbers[ref.ID]
+			value, err := strconv.ParseFloat(string(src[n.Start:n.End]), 64)
+			if err == nil {
+				return value, false, nil
+			}
+		}
+	case parser.NodeKindIdentifier:
+		if isNullIdentifier(doc, src, ref) {
+			return 0, true, nil
+		}
+		if !isParamIdentifier(doc, src, ref) {
+			break
+		}
+		id := &doc.Identifiers[ref.ID]
+		name := string(src[id.Start:id.End])
+		value, found := o.resolveParamScalar(doc, src, ref)
+		if !found {
+			return 0, false, fmt.Errorf("parameter %s is not bound", name)
+		}
+		switch value.Kind {
+		case ScalarNull:
+			return 0, true, nil
+		case ScalarInt:
+			return float64(value.Int), false, nil
+		case ScalarFloat:
+			return value.Float, false, nil
+		case ScalarString:
+			if number, err := strconv.ParseFloat(strings.TrimSpace(string(value.BytesData)), 64); err == nil {
+				return number, false, nil
+			}
+		}
+		return 0, false, fmt.Errorf("parameter %s must be numeric", name)
+	}
+	return 0, false, fmt.Errorf("must be a numeric literal or parameter")
+}
+
+// mmrText resolves a string literal, parameter or NULL argument.
+func (o *Optimizer) mmrText(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) (string, bool, error) {
+	switch ref.Kind {
+	case parser.NodeKindString:
+		return string(decodeSQLStringLiteral(src, doc.Strings[ref.ID])), false, nil
+	case parser.NodeKindIdentifier:
+		if isNullIdentifier(doc, src, ref) {
+			return "", true, nil
+		}
+		if !isParamIdentifier(doc, src, ref) {
+			break
+		}
+		id := &doc.Identifiers[ref.ID]
+		name := string(src[id.Start:id.End])
+		value, found := o.resolveParamScalar(doc, src, ref)
+		if !found {
+			return "", false, fmt.Errorf("parameter %s is not bound", name)
+		}
+		switch value.Kind {
+		case ScalarNull:
+			return "", true, nil
+		case ScalarString:
+			return string(value.BytesData), false, nil
+		}
+		return "", false, fmt.Errorf("parameter %s must be text", name)
+	}
+	return "", false, fmt.Errorf("must be a string literal or parameter")
+}
+
+func isParamIdentifier(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) bool {
+	if ref.Kind != parser.NodeKindIdentifier || ref.ID < 0 || int(ref.ID) >= len(doc.Identifiers) {
+		return false
+	}
+	id := &doc.Identifiers[ref.ID]
+	return id.Start < id.End && id.End <= uint32(len(src)) && (src[id.Start] == '$' || src[id.Start] == '@')
+}
+
+func isNullIdentifier(doc *parser.QueryDoc, src []byte, ref parser.NodeRef) bool {
+	if ref.Kind != parser.NodeKindIdentifier || ref.ID < 0 || int(ref.ID) >= len(doc.Identifiers) {
+		return false
+	}
+	id := &doc.Identifiers[ref.ID]
+	return id.Start < id.End && id.End <= uint32(len(src)) && bytesEqualFold(src[id.Start:id.End], []byte("NULL"))
+}
@@ -75,3 +75,32 @@ func TestRelational_E2E(t *testing.T) {
 		t.Errorf("Expected 5 results for > 'e', got %d", count)
 	}
 }
+
+func TestCreateIndexClauses(t *testing.T) {
+	cases := []struct {
+		text    string
+		method  string
+		options map[string]string
+	}{
+		{text: " (email)", method: ""},
+		{text: " USING fts (content)", method: "fts"},
+		{text: " using FTS(content) WITH (config = 'english', k1 = 1.5, B=0.5)", method: "fts",
+			options: map[string]string{"config": "english", "k1": "1.5", "b": "0.5"}},
+		{text: " USING fts ((content)) WITH (config = 'it''s')", method: "fts", options: map[string]string{"config": "it's"}},
+		{text: " USING btree", method: "btree"},
+	}
+	for _, tc := range cases {
+		method, options := createIndexClauses(tc.text)
+		if method != tc.method {
+			t.Fatalf("createIndexClauses(%q) method = %q, want %q", tc.text, method, tc.method)
+		}
+		if len(options) != len(tc.options) {
+			t.Fatalf("createIndexClauses(%q) options = %v, want %v", tc.text, options, tc.options)
+		}
+		for name, value := range tc.options {
+			if options[name] != value {
+				t.Fatalf("createIndexClauses(%q) option %q = %q, want %q", tc.text, name, options[name], value)
+			}
+		}
+	}
+}
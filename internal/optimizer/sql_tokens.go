@@ -0,0 +1,254 @@
+package optimizer
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xDarkicex/lexer"
+	"github.com/xDarkicex/lexer/parser"
+)
+
+// SQLTokenKind classifies a lexer token by its source text. Statement forms
+// the parser does not model are planned from this token stream, so they
+// share the parser's lexical rules for comments, quoting and operators.
+type SQLTokenKind uint8
+
+const (
+	SQLTokenEOF SQLTokenKind = iota
+	SQLTokenWord
+	SQLTokenQuoted
+	SQLTokenString
+	SQLTokenNumber
+	SQLTokenPunct
+)
+
+// SQLToken is one lexer token. Text is the token as written, except that
+// quoted identifiers and string literals are decoded. Start and End are the
+// token's source span.
+type SQLToken struct {
+	Kind  SQLTokenKind
+	Text  string
+	Start int
+	End   int
+}
+
+// LexSQLTokens scans src with the SQL lexer. The last token is always
+// SQLTokenEOF. When strict is false a lexical error ends the stream instead
+// of failing, so callers that only look for relation names can leave error
+// reporting to the parser.
+func LexSQLTokens(src []byte, strict bool) ([]SQLToken, error) {
+	var tokens []SQLToken
+	scanner := lexer.New(src)
+	for {
+		tok, ok := scanner.Next()
+		if !ok || tok.Kind == lexer.KindEOF {
+			break
+		}
+		if tok.Kind == lexer.KindError {
+			if strict {
+				return nil, fmt.Errorf("invalid SQL near offset %d", tok.Start)
+			}
+			break
+		}
+		if tok.End <= tok.Start || tok.End > uint32(len(src)) {
+			continue
+		}
+		tokens = append(tokens, classifySQLToken(src, int(tok.Start), int(tok.End)))
+	}
+	return append(tokens, SQLToken{Kind: SQLTokenEOF, Start: len(src), End: len(src)}), nil
+}
+
+func classifySQLToken(src []byte, start, end int) SQLToken {
+	raw := string(src[start:end])
+	token := SQLToken{Kind: SQLTokenPunct, Text: raw, Start: start, End: end}
+	switch c := raw[0]; {
+	case c == '"' && len(raw) >= 2:
+		token.Kind = SQLTokenQuoted
+		token.Text = strings.ReplaceAll(raw[1:len(raw)-1], `""`, `"`)
+	case c == '\'' && len(raw) >= 2:
+		token.Kind = SQLTokenString
+		token.Text = strings.ReplaceAll(raw[1:len(raw)-1], `''`, `'`)
+	case c >= '0' && c <= '9', c == '.' && len(raw) > 1 && raw[1] >= '0' && raw[1] <= '9':
+		token.Kind = SQLTokenNumber
+	case (c == '-' || c == '+') && len(raw) > 1 && (raw[1] >= '0' && raw[1] <= '9' || raw[1] == '.'):
+		// A signed numeric literal the lexer kept as one token.
+		token.Kind = SQLTokenNumber
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c >= 0x80:
+		token.Kind = SQLTokenWord
+	}
+	return token
+}
+
+// IsWord reports whether token is the unquoted keyword word.
+func (t SQLToken) IsWord(word string) bool {
+	return t.Kind == SQLTokenWord && strings.EqualFold(t.Text, word)
+}
+
+// IsPunct reports whether token is the operator or punctuation text.
+func (t SQLToken) IsPunct(text string) bool {
+	return t.Kind == SQLTokenPunct && t.Text == text
+}
+
+// IsName reports whether token is an unquoted or quoted identifier.
+func (t SQLToken) IsName() bool {
+	return (t.Kind == SQLTokenWord || t.Kind == SQLTokenQuoted) && t.Text != ""
+}
+
+// parseMasked parses src into doc with the given spans blanked out. It reads
+// a statement whose only syntax outside the grammar is a clause the caller
+// plans from src itself: the blanked copy keeps every offset, so each span
+// in doc still addresses src.
+func parseMasked(src []byte, doc *parser.QueryDoc, spans ...[2]int) error {
+	masked := append([]byte(nil), src...)
+	for _, span := range spans {
+		for i := span[0]; i < span[1]; i++ {
+			if masked[i] != '\n' {
+				masked[i] = ' '
+			}
+		}
+	}
+	*doc = parser.QueryDoc{}
+	if err := parser.Parse(masked, doc); err != nil {
+		return fmt.Errorf("parse error: %w", err)
+	}
+	return nil
+}
+
+// tokenParser reads a statement the grammar does not model from its
+// SQLTokens. end bounds the part being read.
+type tokenParser struct {
+	src    []byte
+	tokens []SQLToken
+	pos    int
+	end    int
+	// statement prefixes syntax errors, e.g. "ALTER TABLE".
+	statement string
+}
+
+func (p *tokenParser) peekAt(offset int) SQLToken {
+	if i := p.pos + offset; i < p.end {
+		return p.tokens[i]
+	}
+	return SQLToken{Kind: SQLTokenEOF, Start: len(p.src), End: len(p.src)}
+}
+
+func (p *tokenParser) peek() SQLToken { return p.peekAt(0) }
+
+func (p *tokenParser) next() SQLToken {
+	token := p.peek()
+	if p.pos < p.end {
+		p.pos++
+	}
+	return token
+}
+
+func (p *tokenParser) done() bool { return p.pos >= p.end }
+
+func (p *tokenParser) isWordAt(offset int, word string) bool {
+	return p.peekAt(offset).IsWord(word)
+}
+
+// keywords consumes the given unquoted words if they appear in sequence.
+func (p *tokenParser) keywords(words ...string) bool {
+	for i, word := range words {
+		if !p.isWordAt(i, word) {
+			return false
+		}
+	}
+	p.pos += len(words)
+	return true
+}
+
+func (p *tokenParser) expectKeywords(words ...string) error {
+	if !p.keywords(words...) {
+		return p.errorf("expected %s near %s", strings.Join(words, " "), p.describe())
+	}
+	return nil
+}
+
+func (p *tokenParser) punct(text string) bool {
+	if p.peek().IsPunct(text) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *tokenParser) expectPunct(text string) error {
+	if !p.punct(text) {
+		return p.errorf("expected %q near %s", text, p.describe())
+	}
+	return nil
+}
+
+// cast consumes a :: operator, which the lexer may report as one token or
+// as two colons.
+func (p *tokenParser) cast() bool {
+	if p.punct("::") {
+		return true
+	}
+	if p.peekAt(0).IsPunct(":") && p.peekAt(1).IsPunct(":") {
+		p.pos += 2
+		return true
+	}
+	return false
+}
+
+func (p *tokenParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf(p.statement+": "+format, args...)
+}
+
+func (p *tokenParser) describe() string {
+	token := p.peek()
+	if token.Kind == SQLTokenEOF {
+		return "end of statement"
+	}
+	return fmt.Sprintf("%q", p.src[token.Start:token.End])
+}
+
+func (p *tokenParser) identifier(what string) (string, error) {
+	token := p.peek()
+	if !token.IsName() {
+		return "", p.errorf("expected %s near %s", what, p.describe())
+	}
+	p.pos++
+	return token.Text, nil
+}
+
+// qualifiedName reads name or schema.name and returns the final component;
+// the engine has a single schema.
+func (p *tokenParser) qualifiedName(what string) (string, error) {
+	name, err := p.identifier(what)
+	if err != nil {
+		return "", err
+	}
+	for p.punct(".") {
+		if name, err = p.identifier(what); err != nil {
+			return "", err
+		}
+	}
+	if dot := strings.LastIndexByte(name, '.'); dot >= 0 && p.tokens[p.pos-1].Kind == SQLTokenWord {
+		name = name[dot+1:]
+	}
+	return name, nil
+}
+
+func (p *tokenParser) identifierList(what string) ([]string, error) {
+	if err := p.expectPunct("("); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, err := p.identifier(what)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if p.punct(")") {
+			return names, nil
+		}
+		if err := p.expectPunct(","); err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package optimizer
+
+import (
+	"strings"
+
+	"github.com/xDarkicex/lexer/parser"
+)
+
+// OptimizeView plans CREATE [OR REPLACE] VIEW, CREATE MATERIALIZED VIEW,
+// REFRESH MATERIALIZED VIEW and DROP [MATERIALIZED] VIEW, which the grammar
+// does not model. Like OptimizeAlterTable it is used when parsing fails;
+// handled is false for any other statement. The defining query of CREATE is
+// parsed here, so a view that could never be read is rejected up front.
+func (o *Optimizer) OptimizeView(src []byte) (*PhysicalPlan, bool, error) {
+	o.src = src
+	return o.planView(src)
+}
+
+func (o *Optimizer) planView(src []byte) (*PhysicalPlan, bool, error) {
+	trimmed := strings.TrimSpace(string(src))
+	if len(trimmed) < 4 {
+		return nil, false, nil
+	}
+	switch strings.ToUpper(trimmed[:4]) {
+	case "CREA", "DROP", "REFR":
+	default:
+		return nil, false, nil
+	}
+	tokens, err := LexSQLTokens(src, true)
+	if err != nil {
+		return nil, false, nil
+	}
+	p := &tokenParser{src: src, tokens: tokens, end: len(tokens) - 1}
+	for p.end > 0 && tokens[p.end-1].IsPunct(";") {
+		p.end--
+	}
+	plan := &PhysicalPlan{Kind: QueryKindDDL, DDLViewWithData: true}
+	switch {
+	case p.keywords("CREATE"):
+		plan.DDLKind = 6
+		plan.DDLViewOrReplace = p.keywords("OR", "REPLACE")
+		plan.DDLViewMaterialized = p.keywords("MATERIALIZED")
+		if !p.keywords("VIEW") {
+			return nil, false, nil
+		}
+	case p.keywords("DROP"):
+		plan.DDLKind = 7
+		plan.DDLViewMaterialized = p.keywords("MATERIALIZED")
+		if !p.keywords("VIEW") {
+			return nil, false, nil
+		}
+	case p.keywords("REFRESH", "MATERIALIZED", "VIEW"):
+		plan.DDLKind = 8
+		plan.DDLViewMaterialized = true
+	default:
+		return nil, false, nil
+	}
+	p.statement = ViewStatementName(plan)
+
+	switch plan.DDLKind {
+	case 6:
+		if plan.DDLViewOrReplace && plan.DDLViewMaterialized {
+			return nil, true, p.errorf("OR REPLACE is not supported; drop and re-create the materialized view")
+		}
+		if plan.DDLViewMaterialized {
+			plan.DDLViewIfNotExists = p.keywords("IF", "NOT", "EXISTS")
+		}
+		name, err := p.qualifiedName("a view name")
+		if err != nil {
+			return nil, true, err
+		}
+		plan.DDLViewNames = []string{name}
+		if p.punct("(") {
+			return nil, true, p.errorf("column lists are not supported; alias the columns in the query instead")
+		}
+		if err := p.expectKeywords("AS"); err != nil {
+			return nil, true, err
+		}
+		if plan.DDLViewMaterialized {
+			if p.trailingKeywords("WITH", "NO", "DATA") {
+				plan.DDLViewWithData = false
+			} else {
+				p.trailingKeywords("WITH", "DATA")
+			}
+		}
+		if !p.isWordAt(0, "SELECT") && !p.isWordAt(0, "WITH") {
+			return nil, true, p.errorf("expected a SELECT query near %s", p.describe())
+		}
+		plan.DDLViewQuery = strings.TrimSpace(string(src[p.peek().Start:tokens[p.end].Start]))
+		if err := parser.Parse([]byte(plan.DDLViewQuery), &parser.QueryDoc{}); err != nil {
+			return nil, true, p.errorf("parse error: %v", err)
+		}
+		p.pos = p.end
+	case 7:
+		plan.DDLIfExists = p.keywords("IF", "EXISTS")
+		for {
+			name, err := p.qualifiedName("a view name")
+			if err != nil {
+				return nil, true, err
+			}
+			plan.DDLViewNames = append(plan.DDLViewNames, name)
+			if !p.punct(",") {
+				break
+			}
+		}
+		if p.keywords("CASCADE") {
+			return nil, true, p.errorf("CASCADE is not supported; drop dependent views first")
+		}
+		p.keywords("RESTRICT")
+	case 8:
+		p.keywords("CONCURRENTLY")
+		name, err := p.qualifiedName("a materialized view name")
+		if err != nil {
+			return nil, true, err
+		}
+		plan.DDLViewNames = []string{name}
+		if p.keywords("WITH", "NO", "DATA") {
+			plan.DDLViewWithData = false
+		} else {
+			p.keywords("WITH", "DATA")
+		}
+	}
+	if !p.done() {
+		return nil, true, p.errorf("unexpected %s", p.describe())
+	}
+	plan.DDLTableName = plan.DDLViewNames[0]
+	return plan, true, nil
+}
+
+// ViewStatementName returns the statement a view DDL plan was made from,
+// e.g. "DROP MATERIALIZED VIEW"; it prefixes the statement's errors.
+func ViewStatementName(plan *PhysicalPlan) string {
+	name := "VIEW"
+	if plan.DDLViewMaterialized {
+		name = "MATERIALIZED VIEW"
+	}
+	switch plan.DDLKind {
+	case 6:
+		return "CREATE " + name
+	case 8:
+		return "REFRESH " + name
+	default:
+		return "DROP " + name
+	}
+}
+
+// trailingKeywords removes the given unquoted words from the end of the
+// part being read if it ends with them.
+func (p *tokenParser) trailingKeywords(words ...string) bool {
+	start := p.end - len(words)
+	if start < p.pos {
+		return false
+	}
+	for i, word := range words {
+		if !p.tokens[start+i].IsWord(word) {
+			return false
+		}
+	}
+	p.end = start
+	return true
+}
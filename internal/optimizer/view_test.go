@@ -0,0 +1,54 @@
+package optimizer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOptimizeViewPlansViewDDL(t *testing.T) {
+	o := NewOptimizer(nil)
+	plan, handled, err := o.OptimizeView([]byte(`CREATE OR REPLACE VIEW public.big AS SELECT id FROM orders WHERE total > 100;`))
+	if !handled || err != nil {
+		t.Fatalf("CREATE VIEW: handled=%v err=%v", handled, err)
+	}
+	if plan.Kind != QueryKindDDL || plan.DDLKind != 6 || !plan.DDLViewOrReplace || plan.DDLViewMaterialized ||
+		len(plan.DDLViewNames) != 1 || plan.DDLViewNames[0] != "big" || plan.DDLViewQuery != "SELECT id FROM orders WHERE total > 100" {
+		t.Fatalf("CREATE VIEW plan = %+v", plan)
+	}
+
+	plan, handled, err = o.OptimizeView([]byte(`CREATE MATERIALIZED VIEW IF NOT EXISTS mv AS SELECT id FROM docs WITH NO DATA`))
+	if !handled || err != nil {
+		t.Fatalf("CREATE MATERIALIZED VIEW: handled=%v err=%v", handled, err)
+	}
+	if !plan.DDLViewMaterialized || !plan.DDLViewIfNotExists || plan.DDLViewWithData || plan.DDLViewQuery != "SELECT id FROM docs" {
+		t.Fatalf("CREATE MATERIALIZED VIEW plan = %+v", plan)
+	}
+
+	plan, handled, err = o.OptimizeView([]byte(`DROP VIEW IF EXISTS a, "B" RESTRICT`))
+	if !handled || err != nil || plan.DDLKind != 7 || !plan.DDLIfExists || strings.Join(plan.DDLViewNames, ",") != "a,B" {
+		t.Fatalf("DROP VIEW: plan=%+v handled=%v err=%v", plan, handled, err)
+	}
+	plan, handled, err = o.OptimizeView([]byte(`REFRESH MATERIALIZED VIEW CONCURRENTLY mv WITH DATA`))
+	if !handled || err != nil || plan.DDLKind != 8 || !plan.DDLViewWithData || ViewStatementName(plan) != "REFRESH MATERIALIZED VIEW" {
+		t.Fatalf("REFRESH: plan=%+v handled=%v err=%v", plan, handled, err)
+	}
+
+	for _, sql := range []string{`CREATE TABLE t (id TEXT)`, `DROP TABLE t`, `SELECT 1`} {
+		if _, handled, err := o.OptimizeView([]byte(sql)); handled || err != nil {
+			t.Fatalf("%s: handled=%v err=%v", sql, handled, err)
+		}
+	}
+	for sql, want := range map[string]string{
+		`CREATE VIEW v (a) AS SELECT id FROM t`:                 "column lists are not supported",
+		`CREATE OR REPLACE MATERIALIZED VIEW v AS SELECT 1`:     "OR REPLACE is not supported",
+		`CREATE VIEW v AS DELETE FROM t`:                        "expected a SELECT query",
+		`CREATE VIEW v AS SELECT FROM WHERE`:                    "CREATE VIEW: parse error",
+		`DROP MATERIALIZED VIEW v CASCADE`:                      "CASCADE is not supported",
+		`REFRESH MATERIALIZED VIEW v WITH DATA AND MORE TOKENS`: "unexpected",
+	} {
+		_, handled, err := o.OptimizeView([]byte(sql))
+		if !handled || err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("%s: handled=%v err=%v, want %q", sql, handled, err, want)
+		}
+	}
+}
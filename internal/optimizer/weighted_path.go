@@ -0,0 +1,180 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xDarkicex/lexer/parser"
+)
+
+// WeightedPathCost is the cost model of a weightedShortestPath() call: the
+// edge property holding each edge's cost and, when positive, the scale of
+// the vector-distance A* heuristic.
+type WeightedPathCost struct {
+	CostProperty   string
+	HeuristicScale float64
+}
+
+const weightedShortestPathName = "weightedShortestPath"
+
+// weightedPathCall is a weightedShortestPath call found in a statement's
+// tokens. prefix spans the "weighted" part of its name and args the
+// arguments after its pattern; without both it reads as shortestPath.
+type weightedPathCall struct {
+	prefixStart, prefixEnd int
+	argsStart, argsEnd     int
+	cost                   WeightedPathCost
+}
+
+// ParseWeightedShortestPath parses a statement calling
+//
+//	weightedShortestPath(<pattern>, '<cost>' [, <heuristic scale>])
+//
+// which the grammar does not model. Like OptimizeView it is used when
+// parsing fails; handled is false for any other statement. The call is read
+// from the statement's tokens, and doc is parsed from a copy of src in which
+// the call's "weighted" prefix and cost arguments are blank, so doc reads as
+// the shortestPath it wraps while every span in it still addresses src.
+// The optimizer reads the cost model from src when it plans the path.
+func ParseWeightedShortestPath(src []byte, doc *parser.QueryDoc) (bool, error) {
+	call, found, err := findWeightedShortestPath(src)
+	if !found {
+		return false, nil
+	}
+	if err != nil {
+		return true, err
+	}
+	return true, parseMasked(src, doc, [2]int{call.prefixStart, call.prefixEnd}, [2]int{call.argsStart, call.argsEnd})
+}
+
+// weightedPathCost returns the cost model of the statement's
+// weightedShortestPath call, or nil when it has none.
+func weightedPathCost(src []byte) (*WeightedPathCost, error) {
+	call, found, err := findWeightedShortestPath(src)
+	if !found || err != nil {
+		return nil, err
+	}
+	return &call.cost, nil
+}
+
+// findWeightedShortestPath locates the statement's weightedShortestPath
+// call. A statement may contain one, and then no other shortestPath, so the
+// cost model belongs to the only shortest path the statement plans.
+func findWeightedShortestPath(src []byte) (weightedPathCall, bool, error) {
+	if !containsFold(src, weightedShortestPathName) {
+		return weightedPathCall{}, false, nil
+	}
+	tokens, err := LexSQLTokens(src, false)
+	if err != nil {
+		return weightedPathCall{}, false, nil
+	}
+	var call weightedPathCall
+	found, plain := false, false
+	for i := 0; i+1 < len(tokens); i++ {
+		if !tokens[i+1].IsPunct("(") {
+			continue
+		}
+		switch {
+		case tokens[i].IsWord("shortestPath"):
+			plain = true
+		case tokens[i].IsWord(weightedShortestPathName):
+			if found {
+				return call, true, fmt.Errorf("a statement may contain only one weightedShortestPath")
+			}
+			found = true
+			if call, i, err = readWeightedPathCall(tokens, i); err != nil {
+				return call, true, err
+			}
+		}
+	}
+	if found && plain {
+		return call, true, fmt.Errorf("weightedShortestPath cannot be combined with shortestPath in one statement")
+	}
+	return call, found, nil
+}
+
+// readWeightedPathCall reads the call whose name is tokens[at] and returns
+// the index of its closing parenthesis.
+func readWeightedPathCall(tokens []SQLToken, at int) (weightedPathCall, int, error) {
+	call := weightedPathCall{
+		prefixStart: tokens[at].Start,
+		prefixEnd:   tokens[at].Start + len("weighted"),
+	}
+	depth, closeAt := 0, -1
+	var commas []int
+	for i := at + 1; i < len(tokens) && closeAt < 0; i++ {
+		token := tokens[i]
+		if token.Kind != SQLTokenPunct {
+			continue
+		}
+		if token.Text == "," && depth == 1 {
+			commas = append(commas, i)
+			continue
+		}
+		// The lexer may join brackets with operators, as in ]->(.
+		for j := 0; j < len(token.Text); j++ {
+			switch token.Text[j] {
+			case '(', '[', '{':
+				depth++
+			case ')', ']', '}':
+				depth--
+				if depth == 0 && closeAt < 0 {
+					if token.Text[j] != ')' {
+						return call, 0, fmt.Errorf("weightedShortestPath: unbalanced argument list")
+					}
+					closeAt, call.argsEnd = i, token.Start+j
+				}
+			}
+		}
+	}
+	if closeAt < 0 {
+		return call, 0, fmt.Errorf("weightedShortestPath: unterminated argument list")
+	}
+	if len(commas) < 1 || len(commas) > 2 || commas[0] == at+2 {
+		return call, 0, fmt.Errorf("weightedShortestPath expects (pattern, 'cost' [, heuristic_scale])")
+	}
+	costEnd := closeAt
+	if len(commas) == 2 {
+		costEnd = commas[1]
+	}
+	cost := tokens[commas[0]+1 : costEnd]
+	if len(cost) != 1 || cost[0].Kind != SQLTokenString {
+		return call, 0, fmt.Errorf("weightedShortestPath cost must be a quoted edge property name, e.g. 'weight' or 'r.cost'")
+	}
+	// r.cost and edge.cost name the property through the edge variable;
+	// only the property itself identifies the cost source.
+	property := cost[0].Text
+	if dot := strings.LastIndexByte(property, '.'); dot >= 0 {
+		property = property[dot+1:]
+	}
+	call.cost.CostProperty = strings.TrimSpace(property)
+	if call.cost.CostProperty == "" {
+		return call, 0, fmt.Errorf("weightedShortestPath cost property must not be empty")
+	}
+	if len(commas) == 2 {
+		scale := tokens[commas[1]+1 : closeAt]
+		if len(scale) != 1 || scale[0].Kind != SQLTokenNumber {
+			return call, 0, fmt.Errorf("weightedShortestPath heuristic scale must be a non-negative number")
+		}
+		value, err := strconv.ParseFloat(scale[0].Text, 64)
+		if err != nil || value < 0 || math.IsInf(value, 0) {
+			return call, 0, fmt.Errorf("weightedShortestPath heuristic scale must be a non-negative number")
+		}
+		call.cost.HeuristicScale = value
+	}
+	call.argsStart = tokens[commas[0]].Start
+	return call, closeAt, nil
+}
+
+// containsFold reports whether src contains word, ignoring ASCII case.
+func containsFold(src []byte, word string) bool {
+	needle := []byte(word)
+	for i := 0; i+len(needle) <= len(src); i++ {
+		if bytesEqualFold(src[i:i+len(needle)], needle) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,48 @@
+package optimizer
+
+import (
+	"testing"
+
+	"github.com/xDarkicex/lexer/parser"
+)
+
+func TestParseWeightedShortestPath(t *testing.T) {
+	src := []byte(`MATCH p = WeightedShortestPath((a)-[:ROUTE*1..4]->(b), 'r.cost', 0.5)
+WHERE a.name = 'weightedShortestPath(' RETURN p`)
+	doc := &parser.QueryDoc{}
+	handled, err := ParseWeightedShortestPath(src, doc)
+	if !handled || err != nil {
+		t.Fatalf("handled=%v err=%v", handled, err)
+	}
+	if len(doc.MatchPaths) == 0 || !doc.MatchPaths[0].Shortest {
+		t.Fatalf("weighted call did not parse as a shortest path: %+v", doc.MatchPaths)
+	}
+	cost, err := weightedPathCost(src)
+	if err != nil || cost == nil || cost.CostProperty != "cost" || cost.HeuristicScale != 0.5 {
+		t.Fatalf("cost = %+v, %v; want cost/0.5", cost, err)
+	}
+
+	for _, sql := range []string{
+		`MATCH p = shortestPath((a)-[*1..3]->(b)) RETURN p`,
+		`SELECT 'weightedShortestPath((a), ''cost'')' AS s`,
+	} {
+		if handled, err := ParseWeightedShortestPath([]byte(sql), &parser.QueryDoc{}); handled || err != nil {
+			t.Fatalf("%s: handled=%v err=%v", sql, handled, err)
+		}
+		if cost, err := weightedPathCost([]byte(sql)); cost != nil || err != nil {
+			t.Fatalf("%s: cost=%+v err=%v", sql, cost, err)
+		}
+	}
+
+	for _, bad := range []string{
+		`MATCH p = weightedShortestPath((a)-[:ROUTE*1..4]->(b), r.cost, 0.5) RETURN p`,
+		`MATCH p = weightedShortestPath((a)-[*1..3]->(b)) RETURN p`,
+		`MATCH p = weightedShortestPath((a)-[*1..3]->(b), 'cost', -1) RETURN p`,
+		`MATCH p = weightedShortestPath((a)-[*1..3]->(b), 'cost'), q = shortestPath((a)-[*1..3]->(b)) RETURN p`,
+		`MATCH p = weightedShortestPath((a)-[*1..3]->(b), 'cost' RETURN p`,
+	} {
+		if handled, err := ParseWeightedShortestPath([]byte(bad), &parser.QueryDoc{}); !handled || err == nil {
+			t.Fatalf("%s: handled=%v err=%v, want an error", bad, handled, err)
+		}
+	}
+}
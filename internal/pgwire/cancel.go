@@ -0,0 +1,143 @@
+package pgwire
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errCancelRequestHandled reports that a startup packet was a CancelRequest.
+// PostgreSQL never answers a cancel connection; the server closes it after
+// signalling the target backend.
+var errCancelRequestHandled = errors.New("pgwire cancel request handled")
+
+// backendKey is the BackendKeyData pair issued to one connection. The process
+// ID only routes the request; the random secret authorizes it.
+type backendKey struct {
+	processID uint32
+	secret    uint32
+}
+
+func (k backendKey) payload() []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:4], k.processID)
+	binary.BigEndian.PutUint32(buf[4:], k.secret)
+	return buf[:]
+}
+
+// cancelRegistry maps issued backend keys to live connection states so a
+// CancelRequest arriving on a separate socket can interrupt the statement
+// currently running on the target connection.
+type cancelRegistry struct {
+	mu    sync.Mutex
+	conns map[uint32]cancelTarget
+}
+
+type cancelTarget struct {
+	secret uint32
+	state  *connState
+}
+
+func newCancelRegistry() *cancelRegistry {
+	return &cancelRegistry{conns: make(map[uint32]cancelTarget)}
+}
+
+// register issues a fresh key for state. Process IDs are kept positive so
+// clients that decode them as int32 PIDs never see a negative value.
+func (r *cancelRegistry) register(state *connState) (backendKey, error) {
+	var raw [8]byte
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for {
+		if _, err := rand.Read(raw[:]); err != nil {
+			return backendKey{}, fmt.Errorf("generate backend key: %w", err)
+		}
+		key := backendKey{
+			processID: binary.BigEndian.Uint32(raw[:4]) & 0x7fffffff,
+			secret:    binary.BigEndian.Uint32(raw[4:]),
+		}
+		if key.processID == 0 {
+			continue
+		}
+		if _, exists := r.conns[key.processID]; exists {
+			continue
+		}
+		r.conns[key.processID] = cancelTarget{secret: key.secret, state: state}
+		return key, nil
+	}
+}
+
+func (r *cancelRegistry) unregister(key backendKey) {
+	r.mu.Lock()
+	delete(r.conns, key.processID)
+	r.mu.Unlock()
+}
+
+// cancel interrupts the running statement of the connection identified by
+// key. Unknown keys and secret mismatches are ignored, matching PostgreSQL,
+// so a cancel connection cannot probe which process IDs exist.
+func (r *cancelRegistry) cancel(key backendKey) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	target, ok := r.conns[key.processID]
+	r.mu.Unlock()
+	if !ok || subtle.ConstantTimeEq(int32(target.secret), int32(key.secret)) != 1 {
+		return false
+	}
+	return target.state.cancelStatement()
+}
+
+// parseCancelRequest decodes a CancelRequest startup payload (the packet
+// without its length prefix).
+func parseCancelRequest(payload []byte) (backendKey, error) {
+	if len(payload) != 12 {
+		return backendKey{}, fmt.Errorf("invalid CancelRequest packet length: %d", len(payload)+4)
+	}
+	return backendKey{
+		processID: binary.BigEndian.Uint32(payload[4:8]),
+		secret:    binary.BigEndian.Uint32(payload[8:12]),
+	}, nil
+}
+
+func isCancelRequest(payload []byte) bool {
+	return len(payload) >= 4 && int32(binary.BigEndian.Uint32(payload[:4])) == cancelRequestCode
+}
+
+// statementCancel tracks the cancel function of the statement a connection is
+// executing. It is the only connState field touched from another goroutine.
+type statementCancel struct {
+	mu     sync.Mutex
+	cancel context.CancelFunc
+}
+
+func (s *connState) cancelStatement() bool {
+	if s == nil {
+		return false
+	}
+	s.running.mu.Lock()
+	cancel := s.running.cancel
+	s.running.mu.Unlock()
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	return true
+}
+
+func (s *connState) trackStatement(cancel context.CancelFunc) context.CancelFunc {
+	s.running.mu.Lock()
+	s.running.cancel = cancel
+	s.running.mu.Unlock()
+	return func() {
+		s.running.mu.Lock()
+		s.running.cancel = nil
+		s.running.mu.Unlock()
+		cancel()
+	}
+}
@@ -0,0 +1,177 @@
+package pgwire
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func sendCancelRequest(t *testing.T, addr string, key backendKey) {
+	t.Helper()
+	conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+	if err != nil {
+		t.Fatalf("dial cancel connection: %v", err)
+	}
+	defer conn.Close()
+
+	var packet [16]byte
+	binary.BigEndian.PutUint32(packet[0:4], 16)
+	binary.BigEndian.PutUint32(packet[4:8], uint32(cancelRequestCode))
+	binary.BigEndian.PutUint32(packet[8:12], key.processID)
+	binary.BigEndian.PutUint32(packet[12:16], key.secret)
+	if _, err := conn.Write(packet[:]); err != nil {
+		t.Fatalf("write CancelRequest: %v", err)
+	}
+	// PostgreSQL closes a cancel connection without a response.
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var one [1]byte
+	if n, err := conn.Read(one[:]); n != 0 || !errors.Is(err, io.EOF) {
+		t.Fatalf("cancel connection read = (%d, %v), want EOF", n, err)
+	}
+}
+
+func startupWithBackendKey(t *testing.T, conn net.Conn) backendKey {
+	t.Helper()
+	if err := sendStartupPacket(conn, "test", "test"); err != nil {
+		t.Fatalf("startup: %v", err)
+	}
+	var key backendKey
+	for {
+		msgType, payload, err := ReadMessage(conn)
+		if err != nil {
+			t.Fatalf("startup message: %v", err)
+		}
+		if msgType == msgBackendKeyData {
+			if len(payload) != 8 {
+				t.Fatalf("BackendKeyData payload length = %d", len(payload))
+			}
+			key.processID = binary.BigEndian.Uint32(payload[:4])
+			key.secret = binary.BigEndian.Uint32(payload[4:])
+		}
+		if msgType == msgReadyForQuery {
+			return key
+		}
+	}
+}
+
+func TestBackendKeyDataIsUniquePerConnection(t *testing.T) {
+	srv := startTestServer(t, openTestDB(t))
+
+	first := dialTestServer(t, srv)
+	defer first.Close()
+	second := dialTestServer(t, srv)
+	defer second.Close()
+
+	a := startupWithBackendKey(t, first)
+	b := startupWithBackendKey(t, second)
+	if a.processID == 0 || b.processID == 0 {
+		t.Fatalf("backend process IDs must be non-zero: %+v %+v", a, b)
+	}
+	if int32(a.processID) < 0 || int32(b.processID) < 0 {
+		t.Fatalf("backend process IDs must be positive int32 values: %+v %+v", a, b)
+	}
+	if a.processID == b.processID {
+		t.Fatalf("connections share process ID %d", a.processID)
+	}
+}
+
+func TestCancelRequestCancelsRunningStatement(t *testing.T) {
+	srv := startTestServer(t, openTestDB(t))
+	conn := dialTestServer(t, srv)
+	defer conn.Close()
+	key := startupWithBackendKey(t, conn)
+
+	srv.cancels.mu.Lock()
+	target, ok := srv.cancels.conns[key.processID]
+	srv.cancels.mu.Unlock()
+	if !ok {
+		t.Fatalf("backend key %d is not registered", key.processID)
+	}
+
+	// Stand in for a long-running statement on the target connection.
+	ctx, cancel := target.state.statementContext(context.Background())
+	defer cancel()
+
+	sendCancelRequest(t, srv.Addr(), backendKey{processID: key.processID, secret: key.secret + 1})
+	if ctx.Err() != nil {
+		t.Fatalf("cancel with the wrong secret interrupted the statement: %v", ctx.Err())
+	}
+
+	sendCancelRequest(t, srv.Addr(), key)
+	select {
+	case <-ctx.Done():
+	case <-time.After(2 * time.Second):
+		t.Fatal("statement context was not cancelled")
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("statement context error = %v, want context.Canceled", ctx.Err())
+	}
+
+	// The target connection remains usable after its statement is cancelled.
+	sendSimpleQuery(conn, "SELECT 1")
+	consumeUntilReady(t, conn)
+}
+
+func TestCancelRegistryIgnoresIdleConnection(t *testing.T) {
+	registry := newCancelRegistry()
+	state := newConnState()
+	key, err := registry.register(state)
+	if err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	if registry.cancel(key) {
+		t.Fatal("cancel reported success without a running statement")
+	}
+
+	ctx, finish := state.statementContext(context.Background())
+	finish()
+	if registry.cancel(key) {
+		t.Fatal("cancel reported success after the statement finished")
+	}
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		t.Fatalf("finished statement context error = %v", ctx.Err())
+	}
+
+	registry.unregister(key)
+	if _, err := parseCancelRequest(make([]byte, 8)); err == nil {
+		t.Fatal("short CancelRequest was accepted")
+	}
+}
+
+func TestCanceledStatementReportsQueryCanceled(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sendSimpleError(&buf, newConnState(), context.Canceled); err != nil {
+		t.Fatalf("sendSimpleError: %v", err)
+	}
+	msgType, payload, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatalf("read error response: %v", err)
+	}
+	if msgType != msgErrorResponse {
+		t.Fatalf("message type = %q, want ErrorResponse", msgType)
+	}
+	if code := errorResponseField(payload, 'C'); code != "57014" {
+		t.Fatalf("SQLSTATE = %q, want 57014", code)
+	}
+}
+
+func errorResponseField(payload []byte, field byte) string {
+	for len(payload) > 0 && payload[0] != 0 {
+		code := payload[0]
+		end := bytes.IndexByte(payload[1:], 0)
+		if end < 0 {
+			return ""
+		}
+		value := string(payload[1 : 1+end])
+		if code == field {
+			return value
+		}
+		payload = payload[end+2:]
+	}
+	return ""
+}
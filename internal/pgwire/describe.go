@@ -9,6 +9,7 @@ import (
 	"github.com/xDarkicex/lexer"
 	"github.com/xDarkicex/lexer/parser"
 	"github.com/xDarkicex/libravdb/internal/catalog"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
 	"github.com/xDarkicex/libravdb/libravdb"
 )
 
@@ -61,6 +62,24 @@ func describeStatement(db *libravdb.Database, query string, paramCount int) ([]u
 		return paramOIDs, columns, nil
 	}
 
+	// ALTER TABLE returns no rows, and its RENAME/ALTER COLUMN/constraint
+	// forms are recognised by the database ahead of the parser.
+	if len(trimmed) >= 6 && strings.EqualFold(trimmed[:6], "ALTER ") {
+		return make([]uint32, paramCount), nil, nil
+	}
+	// View DDL likewise returns no rows; the optimizer plans it outside the
+	// grammar.
+	if _, handled, err := optimizer.NewOptimizer(nil).OptimizeView([]byte(trimmed)); handled {
+		if err != nil {
+			return nil, nil, err
+		}
+		return make([]uint32, paramCount), nil, nil
+	}
+	// CREATE TABLE returns no rows either; a generated vector column's
+	// GENERATED FROM clause is also recognised ahead of the parser.
+	if len(trimmed) >= 13 && strings.EqualFold(trimmed[:13], "CREATE TABLE ") {
+		return make([]uint32, paramCount), nil, nil
+	}
 	src := []byte(trimmed)
 	doc := &parser.QueryDoc{}
 	if err := parser.Parse(src, doc); err != nil {
@@ -81,20 +100,18 @@ func describeStatement(db *libravdb.Database, query string, paramCount int) ([]u
 	if cat == nil {
 		return nil, nil, fmt.Errorf("catalog not initialized")
 	}
+	// EXPLAIN and EXPLAIN ANALYZE always return one JSONB plan column.
+	// Statements evaluated by the query-local row engine (CTEs, derived
+	// tables, ...) are not bindable as a whole; their parameters stay
+	// unspecified, as they would for the statement itself.
 	if doc.Explain {
-		if !doc.ExplainAnalyze {
-			return nil, nil, fmt.Errorf("EXPLAIN without ANALYZE is not supported; use EXPLAIN ANALYZE")
-		}
-		if !describeHasGraphJoin(doc) {
-			return nil, nil, fmt.Errorf("EXPLAIN ANALYZE currently supports graph queries only")
-		}
+		columns := []ColumnMeta{{Name: libravdb.SQLExplainColumn, TypeOID: OIDJSONB}}
 		markParams(doc, src)
 		binder := catalog.NewBinder(cat, src)
 		if err := binder.Bind(doc); err != nil {
-			return nil, nil, fmt.Errorf("bind error: %w", err)
+			return make([]uint32, paramCount), columns, nil
 		}
-		paramOIDs := inferParamOIDs(doc, src, cat, nil, paramCount)
-		return paramOIDs, []ColumnMeta{{Name: libravdb.SQLExplainColumn, TypeOID: OIDJSONB}}, nil
+		return inferParamOIDs(doc, src, cat, nil, paramCount), columns, nil
 	}
 	if dmlColumns, dml := describeDMLReturning(db, doc, src); dml {
 		return inferParamOIDs(doc, src, cat, nil, paramCount), dmlColumns, nil
@@ -138,10 +155,11 @@ func describeStatement(db *libravdb.Database, query string, paramCount int) ([]u
 			// Bare SELECT * has already been expanded from the collection schema.
 			// This keeps Describe Statement/Portal byte-for-byte aligned with the
 			// execution RowDescription used by database/sql and GORM.
-		} else if stmt.CTEsCount > 0 || describeSelectHasDerivedRelation(doc, stmt) || describeSelectHasWindow(doc, stmt) || describeSelectHasTemporalRange(doc, stmt) || len(doc.SubqueryExprs) > 0 {
+		} else if stmt.CTEsCount > 0 || db.ReadsView(src, doc) || describeSelectHasDerivedRelation(doc, stmt) || describeSelectHasWindow(doc, stmt) || describeSelectHasTemporalRange(doc, stmt) || len(doc.SubqueryExprs) > 0 {
 			// A Leiden CTE names a virtual relation (the CTE) that is absent
-			// from the catalog; derived and correlated subqueries likewise have
-			// query-local scope. Describe the projection list leniently instead.
+			// from the catalog, as does a plain view; derived and correlated
+			// subqueries likewise have query-local scope. Describe the
+			// projection list leniently instead.
 			columns = describeProjectionsLenient(db, doc, src, stmt)
 		} else {
 			binder := catalog.NewBinder(cat, src)
@@ -153,6 +171,7 @@ func describeStatement(db *libravdb.Database, query string, paramCount int) ([]u
 			byOID = buildScope(cat, src, stmt, doc)
 			columns = describeSelect(doc, src, cat, stmt, byOID)
 		}
+		describeUserFunctionResults(db, doc, src, stmt, columns)
 	}
 
 	paramOIDs := inferParamOIDs(doc, src, cat, byOID, paramCount)
@@ -267,6 +286,29 @@ func describeCollectionStar(db *libravdb.Database, doc *parser.QueryDoc, src []b
 	return columns, true
 }
 
+// describeUserFunctionResults types projections that call a function
+// registered with the database by its declared return type. Neither the
+// binder nor the lenient describer knows those functions, and both fall back
+// to text.
+func describeUserFunctionResults(db *libravdb.Database, doc *parser.QueryDoc, src []byte, stmt *parser.SelectStmt, columns []ColumnMeta) {
+	if db == nil || int(stmt.ProjectionsCount) != len(columns) {
+		return
+	}
+	for i := range columns {
+		proj := &doc.Projections[stmt.ProjectionsStart+int32(i)]
+		if proj.Star || proj.Expr.Kind != parser.NodeKindFunctionExpr || proj.Expr.ID < 0 || int(proj.Expr.ID) >= len(doc.FunctionExprs) {
+			continue
+		}
+		fn := &doc.FunctionExprs[proj.Expr.ID]
+		if fn.HasWindow {
+			continue
+		}
+		if info, ok := db.LookupSQLFunction(string(src[fn.NameStart:fn.NameEnd])); ok {
+			columns[i].TypeOID = collectionFieldOID(info.ReturnType)
+		}
+	}
+}
+
 func collectionFieldOID(field libravdb.FieldType) uint32 {
 	switch field {
 	case libravdb.IntField:
@@ -467,6 +509,8 @@ func describeSelect(doc *parser.QueryDoc, src []byte, cat *catalog.Catalog, stmt
 				oid = collectionAggregateOID(doc, src, cat, byOID, fn, false)
 			} else if strings.EqualFold(functionName, "string_agg") {
 				oid = OIDText
+			} else if strings.EqualFold(functionName, "EMBED") {
+				oid = OIDFloat4Array
 			} else if strings.EqualFold(functionName, "RRF") || strings.EqualFold(functionName, "FTS_RANK") || strings.EqualFold(functionName, "ts_rank") || strings.EqualFold(functionName, "ts_rank_cd") {
 				oid = OIDFloat8
 			}
@@ -1044,6 +1088,8 @@ func describeProjectionsLenient(db *libravdb.Database, doc *parser.QueryDoc, src
 				oid = collectionAggregateOID(doc, src, nil, nil, fn, true)
 			} else if strings.EqualFold(functionName, "string_agg") {
 				oid = OIDText
+			} else if strings.EqualFold(functionName, "EMBED") {
+				oid = OIDFloat4Array
 			} else if strings.EqualFold(functionName, "RRF") || strings.EqualFold(functionName, "FTS_RANK") || strings.EqualFold(functionName, "ts_rank") || strings.EqualFold(functionName, "ts_rank_cd") {
 				oid = OIDFloat8
 			}
@@ -1697,16 +1743,25 @@ func inferParamOIDs(doc *parser.QueryDoc, src []byte, cat *catalog.Catalog, byOI
 			setOID(pid, paramContextOID(vf.VectorB, OIDFloat4Array))
 		}
 	}
-	// FTS_RANK's query operand is textual. RRF itself is a score-producing
-	// wrapper, so its nested vector/text operands are inferred by these
-	// component walks rather than by treating the wrapper as a generic text
-	// function.
+	// FTS_RANK's query operand and EMBED's arguments are textual. RRF itself
+	// is a score-producing wrapper, so its nested vector/text operands are
+	// inferred by these component walks rather than by treating the wrapper as
+	// a generic text function.
 	for i := range doc.FunctionExprs {
 		fn := &doc.FunctionExprs[i]
 		if fn.ArgsCount == 0 || fn.NameStart >= uint32(len(src)) || fn.NameEnd > uint32(len(src)) {
 			continue
 		}
 		name := string(src[fn.NameStart:fn.NameEnd])
+		if strings.EqualFold(name, "EMBED") {
+			// Both the model name and the embedded text are textual.
+			for i := int32(0); i < fn.ArgsCount && fn.ArgsStart >= 0 && int(fn.ArgsStart+i) < len(doc.FunctionArgs); i++ {
+				if pid, ok := isParam(doc.FunctionArgs[fn.ArgsStart+i]); ok {
+					setOID(pid, OIDText)
+				}
+			}
+			continue
+		}
 		if !strings.EqualFold(name, "FTS_RANK") && !strings.EqualFold(name, "to_tsvector") && !strings.EqualFold(name, "to_tsquery") && !strings.EqualFold(name, "plainto_tsquery") && !strings.EqualFold(name, "phraseto_tsquery") && !strings.EqualFold(name, "websearch_to_tsquery") && !strings.EqualFold(name, "ts_rank") && !strings.EqualFold(name, "ts_rank_cd") {
 			continue
 		}
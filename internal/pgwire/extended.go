@@ -91,6 +91,9 @@ type connState struct {
 	epoch                     *libravdb.EpochTx
 	transactionState          transactionState
 	extendedSyncRequired      bool
+	running                   statementCancel
+	listener                  *changeListener
+	processID                 uint32
 }
 
 func newConnState() *connState {
@@ -823,6 +826,12 @@ func commandTagForSQL(query string, affected int) string {
 		return "CREATE TABLE"
 	case strings.HasPrefix(upper, "CREATE INDEX"):
 		return "CREATE INDEX"
+	case strings.HasPrefix(upper, "CREATE VIEW"), strings.HasPrefix(upper, "CREATE OR REPLACE VIEW"):
+		return "CREATE VIEW"
+	case strings.HasPrefix(upper, "CREATE MATERIALIZED VIEW"):
+		return "CREATE MATERIALIZED VIEW"
+	case strings.HasPrefix(upper, "REFRESH MATERIALIZED VIEW"):
+		return "REFRESH MATERIALIZED VIEW"
 	case strings.HasPrefix(upper, "CREATE COLLECTION"):
 		return "CREATE COLLECTION"
 	case strings.HasPrefix(upper, "ALTER "):
@@ -365,6 +365,43 @@ func handleInformationSchema(sql string) (*libravdb.SearchResults, []ColumnMeta,
 func handleInformationSchemaWithParams(sql string, db *libravdb.Database, params *optimizer.ParameterSet) (*libravdb.SearchResults, []ColumnMeta, bool) {
 	upper := strings.ToUpper(sql)
 	switch {
+	case strings.Contains(upper, "INFORMATION_SCHEMA.VIEWS"):
+		columns := []ColumnMeta{
+			{Name: "table_catalog", TypeOID: OIDName},
+			{Name: "table_schema", TypeOID: OIDName},
+			{Name: "table_name", TypeOID: OIDName},
+			{Name: "view_definition", TypeOID: OIDText},
+			{Name: "check_option", TypeOID: OIDText},
+			{Name: "is_updatable", TypeOID: OIDText},
+			{Name: "is_insertable_into", TypeOID: OIDText},
+			{Name: "is_trigger_updatable", TypeOID: OIDText},
+			{Name: "is_trigger_deletable", TypeOID: OIDText},
+			{Name: "is_trigger_insertable_into", TypeOID: OIDText},
+		}
+		name := catalogTargetTable(sql, params)
+		rows := make([]*libravdb.SearchResult, 0)
+		if db != nil {
+			// Materialized views are not listed, as in PostgreSQL.
+			for _, view := range db.ListViews() {
+				if view.Materialized || name != "" && name != "public" && !strings.EqualFold(name, view.Name) {
+					continue
+				}
+				rows = append(rows, &libravdb.SearchResult{ID: view.Name, Score: 1, Metadata: map[string]interface{}{
+					"table_catalog":              "libravdb",
+					"table_schema":               "public",
+					"table_name":                 view.Name,
+					"view_definition":            view.Query,
+					"check_option":               "NONE",
+					"is_updatable":               "NO",
+					"is_insertable_into":         "NO",
+					"is_trigger_updatable":       "NO",
+					"is_trigger_deletable":       "NO",
+					"is_trigger_insertable_into": "NO",
+				}})
+			}
+		}
+		return &libravdb.SearchResults{Results: rows, Total: len(rows), Columns: columnNames(columns), ColumnTypes: columnOIDs(columns)}, columns, true
+
 	case strings.Contains(upper, "INFORMATION_SCHEMA.TABLES"):
 		columns := []ColumnMeta{{Name: "count", TypeOID: OIDInt8}}
 		name := catalogTargetTable(sql, params)
@@ -869,20 +906,41 @@ func handlePgClassNamespaceQuery(db *libravdb.Database, sql string) (*libravdb.S
 		columns = []ColumnMeta{{Name: "relname", TypeOID: OIDName}}
 	}
 	names := []string{}
+	var views []libravdb.View
 	if db != nil {
 		names = db.ListCollections()
+		views = db.ListViews()
+	}
+	// Materialized views are collections; report them with their own
+	// relkind. Plain views have no collection and are appended.
+	relkinds := make(map[string]string, len(names)+len(views))
+	for _, name := range names {
+		relkinds[name] = "r"
+	}
+	for _, view := range views {
+		if view.Materialized {
+			relkinds[view.Name] = "m"
+		} else {
+			relkinds[view.Name] = "v"
+			names = append(names, view.Name)
+		}
 	}
 	rows := make([]*libravdb.SearchResult, 0, len(names))
 	for i, name := range names {
+		relkind := relkinds[name]
+		typ := "t"
+		if relkind != "r" {
+			typ = "v"
+		}
 		rows = append(rows, &libravdb.SearchResult{
 			ID: name, Score: 1,
 			Metadata: map[string]interface{}{
 				"oid":            int64(100 + i),
 				"relname":        name,
-				"type":           "t",
+				"type":           typ,
 				"comment":        nil,
 				"relnamespace":   int64(2200),
-				"relkind":        "r",
+				"relkind":        relkind,
 				"relpersistence": "p",
 			},
 		})
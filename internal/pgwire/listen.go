@@ -0,0 +1,212 @@
+package pgwire
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/xDarkicex/libravdb/libravdb"
+)
+
+// changesChannel is the LISTEN channel that carries the database change feed.
+const changesChannel = "libravdb_changes"
+
+// maxQueuedNotifications bounds the notifications held back while the session
+// is busy. The feed pauses once it is reached and resumes from durable storage
+// when the queue drains, so nothing is dropped.
+const maxQueuedNotifications = 1024
+
+// changeListener forwards change-feed events to one session as
+// NotificationResponse messages. The connection goroutine owns the socket
+// while it handles a message, so notifications are written directly only
+// while it waits for the next one and are queued otherwise.
+type changeListener struct {
+	mu      sync.Mutex
+	drained *sync.Cond
+	w       io.Writer
+	queue   [][]byte
+	err     error
+	cancel  context.CancelFunc
+	done    chan struct{}
+	pid     uint32
+	idle    bool
+	stopped bool
+}
+
+// changeNotification is the JSON payload of one notification. Record
+// versions are left out to stay inside PostgreSQL's notification size limit;
+// clients read them back through temporal queries at lsn.
+type changeNotification struct {
+	Type       string `json:"type"`
+	Collection string `json:"collection,omitempty"`
+	ID         string `json:"id,omitempty"`
+	LSN        uint64 `json:"lsn"`
+	Src        uint64 `json:"src,omitempty"`
+	Tgt        uint64 `json:"tgt,omitempty"`
+	Node       uint64 `json:"node,omitempty"`
+	Kind       uint8  `json:"kind,omitempty"`
+}
+
+// handleListen answers LISTEN and UNLISTEN for the change feed channel.
+// Other channels fall through to the regular query path.
+func handleListen(rw io.ReadWriter, db *libravdb.Database, state *connState, query string) (bool, error) {
+	command, channel, ok := parseListenCommand(query)
+	if !ok || channel != changesChannel {
+		return false, nil
+	}
+	switch command {
+	case "LISTEN":
+		if err := state.listen(db, rw); err != nil {
+			return true, sendSimpleError(rw, state, err)
+		}
+	case "UNLISTEN":
+		state.unlisten()
+	}
+	if err := sendCommandComplete(rw, command); err != nil {
+		return true, err
+	}
+	return true, sendReadyForQuery(rw, state.readyStatus())
+}
+
+// parseListenCommand splits "LISTEN channel" or "UNLISTEN channel", folding
+// an unquoted channel name to lower case the way PostgreSQL does.
+func parseListenCommand(query string) (command, channel string, ok bool) {
+	fields := strings.Fields(strings.TrimRight(strings.TrimSpace(query), ";"))
+	if len(fields) != 2 {
+		return "", "", false
+	}
+	command = strings.ToUpper(fields[0])
+	if command != "LISTEN" && command != "UNLISTEN" {
+		return "", "", false
+	}
+	channel = fields[1]
+	if len(channel) >= 2 && channel[0] == '"' && channel[len(channel)-1] == '"' {
+		channel = channel[1 : len(channel)-1]
+	} else {
+		channel = strings.ToLower(channel)
+	}
+	return command, channel, true
+}
+
+// listen subscribes the session to changes committed from now on. Listening
+// twice is a no-op, as in PostgreSQL.
+func (s *connState) listen(db *libravdb.Database, w io.Writer) error {
+	if s.listener != nil {
+		return nil
+	}
+	from, err := db.LatestCommitLSN(context.Background())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	listener := &changeListener{w: w, pid: s.processID, cancel: cancel, done: make(chan struct{})}
+	listener.drained = sync.NewCond(&listener.mu)
+	s.listener = listener
+	go listener.run(db.Subscribe(ctx, libravdb.SubscribeOptions{FromLSN: from}))
+	return nil
+}
+
+// unlisten stops the session's change feed and discards queued
+// notifications.
+func (s *connState) unlisten() {
+	if s == nil || s.listener == nil {
+		return
+	}
+	s.listener.stop()
+	s.listener = nil
+}
+
+// awaitingMessage hands the socket to the change listener, flushing what it
+// queued while the last message was handled.
+func (s *connState) awaitingMessage() error {
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.setIdle(true)
+}
+
+// handlingMessage takes the socket back from the change listener.
+func (s *connState) handlingMessage() {
+	if s.listener != nil {
+		_ = s.listener.setIdle(false)
+	}
+}
+
+func (l *changeListener) run(feed iter.Seq2[libravdb.ChangeEvent, error]) {
+	defer close(l.done)
+	for event, err := range feed {
+		if err != nil {
+			return
+		}
+		if !l.deliver(notificationMessage(l.pid, event)) {
+			return
+		}
+	}
+}
+
+func (l *changeListener) deliver(message []byte) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for !l.stopped && !l.idle && len(l.queue) >= maxQueuedNotifications {
+		l.drained.Wait()
+	}
+	if l.stopped || l.err != nil {
+		return false
+	}
+	if l.idle {
+		l.err = WriteMessage(l.w, msgNotificationResponse, message)
+		return l.err == nil
+	}
+	l.queue = append(l.queue, message)
+	return true
+}
+
+func (l *changeListener) setIdle(idle bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if idle && l.err == nil {
+		for _, message := range l.queue {
+			if l.err = WriteMessage(l.w, msgNotificationResponse, message); l.err != nil {
+				break
+			}
+		}
+		l.queue = nil
+		l.drained.Broadcast()
+	}
+	l.idle = idle
+	return l.err
+}
+
+func (l *changeListener) stop() {
+	l.cancel()
+	l.mu.Lock()
+	l.stopped = true
+	l.queue = nil
+	l.drained.Broadcast()
+	l.mu.Unlock()
+	<-l.done
+}
+
+// notificationMessage encodes a NotificationResponse body: the backend
+// process ID, the channel name and a JSON description of the change.
+func notificationMessage(pid uint32, event libravdb.ChangeEvent) []byte {
+	note := changeNotification{
+		Type:       event.Type.String(),
+		Collection: event.Collection,
+		ID:         event.ID,
+		LSN:        event.CommitLSN,
+		Node:       event.NodeID,
+	}
+	switch event.Type {
+	case libravdb.ChangeEdgeAdd, libravdb.ChangeEdgeRemove:
+		note.Src, note.Tgt, note.Kind = event.Edge.Src, event.Edge.Tgt, event.Edge.Kind
+	}
+	payload, _ := json.Marshal(note)
+	buf := binary.BigEndian.AppendUint32(nil, pid)
+	buf = WriteNullTerminated(buf, changesChannel)
+	return WriteNullTerminated(buf, string(payload))
+}
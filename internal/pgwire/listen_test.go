@@ -0,0 +1,109 @@
+package pgwire
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/xDarkicex/libravdb/libravdb"
+)
+
+func TestListenDeliversChangeNotifications(t *testing.T) {
+	db, err := libravdb.Open(
+		libravdb.WithStoragePath(":memory:pgwire_listen"),
+		libravdb.WithMetrics(false),
+	)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	col, err := db.CreateCollection(ctx, "docs", libravdb.WithDimension(2))
+	if err != nil {
+		t.Fatalf("CreateCollection: %v", err)
+	}
+	if err := col.Insert(ctx, "before", []float32{1, 0}, nil); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+
+	srv := startTestServer(t, db)
+	defer srv.Close()
+	conn := dialTestServer(t, srv)
+	defer conn.Close()
+	doTestStartup(t, conn)
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	sendSimpleQuery(conn, "LISTEN libravdb_changes")
+	msgType, payload, err := ReadMessage(conn)
+	if err != nil || msgType != msgCommandComplete || !bytes.HasPrefix(payload, []byte("LISTEN\x00")) {
+		t.Fatalf("LISTEN reply = %c %q, %v", msgType, payload, err)
+	}
+	consumeReadyForQuery(t, conn)
+
+	if err := col.Insert(ctx, "after", []float32{0, 1}, nil); err != nil {
+		t.Fatalf("Insert: %v", err)
+	}
+	receipt, err := db.LatestCommitLSN(ctx)
+	if err != nil {
+		t.Fatalf("LatestCommitLSN: %v", err)
+	}
+	msgType, payload, err = ReadMessage(conn)
+	if err != nil || msgType != msgNotificationResponse {
+		t.Fatalf("expected NotificationResponse, got %c: %v", msgType, err)
+	}
+	fields := bytes.Split(payload[4:], []byte{0})
+	if len(fields) < 2 || string(fields[0]) != changesChannel || binary.BigEndian.Uint32(payload[:4]) == 0 {
+		t.Fatalf("notification = %q", payload)
+	}
+	var note changeNotification
+	if err := json.Unmarshal(fields[1], &note); err != nil {
+		t.Fatalf("notification payload %q: %v", fields[1], err)
+	}
+	if note.Type != "put" || note.Collection != "docs" || note.ID != "after" || note.LSN != receipt {
+		t.Fatalf("notification = %+v, want put docs/after at LSN %d", note, receipt)
+	}
+
+	sendSimpleQuery(conn, "UNLISTEN libravdb_changes")
+	assertMessageType(t, conn, msgCommandComplete, "CommandComplete")
+	consumeReadyForQuery(t, conn)
+	if err := col.Delete(ctx, "after"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	sendSimpleQuery(conn, "SELECT 1")
+	for {
+		msgType, _, err := ReadMessage(conn)
+		if err != nil {
+			t.Fatalf("after UNLISTEN: %v", err)
+		}
+		if msgType == msgNotificationResponse {
+			t.Fatal("notification delivered after UNLISTEN")
+		}
+		if msgType == msgReadyForQuery {
+			break
+		}
+	}
+}
+
+func TestParseListenCommand(t *testing.T) {
+	cases := []struct {
+		query, command, channel string
+		ok                      bool
+	}{
+		{"LISTEN libravdb_changes", "LISTEN", "libravdb_changes", true},
+		{"listen LIBRAVDB_CHANGES;", "LISTEN", "libravdb_changes", true},
+		{`UNLISTEN "libravdb_changes"`, "UNLISTEN", "libravdb_changes", true},
+		{`LISTEN "Mixed"`, "LISTEN", "Mixed", true},
+		{"UNLISTEN *", "UNLISTEN", "*", true},
+		{"SELECT 1", "", "", false},
+		{"LISTEN", "", "", false},
+	}
+	for _, tc := range cases {
+		command, channel, ok := parseListenCommand(tc.query)
+		if command != tc.command || channel != tc.channel || ok != tc.ok {
+			t.Errorf("parseListenCommand(%q) = %q, %q, %v", tc.query, command, channel, ok)
+		}
+	}
+}
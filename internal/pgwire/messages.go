@@ -45,10 +45,15 @@ const (
 	msgPortalSuspended      byte = 's'
 	msgCopyInResponse       byte = 'G'
 	msgCopyOutResponse      byte = 'H'
+	msgNotificationResponse byte = 'A'
 
 	// SSL negotiation
 	sslRequestCode int32 = 80877103
 
+	// CancelRequest is an untyped startup packet carrying a BackendKeyData
+	// process ID and secret key.
+	cancelRequestCode int32 = 80877102
+
 	// Authentication types
 	authOK        int32 = 0
 	authCleartext int32 = 3
@@ -17,6 +17,9 @@ func handleQuery(rw io.ReadWriter, db *libravdb.Database, state *connState, quer
 	if handled, err := handleConnectionReset(rw, state, query); handled {
 		return err
 	}
+	if handled, err := handleListen(rw, db, state, query); handled {
+		return err
+	}
 	if handled, err := handleServerCursorSimple(rw, state, query); handled {
 		return err
 	}
@@ -144,6 +147,7 @@ func handleConnectionReset(rw io.ReadWriter, state *connState, query string) (bo
 				return true, err
 			}
 		case "UNLISTEN *":
+			state.unlisten()
 			if err := sendCommandComplete(rw, "UNLISTEN"); err != nil {
 				return true, err
 			}
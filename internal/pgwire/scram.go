@@ -96,6 +96,63 @@ func deriveSCRAMCredential(username, password string, salt []byte, iterations in
 	}, nil
 }
 
+// ParseSCRAMVerifier decodes a verifier in PostgreSQL's rolpassword format,
+//
+//	SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>
+//
+// with base64 salt and keys, into a credential for username. Verifiers
+// exported from PostgreSQL can be used unchanged.
+func ParseSCRAMVerifier(username, verifier string) (SCRAMCredential, error) {
+	rest, ok := strings.CutPrefix(verifier, scramSHA256Mechanism+"$")
+	if !ok {
+		return SCRAMCredential{}, fmt.Errorf("SCRAM verifier must start with %s$", scramSHA256Mechanism)
+	}
+	params, keys, ok := strings.Cut(rest, "$")
+	if !ok {
+		return SCRAMCredential{}, fmt.Errorf("SCRAM verifier is missing its keys")
+	}
+	iterText, saltText, ok := strings.Cut(params, ":")
+	if !ok {
+		return SCRAMCredential{}, fmt.Errorf("SCRAM verifier is missing its salt")
+	}
+	storedText, serverText, ok := strings.Cut(keys, ":")
+	if !ok {
+		return SCRAMCredential{}, fmt.Errorf("SCRAM verifier is missing its server key")
+	}
+	iterations, err := strconv.Atoi(iterText)
+	if err != nil {
+		return SCRAMCredential{}, fmt.Errorf("SCRAM verifier iteration count is not a number")
+	}
+	credential := SCRAMCredential{Username: username, Iterations: iterations}
+	for _, field := range []struct {
+		name string
+		text string
+		dst  *[]byte
+	}{
+		{"salt", saltText, &credential.Salt},
+		{"stored key", storedText, &credential.StoredKey},
+		{"server key", serverText, &credential.ServerKey},
+	} {
+		decoded, err := base64.StdEncoding.DecodeString(field.text)
+		if err != nil {
+			return SCRAMCredential{}, fmt.Errorf("SCRAM verifier %s is not valid base64", field.name)
+		}
+		*field.dst = decoded
+	}
+	if err := validateSCRAMCredential(credential); err != nil {
+		return SCRAMCredential{}, fmt.Errorf("invalid SCRAM verifier: %w", err)
+	}
+	return credential, nil
+}
+
+// Verifier encodes the credential in the format read by ParseSCRAMVerifier.
+func (c SCRAMCredential) Verifier() string {
+	return scramSHA256Mechanism + "$" + strconv.Itoa(c.Iterations) + ":" +
+		base64.StdEncoding.EncodeToString(c.Salt) + "$" +
+		base64.StdEncoding.EncodeToString(c.StoredKey) + ":" +
+		base64.StdEncoding.EncodeToString(c.ServerKey)
+}
+
 func normalizeSCRAMPassword(password string) ([]byte, error) {
 	// PRECIS OpaqueString is the RFC 7613 successor used by PostgreSQL clients
 	// for SASLprep-compatible password preparation. Credential creation is
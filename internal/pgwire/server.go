@@ -134,6 +134,8 @@ type ServerConfig struct {
 	authMetrics            *authMetrics
 	trustedProxyNetworks   []*net.IPNet
 	authClientKey          string
+	cancelRegistry         *cancelRegistry
+	backendKey             backendKey
 }
 
 // Server is a PostgreSQL wire protocol listener that exposes a libravdb.Database
@@ -148,6 +150,7 @@ type Server struct {
 	connSem     chan struct{} // semaphore for MaxConnections
 	authLimiter *authFailureLimiter
 	authMetrics *authMetrics
+	cancels     *cancelRegistry
 	closed      bool
 }
 
@@ -175,6 +178,7 @@ func NewServer(db *libravdb.Database, config ServerConfig) *Server {
 			globalFailureWindow: config.AuthGlobalFailureWindow,
 		}),
 		authMetrics: &authMetrics{},
+		cancels:     newCancelRegistry(),
 	}
 	if config.TLSConfig != nil {
 		s.config.TLSConfig = config.TLSConfig.Clone()
@@ -458,14 +462,33 @@ func (s *Server) handleConn(ctx context.Context, conn net.Conn, tlsConfig *tls.C
 		startupConn = &proxyConn{Conn: conn, remote: forwardedAddr}
 	}
 
+	// The connection state exists before startup so its backend key can be
+	// announced in BackendKeyData and targeted by later CancelRequests.
+	state := newConnState()
+	key, err := s.cancels.register(state)
+	if err != nil {
+		return
+	}
+	defer s.cancels.unregister(key)
+	state.processID = key.processID
+	// Close the socket before stopping a LISTEN feed so a notification write
+	// blocked on a stalled client returns.
+	defer func() {
+		_ = conn.Close()
+		state.unlisten()
+	}()
+
 	// Startup handshake
 	startupConfig := s.config
 	startupConfig.authLimiter = s.authLimiter
 	startupConfig.authMetrics = s.authMetrics
 	startupConfig.authClientKey = authClientKey(startupConn)
+	startupConfig.cancelRegistry = s.cancels
+	startupConfig.backendKey = key
 	rw, _, err := handleStartupWithConfigContext(ctx, startupConn, s.db, tlsConfig, s.config.RequireTLS, startupConfig)
 	if err != nil {
-		// Already sent error to client in handleStartup
+		// Already sent error to client in handleStartup; a CancelRequest
+		// connection is closed without any response.
 		return
 	}
 
@@ -477,7 +500,6 @@ func (s *Server) handleConn(ctx context.Context, conn net.Conn, tlsConfig *tls.C
 	}
 
 	// Extended query protocol state and optional epoch transaction.
-	state := newConnState()
 	state.maxPreparedStatements = configuredLimit(s.config.MaxPreparedStatements, DefaultMaxPreparedStatements)
 	state.maxPortals = configuredLimit(s.config.MaxPortals, DefaultMaxPortals)
 	state.maxPreparedStatementBytes = configuredLimit(s.config.MaxPreparedStatementBytes, DefaultMaxPreparedStatementBytes)
@@ -503,12 +525,16 @@ func (s *Server) handleConn(ctx context.Context, conn net.Conn, tlsConfig *tls.C
 		// so one message's worth of arena memory is sufficient.
 		arena.reset()
 
+		if err := state.awaitingMessage(); err != nil {
+			return
+		}
 		if hasReadDeadline {
 			if err := readDeadlineConn.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
 				return
 			}
 		}
 		msgType, payload, err := readMessageArena(rw, arena)
+		state.handlingMessage()
 		if err != nil {
 			return
 		}
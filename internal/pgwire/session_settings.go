@@ -13,11 +13,15 @@ import (
 
 const pgwireSafetyTimeout = 30 * time.Second
 
+// statementContext bounds one statement by the session timeout. The returned
+// context is also the target of a CancelRequest for this connection, which
+// surfaces to the client as SQLSTATE 57014.
 func (s *connState) statementContext(base context.Context) (context.Context, context.CancelFunc) {
 	if s == nil {
 		return context.WithTimeout(base, pgwireSafetyTimeout)
 	}
-	return context.WithTimeout(base, s.config.EffectiveTimeout(pgwireSafetyTimeout))
+	ctx, cancel := context.WithTimeout(base, s.config.EffectiveTimeout(pgwireSafetyTimeout))
+	return ctx, s.trackStatement(cancel)
 }
 
 // applySessionSettingSQL parses and applies one SET/RESET command. The
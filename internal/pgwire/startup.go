@@ -56,6 +56,9 @@ func handleStartupWithConfigContext(ctx context.Context, rw io.ReadWriter, db *l
 	if err != nil {
 		return rw, nil, err
 	}
+	if isCancelRequest(payload) {
+		return rw, nil, handleCancelRequest(payload, config)
+	}
 
 	// SSLRequest is a special untyped startup packet. PostgreSQL requires the
 	// one-byte response to be sent before the TLS record layer is installed.
@@ -102,6 +105,9 @@ func handleStartupWithConfigContext(ctx context.Context, rw io.ReadWriter, db *l
 				return rw, nil, fmt.Errorf("reading startup over TLS: %w", err)
 			}
 		}
+		if isCancelRequest(payload) {
+			return rw, nil, handleCancelRequest(payload, config)
+		}
 	} else if requireTLS {
 		startupErr := fmt.Errorf("TLS is required")
 		_ = sendErrorWithCode(rw, "FATAL", "08004", startupErr.Error())
@@ -178,12 +184,24 @@ func handleStartupWithConfigContext(ctx context.Context, rw io.ReadWriter, db *l
 		return rw, nil, err
 	}
 
-	if err := sendStartupReady(rw, db); err != nil {
+	if err := sendStartupReady(rw, db, config.backendKey); err != nil {
 		return rw, nil, err
 	}
 	return rw, result, nil
 }
 
+// handleCancelRequest signals the connection named by a CancelRequest. The
+// request is authorized only by its secret key, so it is accepted before TLS
+// and authentication policy exactly as PostgreSQL does; no response is sent.
+func handleCancelRequest(payload []byte, config ServerConfig) error {
+	key, err := parseCancelRequest(payload)
+	if err != nil {
+		return err
+	}
+	config.cancelRegistry.cancel(key)
+	return errCancelRequestHandled
+}
+
 func shouldRecordAuthFailure(err error) bool {
 	if err == nil || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return false
@@ -284,7 +302,7 @@ func readStartupField(payload []byte, offset int) (string, int, error) {
 	return string(payload[offset:end]), end + 1, nil
 }
 
-func sendStartupReady(w io.Writer, db *libravdb.Database) error {
+func sendStartupReady(w io.Writer, db *libravdb.Database, key backendKey) error {
 	// PostgreSQL exposes the numeric server_version parameter separately from
 	// the verbose version() function result. Drivers such as Django's psycopg
 	// backend parse this startup value as an integer version.
@@ -318,12 +336,9 @@ func sendStartupReady(w io.Writer, db *libravdb.Database) error {
 	if err := sendParameterStatus(w, "libravdb_latest_commit_lsn", strconv.FormatUint(latestLSN, 10)); err != nil {
 		return err
 	}
-	// BackendKeyData: PID and secret key (zeroed — cancel support is not yet
-	// exposed by the server).
-	if err := WriteMessage(w, msgBackendKeyData, []byte{
-		0, 0, 0, 0,
-		0, 0, 0, 0,
-	}); err != nil {
+	// BackendKeyData: the process ID and secret a client sends back in a
+	// CancelRequest. In-process helpers without a server registry send zeroes.
+	if err := WriteMessage(w, msgBackendKeyData, key.payload()); err != nil {
 		return err
 	}
 	return WriteMessage(w, msgReadyForQuery, []byte{'I'})
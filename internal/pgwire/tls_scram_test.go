@@ -866,3 +866,38 @@ func testHMACSHA256(key, data []byte) []byte {
 	_, _ = h.Write(data)
 	return h.Sum(nil)
 }
+
+func TestSCRAMVerifierRoundTrip(t *testing.T) {
+	credential, err := deriveSCRAMCredential("alice", "secret", []byte("fixed-scram-salt"), DefaultSCRAMIterations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := credential.Verifier()
+	if !strings.HasPrefix(verifier, "SCRAM-SHA-256$4096:Zml4ZWQtc2NyYW0tc2FsdA==$") {
+		t.Fatalf("verifier = %q", verifier)
+	}
+	parsed, err := ParseSCRAMVerifier("alice", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Username != "alice" || parsed.Iterations != credential.Iterations ||
+		!bytes.Equal(parsed.Salt, credential.Salt) || !bytes.Equal(parsed.StoredKey, credential.StoredKey) || !bytes.Equal(parsed.ServerKey, credential.ServerKey) {
+		t.Fatalf("parsed credential = %+v, want %+v", parsed, credential)
+	}
+
+	stored, server, _ := strings.Cut(verifier[strings.LastIndexByte(verifier, '$')+1:], ":")
+	for _, bad := range []string{
+		"",
+		"md5c4ca4238a0b923820dcc509a6f75849b",
+		"SCRAM-SHA-256$4096:Zml4ZWQtc2NyYW0tc2FsdA==",
+		"SCRAM-SHA-256$many:Zml4ZWQtc2NyYW0tc2FsdA==$" + stored + ":" + server,
+		"SCRAM-SHA-256$1024:Zml4ZWQtc2NyYW0tc2FsdA==$" + stored + ":" + server,
+		"SCRAM-SHA-256$4096:!!!$" + stored + ":" + server,
+		"SCRAM-SHA-256$4096:Zml4ZWQtc2NyYW0tc2FsdA==$" + stored,
+		"SCRAM-SHA-256$4096:Zml4ZWQtc2NyYW0tc2FsdA==$" + stored + ":c2hvcnQ=",
+	} {
+		if _, err := ParseSCRAMVerifier("alice", bad); err == nil {
+			t.Fatalf("ParseSCRAMVerifier(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,607 @@
+package quant
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+
+	"github.com/xDarkicex/libravdb/internal/util/simd"
+)
+
+// binaryRotationRounds is the number of sign-flip + Hadamard rounds in the
+// random rotation. Three rounds mix well enough that every output
+// coordinate depends on every input coordinate.
+const binaryRotationRounds = 3
+
+const (
+	binaryFlagSignThreshold uint32 = 1 << iota
+	binaryFlagRotation
+)
+
+// BinaryQuantizer implements 1-bit binary quantization. Every coordinate
+// becomes one bit (above or below its threshold), so a 1536-d float32
+// vector packs into 192 bytes and two codes are compared with XOR +
+// popcount. Distances are coarse estimates: callers are expected to rescore
+// the best candidates against full-precision vectors.
+type BinaryQuantizer struct {
+	config      *QuantizationConfig
+	rotation    *binaryRotation
+	means       []float32
+	scales      []float32
+	unitScale   float32
+	dimension   int
+	memoryUsage int64
+	mu          sync.RWMutex
+	trained     bool
+}
+
+// binaryQueryState carries the query's binary code so DistanceToQuery does
+// not re-encode the query for every candidate.
+type binaryQueryState struct {
+	code []byte
+}
+
+func NewBinaryQuantizer() *BinaryQuantizer {
+	return &BinaryQuantizer{}
+}
+
+func (bq *BinaryQuantizer) Configure(config *QuantizationConfig) error {
+	if config == nil {
+		return fmt.Errorf("config cannot be nil")
+	}
+	if err := config.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if config.Type != BinaryQuantization {
+		return fmt.Errorf("expected BinaryQuantization type, got %s", config.Type.String())
+	}
+
+	bq.mu.Lock()
+	defer bq.mu.Unlock()
+
+	configCopy := *config
+	configCopy.Levels = nil
+	bq.config = &configCopy
+	return nil
+}
+
+// Train learns the per-dimension thresholds and reconstruction scales. With
+// the sign threshold the thresholds stay at zero and only the scales are
+// learned.
+func (bq *BinaryQuantizer) Train(ctx context.Context, vectors [][]float32) error {
+	if len(vectors) == 0 {
+		return fmt.Errorf("no training vectors provided")
+	}
+	if bq.config == nil {
+		return fmt.Errorf("quantizer not configured")
+	}
+
+	bq.mu.Lock()
+	defer bq.mu.Unlock()
+
+	dimension := len(vectors[0])
+	if dimension == 0 {
+		return fmt.Errorf("training vectors cannot be empty")
+	}
+	for i, vec := range vectors {
+		if len(vec) != dimension {
+			return fmt.Errorf("vector %d has dimension %d, expected %d", i, len(vec), dimension)
+		}
+	}
+
+	numTraining := int(float64(len(vectors)) * bq.config.TrainRatio)
+	if numTraining < 1 {
+		numTraining = len(vectors)
+	}
+	trainingVectors := sampleVectors(vectors, numTraining)
+
+	var rotation *binaryRotation
+	if bq.config.Rotation {
+		rotation = newBinaryRotation(dimension, bq.config.Seed)
+	}
+	projected := make([][]float32, len(trainingVectors))
+	for i, vec := range trainingVectors {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		if rotation != nil {
+			projected[i] = rotation.apply(vec)[:dimension]
+		} else {
+			projected[i] = vec
+		}
+	}
+
+	means := make([]float32, dimension)
+	if bq.config.Threshold != BinaryThresholdSign {
+		sums := make([]float64, dimension)
+		for _, vec := range projected {
+			for d := range sums {
+				sums[d] += float64(vec[d])
+			}
+		}
+		for d := range means {
+			means[d] = float32(sums[d] / float64(len(projected)))
+		}
+	}
+
+	// Each bit decodes to threshold ± scale, where scale is the mean absolute
+	// deviation from the threshold along that dimension.
+	deviations := make([]float64, dimension)
+	for _, vec := range projected {
+		for d := range deviations {
+			deviations[d] += math.Abs(float64(vec[d] - means[d]))
+		}
+	}
+	scales := make([]float32, dimension)
+	var sumSquares float64
+	for d := range scales {
+		scales[d] = float32(deviations[d] / float64(len(projected)))
+		sumSquares += float64(scales[d]) * float64(scales[d])
+	}
+
+	bq.dimension = dimension
+	bq.rotation = rotation
+	bq.means = means
+	bq.scales = scales
+	bq.unitScale = float32(math.Sqrt(sumSquares / float64(dimension)))
+	bq.trained = true
+	bq.memoryUsage = bq.stateMemoryLocked()
+	return nil
+}
+
+func (bq *BinaryQuantizer) Compress(vector []float32) ([]byte, error) {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+
+	if !bq.trained {
+		return nil, NewQuantizationError(ErrQuantNotTrained, "BinaryQuantizer", "", "quantizer not trained")
+	}
+	if len(vector) != bq.dimension {
+		return nil, fmt.Errorf("vector dimension %d does not match expected %d", len(vector), bq.dimension)
+	}
+	return bq.encodeLocked(vector), nil
+}
+
+func (bq *BinaryQuantizer) Decompress(data []byte) ([]float32, error) {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+
+	if !bq.trained {
+		return nil, NewQuantizationError(ErrQuantNotTrained, "BinaryQuantizer", "", "quantizer not trained")
+	}
+	if len(data) != bq.codeSizeLocked() {
+		return nil, fmt.Errorf("binary code length %d does not match expected %d", len(data), bq.codeSizeLocked())
+	}
+
+	width := bq.dimension
+	if bq.rotation != nil {
+		width = bq.rotation.width
+	}
+	projected := make([]float32, width)
+	for d := 0; d < bq.dimension; d++ {
+		if data[d>>3]&(1<<(d&7)) != 0 {
+			projected[d] = bq.means[d] + bq.scales[d]
+		} else {
+			projected[d] = bq.means[d] - bq.scales[d]
+		}
+	}
+	if bq.rotation != nil {
+		return bq.rotation.invert(projected)[:bq.dimension], nil
+	}
+	return projected, nil
+}
+
+func (bq *BinaryQuantizer) Distance(compressed1, compressed2 []byte) (float32, error) {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+
+	if !bq.trained {
+		return 0, NewQuantizationError(ErrQuantNotTrained, "BinaryQuantizer", "", "quantizer not trained")
+	}
+	codeSize := bq.codeSizeLocked()
+	if len(compressed1) != codeSize || len(compressed2) != codeSize {
+		return 0, fmt.Errorf("binary code lengths %d and %d do not match expected %d", len(compressed1), len(compressed2), codeSize)
+	}
+	return bq.hammingDistanceLocked(compressed1, compressed2), nil
+}
+
+// PrepareQuery encodes the query once; DistanceToQuery then compares codes
+// symmetrically with popcount.
+func (bq *BinaryQuantizer) PrepareQuery(query []float32) any {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+
+	if !bq.trained || len(query) != bq.dimension {
+		return nil
+	}
+	return &binaryQueryState{code: bq.encodeLocked(query)}
+}
+
+func (bq *BinaryQuantizer) DistanceToQuery(compressed []byte, query []float32, state any) (float32, error) {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+
+	if !bq.trained {
+		return 0, NewQuantizationError(ErrQuantNotTrained, "BinaryQuantizer", "", "quantizer not trained")
+	}
+	codeSize := bq.codeSizeLocked()
+	if len(compressed) != codeSize {
+		return 0, fmt.Errorf("binary code length %d does not match expected %d", len(compressed), codeSize)
+	}
+
+	var queryCode []byte
+	if prepared, ok := state.(*binaryQueryState); ok && len(prepared.code) == codeSize {
+		queryCode = prepared.code
+	} else {
+		if len(query) != bq.dimension {
+			return 0, fmt.Errorf("query dimension %d does not match expected %d", len(query), bq.dimension)
+		}
+		queryCode = bq.encodeLocked(query)
+	}
+	return bq.hammingDistanceLocked(compressed, queryCode), nil
+}
+
+// CodeSize returns the byte length of a single compressed vector for
+// binary quantization: ceil(dimension / 8). Returns 0 if the quantizer has
+// not been trained yet.
+func (bq *BinaryQuantizer) CodeSize() int {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+	if !bq.trained {
+		return 0
+	}
+	return bq.codeSizeLocked()
+}
+
+func (bq *BinaryQuantizer) Dimension() int {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+	if !bq.trained {
+		return 0
+	}
+	return bq.dimension
+}
+
+func (bq *BinaryQuantizer) SerializeState() ([]byte, error) {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+	if !bq.trained {
+		return nil, fmt.Errorf("BinaryQuantizer not trained")
+	}
+	var flags uint32
+	if bq.config.Threshold == BinaryThresholdSign {
+		flags |= binaryFlagSignThreshold
+	}
+	if bq.rotation != nil {
+		flags |= binaryFlagRotation
+	}
+	w := &bqStateWriter{buf: make([]byte, 0, binaryStateHeaderSize+bq.dimension*8)}
+	w.u32(uint32(bq.dimension))
+	w.u32(flags)
+	w.f64(bq.config.TrainRatio)
+	w.u64(uint64(bq.config.Seed))
+	w.f32(bq.unitScale)
+	for _, v := range bq.means {
+		w.f32(v)
+	}
+	for _, v := range bq.scales {
+		w.f32(v)
+	}
+	return w.buf, nil
+}
+
+// binaryStateHeaderSize is dimension + flags + trainRatio + seed + unitScale.
+const binaryStateHeaderSize = 4 + 4 + 8 + 8 + 4
+
+func (bq *BinaryQuantizer) DeserializeState(data []byte) error {
+	bq.mu.Lock()
+	defer bq.mu.Unlock()
+	if len(data) < binaryStateHeaderSize {
+		return fmt.Errorf("BQ DeserializeState: too short (%d < %d)", len(data), binaryStateHeaderSize)
+	}
+	r := &bqStateReader{buf: data}
+	dim, err := r.u32()
+	if err != nil {
+		return fmt.Errorf("BQ dim: %w", err)
+	}
+	flags, err := r.u32()
+	if err != nil {
+		return fmt.Errorf("BQ flags: %w", err)
+	}
+	tr, err := r.f64()
+	if err != nil {
+		return fmt.Errorf("BQ trainRatio: %w", err)
+	}
+	seed, err := r.u64()
+	if err != nil {
+		return fmt.Errorf("BQ seed: %w", err)
+	}
+	unitScale, err := r.f32()
+	if err != nil {
+		return fmt.Errorf("BQ unitScale: %w", err)
+	}
+	if dim < 1 || dim > 65536 {
+		return fmt.Errorf("BQ dim %d invalid", dim)
+	}
+	if flags&^(binaryFlagSignThreshold|binaryFlagRotation) != 0 {
+		return fmt.Errorf("BQ flags %#x invalid", flags)
+	}
+	if math.IsNaN(tr) || math.IsInf(tr, 0) || tr <= 0 || tr > 1 {
+		return fmt.Errorf("BQ trainRatio %f invalid", tr)
+	}
+	if math.IsNaN(float64(unitScale)) || math.IsInf(float64(unitScale), 0) || unitScale < 0 {
+		return fmt.Errorf("BQ unitScale %f invalid", unitScale)
+	}
+	expected := binaryStateHeaderSize + int(dim)*8
+	if len(data) != expected {
+		return fmt.Errorf("BQ DeserializeState: len=%d expected=%d", len(data), expected)
+	}
+	means := make([]float32, dim)
+	scales := make([]float32, dim)
+	for i := range means {
+		if means[i], err = r.f32(); err != nil {
+			return fmt.Errorf("BQ mean[%d]: %w", i, err)
+		}
+	}
+	for i := range scales {
+		if scales[i], err = r.f32(); err != nil {
+			return fmt.Errorf("BQ scale[%d]: %w", i, err)
+		}
+	}
+
+	config := &QuantizationConfig{
+		Type:       BinaryQuantization,
+		Bits:       1,
+		TrainRatio: tr,
+		Threshold:  BinaryThresholdMean,
+		Rotation:   flags&binaryFlagRotation != 0,
+		Seed:       int64(seed),
+	}
+	if flags&binaryFlagSignThreshold != 0 {
+		config.Threshold = BinaryThresholdSign
+	}
+	bq.config = config
+	bq.dimension = int(dim)
+	bq.rotation = nil
+	if config.Rotation {
+		bq.rotation = newBinaryRotation(int(dim), config.Seed)
+	}
+	bq.means = means
+	bq.scales = scales
+	bq.unitScale = unitScale
+	bq.trained = true
+	bq.memoryUsage = bq.stateMemoryLocked()
+	return nil
+}
+
+func (bq *BinaryQuantizer) CompressionRatio() float32 {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+	if !bq.trained {
+		return 0
+	}
+	return float32(bq.dimension*32) / float32(bq.codeSizeLocked()*8)
+}
+
+func (bq *BinaryQuantizer) MemoryUsage() int64 {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+	return bq.memoryUsage
+}
+
+func (bq *BinaryQuantizer) IsTrained() bool {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+	return bq.trained
+}
+
+func (bq *BinaryQuantizer) Config() *QuantizationConfig {
+	bq.mu.RLock()
+	defer bq.mu.RUnlock()
+	if bq.config == nil {
+		return nil
+	}
+	configCopy := *bq.config
+	return &configCopy
+}
+
+func (bq *BinaryQuantizer) Close() error {
+	return nil
+}
+
+func (bq *BinaryQuantizer) codeSizeLocked() int {
+	return (bq.dimension + 7) / 8
+}
+
+// encodeLocked packs one bit per dimension, least significant bit first.
+// Padding bits in the last byte stay zero so they never contribute to a
+// Hamming distance.
+func (bq *BinaryQuantizer) encodeLocked(vector []float32) []byte {
+	projected := vector
+	if bq.rotation != nil {
+		projected = bq.rotation.apply(vector)
+	}
+	code := make([]byte, bq.codeSizeLocked())
+	for d := 0; d < bq.dimension; d++ {
+		if projected[d] > bq.means[d] {
+			code[d>>3] |= 1 << (d & 7)
+		}
+	}
+	return code
+}
+
+// hammingDistanceLocked converts a Hamming distance into an L2 estimate.
+// Every differing bit moves the reconstruction by twice the scale of its
+// dimension, so with the RMS scale s the squared distance is about 4·s²·h.
+// Like the other quantizers this returns the unsquared distance.
+func (bq *BinaryQuantizer) hammingDistanceLocked(a, b []byte) float32 {
+	hamming := simd.HammingDistance(a, b)
+	return 2 * bq.unitScale * float32(math.Sqrt(float64(hamming)))
+}
+
+func (bq *BinaryQuantizer) stateMemoryLocked() int64 {
+	usage := int64(len(bq.means)+len(bq.scales)) * 4
+	if bq.rotation != nil {
+		usage += int64(binaryRotationRounds * bq.rotation.width * 4)
+	}
+	return usage
+}
+
+// binaryRotation is a seeded randomized Hadamard transform. Each round flips
+// coordinate signs at random and applies a normalized fast Walsh-Hadamard
+// transform to the zero-padded vector. The result is orthogonal and costs
+// O(P log P) for padded width P instead of the O(D²) of a dense rotation
+// matrix. Only the first D rotated coordinates are binarized, so codes stay
+// at one bit per input dimension; each of those coordinates is still a
+// projection onto a random unit direction.
+type binaryRotation struct {
+	signs [binaryRotationRounds][]float32
+	width int
+	norm  float32
+}
+
+func newBinaryRotation(dimension int, seed int64) *binaryRotation {
+	width := 1
+	for width < dimension {
+		width <<= 1
+	}
+	rng := rand.New(rand.NewSource(seed))
+	r := &binaryRotation{
+		width: width,
+		norm:  float32(1 / math.Sqrt(float64(width))),
+	}
+	for round := range r.signs {
+		signs := make([]float32, width)
+		for i := range signs {
+			signs[i] = 1
+			if rng.Int63()&1 == 1 {
+				signs[i] = -1
+			}
+		}
+		r.signs[round] = signs
+	}
+	return r
+}
+
+// apply returns the rotated, zero-padded copy of vector.
+func (r *binaryRotation) apply(vector []float32) []float32 {
+	out := make([]float32, r.width)
+	copy(out, vector)
+	for _, signs := range r.signs {
+		for i := range out {
+			out[i] *= signs[i]
+		}
+		fastWalshHadamard(out)
+		for i := range out {
+			out[i] *= r.norm
+		}
+	}
+	return out
+}
+
+// invert undoes apply in place. The normalized Hadamard matrix is its own
+// inverse, so the rounds simply run backwards.
+func (r *binaryRotation) invert(rotated []float32) []float32 {
+	for round := len(r.signs) - 1; round >= 0; round-- {
+		fastWalshHadamard(rotated)
+		signs := r.signs[round]
+		for i := range rotated {
+			rotated[i] *= r.norm * signs[i]
+		}
+	}
+	return rotated
+}
+
+// fastWalshHadamard applies the unnormalized Walsh-Hadamard transform in
+// place. len(values) must be a power of two.
+func fastWalshHadamard(values []float32) {
+	for h := 1; h < len(values); h <<= 1 {
+		for i := 0; i < len(values); i += h << 1 {
+			for j := i; j < i+h; j++ {
+				x, y := values[j], values[j+h]
+				values[j], values[j+h] = x+y, x-y
+			}
+		}
+	}
+}
+
+type bqStateWriter struct{ buf []byte }
+
+func (w *bqStateWriter) u32(v uint32) {
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, v)
+}
+func (w *bqStateWriter) u64(v uint64) {
+	w.buf = binary.LittleEndian.AppendUint64(w.buf, v)
+}
+func (w *bqStateWriter) f32(v float32) {
+	w.u32(math.Float32bits(v))
+}
+func (w *bqStateWriter) f64(v float64) {
+	w.u64(math.Float64bits(v))
+}
+
+type bqStateReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *bqStateReader) need(n int) error {
+	if r.pos+n > len(r.buf) {
+		return fmt.Errorf("truncated at pos %d, need %d", r.pos, n)
+	}
+	return nil
+}
+func (r *bqStateReader) u32() (uint32, error) {
+	if err := r.need(4); err != nil {
+		return 0, err
+	}
+	v := binary.LittleEndian.Uint32(r.buf[r.pos:])
+	r.pos += 4
+	return v, nil
+}
+func (r *bqStateReader) u64() (uint64, error) {
+	if err := r.need(8); err != nil {
+		return 0, err
+	}
+	v := binary.LittleEndian.Uint64(r.buf[r.pos:])
+	r.pos += 8
+	return v, nil
+}
+func (r *bqStateReader) f32() (float32, error) {
+	v, err := r.u32()
+	return math.Float32frombits(v), err
+}
+func (r *bqStateReader) f64() (float64, error) {
+	v, err := r.u64()
+	return math.Float64frombits(v), err
+}
+
+type BinaryQuantizerFactory struct{}
+
+func NewBinaryQuantizerFactory() *BinaryQuantizerFactory {
+	return &BinaryQuantizerFactory{}
+}
+
+func (f *BinaryQuantizerFactory) Create(config *QuantizationConfig) (Quantizer, error) {
+	if config.Type != BinaryQuantization {
+		return nil, fmt.Errorf("unsupported quantization type: %s", config.Type.String())
+	}
+	bq := NewBinaryQuantizer()
+	if err := bq.Configure(config); err != nil {
+		return nil, err
+	}
+	return bq, nil
+}
+
+func (f *BinaryQuantizerFactory) Supports(qType QuantizationType) bool {
+	return qType == BinaryQuantization
+}
+
+func (f *BinaryQuantizerFactory) Name() string {
+	return "BinaryQuantizer"
+}
@@ -0,0 +1,234 @@
+package quant
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func binaryTestVectors(n, dim int, seed int64) [][]float32 {
+	rng := rand.New(rand.NewSource(seed))
+	vectors := make([][]float32, n)
+	for i := range vectors {
+		vec := make([]float32, dim)
+		for d := range vec {
+			vec[d] = float32(rng.NormFloat64()) + 0.5
+		}
+		vectors[i] = vec
+	}
+	return vectors
+}
+
+func trainedBinaryQuantizer(t *testing.T, config *QuantizationConfig, vectors [][]float32) *BinaryQuantizer {
+	t.Helper()
+	bq := NewBinaryQuantizer()
+	if err := bq.Configure(config); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+	if err := bq.Train(context.Background(), vectors); err != nil {
+		t.Fatalf("Train: %v", err)
+	}
+	return bq
+}
+
+func TestBinaryQuantizerCodeLayout(t *testing.T) {
+	vectors := binaryTestVectors(64, 70, 1)
+	bq := trainedBinaryQuantizer(t, DefaultConfig(BinaryQuantization), vectors)
+
+	if got := bq.CodeSize(); got != 9 {
+		t.Fatalf("CodeSize = %d, want 9", got)
+	}
+	code, err := bq.Compress(vectors[0])
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if len(code) != 9 {
+		t.Fatalf("code length %d, want 9", len(code))
+	}
+	if code[8]>>6 != 0 {
+		t.Fatalf("padding bits set in last byte: %08b", code[8])
+	}
+	for d := 0; d < 70; d++ {
+		want := vectors[0][d] > bq.means[d]
+		if got := code[d>>3]&(1<<(d&7)) != 0; got != want {
+			t.Fatalf("bit %d = %v, want %v", d, got, want)
+		}
+	}
+
+	square := trainedBinaryQuantizer(t, DefaultConfig(BinaryQuantization), binaryTestVectors(16, 1536, 2))
+	if got := square.CompressionRatio(); got != 32 {
+		t.Fatalf("CompressionRatio = %v, want 32", got)
+	}
+}
+
+func TestBinaryQuantizerSignThreshold(t *testing.T) {
+	vectors := binaryTestVectors(32, 16, 3)
+	config := DefaultConfig(BinaryQuantization)
+	config.Threshold = BinaryThresholdSign
+	bq := trainedBinaryQuantizer(t, config, vectors)
+
+	for d, mean := range bq.means {
+		if mean != 0 {
+			t.Fatalf("sign threshold trained mean[%d] = %v", d, mean)
+		}
+	}
+	code, err := bq.Compress([]float32{1, -1, 2, -2, 0.1, -0.1, 3, -3, 1, 1, 1, 1, -1, -1, -1, -1})
+	if err != nil {
+		t.Fatalf("Compress: %v", err)
+	}
+	if !bytes.Equal(code, []byte{0b01010101, 0b00001111}) {
+		t.Fatalf("code = %08b, want sign bits", code)
+	}
+}
+
+func TestBinaryQuantizerDistanceOrdersNeighbors(t *testing.T) {
+	for _, rotation := range []bool{false, true} {
+		vectors := binaryTestVectors(200, 128, 4)
+		config := DefaultConfig(BinaryQuantization)
+		config.Rotation = rotation
+		config.Seed = 9
+		bq := trainedBinaryQuantizer(t, config, vectors)
+
+		query := vectors[10]
+		rng := rand.New(rand.NewSource(5))
+		near := make([]float32, len(query))
+		for d := range near {
+			near[d] = query[d] + float32(rng.NormFloat64())*0.05
+		}
+		far := vectors[11]
+
+		state := bq.PrepareQuery(query)
+		if state == nil {
+			t.Fatal("PrepareQuery returned nil state")
+		}
+		nearCode, _ := bq.Compress(near)
+		farCode, _ := bq.Compress(far)
+		queryCode, _ := bq.Compress(query)
+
+		nearDist, err := bq.DistanceToQuery(nearCode, query, state)
+		if err != nil {
+			t.Fatalf("DistanceToQuery near: %v", err)
+		}
+		farDist, err := bq.DistanceToQuery(farCode, query, nil)
+		if err != nil {
+			t.Fatalf("DistanceToQuery far: %v", err)
+		}
+		if nearDist >= farDist {
+			t.Fatalf("rotation=%v: near %v should be closer than far %v", rotation, nearDist, farDist)
+		}
+		symmetric, err := bq.Distance(farCode, queryCode)
+		if err != nil {
+			t.Fatalf("Distance: %v", err)
+		}
+		if symmetric != farDist {
+			t.Fatalf("rotation=%v: Distance %v != DistanceToQuery %v", rotation, symmetric, farDist)
+		}
+		if self, _ := bq.Distance(queryCode, queryCode); self != 0 {
+			t.Fatalf("self distance = %v", self)
+		}
+
+		exact := float64(0)
+		for d := range query {
+			diff := float64(query[d] - far[d])
+			exact += diff * diff
+		}
+		exact = math.Sqrt(exact)
+		if ratio := float64(farDist) / exact; ratio < 0.5 || ratio > 2 {
+			t.Fatalf("rotation=%v: estimate %v far from exact distance %v", rotation, farDist, exact)
+		}
+	}
+}
+
+func TestBinaryRotationIsOrthogonal(t *testing.T) {
+	rotation := newBinaryRotation(100, 42)
+	if rotation.width != 128 {
+		t.Fatalf("width = %d, want 128", rotation.width)
+	}
+	vector := binaryTestVectors(1, 100, 6)[0]
+	rotated := rotation.apply(vector)
+
+	var before, after float64
+	for _, v := range vector {
+		before += float64(v) * float64(v)
+	}
+	for _, v := range rotated {
+		after += float64(v) * float64(v)
+	}
+	if math.Abs(before-after) > 1e-3*before {
+		t.Fatalf("rotation changed squared norm: %v -> %v", before, after)
+	}
+	restored := rotation.invert(rotated)
+	for d, v := range vector {
+		if math.Abs(float64(restored[d]-v)) > 1e-4 {
+			t.Fatalf("invert[%d] = %v, want %v", d, restored[d], v)
+		}
+	}
+}
+
+func TestBinaryQuantizerDecompressApproximatesInput(t *testing.T) {
+	vectors := binaryTestVectors(100, 64, 7)
+	bq := trainedBinaryQuantizer(t, DefaultConfig(BinaryQuantization), vectors)
+
+	code, _ := bq.Compress(vectors[3])
+	restored, err := bq.Decompress(code)
+	if err != nil {
+		t.Fatalf("Decompress: %v", err)
+	}
+	for d := range restored {
+		if (restored[d] > bq.means[d]) != (vectors[3][d] > bq.means[d]) {
+			t.Fatalf("decompressed[%d]=%v lands on the wrong side of mean %v", d, restored[d], bq.means[d])
+		}
+	}
+	if _, err := bq.Decompress(code[:len(code)-1]); err == nil {
+		t.Fatal("Decompress accepted a truncated code")
+	}
+}
+
+func TestBinaryQuantizerStateRoundTrip(t *testing.T) {
+	vectors := binaryTestVectors(50, 96, 8)
+	config := DefaultConfig(BinaryQuantization)
+	config.Rotation = true
+	config.Seed = 1234
+	bq := trainedBinaryQuantizer(t, config, vectors)
+
+	state, err := bq.SerializeState()
+	if err != nil {
+		t.Fatalf("SerializeState: %v", err)
+	}
+	restored := NewBinaryQuantizer()
+	if err := restored.DeserializeState(state); err != nil {
+		t.Fatalf("DeserializeState: %v", err)
+	}
+	if !restored.IsTrained() || restored.Dimension() != 96 {
+		t.Fatalf("restored trained=%v dim=%d", restored.IsTrained(), restored.Dimension())
+	}
+	if cfg := restored.Config(); !cfg.Rotation || cfg.Seed != 1234 || cfg.Threshold != BinaryThresholdMean {
+		t.Fatalf("restored config = %+v", cfg)
+	}
+	for _, vec := range vectors[:10] {
+		want, _ := bq.Compress(vec)
+		got, _ := restored.Compress(vec)
+		if !bytes.Equal(got, want) {
+			t.Fatalf("restored code %x, want %x", got, want)
+		}
+	}
+
+	if err := restored.DeserializeState(state[:len(state)-1]); err == nil {
+		t.Fatal("DeserializeState accepted a truncated payload")
+	}
+}
+
+func TestBinaryQuantizerRegistered(t *testing.T) {
+	quantizer, err := Create(DefaultConfig(BinaryQuantization))
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, ok := quantizer.(*BinaryQuantizer); !ok {
+		t.Fatalf("Create returned %T, want *BinaryQuantizer", quantizer)
+	}
+	if _, err := quantizer.Compress([]float32{1}); err == nil {
+		t.Fatal("untrained quantizer compressed a vector")
+	}
+}
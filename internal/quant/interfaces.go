@@ -15,6 +15,17 @@ const (
 	ScalarQuantization
 	// FiniteScalarQuantization uses codebook-free bounded scalar quantization
 	FiniteScalarQuantization
+	// BinaryQuantization keeps one bit per dimension and compares codes by
+	// Hamming distance
+	BinaryQuantization
+)
+
+// Binary quantization thresholds. The mean threshold compares each
+// coordinate against its per-dimension training mean; the sign threshold
+// compares against zero.
+const (
+	BinaryThresholdMean = "mean"
+	BinaryThresholdSign = "sign"
 )
 
 // String returns the string representation of the quantization type
@@ -26,6 +37,8 @@ func (qt QuantizationType) String() string {
 		return "scalar"
 	case FiniteScalarQuantization:
 		return "fsq"
+	case BinaryQuantization:
+		return "binary"
 	default:
 		return "unknown"
 	}
@@ -51,6 +64,23 @@ type QuantizationConfig struct {
 	// Levels specifies the per-coordinate FSQ level cycle. If omitted for FSQ,
 	// a uniform level count derived from Bits is used.
 	Levels []int `json:"levels,omitempty"`
+
+	// Threshold selects how binary quantization turns a coordinate into a
+	// bit: BinaryThresholdMean (the default when empty) or
+	// BinaryThresholdSign.
+	Threshold string `json:"threshold,omitempty"`
+
+	// Rotation applies a seeded random orthogonal rotation before binary
+	// quantization so variance is spread evenly across bits.
+	Rotation bool `json:"rotation,omitempty"`
+
+	// Seed drives the binary quantization rotation.
+	Seed int64 `json:"seed,omitempty"`
+
+	// SharedRange trains scalar quantization with one value range across
+	// every dimension, so all dimensions share a single step and code
+	// differences map directly onto Euclidean distance.
+	SharedRange bool `json:"shared_range,omitempty"`
 }
 
 // Validate checks if the quantization configuration is valid
@@ -79,6 +109,15 @@ func (qc *QuantizationConfig) Validate() error {
 				return fmt.Errorf("fsq levels must be >= 2, got %d", level)
 			}
 		}
+	case BinaryQuantization:
+		if qc.Bits != 1 {
+			return fmt.Errorf("binary quantization uses 1 bit per dimension, got %d", qc.Bits)
+		}
+		switch qc.Threshold {
+		case "", BinaryThresholdMean, BinaryThresholdSign:
+		default:
+			return fmt.Errorf("binary threshold must be %q or %q, got %q", BinaryThresholdMean, BinaryThresholdSign, qc.Threshold)
+		}
 	default:
 		return fmt.Errorf("unsupported quantization type: %v", qc.Type)
 	}
@@ -109,6 +148,13 @@ func DefaultConfig(qType QuantizationType) *QuantizationConfig {
 			Bits:       8,
 			TrainRatio: 0.1,
 		}
+	case BinaryQuantization:
+		return &QuantizationConfig{
+			Type:       BinaryQuantization,
+			Bits:       1,
+			TrainRatio: 1,
+			Threshold:  BinaryThresholdMean,
+		}
 	default:
 		return nil
 	}
@@ -12,6 +12,7 @@ func TestQuantizationType_String(t *testing.T) {
 		{expected: "product", qType: ProductQuantization},
 		{expected: "scalar", qType: ScalarQuantization},
 		{expected: "fsq", qType: FiniteScalarQuantization},
+		{expected: "binary", qType: BinaryQuantization},
 		{expected: "unknown", qType: QuantizationType(999)},
 	}
 
@@ -70,6 +71,36 @@ func TestQuantizationConfig_Validate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid binary config",
+			config: &QuantizationConfig{
+				Type:       BinaryQuantization,
+				Bits:       1,
+				TrainRatio: 1,
+				Threshold:  BinaryThresholdSign,
+				Rotation:   true,
+			},
+			wantErr: false,
+		},
+		{
+			name: "binary with more than one bit",
+			config: &QuantizationConfig{
+				Type:       BinaryQuantization,
+				Bits:       2,
+				TrainRatio: 1,
+			},
+			wantErr: true,
+		},
+		{
+			name: "binary with unknown threshold",
+			config: &QuantizationConfig{
+				Type:       BinaryQuantization,
+				Bits:       1,
+				TrainRatio: 1,
+				Threshold:  "median",
+			},
+			wantErr: true,
+		},
 		{
 			name: "invalid bits - too low",
 			config: &QuantizationConfig{
@@ -183,6 +214,16 @@ func TestDefaultConfig(t *testing.T) {
 				TrainRatio: 0.1,
 			},
 		},
+		{
+			name:  "binary quantization default",
+			qType: BinaryQuantization,
+			want: &QuantizationConfig{
+				Type:       BinaryQuantization,
+				Bits:       1,
+				TrainRatio: 1,
+				Threshold:  BinaryThresholdMean,
+			},
+		},
 		{
 			name:  "unsupported type",
 			qType: QuantizationType(999),
@@ -209,7 +250,8 @@ func TestDefaultConfig(t *testing.T) {
 				got.Codebooks != tt.want.Codebooks ||
 				got.Bits != tt.want.Bits ||
 				got.TrainRatio != tt.want.TrainRatio ||
-				got.CacheSize != tt.want.CacheSize {
+				got.CacheSize != tt.want.CacheSize ||
+				got.Threshold != tt.want.Threshold {
 				t.Errorf("DefaultConfig() = %v, want %v", got, tt.want)
 			}
 		})
@@ -149,4 +149,9 @@ func init() {
 	if err := Register(FiniteScalarQuantization, fsqFactory); err != nil {
 		panic(fmt.Sprintf("Failed to register FSQQuantizer factory: %v", err))
 	}
+
+	bqFactory := NewBinaryQuantizerFactory()
+	if err := Register(BinaryQuantization, bqFactory); err != nil {
+		panic(fmt.Sprintf("Failed to register BinaryQuantizer factory: %v", err))
+	}
 }
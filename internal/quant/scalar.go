@@ -114,6 +114,20 @@ func (sq *ScalarQuantizer) Train(ctx context.Context, vectors [][]float32) error
 		}
 	}
 
+	// A shared range widens every dimension to the global min/max so the
+	// quantizer has one uniform step.
+	if sq.config.SharedRange {
+		lo, hi := sq.minValues[0], sq.maxValues[0]
+		for d := 1; d < sq.dimension; d++ {
+			lo = min(lo, sq.minValues[d])
+			hi = max(hi, sq.maxValues[d])
+		}
+		for d := 0; d < sq.dimension; d++ {
+			sq.minValues[d] = lo
+			sq.maxValues[d] = hi
+		}
+	}
+
 	// Compute scales and offsets for each dimension
 	for d := 0; d < sq.dimension; d++ {
 		range_ := sq.maxValues[d] - sq.minValues[d]
@@ -265,6 +279,25 @@ func (sq *ScalarQuantizer) CodeSize() int {
 	return (sq.dimension*sq.config.Bits + 7) / 8
 }
 
+// UniformRange returns the offset and step shared by every dimension and
+// true when the trained ranges are identical, as they are after training
+// with SharedRange. A code c then reconstructs to offset + step*c in every
+// dimension, so ||x̂-ŷ|| = step * ||code(x)-code(y)||.
+func (sq *ScalarQuantizer) UniformRange() (offset, step float32, ok bool) {
+	sq.mu.RLock()
+	defer sq.mu.RUnlock()
+	if !sq.trained || len(sq.scales) == 0 {
+		return 0, 0, false
+	}
+	offset, step = sq.offsets[0], sq.scales[0]
+	for d := 1; d < len(sq.scales); d++ {
+		if sq.scales[d] != step || sq.offsets[d] != offset {
+			return 0, 0, false
+		}
+	}
+	return offset, step, true
+}
+
 func (sq *ScalarQuantizer) Dimension() int {
 	sq.mu.RLock()
 	defer sq.mu.RUnlock()
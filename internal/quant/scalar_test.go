@@ -149,6 +149,72 @@ func TestScalarQuantizer_Train(t *testing.T) {
 	}
 }
 
+func TestScalarQuantizer_SharedRange(t *testing.T) {
+	vectors := [][]float32{
+		{0.0, 10.0, -2.0},
+		{1.0, 20.0, 3.0},
+		{0.5, 15.0, 1.0},
+	}
+	perDim := NewScalarQuantizer()
+	if err := perDim.Configure(&QuantizationConfig{Type: ScalarQuantization, Bits: 8, TrainRatio: 1.0}); err != nil {
+		t.Fatalf("failed to configure: %v", err)
+	}
+	if err := perDim.Train(context.Background(), vectors); err != nil {
+		t.Fatalf("failed to train: %v", err)
+	}
+	if _, _, ok := perDim.UniformRange(); ok {
+		t.Fatal("per-dimension ranges reported a uniform step")
+	}
+
+	shared := NewScalarQuantizer()
+	if err := shared.Configure(&QuantizationConfig{Type: ScalarQuantization, Bits: 8, TrainRatio: 1.0, SharedRange: true}); err != nil {
+		t.Fatalf("failed to configure: %v", err)
+	}
+	if err := shared.Train(context.Background(), vectors); err != nil {
+		t.Fatalf("failed to train: %v", err)
+	}
+	offset, step, ok := shared.UniformRange()
+	if !ok {
+		t.Fatal("shared range did not produce a uniform step")
+	}
+	if offset != -2 {
+		t.Fatalf("offset = %v, want -2", offset)
+	}
+	if want := float32(22.0 / 255.0); math.Abs(float64(step-want)) > 1e-6 {
+		t.Fatalf("step = %v, want %v", step, want)
+	}
+
+	a, _ := shared.Compress(vectors[0])
+	b, _ := shared.Compress(vectors[1])
+	var codeSq float64
+	for d := range a {
+		diff := float64(a[d]) - float64(b[d])
+		codeSq += diff * diff
+	}
+	ra, _ := shared.Decompress(a)
+	rb, _ := shared.Decompress(b)
+	var exactSq float64
+	for d := range ra {
+		diff := float64(ra[d]) - float64(rb[d])
+		exactSq += diff * diff
+	}
+	if got, want := float64(step)*math.Sqrt(codeSq), math.Sqrt(exactSq); math.Abs(got-want) > 1e-4 {
+		t.Fatalf("code distance %v does not match reconstructed distance %v", got, want)
+	}
+
+	state, err := shared.SerializeState()
+	if err != nil {
+		t.Fatalf("SerializeState: %v", err)
+	}
+	restored := NewScalarQuantizer()
+	if err := restored.DeserializeState(state); err != nil {
+		t.Fatalf("DeserializeState: %v", err)
+	}
+	if gotOffset, gotStep, ok := restored.UniformRange(); !ok || gotOffset != offset || gotStep != step {
+		t.Fatalf("restored range = %v, %v, %v; want %v, %v", gotOffset, gotStep, ok, offset, step)
+	}
+}
+
 func TestScalarQuantizer_CompressDecompress(t *testing.T) {
 	// Create test vectors with known ranges
 	vectors := [][]float32{
@@ -0,0 +1,345 @@
+package sparse
+
+import (
+	"container/heap"
+	"sort"
+	"sync"
+)
+
+// Hit is one top-k inner-product result.
+type Hit struct {
+	ID    string
+	Score float32
+}
+
+// Index is an inverted-list index over sparse vectors, safe for concurrent
+// use. Each dimension owns a posting list of (document, value) pairs ordered
+// by document, plus value extremes that bound the list's contribution to any
+// inner product. Documents are numbered in insertion order, so a Put only
+// appends to the lists it touches. A Delete leaves a tombstone that searches
+// skip; once tombstones outnumber live documents the lists are compacted and
+// their bounds tightened again. Score ties resolve to the lexicographically
+// smaller ID.
+type Index struct {
+	mu    sync.RWMutex
+	ids   []string // document number -> ID
+	gone  []bool   // document number -> tombstoned
+	docs  map[string]uint32
+	dead  int
+	lists map[uint32]*postingList
+}
+
+type postingList struct {
+	docs   []uint32
+	values []float32
+	max    float32
+	min    float32
+}
+
+// minCompaction keeps small indexes from compacting on every delete.
+const minCompaction = 1024
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{docs: make(map[string]uint32), lists: make(map[uint32]*postingList)}
+}
+
+// Builder accumulates vectors for an Index.
+type Builder struct {
+	entries []builderEntry
+}
+
+type builderEntry struct {
+	id     string
+	vector Vector
+}
+
+// NewBuilder returns an empty index builder.
+func NewBuilder() *Builder { return &Builder{} }
+
+// Add queues one document. Empty vectors are ignored because they cannot
+// match any query.
+func (b *Builder) Add(id string, v Vector) {
+	if v.NNZ() == 0 {
+		return
+	}
+	b.entries = append(b.entries, builderEntry{id: id, vector: v})
+}
+
+// Build indexes the queued documents. A later Add of the same ID replaces
+// an earlier one.
+func (b *Builder) Build() *Index {
+	idx := NewIndex()
+	for _, entry := range b.entries {
+		idx.putLocked(entry.id, entry.vector)
+	}
+	b.entries = nil
+	return idx
+}
+
+// Put indexes v under id, replacing any previous vector. An empty vector
+// removes id, because it cannot match any query.
+func (x *Index) Put(id string, v Vector) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.putLocked(id, v)
+}
+
+// Delete removes id from the index.
+func (x *Index) Delete(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.deleteLocked(id)
+}
+
+func (x *Index) putLocked(id string, v Vector) {
+	x.deleteLocked(id)
+	if v.NNZ() == 0 {
+		return
+	}
+	doc := uint32(len(x.ids))
+	x.ids = append(x.ids, id)
+	x.gone = append(x.gone, false)
+	x.docs[id] = doc
+	for i, dim := range v.Indices {
+		value := v.Values[i]
+		list := x.lists[dim]
+		if list == nil {
+			list = &postingList{max: value, min: value}
+			x.lists[dim] = list
+		}
+		list.docs = append(list.docs, doc)
+		list.values = append(list.values, value)
+		list.max = max(list.max, value)
+		list.min = min(list.min, value)
+	}
+}
+
+func (x *Index) deleteLocked(id string) {
+	doc, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	delete(x.docs, id)
+	x.ids[doc] = ""
+	x.gone[doc] = true
+	x.dead++
+	if x.dead >= minCompaction && x.dead > len(x.docs) {
+		x.compactLocked()
+	}
+}
+
+// compactLocked drops tombstoned postings and renumbers the live documents
+// in their existing order, which keeps every list sorted.
+func (x *Index) compactLocked() {
+	renumber := make([]uint32, len(x.ids))
+	ids := make([]string, 0, len(x.docs))
+	for doc, id := range x.ids {
+		if x.gone[doc] {
+			continue
+		}
+		renumber[doc] = uint32(len(ids))
+		x.docs[id] = uint32(len(ids))
+		ids = append(ids, id)
+	}
+	for dim, list := range x.lists {
+		kept := 0
+		for i, doc := range list.docs {
+			if x.gone[doc] {
+				continue
+			}
+			value := list.values[i]
+			if kept == 0 {
+				list.max, list.min = value, value
+			}
+			list.docs[kept] = renumber[doc]
+			list.values[kept] = value
+			list.max = max(list.max, value)
+			list.min = min(list.min, value)
+			kept++
+		}
+		if kept == 0 {
+			delete(x.lists, dim)
+			continue
+		}
+		list.docs = list.docs[:kept:kept]
+		list.values = list.values[:kept:kept]
+	}
+	x.ids = ids
+	x.gone = make([]bool, len(ids))
+	x.dead = 0
+}
+
+// Len returns the number of indexed documents.
+func (x *Index) Len() int {
+	if x == nil {
+		return 0
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Search returns the k documents with the largest inner product against
+// query, best first. Only documents sharing at least one non-zero dimension
+// with the query are candidates.
+func (x *Index) Search(query Vector, k int) []Hit {
+	hits, _ := x.search(query, k)
+	return hits
+}
+
+type termCursor struct {
+	list   *postingList
+	pos    int
+	weight float32
+	bound  float32
+}
+
+func (c *termCursor) doc() (uint32, bool) {
+	if c.pos >= len(c.list.docs) {
+		return 0, false
+	}
+	return c.list.docs[c.pos], true
+}
+
+// seek advances the cursor to the first posting at or after doc.
+func (c *termCursor) seek(doc uint32) {
+	docs := c.list.docs[c.pos:]
+	c.pos += sort.Search(len(docs), func(i int) bool { return docs[i] >= doc })
+}
+
+// search is a document-at-a-time MaxScore traversal. Query terms are ordered
+// by their upper-bound contribution; once the running top-k threshold exceeds
+// the summed bounds of the weakest terms, those terms become non-essential:
+// they are never used to generate candidates and are only probed while the
+// candidate can still reach the threshold. Bounds are taken over signed
+// values, so negative weights and values prune correctly. The second return
+// value counts fully or partially scored documents.
+func (x *Index) search(query Vector, k int) ([]Hit, int) {
+	if x == nil || k <= 0 || query.NNZ() == 0 {
+		return nil, 0
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	cursors := make([]*termCursor, 0, query.NNZ())
+	for i, dim := range query.Indices {
+		list := x.lists[dim]
+		if list == nil {
+			continue
+		}
+		weight := query.Values[i]
+		bound := max(0, weight*list.max, weight*list.min)
+		cursors = append(cursors, &termCursor{list: list, weight: weight, bound: bound})
+	}
+	if len(cursors) == 0 {
+		return nil, 0
+	}
+	sort.SliceStable(cursors, func(i, j int) bool { return cursors[i].bound < cursors[j].bound })
+	prefix := make([]float32, len(cursors))
+	var running float32
+	for i, cursor := range cursors {
+		running += cursor.bound
+		prefix[i] = running
+	}
+
+	top := &hitHeap{}
+	essential := 0
+	scored := 0
+	for {
+		next, found := uint32(0), false
+		for _, cursor := range cursors[essential:] {
+			if doc, ok := cursor.doc(); ok && (!found || doc < next) {
+				next, found = doc, true
+			}
+		}
+		if !found {
+			break
+		}
+		if x.gone[next] {
+			for _, cursor := range cursors[essential:] {
+				if doc, ok := cursor.doc(); ok && doc == next {
+					cursor.pos++
+				}
+			}
+			continue
+		}
+		scored++
+
+		var score float32
+		for _, cursor := range cursors[essential:] {
+			if doc, ok := cursor.doc(); ok && doc == next {
+				score += cursor.weight * cursor.list.values[cursor.pos]
+				cursor.pos++
+			}
+		}
+		full := top.Len() >= k
+		for j := essential - 1; j >= 0; j-- {
+			// A candidate that can at best tie the threshold may still win
+			// on ID, so only strictly weaker candidates stop probing.
+			if full && score+prefix[j] < (*top)[0].score {
+				break
+			}
+			cursor := cursors[j]
+			cursor.seek(next)
+			if doc, ok := cursor.doc(); ok && doc == next {
+				score += cursor.weight * cursor.list.values[cursor.pos]
+			}
+		}
+
+		candidate := scoredDoc{id: x.ids[next], score: score}
+		if !full {
+			heap.Push(top, candidate)
+		} else if (*top)[0].weaker(candidate) {
+			(*top)[0] = candidate
+			heap.Fix(top, 0)
+		} else {
+			continue
+		}
+		if top.Len() >= k {
+			// Terms whose summed bounds cannot even tie the threshold never
+			// generate a candidate that enters the heap.
+			threshold := (*top)[0].score
+			for essential < len(cursors) && prefix[essential] < threshold {
+				essential++
+			}
+		}
+	}
+
+	results := []scoredDoc(*top)
+	sort.Slice(results, func(i, j int) bool {
+		return results[j].weaker(results[i])
+	})
+	hits := make([]Hit, len(results))
+	for i, result := range results {
+		hits[i] = Hit{ID: result.id, Score: result.score}
+	}
+	return hits, scored
+}
+
+type scoredDoc struct {
+	id    string
+	score float32
+}
+
+// weaker reports whether d ranks below other: a lower score, or the larger
+// ID among equal scores.
+func (d scoredDoc) weaker(other scoredDoc) bool {
+	if d.score != other.score {
+		return d.score < other.score
+	}
+	return d.id > other.id
+}
+
+// hitHeap is a min-heap whose root is the weakest retained result.
+type hitHeap []scoredDoc
+
+func (h hitHeap) Len() int            { return len(h) }
+func (h hitHeap) Less(i, j int) bool  { return h[i].weaker(h[j]) }
+func (h hitHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hitHeap) Push(x interface{}) { *h = append(*h, x.(scoredDoc)) }
+func (h *hitHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
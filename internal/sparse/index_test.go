@@ -0,0 +1,196 @@
+package sparse
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomVector(rng *rand.Rand, dim, nnz int, allowNegative bool) Vector {
+	entries := make(map[uint32]float32, nnz)
+	for len(entries) < nnz {
+		value := rng.Float32() + 0.01
+		if allowNegative && rng.Intn(4) == 0 {
+			value = -value
+		}
+		entries[uint32(rng.Intn(dim))] = value
+	}
+	v, err := New(dim, entries)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func bruteForce(ids []string, vectors []Vector, query Vector, k int) []Hit {
+	var hits []Hit
+	for i, v := range vectors {
+		overlap := false
+		for _, dim := range v.Indices {
+			for _, q := range query.Indices {
+				if dim == q {
+					overlap = true
+				}
+			}
+		}
+		if overlap {
+			hits = append(hits, Hit{ID: ids[i], Score: Dot(v, query)})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+func TestIndexSearchMatchesBruteForce(t *testing.T) {
+	for _, negative := range []bool{false, true} {
+		t.Run(fmt.Sprintf("negative=%v", negative), func(t *testing.T) {
+			rng := rand.New(rand.NewSource(11))
+			const dim = 300
+			builder := NewBuilder()
+			ids := make([]string, 2000)
+			vectors := make([]Vector, len(ids))
+			for i := range ids {
+				ids[i] = fmt.Sprintf("doc%05d", i)
+				vectors[i] = randomVector(rng, dim, 1+rng.Intn(12), negative)
+				builder.Add(ids[i], vectors[i])
+			}
+			idx := builder.Build()
+			if idx.Len() != len(ids) {
+				t.Fatalf("Len = %d, want %d", idx.Len(), len(ids))
+			}
+			for q := 0; q < 25; q++ {
+				query := randomVector(rng, dim, 2+rng.Intn(6), negative)
+				got := idx.Search(query, 10)
+				want := bruteForce(ids, vectors, query, 10)
+				if len(got) != len(want) {
+					t.Fatalf("query %d: got %d hits, want %d", q, len(got), len(want))
+				}
+				for i := range want {
+					if got[i].ID != want[i].ID {
+						t.Fatalf("query %d rank %d: got %s (%v), want %s (%v)", q, i, got[i].ID, got[i].Score, want[i].ID, want[i].Score)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestIndexSearchPrunesNonEssentialTerms(t *testing.T) {
+	builder := NewBuilder()
+	// Dimension 0 is a rare, heavy term; dimension 1 is a common, light one.
+	for i := 0; i < 1000; i++ {
+		entries := map[uint32]float32{1: 0.01}
+		if i%100 == 0 {
+			entries[0] = 5
+		}
+		v, err := New(4, entries)
+		if err != nil {
+			t.Fatal(err)
+		}
+		builder.Add(fmt.Sprintf("doc%04d", i), v)
+	}
+	idx := builder.Build()
+	query, err := New(4, map[uint32]float32{0: 1, 1: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, scored := idx.search(query, 5)
+	if len(hits) != 5 || hits[0].ID != "doc0000" || hits[4].ID != "doc0400" {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+	if scored >= 1000 {
+		t.Fatalf("MaxScore scored %d documents; the light term was never pruned", scored)
+	}
+}
+
+func TestIndexSearchEdgeCases(t *testing.T) {
+	var empty *Index
+	if hits := empty.Search(Vector{Dim: 3}, 3); hits != nil {
+		t.Fatalf("nil index returned %v", hits)
+	}
+	builder := NewBuilder()
+	builder.Add("a", Vector{Dim: 3})
+	v, _ := New(3, map[uint32]float32{2: 1})
+	builder.Add("b", v)
+	idx := builder.Build()
+	if idx.Len() != 1 {
+		t.Fatalf("empty vectors should not be indexed, Len = %d", idx.Len())
+	}
+	miss, _ := New(3, map[uint32]float32{0: 1})
+	if hits := idx.Search(miss, 3); len(hits) != 0 {
+		t.Fatalf("query without overlap returned %v", hits)
+	}
+	if hits := idx.Search(v, 0); len(hits) != 0 {
+		t.Fatalf("k=0 returned %v", hits)
+	}
+}
+
+func TestIndexPutAndDeleteMatchBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(5))
+	const dim = 200
+	idx := NewIndex()
+	live := make(map[string]Vector)
+	for step := 0; step < 6000; step++ {
+		id := fmt.Sprintf("doc%04d", rng.Intn(1500))
+		if rng.Intn(3) == 0 {
+			idx.Delete(id)
+			delete(live, id)
+			continue
+		}
+		v := randomVector(rng, dim, 1+rng.Intn(8), true)
+		idx.Put(id, v)
+		live[id] = v
+	}
+	if idx.Len() != len(live) {
+		t.Fatalf("Len = %d, want %d", idx.Len(), len(live))
+	}
+	ids := make([]string, 0, len(live))
+	vectors := make([]Vector, 0, len(live))
+	for id, v := range live {
+		ids = append(ids, id)
+		vectors = append(vectors, v)
+	}
+	for q := 0; q < 25; q++ {
+		query := randomVector(rng, dim, 2+rng.Intn(6), true)
+		got := idx.Search(query, 10)
+		want := bruteForce(ids, vectors, query, 10)
+		if len(got) != len(want) {
+			t.Fatalf("query %d: got %d hits, want %d", q, len(got), len(want))
+		}
+		for i := range want {
+			if got[i].ID != want[i].ID {
+				t.Fatalf("query %d rank %d: got %s (%v), want %s (%v)", q, i, got[i].ID, got[i].Score, want[i].ID, want[i].Score)
+			}
+		}
+	}
+}
+
+func TestIndexCompactsTombstones(t *testing.T) {
+	idx := NewIndex()
+	v, _ := New(4, map[uint32]float32{1: 1})
+	for i := 0; i < 3*minCompaction; i++ {
+		idx.Put(fmt.Sprintf("doc%05d", i), v)
+	}
+	// The deletion that leaves more tombstones than live documents compacts.
+	deleted := 3*minCompaction/2 + 1
+	for i := 0; i < deleted; i++ {
+		idx.Delete(fmt.Sprintf("doc%05d", i))
+	}
+	live := 3*minCompaction - deleted
+	if idx.dead != 0 || len(idx.ids) != live || len(idx.lists[1].docs) != live {
+		t.Fatalf("tombstones were not compacted: dead=%d ids=%d postings=%d", idx.dead, len(idx.ids), len(idx.lists[1].docs))
+	}
+	hits := idx.Search(v, 1)
+	if len(hits) != 1 || hits[0].ID != fmt.Sprintf("doc%05d", deleted) {
+		t.Fatalf("after compaction got %v", hits)
+	}
+}
@@ -0,0 +1,159 @@
+// Package sparse implements sparse vectors and the inverted-list index used
+// for top-k inner-product search over them.
+package sparse
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Vector is a sparse vector of a fixed dimension. Indices are zero-based,
+// strictly increasing and always paired with a non-zero value; the zero
+// entries are implicit.
+type Vector struct {
+	Indices []uint32
+	Values  []float32
+	Dim     int
+}
+
+// New builds a canonical vector from an index->value map, dropping zeros.
+func New(dim int, entries map[uint32]float32) (Vector, error) {
+	v := Vector{Dim: dim}
+	v.Indices = make([]uint32, 0, len(entries))
+	for index, value := range entries {
+		if value != 0 {
+			v.Indices = append(v.Indices, index)
+		}
+	}
+	sort.Slice(v.Indices, func(i, j int) bool { return v.Indices[i] < v.Indices[j] })
+	v.Values = make([]float32, len(v.Indices))
+	for i, index := range v.Indices {
+		v.Values[i] = entries[index]
+	}
+	return v, v.Validate()
+}
+
+// Validate checks the structural invariants of v.
+func (v Vector) Validate() error {
+	if v.Dim <= 0 {
+		return fmt.Errorf("sparse vector dimension must be positive, got %d", v.Dim)
+	}
+	if len(v.Indices) != len(v.Values) {
+		return fmt.Errorf("sparse vector has %d indices but %d values", len(v.Indices), len(v.Values))
+	}
+	for i, index := range v.Indices {
+		if int64(index) >= int64(v.Dim) {
+			return fmt.Errorf("sparse vector index %d out of range for dimension %d", index+1, v.Dim)
+		}
+		if i > 0 && index <= v.Indices[i-1] {
+			return fmt.Errorf("sparse vector indices must be strictly increasing")
+		}
+		value := v.Values[i]
+		if math.IsNaN(float64(value)) || math.IsInf(float64(value), 0) {
+			return fmt.Errorf("sparse vector value at index %d is not finite", index+1)
+		}
+		if value == 0 {
+			return fmt.Errorf("sparse vector stores an explicit zero at index %d", index+1)
+		}
+	}
+	return nil
+}
+
+// NNZ returns the number of stored (non-zero) entries.
+func (v Vector) NNZ() int { return len(v.Indices) }
+
+// String formats v in the pgvector sparsevec text form, {1:0.5,7:2}/10,
+// with one-based indices. The output is canonical and round-trips through
+// Parse.
+func (v Vector) String() string {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, index := range v.Indices {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.FormatUint(uint64(index)+1, 10))
+		b.WriteByte(':')
+		b.WriteString(strconv.FormatFloat(float64(v.Values[i]), 'g', -1, 32))
+	}
+	b.WriteString("}/")
+	b.WriteString(strconv.Itoa(v.Dim))
+	return b.String()
+}
+
+// Parse decodes the pgvector sparsevec text form. Entries may appear in any
+// order; duplicates are rejected and explicit zeros are dropped.
+func Parse(text string) (Vector, error) {
+	text = strings.TrimSpace(text)
+	open := strings.IndexByte(text, '{')
+	end := strings.LastIndexByte(text, '}')
+	if open != 0 || end < 0 {
+		return Vector{}, fmt.Errorf("malformed sparse vector %q: expected {index:value,...}/dim", text)
+	}
+	rest := strings.TrimSpace(text[end+1:])
+	if !strings.HasPrefix(rest, "/") {
+		return Vector{}, fmt.Errorf("malformed sparse vector %q: missing /dim suffix", text)
+	}
+	dim, err := strconv.Atoi(strings.TrimSpace(rest[1:]))
+	if err != nil || dim <= 0 {
+		return Vector{}, fmt.Errorf("malformed sparse vector %q: invalid dimension", text)
+	}
+
+	entries := make(map[uint32]float32)
+	body := strings.TrimSpace(text[1:end])
+	if body != "" {
+		for _, pair := range strings.Split(body, ",") {
+			colon := strings.IndexByte(pair, ':')
+			if colon < 0 {
+				return Vector{}, fmt.Errorf("malformed sparse vector element %q", strings.TrimSpace(pair))
+			}
+			index, err := strconv.ParseUint(strings.TrimSpace(pair[:colon]), 10, 32)
+			if err != nil || index == 0 {
+				return Vector{}, fmt.Errorf("invalid sparse vector index %q", strings.TrimSpace(pair[:colon]))
+			}
+			value, err := strconv.ParseFloat(strings.TrimSpace(pair[colon+1:]), 32)
+			if err != nil {
+				return Vector{}, fmt.Errorf("invalid sparse vector value %q", strings.TrimSpace(pair[colon+1:]))
+			}
+			key := uint32(index - 1)
+			if _, duplicate := entries[key]; duplicate {
+				return Vector{}, fmt.Errorf("duplicate sparse vector index %d", index)
+			}
+			entries[key] = float32(value)
+		}
+	}
+	return New(dim, entries)
+}
+
+// Dot returns the inner product of two sparse vectors by merging their
+// sorted index lists. Dimensions are not compared; callers validate them
+// against the column declaration.
+func Dot(a, b Vector) float32 {
+	sum, _ := DotOverlap(a, b)
+	return sum
+}
+
+// DotOverlap is Dot that also reports whether the vectors share a non-zero
+// dimension, which distinguishes "no match" from a genuine zero product.
+func DotOverlap(a, b Vector) (float32, bool) {
+	var sum float32
+	shared := false
+	i, j := 0, 0
+	for i < len(a.Indices) && j < len(b.Indices) {
+		switch {
+		case a.Indices[i] < b.Indices[j]:
+			i++
+		case a.Indices[i] > b.Indices[j]:
+			j++
+		default:
+			sum += a.Values[i] * b.Values[j]
+			shared = true
+			i++
+			j++
+		}
+	}
+	return sum, shared
+}
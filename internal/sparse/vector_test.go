@@ -0,0 +1,53 @@
+package sparse
+
+import "testing"
+
+func TestParseRoundTrip(t *testing.T) {
+	v, err := Parse(" {5:3, 1:1.5,3:2, 4:0}/5 ")
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if v.Dim != 5 || v.NNZ() != 3 {
+		t.Fatalf("parsed %+v", v)
+	}
+	if want := []uint32{0, 2, 4}; v.Indices[0] != want[0] || v.Indices[1] != want[1] || v.Indices[2] != want[2] {
+		t.Fatalf("indices = %v, want %v", v.Indices, want)
+	}
+	if got := v.String(); got != "{1:1.5,3:2,5:3}/5" {
+		t.Fatalf("String = %q", got)
+	}
+	again, err := Parse(v.String())
+	if err != nil || again.String() != v.String() {
+		t.Fatalf("round trip = %v, %v", again, err)
+	}
+	if empty, err := Parse("{}/3"); err != nil || empty.NNZ() != 0 || empty.String() != "{}/3" {
+		t.Fatalf("empty vector = %v, %v", empty, err)
+	}
+}
+
+func TestParseRejectsMalformedInput(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"[1,2,3]",
+		"{1:1}",
+		"{1:1}/0",
+		"{0:1}/3",
+		"{4:1}/3",
+		"{1:1,1:2}/3",
+		"{1:x}/3",
+		"{1:NaN}/3",
+		"{1}/3",
+	} {
+		if _, err := Parse(text); err == nil {
+			t.Fatalf("Parse(%q) succeeded", text)
+		}
+	}
+}
+
+func TestDot(t *testing.T) {
+	a, _ := Parse("{1:1,2:2,4:4}/5")
+	b, _ := Parse("{2:3,3:5,4:-1}/5")
+	if got := Dot(a, b); got != 2 {
+		t.Fatalf("Dot = %v, want 2", got)
+	}
+}
@@ -53,7 +53,36 @@ type CollectionConfig struct {
 	// the SQL layer uses the reserved "default" namespace for graph tables that
 	// participate in the database-wide graph.
 	GraphNamespace string
-	DataLSN        uint64
+	// SparseVectors maps sparse-vector metadata fields to their declared
+	// dimension. Values live in record metadata; the inverted lists built
+	// over them are derived and rebuilt on load.
+	SparseVectors map[string]int
+	// FullTextIndexes contains durable BM25 full-text index declarations.
+	// Posting lists and term statistics are derived from records and rebuilt
+	// on load; committed writes keep them current afterwards.
+	FullTextIndexes []FullTextIndexDefinition
+	// VectorSpaces contains durable named vector space declarations. Their
+	// vectors live in record metadata; each space's HNSW graph is derived
+	// and rebuilt on load.
+	VectorSpaces []VectorSpaceDefinition
+	// GeneratedVector declares that the primary vector is derived from
+	// metadata text through a registered embedding model. Nil for ordinary
+	// collections.
+	GeneratedVector *GeneratedVectorDefinition
+	// TTLField names the metadata field holding each record's expiry time,
+	// and DefaultTTL is the lifetime stamped into it when an insert omits
+	// the field. Both are zero for collections without expiry.
+	TTLField   string
+	DefaultTTL time.Duration
+	// PruneAlpha is the DiskANN RobustPrune distance ratio and
+	// IndexDirectory the directory of its node file. Both are zero for
+	// other index types.
+	PruneAlpha     float64
+	IndexDirectory string
+	// Int8Construction enables int8 candidate screening for HNSW. The
+	// codes themselves are derived state and are rebuilt on load.
+	Int8Construction bool
+	DataLSN          uint64
 }
 
 // SQLIndexDefinition is the storage-neutral form of a named SQL index.
@@ -63,6 +92,36 @@ type SQLIndexDefinition struct {
 	Unique  bool
 }
 
+// FullTextIndexDefinition is the storage-neutral form of a BM25 full-text
+// index over one text field.
+type FullTextIndexDefinition struct {
+	Name   string
+	Field  string
+	Config string
+	K1     float64
+	B      float64
+}
+
+// VectorSpaceDefinition is the storage-neutral form of a named dense vector
+// space and the HNSW parameters of its graph.
+type VectorSpaceDefinition struct {
+	Name           string
+	Dimension      int
+	Metric         int
+	M              int
+	EfConstruction int
+	EfSearch       int
+}
+
+// GeneratedVectorDefinition is the storage-neutral form of a generated
+// vector column: the SQL column name, the text expression it is generated
+// from and the embedding model name.
+type GeneratedVectorDefinition struct {
+	Column     string
+	Expression string
+	Model      string
+}
+
 // EdgeKindStore is the optional database-level durable registry used by the
 // SQL CREATE EDGE TYPE surface. It is separate from Engine so alternate
 // storage implementations can opt in without breaking the core interface.
@@ -88,6 +147,24 @@ type EdgeKindDefinitionStore interface {
 	CreateEdgeKindDefinition(name string, kind uint8, undirected bool) error
 }
 
+// ViewDefinition is the durable declaration behind SQL CREATE VIEW and
+// CREATE MATERIALIZED VIEW. Query is the defining SELECT as written and is
+// resolved against the live catalog whenever the view is read or refreshed.
+// The rows of a materialized view live in the collection of the same name.
+type ViewDefinition struct {
+	Query        string
+	Materialized bool
+}
+
+// ViewStore is the optional database-level durable registry of SQL views.
+// Like EdgeKindStore it is separate from Engine so alternate storage
+// implementations can opt in without breaking the core interface.
+type ViewStore interface {
+	ListViews() (map[string]ViewDefinition, error)
+	PutView(name string, definition ViewDefinition) error
+	DropView(name string) error
+}
+
 // CostModelStatisticsStore is an optional persistence seam for optimizer
 // statistics.  Keeping this separate from Engine avoids forcing alternate
 // storage backends to implement the feature before they can serve queries.
@@ -165,9 +242,17 @@ const (
 	TxOperationGraphEdgeAdd                           // graph edge add
 	TxOperationGraphEdgeRemove                        // graph edge remove
 	TxOperationGraphNodeDrop                          // graph node drop (all edges)
+	// Schema operations let DDL that rewrites rows publish the new collection
+	// declaration, name, and SQL catalog in the same commit as the rows.
+	TxOperationCollectionConfig // replace Collection's config with Config
+	TxOperationCollectionRename // rename Collection to NewName
+	TxOperationCatalog          // replace the persisted SQL catalog with Catalog
+	TxOperationCollectionDelete // delete Collection and its records
+	TxOperationViewDrop         // remove the SQL view named Collection
 )
 
-// TxOperation represents one row-level or graph mutation in a transactional batch.
+// TxOperation represents one row-level, graph, or schema mutation in a
+// transactional batch.
 type TxOperation struct {
 	Metadata           map[string]interface{}
 	Collection         string
@@ -187,6 +272,14 @@ type TxOperation struct {
 	// EdgeProperties is the versioned JSON property envelope attached to the
 	// node-owned edge record. Empty means no arbitrary properties.
 	EdgeProperties []byte
+
+	// Schema fields (used when Type is TxOperationCollection* or
+	// TxOperationCatalog). A rename must follow every other collection
+	// operation in its batch, and no operation may address a collection
+	// after its delete.
+	Config  *CollectionConfig
+	NewName string
+	Catalog []byte
 }
 
 // TransactionalEngine extends Engine with atomic multi-collection commit support.
@@ -304,6 +397,128 @@ type GraphRecoveryTarget interface {
 	ReplayVertexLabel(nodeID uint64, label string, commitLSN uint64) error
 }
 
+// WALTransaction is one committed transaction shipped out of an engine's
+// write-ahead log. Frames holds the transaction's WAL chunks, TxBegin through
+// TxCommit, exactly as they are framed on disk; every chunk and frame
+// checksum was verified when the transaction was read.
+type WALTransaction struct {
+	TxID      uint64
+	CommitLSN uint64
+	Frames    []byte
+}
+
+// WALCursor is the resume position of a WAL reader. LSN is the commit LSN of
+// the last transaction consumed; only later commits are returned. Offset and
+// Generation are engine-private scan hints and the zero cursor reads from the
+// start of the retained log.
+type WALCursor struct {
+	LSN        uint64
+	Offset     int64
+	Generation uint64
+}
+
+// WALReader is implemented by engines that can ship committed transactions
+// to replicas. ReadWAL returns at most limit transactions committed after
+// cursor.LSN, in commit order, together with the cursor to resume from.
+type WALReader interface {
+	ReadWAL(ctx context.Context, cursor WALCursor, limit int) ([]WALTransaction, WALCursor, error)
+}
+
+// WALChangeType classifies the logical effect of a replicated transaction.
+type WALChangeType uint8
+
+const (
+	// WALChangeRecord reports a record written or deleted.
+	WALChangeRecord WALChangeType = iota + 1
+	// WALChangeCollectionCreate reports a created collection.
+	WALChangeCollectionCreate
+	// WALChangeCollectionDelete reports a deleted collection.
+	WALChangeCollectionDelete
+	// WALChangeCollectionConfig reports a replaced collection declaration.
+	WALChangeCollectionConfig
+	// WALChangeEdgeKind reports a registered SQL edge kind.
+	WALChangeEdgeKind
+	// WALChangeCatalog reports a replaced SQL catalog.
+	WALChangeCatalog
+	// WALChangeView reports a created, replaced or dropped SQL view.
+	WALChangeView
+)
+
+// WALChange describes one effect of a replicated transaction so the caller
+// can maintain state derived from storage, such as vector indexes. Record
+// changes are coalesced per record: Existed and PreviousOrdinal describe the
+// record before the transaction and the current row is read back from
+// storage. Graph frames need no change entry; they are routed to the
+// registered GraphRecoveryTarget.
+type WALChange struct {
+	Type            WALChangeType
+	Collection      string
+	ID              string
+	Existed         bool
+	PreviousOrdinal uint32
+	EdgeKind        string
+	EdgeKindDef     EdgeKindDefinition
+}
+
+// WALApplier is implemented by replica engines. ApplyWAL durably appends a
+// shipped transaction to the local log and applies it; transactions at or
+// below the local commit frontier are ignored. AppliedLSN is that frontier,
+// the position a follower resumes streaming from.
+type WALApplier interface {
+	ApplyWAL(ctx context.Context, tx WALTransaction) ([]WALChange, error)
+	AppliedLSN() uint64
+}
+
+// ChangeType identifies the mutation a ChangeEvent describes.
+type ChangeType uint8
+
+const (
+	ChangeRecordPut ChangeType = iota + 1
+	ChangeRecordDelete
+	ChangeEdgeAdd
+	ChangeEdgeRemove
+	ChangeNodeEdgesDrop
+	ChangeCollectionCreate
+	ChangeCollectionDelete
+	ChangeCollectionConfig
+	ChangeCollectionRename
+)
+
+// ChangeEvent is one logical mutation of a committed transaction. Record
+// events carry the version visible before the commit in Old and after it in
+// New; a transaction that writes a record several times yields one event.
+// Edge is set for edge events, NodeID for ChangeNodeEdgesDrop, Config for
+// collection creation and config changes, and PreviousName for a rename.
+type ChangeEvent struct {
+	Old          *TemporalRecord
+	New          *TemporalRecord
+	Config       *CollectionConfig
+	Collection   string
+	PreviousName string
+	ID           string
+	Edge         GraphEdgeOp
+	NodeID       uint64
+	Type         ChangeType
+}
+
+// ChangeSet holds the events of one committed transaction. CommitLSN is the
+// LSN reported by that transaction's CommitReceipt.
+type ChangeSet struct {
+	Events    []ChangeEvent
+	CommitLSN uint64
+	Timestamp int64 // UTC unix nano; 0 when the commit time is not retained
+}
+
+// ChangeReader is implemented by engines that expose a change data capture
+// feed. ReadChanges returns up to limit change sets committed after
+// cursor.LSN, in commit order, and the cursor that resumes after them.
+// CommitNotify returns a channel closed by the next commit; take it before
+// reading so no commit is missed between a read and the wait.
+type ChangeReader interface {
+	ReadChanges(ctx context.Context, cursor WALCursor, limit int) ([]ChangeSet, WALCursor, error)
+	CommitNotify() <-chan struct{}
+}
+
 // TemporalRecord is a resolved historical record returned by temporal read
 // APIs. It bridges the storage engine's MVCC layer to the public libravdb API.
 type TemporalRecord struct {
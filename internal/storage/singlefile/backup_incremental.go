@@ -0,0 +1,353 @@
+package singlefile
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// An incremental backup holds the committed WAL transactions of one database
+// after a base LSN: a fixed header followed by WAL chunks in the database's
+// own framing, sealed when the database is encrypted.
+const (
+	incrementalMagic      = "LVDBINCR"
+	incrementalVersion    = uint16(1)
+	incrementalHeaderSize = 48
+)
+
+// ErrBackupChainBroken reports a backup chain that cannot be restored: a file
+// that is not an incremental backup, an increment of another database, or a
+// gap between consecutive backups.
+var ErrBackupChainBroken = errors.New("backup chain is broken")
+
+type incrementalHeader struct {
+	FileID       uint64
+	BaseLSN      uint64
+	EndLSN       uint64
+	Transactions uint64
+	FeatureFlags uint32
+}
+
+func encodeIncrementalHeader(h incrementalHeader) []byte {
+	buf := make([]byte, incrementalHeaderSize)
+	copy(buf[0:8], incrementalMagic)
+	binary.LittleEndian.PutUint16(buf[8:10], incrementalVersion)
+	binary.LittleEndian.PutUint32(buf[12:16], h.FeatureFlags)
+	binary.LittleEndian.PutUint64(buf[16:24], h.FileID)
+	binary.LittleEndian.PutUint64(buf[24:32], h.BaseLSN)
+	binary.LittleEndian.PutUint64(buf[32:40], h.EndLSN)
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(h.Transactions))
+	binary.LittleEndian.PutUint32(buf[44:48], crc32.Checksum(buf[:44], castagnoli))
+	return buf
+}
+
+func decodeIncrementalHeader(buf []byte) (incrementalHeader, error) {
+	if len(buf) < incrementalHeaderSize || string(buf[0:8]) != incrementalMagic {
+		return incrementalHeader{}, fmt.Errorf("%w: not an incremental backup", ErrBackupChainBroken)
+	}
+	if version := binary.LittleEndian.Uint16(buf[8:10]); version != incrementalVersion {
+		return incrementalHeader{}, fmt.Errorf("unsupported incremental backup version %d", version)
+	}
+	if crc32.Checksum(buf[:44], castagnoli) != binary.LittleEndian.Uint32(buf[44:48]) {
+		return incrementalHeader{}, fmt.Errorf("invalid incremental backup header checksum")
+	}
+	return incrementalHeader{
+		FeatureFlags: binary.LittleEndian.Uint32(buf[12:16]),
+		FileID:       binary.LittleEndian.Uint64(buf[16:24]),
+		BaseLSN:      binary.LittleEndian.Uint64(buf[24:32]),
+		EndLSN:       binary.LittleEndian.Uint64(buf[32:40]),
+		Transactions: uint64(binary.LittleEndian.Uint32(buf[40:44])),
+	}, nil
+}
+
+// incrementalBatch bounds how many transactions BackupIncremental collects
+// per read lock.
+const incrementalBatch = 256
+
+// BackupIncremental writes the transactions committed after sinceLSN to a new
+// file at destPath and returns the last commit LSN it contains, the sinceLSN
+// of the next increment. sinceLSN is normally the LatestCommitLSN read before
+// the previous full or incremental backup; overlapping increments are
+// harmless. It fails with ErrWALTruncated when compaction has already folded
+// WAL after sinceLSN into a snapshot; take a full Backup then.
+func (e *Engine) BackupIncremental(ctx context.Context, destPath string, sinceLSN uint64) (uint64, error) {
+	endLSN, err := e.LatestCommitLSN()
+	if errors.Is(err, ErrNoCommits) {
+		endLSN, err = sinceLSN, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if endLSN < sinceLSN {
+		return 0, fmt.Errorf("backup base LSN %d is after the latest commit %d", sinceLSN, endLSN)
+	}
+
+	destFile, err := os.OpenFile(destPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("incremental backup create file: %w", err)
+	}
+	cleanup := true
+	defer func() {
+		if cleanup {
+			destFile.Close()
+			os.Remove(destPath)
+		}
+	}()
+
+	header := incrementalHeader{FileID: e.fileID, BaseLSN: sinceLSN, EndLSN: endLSN}
+	if e.cipher != nil {
+		header.FeatureFlags |= featureEncrypted
+	}
+	offset := int64(incrementalHeaderSize)
+	cursor := storage.WALCursor{LSN: sinceLSN}
+	for cursor.LSN < endLSN {
+		e.mu.RLock()
+		if e.closed.Load() {
+			e.mu.RUnlock()
+			return 0, fmt.Errorf("database is closed")
+		}
+		if cursor.LSN < e.state.WALFloorLSN {
+			floor := e.state.WALFloorLSN
+			e.mu.RUnlock()
+			return 0, fmt.Errorf("%w: LSN %d < WAL floor %d", ErrWALTruncated, cursor.LSN, floor)
+		}
+		txs, next, err := e.scanCommittedWALLocked(ctx, cursor, incrementalBatch, endLSN)
+		e.mu.RUnlock()
+		if err != nil {
+			return 0, err
+		}
+		for _, tx := range txs {
+			// Frames are plaintext chunks; seal them again when encrypted.
+			for raw := tx.Frames; len(raw) >= 16; {
+				end := 16 + int(binary.LittleEndian.Uint32(raw[8:12]))
+				chunk, err := e.encodeChunk(chunkTypeWAL, uint64(offset), nil, raw[16:end])
+				if err != nil {
+					return 0, err
+				}
+				if err := chunk.writeAt(destFile, offset); err != nil {
+					return 0, fmt.Errorf("incremental backup write: %w", err)
+				}
+				offset += int64(chunk.size())
+				raw = raw[end:]
+			}
+			header.Transactions++
+		}
+		if len(txs) == 0 {
+			// The log ends below endLSN only if it was compacted between
+			// reading the latest commit and scanning.
+			if next.LSN < endLSN {
+				return 0, fmt.Errorf("%w: WAL after LSN %d is no longer retained", ErrWALTruncated, next.LSN)
+			}
+			break
+		}
+		cursor = next
+	}
+
+	if err := writeFullAt(destFile, encodeIncrementalHeader(header), 0); err != nil {
+		return 0, fmt.Errorf("incremental backup write header: %w", err)
+	}
+	if err := destFile.Sync(); err != nil {
+		return 0, fmt.Errorf("incremental backup sync: %w", err)
+	}
+	if err := destFile.Close(); err != nil {
+		return 0, fmt.Errorf("incremental backup close: %w", err)
+	}
+	if err := syncDatabaseParent(destPath); err != nil {
+		return 0, fmt.Errorf("incremental backup sync parent directory: %w", err)
+	}
+	cleanup = false
+	return endLSN, nil
+}
+
+// RestoreToLSN rebuilds the database as of commit lsn at outPath from a
+// backup chain: a full Backup followed by incremental backups in order. lsn
+// must be a commit LSN at or after the full backup that the chain reaches;
+// the restored commit catalog is checked with ResolveLSN before the file is
+// published. opts are the options the database is opened with, such as
+// WithEncryption.
+func RestoreToLSN(ctx context.Context, chain []string, lsn uint64, outPath string, opts ...Option) error {
+	if len(chain) == 0 {
+		return fmt.Errorf("%w: no full backup", ErrBackupChainBroken)
+	}
+	if _, err := os.Stat(outPath); err == nil {
+		return fmt.Errorf("restore destination %s already exists", outPath)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("stat restore destination: %w", err)
+	}
+	tmpPath := outPath + ".restore"
+	if err := copyFile(chain[0], tmpPath); err != nil {
+		return fmt.Errorf("restore copy full backup: %w", err)
+	}
+	cleanup := true
+	defer func() {
+		if cleanup {
+			os.Remove(tmpPath)
+		}
+	}()
+
+	restored, err := New(tmpPath, append(append([]Option(nil), opts...), WithReplica())...)
+	if err != nil {
+		return fmt.Errorf("restore open full backup: %w", err)
+	}
+	engine := restored.(*Engine)
+	if err := engine.restoreIncrementsTo(ctx, chain[1:], lsn); err != nil {
+		engine.Close()
+		return err
+	}
+	if err := engine.Close(); err != nil {
+		return fmt.Errorf("restore close: %w", err)
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		return fmt.Errorf("restore publish: %w", err)
+	}
+	cleanup = false
+	return syncDatabaseParent(outPath)
+}
+
+// restoreIncrementsTo applies the increments' transactions up to commit lsn
+// to a replica engine opened on a full backup.
+func (e *Engine) restoreIncrementsTo(ctx context.Context, increments []string, lsn uint64) error {
+	base, err := e.committedThrough()
+	if err != nil {
+		return err
+	}
+	if lsn < base {
+		return fmt.Errorf("LSN %d precedes the full backup, which is at LSN %d", lsn, base)
+	}
+	for _, path := range increments {
+		if through, _ := e.committedThrough(); through >= lsn {
+			break
+		}
+		if err := e.applyIncrementTo(ctx, path, lsn); err != nil {
+			return fmt.Errorf("restore %s: %w", path, err)
+		}
+	}
+	if latest, err := e.LatestCommitLSN(); err != nil || latest != lsn {
+		return fmt.Errorf("%w: LSN %d is not a commit in the backup chain (restored through %d)", ErrUnknownLSN, lsn, latest)
+	}
+	if _, err := e.ResolveLSN(lsn); err != nil {
+		return err
+	}
+	return nil
+}
+
+// committedThrough is LatestCommitLSN, zero for a file without commits.
+func (e *Engine) committedThrough() (uint64, error) {
+	lsn, err := e.LatestCommitLSN()
+	if errors.Is(err, ErrNoCommits) {
+		return 0, nil
+	}
+	return lsn, err
+}
+
+func (e *Engine) applyIncrementTo(ctx context.Context, path string, lsn uint64) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	buf := make([]byte, incrementalHeaderSize)
+	if _, err := io.ReadFull(file, buf); err != nil {
+		return fmt.Errorf("%w: read incremental header: %v", ErrBackupChainBroken, err)
+	}
+	header, err := decodeIncrementalHeader(buf)
+	if err != nil {
+		return err
+	}
+	if header.FileID != e.fileID {
+		return fmt.Errorf("%w: increment belongs to database %d, not %d", ErrBackupChainBroken, header.FileID, e.fileID)
+	}
+	if err := e.checkEncryptionFlag(&fileHeader{FeatureFlags: header.FeatureFlags}); err != nil {
+		return err
+	}
+	through, err := e.committedThrough()
+	if err != nil {
+		return err
+	}
+	if header.BaseLSN > through {
+		return fmt.Errorf("%w: increment starts after LSN %d but the chain so far ends at %d", ErrBackupChainBroken, header.BaseLSN, through)
+	}
+
+	var pending []byte
+	var pendingTx uint64
+	var applied uint64
+	offset := uint64(incrementalHeaderSize)
+	for {
+		var chunkHeaderBuf [16]byte
+		if _, err := io.ReadFull(file, chunkHeaderBuf[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("read chunk header: %w", err)
+		}
+		chunk := decodeChunkHeader(chunkHeaderBuf[:])
+		if chunk.Magic != chunkMagic || chunk.Kind != chunkTypeWAL || chunk.PayloadLen > maxChunkSize {
+			return fmt.Errorf("invalid WAL chunk in incremental backup")
+		}
+		payload := make([]byte, chunk.PayloadLen)
+		if _, err := io.ReadFull(file, payload); err != nil {
+			return fmt.Errorf("read WAL chunk: %w", err)
+		}
+		if crc32.Checksum(payload, castagnoli) != chunk.Checksum {
+			return fmt.Errorf("invalid WAL chunk checksum in incremental backup")
+		}
+		payload, err = e.openChunk(offset, chunkHeaderBuf[:], payload)
+		if err != nil {
+			return err
+		}
+		offset += 16 + uint64(chunk.PayloadLen)
+		record, err := decodeWALRecord(payload)
+		if err != nil {
+			return err
+		}
+		if record.Header.RecordType == recordTypeTxBegin {
+			pending, pendingTx = pending[:0], record.Header.TxID
+		}
+		pending = append(pending, plaintextChunk(chunkTypeWAL, payload)...)
+		if record.Header.RecordType != recordTypeTxCommit {
+			continue
+		}
+		if record.Header.LSN > lsn {
+			return nil
+		}
+		tx := storage.WALTransaction{TxID: pendingTx, CommitLSN: record.Header.LSN, Frames: pending}
+		if _, err := e.ApplyWAL(ctx, tx); err != nil {
+			return fmt.Errorf("apply transaction %d at LSN %d: %w", tx.TxID, tx.CommitLSN, err)
+		}
+		pending = nil
+		applied++
+	}
+	if applied > header.Transactions {
+		return fmt.Errorf("incremental backup holds %d transactions, header declares %d", applied, header.Transactions)
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
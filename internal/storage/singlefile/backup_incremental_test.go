@@ -0,0 +1,124 @@
+package singlefile
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+func insertDoc(t *testing.T, engine *Engine, id string, ordinal uint32) uint64 {
+	t.Helper()
+	coll, err := engine.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &index.VectorEntry{ID: id, Ordinal: ordinal, Vector: []float32{1, float32(ordinal)}}
+	if err := coll.Insert(context.Background(), entry); err != nil {
+		t.Fatal(err)
+	}
+	lsn, err := engine.LatestCommitLSN()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lsn
+}
+
+func assertDocs(t *testing.T, path string, present, absent []string) {
+	t.Helper()
+	restored, err := New(path)
+	if err != nil {
+		t.Fatalf("open %s: %v", filepath.Base(path), err)
+	}
+	defer restored.Close()
+	coll, err := restored.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range present {
+		if _, err := coll.Get(context.Background(), id); err != nil {
+			t.Fatalf("%s: Get(%q): %v", filepath.Base(path), id, err)
+		}
+	}
+	for _, id := range absent {
+		if _, err := coll.Get(context.Background(), id); err == nil {
+			t.Fatalf("%s: %q is present after its commit", filepath.Base(path), id)
+		}
+	}
+}
+
+func TestIncrementalBackupRestoresToLSN(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	primaryIface, err := New(filepath.Join(dir, "primary.libravdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := primaryIface.(*Engine)
+	defer primary.Close()
+	if _, err := primary.CreateCollection("docs", &storage.CollectionConfig{Dimension: 2}); err != nil {
+		t.Fatal(err)
+	}
+	base := insertDoc(t, primary, "a", 1)
+
+	full := filepath.Join(dir, "full.libravdb")
+	if err := primary.Backup(ctx, full); err != nil {
+		t.Fatal(err)
+	}
+	lsnB := insertDoc(t, primary, "b", 2)
+	insertDoc(t, primary, "c", 3)
+	inc1 := filepath.Join(dir, "inc1.libravdb")
+	end1, err := primary.BackupIncremental(ctx, inc1, base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lsnD := insertDoc(t, primary, "d", 4)
+	inc2 := filepath.Join(dir, "inc2.libravdb")
+	end2, err := primary.BackupIncremental(ctx, inc2, end1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if end2 != lsnD {
+		t.Fatalf("BackupIncremental end LSN = %d, want %d", end2, lsnD)
+	}
+	chain := []string{full, inc1, inc2}
+
+	atB := filepath.Join(dir, "at-b.libravdb")
+	if err := RestoreToLSN(ctx, chain, lsnB, atB); err != nil {
+		t.Fatal(err)
+	}
+	assertDocs(t, atB, []string{"a", "b"}, []string{"c", "d"})
+
+	atEnd := filepath.Join(dir, "at-end.libravdb")
+	if err := RestoreToLSN(ctx, chain, lsnD, atEnd); err != nil {
+		t.Fatal(err)
+	}
+	assertDocs(t, atEnd, []string{"a", "b", "c", "d"}, nil)
+
+	if err := RestoreToLSN(ctx, chain, lsnD+1000, filepath.Join(dir, "future.libravdb")); !errors.Is(err, ErrUnknownLSN) {
+		t.Fatalf("restore past the chain: %v, want ErrUnknownLSN", err)
+	}
+	if err := RestoreToLSN(ctx, chain, base-1, filepath.Join(dir, "early.libravdb")); err == nil {
+		t.Fatal("restore before the full backup succeeded")
+	}
+	if err := RestoreToLSN(ctx, []string{full, inc2}, lsnD, filepath.Join(dir, "gap.libravdb")); !errors.Is(err, ErrBackupChainBroken) {
+		t.Fatalf("restore across a gap: %v, want ErrBackupChainBroken", err)
+	}
+
+	otherIface, err := New(filepath.Join(dir, "other.libravdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := otherIface.(*Engine)
+	defer other.Close()
+	foreign := filepath.Join(dir, "foreign.libravdb")
+	if _, err := other.BackupIncremental(ctx, foreign, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := RestoreToLSN(ctx, []string{full, foreign}, lsnD, filepath.Join(dir, "mixed.libravdb")); !errors.Is(err, ErrBackupChainBroken) {
+		t.Fatalf("restore with a foreign increment: %v, want ErrBackupChainBroken", err)
+	}
+}
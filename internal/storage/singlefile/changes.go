@@ -0,0 +1,327 @@
+package singlefile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// CommitNotify returns a channel that is closed once the next transaction
+// commits.
+func (e *Engine) CommitNotify() <-chan struct{} {
+	e.commitNotifyMu.Lock()
+	defer e.commitNotifyMu.Unlock()
+	if e.commitNotify == nil {
+		e.commitNotify = make(chan struct{})
+	}
+	return e.commitNotify
+}
+
+// notifyCommit wakes every CommitNotify waiter.
+func (e *Engine) notifyCommit() {
+	e.commitNotifyMu.Lock()
+	if e.commitNotify != nil {
+		close(e.commitNotify)
+		e.commitNotify = nil
+	}
+	e.commitNotifyMu.Unlock()
+}
+
+// ReadChanges returns up to limit change sets committed after cursor.LSN in
+// commit order. Commits still in the WAL are decoded from it. Commits that
+// compaction folded into the snapshot are rebuilt from retained record
+// history and the graph WAL that compaction keeps; for those, only collection
+// creation is reported among the DDL events. ErrRetentionExpired is returned
+// once temporal retention no longer covers cursor.LSN.
+func (e *Engine) ReadChanges(ctx context.Context, cursor storage.WALCursor, limit int) ([]storage.ChangeSet, storage.WALCursor, error) {
+	if limit <= 0 {
+		return nil, cursor, fmt.Errorf("change read limit must be positive")
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed.Load() {
+		return nil, cursor, fmt.Errorf("database is closed")
+	}
+	if cursor.LSN < e.oldestRetainedLSN {
+		return nil, cursor, fmt.Errorf("%w: LSN %d < oldest retained %d", ErrRetentionExpired, cursor.LSN, e.oldestRetainedLSN)
+	}
+
+	var out []storage.ChangeSet
+	if floor := e.state.WALFloorLSN; cursor.LSN < floor {
+		historical, err := e.historicalChangesLocked(ctx, cursor.LSN, floor, limit)
+		if err != nil {
+			return nil, cursor, err
+		}
+		out = historical
+		if len(out) == limit {
+			return out, storage.WALCursor{LSN: out[len(out)-1].CommitLSN}, nil
+		}
+		cursor = storage.WALCursor{LSN: floor}
+	}
+	txs, next, err := e.scanCommittedWALLocked(ctx, cursor, limit-len(out), 0)
+	if err != nil {
+		return nil, cursor, err
+	}
+	for _, tx := range txs {
+		set, err := e.walChangeSetLocked(tx.CommitLSN, tx.frames)
+		if err != nil {
+			return nil, cursor, err
+		}
+		out = append(out, set)
+	}
+	return out, next, nil
+}
+
+// walChangeSetLocked decodes one committed transaction into change events.
+// Caller must hold e.mu.
+func (e *Engine) walChangeSetLocked(commitLSN uint64, frames []walRecord) (storage.ChangeSet, error) {
+	type recordKey struct{ collection, id string }
+	set := storage.ChangeSet{CommitLSN: commitLSN}
+	set.Timestamp, _ = e.commitTimestampForLSN(commitLSN)
+	recordEvents := make(map[recordKey]int)
+	// lastPut keeps the newest payload written to each record so a record in
+	// a dropped collection still reports its new version.
+	lastPut := make(map[recordKey]*recordPutPayload)
+	noteRecord := func(collection, id string) recordKey {
+		key := recordKey{collection: collection, id: id}
+		if _, ok := recordEvents[key]; !ok {
+			recordEvents[key] = len(set.Events)
+			set.Events = append(set.Events, storage.ChangeEvent{Collection: collection, ID: id})
+		}
+		return key
+	}
+	for _, record := range frames {
+		switch record.Header.RecordType {
+		case recordTypeCollectionCreate, recordTypeCollectionConfig:
+			payload, err := decodeCollectionCreatePayloadBinary(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			changeType := storage.ChangeCollectionCreate
+			if record.Header.RecordType == recordTypeCollectionConfig {
+				changeType = storage.ChangeCollectionConfig
+			}
+			config := payload.Config
+			set.Events = append(set.Events, storage.ChangeEvent{Type: changeType, Collection: payload.Name, Config: &config})
+		case recordTypeCollectionDelete:
+			payload, err := decodeCollectionDeletePayloadBinary(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			set.Events = append(set.Events, storage.ChangeEvent{Type: storage.ChangeCollectionDelete, Collection: payload.Name})
+		case recordTypeCollectionRename:
+			payload, err := decodeCollectionRenamePayloadBinary(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			set.Events = append(set.Events, storage.ChangeEvent{Type: storage.ChangeCollectionRename, Collection: payload.NewName, PreviousName: payload.Name})
+		case recordTypeRecordPut:
+			payload, err := decodeRecordPutPayloadBinary(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			lastPut[noteRecord(payload.Collection, payload.ID)] = &payload
+		case recordTypeRecordDelete:
+			payload, err := decodeRecordDeletePayloadBinary(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			lastPut[noteRecord(payload.Collection, payload.ID)] = nil
+		case recordTypeGraphEdgeAdd:
+			payload, err := decodeGraphEdgeAddPayload(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			set.Events = append(set.Events, storage.ChangeEvent{
+				Type:       storage.ChangeEdgeAdd,
+				Collection: payload.Collection,
+				Edge: storage.GraphEdgeOp{
+					Collection: payload.Collection, Src: payload.Src, Tgt: payload.Tgt,
+					Weight: payload.Weight, Kind: payload.Kind, Properties: payload.Properties,
+				},
+			})
+		case recordTypeGraphEdgeRemove:
+			payload, err := decodeGraphEdgeRemovePayload(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			set.Events = append(set.Events, storage.ChangeEvent{
+				Type:       storage.ChangeEdgeRemove,
+				Collection: payload.Collection,
+				Edge:       storage.GraphEdgeOp{Collection: payload.Collection, Src: payload.Src, Tgt: payload.Tgt, Kind: payload.Kind},
+			})
+		case recordTypeGraphNodeDrop:
+			payload, err := decodeGraphNodeDropPayload(record.Payload)
+			if err != nil {
+				return set, err
+			}
+			set.Events = append(set.Events, storage.ChangeEvent{Type: storage.ChangeNodeEdgesDrop, Collection: payload.Collection, NodeID: payload.NodeID})
+		}
+	}
+
+	kept := set.Events[:0]
+	for i := range set.Events {
+		event := set.Events[i]
+		key := recordKey{collection: event.Collection, id: event.ID}
+		if index, ok := recordEvents[key]; !ok || index != i {
+			kept = append(kept, event)
+			continue
+		}
+		live := e.liveCollectionLocked(event.Collection)
+		if live {
+			event.Old = e.changedRecordAtLocked(event.Collection, event.ID, commitLSN-1)
+			event.New = e.changedRecordAtLocked(event.Collection, event.ID, commitLSN)
+		} else if put := lastPut[key]; put != nil {
+			event.New = &storage.TemporalRecord{
+				ID: put.ID, Vector: cloneVector(put.Vector), Metadata: cloneMetadata(put.Metadata), Ordinal: put.Ordinal,
+			}
+		}
+		switch {
+		case event.New != nil:
+			event.Type = storage.ChangeRecordPut
+		case event.Old != nil || !live:
+			// A dropped collection's history is gone, so its deletes are
+			// reported without the old version.
+			event.Type = storage.ChangeRecordDelete
+		default:
+			// Written and deleted inside the same transaction.
+			continue
+		}
+		kept = append(kept, event)
+	}
+	set.Events = kept
+	return set, nil
+}
+
+// historicalChangesLocked rebuilds the first limit change sets in
+// (fromLSN, toLSN] from retained record versions, collection creation LSNs
+// and the committed graph WAL preserved by compaction. Caller must hold e.mu.
+func (e *Engine) historicalChangesLocked(ctx context.Context, fromLSN, toLSN uint64, limit int) ([]storage.ChangeSet, error) {
+	type recordKey struct{ collection, id string }
+	type pendingSet struct {
+		creates []storage.ChangeEvent
+		records map[recordKey]struct{}
+		graph   []storage.ChangeEvent
+	}
+	sets := make(map[uint64]*pendingSet)
+	at := func(lsn uint64) *pendingSet {
+		set := sets[lsn]
+		if set == nil {
+			set = &pendingSet{records: make(map[recordKey]struct{})}
+			sets[lsn] = set
+		}
+		return set
+	}
+	inRange := func(lsn uint64) bool { return lsn > fromLSN && lsn <= toLSN }
+
+	for name, collection := range e.state.Collections {
+		if collection.Deleted {
+			continue
+		}
+		if inRange(collection.CreatedLSN) {
+			config := collection.Config
+			set := at(collection.CreatedLSN)
+			set.creates = append(set.creates, storage.ChangeEvent{Type: storage.ChangeCollectionCreate, Collection: name, Config: &config})
+		}
+		for id, versions := range collection.HistoricalVersions {
+			for _, version := range versions {
+				if inRange(version.BeginLSN) {
+					at(version.BeginLSN).records[recordKey{name, id}] = struct{}{}
+				}
+				if inRange(version.EndLSN) {
+					at(version.EndLSN).records[recordKey{name, id}] = struct{}{}
+				}
+			}
+		}
+		for id, current := range collection.Records {
+			if !current.Deleted && inRange(current.CreatedLSN) {
+				at(current.CreatedLSN).records[recordKey{name, id}] = struct{}{}
+			}
+		}
+	}
+	graphTxs, _, err := e.scanCommittedWALLocked(ctx, storage.WALCursor{LSN: fromLSN}, math.MaxInt, toLSN)
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range graphTxs {
+		decoded, err := e.walChangeSetLocked(tx.CommitLSN, tx.frames)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range decoded.Events {
+			switch event.Type {
+			case storage.ChangeEdgeAdd, storage.ChangeEdgeRemove, storage.ChangeNodeEdgesDrop:
+				set := at(tx.CommitLSN)
+				set.graph = append(set.graph, event)
+			}
+		}
+	}
+
+	lsns := make([]uint64, 0, len(sets))
+	for lsn := range sets {
+		lsns = append(lsns, lsn)
+	}
+	sort.Slice(lsns, func(i, j int) bool { return lsns[i] < lsns[j] })
+	var out []storage.ChangeSet
+	for _, lsn := range lsns {
+		if len(out) == limit {
+			break
+		}
+		pending := sets[lsn]
+		set := storage.ChangeSet{CommitLSN: lsn, Events: pending.creates}
+		set.Timestamp, _ = e.commitTimestampForLSN(lsn)
+		keys := make([]recordKey, 0, len(pending.records))
+		for key := range pending.records {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].collection != keys[j].collection {
+				return keys[i].collection < keys[j].collection
+			}
+			return keys[i].id < keys[j].id
+		})
+		for _, key := range keys {
+			event := storage.ChangeEvent{
+				Collection: key.collection,
+				ID:         key.id,
+				Old:        e.changedRecordAtLocked(key.collection, key.id, lsn-1),
+				New:        e.changedRecordAtLocked(key.collection, key.id, lsn),
+			}
+			switch {
+			case event.New != nil:
+				event.Type = storage.ChangeRecordPut
+			case event.Old != nil:
+				event.Type = storage.ChangeRecordDelete
+			default:
+				continue
+			}
+			set.Events = append(set.Events, event)
+		}
+		set.Events = append(set.Events, pending.graph...)
+		if len(set.Events) > 0 {
+			out = append(out, set)
+		}
+	}
+	return out, nil
+}
+
+func (e *Engine) liveCollectionLocked(name string) bool {
+	collection := e.state.Collections[name]
+	return collection != nil && !collection.Deleted
+}
+
+// changedRecordAtLocked returns a detached copy of the record version visible
+// at lsn, or nil when the record did not exist. Caller must hold e.mu.
+func (e *Engine) changedRecordAtLocked(collectionName, id string, lsn uint64) *storage.TemporalRecord {
+	record, err := e.recordAtLSNLocked(collectionName, id, lsn)
+	if err != nil || record == nil {
+		return nil
+	}
+	return &storage.TemporalRecord{
+		ID: record.ID, Vector: cloneVector(record.Vector), Metadata: cloneMetadata(record.Metadata),
+		Ordinal: record.Ordinal, Version: record.Version,
+	}
+}
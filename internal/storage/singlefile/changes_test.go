@@ -0,0 +1,132 @@
+package singlefile
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// changeSummary flattens change sets into comparable "type collection/id"
+// strings, with the first vector component of the old and new versions.
+func changeSummary(sets []storage.ChangeSet) []string {
+	names := map[storage.ChangeType]string{
+		storage.ChangeRecordPut:        "put",
+		storage.ChangeRecordDelete:     "delete",
+		storage.ChangeEdgeAdd:          "edge_add",
+		storage.ChangeCollectionCreate: "collection_create",
+	}
+	var out []string
+	version := func(record *storage.TemporalRecord) string {
+		if record == nil {
+			return "-"
+		}
+		return string('0' + rune(record.Vector[0]))
+	}
+	for _, set := range sets {
+		for _, event := range set.Events {
+			switch event.Type {
+			case storage.ChangeRecordPut, storage.ChangeRecordDelete:
+				out = append(out, names[event.Type]+" "+event.Collection+"/"+event.ID+" "+version(event.Old)+">"+version(event.New))
+			default:
+				out = append(out, names[event.Type]+" "+event.Collection)
+			}
+		}
+	}
+	return out
+}
+
+func TestReadChangesDecodesCommittedMutations(t *testing.T) {
+	ctx := context.Background()
+	engineIface, err := New(filepath.Join(t.TempDir(), "changes.libravdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := engineIface.(*Engine)
+	defer engine.Close()
+
+	committed := engine.CommitNotify()
+	coll, err := engine.CreateCollection("docs", &storage.CollectionConfig{Dimension: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-committed:
+	default:
+		t.Fatal("CommitNotify was not signalled by a commit")
+	}
+	for _, value := range []float32{1, 2} {
+		if err := coll.Insert(ctx, &index.VectorEntry{ID: "a", Ordinal: 1, Vector: []float32{value}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := coll.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.AppendGraphEdges(ctx, []storage.GraphEdgeOp{{Collection: "docs", Src: 1, Tgt: 2, Weight: 1, Kind: 3}}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"collection_create docs",
+		"put docs/a ->1",
+		"put docs/a 1>2",
+		"delete docs/a 2>-",
+		"edge_add docs",
+	}
+
+	sets, _, err := engine.ReadChanges(ctx, storage.WALCursor{}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changeSummary(sets); !slices.Equal(got, want) {
+		t.Fatalf("WAL changes = %q, want %q", got, want)
+	}
+	latest, _ := engine.LatestCommitLSN()
+	if last := sets[len(sets)-1]; last.CommitLSN != latest || last.Timestamp == 0 {
+		t.Fatalf("last change set at LSN %d (timestamp %d), want LSN %d", last.CommitLSN, last.Timestamp, latest)
+	}
+	first, next, err := engine.ReadChanges(ctx, storage.WALCursor{}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, _, err := engine.ReadChanges(ctx, next, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changeSummary(append(first, rest...)); !slices.Equal(got, want) {
+		t.Fatalf("paged changes = %q, want %q", got, want)
+	}
+
+	// Compaction drops the record WAL; the feed is rebuilt from history.
+	if err := engine.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	sets, _, err = engine.ReadChanges(ctx, storage.WALCursor{}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changeSummary(sets); !slices.Equal(got, want) {
+		t.Fatalf("compacted changes = %q, want %q", got, want)
+	}
+	if err := coll.Insert(ctx, &index.VectorEntry{ID: "b", Ordinal: 2, Vector: []float32{4}}); err != nil {
+		t.Fatal(err)
+	}
+	tail, _, err := engine.ReadChanges(ctx, storage.WALCursor{LSN: latest}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changeSummary(tail); !slices.Equal(got, []string{"put docs/b ->4"}) {
+		t.Fatalf("changes after compaction = %q", got)
+	}
+
+	if _, err := engine.CompactTemporalHistory(latest); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := engine.ReadChanges(ctx, storage.WALCursor{}, 100); !errors.Is(err, ErrRetentionExpired) {
+		t.Fatalf("ReadChanges below retention: %v, want ErrRetentionExpired", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/xDarkicex/libravdb/internal/storage"
 	"github.com/xDarkicex/libravdb/internal/util"
@@ -15,7 +16,7 @@ const (
 	codecVersion byte = 3 // Binary payload encoding (snapshot state, WAL frames, collection records)
 )
 
-const snapshotCodecVersion byte = 8 // v4: historical versions; v5: graph tombstones; v6: temporal catalog; v7: edge kinds; v8: edge directionality
+const snapshotCodecVersion byte = 10 // v4: historical versions; v5: graph tombstones; v6: temporal catalog; v7: edge kinds; v8: edge directionality; v9: WAL floor; v10: SQL views
 
 var graphConfigFieldMagic = []byte{'G', 'R', 'P', 'H', 1}
 
@@ -78,6 +79,23 @@ func encodeStateBinary(state *persistedState) ([]byte, error) {
 			_ = enc.WriteByte(0)
 		}
 	}
+	enc.WriteUint64(state.WALFloorLSN)
+	viewNames := make([]string, 0, len(state.Views))
+	for name := range state.Views {
+		viewNames = append(viewNames, name)
+	}
+	sort.Strings(viewNames)
+	enc.WriteUint32(uint32(len(viewNames)))
+	for _, name := range viewNames {
+		view := state.Views[name]
+		enc.WriteString(name)
+		enc.WriteString(view.Query)
+		if view.Materialized {
+			_ = enc.WriteByte(1)
+		} else {
+			_ = enc.WriteByte(0)
+		}
+	}
 	names := make([]string, 0, len(state.Collections))
 	for name := range state.Collections {
 		names = append(names, name)
@@ -187,6 +205,43 @@ func decodeStateBinary(data []byte) (*persistedState, error) {
 			}
 		}
 	}
+	// Snapshots older than v9 do not record how much WAL an earlier
+	// compaction discarded. Every commit in the catalog is already folded
+	// into this snapshot, so the newest one is a safe floor.
+	var walFloorLSN uint64
+	if version >= 9 {
+		walFloorLSN, err = dec.ReadUint64()
+		if err != nil {
+			return nil, err
+		}
+	} else if len(commitCatalog) > 0 {
+		walFloorLSN = commitCatalog[len(commitCatalog)-1].LSN
+	}
+	var views map[string]storage.ViewDefinition
+	if version >= 10 {
+		count, err := dec.ReadUint32()
+		if err != nil {
+			return nil, err
+		}
+		if count > 0 {
+			views = make(map[string]storage.ViewDefinition, count)
+		}
+		for i := uint32(0); i < count; i++ {
+			name, err := dec.ReadString()
+			if err != nil {
+				return nil, err
+			}
+			query, err := dec.ReadString()
+			if err != nil {
+				return nil, err
+			}
+			materialized, err := dec.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			views[name] = storage.ViewDefinition{Query: query, Materialized: materialized != 0}
+		}
+	}
 	count, err := dec.ReadUint32()
 	if err != nil {
 		return nil, err
@@ -197,8 +252,10 @@ func decodeStateBinary(data []byte) (*persistedState, error) {
 		TombstonedGraphNodeIDs: tombstonedGraphNodeIDs,
 		CommitCatalog:          commitCatalog,
 		OldestRetainedLSN:      oldestRetainedLSN,
+		WALFloorLSN:            walFloorLSN,
 		EdgeKinds:              edgeKinds,
 		UndirectedEdgeKinds:    stateUndirectedEdgeKinds,
+		Views:                  views,
 		Collections:            make(map[string]*persistedCollection, count),
 	}
 	for i := uint32(0); i < count; i++ {
@@ -261,6 +318,45 @@ func decodeCollectionDeletePayloadBinary(data []byte) (collectionDeletePayload,
 	return collectionDeletePayload{Name: name}, nil
 }
 
+func encodeCollectionRenamePayloadBinary(payload collectionRenamePayload) (encodedPayload, error) {
+	enc := util.AcquireBinaryEncoder(1 + 4 + len(payload.Name) + 4 + len(payload.NewName))
+	enc.WriteByte(codecVersion)
+	enc.WriteString(payload.Name)
+	enc.WriteString(payload.NewName)
+	return detachPayload(enc), nil
+}
+
+func decodeCollectionRenamePayloadBinary(data []byte) (collectionRenamePayload, error) {
+	dec := &util.BinaryDecoder{Data: data}
+	if err := dec.ExpectVersion(); err != nil {
+		return collectionRenamePayload{}, err
+	}
+	name, err := dec.ReadString()
+	if err != nil {
+		return collectionRenamePayload{}, err
+	}
+	newName, err := dec.ReadString()
+	if err != nil {
+		return collectionRenamePayload{}, err
+	}
+	return collectionRenamePayload{Name: name, NewName: newName}, nil
+}
+
+func encodeCatalogPayloadBinary(catalog []byte) (encodedPayload, error) {
+	enc := util.AcquireBinaryEncoder(1 + 4 + len(catalog))
+	enc.WriteByte(codecVersion)
+	enc.WriteBytes(catalog)
+	return detachPayload(enc), nil
+}
+
+func decodeCatalogPayloadBinary(data []byte) ([]byte, error) {
+	dec := &util.BinaryDecoder{Data: data}
+	if err := dec.ExpectVersion(); err != nil {
+		return nil, err
+	}
+	return dec.ReadBytes()
+}
+
 func encodeCollectionStatsPayloadBinary(payload collectionStatsPayload) (encodedPayload, error) {
 	enc := util.AcquireBinaryEncoder(1 + 4 + len(payload.Name) + 4 + len(payload.Stats))
 	enc.WriteByte(codecVersion)
@@ -444,6 +540,59 @@ func decodeEdgeKindCreatePayload(data []byte) (edgeKindCreatePayload, error) {
 	return edgeKindCreatePayload{Name: name, Kind: kind, Undirected: undirected}, nil
 }
 
+type viewPutPayload struct {
+	Name       string
+	Definition storage.ViewDefinition
+}
+
+func encodeViewPutPayload(p viewPutPayload) encodedPayload {
+	enc := util.AcquireBinaryEncoder(1 + 4 + len(p.Name) + 4 + len(p.Definition.Query) + 1)
+	enc.WriteByte(codecVersion)
+	enc.WriteString(p.Name)
+	enc.WriteString(p.Definition.Query)
+	if p.Definition.Materialized {
+		_ = enc.WriteByte(1)
+	} else {
+		_ = enc.WriteByte(0)
+	}
+	return detachPayload(enc)
+}
+
+func decodeViewPutPayload(data []byte) (viewPutPayload, error) {
+	dec := &util.BinaryDecoder{Data: data}
+	if err := dec.ExpectVersion(); err != nil {
+		return viewPutPayload{}, err
+	}
+	name, err := dec.ReadString()
+	if err != nil {
+		return viewPutPayload{}, err
+	}
+	query, err := dec.ReadString()
+	if err != nil {
+		return viewPutPayload{}, err
+	}
+	materialized, err := dec.ReadByte()
+	if err != nil {
+		return viewPutPayload{}, err
+	}
+	return viewPutPayload{Name: name, Definition: storage.ViewDefinition{Query: query, Materialized: materialized != 0}}, nil
+}
+
+func encodeViewDropPayload(name string) encodedPayload {
+	enc := util.AcquireBinaryEncoder(1 + 4 + len(name))
+	enc.WriteByte(codecVersion)
+	enc.WriteString(name)
+	return detachPayload(enc)
+}
+
+func decodeViewDropPayload(data []byte) (string, error) {
+	dec := &util.BinaryDecoder{Data: data}
+	if err := dec.ExpectVersion(); err != nil {
+		return "", err
+	}
+	return dec.ReadString()
+}
+
 func encodeGraphVertexLabelPayload(p graphVertexLabelPayload) encodedPayload {
 	enc := util.AcquireBinaryEncoder(1 + 8 + 4 + len(p.Label))
 	enc.WriteByte(codecVersion)
@@ -657,7 +806,9 @@ func writeCollectionConfig(enc *util.BinaryEncoder, config storage.CollectionCon
 // inside the existing optional config block. It carries declarations only;
 // metadata posting lists remain derived from records and are rebuilt on load.
 func encodeCollectionDeclarations(config storage.CollectionConfig) []byte {
-	if len(config.MetadataSchema) == 0 && len(config.IndexedFields) == 0 && len(config.SQLIndexes) == 0 {
+	if len(config.MetadataSchema) == 0 && len(config.IndexedFields) == 0 && len(config.SQLIndexes) == 0 &&
+		len(config.SparseVectors) == 0 && len(config.FullTextIndexes) == 0 && len(config.VectorSpaces) == 0 &&
+		config.GeneratedVector == nil && config.TTLField == "" && config.DefaultTTL == 0 && !hasIndexDeclaration(config) {
 		return nil
 	}
 
@@ -694,6 +845,59 @@ func encodeCollectionDeclarations(config storage.CollectionConfig) []byte {
 			enc.WriteString(column)
 		}
 	}
+	if len(config.SparseVectors) > 0 || len(config.FullTextIndexes) > 0 || len(config.VectorSpaces) > 0 || config.GeneratedVector != nil || writesTTLSection(config) {
+		sparseFields := make([]string, 0, len(config.SparseVectors))
+		for field := range config.SparseVectors {
+			sparseFields = append(sparseFields, field)
+		}
+		sort.Strings(sparseFields)
+		enc.WriteUint32(uint32(len(sparseFields)))
+		for _, field := range sparseFields {
+			enc.WriteString(field)
+			enc.WriteUint32(uint32(config.SparseVectors[field]))
+		}
+	}
+	if len(config.FullTextIndexes) > 0 || len(config.VectorSpaces) > 0 || config.GeneratedVector != nil || writesTTLSection(config) {
+		enc.WriteUint32(uint32(len(config.FullTextIndexes)))
+		for _, index := range config.FullTextIndexes {
+			enc.WriteString(index.Name)
+			enc.WriteString(index.Field)
+			enc.WriteString(index.Config)
+			enc.WriteFloat64(index.K1)
+			enc.WriteFloat64(index.B)
+		}
+	}
+	if len(config.VectorSpaces) > 0 || config.GeneratedVector != nil || writesTTLSection(config) {
+		enc.WriteUint32(uint32(len(config.VectorSpaces)))
+		for _, space := range config.VectorSpaces {
+			enc.WriteString(space.Name)
+			enc.WriteUint32(uint32(space.Dimension))
+			enc.WriteUint32(uint32(space.Metric))
+			enc.WriteUint32(uint32(space.M))
+			enc.WriteUint32(uint32(space.EfConstruction))
+			enc.WriteUint32(uint32(space.EfSearch))
+		}
+	}
+	if generated := config.GeneratedVector; generated != nil {
+		enc.WriteString(generated.Column)
+		enc.WriteString(generated.Expression)
+		enc.WriteString(generated.Model)
+	} else if writesTTLSection(config) {
+		// An empty column marks an absent generated vector so the TTL
+		// section that follows stays addressable.
+		enc.WriteString("")
+		enc.WriteString("")
+		enc.WriteString("")
+	}
+	if writesTTLSection(config) {
+		enc.WriteString(config.TTLField)
+		enc.WriteUint64(uint64(config.DefaultTTL))
+	}
+	if hasIndexDeclaration(config) {
+		enc.WriteFloat64(config.PruneAlpha)
+		enc.WriteString(config.IndexDirectory)
+		enc.WriteBool(config.Int8Construction)
+	}
 	data := append([]byte(nil), enc.Bytes()...)
 	util.ReleaseBinaryEncoder(enc)
 	return data
@@ -718,17 +922,79 @@ func estimateCollectionDeclarationsSize(config storage.CollectionConfig) int {
 			size += 4 + len(column)
 		}
 	}
+	if len(config.SparseVectors) > 0 || len(config.FullTextIndexes) > 0 || len(config.VectorSpaces) > 0 || config.GeneratedVector != nil || writesTTLSection(config) {
+		size += 4
+		for field := range config.SparseVectors {
+			size += 4 + len(field) + 4
+		}
+	}
+	if len(config.FullTextIndexes) > 0 || len(config.VectorSpaces) > 0 || config.GeneratedVector != nil || writesTTLSection(config) {
+		size += 4
+		for _, index := range config.FullTextIndexes {
+			size += 4 + len(index.Name) + 4 + len(index.Field) + 4 + len(index.Config) + 8 + 8
+		}
+	}
+	if len(config.VectorSpaces) > 0 || config.GeneratedVector != nil || writesTTLSection(config) {
+		size += 4
+		for _, space := range config.VectorSpaces {
+			size += 4 + len(space.Name) + 5*4
+		}
+	}
+	if generated := config.GeneratedVector; generated != nil {
+		size += 4 + len(generated.Column) + 4 + len(generated.Expression) + 4 + len(generated.Model)
+	} else if writesTTLSection(config) {
+		size += 3 * 4
+	}
+	if writesTTLSection(config) {
+		size += 4 + len(config.TTLField) + 8
+	}
+	if hasIndexDeclaration(config) {
+		size += 8 + 4 + len(config.IndexDirectory) + 1
+	}
 	return size
 }
 
-func decodeCollectionDeclarations(data []byte) (map[string]uint8, []string, []storage.SQLIndexDefinition, []string, error) {
+func hasTTLDeclaration(config storage.CollectionConfig) bool {
+	return config.TTLField != "" || config.DefaultTTL != 0
+}
+
+// writesTTLSection reports whether the TTL section is written: for a TTL
+// declaration, or empty so that the index section after it stays
+// addressable.
+func writesTTLSection(config storage.CollectionConfig) bool {
+	return hasTTLDeclaration(config) || hasIndexDeclaration(config)
+}
+
+func hasIndexDeclaration(config storage.CollectionConfig) bool {
+	return config.PruneAlpha != 0 || config.IndexDirectory != "" || config.Int8Construction
+}
+
+// collectionDeclarations is the decoded form of the optional declaration
+// blob. Sections appended by newer writers are zero-valued when absent.
+type collectionDeclarations struct {
+	metadataSchema   map[string]uint8
+	indexedFields    []string
+	sqlIndexes       []storage.SQLIndexDefinition
+	sqlIndexedFields []string
+	sparseVectors    map[string]int
+	fullTextIndexes  []storage.FullTextIndexDefinition
+	vectorSpaces     []storage.VectorSpaceDefinition
+	generatedVector  *storage.GeneratedVectorDefinition
+	ttlField         string
+	defaultTTL       time.Duration
+	pruneAlpha       float64
+	indexDirectory   string
+	int8Construction bool
+}
+
+func decodeCollectionDeclarations(data []byte) (collectionDeclarations, error) {
 	if len(data) == 0 {
-		return nil, nil, nil, nil, nil
+		return collectionDeclarations{}, nil
 	}
 	dec := &util.BinaryDecoder{Data: data}
 	schemaCount, err := dec.ReadUint32()
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return collectionDeclarations{}, err
 	}
 	var schema map[string]uint8
 	if schemaCount > 0 {
@@ -737,17 +1003,17 @@ func decodeCollectionDeclarations(data []byte) (map[string]uint8, []string, []st
 	for i := uint32(0); i < schemaCount; i++ {
 		field, err := dec.ReadString()
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return collectionDeclarations{}, err
 		}
 		fieldType, err := dec.ReadByte()
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return collectionDeclarations{}, err
 		}
 		schema[field] = fieldType
 	}
 	indexedCount, err := dec.ReadUint32()
 	if err != nil {
-		return nil, nil, nil, nil, err
+		return collectionDeclarations{}, err
 	}
 	var indexed []string
 	if indexedCount > 0 {
@@ -756,7 +1022,7 @@ func decodeCollectionDeclarations(data []byte) (map[string]uint8, []string, []st
 	for i := uint32(0); i < indexedCount; i++ {
 		field, err := dec.ReadString()
 		if err != nil {
-			return nil, nil, nil, nil, err
+			return collectionDeclarations{}, err
 		}
 		indexed = append(indexed, field)
 	}
@@ -767,7 +1033,7 @@ func decodeCollectionDeclarations(data []byte) (map[string]uint8, []string, []st
 	if dec.Off < len(dec.Data) {
 		sqlIndexedCount, readErr := dec.ReadUint32()
 		if readErr != nil {
-			return nil, nil, nil, nil, readErr
+			return collectionDeclarations{}, readErr
 		}
 		if sqlIndexedCount > 0 {
 			sqlIndexedFields = make([]string, 0, sqlIndexedCount)
@@ -775,13 +1041,13 @@ func decodeCollectionDeclarations(data []byte) (map[string]uint8, []string, []st
 		for i := uint32(0); i < sqlIndexedCount; i++ {
 			field, readErr := dec.ReadString()
 			if readErr != nil {
-				return nil, nil, nil, nil, readErr
+				return collectionDeclarations{}, readErr
 			}
 			sqlIndexedFields = append(sqlIndexedFields, field)
 		}
 		indexCount, readErr := dec.ReadUint32()
 		if readErr != nil {
-			return nil, nil, nil, nil, readErr
+			return collectionDeclarations{}, readErr
 		}
 		if indexCount > 0 {
 			sqlIndexes = make([]storage.SQLIndexDefinition, 0, indexCount)
@@ -789,15 +1055,15 @@ func decodeCollectionDeclarations(data []byte) (map[string]uint8, []string, []st
 		for i := uint32(0); i < indexCount; i++ {
 			name, readErr := dec.ReadString()
 			if readErr != nil {
-				return nil, nil, nil, nil, readErr
+				return collectionDeclarations{}, readErr
 			}
 			unique, readErr := dec.ReadBool()
 			if readErr != nil {
-				return nil, nil, nil, nil, readErr
+				return collectionDeclarations{}, readErr
 			}
 			columnCount, readErr := dec.ReadUint32()
 			if readErr != nil {
-				return nil, nil, nil, nil, readErr
+				return collectionDeclarations{}, readErr
 			}
 			definition := storage.SQLIndexDefinition{Name: name, Unique: unique}
 			if columnCount > 0 {
@@ -806,17 +1072,163 @@ func decodeCollectionDeclarations(data []byte) (map[string]uint8, []string, []st
 			for j := uint32(0); j < columnCount; j++ {
 				column, readErr := dec.ReadString()
 				if readErr != nil {
-					return nil, nil, nil, nil, readErr
+					return collectionDeclarations{}, readErr
 				}
 				definition.Columns = append(definition.Columns, column)
 			}
 			sqlIndexes = append(sqlIndexes, definition)
 		}
 	}
-	if dec.Off != len(dec.Data) {
-		return nil, nil, nil, nil, fmt.Errorf("trailing bytes in collection declarations: %d", len(dec.Data)-dec.Off)
+	var sparseVectors map[string]int
+	// Sparse-vector declarations were appended after the SQL index section
+	// and are present only when the collection declares one.
+	if dec.Off < len(dec.Data) {
+		sparseCount, readErr := dec.ReadUint32()
+		if readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		sparseVectors = make(map[string]int, sparseCount)
+		for i := uint32(0); i < sparseCount; i++ {
+			field, readErr := dec.ReadString()
+			if readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			dimension, readErr := dec.ReadUint32()
+			if readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			sparseVectors[field] = int(dimension)
+		}
 	}
-	return schema, indexed, sqlIndexes, sqlIndexedFields, nil
+	var fullTextIndexes []storage.FullTextIndexDefinition
+	// Full-text index declarations follow the sparse-vector section, which is
+	// always written (possibly empty) when this section is present.
+	if dec.Off < len(dec.Data) {
+		ftsCount, readErr := dec.ReadUint32()
+		if readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		fullTextIndexes = make([]storage.FullTextIndexDefinition, 0, ftsCount)
+		for i := uint32(0); i < ftsCount; i++ {
+			var definition storage.FullTextIndexDefinition
+			if definition.Name, readErr = dec.ReadString(); readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			if definition.Field, readErr = dec.ReadString(); readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			if definition.Config, readErr = dec.ReadString(); readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			if definition.K1, readErr = dec.ReadFloat64(); readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			if definition.B, readErr = dec.ReadFloat64(); readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			fullTextIndexes = append(fullTextIndexes, definition)
+		}
+	}
+	var vectorSpaces []storage.VectorSpaceDefinition
+	// Vector space declarations follow the full-text section, which is
+	// always written (possibly empty) when this section is present.
+	if dec.Off < len(dec.Data) {
+		spaceCount, readErr := dec.ReadUint32()
+		if readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		vectorSpaces = make([]storage.VectorSpaceDefinition, 0, spaceCount)
+		for i := uint32(0); i < spaceCount; i++ {
+			var definition storage.VectorSpaceDefinition
+			if definition.Name, readErr = dec.ReadString(); readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+			var values [5]uint32
+			for j := range values {
+				if values[j], readErr = dec.ReadUint32(); readErr != nil {
+					return collectionDeclarations{}, readErr
+				}
+			}
+			definition.Dimension = int(values[0])
+			definition.Metric = int(values[1])
+			definition.M = int(values[2])
+			definition.EfConstruction = int(values[3])
+			definition.EfSearch = int(values[4])
+			vectorSpaces = append(vectorSpaces, definition)
+		}
+	}
+	var generatedVector *storage.GeneratedVectorDefinition
+	// The generated vector declaration follows the vector space section,
+	// which is always written (possibly empty) when this section is present.
+	if dec.Off < len(dec.Data) {
+		var definition storage.GeneratedVectorDefinition
+		var readErr error
+		if definition.Column, readErr = dec.ReadString(); readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		if definition.Expression, readErr = dec.ReadString(); readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		if definition.Model, readErr = dec.ReadString(); readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		if definition.Column != "" {
+			generatedVector = &definition
+		}
+	}
+	var ttlField string
+	var defaultTTL time.Duration
+	// The TTL declaration follows the generated vector section, which is
+	// written with an empty column when only the TTL is declared.
+	if dec.Off < len(dec.Data) {
+		var readErr error
+		if ttlField, readErr = dec.ReadString(); readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		ttl, readErr := dec.ReadUint64()
+		if readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		defaultTTL = time.Duration(ttl)
+	}
+	var pruneAlpha float64
+	var indexDirectory string
+	var int8Construction bool
+	// The index declaration follows the TTL section, which is written with
+	// zero values when only the index parameters are declared.
+	if dec.Off < len(dec.Data) {
+		var readErr error
+		if pruneAlpha, readErr = dec.ReadFloat64(); readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		if indexDirectory, readErr = dec.ReadString(); readErr != nil {
+			return collectionDeclarations{}, readErr
+		}
+		// Older writers end the index section after the directory.
+		if dec.Off < len(dec.Data) {
+			if int8Construction, readErr = dec.ReadBool(); readErr != nil {
+				return collectionDeclarations{}, readErr
+			}
+		}
+	}
+	if dec.Off != len(dec.Data) {
+		return collectionDeclarations{}, fmt.Errorf("trailing bytes in collection declarations: %d", len(dec.Data)-dec.Off)
+	}
+	return collectionDeclarations{
+		metadataSchema:   schema,
+		indexedFields:    indexed,
+		sqlIndexes:       sqlIndexes,
+		sqlIndexedFields: sqlIndexedFields,
+		sparseVectors:    sparseVectors,
+		fullTextIndexes:  fullTextIndexes,
+		vectorSpaces:     vectorSpaces,
+		generatedVector:  generatedVector,
+		ttlField:         ttlField,
+		defaultTTL:       defaultTTL,
+		pruneAlpha:       pruneAlpha,
+		indexDirectory:   indexDirectory,
+		int8Construction: int8Construction,
+	}, nil
 }
 
 func writeCollection(enc *util.BinaryEncoder, collection *persistedCollection) error {
@@ -877,7 +1289,10 @@ func writeCollection(enc *util.BinaryEncoder, collection *persistedCollection) e
 }
 
 func estimateStateSize(state *persistedState) int {
-	size := 1 + 8 + 8 + 4 + len(state.TombstonedGraphNodeIDs)*8 + 4 + len(state.CommitCatalog)*16 + 8 // version + IDs + tombstones + catalog + collection count
+	size := 1 + 8 + 8 + 4 + len(state.TombstonedGraphNodeIDs)*8 + 4 + len(state.CommitCatalog)*16 + 8 + 4 + 8 // version + IDs + tombstones + catalog + WAL floor + view count + collection count
+	for name, view := range state.Views {
+		size += 4 + len(name) + 4 + len(view.Query) + 1
+	}
 	for name, collection := range state.Collections {
 		size += 4 + len(name)
 		size += estimateCollectionSize(collection)
@@ -902,7 +1317,7 @@ func estimateCollectionSize(collection *persistedCollection) int {
 func estimateCollectionConfigSize(config storage.CollectionConfig) int {
 	size := 4 + 4 + 4 + 4 + 4 + 4 + 8 + 4 + 4 + len(config.RawVectorStore) + 4 + 4 + 4
 	if config.Version >= 2 {
-		size += 4 + 4 + len(config.CostModelStats) // block length + stats bytes length + payload
+		size += 4 + 4 + 4 + len(config.CostModelStats) // block length + ID map capacity + stats bytes length + payload
 		if declarations := encodeCollectionDeclarations(config); len(declarations) > 0 {
 			size += 4 + len(declarations)
 		}
@@ -967,10 +1382,7 @@ func readCollectionConfig(dec *util.BinaryDecoder) (storage.CollectionConfig, er
 	var nProbes uint32
 	var idMapCapacity uint32
 	var costModelStats []byte
-	var metadataSchema map[string]uint8
-	var indexedFields []string
-	var sqlIndexes []storage.SQLIndexDefinition
-	var sqlIndexedFields []string
+	var declarations collectionDeclarations
 	var graphEnabled bool
 	var graphNamespace string
 
@@ -1014,7 +1426,7 @@ func readCollectionConfig(dec *util.BinaryDecoder) (storage.CollectionConfig, er
 				if readErr != nil {
 					return storage.CollectionConfig{}, readErr
 				}
-				metadataSchema, indexedFields, sqlIndexes, sqlIndexedFields, readErr = decodeCollectionDeclarations(declarationBytes)
+				declarations, readErr = decodeCollectionDeclarations(declarationBytes)
 				if readErr != nil {
 					return storage.CollectionConfig{}, fmt.Errorf("decode collection declarations: %w", readErr)
 				}
@@ -1066,12 +1478,21 @@ func readCollectionConfig(dec *util.BinaryDecoder) (storage.CollectionConfig, er
 		RawStoreCap:      int(rawStoreCap),
 		IDMapCapacity:    int(idMapCapacity),
 		CostModelStats:   costModelStats,
-		MetadataSchema:   metadataSchema,
-		IndexedFields:    indexedFields,
-		SQLIndexes:       sqlIndexes,
-		SQLIndexedFields: sqlIndexedFields,
+		MetadataSchema:   declarations.metadataSchema,
+		IndexedFields:    declarations.indexedFields,
+		SQLIndexes:       declarations.sqlIndexes,
+		SQLIndexedFields: declarations.sqlIndexedFields,
 		GraphEnabled:     graphEnabled,
 		GraphNamespace:   graphNamespace,
+		SparseVectors:    declarations.sparseVectors,
+		FullTextIndexes:  declarations.fullTextIndexes,
+		VectorSpaces:     declarations.vectorSpaces,
+		GeneratedVector:  declarations.generatedVector,
+		TTLField:         declarations.ttlField,
+		DefaultTTL:       declarations.defaultTTL,
+		PruneAlpha:       declarations.pruneAlpha,
+		IndexDirectory:   declarations.indexDirectory,
+		Int8Construction: declarations.int8Construction,
 	}, nil
 }
 
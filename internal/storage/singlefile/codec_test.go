@@ -2,7 +2,9 @@ package singlefile
 
 import (
 	"testing"
+	"time"
 
+	"github.com/xDarkicex/libravdb/internal/storage"
 	"github.com/xDarkicex/libravdb/internal/util"
 )
 
@@ -65,3 +67,251 @@ func TestMetadataCodecAcceptsAllIntegerWidths(t *testing.T) {
 		t.Fatalf("slice decoded as %#v, want [int64(7), uint64(9)]", slice)
 	}
 }
+
+func TestCollectionConfigRoundTripsSparseVectorDeclarations(t *testing.T) {
+	config := storage.CollectionConfig{
+		Dimension:      4,
+		Version:        2,
+		IndexedFields:  []string{"tenant"},
+		SparseVectors:  map[string]int{"terms": 30522, "bigrams": 1 << 20},
+		GraphEnabled:   true,
+		GraphNamespace: "default",
+	}
+	enc := util.AcquireBinaryEncoder(estimateCollectionConfigSize(config))
+	if err := writeCollectionConfig(enc, config); err != nil {
+		t.Fatalf("writeCollectionConfig() error = %v", err)
+	}
+	encoded := enc.DetachBytes()
+	util.ReleaseBinaryEncoder(enc)
+
+	dec := &util.BinaryDecoder{Data: encoded}
+	got, err := readCollectionConfig(dec)
+	if err != nil {
+		t.Fatalf("readCollectionConfig() error = %v", err)
+	}
+	if dec.Off != len(encoded) {
+		t.Fatalf("decoder consumed %d of %d bytes", dec.Off, len(encoded))
+	}
+	if len(got.SparseVectors) != 2 || got.SparseVectors["terms"] != 30522 || got.SparseVectors["bigrams"] != 1<<20 {
+		t.Fatalf("SparseVectors = %#v", got.SparseVectors)
+	}
+	if len(got.IndexedFields) != 1 || got.IndexedFields[0] != "tenant" {
+		t.Fatalf("IndexedFields = %#v", got.IndexedFields)
+	}
+	if !got.GraphEnabled || got.GraphNamespace != "default" {
+		t.Fatalf("graph declaration lost: enabled=%v namespace=%q", got.GraphEnabled, got.GraphNamespace)
+	}
+}
+
+func TestCollectionConfigRoundTripsFullTextIndexDeclarations(t *testing.T) {
+	// Full-text declarations without sparse vectors still emit the (empty)
+	// sparse section so the decoder can find the trailing full-text section.
+	config := storage.CollectionConfig{
+		Dimension: 4,
+		Version:   2,
+		FullTextIndexes: []storage.FullTextIndexDefinition{
+			{Name: "docs_body_fts", Field: "body", Config: "english", K1: 1.2, B: 0.75},
+			{Name: "docs_title_fts", Field: "title", Config: "simple", K1: 2, B: 0},
+		},
+	}
+	enc := util.AcquireBinaryEncoder(estimateCollectionConfigSize(config))
+	if err := writeCollectionConfig(enc, config); err != nil {
+		t.Fatalf("writeCollectionConfig() error = %v", err)
+	}
+	encoded := enc.DetachBytes()
+	util.ReleaseBinaryEncoder(enc)
+	if estimate := estimateCollectionConfigSize(config); estimate < len(encoded) {
+		t.Fatalf("estimate %d is smaller than the encoded size %d", estimate, len(encoded))
+	}
+
+	dec := &util.BinaryDecoder{Data: encoded}
+	got, err := readCollectionConfig(dec)
+	if err != nil {
+		t.Fatalf("readCollectionConfig() error = %v", err)
+	}
+	if dec.Off != len(encoded) {
+		t.Fatalf("decoder consumed %d of %d bytes", dec.Off, len(encoded))
+	}
+	if len(got.SparseVectors) != 0 {
+		t.Fatalf("SparseVectors = %#v, want none", got.SparseVectors)
+	}
+	if len(got.FullTextIndexes) != 2 {
+		t.Fatalf("FullTextIndexes = %#v", got.FullTextIndexes)
+	}
+	for i, want := range config.FullTextIndexes {
+		if got.FullTextIndexes[i] != want {
+			t.Fatalf("FullTextIndexes[%d] = %#v, want %#v", i, got.FullTextIndexes[i], want)
+		}
+	}
+}
+
+func TestCollectionConfigRoundTripsVectorSpaceDeclarations(t *testing.T) {
+	// Vector spaces alone still emit the (empty) sparse and full-text
+	// sections ahead of their own.
+	config := storage.CollectionConfig{
+		Dimension: 4,
+		Version:   2,
+		VectorSpaces: []storage.VectorSpaceDefinition{
+			{Name: "title", Dimension: 3, Metric: 2, M: 16, EfConstruction: 100, EfSearch: 64},
+			{Name: "body", Dimension: 8, Metric: 0, M: 32, EfConstruction: 200, EfSearch: 200},
+		},
+	}
+	enc := util.AcquireBinaryEncoder(estimateCollectionConfigSize(config))
+	if err := writeCollectionConfig(enc, config); err != nil {
+		t.Fatalf("writeCollectionConfig() error = %v", err)
+	}
+	encoded := enc.DetachBytes()
+	util.ReleaseBinaryEncoder(enc)
+	if estimate := estimateCollectionConfigSize(config); estimate < len(encoded) {
+		t.Fatalf("estimate %d is smaller than the encoded size %d", estimate, len(encoded))
+	}
+
+	dec := &util.BinaryDecoder{Data: encoded}
+	got, err := readCollectionConfig(dec)
+	if err != nil {
+		t.Fatalf("readCollectionConfig() error = %v", err)
+	}
+	if dec.Off != len(encoded) {
+		t.Fatalf("decoder consumed %d of %d bytes", dec.Off, len(encoded))
+	}
+	if len(got.SparseVectors) != 0 || len(got.FullTextIndexes) != 0 {
+		t.Fatalf("unexpected sparse %#v or full-text %#v declarations", got.SparseVectors, got.FullTextIndexes)
+	}
+	if len(got.VectorSpaces) != 2 {
+		t.Fatalf("VectorSpaces = %#v", got.VectorSpaces)
+	}
+	for i, want := range config.VectorSpaces {
+		if got.VectorSpaces[i] != want {
+			t.Fatalf("VectorSpaces[%d] = %#v, want %#v", i, got.VectorSpaces[i], want)
+		}
+	}
+}
+
+func TestCollectionConfigRoundTripsGeneratedVectorDeclaration(t *testing.T) {
+	config := storage.CollectionConfig{
+		Dimension: 8,
+		Version:   2,
+		GeneratedVector: &storage.GeneratedVectorDefinition{
+			Column:     "embedding",
+			Expression: "title || ' ' || body",
+			Model:      "mini",
+		},
+	}
+	enc := util.AcquireBinaryEncoder(estimateCollectionConfigSize(config))
+	if err := writeCollectionConfig(enc, config); err != nil {
+		t.Fatalf("writeCollectionConfig() error = %v", err)
+	}
+	encoded := enc.DetachBytes()
+	util.ReleaseBinaryEncoder(enc)
+	if estimate := estimateCollectionConfigSize(config); estimate < len(encoded) {
+		t.Fatalf("estimate %d is smaller than the encoded size %d", estimate, len(encoded))
+	}
+
+	dec := &util.BinaryDecoder{Data: encoded}
+	got, err := readCollectionConfig(dec)
+	if err != nil {
+		t.Fatalf("readCollectionConfig() error = %v", err)
+	}
+	if dec.Off != len(encoded) {
+		t.Fatalf("decoder consumed %d of %d bytes", dec.Off, len(encoded))
+	}
+	if len(got.VectorSpaces) != 0 {
+		t.Fatalf("VectorSpaces = %#v, want none", got.VectorSpaces)
+	}
+	if got.GeneratedVector == nil || *got.GeneratedVector != *config.GeneratedVector {
+		t.Fatalf("GeneratedVector = %#v, want %#v", got.GeneratedVector, config.GeneratedVector)
+	}
+}
+
+func TestCollectionConfigRoundTripsTTLDeclaration(t *testing.T) {
+	config := storage.CollectionConfig{
+		Dimension:  8,
+		Version:    2,
+		TTLField:   "expires_at",
+		DefaultTTL: 90 * time.Minute,
+	}
+	enc := util.AcquireBinaryEncoder(estimateCollectionConfigSize(config))
+	if err := writeCollectionConfig(enc, config); err != nil {
+		t.Fatalf("writeCollectionConfig() error = %v", err)
+	}
+	encoded := enc.DetachBytes()
+	util.ReleaseBinaryEncoder(enc)
+	if estimate := estimateCollectionConfigSize(config); estimate < len(encoded) {
+		t.Fatalf("estimate %d is smaller than the encoded size %d", estimate, len(encoded))
+	}
+
+	dec := &util.BinaryDecoder{Data: encoded}
+	got, err := readCollectionConfig(dec)
+	if err != nil {
+		t.Fatalf("readCollectionConfig() error = %v", err)
+	}
+	if dec.Off != len(encoded) {
+		t.Fatalf("decoder consumed %d of %d bytes", dec.Off, len(encoded))
+	}
+	if got.GeneratedVector != nil {
+		t.Fatalf("GeneratedVector = %#v, want nil", got.GeneratedVector)
+	}
+	if got.TTLField != config.TTLField || got.DefaultTTL != config.DefaultTTL {
+		t.Fatalf("TTL = (%q, %v), want (%q, %v)", got.TTLField, got.DefaultTTL, config.TTLField, config.DefaultTTL)
+	}
+}
+
+func TestCollectionConfigRoundTripsIndexDeclaration(t *testing.T) {
+	config := storage.CollectionConfig{
+		Dimension:      8,
+		Version:        2,
+		IndexType:      4,
+		PruneAlpha:     1.2,
+		IndexDirectory: "/var/lib/libravdb/nodes",
+	}
+	enc := util.AcquireBinaryEncoder(estimateCollectionConfigSize(config))
+	if err := writeCollectionConfig(enc, config); err != nil {
+		t.Fatalf("writeCollectionConfig() error = %v", err)
+	}
+	encoded := enc.DetachBytes()
+	util.ReleaseBinaryEncoder(enc)
+	if estimate := estimateCollectionConfigSize(config); estimate < len(encoded) {
+		t.Fatalf("estimate %d is smaller than the encoded size %d", estimate, len(encoded))
+	}
+
+	dec := &util.BinaryDecoder{Data: encoded}
+	got, err := readCollectionConfig(dec)
+	if err != nil {
+		t.Fatalf("readCollectionConfig() error = %v", err)
+	}
+	if dec.Off != len(encoded) {
+		t.Fatalf("decoder consumed %d of %d bytes", dec.Off, len(encoded))
+	}
+	if got.TTLField != "" || got.DefaultTTL != 0 {
+		t.Fatalf("TTL = (%q, %v), want none", got.TTLField, got.DefaultTTL)
+	}
+	if got.PruneAlpha != config.PruneAlpha || got.IndexDirectory != config.IndexDirectory {
+		t.Fatalf("index declaration = (%v, %q), want (%v, %q)", got.PruneAlpha, got.IndexDirectory, config.PruneAlpha, config.IndexDirectory)
+	}
+}
+
+func TestCollectionConfigRoundTripsInt8Construction(t *testing.T) {
+	config := storage.CollectionConfig{
+		Dimension:        8,
+		Version:          2,
+		Int8Construction: true,
+	}
+	enc := util.AcquireBinaryEncoder(estimateCollectionConfigSize(config))
+	if err := writeCollectionConfig(enc, config); err != nil {
+		t.Fatalf("writeCollectionConfig() error = %v", err)
+	}
+	encoded := enc.DetachBytes()
+	util.ReleaseBinaryEncoder(enc)
+	if estimate := estimateCollectionConfigSize(config); estimate < len(encoded) {
+		t.Fatalf("estimate %d is smaller than the encoded size %d", estimate, len(encoded))
+	}
+
+	dec := &util.BinaryDecoder{Data: encoded}
+	got, err := readCollectionConfig(dec)
+	if err != nil {
+		t.Fatalf("readCollectionConfig() error = %v", err)
+	}
+	if !got.Int8Construction || got.PruneAlpha != 0 || got.IndexDirectory != "" {
+		t.Fatalf("index declaration = (%v, %v, %q), want int8 construction only", got.Int8Construction, got.PruneAlpha, got.IndexDirectory)
+	}
+}
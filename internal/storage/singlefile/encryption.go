@@ -0,0 +1,437 @@
+package singlefile
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+)
+
+// featureEncrypted is the header FeatureFlags bit of a file whose chunks and
+// catalog page are sealed with AES-GCM. The header and metapages stay
+// plaintext: they hold offsets, lengths and LSNs, never record data.
+const featureEncrypted uint32 = 1 << 0
+
+const (
+	// sealedPrefix is the key ID and nonce stored in front of the ciphertext.
+	sealedPrefix = 4 + 12
+	// sealedOverhead is what sealing adds to a plaintext: prefix and GCM tag.
+	sealedOverhead = sealedPrefix + 16
+)
+
+// catalogPageAAD binds a sealed catalog page to its role, since it has no
+// chunk header to authenticate.
+var catalogPageAAD = []byte("libravdb catalog page")
+
+// chunkAADSize is the length of a chunk's associated data: its header up to
+// the checksum, the file ID and the chunk's offset.
+const chunkAADSize = 12 + 8 + 8
+
+var (
+	// ErrEncryptionKeyRequired reports an encrypted file opened without
+	// WithEncryption.
+	ErrEncryptionKeyRequired = errors.New("database file is encrypted; an encryption key is required")
+	// ErrNotEncrypted reports a plaintext file opened with WithEncryption.
+	ErrNotEncrypted = errors.New("database file is not encrypted")
+	// ErrDecryptionFailed reports sealed data that does not authenticate
+	// under the provided keys: a wrong key or a modified file.
+	ErrDecryptionFailed = errors.New("decryption failed: wrong key or tampered data")
+)
+
+// KeyProvider supplies AES keys (16, 24 or 32 bytes) for encryption at rest.
+// Every sealed chunk records the ID of the key that sealed it, so keys that
+// were rotated out must stay available through Key until a Vacuum or
+// compaction has rewritten the file under the current key.
+type KeyProvider interface {
+	// CurrentKey returns the key new data is sealed with.
+	CurrentKey() (id uint32, key []byte, err error)
+	// Key returns a key by ID for reading data sealed with it.
+	Key(id uint32) ([]byte, error)
+}
+
+// StaticKey returns a KeyProvider with a single key, ID 1.
+func StaticKey(key []byte) KeyProvider {
+	return staticKey(append([]byte(nil), key...))
+}
+
+type staticKey []byte
+
+func (k staticKey) CurrentKey() (uint32, []byte, error) { return 1, k, nil }
+
+func (k staticKey) Key(id uint32) ([]byte, error) {
+	if id != 1 {
+		return nil, fmt.Errorf("unknown encryption key %d", id)
+	}
+	return k, nil
+}
+
+// WithEncryption encrypts the database file at rest. WAL frames, checkpoint
+// snapshots, index snapshots and the catalog page are sealed with AES-GCM
+// under provider's current key; the GCM tag authenticates each chunk. The
+// current key is read at open and again by Vacuum and compaction, which
+// rewrite the whole file under it, so rotating a key is: make it current in
+// the provider, then Vacuum.
+func WithEncryption(provider KeyProvider) Option {
+	return func(e *Engine) error {
+		if provider == nil {
+			return fmt.Errorf("encryption key provider is nil")
+		}
+		c := &chunkCipher{provider: provider, aeads: make(map[uint32]cipher.AEAD)}
+		if err := c.rotate(); err != nil {
+			return err
+		}
+		e.cipher = c
+		return nil
+	}
+}
+
+// chunkCipher seals and opens chunk payloads. AEADs are cached per key ID.
+// Vacuum and Backup seal outside e.mu, so mu guards the current key too.
+type chunkCipher struct {
+	provider  KeyProvider
+	current   cipher.AEAD
+	aeads     map[uint32]cipher.AEAD
+	mu        sync.Mutex
+	currentID uint32
+}
+
+// rotate reloads the provider's current key for data sealed from now on.
+func (c *chunkCipher) rotate() error {
+	id, key, err := c.provider.CurrentKey()
+	if err != nil {
+		return fmt.Errorf("load current encryption key: %w", err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return fmt.Errorf("encryption key %d: %w", id, err)
+	}
+	c.mu.Lock()
+	c.aeads[id] = aead
+	c.currentID, c.current = id, aead
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *chunkCipher) aead(id uint32) (cipher.AEAD, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if aead, ok := c.aeads[id]; ok {
+		return aead, nil
+	}
+	key, err := c.provider.Key(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: load encryption key %d: %v", ErrDecryptionFailed, id, err)
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, fmt.Errorf("encryption key %d: %w", id, err)
+	}
+	c.aeads[id] = aead
+	return aead, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealInPlace seals the plaintext at buf[sealedPrefix:], filling the prefix
+// and appending the tag. buf must have sealedOverhead-sealedPrefix bytes of
+// spare capacity.
+func (c *chunkCipher) sealInPlace(buf, aad []byte) ([]byte, error) {
+	c.mu.Lock()
+	id, aead := c.currentID, c.current
+	c.mu.Unlock()
+	binary.LittleEndian.PutUint32(buf[0:4], id)
+	nonce := buf[4:sealedPrefix]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	plaintext := buf[sealedPrefix:]
+	sealed := aead.Seal(plaintext[:0], nonce, plaintext, aad)
+	return buf[:sealedPrefix+len(sealed)], nil
+}
+
+// seal returns the sealed concatenation of parts.
+func (c *chunkCipher) seal(aad []byte, parts ...[]byte) ([]byte, error) {
+	n := 0
+	for _, part := range parts {
+		n += len(part)
+	}
+	buf := make([]byte, sealedPrefix, n+sealedOverhead)
+	for _, part := range parts {
+		buf = append(buf, part...)
+	}
+	return c.sealInPlace(buf, aad)
+}
+
+// open authenticates and decrypts sealed, reusing its storage for the
+// plaintext.
+func (c *chunkCipher) open(sealed, aad []byte) ([]byte, error) {
+	if len(sealed) < sealedOverhead {
+		return nil, fmt.Errorf("%w: sealed payload of %d bytes is truncated", ErrDecryptionFailed, len(sealed))
+	}
+	aead, err := c.aead(binary.LittleEndian.Uint32(sealed[0:4]))
+	if err != nil {
+		return nil, err
+	}
+	ciphertext := sealed[sealedPrefix:]
+	plaintext, err := aead.Open(ciphertext[:0], sealed[4:sealedPrefix], ciphertext, aad)
+	if err != nil {
+		return nil, ErrDecryptionFailed
+	}
+	return plaintext, nil
+}
+
+// encodedChunk is a chunk header and its payload, which may be split in two
+// parts to avoid copying an index block behind its entry header.
+type encodedChunk struct {
+	header [16]byte
+	parts  [2][]byte
+}
+
+// chunkAAD returns the associated data of a chunk with header written at
+// offset. Binding the file ID and offset means a sealed chunk only opens
+// where it was written: copied into another database file or moved within
+// this one, it fails authentication like any other modification.
+func (e *Engine) chunkAAD(header []byte, offset uint64) []byte {
+	aad := make([]byte, chunkAADSize)
+	copy(aad, header[:12])
+	binary.LittleEndian.PutUint64(aad[12:20], e.fileID)
+	binary.LittleEndian.PutUint64(aad[20:28], offset)
+	return aad
+}
+
+// encodeChunk frames headerPart and payloadPart as one chunk of kind to be
+// written at offset, sealing them when the file is encrypted. The header
+// checksum always covers the stored bytes so torn writes are told apart from
+// failed authentication.
+func (e *Engine) encodeChunk(kind uint16, offset uint64, headerPart, payloadPart []byte) (encodedChunk, error) {
+	var chunk encodedChunk
+	chunk.parts = [2][]byte{headerPart, payloadPart}
+	payloadLen := len(headerPart) + len(payloadPart)
+	if e.cipher != nil {
+		payloadLen += sealedOverhead
+	}
+	putChunkHeader(chunk.header[:], kind, uint32(payloadLen))
+	if e.cipher != nil {
+		sealed, err := e.cipher.seal(e.chunkAAD(chunk.header[:], offset), headerPart, payloadPart)
+		if err != nil {
+			return chunk, err
+		}
+		chunk.parts = [2][]byte{sealed, nil}
+	}
+	checksum := crc32.Update(0, castagnoli, chunk.parts[0])
+	checksum = crc32.Update(checksum, castagnoli, chunk.parts[1])
+	binary.LittleEndian.PutUint32(chunk.header[12:16], checksum)
+	return chunk, nil
+}
+
+// putChunkHeader writes a chunk header without its checksum.
+func putChunkHeader(dst []byte, kind uint16, payloadLen uint32) {
+	binary.LittleEndian.PutUint32(dst[0:4], chunkMagic)
+	binary.LittleEndian.PutUint16(dst[4:6], kind)
+	binary.LittleEndian.PutUint16(dst[6:8], formatVersion)
+	binary.LittleEndian.PutUint32(dst[8:12], payloadLen)
+}
+
+// size is the number of bytes the chunk occupies in the file.
+func (c *encodedChunk) size() uint64 {
+	return uint64(16 + len(c.parts[0]) + len(c.parts[1]))
+}
+
+func (c *encodedChunk) bytes() []byte {
+	out := make([]byte, 0, c.size())
+	out = append(out, c.header[:]...)
+	out = append(out, c.parts[0]...)
+	return append(out, c.parts[1]...)
+}
+
+func (c *encodedChunk) writeAt(file *os.File, offset int64) error {
+	if err := writeFullAt(file, c.header[:], offset); err != nil {
+		return err
+	}
+	offset += 16
+	for _, part := range c.parts {
+		if len(part) == 0 {
+			continue
+		}
+		if err := writeFullAt(file, part, offset); err != nil {
+			return err
+		}
+		offset += int64(len(part))
+	}
+	return nil
+}
+
+// openChunk returns the plaintext of a checksummed chunk payload whose
+// header was read at offset. Plaintext files return payload as is.
+func (e *Engine) openChunk(offset uint64, header, payload []byte) ([]byte, error) {
+	if e.cipher == nil {
+		return payload, nil
+	}
+	return e.cipher.open(payload, e.chunkAAD(header, offset))
+}
+
+// sealChunks encodes plaintext chunk frames for writing at offset. Plaintext
+// files return frames as is; sealing adds sealedOverhead bytes per chunk.
+func (e *Engine) sealChunks(frames []byte, offset uint64) ([]byte, error) {
+	if e.cipher == nil {
+		return frames, nil
+	}
+	var out []byte
+	for len(frames) >= 16 {
+		end := 16 + int(binary.LittleEndian.Uint32(frames[8:12]))
+		chunk, err := e.encodeChunk(binary.LittleEndian.Uint16(frames[4:6]), offset+uint64(len(out)), nil, frames[16:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk.bytes()...)
+		frames = frames[end:]
+	}
+	return out, nil
+}
+
+// copyTail copies e.file's bytes [from, end) to dst at offset to. An
+// encrypted file's chunks are opened and sealed again for their new offset;
+// resealing keeps each chunk's size, so the copy keeps its layout. Bytes that
+// are not a complete chunk, such as page padding or a torn final append, are
+// copied as they are.
+func (e *Engine) copyTail(dst *os.File, from, end, to int64) error {
+	if e.cipher == nil {
+		if _, err := dst.Seek(to, io.SeekStart); err != nil {
+			return err
+		}
+		_, err := io.Copy(dst, io.NewSectionReader(e.file, from, end-from))
+		return err
+	}
+	for offset := from; offset < end; {
+		var raw []byte
+		var headerBuf [16]byte
+		header := chunkHeader{}
+		if end-offset >= 16 {
+			if _, err := e.file.ReadAt(headerBuf[:], offset); err != nil {
+				return err
+			}
+			header = decodeChunkHeader(headerBuf[:])
+		}
+		chunkEnd := offset + 16 + int64(header.PayloadLen)
+		if header.Magic == chunkMagic && header.PayloadLen <= maxChunkSize && chunkEnd <= end {
+			raw = make([]byte, chunkEnd-offset)
+			if _, err := e.file.ReadAt(raw, offset); err != nil {
+				return err
+			}
+			if crc32.Checksum(raw[16:], castagnoli) == header.Checksum {
+				payload, err := e.openChunk(uint64(offset), raw[:16], raw[16:])
+				if err != nil {
+					return fmt.Errorf("chunk at offset %d: %w", offset, err)
+				}
+				chunk, err := e.encodeChunk(header.Kind, uint64(to+offset-from), nil, payload)
+				if err != nil {
+					return err
+				}
+				raw = chunk.bytes()
+			}
+		} else {
+			next := min(alignUp(offset+1, pageSize), end)
+			raw = make([]byte, next-offset)
+			if _, err := e.file.ReadAt(raw, offset); err != nil {
+				return err
+			}
+		}
+		if err := writeFullAt(dst, raw, to+offset-from); err != nil {
+			return err
+		}
+		offset += int64(len(raw))
+	}
+	return nil
+}
+
+// plaintextChunk reframes an opened chunk payload as a plaintext chunk, the
+// form WAL is shipped in so replicas can seal it under their own key.
+func plaintextChunk(kind uint16, payload []byte) []byte {
+	raw := make([]byte, 16, 16+len(payload))
+	putChunkHeader(raw, kind, uint32(len(payload)))
+	binary.LittleEndian.PutUint32(raw[12:16], crc32.Checksum(payload, castagnoli))
+	return append(raw, payload...)
+}
+
+// catalogAAD returns the associated data of a catalog page published with
+// the metapage of epoch. Binding the file ID and epoch means a catalog page
+// from another database file, or an older one of this file, fails
+// authentication instead of being adopted.
+func (e *Engine) catalogAAD(epoch uint64) []byte {
+	aad := make([]byte, len(catalogPageAAD)+16)
+	n := copy(aad, catalogPageAAD)
+	binary.LittleEndian.PutUint64(aad[n:], e.fileID)
+	binary.LittleEndian.PutUint64(aad[n+8:], epoch)
+	return aad
+}
+
+// encodeCatalogPage lays the catalog out on page 3 for the metapage of
+// epoch. An encrypted page holds the sealed catalog's length followed by the
+// sealed bytes.
+func (e *Engine) encodeCatalogPage(data []byte, epoch uint64) ([]byte, error) {
+	page := make([]byte, pageSize)
+	if e.cipher == nil {
+		copy(page, data)
+		return page, nil
+	}
+	if len(data)+sealedOverhead > pageSize-4 {
+		return nil, fmt.Errorf("catalog of %d bytes does not fit the encrypted catalog page", len(data))
+	}
+	sealed, err := e.cipher.seal(e.catalogAAD(epoch), data)
+	if err != nil {
+		return nil, err
+	}
+	binary.LittleEndian.PutUint32(page[0:4], uint32(len(sealed)))
+	copy(page[4:], sealed)
+	return page, nil
+}
+
+// decodeCatalogPage reverses encodeCatalogPage for a page read by
+// readCatalogPageLocked, accepting only a page sealed for the metapage of
+// epoch. Only the initial metapages, which no checkpoint has written a
+// catalog page for, may go with an empty page.
+func (e *Engine) decodeCatalogPage(page []byte, epoch uint64) ([]byte, error) {
+	if e.cipher == nil {
+		return page, nil
+	}
+	if page == nil {
+		if epoch == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: catalog page missing for metapage epoch %d", ErrDecryptionFailed, epoch)
+	}
+	n := binary.LittleEndian.Uint32(page[0:4])
+	if n < sealedOverhead || n > pageSize-4 {
+		return nil, fmt.Errorf("%w: catalog page length %d", ErrDecryptionFailed, n)
+	}
+	catalog, err := e.cipher.open(page[4:4+n], e.catalogAAD(epoch))
+	if err != nil {
+		return nil, fmt.Errorf("catalog page: %w", err)
+	}
+	return catalog, nil
+}
+
+// checkEncryptionFlag matches the file's encryption flag against the
+// engine's configuration.
+func (e *Engine) checkEncryptionFlag(header *fileHeader) error {
+	encrypted := header.FeatureFlags&featureEncrypted != 0
+	switch {
+	case encrypted && e.cipher == nil:
+		return ErrEncryptionKeyRequired
+	case !encrypted && e.cipher != nil:
+		return ErrNotEncrypted
+	}
+	return nil
+}
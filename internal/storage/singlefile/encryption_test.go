@@ -0,0 +1,272 @@
+package singlefile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// keyRing is a rotating KeyProvider.
+type keyRing struct {
+	keys    map[uint32][]byte
+	current uint32
+}
+
+func (r *keyRing) CurrentKey() (uint32, []byte, error) { return r.current, r.keys[r.current], nil }
+
+func (r *keyRing) Key(id uint32) ([]byte, error) {
+	key, ok := r.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("no key %d", id)
+	}
+	return key, nil
+}
+
+func assertNoPlaintext(t *testing.T, path string, secrets ...string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range secrets {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Fatalf("%s contains plaintext %q", filepath.Base(path), secret)
+		}
+	}
+}
+
+func openEncrypted(t *testing.T, path string, provider KeyProvider) *Engine {
+	t.Helper()
+	engine, err := New(path, WithEncryption(provider))
+	if err != nil {
+		t.Fatalf("open %s: %v", filepath.Base(path), err)
+	}
+	return engine.(*Engine)
+}
+
+func assertSecretRecord(t *testing.T, engine *Engine, id string) {
+	t.Helper()
+	coll, err := engine.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry, err := coll.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get(%q): %v", id, err)
+	}
+	if entry.Metadata["owner"] != "customer-secret" {
+		t.Fatalf("Get(%q) metadata = %v", id, entry.Metadata)
+	}
+}
+
+func TestEncryptedFileRoundTripAndRotation(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sealed.libravdb")
+	ring := &keyRing{current: 1, keys: map[uint32][]byte{1: bytes.Repeat([]byte{1}, 32)}}
+	secrets := []string{"customer-secret", "catalog-secret", "docs"}
+
+	engine := openEncrypted(t, path, ring)
+	coll, err := engine.CreateCollection("docs", &storage.CollectionConfig{Dimension: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := &index.VectorEntry{ID: "a", Ordinal: 1, Vector: []float32{1, 0}, Metadata: map[string]interface{}{"owner": "customer-secret"}}
+	if err := coll.Insert(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := engine.AppendGraphEdges(ctx, []storage.GraphEdgeOp{{Collection: "docs", Src: 1, Tgt: 2, Weight: 1, Kind: 3}}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	engine.SetCatalogData([]byte("catalog-secret"))
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	assertNoPlaintext(t, path, secrets...)
+
+	if _, err := New(path); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Fatalf("open without key: %v, want ErrEncryptionKeyRequired", err)
+	}
+	if _, err := New(path, WithEncryption(StaticKey(bytes.Repeat([]byte{2}, 32)))); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("open with wrong key: %v, want ErrDecryptionFailed", err)
+	}
+
+	engine = openEncrypted(t, path, ring)
+	assertSecretRecord(t, engine, "a")
+	if got := string(engine.CatalogData()); got != "catalog-secret" {
+		t.Fatalf("CatalogData = %q", got)
+	}
+
+	backupPath := filepath.Join(dir, "backup.libravdb")
+	if err := engine.Backup(ctx, backupPath); err != nil {
+		t.Fatal(err)
+	}
+	assertNoPlaintext(t, backupPath, secrets[:1]...)
+	if _, err := New(backupPath); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Fatalf("open backup without key: %v, want ErrEncryptionKeyRequired", err)
+	}
+	backup := openEncrypted(t, backupPath, ring)
+	assertSecretRecord(t, backup, "a")
+	if err := backup.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rotate: once Vacuum has rewritten the file, the old key is not needed.
+	ring.keys[2] = bytes.Repeat([]byte{3}, 32)
+	ring.current = 2
+	if err := engine.Vacuum(ctx); err != nil {
+		t.Fatal(err)
+	}
+	coll, err = engine.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.ID, entry.Ordinal = "b", 2
+	if err := coll.Insert(ctx, entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	delete(ring.keys, 1)
+	assertNoPlaintext(t, path, secrets...)
+
+	engine = openEncrypted(t, path, ring)
+	defer engine.Close()
+	assertSecretRecord(t, engine, "a")
+	assertSecretRecord(t, engine, "b")
+	edges := &recordedEdges{edges: make(map[[3]uint64]uint64)}
+	engine.SetGraphRecoveryTarget("docs", edges)
+	if len(edges.edges) != 1 {
+		t.Fatalf("replayed edges after rotation = %v", edges.edges)
+	}
+}
+
+func TestEncryptionRejectsPlaintextFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plain.libravdb")
+	engine, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(path, WithEncryption(StaticKey(bytes.Repeat([]byte{1}, 16)))); !errors.Is(err, ErrNotEncrypted) {
+		t.Fatalf("open plaintext file with key: %v, want ErrNotEncrypted", err)
+	}
+	if _, err := New(filepath.Join(t.TempDir(), "bad.libravdb"), WithEncryption(StaticKey([]byte("short")))); err == nil {
+		t.Fatal("New accepted an invalid AES key")
+	}
+}
+
+func TestEncryptedChunksOpenOnlyWhereTheyWereWritten(t *testing.T) {
+	key := StaticKey(bytes.Repeat([]byte{1}, 32))
+	engines := make([]*Engine, 2)
+	for i := range engines {
+		engines[i] = &Engine{fileID: uint64(i + 1)}
+		if err := WithEncryption(key)(engines[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	chunk, err := engines[0].encodeChunk(chunkTypeWAL, 4*pageSize, nil, []byte("wal-frame"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := func(e *Engine, offset uint64) error {
+		raw := chunk.bytes()
+		_, err := e.openChunk(offset, raw[:16], raw[16:])
+		return err
+	}
+	if err := open(engines[0], 4*pageSize); err != nil {
+		t.Fatalf("open chunk where it was written: %v", err)
+	}
+	if err := open(engines[0], 5*pageSize); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("open chunk at another offset: %v, want ErrDecryptionFailed", err)
+	}
+	if err := open(engines[1], 4*pageSize); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("open chunk in another file: %v, want ErrDecryptionFailed", err)
+	}
+}
+
+func TestEncryptedCatalogPageIsBoundToFileAndCheckpoint(t *testing.T) {
+	dir := t.TempDir()
+	key := StaticKey(bytes.Repeat([]byte{1}, 32))
+	readCatalogPage := func(path string) []byte {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data[3*pageSize : 4*pageSize]
+	}
+	writeCatalogPage := func(path string, page []byte) {
+		t.Helper()
+		file, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteAt(page, 3*pageSize); err != nil {
+			t.Fatal(err)
+		}
+	}
+	create := func(name, catalog string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		engine := openEncrypted(t, path, key)
+		engine.SetCatalogData([]byte(catalog))
+		if err := engine.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	pathA := create("a.libravdb", "catalog-a")
+	pathB := create("b.libravdb", "catalog-b")
+
+	// A catalog page copied from another database does not authenticate.
+	pageA := append([]byte(nil), readCatalogPage(pathA)...)
+	writeCatalogPage(pathA, readCatalogPage(pathB))
+	if _, err := New(pathA, WithEncryption(key)); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("open with another file's catalog page: %v, want ErrDecryptionFailed", err)
+	}
+	writeCatalogPage(pathA, pageA)
+
+	// A crash after a checkpoint's metapage but before its catalog page
+	// leaves the previous page in place; recovery falls back to the
+	// checkpoint that page was sealed for.
+	setCatalog := func(catalog string) {
+		t.Helper()
+		engine := openEncrypted(t, pathA, key)
+		engine.SetCatalogData([]byte(catalog))
+		if err := engine.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	setCatalog("catalog-a2")
+	pageA2 := append([]byte(nil), readCatalogPage(pathA)...)
+	setCatalog("catalog-a3")
+	writeCatalogPage(pathA, pageA2)
+	engine := openEncrypted(t, pathA, key)
+	if got := string(engine.CatalogData()); got != "catalog-a2" {
+		t.Fatalf("CatalogData after an interrupted checkpoint = %q, want catalog-a2", got)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A page sealed for a checkpoint neither metapage records does not
+	// authenticate under a later epoch.
+	setCatalog("catalog-a4")
+	setCatalog("catalog-a5")
+	writeCatalogPage(pathA, pageA)
+	if _, err := New(pathA, WithEncryption(key)); !errors.Is(err, ErrDecryptionFailed) {
+		t.Fatalf("open with a rolled back catalog page: %v, want ErrDecryptionFailed", err)
+	}
+}
@@ -41,8 +41,12 @@ const (
 	chunkTypeWAL       = uint16(2)
 	chunkTypeIndex     = uint16(3)
 	chunkTypeCommunity = uint16(4)
+	chunkTypeExtent    = uint16(5)          // one piece of an index extent
 	indexBlockMagic    = uint32(0x4C564449) // "LVDI"
-	indexBlockVersion  = uint16(1)
+	indexBlockVersion  = uint16(2)
+	// derivedIndexType marks an index block entry holding a derived image
+	// rather than a collection's vector index.
+	derivedIndexType = uint8(0xff)
 
 	recordTypeTxBegin          = uint16(1)
 	recordTypeTxCommit         = uint16(2)
@@ -51,6 +55,8 @@ const (
 	recordTypeCollectionDelete = uint16(11)
 	recordTypeCollectionStats  = uint16(12)
 	recordTypeCollectionConfig = uint16(13)
+	recordTypeCollectionRename = uint16(14)
+	recordTypeCatalog          = uint16(15) // replacement SQL catalog bytes
 	recordTypeRecordPut        = uint16(20)
 	recordTypeRecordDelete     = uint16(21)
 	recordTypeGraphEdgeAdd     = uint16(22)
@@ -59,6 +65,8 @@ const (
 	recordTypeCommitTimestamp  = uint16(25) // commit LSN → UTC timestamp mapping
 	recordTypeGraphVertexLabel = uint16(26)
 	recordTypeEdgeKindCreate   = uint16(27)
+	recordTypeViewPut          = uint16(28)
+	recordTypeViewDrop         = uint16(29)
 )
 
 // ReserveGraphNodeIDs reserves n sequential graph node IDs atomically.
@@ -161,8 +169,9 @@ type metaPage struct {
 }
 
 type recoveryCandidate struct {
-	meta  *metaPage
-	state *persistedState
+	meta    *metaPage
+	state   *persistedState
+	catalog []byte
 }
 
 type chunkHeader struct {
@@ -191,14 +200,19 @@ type walRecord struct {
 }
 
 type persistedState struct {
-	Collections            map[string]*persistedCollection `json:"collections"`
-	NextCollectionID       uint64                          `json:"next_collection_id"`
-	NextGraphNodeID        uint64                          `json:"next_graph_node_id"`
-	TombstonedGraphNodeIDs []uint64                        `json:"tombstoned_graph_node_ids,omitempty"`
-	CommitCatalog          []commitEntry                   `json:"commit_catalog,omitempty"`
-	OldestRetainedLSN      uint64                          `json:"oldest_retained_lsn,omitempty"`
-	EdgeKinds              map[string]uint8                `json:"edge_kinds,omitempty"`
-	UndirectedEdgeKinds    map[string]bool                 `json:"undirected_edge_kinds,omitempty"`
+	Collections            map[string]*persistedCollection   `json:"collections"`
+	NextCollectionID       uint64                            `json:"next_collection_id"`
+	NextGraphNodeID        uint64                            `json:"next_graph_node_id"`
+	TombstonedGraphNodeIDs []uint64                          `json:"tombstoned_graph_node_ids,omitempty"`
+	CommitCatalog          []commitEntry                     `json:"commit_catalog,omitempty"`
+	OldestRetainedLSN      uint64                            `json:"oldest_retained_lsn,omitempty"`
+	EdgeKinds              map[string]uint8                  `json:"edge_kinds,omitempty"`
+	UndirectedEdgeKinds    map[string]bool                   `json:"undirected_edge_kinds,omitempty"`
+	Views                  map[string]storage.ViewDefinition `json:"views,omitempty"`
+	// WALFloorLSN is the LSN through which WAL history was folded into a
+	// snapshot by compaction or backup. Committed transactions at or below
+	// it can no longer be shipped to replicas.
+	WALFloorLSN uint64 `json:"wal_floor_lsn,omitempty"`
 }
 
 type persistedCollection struct {
@@ -259,6 +273,11 @@ type collectionDeletePayload struct {
 	Name string `json:"name"`
 }
 
+type collectionRenamePayload struct {
+	Name    string `json:"name"`
+	NewName string `json:"new_name"`
+}
+
 type recordPutPayload struct {
 	Metadata    map[string]interface{} `json:"metadata"`
 	Collection  string                 `json:"collection"`
@@ -307,6 +326,21 @@ type CoordinatedIndexSnapshotProvider interface {
 	SerializeIndexAt(collectionName string, checkpointLSN uint64) (indexBytes []byte, appliedLSN uint64, err error)
 }
 
+// IndexExtentSnapshotProvider lets a provider stream the bulk of an index
+// into the database file instead of returning it in memory. The streamed
+// region is stored as an index extent next to the index chunk; the returned
+// bytes stay in the index block and must describe how to read the region.
+type IndexExtentSnapshotProvider interface {
+	// SerializeIndexExtentAt behaves like SerializeIndexAt and may also
+	// write a region to extent. streamed reports whether the index uses
+	// the region; when it is false the written bytes are ignored.
+	SerializeIndexExtentAt(collectionName string, checkpointLSN uint64, extent io.Writer) (indexBytes []byte, appliedLSN uint64, streamed bool, err error)
+	// DeserializeIndexExtent restores an index serialized with a region.
+	// The index owns extent from then on and closes it when it no longer
+	// reads from it, including when DeserializeIndexExtent fails.
+	DeserializeIndexExtent(collectionName string, indexBytes []byte, extent IndexExtent, config *storage.CollectionConfig) error
+}
+
 // IndexRestorePolicy lets a provider reject direct restoration of an otherwise
 // compatible persisted index. Rejected indexes are rebuilt from durable records.
 type IndexRestorePolicy interface {
@@ -324,15 +358,40 @@ type IncrementalIndexRecoveryProvider interface {
 	DiscardIndex(collectionName string)
 }
 
+// DerivedIndexSnapshotProvider persists structures a collection derives from
+// its records besides the vector index, such as full-text postings. Each image
+// is stored as its own index block entry at the frontier it covers and is
+// restored independently of the vector index, after it: recovery hands the
+// image the records the snapshot changed after that frontier, and later WAL
+// mutations reach it through the provider's IncrementalIndexRecoveryProvider
+// methods. A provider drops its restored images whenever it rebuilds or
+// discards the collection's index, and derives dropped structures again from
+// records.
+type DerivedIndexSnapshotProvider interface {
+	// SerializeDerivedIndexes returns one image per derived structure, keyed
+	// by a name unique within the collection, and the frontier they cover:
+	// every write committed at or before appliedLSN is in the images, which
+	// may also hold later ones. appliedLSN is at most checkpointLSN.
+	SerializeDerivedIndexes(collectionName string, checkpointLSN uint64) (images map[string][]byte, appliedLSN uint64, err error)
+	// RestoreDerivedIndex restores one image and applies changed, the
+	// records written after its frontier keyed by ID. A deleted record has
+	// nil metadata.
+	RestoreDerivedIndex(collectionName, derivedName string, image []byte, changed map[string]map[string]interface{}) error
+}
+
 // indexBlockEntry is a single collection's serialized index within the index chunk.
 type indexBlockEntry struct {
 	name            string
 	payload         []byte
 	payloadChecksum uint32
 	appliedLSN      uint64
+	extent          indexExtentRef
 	indexVersion    uint16
 	indexType       uint8
 	hasAppliedLSN   bool
+	// hasExtent marks a payload whose bulk region was streamed to extent
+	// instead of being part of it.
+	hasExtent bool
 }
 
 // Engine is the single-file storage engine.
@@ -376,6 +435,8 @@ type Engine struct {
 	rebuiltIndexes       uint64
 	replayedIndexPuts    uint64
 	replayedIndexDeletes uint64
+	restoredDerived      uint64
+	droppedDerived       uint64
 	lastLSN              atomic.Uint64
 	activeMetaPage       uint64
 	// walReplayStart is normally page 3. Recovery sets it to the end of the
@@ -392,7 +453,27 @@ type Engine struct {
 	// ambiguous. All mutation paths must reject writes until the
 	// engine is closed and reopened for recovery. Never reset
 	// in-process: reserved GraphNodeID capacity stays reserved.
-	writesDisabled        atomic.Bool
+	writesDisabled atomic.Bool
+	// replica engines follow another engine's WAL; local mutations are
+	// rejected and transactions arrive only through ApplyWAL.
+	replica bool
+	// readOnly engines never write the file and hold a shared lock on it.
+	readOnly bool
+	// walGeneration identifies the physical WAL layout that WAL cursor
+	// offsets refer to. Compaction rewrites the file and takes a new one.
+	walGeneration uint64
+	// cipher seals chunks and the catalog page; nil for a plaintext file.
+	cipher *chunkCipher
+	// extentMu guards e.file against replacement while an index extent
+	// reads from it, and the location of every live extent. Vacuum and
+	// compaction hold it exclusively across the file swap.
+	extentMu sync.RWMutex
+	// extents are the index extents handed to recovered indexes that still
+	// read them in place.
+	extents map[*indexExtent]struct{}
+	// commitNotify is closed by the next commit to wake change feed readers.
+	commitNotifyMu        sync.Mutex
+	commitNotify          chan struct{}
 	walSync               bool
 	groupCommitTarget     int32
 	groupCommitMaxDelay   time.Duration
@@ -652,6 +733,10 @@ type RecoveryStats struct {
 	RebuiltIndexes        uint64
 	ReplayedIndexPuts     uint64
 	ReplayedIndexDeletes  uint64
+	// RestoredDerivedIndexes and DroppedDerivedIndexes count derived images
+	// handed to the provider and those that failed validation or restore.
+	RestoredDerivedIndexes uint64
+	DroppedDerivedIndexes  uint64
 }
 
 // Collection is a storage-backed collection view.
@@ -687,6 +772,16 @@ func WithIndexSnapshotProvider(provider IndexSnapshotProvider) Option {
 	}
 }
 
+// WithReplica opens the database as a read-only replica. Local mutations
+// fail with ErrReplicaReadOnly; committed transactions shipped from a primary
+// are applied with ApplyWAL.
+func WithReplica() Option {
+	return func(e *Engine) error {
+		e.replica = true
+		return nil
+	}
+}
+
 // WithWALSync controls whether foreground WAL commits wait for file.Sync.
 // Production callers should keep this enabled. Disabling it is only suitable
 // for benchmarks that intentionally measure the non-durable upper bound.
@@ -721,17 +816,48 @@ func New(path string, opts ...Option) (storage.Engine, error) {
 		return nil, err
 	}
 
-	file, err := os.OpenFile(resolved, os.O_RDWR|os.O_CREATE|oNoFollow, 0644)
+	engine := &Engine{
+		path:          resolved,
+		state:         &persistedState{NextCollectionID: 1, NextGraphNodeID: 1, Collections: make(map[string]*persistedCollection), EdgeKinds: make(map[string]uint8), UndirectedEdgeKinds: make(map[string]bool)},
+		collections:   make(map[string]*Collection),
+		walSync:       true,
+		walGeneration: walGenerations.Add(1),
+	}
+
+	// Apply options before recovery so provider is available to loadIndexes.
+	for _, opt := range opts {
+		if err := opt(engine); err != nil {
+			return nil, err
+		}
+	}
+	if engine.readOnly && engine.replica {
+		return nil, fmt.Errorf("read-only and replica modes are mutually exclusive")
+	}
+
+	flags := os.O_RDWR | os.O_CREATE
+	if engine.readOnly {
+		flags = os.O_RDONLY
+	}
+	file, err := os.OpenFile(resolved, flags|oNoFollow, 0644)
 	if err != nil {
 		return nil, fmt.Errorf("open database file: %w", err)
 	}
+	if err := engine.lockDatabaseFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	engine.file = file
 
-	// Check format version early before applying options or starting goroutines
+	// Check format version early before starting goroutines
 	stat, err := file.Stat()
 	if err != nil {
 		file.Close()
 		return nil, fmt.Errorf("stat database file: %w", err)
 	}
+	if stat.Size() == 0 && engine.readOnly {
+		file.Close()
+		return nil, fmt.Errorf("read-only database file %s is empty", resolved)
+	}
 
 	if stat.Size() > 0 {
 		buf := make([]byte, 10)
@@ -745,23 +871,8 @@ func New(path string, opts ...Option) (storage.Engine, error) {
 		}
 	}
 
-	engine := &Engine{
-		path:        resolved,
-		file:        file,
-		state:       &persistedState{NextCollectionID: 1, NextGraphNodeID: 1, Collections: make(map[string]*persistedCollection), EdgeKinds: make(map[string]uint8), UndirectedEdgeKinds: make(map[string]bool)},
-		collections: make(map[string]*Collection),
-		walSync:     true,
-	}
 	engine.ctx, engine.cancel = context.WithCancel(context.Background())
 
-	// Apply options before recovery so provider is available to loadIndexes.
-	for _, opt := range opts {
-		if err := opt(engine); err != nil {
-			file.Close()
-			return nil, err
-		}
-	}
-
 	// Initialize WAL batch buffer channels (flusher goroutine started after init succeeds)
 	engine.batchBuffer.flusher = make(chan struct{})
 	engine.batchBuffer.entries = make([]batchEntry, 0, batchSize)
@@ -873,6 +984,10 @@ func (e *Engine) openExisting() error {
 		return err
 	}
 	e.fileID = header.FileID
+	if err := e.checkEncryptionFlag(header); err != nil {
+		e.fail(err)
+		return err
+	}
 
 	chosen, err := e.selectRecoveryCandidate()
 	if err != nil {
@@ -908,8 +1023,9 @@ func (e *Engine) openExisting() error {
 		e.catalogData = nil
 		replayStart = int64(chosen.meta.SnapshotOffset) + 16 + int64(chosen.meta.SnapshotLength)
 	} else {
-		// Load catalog data from page 3 if present.
-		e.catalogData = e.readCatalogPageLocked()
+		// Load catalog data from page 3 if present. selectRecoveryCandidate
+		// already opened an encrypted page under the chosen epoch.
+		e.catalogData = chosen.catalog
 	}
 	e.walReplayStart = replayStart
 
@@ -1062,9 +1178,14 @@ func (e *Engine) loadIndexes(chosen *metaPage) error {
 		return e.rebuildIndexesFromRecords()
 	}
 	handled := make(map[string]struct{}, len(entries))
+	var derived []indexBlockEntry
 
 	// Per-collection validation and deserialization.
 	for _, entry := range entries {
+		if entry.indexType == derivedIndexType {
+			derived = append(derived, entry)
+			continue
+		}
 		collection := e.state.Collections[entry.name]
 		if collection == nil {
 			continue // stale collection, skip
@@ -1118,7 +1239,7 @@ func (e *Engine) loadIndexes(chosen *metaPage) error {
 			continue
 		}
 
-		if err := e.indexProvider.DeserializeIndex(entry.name, entry.payload, &collection.Config); err != nil {
+		if err := e.deserializeIndexEntry(entry, &collection.Config); err != nil {
 			if err := e.rebuildCollectionIndexFromRecords(entry.name, collection); err != nil {
 				return err
 			}
@@ -1143,9 +1264,54 @@ func (e *Engine) loadIndexes(chosen *metaPage) error {
 		}
 	}
 
+	// Derived images go last: rebuilding a vector index drops them.
+	e.restoreDerivedIndexes(derived, chosen)
 	return nil
 }
 
+// restoreDerivedIndexes hands the provider every derived image whose frontier
+// lies within its collection's lifetime at the selected snapshot, together
+// with the records changed after that frontier. Other images are dropped and
+// counted; the provider derives those structures again from records.
+func (e *Engine) restoreDerivedIndexes(entries []indexBlockEntry, chosen *metaPage) {
+	provider, ok := e.indexProvider.(DerivedIndexSnapshotProvider)
+	if !ok {
+		return
+	}
+	for _, entry := range entries {
+		name, derivedName, _ := strings.Cut(entry.name, "\x00")
+		collection := e.state.Collections[name]
+		if collection == nil || collection.Deleted ||
+			crc32.Checksum(entry.payload, castagnoli) != entry.payloadChecksum ||
+			entry.appliedLSN < collection.CreatedLSN || entry.appliedLSN > chosen.LastAppliedLSN ||
+			provider.RestoreDerivedIndex(name, derivedName, entry.payload, changedSince(collection, entry.appliedLSN)) != nil {
+			e.droppedDerived++
+			continue
+		}
+		e.restoredDerived++
+	}
+}
+
+// changedSince returns the metadata of every record of collection written
+// after lsn, nil for a deleted record.
+func changedSince(collection *persistedCollection, lsn uint64) map[string]map[string]interface{} {
+	var changed map[string]map[string]interface{}
+	for id, record := range collection.Records {
+		if record.UpdatedLSN <= lsn {
+			continue
+		}
+		if changed == nil {
+			changed = make(map[string]map[string]interface{})
+		}
+		if record.Deleted {
+			changed[id] = nil
+		} else {
+			changed[id] = record.Metadata
+		}
+	}
+	return changed
+}
+
 // rebuildIndexesFromRecords rebuilds every collection's index from its Records map.
 func (e *Engine) rebuildReverseDirectoryFromRecords() error {
 	// If no reverse directory exists yet, create one.
@@ -1277,6 +1443,12 @@ func (e *Engine) disableWrites() {
 // writesAvailable returns errRecoveryRequired if a post-WAL durability
 // failure has gated writes on this instance.
 func (e *Engine) writesAvailable() error {
+	if e.readOnly {
+		return ErrReadOnly
+	}
+	if e.replica {
+		return ErrReplicaReadOnly
+	}
 	if e.writesDisabled.Load() {
 		return errRecoveryRequired
 	}
@@ -1357,6 +1529,9 @@ func (e *Engine) writeInitialPages() error {
 		WALHeadPage:     3,
 		WALTailPage:     3,
 	}
+	if e.cipher != nil {
+		header.FeatureFlags |= featureEncrypted
+	}
 	copy(header.Magic[:], []byte(fileMagic))
 	copy(header.Creator[:], []byte(fileCreator))
 	if err := writeFixedPage(e.file, 0, encodeHeader(header, make([]byte, pageSize))); err != nil {
@@ -1580,7 +1755,24 @@ func (e *Engine) selectRecoveryCandidate() (*recoveryCandidate, error) {
 	for i := 0; i < count; i++ {
 		candidate, err := e.decodeRecoveryCandidate(ordered[i])
 		if err == nil {
-			return candidate, nil
+			candidate.catalog, err = e.decodeCatalogPage(e.readCatalogPageLocked(), candidate.meta.MetaEpoch)
+			if err == nil {
+				return candidate, nil
+			}
+			// An encrypted checkpoint writes its catalog page after its
+			// metapage, so a crash between the two leaves the previous
+			// checkpoint's page behind. Only that checkpoint may use it.
+			if i+1 < count {
+				candidateErrors[i] = err
+				continue
+			}
+			return nil, err
+		}
+		// Torn writes fail the chunk checksum before decryption, so a
+		// snapshot that does not authenticate means a wrong key or a
+		// modified file. Falling back to an older checkpoint would hide it.
+		if errors.Is(err, ErrDecryptionFailed) {
+			return nil, err
 		}
 		candidateErrors[i] = err
 	}
@@ -1668,6 +1860,10 @@ func (e *Engine) readChunkAtKind(offset uint64, expectedKind uint16) ([]byte, er
 	if crc32.Checksum(payload, castagnoli) != header.Checksum {
 		return nil, fmt.Errorf("invalid chunk checksum at offset %d", offset)
 	}
+	payload, err = e.openChunk(offset, headerBuf, payload)
+	if err != nil {
+		return nil, fmt.Errorf("chunk at offset %d: %w", offset, err)
+	}
 	return payload, nil
 }
 
@@ -1690,7 +1886,9 @@ func decodeChunkHeader(buf []byte) chunkHeader {
 // prefix containing graph or edge-kind frames are not classified as this
 // case, because silently skipping those bytes could lose topology.
 func (e *Engine) detectStaleRelocationPrefix(meta *metaPage) (bool, error) {
-	if meta == nil || meta.SnapshotLength == 0 || meta.SnapshotOffset < uint64(4*pageSize) || meta.SnapshotOffset%uint64(pageSize) != 0 {
+	// Encrypted files postdate the catalog page and never went through the
+	// legacy relocation.
+	if e.cipher != nil || meta == nil || meta.SnapshotLength == 0 || meta.SnapshotOffset < uint64(4*pageSize) || meta.SnapshotOffset%uint64(pageSize) != 0 {
 		return false, nil
 	}
 	stat, err := e.file.Stat()
@@ -1858,6 +2056,10 @@ func (e *Engine) replayWALFrom(lastApplied uint64, startOffset int64) error {
 				offset, chunk.Kind, chunk.Version, chunk.PayloadLen, chunk.Checksum, calculated,
 			)
 		}
+		payload, err = e.openChunk(uint64(offset), headerBuf, payload)
+		if err != nil {
+			return fmt.Errorf("WAL chunk at offset %d: %w", offset, err)
+		}
 
 		record, err := decodeWALRecord(payload)
 		if err != nil {
@@ -2062,12 +2264,45 @@ func (e *Engine) applyCommittedFrames(
 				return err
 			}
 			e.applyCollectionConfig(payload.Name, payload.Config, record.Header.LSN)
+		case recordTypeCollectionRename:
+			payload, err := decodeCollectionRenamePayloadBinary(record.Payload)
+			if err != nil {
+				return err
+			}
+			collection := e.state.Collections[payload.Name]
+			if collection == nil || collection.Deleted {
+				continue
+			}
+			e.applyCollectionRename(payload.Name, payload.NewName, record.Header.LSN)
+			// Indexes recovered from the checkpoint are keyed by the old
+			// name: discard that one and rebuild the renamed collection's
+			// index from its records.
+			touchedCollections[payload.Name] = struct{}{}
+			touchedCollections[payload.NewName] = struct{}{}
+		case recordTypeCatalog:
+			catalog, err := decodeCatalogPayloadBinary(record.Payload)
+			if err != nil {
+				return err
+			}
+			e.applyCatalog(catalog)
 		case recordTypeEdgeKindCreate:
 			payload, err := decodeEdgeKindCreatePayload(record.Payload)
 			if err != nil {
 				return err
 			}
 			e.applyEdgeKindCreate(payload.Name, payload.Kind, payload.Undirected)
+		case recordTypeViewPut:
+			payload, err := decodeViewPutPayload(record.Payload)
+			if err != nil {
+				return err
+			}
+			e.applyViewPut(payload.Name, payload.Definition)
+		case recordTypeViewDrop:
+			name, err := decodeViewDropPayload(record.Payload)
+			if err != nil {
+				return err
+			}
+			e.applyViewDrop(name)
 		case recordTypeRecordPut:
 			payload, err := decodeRecordPutPayloadBinary(record.Payload)
 			if err != nil {
@@ -2190,6 +2425,17 @@ func (e *Engine) applyEdgeKindCreate(name string, kind uint8, undirected bool) {
 	}
 }
 
+func (e *Engine) applyViewPut(name string, definition storage.ViewDefinition) {
+	if e.state.Views == nil {
+		e.state.Views = make(map[string]storage.ViewDefinition)
+	}
+	e.state.Views[name] = definition
+}
+
+func (e *Engine) applyViewDrop(name string) {
+	delete(e.state.Views, name)
+}
+
 func (e *Engine) applyCreateCollection(name string, config storage.CollectionConfig, lsn uint64) {
 	if collection := e.state.Collections[name]; collection != nil && !collection.Deleted {
 		return
@@ -2218,6 +2464,41 @@ func (e *Engine) applyCollectionConfig(name string, config storage.CollectionCon
 	collection.UpdatedLSN = lsn
 }
 
+// applyCollectionRename moves a live collection to newName. Records, history,
+// and the collection ID are unchanged; open handles follow the rename.
+func (e *Engine) applyCollectionRename(name, newName string, lsn uint64) {
+	collection := e.state.Collections[name]
+	if collection == nil || collection.Deleted {
+		return
+	}
+	if tombstone := e.state.Collections[newName]; tombstone != nil && e.collectionsByID[tombstone.ID] == newName {
+		delete(e.collectionsByID, tombstone.ID)
+	}
+	delete(e.state.Collections, name)
+	e.state.Collections[newName] = collection
+	collection.UpdatedLSN = lsn
+	if e.collectionsByID == nil {
+		e.collectionsByID = make(map[uint64]string)
+	}
+	e.collectionsByID[collection.ID] = newName
+	if handle, ok := e.collections[name]; ok {
+		delete(e.collections, name)
+		handle.name = newName
+		e.collections[newName] = handle
+	}
+	if target, ok := e.graphRecoveryTargets[name]; ok {
+		delete(e.graphRecoveryTargets, name)
+		e.graphRecoveryTargets[newName] = target
+	}
+}
+
+// applyCatalog adopts catalog bytes committed through the WAL. They reach the
+// catalog page at the next checkpoint, like SetCatalogData.
+func (e *Engine) applyCatalog(catalog []byte) {
+	e.catalogData = catalog
+	e.catalogDirty = true
+}
+
 func (e *Engine) applyDeleteCollection(name string, lsn uint64) {
 	if collection := e.state.Collections[name]; collection != nil {
 		collection.Deleted = true
@@ -2460,7 +2741,10 @@ func (e *Engine) applyRecordPutFields(collectionName, id string, ordinal uint32,
 		collection.Records[id] = current
 		collection.LiveCount++
 	} else if current.Deleted {
+		// The archived version ended at the delete; the revived record is
+		// visible from this LSN on.
 		current.Deleted = false
+		current.CreatedLSN = lsn
 		collection.LiveCount++
 	} else {
 		// Archive the current version as a historical snapshot before
@@ -2651,7 +2935,11 @@ type temporalRecord struct {
 func (e *Engine) getRecordAtLSN(collectionName, id string, snapshotLSN uint64) (*temporalRecord, error) {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
+	return e.recordAtLSNLocked(collectionName, id, snapshotLSN)
+}
 
+// recordAtLSNLocked is getRecordAtLSN for callers that already hold e.mu.
+func (e *Engine) recordAtLSNLocked(collectionName, id string, snapshotLSN uint64) (*temporalRecord, error) {
 	collection := e.state.Collections[collectionName]
 	if collection == nil || collection.Deleted {
 		return nil, fmt.Errorf("collection %s not found", collectionName)
@@ -2847,13 +3135,19 @@ func (e *Engine) applyRecordDelete(collectionName, id string, lsn uint64) {
 
 // encodeIndexBlock serializes all collection indexes into a single binary blob.
 // Format: magic, version, collectionCount | repeated
-// { nameLen, name, indexType, indexVersion, appliedLSN, payloadLen, payload, payloadChecksum }.
+// { nameLen, name, indexType, indexVersion, appliedLSN, payloadLen, payload, payloadChecksum,
+// hasExtent, [extent offset, length, stride, chunkBytes] }.
+// Version 1 blocks end each entry at payloadChecksum.
 func encodeIndexBlock(entries []indexBlockEntry) []byte {
 	size := 12 // magic uint32 + version/reserved uint16 + collectionCount uint32
 	for _, e := range entries {
 		size += 2 + len(e.name)        // nameLen uint16 + name bytes
 		size += 1 + 2 + 8              // indexType uint8 + indexVersion uint16 + appliedLSN uint64
 		size += 4 + len(e.payload) + 4 // payloadLen uint32 + payload bytes + payloadChecksum uint32
+		size += 1                      // hasExtent
+		if e.hasExtent {
+			size += 8 + 8 + 8 + 4
+		}
 	}
 	buf := make([]byte, 0, size)
 	buf = binary.LittleEndian.AppendUint32(buf, indexBlockMagic)
@@ -2869,6 +3163,15 @@ func encodeIndexBlock(entries []indexBlockEntry) []byte {
 		buf = binary.LittleEndian.AppendUint32(buf, uint32(len(e.payload)))
 		buf = append(buf, e.payload...)
 		buf = binary.LittleEndian.AppendUint32(buf, e.payloadChecksum)
+		if !e.hasExtent {
+			buf = append(buf, 0)
+			continue
+		}
+		buf = append(buf, 1)
+		buf = binary.LittleEndian.AppendUint64(buf, e.extent.offset)
+		buf = binary.LittleEndian.AppendUint64(buf, e.extent.length)
+		buf = binary.LittleEndian.AppendUint64(buf, e.extent.stride)
+		buf = binary.LittleEndian.AppendUint32(buf, e.extent.chunkBytes)
 	}
 	return buf
 }
@@ -2882,12 +3185,13 @@ func decodeIndexBlock(data []byte) ([]indexBlockEntry, error) {
 	versioned := binary.LittleEndian.Uint32(data) == indexBlockMagic
 	count := binary.LittleEndian.Uint32(data)
 	pos := 4
+	var version uint16
 	if versioned {
 		if len(data) < 12 {
 			return nil, fmt.Errorf("versioned index block too small")
 		}
-		version := binary.LittleEndian.Uint16(data[4:6])
-		if version != indexBlockVersion {
+		version = binary.LittleEndian.Uint16(data[4:6])
+		if version == 0 || version > indexBlockVersion {
 			return nil, fmt.Errorf("unsupported index block version %d", version)
 		}
 		count = binary.LittleEndian.Uint32(data[8:12])
@@ -2897,6 +3201,9 @@ func decodeIndexBlock(data []byte) ([]indexBlockEntry, error) {
 	if versioned {
 		minEntrySize += 8
 	}
+	if version >= 2 {
+		minEntrySize++
+	}
 	if uint64(count) > uint64((len(data)-pos)/minEntrySize) {
 		return nil, fmt.Errorf("index block entry count %d exceeds payload capacity", count)
 	}
@@ -2940,6 +3247,25 @@ func decodeIndexBlock(data []byte) ([]indexBlockEntry, error) {
 		pos += payloadLen
 		payloadChecksum := binary.LittleEndian.Uint32(data[pos:])
 		pos += 4
+		var extent indexExtentRef
+		hasExtent := false
+		if version >= 2 {
+			if pos+1 > len(data) {
+				return nil, fmt.Errorf("truncated index entry for collection %s", name)
+			}
+			hasExtent = data[pos] != 0
+			pos++
+			if hasExtent {
+				if pos+28 > len(data) {
+					return nil, fmt.Errorf("truncated index extent for collection %s", name)
+				}
+				extent.offset = binary.LittleEndian.Uint64(data[pos:])
+				extent.length = binary.LittleEndian.Uint64(data[pos+8:])
+				extent.stride = binary.LittleEndian.Uint64(data[pos+16:])
+				extent.chunkBytes = binary.LittleEndian.Uint32(data[pos+24:])
+				pos += 28
+			}
+		}
 		entries = append(entries, indexBlockEntry{
 			name:            name,
 			indexType:       indexType,
@@ -2948,6 +3274,8 @@ func decodeIndexBlock(data []byte) ([]indexBlockEntry, error) {
 			hasAppliedLSN:   versioned,
 			payload:         payload,
 			payloadChecksum: payloadChecksum,
+			extent:          extent,
+			hasExtent:       hasExtent,
 		})
 	}
 	if pos != len(data) {
@@ -2956,13 +3284,35 @@ func decodeIndexBlock(data []byte) ([]indexBlockEntry, error) {
 	return entries, nil
 }
 
-func (e *Engine) serializeIndexEntry(name string, checkpointLSN uint64) (indexBlockEntry, bool, error) {
+// deserializeIndexEntry restores one collection's index, handing the
+// provider its extent when the entry has one.
+func (e *Engine) deserializeIndexEntry(entry indexBlockEntry, config *storage.CollectionConfig) error {
+	if !entry.hasExtent {
+		return e.indexProvider.DeserializeIndex(entry.name, entry.payload, config)
+	}
+	provider, ok := e.indexProvider.(IndexExtentSnapshotProvider)
+	if !ok {
+		return fmt.Errorf("index for %s is stored with an extent the provider cannot read", entry.name)
+	}
+	extent, err := e.openIndexExtent(entry.extent)
+	if err != nil {
+		return err
+	}
+	return provider.DeserializeIndexExtent(entry.name, entry.payload, extent, config)
+}
+
+// serializeIndexEntry serializes one collection's index. A provider that
+// streams a region writes it through extent.
+func (e *Engine) serializeIndexEntry(name string, checkpointLSN uint64, extent *indexExtentWriter) (indexBlockEntry, bool, error) {
 	var (
 		indexBytes []byte
 		appliedLSN = checkpointLSN
+		streamed   bool
 		err        error
 	)
-	if provider, ok := e.indexProvider.(CoordinatedIndexSnapshotProvider); ok {
+	if provider, ok := e.indexProvider.(IndexExtentSnapshotProvider); ok {
+		indexBytes, appliedLSN, streamed, err = provider.SerializeIndexExtentAt(name, checkpointLSN, extent)
+	} else if provider, ok := e.indexProvider.(CoordinatedIndexSnapshotProvider); ok {
 		indexBytes, appliedLSN, err = provider.SerializeIndexAt(name, checkpointLSN)
 	} else {
 		indexBytes, err = e.indexProvider.SerializeIndex(name)
@@ -2974,7 +3324,7 @@ func (e *Engine) serializeIndexEntry(name string, checkpointLSN uint64) (indexBl
 		return indexBlockEntry{}, false, nil
 	}
 	indexType, indexVersion := e.indexProvider.IndexTypeVersion(name)
-	return indexBlockEntry{
+	entry := indexBlockEntry{
 		name:            name,
 		indexType:       indexType,
 		indexVersion:    indexVersion,
@@ -2982,7 +3332,46 @@ func (e *Engine) serializeIndexEntry(name string, checkpointLSN uint64) (indexBl
 		hasAppliedLSN:   true,
 		payload:         indexBytes,
 		payloadChecksum: crc32.Checksum(indexBytes, castagnoli),
-	}, true, nil
+	}
+	if streamed {
+		if entry.extent, err = extent.finish(); err != nil {
+			return indexBlockEntry{}, false, err
+		}
+		entry.hasExtent = true
+	}
+	return entry, true, nil
+}
+
+// serializeDerivedIndexEntries serializes the derived images of one
+// collection, each as its own entry named <collection>\x00<derived name>.
+func (e *Engine) serializeDerivedIndexEntries(name string, checkpointLSN uint64) ([]indexBlockEntry, error) {
+	provider, ok := e.indexProvider.(DerivedIndexSnapshotProvider)
+	if !ok {
+		return nil, nil
+	}
+	images, appliedLSN, err := provider.SerializeDerivedIndexes(name, checkpointLSN)
+	if err != nil || len(images) == 0 {
+		return nil, err
+	}
+	derivedNames := make([]string, 0, len(images))
+	for derivedName := range images {
+		derivedNames = append(derivedNames, derivedName)
+	}
+	sort.Strings(derivedNames)
+	entries := make([]indexBlockEntry, 0, len(images))
+	for _, derivedName := range derivedNames {
+		image := images[derivedName]
+		entries = append(entries, indexBlockEntry{
+			name:            name + "\x00" + derivedName,
+			indexType:       derivedIndexType,
+			indexVersion:    1,
+			appliedLSN:      min(appliedLSN, checkpointLSN),
+			hasAppliedLSN:   true,
+			payload:         image,
+			payloadChecksum: crc32.Checksum(image, castagnoli),
+		})
+	}
+	return entries, nil
 }
 
 // checkpointWriteAtLocked issues a raw positioned write for checkpoint data,
@@ -3275,6 +3664,83 @@ func (e *Engine) CreateEdgeKindDefinition(name string, kind uint8, undirected bo
 	return e.maybeCheckpointLocked()
 }
 
+// ListViews returns the durable SQL view registry. The map is copied so
+// callers can cache it without holding the storage lock.
+func (e *Engine) ListViews() (map[string]storage.ViewDefinition, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	result := make(map[string]storage.ViewDefinition, len(e.state.Views))
+	for name, view := range e.state.Views {
+		result[name] = view
+	}
+	return result, nil
+}
+
+// PutView durably creates or replaces one SQL view definition through the
+// same transaction/WAL/checkpoint machinery as other database metadata.
+func (e *Engine) PutView(name string, definition storage.ViewDefinition) error {
+	if name == "" {
+		return fmt.Errorf("view name must not be empty")
+	}
+	if definition.Query == "" {
+		return fmt.Errorf("view %q has no defining query", name)
+	}
+	payload := encodeViewPutPayload(viewPutPayload{Name: name, Definition: definition})
+	return e.commitViewRecord(recordTypeViewPut, payload, func() {
+		e.applyViewPut(name, definition)
+	})
+}
+
+// DropView durably removes one SQL view definition. Dropping a view that does
+// not exist is a no-op.
+func (e *Engine) DropView(name string) error {
+	e.mu.RLock()
+	_, exists := e.state.Views[name]
+	e.mu.RUnlock()
+	if !exists {
+		return nil
+	}
+	return e.commitViewRecord(recordTypeViewDrop, encodeViewDropPayload(name), func() {
+		e.applyViewDrop(name)
+	})
+}
+
+// commitViewRecord writes one view registry frame as its own transaction
+// and applies it once the WAL is synced.
+func (e *Engine) commitViewRecord(recordType uint16, payload encodedPayload, apply func()) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.writesAvailable(); err != nil {
+		releaseDetachedPayload(payload.bytes, payload.encoder)
+		return err
+	}
+	txID := e.nextTxID()
+	beginLSN := e.nextLSN()
+	opLSN := e.nextLSN()
+	commitLSN := e.nextLSN()
+	frames := []walRecord{
+		newFrame(recordTypeTxBegin, beginLSN, txID, 0, emptyPayload()),
+		newFrame(recordType, opLSN, txID, beginLSN, payload),
+		e.makeTxCommitFrame(commitLSN, txID, opLSN),
+	}
+	written, err := e.appendTransactionLocked(frames)
+	if err != nil {
+		releaseDetachedPayload(payload.bytes, payload.encoder)
+		if isAmbiguousWriteError(err) {
+			e.disableWrites()
+		}
+		return err
+	}
+	if err := e.syncWALLocked(); err != nil {
+		e.disableWrites()
+		return err
+	}
+	e.recordPendingCommitLocked()
+	apply()
+	e.markDirtyLocked(written, 1)
+	return e.maybeCheckpointLocked()
+}
+
 // AppendGraphEdges submits graph edge operations into the unified batch
 // system. The ops share a commit LSN with any concurrent record writes in
 // the same flush. It waits for that flush so Graph Txn.Commit has a real
@@ -3483,6 +3949,11 @@ func (e *Engine) checkpointLocked() error {
 	if !e.dirty && !e.catalogDirty {
 		return nil
 	}
+	// A read-only engine leaves replayed WAL in place for the next writer
+	// to checkpoint.
+	if e.readOnly {
+		return nil
+	}
 	// Commit timestamps/LSNs are part of the temporal snapshot contract. Keep
 	// the persisted state copy synchronized before encoding so compaction and
 	// reopen can resolve historical timestamps even after old WAL is removed.
@@ -3491,8 +3962,10 @@ func (e *Engine) checkpointLocked() error {
 
 	// Page 3 is the legacy first-chunk location when no catalog is present.
 	// Once catalog bytes exist, reserve that page permanently and append at
-	// page 4 so a later catalog write cannot overwrite the first chunk.
-	if len(e.catalogData) > 0 {
+	// page 4 so a later catalog write cannot overwrite the first chunk. An
+	// encrypted file reserves it from the start: its chunks are sealed for
+	// their offset, so the legacy relocation could not move them.
+	if len(e.catalogData) > 0 || e.cipher != nil {
 		if err := e.ensureFileExtendsPastReservedLocked(); err != nil {
 			return err
 		}
@@ -3508,28 +3981,31 @@ func (e *Engine) checkpointLocked() error {
 		return err
 	}
 
-	// STEP 2: serialize and write index chunk (after snapshot, before metapage)
+	// STEP 2: serialize and write index extents and the index chunk (after
+	// snapshot, before metapage). Empty collections have no index to
+	// persist and are rebuilt from Records on recovery.
 	var indexBlock []byte
 	var indexChecksum uint32
+	var extentWriters []*indexExtentWriter
 	if e.indexProvider != nil {
 		names := make([]string, 0, len(e.state.Collections))
 		for name := range e.state.Collections {
 			names = append(names, name)
 		}
-		sort.Strings(names)
-		entries := make([]indexBlockEntry, 0, len(names))
-		for _, name := range names {
-			entry, present, err := e.serializeIndexEntry(name, e.lastLSN.Load())
-			if err != nil {
-				return fmt.Errorf("serialize index for %s: %w", name, err)
-			}
-			if !present {
-				continue // empty collection (no index to persist); rebuilt from Records on recovery
+		extentStart, err := e.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+		indexBlock, extentWriters, _, err = e.serializeIndexBlock(names, e.lastLSN.Load(), e.file, extentStart, true)
+		if err != nil {
+			// Drop partially written extents so the next append does not
+			// land inside a chunk whose length covers it.
+			if terr := e.file.Truncate(extentStart); terr != nil {
+				return fmt.Errorf("%w; truncate index extents: %w", err, terr)
 			}
-			entries = append(entries, entry)
+			return err
 		}
-		if len(entries) > 0 {
-			indexBlock = encodeIndexBlock(entries)
+		if len(indexBlock) > 0 {
 			indexChecksum = crc32.Checksum(indexBlock, castagnoli)
 		}
 	}
@@ -3600,9 +4076,13 @@ func (e *Engine) checkpointLocked() error {
 	// this snapshot. A crash after publishing the metapage but before writing
 	// page 3 otherwise leaves a valid checkpoint paired with a stale catalog
 	// page, which is exactly the ordering that exposed the relocation bug.
-	if len(e.catalogData) > 0 {
-		catalogPage := make([]byte, pageSize)
-		copy(catalogPage, e.catalogData)
+	// An encrypted catalog page is sealed for exactly this metapage's epoch,
+	// so it is written after the metapage instead; see STEP 4b.
+	if len(e.catalogData) > 0 && e.cipher == nil {
+		catalogPage, err := e.encodeCatalogPage(e.catalogData, e.metaEpoch)
+		if err != nil {
+			return fmt.Errorf("write catalog page: %w", err)
+		}
 		if err := e.checkpointWriteAtLocked(3*pageSize, catalogPage); err != nil {
 			return fmt.Errorf("write catalog page: %w", err)
 		}
@@ -3616,6 +4096,23 @@ func (e *Engine) checkpointLocked() error {
 		return err
 	}
 
+	// STEP 4b: write the encrypted catalog page, even an empty one, once its
+	// metapage is durable. Until it lands, page 3 still authenticates only
+	// under the previous metapage's epoch, and recovery falls back to that
+	// checkpoint, whose snapshot and WAL are untouched.
+	if e.cipher != nil {
+		catalogPage, err := e.encodeCatalogPage(e.catalogData, e.metaEpoch)
+		if err != nil {
+			return fmt.Errorf("write catalog page: %w", err)
+		}
+		if err := e.checkpointWriteAtLocked(3*pageSize, catalogPage); err != nil {
+			return fmt.Errorf("write catalog page: %w", err)
+		}
+		if err := e.checkpointSyncLocked(); err != nil {
+			return err
+		}
+	}
+
 	// STEP 5: write header (publishes the new metapage as authoritative)
 	header, err := e.readHeader()
 	if err != nil {
@@ -3643,9 +4140,12 @@ func (e *Engine) checkpointLocked() error {
 	e.dirtyBytes = 0
 	e.dirtyOps = 0
 	e.checkpoints++
+	e.extentMu.Lock()
+	e.rebaseIndexExtentsLocked(extentWriters, false)
+	e.extentMu.Unlock()
 
 	// Auto-compact when WAL bloat exceeds 2× the minimum file size.
-	compactSize := int64(3*pageSize) + 16 + int64(len(snapshot)) + 16 + int64(len(indexBlock))
+	compactSize := int64(3*pageSize) + 16 + int64(len(snapshot)) + 16 + int64(len(indexBlock)) + extentDiskBytes(extentWriters)
 	if stat.Size() > compactSize*2 {
 		if err := e.compactFile(); err != nil {
 			log.Printf("singlefile: auto-compact failed: %v", err)
@@ -3661,10 +4161,15 @@ func captureState(e *Engine) *persistedState {
 		TombstonedGraphNodeIDs: append([]uint64(nil), e.state.TombstonedGraphNodeIDs...),
 		CommitCatalog:          append([]commitEntry(nil), e.commitCatalog...),
 		OldestRetainedLSN:      e.oldestRetainedLSN,
+		WALFloorLSN:            e.state.WALFloorLSN,
 		EdgeKinds:              make(map[string]uint8, len(e.state.EdgeKinds)),
 		UndirectedEdgeKinds:    make(map[string]bool, len(e.state.UndirectedEdgeKinds)),
+		Views:                  make(map[string]storage.ViewDefinition, len(e.state.Views)),
 		Collections:            make(map[string]*persistedCollection, len(e.state.Collections)),
 	}
+	for name, view := range e.state.Views {
+		cloned.Views[name] = view
+	}
 	for name, kind := range e.state.EdgeKinds {
 		cloned.EdgeKinds[name] = kind
 	}
@@ -3718,10 +4223,22 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 		e.mu.Unlock()
 		return fmt.Errorf("engine closed")
 	}
+	if e.readOnly {
+		e.mu.Unlock()
+		return ErrReadOnly
+	}
 	if err := e.checkpointLocked(); err != nil {
 		e.mu.Unlock()
 		return fmt.Errorf("vacuum pre-checkpoint: %w", err)
 	}
+	// The rewritten file, and WAL appended while it is written, is sealed
+	// under the provider's current key. This is how keys are rotated.
+	if e.cipher != nil {
+		if err := e.cipher.rotate(); err != nil {
+			e.mu.Unlock()
+			return fmt.Errorf("vacuum: %w", err)
+		}
+	}
 
 	// Snapshot vectors for indexes safely
 	if e.indexProvider != nil {
@@ -3738,6 +4255,15 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 	}
 	phase1Size := stat.Size()
 	snapshotState := captureState(e)
+	// The rewritten file starts with a fresh snapshot, so it can only ship WAL written
+	// after phase 1. Graph topology is not part of that snapshot; carry the
+	// committed graph WAL the same way compaction does.
+	snapshotState.WALFloorLSN = e.lastLSN.Load()
+	graphWAL, err := e.collectCommittedGraphWALLocked()
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("vacuum preserve graph WAL: %w", err)
+	}
 	origHeader, err := e.readHeader()
 	if err != nil {
 		e.mu.Unlock()
@@ -3755,29 +4281,14 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 		return fmt.Errorf("vacuum snapshot size %d exceeds limit %d", len(snapshotBytes), maxChunkSize)
 	}
 
-	var indexBlock []byte
-	var indexChecksum uint32
-	if e.indexProvider != nil {
-		names := make([]string, 0, len(snapshotState.Collections))
-		for name := range snapshotState.Collections {
-			names = append(names, name)
-		}
-		sort.Strings(names)
-		entries := make([]indexBlockEntry, 0, len(names))
-		for _, name := range names {
-			entry, present, err := e.serializeIndexEntry(name, phase1LSN)
-			if err != nil {
-				return fmt.Errorf("vacuum serialize index %s: %w", name, err)
-			}
-			if !present {
-				continue
-			}
-			entries = append(entries, entry)
-		}
-		if len(entries) > 0 {
-			indexBlock = encodeIndexBlock(entries)
-			indexChecksum = crc32.Checksum(indexBlock, castagnoli)
-		}
+	// Page 3 is the catalog page, written once the final checkpoint has
+	// settled the catalog; the snapshot follows it as in compaction. A
+	// snapshot on page 3 would leave the rewritten file without its catalog
+	// and be overwritten by the next checkpoint's catalog write.
+	const snapshotOffset = uint64(4 * pageSize)
+	snapshotChunk, err := e.encodeChunk(chunkTypeSnapshot, snapshotOffset, nil, snapshotBytes)
+	if err != nil {
+		return fmt.Errorf("vacuum encode snapshot chunk: %w", err)
 	}
 
 	tmpPath := e.path + ".vacuum"
@@ -3794,13 +4305,43 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 		}
 	}()
 
-	const snapshotOffset = uint64(3 * pageSize)
-	indexOffset := snapshotOffset + 16 + uint64(len(snapshotBytes))
+	// Index extents stream in behind the snapshot, ahead of the index chunk.
+	indexOffset := snapshotOffset + snapshotChunk.size()
+	var indexBlock []byte
+	var extentWriters []*indexExtentWriter
+	if e.indexProvider != nil {
+		names := make([]string, 0, len(snapshotState.Collections))
+		for name := range snapshotState.Collections {
+			names = append(names, name)
+		}
+		var end int64
+		indexBlock, extentWriters, end, err = e.serializeIndexBlock(names, phase1LSN, tmpFile, int64(indexOffset), false)
+		if err != nil {
+			return fmt.Errorf("vacuum %w", err)
+		}
+		// Indexes still reading an extent the new file would not hold
+		// get a copy of it.
+		extentWriters, end, err = e.carryIndexExtents(extentWriters, tmpFile, end)
+		if err != nil {
+			return fmt.Errorf("vacuum %w", err)
+		}
+		indexOffset = uint64(end)
+	}
+	indexChunk, err := e.encodeChunk(chunkTypeIndex, indexOffset, nil, indexBlock)
+	if err != nil {
+		return fmt.Errorf("vacuum encode index chunk: %w", err)
+	}
 	indexLength := uint64(len(indexBlock))
 	totalSize := int64(indexOffset)
 	if len(indexBlock) > 0 {
-		totalSize += 16 + int64(indexLength)
+		totalSize += int64(indexChunk.size())
+	}
+	graphWALOffset := totalSize
+	graphWAL, err = e.sealChunks(graphWAL, uint64(graphWALOffset))
+	if err != nil {
+		return fmt.Errorf("vacuum seal graph WAL: %w", err)
 	}
+	totalSize += int64(len(graphWAL))
 	pageCount := uint64((totalSize + pageSize - 1) / pageSize)
 
 	fh := &fileHeader{
@@ -3840,7 +4381,7 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 		SnapshotLength:  uint64(len(snapshotBytes)),
 		IndexOffset:     indexOffset,
 		IndexLength:     indexLength,
-		IndexChecksum:   indexChecksum,
+		IndexChecksum:   crc32.Checksum(indexBlock, castagnoli),
 	}
 	for i := range buf {
 		buf[i] = 0
@@ -3860,31 +4401,18 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 	}
 	pagePool.Put(bufPtr)
 
-	var chunkHdr [16]byte
-	binary.LittleEndian.PutUint32(chunkHdr[0:4], chunkMagic)
-	binary.LittleEndian.PutUint16(chunkHdr[4:6], chunkTypeSnapshot)
-	binary.LittleEndian.PutUint16(chunkHdr[6:8], formatVersion)
-	binary.LittleEndian.PutUint32(chunkHdr[8:12], uint32(len(snapshotBytes)))
-	binary.LittleEndian.PutUint32(chunkHdr[12:16], crc32.Checksum(snapshotBytes, castagnoli))
-	if err := writeFullAt(tmpFile, chunkHdr[:], int64(snapshotOffset)); err != nil {
-		return fmt.Errorf("vacuum write chunk header: %w", err)
-	}
-	if err := writeFullAt(tmpFile, snapshotBytes, int64(snapshotOffset)+16); err != nil {
-		return fmt.Errorf("vacuum write snapshot payload: %w", err)
+	if err := snapshotChunk.writeAt(tmpFile, int64(snapshotOffset)); err != nil {
+		return fmt.Errorf("vacuum write snapshot chunk: %w", err)
 	}
 
 	if len(indexBlock) > 0 {
-		var idxHdr [16]byte
-		binary.LittleEndian.PutUint32(idxHdr[0:4], chunkMagic)
-		binary.LittleEndian.PutUint16(idxHdr[4:6], chunkTypeIndex)
-		binary.LittleEndian.PutUint16(idxHdr[6:8], formatVersion)
-		binary.LittleEndian.PutUint32(idxHdr[8:12], uint32(len(indexBlock)))
-		binary.LittleEndian.PutUint32(idxHdr[12:16], indexChecksum)
-		if err := writeFullAt(tmpFile, idxHdr[:], int64(indexOffset)); err != nil {
-			return fmt.Errorf("vacuum write index chunk header: %w", err)
+		if err := indexChunk.writeAt(tmpFile, int64(indexOffset)); err != nil {
+			return fmt.Errorf("vacuum write index chunk: %w", err)
 		}
-		if err := writeFullAt(tmpFile, indexBlock, int64(indexOffset)+16); err != nil {
-			return fmt.Errorf("vacuum write index payload: %w", err)
+	}
+	if len(graphWAL) > 0 {
+		if err := writeFullAt(tmpFile, graphWAL, graphWALOffset); err != nil {
+			return fmt.Errorf("vacuum write graph WAL: %w", err)
 		}
 	}
 
@@ -3899,6 +4427,15 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 	if err := e.checkpointLocked(); err != nil {
 		return fmt.Errorf("vacuum final checkpoint: %w", err)
 	}
+	if len(e.catalogData) > 0 || e.cipher != nil {
+		catalogPage, err := e.encodeCatalogPage(e.catalogData, meta.MetaEpoch)
+		if err != nil {
+			return fmt.Errorf("vacuum encode catalog page: %w", err)
+		}
+		if err := writeFixedPage(tmpFile, 3, catalogPage); err != nil {
+			return fmt.Errorf("vacuum write catalog page: %w", err)
+		}
+	}
 
 	stat, err = e.file.Stat()
 	if err != nil {
@@ -3908,14 +4445,12 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 
 	if currentSize > phase1Size {
 		// Copy WAL bytes that landed during Phase 2
-		// Seek temp file to end (should be at totalSize)
-		if _, err := tmpFile.Seek(0, io.SeekEnd); err != nil {
+		// to the temp file's end (should be at totalSize)
+		tail, err := tmpFile.Seek(0, io.SeekEnd)
+		if err != nil {
 			return fmt.Errorf("vacuum seek temp file: %w", err)
 		}
-
-		// Create section reader for the delta
-		deltaReader := io.NewSectionReader(e.file, phase1Size, currentSize-phase1Size)
-		if _, err := io.Copy(tmpFile, deltaReader); err != nil {
+		if err := e.copyTail(tmpFile, phase1Size, currentSize, tail); err != nil {
 			return fmt.Errorf("vacuum copy WAL deltas: %w", err)
 		}
 	}
@@ -3927,9 +4462,13 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 		return fmt.Errorf("vacuum close temp: %w", err)
 	}
 
+	// Index extents read through e.file; hold them off until they point
+	// into whichever file is open once the swap settles.
+	e.extentMu.Lock()
+	defer e.extentMu.Unlock()
 	if err := e.file.Close(); err != nil {
 		// reopen guard
-		if f, ferr := os.OpenFile(e.path, os.O_RDWR, 0644); ferr != nil {
+		if f, ferr := e.reopenDatabaseFile(); ferr != nil {
 			e.status.Store(int32(storage.StatusFailed))
 			return fmt.Errorf("vacuum close original: %w; reopen: %w", err, ferr)
 		} else {
@@ -3940,7 +4479,7 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 
 	e.file = nil // Safety before rename
 	if err := replaceDatabaseFile(tmpPath, e.path); err != nil {
-		if f, ferr := os.OpenFile(e.path, os.O_RDWR, 0644); ferr != nil {
+		if f, ferr := e.reopenDatabaseFile(); ferr != nil {
 			e.status.Store(int32(storage.StatusFailed))
 			return fmt.Errorf("vacuum rename: %w; reopen: %w", err, ferr)
 		} else {
@@ -3949,13 +4488,20 @@ func (e *Engine) Vacuum(ctx context.Context) error {
 		return fmt.Errorf("vacuum rename: %w", err)
 	}
 
-	f, err := os.OpenFile(e.path, os.O_RDWR, 0644)
+	f, err := e.reopenDatabaseFile()
 	if err != nil {
 		e.status.Store(int32(storage.StatusFailed))
 		return fmt.Errorf("vacuum open new file: %w", err)
 	}
 	e.file = f
+	e.rebaseIndexExtentsLocked(extentWriters, true)
+	// The new file starts its metapages over at epoch 1; the next
+	// checkpoint's catalog page is sealed for the epoch that follows.
+	e.activeMetaPage = 1
+	e.metaEpoch = 1
 	e.dirty = false
+	e.state.WALFloorLSN = snapshotState.WALFloorLSN
+	e.walGeneration = walGenerations.Add(1)
 	cleanup = false // Successfully swapped, defer won't remove it
 	if err := syncDatabaseParent(e.path); err != nil {
 		return fmt.Errorf("vacuum sync parent directory: %w", err)
@@ -3991,6 +4537,15 @@ func (e *Engine) Backup(ctx context.Context, destPath string) error {
 	}
 	phase1Size := stat.Size()
 	snapshotState := captureState(e)
+	// The copy starts with a fresh snapshot, so it can only ship WAL written
+	// after phase 1. Graph topology is not part of that snapshot; carry the
+	// committed graph WAL the same way compaction does.
+	snapshotState.WALFloorLSN = e.lastLSN.Load()
+	graphWAL, err := e.collectCommittedGraphWALLocked()
+	if err != nil {
+		e.mu.Unlock()
+		return fmt.Errorf("backup preserve graph WAL: %w", err)
+	}
 	origHeader, err := e.readHeader()
 	if err != nil {
 		e.mu.Unlock()
@@ -4008,29 +4563,14 @@ func (e *Engine) Backup(ctx context.Context, destPath string) error {
 		return fmt.Errorf("backup snapshot size %d exceeds limit %d", len(snapshotBytes), maxChunkSize)
 	}
 
-	var indexBlock []byte
-	var indexChecksum uint32
-	if e.indexProvider != nil {
-		names := make([]string, 0, len(snapshotState.Collections))
-		for name := range snapshotState.Collections {
-			names = append(names, name)
-		}
-		sort.Strings(names)
-		entries := make([]indexBlockEntry, 0, len(names))
-		for _, name := range names {
-			entry, present, err := e.serializeIndexEntry(name, phase1LSN)
-			if err != nil {
-				return fmt.Errorf("backup serialize index %s: %w", name, err)
-			}
-			if !present {
-				continue
-			}
-			entries = append(entries, entry)
-		}
-		if len(entries) > 0 {
-			indexBlock = encodeIndexBlock(entries)
-			indexChecksum = crc32.Checksum(indexBlock, castagnoli)
-		}
+	// Page 3 is the catalog page, written once the final checkpoint has
+	// settled the catalog; the snapshot follows it as in compaction. A
+	// snapshot on page 3 would leave the rewritten file without its catalog
+	// and be overwritten by the next checkpoint's catalog write.
+	const snapshotOffset = uint64(4 * pageSize)
+	snapshotChunk, err := e.encodeChunk(chunkTypeSnapshot, snapshotOffset, nil, snapshotBytes)
+	if err != nil {
+		return fmt.Errorf("backup encode snapshot chunk: %w", err)
 	}
 
 	destFile, err := os.OpenFile(destPath, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0644)
@@ -4046,13 +4586,36 @@ func (e *Engine) Backup(ctx context.Context, destPath string) error {
 		}
 	}()
 
-	const snapshotOffset = uint64(3 * pageSize)
-	indexOffset := snapshotOffset + 16 + uint64(len(snapshotBytes))
+	// Index extents stream in behind the snapshot, ahead of the index chunk.
+	indexOffset := snapshotOffset + snapshotChunk.size()
+	var indexBlock []byte
+	if e.indexProvider != nil {
+		names := make([]string, 0, len(snapshotState.Collections))
+		for name := range snapshotState.Collections {
+			names = append(names, name)
+		}
+		var end int64
+		indexBlock, _, end, err = e.serializeIndexBlock(names, phase1LSN, destFile, int64(indexOffset), false)
+		if err != nil {
+			return fmt.Errorf("backup %w", err)
+		}
+		indexOffset = uint64(end)
+	}
+	indexChunk, err := e.encodeChunk(chunkTypeIndex, indexOffset, nil, indexBlock)
+	if err != nil {
+		return fmt.Errorf("backup encode index chunk: %w", err)
+	}
 	indexLength := uint64(len(indexBlock))
 	totalSize := int64(indexOffset)
 	if len(indexBlock) > 0 {
-		totalSize += 16 + int64(indexLength)
+		totalSize += int64(indexChunk.size())
 	}
+	graphWALOffset := totalSize
+	graphWAL, err = e.sealChunks(graphWAL, uint64(graphWALOffset))
+	if err != nil {
+		return fmt.Errorf("backup seal graph WAL: %w", err)
+	}
+	totalSize += int64(len(graphWAL))
 	pageCount := uint64((totalSize + pageSize - 1) / pageSize)
 
 	fh := &fileHeader{
@@ -4092,7 +4655,7 @@ func (e *Engine) Backup(ctx context.Context, destPath string) error {
 		SnapshotLength:  uint64(len(snapshotBytes)),
 		IndexOffset:     indexOffset,
 		IndexLength:     indexLength,
-		IndexChecksum:   indexChecksum,
+		IndexChecksum:   crc32.Checksum(indexBlock, castagnoli),
 	}
 	for i := range buf {
 		buf[i] = 0
@@ -4112,31 +4675,18 @@ func (e *Engine) Backup(ctx context.Context, destPath string) error {
 	}
 	pagePool.Put(bufPtr)
 
-	var chunkHdr [16]byte
-	binary.LittleEndian.PutUint32(chunkHdr[0:4], chunkMagic)
-	binary.LittleEndian.PutUint16(chunkHdr[4:6], chunkTypeSnapshot)
-	binary.LittleEndian.PutUint16(chunkHdr[6:8], formatVersion)
-	binary.LittleEndian.PutUint32(chunkHdr[8:12], uint32(len(snapshotBytes)))
-	binary.LittleEndian.PutUint32(chunkHdr[12:16], crc32.Checksum(snapshotBytes, castagnoli))
-	if err := writeFullAt(destFile, chunkHdr[:], int64(snapshotOffset)); err != nil {
-		return fmt.Errorf("backup write chunk header: %w", err)
-	}
-	if err := writeFullAt(destFile, snapshotBytes, int64(snapshotOffset)+16); err != nil {
-		return fmt.Errorf("backup write snapshot payload: %w", err)
+	if err := snapshotChunk.writeAt(destFile, int64(snapshotOffset)); err != nil {
+		return fmt.Errorf("backup write snapshot chunk: %w", err)
 	}
 
 	if len(indexBlock) > 0 {
-		var idxHdr [16]byte
-		binary.LittleEndian.PutUint32(idxHdr[0:4], chunkMagic)
-		binary.LittleEndian.PutUint16(idxHdr[4:6], chunkTypeIndex)
-		binary.LittleEndian.PutUint16(idxHdr[6:8], formatVersion)
-		binary.LittleEndian.PutUint32(idxHdr[8:12], uint32(len(indexBlock)))
-		binary.LittleEndian.PutUint32(idxHdr[12:16], indexChecksum)
-		if err := writeFullAt(destFile, idxHdr[:], int64(indexOffset)); err != nil {
-			return fmt.Errorf("backup write index chunk header: %w", err)
+		if err := indexChunk.writeAt(destFile, int64(indexOffset)); err != nil {
+			return fmt.Errorf("backup write index chunk: %w", err)
 		}
-		if err := writeFullAt(destFile, indexBlock, int64(indexOffset)+16); err != nil {
-			return fmt.Errorf("backup write index payload: %w", err)
+	}
+	if len(graphWAL) > 0 {
+		if err := writeFullAt(destFile, graphWAL, graphWALOffset); err != nil {
+			return fmt.Errorf("backup write graph WAL: %w", err)
 		}
 	}
 
@@ -4150,6 +4700,15 @@ func (e *Engine) Backup(ctx context.Context, destPath string) error {
 	if err := e.checkpointLocked(); err != nil {
 		return fmt.Errorf("backup final checkpoint: %w", err)
 	}
+	if len(e.catalogData) > 0 || e.cipher != nil {
+		catalogPage, err := e.encodeCatalogPage(e.catalogData, meta.MetaEpoch)
+		if err != nil {
+			return fmt.Errorf("backup encode catalog page: %w", err)
+		}
+		if err := writeFixedPage(destFile, 3, catalogPage); err != nil {
+			return fmt.Errorf("backup write catalog page: %w", err)
+		}
+	}
 
 	stat, err = e.file.Stat()
 	if err != nil {
@@ -4158,11 +4717,11 @@ func (e *Engine) Backup(ctx context.Context, destPath string) error {
 	currentSize := stat.Size()
 
 	if currentSize > phase1Size {
-		if _, err := destFile.Seek(0, io.SeekEnd); err != nil {
+		tail, err := destFile.Seek(0, io.SeekEnd)
+		if err != nil {
 			return fmt.Errorf("backup seek temp file: %w", err)
 		}
-		deltaReader := io.NewSectionReader(e.file, phase1Size, currentSize-phase1Size)
-		if _, err := io.Copy(destFile, deltaReader); err != nil {
+		if err := e.copyTail(destFile, phase1Size, currentSize, tail); err != nil {
 			return fmt.Errorf("backup copy WAL deltas: %w", err)
 		}
 	}
@@ -4219,7 +4778,9 @@ func (e *Engine) compactFile() error {
 // collectCommittedGraphWALLocked extracts committed WAL chunks containing
 // graph operations. Record frames are covered by the snapshot and are omitted;
 // transaction begin/commit frames are retained with graph frames so replay
-// preserves transaction atomicity. Caller must hold e.mu.
+// preserves transaction atomicity. Chunks are returned as plaintext frames;
+// the caller seals them for their new offset with sealChunks. Caller must
+// hold e.mu.
 func (e *Engine) collectCommittedGraphWALLocked() ([]byte, error) {
 	stat, err := e.file.Stat()
 	if err != nil {
@@ -4264,10 +4825,19 @@ func (e *Engine) collectCommittedGraphWALLocked() ([]byte, error) {
 			return nil, err
 		}
 		if header.Kind == chunkTypeWAL {
-			record, err := decodeWALRecord(raw[16:])
+			payload, err := e.openChunk(uint64(offset), raw[:16], raw[16:])
+			if err != nil {
+				return nil, fmt.Errorf("WAL chunk at offset %d: %w", offset, err)
+			}
+			record, err := decodeWALRecord(payload)
 			if err != nil {
 				return nil, err
 			}
+			if e.cipher != nil {
+				// The rewrite places preserved graph WAL elsewhere and
+				// seals it there with sealChunks.
+				raw = plaintextChunk(chunkTypeWAL, payload)
+			}
 			chunks = append(chunks, graphWALChunk{raw: raw, rec: record})
 			switch record.Header.RecordType {
 			case recordTypeGraphEdgeAdd, recordTypeGraphEdgeRemove,
@@ -4297,6 +4867,15 @@ func (e *Engine) collectCommittedGraphWALLocked() ([]byte, error) {
 // compactFileLocked implements the actual compaction logic. The caller
 // (compactFile) wraps it to increment compactionErrors on failure.
 func (e *Engine) compactFileLocked() error {
+	// The rewrite seals everything under the provider's current key.
+	if e.cipher != nil {
+		if err := e.cipher.rotate(); err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+	}
+	// Record WAL of non-graph transactions is dropped below, so history up
+	// to the current LSN can no longer be shipped to replicas.
+	e.state.WALFloorLSN = e.lastLSN.Load()
 	snapshot, err := encodeStateBinary(e.state)
 	if err != nil {
 		return fmt.Errorf("compact: encode state: %w", err)
@@ -4311,32 +4890,6 @@ func (e *Engine) compactFileLocked() error {
 		return fmt.Errorf("compact: read header: %w", err)
 	}
 
-	// Build index block (same as checkpointLocked).
-	var indexBlock []byte
-	var indexChecksum uint32
-	if e.indexProvider != nil {
-		names := make([]string, 0, len(e.state.Collections))
-		for name := range e.state.Collections {
-			names = append(names, name)
-		}
-		sort.Strings(names)
-		entries := make([]indexBlockEntry, 0, len(names))
-		for _, name := range names {
-			entry, present, err := e.serializeIndexEntry(name, e.lastLSN.Load())
-			if err != nil {
-				return fmt.Errorf("compact: serialize index for %s: %w", name, err)
-			}
-			if !present {
-				continue
-			}
-			entries = append(entries, entry)
-		}
-		if len(entries) > 0 {
-			indexBlock = encodeIndexBlock(entries)
-			indexChecksum = crc32.Checksum(indexBlock, castagnoli)
-		}
-	}
-
 	// Graph topology is maintained by external graph instances and is not
 	// represented in the persisted record snapshot. Preserve committed graph
 	// WAL transactions across compaction so a freshly attached graph can
@@ -4345,6 +4898,14 @@ func (e *Engine) compactFileLocked() error {
 	if err != nil {
 		return fmt.Errorf("compact: preserve graph WAL: %w", err)
 	}
+	// Page 3 is the fixed catalog page. Keep the compacted snapshot after
+	// it, just like normal checkpoints, so catalog persistence cannot be
+	// overwritten by the snapshot chunk.
+	const snapshotOffset = uint64(4 * pageSize)
+	snapshotChunk, err := e.encodeChunk(chunkTypeSnapshot, snapshotOffset, nil, snapshot)
+	if err != nil {
+		return fmt.Errorf("compact: encode snapshot chunk: %w", err)
+	}
 
 	tmpPath := e.path + ".compact"
 	tmpFile, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
@@ -4360,17 +4921,42 @@ func (e *Engine) compactFileLocked() error {
 	}()
 
 	// ── Calculate layout ───────────────────────────────────────────────────
-	// Page 3 is the fixed catalog page. Keep the compacted snapshot after
-	// it, just like normal checkpoints, so catalog persistence cannot be
-	// overwritten by the snapshot chunk.
-	const snapshotOffset = uint64(4 * pageSize)
-	indexOffset := snapshotOffset + 16 + uint64(len(snapshot))
+	// Index extents stream in behind the snapshot, ahead of the index chunk.
+	indexOffset := snapshotOffset + snapshotChunk.size()
+	var indexBlock []byte
+	var extentWriters []*indexExtentWriter
+	if e.indexProvider != nil {
+		names := make([]string, 0, len(e.state.Collections))
+		for name := range e.state.Collections {
+			names = append(names, name)
+		}
+		var end int64
+		indexBlock, extentWriters, end, err = e.serializeIndexBlock(names, e.lastLSN.Load(), tmpFile, int64(indexOffset), false)
+		if err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+		// Indexes still reading an extent the new file would not hold
+		// get a copy of it.
+		extentWriters, end, err = e.carryIndexExtents(extentWriters, tmpFile, end)
+		if err != nil {
+			return fmt.Errorf("compact: %w", err)
+		}
+		indexOffset = uint64(end)
+	}
+	indexChunk, err := e.encodeChunk(chunkTypeIndex, indexOffset, nil, indexBlock)
+	if err != nil {
+		return fmt.Errorf("compact: encode index chunk: %w", err)
+	}
 	indexLength := uint64(len(indexBlock))
 	totalSize := int64(indexOffset)
 	if len(indexBlock) > 0 {
-		totalSize += 16 + int64(indexLength)
+		totalSize += int64(indexChunk.size())
 	}
 	graphWALOffset := uint64(totalSize)
+	graphWAL, err = e.sealChunks(graphWAL, graphWALOffset)
+	if err != nil {
+		return fmt.Errorf("compact: seal graph WAL: %w", err)
+	}
 	if len(graphWAL) > 0 {
 		totalSize += int64(len(graphWAL))
 	}
@@ -4416,7 +5002,7 @@ func (e *Engine) compactFileLocked() error {
 		SnapshotLength:  uint64(len(snapshot)),
 		IndexOffset:     indexOffset,
 		IndexLength:     indexLength,
-		IndexChecksum:   indexChecksum,
+		IndexChecksum:   crc32.Checksum(indexBlock, castagnoli),
 	}
 	if len(indexBlock) > maxChunkSize {
 		return fmt.Errorf("compact: index block size %d exceeds limit %d", len(indexBlock), maxChunkSize)
@@ -4437,22 +5023,14 @@ func (e *Engine) compactFileLocked() error {
 	}
 
 	// ── Page 3 (offset 12288): snapshot chunk ─────────────────────────────
-	checksum := crc32.Checksum(snapshot, castagnoli)
-	var chunkHdr [16]byte
-	binary.LittleEndian.PutUint32(chunkHdr[0:4], chunkMagic)
-	binary.LittleEndian.PutUint16(chunkHdr[4:6], chunkTypeSnapshot)
-	binary.LittleEndian.PutUint16(chunkHdr[6:8], formatVersion)
-	binary.LittleEndian.PutUint32(chunkHdr[8:12], uint32(len(snapshot)))
-	binary.LittleEndian.PutUint32(chunkHdr[12:16], checksum)
-	if err := writeFullAt(tmpFile, chunkHdr[:], int64(snapshotOffset)); err != nil {
-		return fmt.Errorf("compact: write chunk header: %w", err)
-	}
-	if err := writeFullAt(tmpFile, snapshot, int64(snapshotOffset)+16); err != nil {
-		return fmt.Errorf("compact: write snapshot payload: %w", err)
+	if err := snapshotChunk.writeAt(tmpFile, int64(snapshotOffset)); err != nil {
+		return fmt.Errorf("compact: write snapshot chunk: %w", err)
 	}
-	if len(e.catalogData) > 0 {
-		catalogPage := make([]byte, pageSize)
-		copy(catalogPage, e.catalogData)
+	if len(e.catalogData) > 0 || e.cipher != nil {
+		catalogPage, err := e.encodeCatalogPage(e.catalogData, meta.MetaEpoch)
+		if err != nil {
+			return fmt.Errorf("compact: encode catalog page: %w", err)
+		}
 		if err := writeFixedPage(tmpFile, 3, catalogPage); err != nil {
 			return fmt.Errorf("compact: write catalog page: %w", err)
 		}
@@ -4461,17 +5039,8 @@ func (e *Engine) compactFileLocked() error {
 
 	// ── Index chunk (if present) ──────────────────────────────────────────
 	if len(indexBlock) > 0 {
-		var idxHdr [16]byte
-		binary.LittleEndian.PutUint32(idxHdr[0:4], chunkMagic)
-		binary.LittleEndian.PutUint16(idxHdr[4:6], chunkTypeIndex)
-		binary.LittleEndian.PutUint16(idxHdr[6:8], formatVersion)
-		binary.LittleEndian.PutUint32(idxHdr[8:12], uint32(len(indexBlock)))
-		binary.LittleEndian.PutUint32(idxHdr[12:16], indexChecksum)
-		if err := writeFullAt(tmpFile, idxHdr[:], int64(indexOffset)); err != nil {
-			return fmt.Errorf("compact: write index chunk header: %w", err)
-		}
-		if err := writeFullAt(tmpFile, indexBlock, int64(indexOffset)+16); err != nil {
-			return fmt.Errorf("compact: write index block payload: %w", err)
+		if err := indexChunk.writeAt(tmpFile, int64(indexOffset)); err != nil {
+			return fmt.Errorf("compact: write index chunk: %w", err)
 		}
 	}
 	if len(graphWAL) > 0 {
@@ -4490,8 +5059,11 @@ func (e *Engine) compactFileLocked() error {
 
 	// ── Atomic rename ─────────────────────────────────────────────────────
 	// Close original before rename; if anything fails, reopen original.
+	// Index extents read through e.file and wait until it is settled.
+	e.extentMu.Lock()
+	defer e.extentMu.Unlock()
 	if err := e.file.Close(); err != nil {
-		if f, ferr := os.OpenFile(e.path, os.O_RDWR, 0644); ferr != nil {
+		if f, ferr := e.reopenDatabaseFile(); ferr != nil {
 			e.status.Store(int32(storage.StatusFailed))
 			return fmt.Errorf("compact: close original: %w; reopen: %w", err, ferr)
 		} else {
@@ -4502,7 +5074,7 @@ func (e *Engine) compactFileLocked() error {
 	e.file = nil
 
 	if err := replaceDatabaseFile(tmpPath, e.path); err != nil {
-		if f, ferr := os.OpenFile(e.path, os.O_RDWR, 0644); ferr != nil {
+		if f, ferr := e.reopenDatabaseFile(); ferr != nil {
 			e.status.Store(int32(storage.StatusFailed))
 			return fmt.Errorf("compact: rename: %w; reopen: %w", err, ferr)
 		} else {
@@ -4511,16 +5083,19 @@ func (e *Engine) compactFileLocked() error {
 		return fmt.Errorf("compact: rename: %w", err)
 	}
 
-	newFile, err := os.OpenFile(e.path, os.O_RDWR, 0644)
+	newFile, err := e.reopenDatabaseFile()
 	if err != nil {
+		e.status.Store(int32(storage.StatusFailed))
 		return fmt.Errorf("compact: reopen: %w", err)
 	}
 	e.file = newFile
+	e.rebaseIndexExtentsLocked(extentWriters, true)
 
 	// ── Reset bookkeeping to match the compacted file ─────────────────────
 	e.activeMetaPage = 1
 	e.metaEpoch = 1
 	e.walReplayStart = int64(3 * pageSize)
+	e.walGeneration = walGenerations.Add(1)
 	e.dirty = false
 	e.dirtyBytes = 0
 	e.dirtyOps = 0
@@ -4554,6 +5129,21 @@ func (e *Engine) Compact() error {
 	return e.compactFile()
 }
 
+// Checkpoint flushes buffered writes and persists a snapshot, so the next
+// open replays no WAL. It is a no-op when nothing changed since the last
+// checkpoint.
+func (e *Engine) Checkpoint() error {
+	if err := e.flushBatch(); err != nil {
+		return fmt.Errorf("checkpoint: flush batch: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed.Load() {
+		return fmt.Errorf("checkpoint: database is closed")
+	}
+	return e.checkpointLocked()
+}
+
 // CompactionErrors returns the count of compaction errors since engine startup.
 func (e *Engine) CompactionErrors() uint64 {
 	e.mu.RLock()
@@ -4573,26 +5163,18 @@ func (e *Engine) appendChunkHeaderPayloadLocked(kind uint16, headerPart, payload
 	if err != nil {
 		return 0, err
 	}
-	payloadLen := uint32(len(headerPart) + len(payloadPart))
-	checksum := crc32.Update(0, castagnoli, headerPart)
-	checksum = crc32.Update(checksum, castagnoli, payloadPart)
-	var header [16]byte
-	binary.LittleEndian.PutUint32(header[0:4], chunkMagic)
-	binary.LittleEndian.PutUint16(header[4:6], kind)
-	binary.LittleEndian.PutUint16(header[6:8], formatVersion)
-	binary.LittleEndian.PutUint32(header[8:12], payloadLen)
-	binary.LittleEndian.PutUint32(header[12:16], checksum)
-	if err := writeFull(e.file, header[:]); err != nil {
+	chunk, err := e.encodeChunk(kind, uint64(offset), headerPart, payloadPart)
+	if err != nil {
 		return 0, err
 	}
-	if len(headerPart) > 0 {
-		if err := writeFull(e.file, headerPart); err != nil {
-			return 0, err
-		}
+	if err := writeFull(e.file, chunk.header[:]); err != nil {
+		return 0, err
 	}
-	if len(payloadPart) > 0 {
-		if err := writeFull(e.file, payloadPart); err != nil {
-			return 0, err
+	for _, part := range chunk.parts {
+		if len(part) > 0 {
+			if err := writeFull(e.file, part); err != nil {
+				return 0, err
+			}
 		}
 	}
 	return uint64(offset), nil
@@ -4605,9 +5187,15 @@ func (e *Engine) appendTransactionLocked(records []walRecord) (uint64, error) {
 		return 0, err
 	}
 
+	// A sealed chunk stores the key ID and nonce in front of the frame and
+	// the GCM tag after it.
+	prefix, overhead := 0, 0
+	if e.cipher != nil {
+		prefix, overhead = sealedPrefix, sealedOverhead
+	}
 	totalSize := 0
 	for _, record := range records {
-		totalSize += 16 + 40 + len(record.Payload)
+		totalSize += 16 + overhead + 40 + len(record.Payload)
 	}
 
 	buf, temporaryArena, err := e.allocateWALWriteBufferLocked(totalSize)
@@ -4621,10 +5209,11 @@ func (e *Engine) appendTransactionLocked(records []walRecord) (uint64, error) {
 	for _, record := range records {
 		// Reserve space in buf for chunk header + frame header.
 		start := len(buf)
-		buf = buf[:start+16+40]
+		frame := start + 16 + prefix
+		buf = buf[:frame+40]
 
 		// Write frame header directly into buf — no stack array escape.
-		fh := buf[start+16 : start+16+40]
+		fh := buf[frame : frame+40]
 		binary.LittleEndian.PutUint32(fh[0:4], record.Header.Magic)
 		binary.LittleEndian.PutUint16(fh[4:6], record.Header.Version)
 		binary.LittleEndian.PutUint16(fh[6:8], record.Header.RecordType)
@@ -4633,21 +5222,21 @@ func (e *Engine) appendTransactionLocked(records []walRecord) (uint64, error) {
 		binary.LittleEndian.PutUint64(fh[24:32], record.Header.PrevLSN)
 		binary.LittleEndian.PutUint32(fh[32:36], record.Header.PayloadLen)
 		binary.LittleEndian.PutUint32(fh[36:40], record.Header.Checksum)
+		buf = append(buf, record.Payload...)
 
-		payloadLen := uint32(40 + len(record.Payload))
-		checksum := crc32.Update(0, castagnoli, fh)
-		checksum = crc32.Update(checksum, castagnoli, record.Payload)
-
-		// Write chunk header directly into buf.
+		// Write chunk header directly into buf. The length is part of the
+		// sealed chunk's associated data, so it precedes sealing.
 		ch := buf[start : start+16]
-		binary.LittleEndian.PutUint32(ch[0:4], chunkMagic)
-		binary.LittleEndian.PutUint16(ch[4:6], chunkTypeWAL)
-		binary.LittleEndian.PutUint16(ch[6:8], formatVersion)
-		binary.LittleEndian.PutUint32(ch[8:12], payloadLen)
-		binary.LittleEndian.PutUint32(ch[12:16], checksum)
-
-		buf = append(buf, record.Payload...)
-		written += uint64(16 + 40 + len(record.Payload))
+		putChunkHeader(ch, chunkTypeWAL, uint32(overhead+40+len(record.Payload)))
+		if e.cipher != nil {
+			body, err := e.cipher.sealInPlace(buf[start+16:], e.chunkAAD(ch, uint64(offset)+uint64(start)))
+			if err != nil {
+				return 0, err
+			}
+			buf = buf[:start+16+len(body)]
+		}
+		binary.LittleEndian.PutUint32(ch[12:16], crc32.Checksum(buf[start+16:], castagnoli))
+		written += uint64(len(buf) - start)
 	}
 
 	var writeErr error
@@ -4815,6 +5404,7 @@ func (e *Engine) recordPendingCommitLocked() {
 		})
 		e.pendingCommitLSN = 0
 		e.pendingCommitTS = 0
+		e.notifyCommit()
 	}
 }
 
@@ -4823,6 +5413,7 @@ func (e *Engine) recordPendingCommitLocked() {
 // frame rather than captured live.
 func (e *Engine) recordCommitLocked(lsn uint64, ts int64) {
 	e.commitCatalog = append(e.commitCatalog, commitEntry{LSN: lsn, Timestamp: ts})
+	e.notifyCommit()
 }
 
 // commitTimestampForLSN returns the UTC timestamp for a committed LSN by
@@ -5716,13 +6307,45 @@ func (e *Engine) commitTx(ctx context.Context, ops []storage.TxOperation, receip
 	nextID := e.state.NextGraphNodeID
 	numAllocated := uint64(0)
 
+	catalogOp := false
+	renamed := false
+	deleted := make(map[string]bool)
 	for i, op := range ops {
 		if err := ctx.Err(); err != nil {
 			return err
 		}
 
+		if op.Type == storage.TxOperationCatalog {
+			if len(op.Catalog) == 0 {
+				return fmt.Errorf("catalog operation carries no catalog")
+			}
+			payload, err := encodeCatalogPayloadBinary(op.Catalog)
+			if err != nil {
+				return err
+			}
+			lsn := e.nextLSN()
+			frames[i+1] = newFrame(recordTypeCatalog, lsn, txID, prevLSN, payload)
+			prevLSN = lsn
+			catalogOp = true
+			continue
+		}
+		if op.Type == storage.TxOperationViewDrop {
+			if _, exists := e.state.Views[op.Collection]; !exists {
+				return fmt.Errorf("view %s not found", op.Collection)
+			}
+			lsn := e.nextLSN()
+			frames[i+1] = newFrame(recordTypeViewDrop, lsn, txID, prevLSN, encodeViewDropPayload(op.Collection))
+			prevLSN = lsn
+			continue
+		}
+		// Replay applies frames in order, so a rename must not be followed
+		// by operations that address the collection under either name.
+		if renamed {
+			return fmt.Errorf("a collection rename must be the last collection operation in a transaction")
+		}
+
 		collection := e.state.Collections[op.Collection]
-		if collection == nil || collection.Deleted {
+		if collection == nil || collection.Deleted || deleted[op.Collection] {
 			return fmt.Errorf("collection %s not found", op.Collection)
 		}
 
@@ -5790,11 +6413,47 @@ func (e *Engine) commitTx(ctx context.Context, ops []storage.TxOperation, receip
 				Collection: op.Collection, NodeID: op.EdgeSrc,
 			})
 			frames[i+1] = newFrame(recordTypeGraphNodeDrop, lsn, txID, prevLSN, payload)
+		case storage.TxOperationCollectionConfig:
+			if op.Config == nil {
+				return fmt.Errorf("collection config must not be nil")
+			}
+			payload, err := encodeCollectionCreatePayloadBinary(collectionCreatePayload{Name: op.Collection, Config: *op.Config})
+			if err != nil {
+				return err
+			}
+			frames[i+1] = newFrame(recordTypeCollectionConfig, lsn, txID, prevLSN, payload)
+		case storage.TxOperationCollectionRename:
+			if op.NewName == "" || op.NewName == op.Collection {
+				return fmt.Errorf("invalid new name %q for collection %s", op.NewName, op.Collection)
+			}
+			if existing := e.state.Collections[op.NewName]; existing != nil && !existing.Deleted {
+				return fmt.Errorf("collection %s already exists", op.NewName)
+			}
+			payload, err := encodeCollectionRenamePayloadBinary(collectionRenamePayload{Name: op.Collection, NewName: op.NewName})
+			if err != nil {
+				return err
+			}
+			frames[i+1] = newFrame(recordTypeCollectionRename, lsn, txID, prevLSN, payload)
+			renamed = true
+		case storage.TxOperationCollectionDelete:
+			payload, err := encodeCollectionDeletePayloadBinary(collectionDeletePayload{Name: op.Collection})
+			if err != nil {
+				return err
+			}
+			frames[i+1] = newFrame(recordTypeCollectionDelete, lsn, txID, prevLSN, payload)
+			deleted[op.Collection] = true
 		default:
 			return fmt.Errorf("unsupported transaction operation type %d", op.Type)
 		}
 		prevLSN = lsn
 	}
+	// The catalog page is written at checkpoints; reserve it before the
+	// transaction that makes a new catalog durable, as SetCatalogData does.
+	if catalogOp && !e.readOnly {
+		if err := e.relocateLegacyChunksPastCatalogLocked(); err != nil {
+			return fmt.Errorf("reserve catalog page: %w", err)
+		}
+	}
 
 	// Pre-admission: reserve reverse directory capacity before any WAL bytes.
 	var reservedPtrs []unsafe.Pointer
@@ -5876,6 +6535,20 @@ func (e *Engine) commitTx(ctx context.Context, ops []storage.TxOperation, receip
 			}
 		case storage.TxOperationDelete:
 			e.applyRecordDelete(op.Collection, op.ID, commitLSN)
+		case storage.TxOperationCollectionConfig:
+			e.applyCollectionConfig(op.Collection, *op.Config, frames[i+1].Header.LSN)
+		case storage.TxOperationCollectionRename:
+			e.applyCollectionRename(op.Collection, op.NewName, frames[i+1].Header.LSN)
+		case storage.TxOperationCatalog:
+			e.applyCatalog(op.Catalog)
+		case storage.TxOperationCollectionDelete:
+			e.applyDeleteCollection(op.Collection, frames[i+1].Header.LSN)
+			if collectionObj := e.collections[op.Collection]; collectionObj != nil {
+				collectionObj.closed.Store(true)
+				delete(e.collections, op.Collection)
+			}
+		case storage.TxOperationViewDrop:
+			e.applyViewDrop(op.Collection)
 		}
 	}
 
@@ -6082,7 +6755,7 @@ func (e *Engine) CollectionDataLSN(name string) (uint64, error) {
 // checkpoint and survives Close/Reopen.
 func (e *Engine) SetCatalogData(data []byte) {
 	e.mu.Lock()
-	if len(data) > 0 {
+	if len(data) > 0 && !e.readOnly {
 		if err := e.relocateLegacyChunksPastCatalogLocked(); err != nil {
 			e.fail(fmt.Errorf("reserve catalog page: %w", err))
 			e.mu.Unlock()
@@ -6204,12 +6877,15 @@ func (e *Engine) Close() error {
 	if e.closed.Load() {
 		return nil
 	}
+	// Change feed readers waiting for a commit observe the close on their
+	// next read.
+	defer e.notifyCommit()
 
 	// Force a checkpoint so schema changes (catalog) and collection
 	// state survive Close/Reopen.  The index provider is temporarily
 	// detached because it holds a reference to database-level state
 	// that is locked by our caller; the index rebuilds on recovery.
-	if e.dirty || e.catalogDirty {
+	if (e.dirty || e.catalogDirty) && !e.readOnly {
 		savedProvider := e.indexProvider
 		e.indexProvider = nil // skip index serialization (rebuilt on recovery)
 		// Best-effort: WAL replay will recover on next Open.
@@ -6234,7 +6910,7 @@ func (e *Engine) Close() error {
 		}
 		e.walRequests = nil
 	}
-	if e.dirty {
+	if e.dirty && !e.readOnly {
 		if err := e.file.Sync(); err != nil {
 			return err
 		}
@@ -6244,6 +6920,9 @@ func (e *Engine) Close() error {
 		e.reverseDir.close()
 		e.reverseDir = nil
 	}
+	// Index extents still held by indexes fail their next read.
+	e.extentMu.Lock()
+	defer e.extentMu.Unlock()
 	return e.file.Close()
 }
 
@@ -6265,11 +6944,13 @@ func (e *Engine) RecoveryStats() RecoveryStats {
 	e.mu.RLock()
 	defer e.mu.RUnlock()
 	return RecoveryStats{
-		ReplayedTransactions:  e.replayedTxs,
-		DiscardedTransactions: e.discardedTxs,
-		RebuiltIndexes:        e.rebuiltIndexes,
-		ReplayedIndexPuts:     e.replayedIndexPuts,
-		ReplayedIndexDeletes:  e.replayedIndexDeletes,
+		ReplayedTransactions:   e.replayedTxs,
+		DiscardedTransactions:  e.discardedTxs,
+		RebuiltIndexes:         e.rebuiltIndexes,
+		ReplayedIndexPuts:      e.replayedIndexPuts,
+		ReplayedIndexDeletes:   e.replayedIndexDeletes,
+		RestoredDerivedIndexes: e.restoredDerived,
+		DroppedDerivedIndexes:  e.droppedDerived,
 	}
 }
 
@@ -6596,6 +7277,34 @@ func (c *Collection) GetIDByOrdinal(ctx context.Context, ordinal uint32) (string
 	return id, nil
 }
 
+// GetMetadataByOrdinal resolves a local ordinal to its ID and a copy of its
+// metadata without cloning the vector. Query-time metadata predicates use it
+// to admit ANN candidates during traversal.
+func (c *Collection) GetMetadataByOrdinal(ctx context.Context, ordinal uint32) (string, map[string]interface{}, error) {
+	_ = ctx
+	c.engine.mu.RLock()
+	defer c.engine.mu.RUnlock()
+	if c.closed.Load() || c.engine.closed.Load() {
+		return "", nil, fmt.Errorf("collection %s is closed", c.name)
+	}
+	persisted := c.engine.state.Collections[c.name]
+	if persisted == nil || persisted.Deleted {
+		return "", nil, fmt.Errorf("collection %s not found", c.name)
+	}
+	if int(ordinal) >= len(persisted.ordinalToID) {
+		return "", nil, fmt.Errorf("ordinal %d not found", ordinal)
+	}
+	id := persisted.ordinalToID[ordinal]
+	if id == "" {
+		return "", nil, fmt.Errorf("ordinal %d not found", ordinal)
+	}
+	record := persisted.Records[id]
+	if record == nil || record.Deleted {
+		return "", nil, fmt.Errorf("ordinal %d not found", ordinal)
+	}
+	return id, cloneMetadata(record.Metadata), nil
+}
+
 func (c *Collection) MemoryUsage(ctx context.Context) (int64, error) {
 	_ = ctx
 	c.engine.mu.RLock()
@@ -446,6 +446,164 @@ func TestRecoveryReplaysIndexDeltasWithoutSecondRebuild(t *testing.T) {
 	}
 }
 
+type derivedRecoveryIndexProvider struct {
+	incrementalRecoveryIndexProvider
+	images   map[string][]byte
+	restored map[string]string
+	changed  map[string]map[string]interface{}
+	failures bool
+	// frontier, when set, is the LSN the images cover instead of the
+	// checkpoint's.
+	frontier uint64
+}
+
+func (p *derivedRecoveryIndexProvider) SerializeDerivedIndexes(name string, checkpointLSN uint64) (map[string][]byte, uint64, error) {
+	if name != "vectors" {
+		return nil, checkpointLSN, nil
+	}
+	if p.frontier != 0 {
+		return p.images, p.frontier, nil
+	}
+	return p.images, checkpointLSN, nil
+}
+
+func (p *derivedRecoveryIndexProvider) RestoreDerivedIndex(name, derivedName string, image []byte, changed map[string]map[string]interface{}) error {
+	if p.failures {
+		return fmt.Errorf("corrupt image")
+	}
+	p.mu.Lock()
+	if p.restored == nil {
+		p.restored = make(map[string]string)
+	}
+	p.restored[name+"/"+derivedName] = string(image)
+	p.changed = changed
+	p.mu.Unlock()
+	return nil
+}
+
+func TestRecoveryRestoresDerivedIndexesBeforeReplayingDeltas(t *testing.T) {
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "derived-source.libravdb")
+	provider := &derivedRecoveryIndexProvider{images: map[string][]byte{"fts:body": []byte("postings"), "fts:title": []byte("titles")}}
+	engineIface, err := New(sourcePath, WithIndexSnapshotProvider(provider))
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	engine := engineIface.(*Engine)
+	collection, err := engine.CreateCollection("vectors", &storage.CollectionConfig{Dimension: 2, IndexType: 0})
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	if err := collection.Insert(context.Background(), &index.VectorEntry{ID: "checkpointed", Vector: []float32{1, 0}}); err != nil {
+		t.Fatalf("insert checkpoint entry: %v", err)
+	}
+	engine.mu.Lock()
+	err = engine.checkpointLocked()
+	engine.mu.Unlock()
+	if err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if err := collection.Insert(context.Background(), &index.VectorEntry{ID: "later", Vector: []float32{0, 1}}); err != nil {
+		t.Fatalf("insert after checkpoint: %v", err)
+	}
+	// Copy the live file so recovery sees the checkpoint and a WAL tail.
+	contents, err := os.ReadFile(sourcePath)
+	if err != nil {
+		t.Fatalf("read live database: %v", err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("close source: %v", err)
+	}
+
+	for _, failures := range []bool{false, true} {
+		recovery := &derivedRecoveryIndexProvider{failures: failures}
+		path := filepath.Join(dir, fmt.Sprintf("derived-copy-%v.libravdb", failures))
+		if err := os.WriteFile(path, contents, 0o600); err != nil {
+			t.Fatalf("write copy: %v", err)
+		}
+		reopenedIface, err := New(path, WithIndexSnapshotProvider(recovery))
+		if err != nil {
+			t.Fatalf("reopen live copy: %v", err)
+		}
+		reopened := reopenedIface.(*Engine)
+		stats := reopened.RecoveryStats()
+		recovery.mu.Lock()
+		restored := fmt.Sprint(recovery.restored)
+		puts := len(recovery.puts)
+		recovery.mu.Unlock()
+		_ = reopened.Close()
+
+		if puts != 1 {
+			t.Fatalf("failures=%v: replayed %d puts, want 1", failures, puts)
+		}
+		if failures {
+			if stats.RestoredDerivedIndexes != 0 || stats.DroppedDerivedIndexes != 2 {
+				t.Fatalf("failed restores were not dropped: %+v", stats)
+			}
+			continue
+		}
+		if restored != "map[vectors/fts:body:postings vectors/fts:title:titles]" {
+			t.Fatalf("restored derived images = %s", restored)
+		}
+		if stats.RestoredDerivedIndexes != 2 || stats.DroppedDerivedIndexes != 0 {
+			t.Fatalf("recovery stats = %+v", stats)
+		}
+	}
+}
+
+func TestRecoveryAppliesRecordsWrittenAfterDerivedFrontier(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "derived-frontier.libravdb")
+	provider := &derivedRecoveryIndexProvider{images: map[string][]byte{"fts:body": []byte("postings")}}
+	engineIface, err := New(path, WithIndexSnapshotProvider(provider))
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	engine := engineIface.(*Engine)
+	collection, err := engine.CreateCollection("vectors", &storage.CollectionConfig{Dimension: 2, IndexType: 0})
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	for _, id := range []string{"kept", "deleted"} {
+		if err := collection.Insert(context.Background(), &index.VectorEntry{ID: id, Vector: []float32{1, 0}}); err != nil {
+			t.Fatalf("insert %s: %v", id, err)
+		}
+	}
+	// The images cover the state after the first inserts; the writes after
+	// it were still on their way to the postings when they were taken.
+	provider.frontier = engine.lastLSN.Load()
+	if err := collection.Insert(context.Background(), &index.VectorEntry{ID: "later", Vector: []float32{0, 1}, Metadata: map[string]interface{}{"body": "text"}}); err != nil {
+		t.Fatalf("insert later: %v", err)
+	}
+	if err := collection.Delete(context.Background(), "deleted"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	engine.mu.Lock()
+	err = engine.checkpointLocked()
+	engine.mu.Unlock()
+	if err != nil {
+		t.Fatalf("checkpoint: %v", err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+
+	recovery := &derivedRecoveryIndexProvider{}
+	reopened, err := New(path, WithIndexSnapshotProvider(recovery))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	if stats := reopened.(*Engine).RecoveryStats(); stats.RestoredDerivedIndexes != 1 {
+		t.Fatalf("recovery stats = %+v, want the image restored", stats)
+	}
+	recovery.mu.Lock()
+	changed := fmt.Sprint(recovery.changed)
+	recovery.mu.Unlock()
+	if changed != "map[deleted:map[] later:map[body:text]]" {
+		t.Fatalf("records changed after the frontier = %s", changed)
+	}
+}
+
 func TestRecoveryReplaysIndexLifecycleDeltas(t *testing.T) {
 	dir := t.TempDir()
 	sourcePath := filepath.Join(dir, "index-lifecycle-source.libravdb")
@@ -2857,3 +3015,230 @@ func TestPersistedCollectionVectorSegmentSizingIsBounded(t *testing.T) {
 		})
 	}
 }
+
+func TestCommitTxRenamesCollectionWithConfigAndCatalog(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "rename.libravdb")
+	engineIface, err := New(path)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	engine := engineIface.(*Engine)
+	if _, err := engine.CreateCollection("old", &storage.CollectionConfig{Dimension: 2, Version: 2}); err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	if err := engine.CommitTx(ctx, []storage.TxOperation{
+		{Collection: "old", ID: "r1", Vector: []float32{1, 0}, Type: storage.TxOperationPut},
+	}); err != nil {
+		t.Fatalf("seed record: %v", err)
+	}
+
+	// Nothing may address the collection after its rename in one batch.
+	err = engine.CommitTx(ctx, []storage.TxOperation{
+		{Collection: "old", NewName: "new", Type: storage.TxOperationCollectionRename},
+		{Collection: "new", ID: "r2", Vector: []float32{0, 1}, Type: storage.TxOperationPut},
+	})
+	if err == nil {
+		t.Fatal("CommitTx accepted an operation after a rename")
+	}
+
+	err = engine.CommitTx(ctx, []storage.TxOperation{
+		{Collection: "old", ID: "r2", Vector: []float32{0, 1}, Metadata: map[string]interface{}{"n": "2"}, Type: storage.TxOperationPut},
+		{Collection: "old", Config: &storage.CollectionConfig{Dimension: 2, Version: 2, IndexedFields: []string{"n"}}, Type: storage.TxOperationCollectionConfig},
+		{Collection: "old", NewName: "new", Type: storage.TxOperationCollectionRename},
+		{Catalog: []byte("catalog-v2"), Type: storage.TxOperationCatalog},
+	})
+	if err != nil {
+		t.Fatalf("commit schema transaction: %v", err)
+	}
+
+	check := func(engine *Engine, stage string) {
+		t.Helper()
+		if _, err := engine.GetCollection("old"); err == nil {
+			t.Fatalf("%s: collection still reachable under its old name", stage)
+		}
+		col, cfg, err := engine.GetCollectionWithConfig("new")
+		if err != nil {
+			t.Fatalf("%s: get renamed collection: %v", stage, err)
+		}
+		if len(cfg.IndexedFields) != 1 || cfg.IndexedFields[0] != "n" {
+			t.Fatalf("%s: config = %+v", stage, cfg)
+		}
+		for _, id := range []string{"r1", "r2"} {
+			if _, err := col.Get(ctx, id); err != nil {
+				t.Fatalf("%s: get %s: %v", stage, id, err)
+			}
+		}
+		if got := string(engine.CatalogData()); got != "catalog-v2" {
+			t.Fatalf("%s: catalog = %q", stage, got)
+		}
+	}
+	check(engine, "live")
+
+	// Lose the process without a checkpoint so reopen replays the WAL.
+	engine.cancel()
+	if engine.walWriteArena != nil {
+		_ = engine.walWriteArena.Free()
+		engine.walWriteArena = nil
+	}
+	for _, persisted := range engine.state.Collections {
+		persisted.freeVectorSFLs()
+	}
+	if err := engine.file.Close(); err != nil {
+		t.Fatalf("crash close: %v", err)
+	}
+
+	reopenedIface, err := New(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	reopened := reopenedIface.(*Engine)
+	defer reopened.Close()
+	check(reopened, "replayed")
+}
+
+func TestCommitTxDropsViewsWithTheirCollections(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "drop-views.libravdb")
+	engineIface, err := New(path)
+	if err != nil {
+		t.Fatalf("new engine: %v", err)
+	}
+	engine := engineIface.(*Engine)
+	if _, err := engine.CreateCollection("docs_mv", &storage.CollectionConfig{Dimension: 2, Version: 2}); err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	if err := engine.CommitTx(ctx, []storage.TxOperation{
+		{Collection: "docs_mv", ID: "r1", Vector: []float32{1, 0}, Type: storage.TxOperationPut},
+	}); err != nil {
+		t.Fatalf("seed record: %v", err)
+	}
+	for name, definition := range map[string]storage.ViewDefinition{
+		"docs_mv": {Query: "SELECT id FROM docs", Materialized: true},
+		"recent":  {Query: "SELECT id FROM docs WHERE id > 'a'"},
+		"kept":    {Query: "SELECT id FROM docs"},
+	} {
+		if err := engine.PutView(name, definition); err != nil {
+			t.Fatalf("put view %s: %v", name, err)
+		}
+	}
+
+	// A missing view fails the whole batch before anything is written.
+	err = engine.CommitTx(ctx, []storage.TxOperation{
+		{Collection: "recent", Type: storage.TxOperationViewDrop},
+		{Collection: "missing", Type: storage.TxOperationViewDrop},
+	})
+	if err == nil {
+		t.Fatal("CommitTx dropped an unknown view")
+	}
+	// Nothing may address the collection after its delete in one batch.
+	err = engine.CommitTx(ctx, []storage.TxOperation{
+		{Collection: "docs_mv", Type: storage.TxOperationCollectionDelete},
+		{Collection: "docs_mv", ID: "r2", Vector: []float32{0, 1}, Type: storage.TxOperationPut},
+	})
+	if err == nil {
+		t.Fatal("CommitTx accepted an operation after a delete")
+	}
+	if views, _ := engine.ListViews(); len(views) != 3 {
+		t.Fatalf("rejected batches changed the view registry: %+v", views)
+	}
+
+	err = engine.CommitTx(ctx, []storage.TxOperation{
+		{Collection: "recent", Type: storage.TxOperationViewDrop},
+		{Collection: "docs_mv", Type: storage.TxOperationViewDrop},
+		{Collection: "docs_mv", Type: storage.TxOperationCollectionDelete},
+	})
+	if err != nil {
+		t.Fatalf("commit drop transaction: %v", err)
+	}
+
+	check := func(engine *Engine, stage string) {
+		t.Helper()
+		if _, err := engine.GetCollection("docs_mv"); err == nil {
+			t.Fatalf("%s: dropped collection still reachable", stage)
+		}
+		views, err := engine.ListViews()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(views) != 1 || views["kept"].Query != "SELECT id FROM docs" {
+			t.Fatalf("%s: views = %+v", stage, views)
+		}
+	}
+	check(engine, "live")
+
+	// Lose the process without a checkpoint so reopen replays the WAL.
+	engine.cancel()
+	if engine.walWriteArena != nil {
+		_ = engine.walWriteArena.Free()
+		engine.walWriteArena = nil
+	}
+	for _, persisted := range engine.state.Collections {
+		persisted.freeVectorSFLs()
+	}
+	if err := engine.file.Close(); err != nil {
+		t.Fatalf("crash close: %v", err)
+	}
+
+	reopenedIface, err := New(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	reopened := reopenedIface.(*Engine)
+	defer reopened.Close()
+	check(reopened, "replayed")
+}
+
+func TestViewRegistrySurvivesReplayAndCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.libravdb")
+	open := func() *Engine {
+		t.Helper()
+		engineIface, err := New(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return engineIface.(*Engine)
+	}
+	check := func(engine *Engine, stage string) {
+		t.Helper()
+		views, err := engine.ListViews()
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := storage.ViewDefinition{Query: "SELECT id FROM docs", Materialized: true}
+		if len(views) != 1 || views["docs_mv"] != want {
+			t.Fatalf("%s: views = %+v", stage, views)
+		}
+	}
+
+	engine := open()
+	if err := engine.PutView("recent", storage.ViewDefinition{Query: "SELECT id FROM docs WHERE id > 'a'"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.PutView("docs_mv", storage.ViewDefinition{Query: "SELECT id FROM docs", Materialized: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DropView("recent"); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.DropView("missing"); err != nil {
+		t.Fatalf("DropView of an unknown view: %v", err)
+	}
+	check(engine, "live")
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	engine = open()
+	check(engine, "WAL replay")
+	if err := engine.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	engine = open()
+	defer engine.Close()
+	check(engine, "snapshot")
+}
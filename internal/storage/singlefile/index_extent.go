@@ -0,0 +1,511 @@
+package singlefile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// An index extent holds the bulk region of an index that is too large to
+// serialize into memory, such as DiskANN's node file. The provider streams
+// the region while its index is serialized; the engine stores it as a run of
+// extent chunks ahead of the index chunk and records their location in the
+// index entry.
+//
+// In a plaintext file every extent chunk carries its region bytes from a
+// page boundary, so a recovered index can read the region in place with
+// aligned I/O instead of copying it out of the database first. A full chunk
+// is padded to end 16 bytes short of a page boundary, which puts the next
+// chunk's header there and its region bytes on the boundary. Sealed chunks
+// are laid end to end; their region bytes are only reachable through
+// decryption anyway.
+const (
+	// extentChunkBytes is the region carried by one plaintext extent
+	// chunk. It is a whole number of pages.
+	extentChunkBytes = 1 << 20
+	// sealedExtentChunkBytes is the region carried by one sealed extent
+	// chunk. Every read authenticates the whole chunk, so sealed chunks
+	// are kept small.
+	sealedExtentChunkBytes = 64 << 10
+	// sealedExtentCacheChunks is how many opened sealed chunks an extent
+	// keeps for repeated reads.
+	sealedExtentCacheChunks = 16
+)
+
+// errIndexExtentClosed is returned by reads of an extent that was closed, or
+// whose engine was.
+var errIndexExtentClosed = errors.New("index extent is closed")
+
+// extentPadding is the zero tail of a full plaintext extent chunk.
+var extentPadding [pageSize - 16]byte
+
+// indexExtentRef locates an extent. Chunk i's header is at offset+i*stride
+// and its payload starts with region bytes [i*chunkBytes, (i+1)*chunkBytes).
+type indexExtentRef struct {
+	offset     uint64
+	length     uint64
+	stride     uint64
+	chunkBytes uint32
+}
+
+func (r indexExtentRef) chunks() uint64 {
+	return (r.length + uint64(r.chunkBytes) - 1) / uint64(r.chunkBytes)
+}
+
+// dataBytes is the region length carried by chunk i.
+func (r indexExtentRef) dataBytes(i uint64) int {
+	return int(min(uint64(r.chunkBytes), r.length-i*uint64(r.chunkBytes)))
+}
+
+// diskBytes approximates the file bytes the extent occupies.
+func (r indexExtentRef) diskBytes() int64 {
+	return int64(r.chunks() * r.stride)
+}
+
+// extentLayout returns the chunk size and stride extent chunks use in this
+// file.
+func (e *Engine) extentLayout() (chunkBytes uint32, stride uint64) {
+	if e.cipher != nil {
+		return sealedExtentChunkBytes, 16 + sealedOverhead + sealedExtentChunkBytes
+	}
+	return extentChunkBytes, extentChunkBytes + pageSize
+}
+
+// validateExtentRef checks that ref describes extent chunks of this file
+// that lie within size bytes.
+func (e *Engine) validateExtentRef(ref indexExtentRef, size int64) error {
+	chunkBytes, stride := e.extentLayout()
+	if ref.chunkBytes != chunkBytes || ref.stride != stride {
+		return fmt.Errorf("index extent chunks of %d bytes every %d, want %d every %d", ref.chunkBytes, ref.stride, chunkBytes, stride)
+	}
+	if e.cipher == nil && (ref.offset+16)%pageSize != 0 {
+		return fmt.Errorf("index extent at offset %d is not page aligned", ref.offset)
+	}
+	chunks := ref.chunks()
+	if chunks == 0 {
+		return nil
+	}
+	if size < 0 || chunks-1 > (math.MaxInt64-ref.offset)/ref.stride {
+		return fmt.Errorf("index extent at offset %d overflows the file address range", ref.offset)
+	}
+	if last := ref.offset + (chunks-1)*ref.stride; last+16 > uint64(size) {
+		return fmt.Errorf("index extent at offset %d ends beyond file size %d", ref.offset, size)
+	}
+	return nil
+}
+
+// IndexExtent is an index region stored in the database file. It stays
+// readable across checkpoints, Vacuum and compaction until it is closed.
+type IndexExtent interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+// indexExtent reads a region from its extent chunks. Plaintext chunks are
+// verified against their checksum on first use and then read in place;
+// sealed chunks are opened on every cache miss.
+type indexExtent struct {
+	engine *Engine
+	// ref, verified and closed are guarded by engine.extentMu.
+	ref      indexExtentRef
+	verified []uint64
+	closed   bool
+
+	cacheMu sync.Mutex
+	cache   [sealedExtentCacheChunks]openedExtentChunk
+}
+
+type openedExtentChunk struct {
+	data  []byte
+	index uint64
+}
+
+// openIndexExtent registers an extent recovered from the index block.
+func (e *Engine) openIndexExtent(ref indexExtentRef) (*indexExtent, error) {
+	e.extentMu.Lock()
+	defer e.extentMu.Unlock()
+	stat, err := e.file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := e.validateExtentRef(ref, stat.Size()); err != nil {
+		return nil, err
+	}
+	x := &indexExtent{engine: e}
+	x.moveLocked(ref)
+	if e.extents == nil {
+		e.extents = make(map[*indexExtent]struct{})
+	}
+	e.extents[x] = struct{}{}
+	return x, nil
+}
+
+// moveLocked points x at ref. Caller must hold engine.extentMu exclusively.
+func (x *indexExtent) moveLocked(ref indexExtentRef) {
+	x.ref = ref
+	x.verified = make([]uint64, (ref.chunks()+63)/64)
+	x.cacheMu.Lock()
+	x.cache = [sealedExtentCacheChunks]openedExtentChunk{}
+	x.cacheMu.Unlock()
+}
+
+// Size returns the region length.
+func (x *indexExtent) Size() int64 {
+	x.engine.extentMu.RLock()
+	defer x.engine.extentMu.RUnlock()
+	return int64(x.ref.length)
+}
+
+// Close releases the extent. Later reads fail.
+func (x *indexExtent) Close() error {
+	e := x.engine
+	e.extentMu.Lock()
+	defer e.extentMu.Unlock()
+	x.closed = true
+	delete(e.extents, x)
+	return nil
+}
+
+// ReadAt reads region bytes starting at off.
+func (x *indexExtent) ReadAt(p []byte, off int64) (int, error) {
+	e := x.engine
+	e.extentMu.RLock()
+	defer e.extentMu.RUnlock()
+	if x.closed || e.closed.Load() || e.file == nil {
+		return 0, errIndexExtentClosed
+	}
+	if off < 0 {
+		return 0, fmt.Errorf("index extent read at negative offset %d", off)
+	}
+	n := 0
+	for n < len(p) && uint64(off) < x.ref.length {
+		chunk := uint64(off) / uint64(x.ref.chunkBytes)
+		within := int(uint64(off) % uint64(x.ref.chunkBytes))
+		want := min(len(p)-n, x.ref.dataBytes(chunk)-within)
+		if err := x.readChunkLocked(p[n:n+want], chunk, within); err != nil {
+			return n, err
+		}
+		n += want
+		off += int64(want)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// readChunkLocked fills p from chunk at within. Caller must hold
+// engine.extentMu.
+func (x *indexExtent) readChunkLocked(p []byte, chunk uint64, within int) error {
+	e := x.engine
+	offset := x.ref.offset + chunk*x.ref.stride
+	if e.cipher == nil {
+		word, bit := &x.verified[chunk/64], uint64(1)<<(chunk%64)
+		if atomic.LoadUint64(word)&bit != 0 {
+			_, err := e.file.ReadAt(p, int64(offset)+16+int64(within))
+			return err
+		}
+		data, err := x.loadChunkLocked(chunk)
+		if err != nil {
+			return err
+		}
+		atomic.OrUint64(word, bit)
+		copy(p, data[within:])
+		return nil
+	}
+
+	slot := &x.cache[chunk%sealedExtentCacheChunks]
+	x.cacheMu.Lock()
+	if slot.data != nil && slot.index == chunk {
+		copy(p, slot.data[within:])
+		x.cacheMu.Unlock()
+		return nil
+	}
+	x.cacheMu.Unlock()
+	data, err := x.loadChunkLocked(chunk)
+	if err != nil {
+		return err
+	}
+	copy(p, data[within:])
+	x.cacheMu.Lock()
+	*slot = openedExtentChunk{data: data, index: chunk}
+	x.cacheMu.Unlock()
+	return nil
+}
+
+// loadChunkLocked reads, checks and opens chunk, returning its region bytes.
+func (x *indexExtent) loadChunkLocked(chunk uint64) ([]byte, error) {
+	payload, err := x.engine.readChunkAtKind(x.ref.offset+chunk*x.ref.stride, chunkTypeExtent)
+	if err != nil {
+		return nil, fmt.Errorf("index extent: %w", err)
+	}
+	want := x.ref.dataBytes(chunk)
+	if len(payload) < want {
+		return nil, fmt.Errorf("index extent chunk %d holds %d bytes, want %d", chunk, len(payload), want)
+	}
+	return payload[:want], nil
+}
+
+// indexExtentWriter stores a streamed region as extent chunks in file,
+// starting at the first suitable position at or after next.
+type indexExtentWriter struct {
+	engine *Engine
+	file   *os.File
+	next   int64
+	// live is set when file is the engine's own file, where an unchanged
+	// extent can be referenced instead of written again.
+	live bool
+	buf  []byte
+	ref  indexExtentRef
+	// base is the extent the streamed region supersedes; reused means the
+	// entry references base instead of a new extent.
+	base    *indexExtent
+	reused  bool
+	started bool
+}
+
+func (e *Engine) newIndexExtentWriter(file *os.File, next int64, live bool) *indexExtentWriter {
+	chunkBytes, stride := e.extentLayout()
+	return &indexExtentWriter{
+		engine: e,
+		file:   file,
+		next:   next,
+		live:   live,
+		ref:    indexExtentRef{stride: stride, chunkBytes: chunkBytes},
+	}
+}
+
+// RebaseExtent records that the region being written replaces base, an
+// extent of this engine the index still reads unmodified bytes from, so
+// base can follow the new extent once it is durable. When the region is
+// unchanged since base and both live in the engine's file, the entry
+// references base instead and RebaseExtent returns true; nothing is written.
+func (w *indexExtentWriter) RebaseExtent(base io.ReaderAt, unchanged bool) bool {
+	x, ok := base.(*indexExtent)
+	if !ok || x.engine != w.engine || w.started {
+		return false
+	}
+	w.engine.extentMu.RLock()
+	defer w.engine.extentMu.RUnlock()
+	if x.closed {
+		return false
+	}
+	if unchanged && w.live {
+		w.ref = x.ref
+		w.reused = true
+		return true
+	}
+	w.base = x
+	return false
+}
+
+// Write buffers region bytes and writes every full chunk.
+func (w *indexExtentWriter) Write(p []byte) (int, error) {
+	if w.reused {
+		return 0, fmt.Errorf("index extent reuses its base; nothing may be written")
+	}
+	written := 0
+	for len(p) > 0 {
+		if w.buf == nil {
+			w.buf = make([]byte, 0, w.ref.chunkBytes)
+		}
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+		if len(w.buf) == cap(w.buf) {
+			if err := w.flush(); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// flush writes the buffered region bytes as one chunk.
+func (w *indexExtentWriter) flush() error {
+	e := w.engine
+	if !w.started {
+		if err := w.start(); err != nil {
+			return err
+		}
+	}
+	var padding []byte
+	if e.cipher == nil && len(w.buf) == int(w.ref.chunkBytes) {
+		padding = extentPadding[:]
+	}
+	chunk, err := e.encodeChunk(chunkTypeExtent, uint64(w.next), w.buf, padding)
+	if err != nil {
+		return err
+	}
+	if err := chunk.writeAt(w.file, w.next); err != nil {
+		return fmt.Errorf("write index extent: %w", err)
+	}
+	w.next += int64(chunk.size())
+	w.ref.length += uint64(len(w.buf))
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// start places the first chunk. A plaintext extent needs its first header
+// 16 bytes before a page boundary; a padding chunk fills the gap so scans
+// of the file still step from chunk to chunk.
+func (w *indexExtentWriter) start() error {
+	w.started = true
+	if w.engine.cipher != nil {
+		w.ref.offset = uint64(w.next)
+		return nil
+	}
+	header := alignUp(w.next+16, pageSize) - 16
+	if gap := header - w.next; gap > 0 {
+		if gap < 16 {
+			header += pageSize
+			gap += pageSize
+		}
+		padding := make([]byte, gap-16)
+		chunk, err := w.engine.encodeChunk(chunkTypeExtent, uint64(w.next), nil, padding)
+		if err != nil {
+			return err
+		}
+		if err := chunk.writeAt(w.file, w.next); err != nil {
+			return fmt.Errorf("write index extent: %w", err)
+		}
+	}
+	w.next = header
+	w.ref.offset = uint64(header)
+	return nil
+}
+
+// finish writes the final partial chunk and returns the extent.
+func (w *indexExtentWriter) finish() (indexExtentRef, error) {
+	if w.reused {
+		return w.ref, nil
+	}
+	if len(w.buf) > 0 {
+		if err := w.flush(); err != nil {
+			return indexExtentRef{}, err
+		}
+	}
+	if !w.started {
+		// An empty region still needs a position that validates.
+		if err := w.start(); err != nil {
+			return indexExtentRef{}, err
+		}
+	}
+	return w.ref, nil
+}
+
+func alignUp(n, align int64) int64 {
+	return (n + align - 1) / align * align
+}
+
+// serializeIndexBlock serializes the index and derived images of every named
+// collection as of checkpointLSN. Extents stream into file from offset; end is the offset
+// after the last one. live marks file as the engine's own file. The returned
+// writers carry the extents that supersede ones recovered indexes read.
+func (e *Engine) serializeIndexBlock(names []string, checkpointLSN uint64, file *os.File, offset int64, live bool) (block []byte, writers []*indexExtentWriter, end int64, err error) {
+	sort.Strings(names)
+	entries := make([]indexBlockEntry, 0, len(names))
+	for _, name := range names {
+		w := e.newIndexExtentWriter(file, offset, live)
+		entry, present, err := e.serializeIndexEntry(name, checkpointLSN, w)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("serialize index for %s: %w", name, err)
+		}
+		if !present {
+			continue
+		}
+		if entry.hasExtent {
+			offset = max(offset, w.next)
+			writers = append(writers, w)
+		}
+		entries = append(entries, entry)
+	}
+	for _, name := range names {
+		derived, err := e.serializeDerivedIndexEntries(name, checkpointLSN)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("serialize derived indexes for %s: %w", name, err)
+		}
+		entries = append(entries, derived...)
+	}
+	if len(entries) > 0 {
+		block = encodeIndexBlock(entries)
+	}
+	return block, writers, offset, nil
+}
+
+// carryIndexExtents copies every live extent that no writer supersedes into
+// file, so indexes still reading one keep working once file replaces the
+// engine's file.
+func (e *Engine) carryIndexExtents(writers []*indexExtentWriter, file *os.File, offset int64) ([]*indexExtentWriter, int64, error) {
+	superseded := make(map[*indexExtent]bool, len(writers))
+	for _, w := range writers {
+		if w.base != nil {
+			superseded[w.base] = true
+		}
+	}
+	e.extentMu.RLock()
+	var carried []*indexExtent
+	for x := range e.extents {
+		if !superseded[x] {
+			carried = append(carried, x)
+		}
+	}
+	e.extentMu.RUnlock()
+	for _, x := range carried {
+		w := e.newIndexExtentWriter(file, offset, false)
+		w.base = x
+		if _, err := io.Copy(w, io.NewSectionReader(x, 0, x.Size())); err != nil {
+			if errors.Is(err, errIndexExtentClosed) {
+				continue
+			}
+			return nil, 0, fmt.Errorf("carry index extent: %w", err)
+		}
+		if _, err := w.finish(); err != nil {
+			return nil, 0, fmt.Errorf("carry index extent: %w", err)
+		}
+		offset = w.next
+		writers = append(writers, w)
+	}
+	return writers, offset, nil
+}
+
+// rebaseIndexExtentsLocked points every extent a writer superseded at the
+// writer's extent, once that is durable in e.file. After a file swap,
+// replaced is set and extents left behind can no longer be read. Caller
+// must hold e.extentMu exclusively.
+func (e *Engine) rebaseIndexExtentsLocked(writers []*indexExtentWriter, replaced bool) {
+	moved := make(map[*indexExtent]bool, len(writers))
+	for _, w := range writers {
+		if w.base == nil || w.reused || w.base.closed {
+			continue
+		}
+		w.base.moveLocked(w.ref)
+		moved[w.base] = true
+	}
+	if !replaced {
+		return
+	}
+	for x := range e.extents {
+		if !moved[x] {
+			x.closed = true
+			delete(e.extents, x)
+		}
+	}
+}
+
+// extentDiskBytes sums the file bytes of the extents an index block refers
+// to.
+func extentDiskBytes(writers []*indexExtentWriter) int64 {
+	var size int64
+	for _, w := range writers {
+		size += w.ref.diskBytes()
+	}
+	return size
+}
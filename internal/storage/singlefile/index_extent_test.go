@@ -0,0 +1,192 @@
+package singlefile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// extentIndexProvider streams region as the index extent. Once an index is
+// restored it offers the recovered extent as the base of later writes.
+type extentIndexProvider struct {
+	recoveryIndexProvider
+	region   []byte
+	base     IndexExtent
+	modified bool
+	reused   int
+}
+
+func (p *extentIndexProvider) SerializeIndexExtentAt(_ string, checkpointLSN uint64, extent io.Writer) ([]byte, uint64, bool, error) {
+	if rebaser, ok := extent.(interface {
+		RebaseExtent(io.ReaderAt, bool) bool
+	}); ok && p.base != nil {
+		if rebaser.RebaseExtent(p.base, !p.modified) {
+			p.reused++
+			return []byte("extent-index"), checkpointLSN, true, nil
+		}
+	}
+	if _, err := extent.Write(p.region); err != nil {
+		return nil, 0, false, err
+	}
+	return []byte("extent-index"), checkpointLSN, true, nil
+}
+
+func (p *extentIndexProvider) DeserializeIndexExtent(name string, indexBytes []byte, extent IndexExtent, _ *storage.CollectionConfig) error {
+	if string(indexBytes) != "extent-index" {
+		extent.Close()
+		return errors.New("unexpected index bytes")
+	}
+	p.base = extent
+	p.mu.Lock()
+	p.deserialized = append(p.deserialized, name)
+	p.mu.Unlock()
+	return nil
+}
+
+func readExtent(t *testing.T, extent IndexExtent) []byte {
+	t.Helper()
+	data := make([]byte, extent.Size())
+	if _, err := extent.ReadAt(data, 0); err != nil {
+		t.Fatalf("read extent: %v", err)
+	}
+	// An unaligned read that crosses a chunk boundary.
+	if len(data) > extentChunkBytes+10 {
+		part := make([]byte, 20)
+		if _, err := extent.ReadAt(part, extentChunkBytes-10); err != nil {
+			t.Fatalf("read across chunks: %v", err)
+		}
+		if !bytes.Equal(part, data[extentChunkBytes-10:extentChunkBytes+10]) {
+			t.Fatal("read across chunks differs from full read")
+		}
+	}
+	return data
+}
+
+func TestIndexExtentSurvivesCheckpointVacuumAndCompaction(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "plaintext"},
+		{name: "encrypted", opts: []Option{WithEncryption(StaticKey(bytes.Repeat([]byte{7}, 32)))}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			path := filepath.Join(t.TempDir(), "extent.libravdb")
+			region := make([]byte, 2*extentChunkBytes+12345)
+			rand.New(rand.NewSource(1)).Read(region)
+
+			open := func(provider *extentIndexProvider) *Engine {
+				t.Helper()
+				engine, err := New(path, append([]Option{WithIndexSnapshotProvider(provider)}, tc.opts...)...)
+				if err != nil {
+					t.Fatalf("open: %v", err)
+				}
+				return engine.(*Engine)
+			}
+			insert := func(engine *Engine, id string) {
+				t.Helper()
+				coll, err := engine.GetCollection("vectors")
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := coll.Insert(ctx, &index.VectorEntry{ID: id, Vector: []float32{1, 0, 0}}); err != nil {
+					t.Fatalf("insert %s: %v", id, err)
+				}
+			}
+
+			engine := open(&extentIndexProvider{region: region})
+			if _, err := engine.CreateCollection("vectors", &storage.CollectionConfig{
+				Dimension:      3,
+				RawVectorStore: "memory",
+				RawStoreCap:    16,
+			}); err != nil {
+				t.Fatalf("create collection: %v", err)
+			}
+			insert(engine, "a")
+			if err := engine.Checkpoint(); err != nil {
+				t.Fatalf("checkpoint: %v", err)
+			}
+			if err := engine.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+
+			provider := &extentIndexProvider{}
+			engine = open(provider)
+			if provider.base == nil {
+				t.Fatalf("index was not restored from its extent (rebuilt %v)", provider.rebuilt)
+			}
+			if got := readExtent(t, provider.base); !bytes.Equal(got, region) {
+				t.Fatal("restored extent differs from the streamed region")
+			}
+			if tc.opts == nil {
+				ref := provider.base.(*indexExtent).ref
+				if (ref.offset+16)%pageSize != 0 {
+					t.Fatalf("extent data at offset %d is not page aligned", ref.offset+16)
+				}
+			}
+
+			// An unchanged index references its extent instead of writing
+			// it again.
+			before, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			insert(engine, "b")
+			if err := engine.Checkpoint(); err != nil {
+				t.Fatalf("checkpoint: %v", err)
+			}
+			after, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if provider.reused != 1 || after.Size()-before.Size() >= int64(len(region)) {
+				t.Fatalf("unchanged extent rewritten: reused %d, file grew %d bytes", provider.reused, after.Size()-before.Size())
+			}
+
+			// Vacuum and compaction move the extent; the index keeps
+			// reading it from the new file.
+			provider.modified = true
+			provider.region = region
+			insert(engine, "c")
+			if err := engine.Vacuum(ctx); err != nil {
+				t.Fatalf("vacuum: %v", err)
+			}
+			if got := readExtent(t, provider.base); !bytes.Equal(got, region) {
+				t.Fatal("extent differs after vacuum")
+			}
+			insert(engine, "d")
+			if err := engine.Compact(); err != nil {
+				t.Fatalf("compact: %v", err)
+			}
+			if got := readExtent(t, provider.base); !bytes.Equal(got, region) {
+				t.Fatal("extent differs after compaction")
+			}
+			held := provider.base
+			if err := engine.Close(); err != nil {
+				t.Fatalf("close: %v", err)
+			}
+			if _, err := held.ReadAt(make([]byte, 1), 0); !errors.Is(err, errIndexExtentClosed) {
+				t.Fatalf("read after close: %v, want %v", err, errIndexExtentClosed)
+			}
+
+			provider = &extentIndexProvider{}
+			engine = open(provider)
+			defer engine.Close()
+			if provider.base == nil {
+				t.Fatalf("index was not restored after compaction (rebuilt %v)", provider.rebuilt)
+			}
+			if got := readExtent(t, provider.base); !bytes.Equal(got, region) {
+				t.Fatal("extent differs after reopening the compacted file")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package singlefile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrReadOnly is returned by every mutation on an engine opened with
+	// WithReadOnly.
+	ErrReadOnly = errors.New("database is opened read-only")
+	// ErrDatabaseLocked reports a database file held by another engine in a
+	// conflicting mode: a writer excludes every other engine, readers exclude
+	// writers.
+	ErrDatabaseLocked = errors.New("database file is locked by another engine")
+)
+
+// errLockHeld is returned by lockFile when a conflicting lock is held.
+var errLockHeld = errors.New("file lock held")
+
+// WithReadOnly opens an existing database without writing to it. The file is
+// opened read-only under a shared lock, so any number of readers may open it
+// while no writer has it open. Recovery replays the WAL in memory only and
+// checkpoints are skipped; mutations and Vacuum fail with ErrReadOnly.
+// Backup and the read APIs work as usual.
+func WithReadOnly() Option {
+	return func(e *Engine) error {
+		e.readOnly = true
+		return nil
+	}
+}
+
+// lockDatabaseFile takes the advisory lock on file for this engine's mode:
+// shared for a read-only engine, exclusive otherwise. It does not wait for a
+// conflicting lock to be released.
+func (e *Engine) lockDatabaseFile(file *os.File) error {
+	err := lockFile(file, !e.readOnly)
+	switch {
+	case errors.Is(err, errLockHeld) && e.readOnly:
+		return fmt.Errorf("%w: %s is open for writing", ErrDatabaseLocked, e.path)
+	case errors.Is(err, errLockHeld):
+		return fmt.Errorf("%w: %s is already open", ErrDatabaseLocked, e.path)
+	case err != nil:
+		return fmt.Errorf("lock database file: %w", err)
+	}
+	return nil
+}
+
+// reopenDatabaseFile opens e.path again after Vacuum or compaction replaced
+// it, and locks the new file. Closing the old descriptor released its lock;
+// if another engine locked the new file in between, this engine must stop.
+func (e *Engine) reopenDatabaseFile() (*os.File, error) {
+	file, err := os.OpenFile(e.path, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.lockDatabaseFile(file); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
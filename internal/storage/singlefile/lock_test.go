@@ -0,0 +1,119 @@
+package singlefile
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+func TestFileLockAndReadOnlyOpen(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "locked.libravdb")
+	writerIface, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := writerIface.(*Engine)
+	if _, err := New(path); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("second writer: %v, want ErrDatabaseLocked", err)
+	}
+	if _, err := New(path, WithReadOnly()); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("reader beside a writer: %v, want ErrDatabaseLocked", err)
+	}
+	coll, err := writer.CreateCollection("docs", &storage.CollectionConfig{Dimension: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := coll.Insert(ctx, &index.VectorEntry{ID: "a", Ordinal: 1, Vector: []float32{1, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	// The replacement file written by Vacuum must be locked too.
+	if err := writer.Vacuum(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(path); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("second writer after Vacuum: %v, want ErrDatabaseLocked", err)
+	}
+	if err := coll.Insert(ctx, &index.VectorEntry{ID: "b", Ordinal: 2, Vector: []float32{0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lose the process so "b" is only in the WAL and replay has work to do.
+	writer.cancel()
+	if writer.walWriteArena != nil {
+		_ = writer.walWriteArena.Free()
+		writer.walWriteArena = nil
+	}
+	for _, persisted := range writer.state.Collections {
+		persisted.freeVectorSFLs()
+	}
+	if err := writer.file.Close(); err != nil {
+		t.Fatal(err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var readers []*Engine
+	for i := 0; i < 2; i++ {
+		reader, err := New(path, WithReadOnly())
+		if err != nil {
+			t.Fatalf("reader %d: %v", i, err)
+		}
+		readers = append(readers, reader.(*Engine))
+	}
+	if _, err := New(path); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("writer beside readers: %v, want ErrDatabaseLocked", err)
+	}
+	reader := readers[0]
+	coll, err = reader.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := coll.Get(ctx, "b"); err != nil {
+		t.Fatalf("Get replayed record: %v", err)
+	}
+	if err := coll.Insert(ctx, &index.VectorEntry{ID: "c", Ordinal: 3, Vector: []float32{1, 1}}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Insert on reader: %v, want ErrReadOnly", err)
+	}
+	if _, err := reader.CreateCollection("other", &storage.CollectionConfig{Dimension: 2}); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("CreateCollection on reader: %v, want ErrReadOnly", err)
+	}
+	if err := reader.Vacuum(ctx); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Vacuum on reader: %v, want ErrReadOnly", err)
+	}
+	backupPath := filepath.Join(t.TempDir(), "backup.libravdb")
+	if err := reader.Backup(ctx, backupPath); err != nil {
+		t.Fatalf("Backup from reader: %v", err)
+	}
+	for _, r := range readers {
+		if err := r.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	after, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(before, after) {
+		t.Fatal("read-only engines modified the database file")
+	}
+
+	writerIface, err = New(path)
+	if err != nil {
+		t.Fatalf("writer after readers closed: %v", err)
+	}
+	if err := writerIface.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(filepath.Join(t.TempDir(), "missing.libravdb"), WithReadOnly()); err == nil {
+		t.Fatal("read-only open created a missing database")
+	}
+}
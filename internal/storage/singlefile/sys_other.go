@@ -0,0 +1,20 @@
+//go:build !unix && !windows
+
+package singlefile
+
+import (
+	"errors"
+	"os"
+	"runtime"
+)
+
+const oNoFollow = 0
+
+// errLockUnsupported is returned on platforms without an advisory file lock.
+// Opening a database there fails rather than running without the guarantee
+// that only one writer has the file open.
+var errLockUnsupported = errors.New("file locking is not supported on " + runtime.GOOS)
+
+func lockFile(*os.File, bool) error {
+	return errLockUnsupported
+}
@@ -1,7 +1,29 @@
-//go:build !windows
+//go:build unix
 
 package singlefile
 
-import "syscall"
+import (
+	"os"
+	"syscall"
+)
 
 const oNoFollow = syscall.O_NOFOLLOW
+
+// lockFile takes a non-blocking flock on file. The lock belongs to the open
+// file description and is released when file is closed.
+func lockFile(file *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	for {
+		err := syscall.Flock(int(file.Fd()), how|syscall.LOCK_NB)
+		switch err {
+		case syscall.EINTR:
+			continue
+		case syscall.EWOULDBLOCK:
+			return errLockHeld
+		}
+		return err
+	}
+}
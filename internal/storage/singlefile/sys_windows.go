@@ -2,4 +2,26 @@
 
 package singlefile
 
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
 const oNoFollow = 0
+
+// lockFile takes a non-blocking LockFileEx lock on file. Windows byte-range
+// locks are mandatory, so the lock covers one byte far past any real file
+// offset instead of the header. It is released when file is closed.
+func lockFile(file *os.File, exclusive bool) error {
+	flags := uint32(windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if exclusive {
+		flags |= windows.LOCKFILE_EXCLUSIVE_LOCK
+	}
+	overlapped := &windows.Overlapped{Offset: 0xFFFFFFFF, OffsetHigh: 0x7FFFFFFF}
+	err := windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, overlapped)
+	if err == windows.ERROR_LOCK_VIOLATION {
+		return errLockHeld
+	}
+	return err
+}
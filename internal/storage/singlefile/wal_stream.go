@@ -0,0 +1,384 @@
+package singlefile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"sync/atomic"
+
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+var (
+	// ErrWALTruncated is returned by ReadWAL when transactions after the
+	// requested LSN were folded into a snapshot by compaction or backup. The
+	// follower must be re-seeded from a fresh backup.
+	ErrWALTruncated = errors.New("requested WAL history has been compacted")
+	// ErrReplicaReadOnly is returned by every local mutation on an engine
+	// opened with WithReplica.
+	ErrReplicaReadOnly = errors.New("database is a read-only replica")
+)
+
+// walGenerations hands out process-unique WAL layout generations so a cursor
+// taken from one engine instance, or from before a compaction, can never
+// resume at a byte offset of a different layout.
+var walGenerations atomic.Uint64
+
+// pendingWALTx accumulates the raw chunks of a transaction whose TxCommit
+// frame has not been reached yet.
+type pendingWALTx struct {
+	raw    []byte
+	frames []walRecord
+	start  int64
+}
+
+// ReadWAL returns up to limit committed transactions with a commit LSN above
+// cursor.LSN, in log order, and the cursor that resumes after them. Chunk and
+// frame checksums are verified; aborted and incomplete transactions are never
+// returned. The scan resumes at the cursor offset when it belongs to the
+// current WAL layout and otherwise rescans the retained log.
+func (e *Engine) ReadWAL(ctx context.Context, cursor storage.WALCursor, limit int) ([]storage.WALTransaction, storage.WALCursor, error) {
+	if limit <= 0 {
+		return nil, cursor, fmt.Errorf("WAL read limit must be positive")
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed.Load() {
+		return nil, cursor, fmt.Errorf("database is closed")
+	}
+	if cursor.LSN < e.state.WALFloorLSN {
+		return nil, cursor, fmt.Errorf("%w: LSN %d < WAL floor %d", ErrWALTruncated, cursor.LSN, e.state.WALFloorLSN)
+	}
+	txs, next, err := e.scanCommittedWALLocked(ctx, cursor, limit, 0)
+	if err != nil {
+		return nil, cursor, err
+	}
+	out := make([]storage.WALTransaction, len(txs))
+	for i := range txs {
+		out[i] = txs[i].WALTransaction
+	}
+	return out, next, nil
+}
+
+// committedWALTx is a committed transaction read back from the log, raw and
+// decoded.
+type committedWALTx struct {
+	storage.WALTransaction
+	frames []walRecord
+}
+
+// scanCommittedWALLocked collects up to limit committed transactions with a
+// commit LSN above cursor.LSN, stopping before the first commit above maxLSN
+// when maxLSN is non-zero. Caller must hold e.mu.
+func (e *Engine) scanCommittedWALLocked(ctx context.Context, cursor storage.WALCursor, limit int, maxLSN uint64) ([]committedWALTx, storage.WALCursor, error) {
+	stat, err := e.file.Stat()
+	if err != nil {
+		return nil, cursor, err
+	}
+	fileSize := stat.Size()
+	logStart := e.walReplayStart
+	if logStart < int64(3*pageSize) {
+		logStart = int64(3 * pageSize)
+	}
+	offset := cursor.Offset
+	if cursor.Generation != e.walGeneration || offset < logStart || offset > fileSize {
+		offset = logStart
+	}
+
+	next := storage.WALCursor{LSN: cursor.LSN, Offset: offset, Generation: e.walGeneration}
+	pending := make(map[uint64]*pendingWALTx)
+	var out []committedWALTx
+	started := offset != logStart
+scan:
+	for offset <= fileSize-16 && len(out) < limit {
+		if err := ctx.Err(); err != nil {
+			return nil, cursor, err
+		}
+		var headerBuf [16]byte
+		if _, err := e.file.ReadAt(headerBuf[:], offset); err != nil {
+			return nil, cursor, err
+		}
+		chunk := decodeChunkHeader(headerBuf[:])
+		if chunk.Magic != chunkMagic {
+			// Same page-gap rules as replayWALFrom: reserved pages may
+			// precede the first chunk and padding may separate chunks.
+			if rem := offset % int64(pageSize); rem != 0 {
+				offset += int64(pageSize) - rem
+				continue
+			}
+			if !started {
+				offset += int64(pageSize)
+				continue
+			}
+			break
+		}
+		started = true
+		chunkEnd := offset + 16 + int64(chunk.PayloadLen)
+		if chunk.PayloadLen > maxChunkSize || chunkEnd < offset || chunkEnd > fileSize {
+			break
+		}
+		if chunk.Kind != chunkTypeWAL {
+			offset = chunkEnd
+			next.Offset = offset
+			continue
+		}
+		raw := make([]byte, 16+chunk.PayloadLen)
+		if _, err := e.file.ReadAt(raw, offset); err != nil {
+			return nil, cursor, err
+		}
+		if crc32.Checksum(raw[16:], castagnoli) != chunk.Checksum {
+			return nil, cursor, fmt.Errorf("invalid WAL chunk checksum at offset %d", offset)
+		}
+		if e.cipher != nil {
+			// WAL is shipped in plaintext framing; each replica seals it
+			// under its own key as ApplyWAL appends it.
+			payload, err := e.openChunk(uint64(offset), raw[:16], raw[16:])
+			if err != nil {
+				return nil, cursor, fmt.Errorf("WAL chunk at offset %d: %w", offset, err)
+			}
+			raw = plaintextChunk(chunkTypeWAL, payload)
+		}
+		record, err := decodeWALRecord(raw[16:])
+		if err != nil {
+			return nil, cursor, fmt.Errorf("WAL frame at offset %d: %w", offset, err)
+		}
+		txID := record.Header.TxID
+		switch record.Header.RecordType {
+		case recordTypeTxBegin:
+			pending[txID] = &pendingWALTx{start: offset, raw: raw, frames: []walRecord{record}}
+		case recordTypeTxAbort:
+			delete(pending, txID)
+		case recordTypeTxCommit:
+			if maxLSN != 0 && record.Header.LSN > maxLSN {
+				break scan
+			}
+			tx := pending[txID]
+			delete(pending, txID)
+			if tx != nil && record.Header.LSN > cursor.LSN {
+				out = append(out, committedWALTx{
+					WALTransaction: storage.WALTransaction{
+						TxID:      txID,
+						CommitLSN: record.Header.LSN,
+						Frames:    append(tx.raw, raw...),
+					},
+					frames: append(tx.frames, record),
+				})
+				next.LSN = record.Header.LSN
+			}
+		default:
+			// A frame without a visible TxBegin belongs to a transaction
+			// that began before the cursor; its commit was already shipped.
+			if tx := pending[txID]; tx != nil {
+				tx.raw = append(tx.raw, raw...)
+				tx.frames = append(tx.frames, record)
+			}
+		}
+		offset = chunkEnd
+		next.Offset = offset
+	}
+	// Resume at the earliest transaction still waiting for its commit so it
+	// is shipped whole once the commit frame is written.
+	for _, tx := range pending {
+		if tx.start < next.Offset {
+			next.Offset = tx.start
+		}
+	}
+	return out, next, nil
+}
+
+// ApplyWAL appends one shipped transaction to this replica's log and applies
+// it. The frames keep the primary's LSNs and TxIDs, so LatestCommitLSN tracks
+// the primary's commit frontier. Transactions at or below the local LSN are
+// ignored, which makes re-delivery after a reconnect harmless.
+func (e *Engine) ApplyWAL(ctx context.Context, tx storage.WALTransaction) ([]storage.WALChange, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	frames, err := decodeShippedFrames(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.closed.Load() {
+		return nil, fmt.Errorf("database is closed")
+	}
+	if !e.replica {
+		return nil, fmt.Errorf("ApplyWAL requires an engine opened with WithReplica")
+	}
+	if e.writesDisabled.Load() {
+		return nil, errRecoveryRequired
+	}
+	if tx.CommitLSN <= e.lastLSN.Load() {
+		return nil, nil
+	}
+	changes, err := e.walChangesLocked(frames)
+	if err != nil {
+		return nil, err
+	}
+
+	written, err := e.appendTransactionLocked(frames)
+	if err != nil {
+		if isAmbiguousWriteError(err) {
+			e.disableWrites()
+		}
+		return nil, err
+	}
+	if err := e.syncWALLocked(); err != nil {
+		e.disableWrites()
+		return nil, err
+	}
+	// The transaction is durable locally; a failure to apply it leaves the
+	// in-memory state behind the log, which only reopening can repair.
+	touched := make(map[string]struct{})
+	if err := e.applyCommittedFrames(frames, touched, nil); err != nil {
+		e.disableWrites()
+		return nil, fmt.Errorf("apply replicated transaction %d at LSN %d: %w", tx.TxID, tx.CommitLSN, err)
+	}
+	commit := frames[len(frames)-1]
+	if payload, ok := decodeTxCommitPayload(commit.Payload); ok {
+		e.recordCommitLocked(commit.Header.LSN, payload.Timestamp)
+	}
+	e.lastLSN.Store(commit.Header.LSN)
+	if commit.Header.TxID > e.lastTxID.Load() {
+		e.lastTxID.Store(commit.Header.TxID)
+	}
+	e.markDirtyLocked(written, len(frames)-2)
+	if err := e.maybeCheckpointLocked(); err != nil {
+		return changes, err
+	}
+	return changes, nil
+}
+
+// AppliedLSN returns the LSN of the last transaction present in this engine,
+// shipped or recovered.
+func (e *Engine) AppliedLSN() uint64 {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.lastLSN.Load()
+}
+
+// decodeShippedFrames validates a shipped transaction: whole chunks with
+// valid checksums, one TxID, TxBegin first and the TxCommit at CommitLSN last.
+func decodeShippedFrames(tx storage.WALTransaction) ([]walRecord, error) {
+	var frames []walRecord
+	data := tx.Frames
+	for len(data) > 0 {
+		if len(data) < 16 {
+			return nil, fmt.Errorf("shipped transaction %d: truncated chunk header", tx.TxID)
+		}
+		chunk := decodeChunkHeader(data[:16])
+		if chunk.Magic != chunkMagic || chunk.Kind != chunkTypeWAL {
+			return nil, fmt.Errorf("shipped transaction %d: not a WAL chunk", tx.TxID)
+		}
+		end := 16 + int(chunk.PayloadLen)
+		if chunk.PayloadLen > maxChunkSize || end > len(data) {
+			return nil, fmt.Errorf("shipped transaction %d: truncated chunk", tx.TxID)
+		}
+		if crc32.Checksum(data[16:end], castagnoli) != chunk.Checksum {
+			return nil, fmt.Errorf("shipped transaction %d: invalid chunk checksum", tx.TxID)
+		}
+		record, err := decodeWALRecord(data[16:end])
+		if err != nil {
+			return nil, fmt.Errorf("shipped transaction %d: %w", tx.TxID, err)
+		}
+		if record.Header.TxID != tx.TxID {
+			return nil, fmt.Errorf("shipped transaction %d contains a frame of transaction %d", tx.TxID, record.Header.TxID)
+		}
+		frames = append(frames, record)
+		data = data[end:]
+	}
+	if len(frames) < 2 || frames[0].Header.RecordType != recordTypeTxBegin {
+		return nil, fmt.Errorf("shipped transaction %d does not start with TxBegin", tx.TxID)
+	}
+	commit := frames[len(frames)-1]
+	if commit.Header.RecordType != recordTypeTxCommit || commit.Header.LSN != tx.CommitLSN {
+		return nil, fmt.Errorf("shipped transaction %d does not end with its commit at LSN %d", tx.TxID, tx.CommitLSN)
+	}
+	return frames, nil
+}
+
+// walChangesLocked summarizes the logical effect of frames against the
+// current state, before they are applied. Record changes are coalesced so
+// each record reports its pre-transaction state once. Caller must hold e.mu.
+func (e *Engine) walChangesLocked(frames []walRecord) ([]storage.WALChange, error) {
+	type recordKey struct{ collection, id string }
+	var changes []storage.WALChange
+	seen := make(map[recordKey]bool)
+	noteRecord := func(collection, id string) {
+		key := recordKey{collection: collection, id: id}
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+		change := storage.WALChange{Type: storage.WALChangeRecord, Collection: collection, ID: id}
+		if persisted := e.state.Collections[collection]; persisted != nil && !persisted.Deleted {
+			if current := persisted.Records[id]; current != nil && !current.Deleted {
+				change.Existed = true
+				change.PreviousOrdinal = current.Ordinal
+			}
+		}
+		changes = append(changes, change)
+	}
+	for _, record := range frames {
+		switch record.Header.RecordType {
+		case recordTypeCollectionCreate:
+			payload, err := decodeCollectionCreatePayloadBinary(record.Payload)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, storage.WALChange{Type: storage.WALChangeCollectionCreate, Collection: payload.Name})
+		case recordTypeCollectionDelete:
+			payload, err := decodeCollectionDeletePayloadBinary(record.Payload)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, storage.WALChange{Type: storage.WALChangeCollectionDelete, Collection: payload.Name})
+		case recordTypeCollectionConfig:
+			payload, err := decodeCollectionCreatePayloadBinary(record.Payload)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, storage.WALChange{Type: storage.WALChangeCollectionConfig, Collection: payload.Name})
+		case recordTypeCollectionRename:
+			// Followers reload a renamed collection under its new name.
+			payload, err := decodeCollectionRenamePayloadBinary(record.Payload)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes,
+				storage.WALChange{Type: storage.WALChangeCollectionDelete, Collection: payload.Name},
+				storage.WALChange{Type: storage.WALChangeCollectionCreate, Collection: payload.NewName},
+			)
+		case recordTypeCatalog:
+			changes = append(changes, storage.WALChange{Type: storage.WALChangeCatalog})
+		case recordTypeEdgeKindCreate:
+			payload, err := decodeEdgeKindCreatePayload(record.Payload)
+			if err != nil {
+				return nil, err
+			}
+			changes = append(changes, storage.WALChange{
+				Type:        storage.WALChangeEdgeKind,
+				EdgeKind:    payload.Name,
+				EdgeKindDef: storage.EdgeKindDefinition{Kind: payload.Kind, Undirected: payload.Undirected},
+			})
+		case recordTypeViewPut, recordTypeViewDrop:
+			changes = append(changes, storage.WALChange{Type: storage.WALChangeView})
+		case recordTypeRecordPut:
+			payload, err := decodeRecordPutPayloadBinary(record.Payload)
+			if err != nil {
+				return nil, err
+			}
+			noteRecord(payload.Collection, payload.ID)
+		case recordTypeRecordDelete:
+			payload, err := decodeRecordDeletePayloadBinary(record.Payload)
+			if err != nil {
+				return nil, err
+			}
+			noteRecord(payload.Collection, payload.ID)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,229 @@
+package singlefile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+type recordedEdges struct {
+	edges map[[3]uint64]uint64
+}
+
+func (r *recordedEdges) ReplayEdgeAdd(src, tgt uint64, _ float32, kind uint8, _ []byte, commitLSN uint64) error {
+	r.edges[[3]uint64{src, tgt, uint64(kind)}] = commitLSN
+	return nil
+}
+
+func (r *recordedEdges) ReplayEdgeRemove(src, tgt uint64, kind uint8, _ uint64) error {
+	delete(r.edges, [3]uint64{src, tgt, uint64(kind)})
+	return nil
+}
+
+func (r *recordedEdges) ReplayNodeEdgeDrop(uint64, uint64) error { return nil }
+
+func (r *recordedEdges) ReplayVertexLabel(uint64, string, uint64) error { return nil }
+
+func readAllWAL(t *testing.T, engine *Engine, from uint64) []storage.WALTransaction {
+	t.Helper()
+	var out []storage.WALTransaction
+	cursor := storage.WALCursor{LSN: from}
+	for {
+		batch, next, err := engine.ReadWAL(context.Background(), cursor, 2)
+		if err != nil {
+			t.Fatalf("ReadWAL(%+v): %v", cursor, err)
+		}
+		out = append(out, batch...)
+		if len(batch) < 2 {
+			return out
+		}
+		cursor = next
+	}
+}
+
+func TestReadWALShipsCommittedTransactionsToReplica(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	primaryIface, err := New(filepath.Join(dir, "primary.libravdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := primaryIface.(*Engine)
+	defer primary.Close()
+
+	coll, err := primary.CreateCollection("docs", &storage.CollectionConfig{Dimension: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.CreateEdgeKindDefinition("knows", 7, true); err != nil {
+		t.Fatal(err)
+	}
+	ordinals := make(map[string]uint32)
+	for i := 0; i < 5; i++ {
+		entry := &index.VectorEntry{ID: fmt.Sprintf("id-%d", i), Vector: []float32{1, float32(i)}}
+		if err := coll.Insert(ctx, entry); err != nil {
+			t.Fatal(err)
+		}
+		ordinals[entry.ID] = entry.Ordinal
+	}
+	if err := coll.Delete(ctx, "id-0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.AppendGraphEdges(ctx, []storage.GraphEdgeOp{{Collection: "docs", Src: 1, Tgt: 2, Weight: 1, Kind: 7}}, nil, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := readAllWAL(t, primary, 0)
+	if len(txs) != 9 {
+		t.Fatalf("shipped %d transactions, want 9", len(txs))
+	}
+	for i := 1; i < len(txs); i++ {
+		if txs[i].CommitLSN <= txs[i-1].CommitLSN {
+			t.Fatalf("commit LSNs out of order: %d after %d", txs[i].CommitLSN, txs[i-1].CommitLSN)
+		}
+	}
+	if tail := readAllWAL(t, primary, txs[3].CommitLSN); len(tail) != 5 || tail[0].TxID != txs[4].TxID {
+		t.Fatalf("resume from LSN %d shipped %d transactions", txs[3].CommitLSN, len(tail))
+	}
+
+	replicaIface, err := New(filepath.Join(dir, "replica.libravdb"), WithReplica())
+	if err != nil {
+		t.Fatal(err)
+	}
+	replica := replicaIface.(*Engine)
+	defer replica.Close()
+	edges := &recordedEdges{edges: make(map[[3]uint64]uint64)}
+	replica.SetGraphRecoveryTarget("docs", edges)
+
+	corrupt := txs[0]
+	corrupt.Frames = append([]byte(nil), corrupt.Frames...)
+	corrupt.Frames[len(corrupt.Frames)-1] ^= 0xff
+	if _, err := replica.ApplyWAL(ctx, corrupt); err == nil {
+		t.Fatal("ApplyWAL accepted a frame with a bad checksum")
+	}
+
+	var changes []storage.WALChange
+	for _, tx := range txs {
+		applied, err := replica.ApplyWAL(ctx, tx)
+		if err != nil {
+			t.Fatalf("ApplyWAL(tx %d): %v", tx.TxID, err)
+		}
+		changes = append(changes, applied...)
+	}
+	if again, err := replica.ApplyWAL(ctx, txs[2]); err != nil || again != nil {
+		t.Fatalf("re-delivered transaction applied again: %v %v", again, err)
+	}
+	if replica.AppliedLSN() != primary.AppliedLSN() {
+		t.Fatalf("replica applied LSN %d, primary %d", replica.AppliedLSN(), primary.AppliedLSN())
+	}
+	primaryLSN, _ := primary.LatestCommitLSN()
+	replicaLSN, _ := replica.LatestCommitLSN()
+	if replicaLSN != primaryLSN {
+		t.Fatalf("replica LatestCommitLSN %d, primary %d", replicaLSN, primaryLSN)
+	}
+	if changes[0].Type != storage.WALChangeCollectionCreate || changes[1].Type != storage.WALChangeEdgeKind || !changes[1].EdgeKindDef.Undirected {
+		t.Fatalf("DDL changes = %+v", changes[:2])
+	}
+	if last := changes[len(changes)-1]; last.Type != storage.WALChangeRecord || last.ID != "id-0" || !last.Existed || last.PreviousOrdinal != ordinals["id-0"] {
+		t.Fatalf("delete change = %+v", last)
+	}
+	if len(edges.edges) != 1 {
+		t.Fatalf("replica graph edges = %v", edges.edges)
+	}
+
+	replicaColl, err := replica.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := replicaColl.Exists(ctx, "id-0"); exists {
+		t.Fatal("deleted record present on replica")
+	}
+	if exists, _ := replicaColl.Exists(ctx, "id-4"); !exists {
+		t.Fatal("record id-4 missing on replica")
+	}
+	if err := replicaColl.Insert(ctx, &index.VectorEntry{ID: "local", Ordinal: 99, Vector: []float32{0, 0}}); !errors.Is(err, ErrReplicaReadOnly) {
+		t.Fatalf("replica accepted a local write: %v", err)
+	}
+	if _, err := primary.ApplyWAL(ctx, txs[0]); err == nil {
+		t.Fatal("primary accepted a shipped transaction")
+	}
+
+	// Reopening keeps the primary's LSNs, so the replica resumes in place.
+	if err := replica.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopenedIface, err := New(filepath.Join(dir, "replica.libravdb"), WithReplica())
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened := reopenedIface.(*Engine)
+	defer reopened.Close()
+	if reopened.AppliedLSN() != primary.AppliedLSN() {
+		t.Fatalf("reopened replica at LSN %d, primary %d", reopened.AppliedLSN(), primary.AppliedLSN())
+	}
+	kinds, _ := reopened.ListEdgeKindDefinitions()
+	if def := kinds["knows"]; def.Kind != 7 || !def.Undirected {
+		t.Fatalf("replicated edge kinds = %v", kinds)
+	}
+}
+
+func TestReadWALRejectsCompactedHistory(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	engineIface, err := New(filepath.Join(dir, "primary.libravdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	engine := engineIface.(*Engine)
+	defer engine.Close()
+
+	coll, err := engine.CreateCollection("docs", &storage.CollectionConfig{Dimension: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := coll.Insert(ctx, &index.VectorEntry{ID: "a", Ordinal: 1, Vector: []float32{1, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	_, stale, err := engine.ReadWAL(ctx, storage.WALCursor{}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := engine.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	floor := engine.AppliedLSN()
+	if _, _, err := engine.ReadWAL(ctx, storage.WALCursor{}, 10); !errors.Is(err, ErrWALTruncated) {
+		t.Fatalf("ReadWAL below the floor: %v, want ErrWALTruncated", err)
+	}
+
+	if err := coll.Insert(ctx, &index.VectorEntry{ID: "b", Ordinal: 2, Vector: []float32{0, 1}}); err != nil {
+		t.Fatal(err)
+	}
+	// The pre-compaction cursor offset no longer describes this file.
+	stale.LSN = floor
+	txs, _, err := engine.ReadWAL(ctx, stale, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(txs) != 1 || txs[0].CommitLSN <= floor {
+		t.Fatalf("after compaction shipped %d transactions", len(txs))
+	}
+
+	// The floor is durable.
+	if err := engine.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopenedIface, err := New(filepath.Join(dir, "primary.libravdb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reopenedIface.Close()
+	if _, _, err := reopenedIface.(*Engine).ReadWAL(ctx, storage.WALCursor{LSN: floor - 1}, 10); !errors.Is(err, ErrWALTruncated) {
+		t.Fatalf("reopened ReadWAL below the floor: %v, want ErrWALTruncated", err)
+	}
+}
@@ -10,3 +10,15 @@ var (
 	ErrDimension  = errors.New("vector dimension does not match index dimension")
 	ErrNotFound   = errors.New("not found")
 )
+
+// BatchInsertError reports a BatchInsert that stopped early. The first
+// Inserted entries of the batch stay in the index; the rest were not
+// inserted.
+type BatchInsertError struct {
+	Inserted int
+	Err      error
+}
+
+func (e *BatchInsertError) Error() string { return e.Err.Error() }
+
+func (e *BatchInsertError) Unwrap() error { return e.Err }
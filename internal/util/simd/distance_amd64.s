@@ -697,6 +697,109 @@ l2x8any_compare_next_7:
 	MOVL AX, ret+96(FP)
 	RET
 
+// func hammingPOPCNT(a []byte, b []byte) uint64
+// Requires: POPCNT
+TEXT ·hammingPOPCNT(SB), NOSPLIT, $0-56
+	MOVQ a_base+0(FP), AX
+	MOVQ b_base+24(FP), CX
+	MOVQ a_len+8(FP), DX
+	XORQ BX, BX
+	XORQ SI, SI
+	XORQ DI, DI
+	XORQ R8, R8
+
+hamming_loop:
+	CMPQ    DX, $0x20
+	JL      hamming_tail
+	MOVQ    (AX), R9
+	XORQ    (CX), R9
+	POPCNTQ R9, R9
+	ADDQ    R9, BX
+	MOVQ    8(AX), R9
+	XORQ    8(CX), R9
+	POPCNTQ R9, R9
+	ADDQ    R9, SI
+	MOVQ    16(AX), R9
+	XORQ    16(CX), R9
+	POPCNTQ R9, R9
+	ADDQ    R9, DI
+	MOVQ    24(AX), R9
+	XORQ    24(CX), R9
+	POPCNTQ R9, R9
+	ADDQ    R9, R8
+	ADDQ    $0x20, AX
+	ADDQ    $0x20, CX
+	SUBQ    $0x20, DX
+	JMP     hamming_loop
+
+hamming_tail:
+	CMPQ    DX, $0x08
+	JL      hamming_done
+	MOVQ    (AX), R9
+	XORQ    (CX), R9
+	POPCNTQ R9, R9
+	ADDQ    R9, BX
+	ADDQ    $0x08, AX
+	ADDQ    $0x08, CX
+	SUBQ    $0x08, DX
+	JMP     hamming_tail
+
+hamming_done:
+	ADDQ SI, BX
+	ADDQ DI, BX
+	ADDQ R8, BX
+	MOVQ BX, ret+48(FP)
+	RET
+
+// func l2SquaredUint8AVX2(a []byte, b []byte) uint64
+// Requires: AVX, AVX2
+TEXT ·l2SquaredUint8AVX2(SB), NOSPLIT, $0-56
+	MOVQ  a_base+0(FP), AX
+	MOVQ  b_base+24(FP), CX
+	MOVQ  a_len+8(FP), DX
+	VPXOR Y0, Y0, Y0
+	VPXOR Y1, Y1, Y1
+
+l2u8_loop:
+	CMPQ      DX, $0x20
+	JL        l2u8_tail
+	VPMOVZXBW (AX), Y2
+	VPMOVZXBW (CX), Y3
+	VPSUBW    Y3, Y2, Y2
+	VPMADDWD  Y2, Y2, Y2
+	VPADDD    Y2, Y0, Y0
+	VPMOVZXBW 16(AX), Y4
+	VPMOVZXBW 16(CX), Y5
+	VPSUBW    Y5, Y4, Y4
+	VPMADDWD  Y4, Y4, Y4
+	VPADDD    Y4, Y1, Y1
+	ADDQ      $0x20, AX
+	ADDQ      $0x20, CX
+	SUBQ      $0x20, DX
+	JMP       l2u8_loop
+
+l2u8_tail:
+	CMPQ      DX, $0x10
+	JL        l2u8_done
+	VPMOVZXBW (AX), Y2
+	VPMOVZXBW (CX), Y3
+	VPSUBW    Y3, Y2, Y2
+	VPMADDWD  Y2, Y2, Y2
+	VPADDD    Y2, Y0, Y0
+
+l2u8_done:
+	VPADDD       Y1, Y0, Y0
+	VEXTRACTI128 $0x01, Y0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0x4e, X0, X1
+	VPADDD       X1, X0, X0
+	VPSHUFD      $0xb1, X0, X1
+	VPADDD       X1, X0, X0
+	VMOVD        X0, AX
+	VZEROUPPER
+	MOVQ         AX, ret+48(FP)
+	RET
+
 // func prefetch8L1AMD64(ptrs *[8]*byte)
 // Requires: MMX+
 TEXT ·prefetch8L1AMD64(SB), NOSPLIT, $0-8
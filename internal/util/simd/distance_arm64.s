@@ -1462,3 +1462,96 @@ l2x8any_true:
 	MOVD $1, R7
 	MOVW R7, ret+96(FP)
 	RET
+
+// func hammingNEON(a, b []byte) uint64
+TEXT ·hammingNEON(SB), NOSPLIT, $0-56
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	MOVD $0, R3
+
+hamming_loop64:
+	CMP $64, R2
+	BLT hamming_loop16
+
+	VLD1.P 64(R0), [V0.B16, V1.B16, V2.B16, V3.B16]
+	VLD1.P 64(R1), [V4.B16, V5.B16, V6.B16, V7.B16]
+
+	VEOR V4.B16, V0.B16, V0.B16
+	VEOR V5.B16, V1.B16, V1.B16
+	VEOR V6.B16, V2.B16, V2.B16
+	VEOR V7.B16, V3.B16, V3.B16
+
+	VCNT V0.B16, V0.B16
+	VCNT V1.B16, V1.B16
+	VCNT V2.B16, V2.B16
+	VCNT V3.B16, V3.B16
+
+	// Each byte lane holds at most 8, so four lanes sum to at most 32.
+	VADD V1.B16, V0.B16, V0.B16
+	VADD V3.B16, V2.B16, V2.B16
+	VADD V2.B16, V0.B16, V0.B16
+
+	VUADDLV V0.B16, V8
+	VMOV V8.H[0], R4
+	ADD R4, R3, R3
+
+	SUB $64, R2, R2
+	JMP hamming_loop64
+
+hamming_loop16:
+	CMP $16, R2
+	BLT hamming_done
+
+	VLD1.P 16(R0), [V0.B16]
+	VLD1.P 16(R1), [V1.B16]
+	VEOR V1.B16, V0.B16, V0.B16
+	VCNT V0.B16, V0.B16
+	VUADDLV V0.B16, V8
+	VMOV V8.H[0], R4
+	ADD R4, R3, R3
+
+	SUB $16, R2, R2
+	JMP hamming_loop16
+
+hamming_done:
+	MOVD R3, ret+48(FP)
+	RET
+
+// func l2SquaredUint8NEON(a, b []byte) uint64
+TEXT ·l2SquaredUint8NEON(SB), NOSPLIT, $0-56
+	MOVD a_base+0(FP), R0
+	MOVD a_len+8(FP), R2
+	MOVD b_base+24(FP), R1
+	VEOR V16.B16, V16.B16, V16.B16
+	VEOR V17.B16, V17.B16, V17.B16
+
+l2u8_loop16:
+	CMP $16, R2
+	BLT l2u8_done
+
+	VLD1.P 16(R0), [V0.B16]
+	VLD1.P 16(R1), [V1.B16]
+
+	// |a-b| per byte lane as max-min, then widen-square into halfwords.
+	VUMAX V1.B16, V0.B16, V2.B16
+	VUMIN V1.B16, V0.B16, V3.B16
+	VSUB V3.B16, V2.B16, V2.B16
+	VUMULL V2.B8, V2.B8, V4.H8
+	VUMULL2 V2.B16, V2.B16, V5.H8
+
+	// Each square fits in 16 bits; widen into the 32-bit accumulators.
+	VUADDW V4.H4, V16.S4, V16.S4
+	VUADDW2 V4.H8, V17.S4, V17.S4
+	VUADDW V5.H4, V16.S4, V16.S4
+	VUADDW2 V5.H8, V17.S4, V17.S4
+
+	SUB $16, R2, R2
+	JMP l2u8_loop16
+
+l2u8_done:
+	VADD V17.S4, V16.S4, V16.S4
+	VUADDLV V16.S4, V18
+	VMOV V18.D[0], R3
+	MOVD R3, ret+48(FP)
+	RET
@@ -19,6 +19,8 @@ func main() {
 	genL2x4Ptr()
 	genL2x8Ptr()
 	genL2AnyLessThan8Ptr()
+	genHamming()
+	genL2SquaredUint8()
 	genPrefetch()
 	build.Generate()
 }
@@ -437,6 +439,125 @@ func genL2x4Ptr() {
 	build.RET()
 }
 
+func genHamming() {
+	build.TEXT("hammingPOPCNT", build.NOSPLIT, "func(a, b []byte) uint64")
+	build.Pragma("noescape")
+	build.Doc("hammingPOPCNT counts differing bits over the whole 8-byte words of a and b using POPCNT.")
+
+	aPtr := build.Load(build.Param("a").Base(), build.GP64())
+	bPtr := build.Load(build.Param("b").Base(), build.GP64())
+	n := build.Load(build.Param("a").Len(), build.GP64())
+
+	accs := make([]reg.GPVirtual, unroll)
+	for i := 0; i < unroll; i++ {
+		accs[i] = build.GP64()
+		build.XORQ(accs[i], accs[i])
+	}
+
+	build.Label("hamming_loop")
+	build.CMPQ(n, operand.Imm(unroll*8))
+	build.JL(operand.LabelRef("hamming_tail"))
+
+	for i := 0; i < unroll; i++ {
+		x := build.GP64()
+		build.MOVQ(operand.Mem{Base: aPtr, Disp: i * 8}, x)
+		build.XORQ(operand.Mem{Base: bPtr, Disp: i * 8}, x)
+		build.POPCNTQ(x, x)
+		build.ADDQ(x, accs[i])
+	}
+
+	build.ADDQ(operand.Imm(unroll*8), aPtr)
+	build.ADDQ(operand.Imm(unroll*8), bPtr)
+	build.SUBQ(operand.Imm(unroll*8), n)
+	build.JMP(operand.LabelRef("hamming_loop"))
+
+	build.Label("hamming_tail")
+	build.CMPQ(n, operand.Imm(8))
+	build.JL(operand.LabelRef("hamming_done"))
+
+	x := build.GP64()
+	build.MOVQ(operand.Mem{Base: aPtr}, x)
+	build.XORQ(operand.Mem{Base: bPtr}, x)
+	build.POPCNTQ(x, x)
+	build.ADDQ(x, accs[0])
+
+	build.ADDQ(operand.Imm(8), aPtr)
+	build.ADDQ(operand.Imm(8), bPtr)
+	build.SUBQ(operand.Imm(8), n)
+	build.JMP(operand.LabelRef("hamming_tail"))
+
+	build.Label("hamming_done")
+	for i := 1; i < unroll; i++ {
+		build.ADDQ(accs[i], accs[0])
+	}
+	build.Store(accs[0], build.ReturnIndex(0))
+	build.RET()
+}
+
+func genL2SquaredUint8() {
+	build.TEXT("l2SquaredUint8AVX2", build.NOSPLIT, "func(a, b []byte) uint64")
+	build.Pragma("noescape")
+	build.Doc("l2SquaredUint8AVX2 sums squared byte differences over the whole 16-byte blocks of a and b using AVX2.")
+
+	aPtr := build.Load(build.Param("a").Base(), build.GP64())
+	bPtr := build.Load(build.Param("b").Base(), build.GP64())
+	n := build.Load(build.Param("a").Len(), build.GP64())
+
+	// Bytes widen to 16-bit lanes so differences stay signed; VPMADDWD then
+	// squares and pairs them into 32-bit sums.
+	accs := []reg.VecVirtual{build.YMM(), build.YMM()}
+	for _, acc := range accs {
+		build.VPXOR(acc, acc, acc)
+	}
+
+	build.Label("l2u8_loop")
+	build.CMPQ(n, operand.Imm(32))
+	build.JL(operand.LabelRef("l2u8_tail"))
+
+	for i, acc := range accs {
+		x := build.YMM()
+		y := build.YMM()
+		build.VPMOVZXBW(operand.Mem{Base: aPtr, Disp: i * 16}, x)
+		build.VPMOVZXBW(operand.Mem{Base: bPtr, Disp: i * 16}, y)
+		build.VPSUBW(y, x, x)
+		build.VPMADDWD(x, x, x)
+		build.VPADDD(x, acc, acc)
+	}
+
+	build.ADDQ(operand.Imm(32), aPtr)
+	build.ADDQ(operand.Imm(32), bPtr)
+	build.SUBQ(operand.Imm(32), n)
+	build.JMP(operand.LabelRef("l2u8_loop"))
+
+	build.Label("l2u8_tail")
+	build.CMPQ(n, operand.Imm(16))
+	build.JL(operand.LabelRef("l2u8_done"))
+
+	x := build.YMM()
+	y := build.YMM()
+	build.VPMOVZXBW(operand.Mem{Base: aPtr}, x)
+	build.VPMOVZXBW(operand.Mem{Base: bPtr}, y)
+	build.VPSUBW(y, x, x)
+	build.VPMADDWD(x, x, x)
+	build.VPADDD(x, accs[0], accs[0])
+
+	build.Label("l2u8_done")
+	build.VPADDD(accs[1], accs[0], accs[0])
+	lo := accs[0].AsX()
+	hi := build.XMM()
+	build.VEXTRACTI128(operand.Imm(1), accs[0], hi)
+	build.VPADDD(hi, lo, lo)
+	build.VPSHUFD(operand.Imm(0x4e), lo, hi)
+	build.VPADDD(hi, lo, lo)
+	build.VPSHUFD(operand.Imm(0xb1), lo, hi)
+	build.VPADDD(hi, lo, lo)
+	sum := build.GP32()
+	build.VMOVD(lo, sum)
+	build.VZEROUPPER()
+	build.Store(sum.As64(), build.ReturnIndex(0))
+	build.RET()
+}
+
 func reduceYMM(acc reg.VecVirtual, dst reg.Register) {
 	temp := build.XMM()
 	build.VEXTRACTF128(operand.Imm(1), acc, temp)
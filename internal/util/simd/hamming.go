@@ -0,0 +1,31 @@
+package simd
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// HammingDistance returns the number of bit positions in which a and b
+// differ. It is the distance kernel for packed binary codes: amd64 uses
+// POPCNT, arm64 uses NEON CNT, and other platforms fall back to
+// math/bits. a and b must have the same length.
+func HammingDistance(a, b []byte) int {
+	if len(a) != len(b) {
+		panic("binary code lengths must match")
+	}
+	return int(hammingDistance(a, b))
+}
+
+// hammingGeneric is the portable kernel and also finishes the byte tail
+// the assembly kernels leave behind.
+func hammingGeneric(a, b []byte) uint64 {
+	var count int
+	i := 0
+	for ; i+8 <= len(a); i += 8 {
+		count += bits.OnesCount64(binary.LittleEndian.Uint64(a[i:]) ^ binary.LittleEndian.Uint64(b[i:]))
+	}
+	for ; i < len(a); i++ {
+		count += bits.OnesCount8(a[i] ^ b[i])
+	}
+	return uint64(count)
+}
@@ -0,0 +1,15 @@
+//go:build amd64
+
+package simd
+
+import "golang.org/x/sys/cpu"
+
+var hasPOPCNT = cpu.X86.HasPOPCNT
+
+func hammingDistance(a, b []byte) uint64 {
+	if !hasPOPCNT {
+		return hammingGeneric(a, b)
+	}
+	n := len(a) &^ 7
+	return hammingPOPCNT(a[:n], b[:n]) + hammingGeneric(a[n:], b[n:])
+}
@@ -0,0 +1,8 @@
+//go:build arm64
+
+package simd
+
+func hammingDistance(a, b []byte) uint64 {
+	n := len(a) &^ 15
+	return hammingNEON(a[:n], b[:n]) + hammingGeneric(a[n:], b[n:])
+}
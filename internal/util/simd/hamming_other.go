@@ -0,0 +1,7 @@
+//go:build !arm64 && !amd64
+
+package simd
+
+func hammingDistance(a, b []byte) uint64 {
+	return hammingGeneric(a, b)
+}
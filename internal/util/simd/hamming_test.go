@@ -0,0 +1,86 @@
+package simd
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+var hammingBenchmarkSink int
+
+func naiveHamming(a, b []byte) int {
+	count := 0
+	for i := range a {
+		for bit := 0; bit < 8; bit++ {
+			if (a[i]>>bit)&1 != (b[i]>>bit)&1 {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func TestHammingDistanceMatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	for _, n := range []int{0, 1, 7, 8, 9, 15, 16, 17, 31, 32, 33, 63, 64, 65, 127, 128, 192, 257} {
+		a := make([]byte, n)
+		b := make([]byte, n)
+		rng.Read(a)
+		rng.Read(b)
+		if got, want := HammingDistance(a, b), naiveHamming(a, b); got != want {
+			t.Fatalf("len=%d got=%d want=%d", n, got, want)
+		}
+		if got := HammingDistance(a, a); got != 0 {
+			t.Fatalf("len=%d self distance=%d", n, got)
+		}
+		inverted := make([]byte, n)
+		for i := range a {
+			inverted[i] = ^a[i]
+		}
+		if got := HammingDistance(a, inverted); got != n*8 {
+			t.Fatalf("len=%d inverted distance=%d want=%d", n, got, n*8)
+		}
+	}
+}
+
+func TestHammingDistanceKernelMatchesGeneric(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	a := make([]byte, 1024)
+	b := make([]byte, 1024)
+	rng.Read(a)
+	rng.Read(b)
+	for n := 0; n <= len(a); n++ {
+		if got, want := hammingDistance(a[:n], b[:n]), hammingGeneric(a[:n], b[:n]); got != want {
+			t.Fatalf("len=%d kernel=%d generic=%d", n, got, want)
+		}
+	}
+}
+
+func TestHammingDistanceRejectsLengthMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for mismatched code lengths")
+		}
+	}()
+	HammingDistance(make([]byte, 8), make([]byte, 9))
+}
+
+func BenchmarkHammingDistance(b *testing.B) {
+	for _, dimension := range []int{384, 768, 1536} {
+		b.Run(strconv.Itoa(dimension), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			x := make([]byte, dimension/8)
+			y := make([]byte, dimension/8)
+			rng.Read(x)
+			rng.Read(y)
+			b.SetBytes(int64(len(x) * 2))
+			b.ReportAllocs()
+			b.ResetTimer()
+			var sum int
+			for i := 0; i < b.N; i++ {
+				sum += HammingDistance(x, y)
+			}
+			hammingBenchmarkSink = sum
+		})
+	}
+}
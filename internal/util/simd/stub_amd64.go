@@ -32,5 +32,15 @@ func l2Distance8PtrAVX2(q []float32, b0 *byte, b1 *byte, b2 *byte, b3 *byte, b4
 //go:noescape
 func l2AnyLessThan8PtrAVX2(q []float32, b0 *byte, b1 *byte, b2 *byte, b3 *byte, b4 *byte, b5 *byte, b6 *byte, b7 *byte, cutoff float32) uint32
 
+// hammingPOPCNT counts differing bits over the whole 8-byte words of a and b using POPCNT.
+//
+//go:noescape
+func hammingPOPCNT(a []byte, b []byte) uint64
+
+// l2SquaredUint8AVX2 sums squared byte differences over the whole 16-byte blocks of a and b using AVX2.
+//
+//go:noescape
+func l2SquaredUint8AVX2(a []byte, b []byte) uint64
+
 //go:noescape
 func prefetch8L1AMD64(ptrs *[8]*byte)
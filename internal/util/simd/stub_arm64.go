@@ -31,6 +31,18 @@ func L2Distance8AlignedPtrNEON(q []float32, b0, b1, b2, b3, b4, b5, b6, b7 unsaf
 //go:noescape
 func L2AnyLessThan8AlignedPtrNEON(q []float32, b0, b1, b2, b3, b4, b5, b6, b7 unsafe.Pointer, cutoff float32) uint32
 
+// hammingNEON counts differing bits between a and b. Only whole 16-byte
+// blocks are processed; the caller handles the tail.
+//
+//go:noescape
+func hammingNEON(a, b []byte) uint64
+
+// l2SquaredUint8NEON sums squared byte differences between a and b. Only
+// whole 16-byte blocks are processed; the caller handles the tail.
+//
+//go:noescape
+func l2SquaredUint8NEON(a, b []byte) uint64
+
 //go:noescape
 func PrefetchL1(ptr unsafe.Pointer)
 
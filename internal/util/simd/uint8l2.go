@@ -0,0 +1,34 @@
+package simd
+
+// uint8L2Chunk bounds how many bytes one assembly call accumulates. Every
+// kernel lane gathers at most 4*255^2 per 16-byte block, so 64 KiB chunks
+// keep the 32-bit lane sums far from overflow.
+const uint8L2Chunk = 1 << 16
+
+// L2SquaredUint8 returns the squared Euclidean distance between two byte
+// code vectors, treating each byte as an unsigned integer. It is the kernel
+// for 8-bit scalar codes: amd64 widens to 16-bit lanes under AVX2, arm64
+// squares absolute differences with NEON, and other platforms use a
+// portable loop. a and b must have the same length.
+func L2SquaredUint8(a, b []byte) uint64 {
+	if len(a) != len(b) {
+		panic("uint8 code lengths must match")
+	}
+	var sum uint64
+	for len(a) > uint8L2Chunk {
+		sum += l2SquaredUint8(a[:uint8L2Chunk], b[:uint8L2Chunk])
+		a, b = a[uint8L2Chunk:], b[uint8L2Chunk:]
+	}
+	return sum + l2SquaredUint8(a, b)
+}
+
+// l2SquaredUint8Generic is the portable kernel and also finishes the byte
+// tail the assembly kernels leave behind.
+func l2SquaredUint8Generic(a, b []byte) uint64 {
+	var sum uint64
+	for i := range a {
+		diff := int32(a[i]) - int32(b[i])
+		sum += uint64(diff * diff)
+	}
+	return sum
+}
@@ -0,0 +1,15 @@
+//go:build amd64
+
+package simd
+
+import "golang.org/x/sys/cpu"
+
+var hasUint8L2AVX2 = cpu.X86.HasAVX2
+
+func l2SquaredUint8(a, b []byte) uint64 {
+	if !hasUint8L2AVX2 {
+		return l2SquaredUint8Generic(a, b)
+	}
+	n := len(a) &^ 15
+	return l2SquaredUint8AVX2(a[:n], b[:n]) + l2SquaredUint8Generic(a[n:], b[n:])
+}
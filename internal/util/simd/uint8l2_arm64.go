@@ -0,0 +1,8 @@
+//go:build arm64
+
+package simd
+
+func l2SquaredUint8(a, b []byte) uint64 {
+	n := len(a) &^ 15
+	return l2SquaredUint8NEON(a[:n], b[:n]) + l2SquaredUint8Generic(a[n:], b[n:])
+}
@@ -0,0 +1,7 @@
+//go:build !arm64 && !amd64
+
+package simd
+
+func l2SquaredUint8(a, b []byte) uint64 {
+	return l2SquaredUint8Generic(a, b)
+}
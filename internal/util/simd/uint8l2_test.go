@@ -0,0 +1,95 @@
+package simd
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+var uint8L2BenchmarkSink uint64
+
+func naiveL2SquaredUint8(a, b []byte) uint64 {
+	var sum uint64
+	for i := range a {
+		diff := int64(a[i]) - int64(b[i])
+		sum += uint64(diff * diff)
+	}
+	return sum
+}
+
+func TestL2SquaredUint8MatchesNaive(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+	for _, n := range []int{0, 1, 15, 16, 17, 31, 32, 33, 47, 48, 64, 127, 128, 384, 769} {
+		a := make([]byte, n)
+		b := make([]byte, n)
+		rng.Read(a)
+		rng.Read(b)
+		if got, want := L2SquaredUint8(a, b), naiveL2SquaredUint8(a, b); got != want {
+			t.Fatalf("len=%d got=%d want=%d", n, got, want)
+		}
+		if got := L2SquaredUint8(a, a); got != 0 {
+			t.Fatalf("len=%d self distance=%d", n, got)
+		}
+	}
+}
+
+func TestL2SquaredUint8ExtremeValues(t *testing.T) {
+	// Saturated codes exercise the widest lane sums, including across the
+	// chunk boundary of the public wrapper.
+	for _, n := range []int{16, 1536, uint8L2Chunk + 48} {
+		zeros := make([]byte, n)
+		ones := make([]byte, n)
+		for i := range ones {
+			ones[i] = 0xff
+		}
+		want := uint64(n) * 255 * 255
+		if got := L2SquaredUint8(zeros, ones); got != want {
+			t.Fatalf("len=%d got=%d want=%d", n, got, want)
+		}
+		if got := L2SquaredUint8(ones, zeros); got != want {
+			t.Fatalf("len=%d reversed got=%d want=%d", n, got, want)
+		}
+	}
+}
+
+func TestL2SquaredUint8KernelMatchesGeneric(t *testing.T) {
+	rng := rand.New(rand.NewSource(17))
+	a := make([]byte, 1024)
+	b := make([]byte, 1024)
+	rng.Read(a)
+	rng.Read(b)
+	for n := 0; n <= len(a); n++ {
+		if got, want := l2SquaredUint8(a[:n], b[:n]), l2SquaredUint8Generic(a[:n], b[:n]); got != want {
+			t.Fatalf("len=%d kernel=%d generic=%d", n, got, want)
+		}
+	}
+}
+
+func TestL2SquaredUint8RejectsLengthMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic for mismatched code lengths")
+		}
+	}()
+	L2SquaredUint8(make([]byte, 16), make([]byte, 17))
+}
+
+func BenchmarkL2SquaredUint8(b *testing.B) {
+	for _, dimension := range []int{384, 768, 1536} {
+		b.Run(strconv.Itoa(dimension), func(b *testing.B) {
+			rng := rand.New(rand.NewSource(1))
+			x := make([]byte, dimension)
+			y := make([]byte, dimension)
+			rng.Read(x)
+			rng.Read(y)
+			b.SetBytes(int64(len(x) * 2))
+			b.ReportAllocs()
+			b.ResetTimer()
+			var sum uint64
+			for i := 0; i < b.N; i++ {
+				sum += L2SquaredUint8(x, y)
+			}
+			uint8L2BenchmarkSink = sum
+		})
+	}
+}
@@ -12,11 +12,16 @@ import (
 
 	"github.com/xDarkicex/libravdb/internal/index"
 	"github.com/xDarkicex/libravdb/internal/storage"
+	"github.com/xDarkicex/libravdb/internal/util"
 	offheap "github.com/xDarkicex/memory"
 )
 
 var errAsyncIndexerClosed = errors.New("asynchronous indexer is closed")
 
+// asyncIndexApplyBatch bounds how many ready tasks one worker drains into a
+// single index BatchInsert.
+const asyncIndexApplyBatch = 64
+
 // IndexingStats reports the durable-storage to derived-index gap. AppliedLSN is
 // the exact contiguous transaction frontier: no known index mutation at or
 // below that LSN remains queued or in flight.
@@ -28,6 +33,9 @@ type IndexingStats struct {
 	Reserved   uint64
 	Capacity   int
 	Failed     bool
+	// Regenerating counts records whose generated vector an update left to
+	// be embedded again; see GeneratedVectorDefinition.
+	Regenerating uint64
 }
 
 type asyncIndexTask struct {
@@ -72,6 +80,16 @@ type asyncIndexQueue struct {
 	applyGate      sync.RWMutex
 	maintainWakeup chan struct{}
 
+	// Generated vector regeneration runs beside the ring: it writes through
+	// Collection.Update, which itself flushes the ring, so it is neither a
+	// ring task nor counted by outstanding.
+	regenerateOnce   sync.Once
+	regenerateMu     sync.Mutex
+	regenerateIDs    []string
+	regenerateQueued map[string]struct{}
+	regenerateWakeup chan struct{}
+	regenerating     atomic.Uint64
+
 	enqueuePos  atomic.Uint64
 	_           [56]byte
 	dequeuePos  atomic.Uint64
@@ -129,6 +147,9 @@ func newAsyncIndexQueue(collection *Collection, depth, workers int) (*asyncIndex
 		maintainWakeup: make(chan struct{}, 1),
 		capacity:       uint64(depth),
 		workers:        workers,
+
+		regenerateQueued: make(map[string]struct{}),
+		regenerateWakeup: make(chan struct{}, 1),
 	}
 	frontier := store.DurableFrontier()
 	q.durable.Store(frontier)
@@ -209,9 +230,10 @@ func (q *asyncIndexQueue) commitOne(entry *index.VectorEntry, durable storage.Du
 
 func (q *asyncIndexQueue) worker() {
 	defer q.wg.Done()
+	buf := make([]asyncIndexTask, 0, asyncIndexApplyBatch)
 	for {
-		if task, pos, ok := q.pop(); ok {
-			q.apply(task, pos)
+		if tasks, first, ok := q.popRange(buf[:0]); ok {
+			q.apply(tasks, first)
 			continue
 		}
 		if q.closing.Load() && q.outstanding.Load() == 0 {
@@ -221,6 +243,74 @@ func (q *asyncIndexQueue) worker() {
 	}
 }
 
+// regenerate queues id for its generated vector to be embedded again. An id
+// already waiting is queued once. The worker starts with the first id, so
+// collections without a generated vector never run one.
+func (q *asyncIndexQueue) regenerate(id string) {
+	q.regenerateOnce.Do(func() { go q.regenerateWorker() })
+	q.regenerateMu.Lock()
+	if _, queued := q.regenerateQueued[id]; !queued {
+		q.regenerateQueued[id] = struct{}{}
+		q.regenerateIDs = append(q.regenerateIDs, id)
+		q.regenerating.Add(1)
+	}
+	q.regenerateMu.Unlock()
+	select {
+	case q.regenerateWakeup <- struct{}{}:
+	default:
+	}
+}
+
+// regenerateWorker embeds queued generated vectors again in batches of up to
+// asyncIndexApplyBatch records. An id is dequeued before its batch runs, so
+// an update committed meanwhile queues it again. A failure is recorded like a
+// failed index task; a closed collection ends the worker.
+func (q *asyncIndexQueue) regenerateWorker() {
+	for {
+		<-q.regenerateWakeup
+		for {
+			q.regenerateMu.Lock()
+			batch := q.regenerateIDs[:min(len(q.regenerateIDs), asyncIndexApplyBatch)]
+			q.regenerateIDs = q.regenerateIDs[len(batch):]
+			for _, id := range batch {
+				delete(q.regenerateQueued, id)
+			}
+			q.regenerateMu.Unlock()
+			if len(batch) == 0 {
+				break
+			}
+			err := q.collection.regenerateVectors(context.Background(), batch)
+			q.regenerating.Add(^uint64(len(batch) - 1))
+			if errors.Is(err, ErrCollectionClosed) {
+				return
+			}
+			if err != nil {
+				q.recordFailure(fmt.Errorf("regenerate generated vectors: %w", err))
+			}
+		}
+		if q.closing.Load() {
+			return
+		}
+	}
+}
+
+// flushRegeneration waits until no generated vector waits to be embedded
+// again. It must not be called with the collection or database locked,
+// since regeneration writes through Collection.Update.
+func (q *asyncIndexQueue) flushRegeneration(ctx context.Context) error {
+	var backoff lockFreeBackoff
+	for q.regenerating.Load() != 0 {
+		if q.failure.Load() != nil {
+			return q.failureValue()
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		backoff.wait()
+	}
+	return q.failureValue()
+}
+
 // physicalMigrator is implemented by indexes that support physical node relocation (e.g., hmgi).
 type physicalMigrator interface {
 	IncrementalMigrate(cursor *uint32, budget int)
@@ -260,23 +350,34 @@ func (q *asyncIndexQueue) maintainWorker() {
 	}
 }
 
-func (q *asyncIndexQueue) pop() (asyncIndexTask, uint64, bool) {
+// popRange claims the run of consecutive published tasks at the head of the
+// ring, up to asyncIndexApplyBatch, and appends them to tasks in ring order.
+// The whole run is claimed with one CAS, so a batch holds one worker's
+// ordered range and never tasks interleaved with another worker's.
+func (q *asyncIndexQueue) popRange(tasks []asyncIndexTask) ([]asyncIndexTask, uint64, bool) {
+	limit := min(uint64(asyncIndexApplyBatch), q.capacity)
 	for {
 		pos := q.dequeuePos.Load()
-		slot := &q.slots[pos%q.capacity]
-		sequence := atomic.LoadUint64(&slot.sequence)
-		delta := int64(sequence) - int64(pos+1)
-		switch {
-		case delta == 0:
-			if !q.dequeuePos.CompareAndSwap(pos, pos+1) {
-				continue
+		ready := uint64(0)
+		for ready < limit && atomic.LoadUint64(&q.slots[(pos+ready)%q.capacity].sequence) == pos+ready+1 {
+			ready++
+		}
+		if ready == 0 {
+			sequence := atomic.LoadUint64(&q.slots[pos%q.capacity].sequence)
+			if int64(sequence)-int64(pos+1) < 0 {
+				return tasks, 0, false
 			}
-			return slot.task, pos, true
-		case delta < 0:
-			return asyncIndexTask{}, 0, false
-		default:
+			// Another worker claimed pos; reload the head.
 			runtime.Gosched()
+			continue
+		}
+		if !q.dequeuePos.CompareAndSwap(pos, pos+ready) {
+			continue
+		}
+		for i := uint64(0); i < ready; i++ {
+			tasks = append(tasks, q.slots[(pos+i)%q.capacity].task)
 		}
+		return tasks, pos, true
 	}
 }
 
@@ -292,35 +393,76 @@ func (q *asyncIndexQueue) enqueue(task asyncIndexTask) {
 	atomic.StoreUint64(&slot.sequence, pos+1)
 }
 
-func (q *asyncIndexQueue) apply(task asyncIndexTask, pos uint64) {
-	id, err := q.storage.GetIDByOrdinal(context.Background(), task.ordinal)
-	var vector []float32
-	if err == nil {
-		vector, err = q.storage.GetByOrdinal(task.ordinal)
+// apply publishes the run of tasks claimed at ring position first in one
+// index BatchInsert, so HNSW collections plan the whole run against one
+// graph epoch instead of contending on per-vector inserts. Every task that
+// cannot be applied records its own failure.
+func (q *asyncIndexQueue) apply(tasks []asyncIndexTask, first uint64) {
+	metric := q.collection.config.Metric
+	entries := make([]*index.VectorEntry, 0, len(tasks))
+	owners := make([]asyncIndexTask, 0, len(tasks))
+	for _, task := range tasks {
+		id, err := q.storage.GetIDByOrdinal(context.Background(), task.ordinal)
+		var vector []float32
+		if err == nil {
+			vector, err = q.storage.GetByOrdinal(task.ordinal)
+		}
+		if err != nil {
+			q.recordFailure(taskApplyError(task, err))
+			continue
+		}
+		entries = append(entries, entryForIndex(metric, &index.VectorEntry{ID: id, Vector: vector, Ordinal: task.ordinal}))
+		owners = append(owners, task)
 	}
 
 	// Keep publication, active-state retirement, and slot reuse within the same
 	// read-side gate so a precise frontier scan cannot observe a recycled task.
 	q.applyGate.RLock()
-	if err == nil {
-		entry := index.VectorEntry{ID: id, Vector: vector, Ordinal: task.ordinal}
+	if len(entries) > 0 {
 		q.collection.mu.RLock()
-		err = q.collection.index.Insert(context.Background(), entryForIndex(q.collection.config.Metric, &entry))
+		q.insertEntries(entries, owners)
 		q.collection.mu.RUnlock()
 	}
-	if err != nil {
-		q.recordFailure(fmt.Errorf("apply durable transaction %d ordinal %d: %w", task.commitLSN, task.ordinal, err))
-	}
 
-	slot := &q.slots[pos%q.capacity]
-	atomic.StoreUint64(&slot.active, 0)
-	atomic.StoreUint64(&slot.sequence, pos+q.capacity)
-	q.pending.Add(^uint64(0))
-	q.outstanding.Add(^uint64(0))
+	for pos := first; pos < first+uint64(len(tasks)); pos++ {
+		slot := &q.slots[pos%q.capacity]
+		atomic.StoreUint64(&slot.active, 0)
+		atomic.StoreUint64(&slot.sequence, pos+q.capacity)
+	}
+	q.pending.Add(^uint64(len(tasks) - 1))
+	q.outstanding.Add(^uint64(len(tasks) - 1))
 	q.applyGate.RUnlock()
 	q.advanceAppliedIfDrained()
 }
 
+// insertEntries inserts entries, which belong to owners, with one
+// BatchInsert. When the batch stops early, the entries it did not insert are
+// applied one at a time so each failure is recorded against its own
+// transaction instead of retiring the rest of the batch unapplied. Indexes
+// that do not report how many entries they inserted are re-applied from the
+// first entry; their Insert replaces an existing ID.
+func (q *asyncIndexQueue) insertEntries(entries []*index.VectorEntry, owners []asyncIndexTask) {
+	idx := q.collection.index
+	err := idx.BatchInsert(context.Background(), entries)
+	if err == nil {
+		return
+	}
+	inserted := 0
+	var batchErr *util.BatchInsertError
+	if errors.As(err, &batchErr) {
+		inserted = batchErr.Inserted
+	}
+	for i := inserted; i < len(entries); i++ {
+		if err := idx.Insert(context.Background(), entries[i]); err != nil {
+			q.recordFailure(taskApplyError(owners[i], err))
+		}
+	}
+}
+
+func taskApplyError(task asyncIndexTask, err error) error {
+	return fmt.Errorf("apply durable transaction %d ordinal %d: %w", task.commitLSN, task.ordinal, err)
+}
+
 func (q *asyncIndexQueue) flush(ctx context.Context) error {
 	var backoff lockFreeBackoff
 	for {
@@ -339,6 +481,13 @@ func (q *asyncIndexQueue) close() error {
 	if owner {
 		q.accepting.Store(false)
 		q.signalWorkers(q.workers)
+		// The regeneration worker is not waited for: it may be blocked on the
+		// collection lock Close holds, and exits once it sees the collection
+		// closed. Database.Close drains it first.
+		select {
+		case q.regenerateWakeup <- struct{}{}:
+		default:
+		}
 		// Wake maintainWorker so it sees closing=true and exits
 		select {
 		case q.maintainWakeup <- struct{}{}:
@@ -384,6 +533,8 @@ func (q *asyncIndexQueue) stats() IndexingStats {
 		Reserved:   q.reserved.Load(),
 		Capacity:   int(q.capacity),
 		Failed:     q.failure.Load() != nil,
+
+		Regenerating: q.regenerating.Load(),
 	}
 }
 
@@ -431,13 +582,24 @@ func (q *asyncIndexQueue) preciseAppliedLocked() uint64 {
 	}
 }
 
+// recordFailure adds err to the queue's failure. The first failure stops
+// admission; later ones are joined to it so no failed task goes unreported.
 func (q *asyncIndexQueue) recordFailure(err error) {
 	if err == nil {
 		return
 	}
-	failure := &asyncIndexFailure{err: err}
-	if q.failure.CompareAndSwap(nil, failure) {
-		q.accepting.Store(false)
+	for {
+		current := q.failure.Load()
+		failure := &asyncIndexFailure{err: err}
+		if current != nil {
+			failure.err = errors.Join(current.err, err)
+		}
+		if q.failure.CompareAndSwap(current, failure) {
+			if current == nil {
+				q.accepting.Store(false)
+			}
+			return
+		}
 	}
 }
 
@@ -497,11 +659,15 @@ func atomicMax(dst *atomic.Uint64, value uint64) {
 }
 
 // FlushIndex waits until every durable asynchronous insert has been applied to
-// the derived index. It is a no-op for synchronous collections.
+// the derived index, and every generated vector an update left to the
+// indexer has been embedded again. It is a no-op for synchronous collections.
 func (c *Collection) FlushIndex(ctx context.Context) error {
 	if c == nil || c.asyncIndex == nil {
 		return nil
 	}
+	if err := c.asyncIndex.flushRegeneration(ctx); err != nil {
+		return err
+	}
 	return c.asyncIndex.flush(ctx)
 }
 
@@ -0,0 +1,58 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xDarkicex/libravdb/internal/storage/singlefile"
+)
+
+// ErrBackupChainBroken reports a backup chain RestoreToLSN cannot replay: a
+// file that is not an incremental backup, an increment of another database,
+// or a gap between consecutive backups.
+var ErrBackupChainBroken = singlefile.ErrBackupChainBroken
+
+// BackupIncremental writes the transactions committed after sinceLSN to a
+// new file at destPath and returns the LSN it ends at, which is the sinceLSN
+// of the next increment. For the first increment after a full Backup, pass
+// the LatestCommitLSN read before that Backup; overlapping increments are
+// harmless. It fails with ErrWALTruncated once Vacuum or compaction has
+// folded the needed WAL into a snapshot, after which a new full Backup is
+// required. Increments of an encrypted database are encrypted.
+func (db *Database) BackupIncremental(ctx context.Context, destPath string, sinceLSN uint64) (uint64, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	if db.closed {
+		return 0, ErrDatabaseClosed
+	}
+
+	if v, ok := db.storage.(interface {
+		BackupIncremental(context.Context, string, uint64) (uint64, error)
+	}); ok {
+		return v.BackupIncremental(ctx, destPath, sinceLSN)
+	}
+
+	return 0, fmt.Errorf("underlying storage engine does not support incremental backup")
+}
+
+// RestoreToLSN rebuilds the database as of commit lsn into a new file at
+// outPath. backupChain is a full Backup followed by the incremental backups
+// taken after it, in order. lsn must be a commit the chain covers; the result
+// is validated against the restored commit catalog, the one SnapshotAtLSN
+// resolves against, so an LSN that is not a commit fails. Unlike SnapshotAtLSN
+// this is not bounded by the temporal retention window. Pass WithEncryptionKey
+// to restore an encrypted chain; other options are ignored.
+func RestoreToLSN(ctx context.Context, backupChain []string, lsn uint64, outPath string, opts ...Option) error {
+	config := &Config{}
+	for _, opt := range opts {
+		if err := opt(config); err != nil {
+			return fmt.Errorf("failed to apply option: %w", err)
+		}
+	}
+	var storageOptions []singlefile.Option
+	if config.encryption != nil {
+		storageOptions = append(storageOptions, singlefile.WithEncryption(config.encryption))
+	}
+	return singlefile.RestoreToLSN(ctx, backupChain, lsn, outPath, storageOptions...)
+}
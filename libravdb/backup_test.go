@@ -0,0 +1,69 @@
+package libravdb
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestBackupIncrementalRestoresToLSN(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	db, err := Open(WithStoragePath(filepath.Join(dir, "primary.libravdb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	docs, err := db.CreateCollection(ctx, "docs", WithDimension(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "a", []float32{1, 0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	base, err := db.LatestCommitLSN(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	full := filepath.Join(dir, "full.libravdb")
+	if err := db.Backup(ctx, full); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "b", []float32{0, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	atB, err := db.LatestCommitLSN(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "c", []float32{1, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	inc := filepath.Join(dir, "inc.libravdb")
+	if _, err := db.BackupIncremental(ctx, inc, base); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "restored.libravdb")
+	if err := RestoreToLSN(ctx, []string{full, inc}, atB, out); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := Open(WithStoragePath(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer restored.Close()
+	if latest, err := restored.LatestCommitLSN(ctx); err != nil || latest != atB {
+		t.Fatalf("restored LatestCommitLSN = %d, %v; want %d", latest, err, atB)
+	}
+	coll, err := restored.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := coll.Get(ctx, "b"); err != nil {
+		t.Fatalf("Get(b): %v", err)
+	}
+	if _, err := coll.Get(ctx, "c"); err == nil {
+		t.Fatal("restore to LSN kept a later commit")
+	}
+}
@@ -0,0 +1,225 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/storage"
+	"github.com/xDarkicex/libravdb/internal/storage/singlefile"
+)
+
+// ErrRetentionExpired reports that temporal retention no longer covers the
+// requested LSN. A change feed cannot resume from it.
+var ErrRetentionExpired = singlefile.ErrRetentionExpired
+
+// ChangeType identifies the mutation a ChangeEvent describes.
+type ChangeType uint8
+
+const (
+	ChangeRecordPut        = ChangeType(storage.ChangeRecordPut)
+	ChangeRecordDelete     = ChangeType(storage.ChangeRecordDelete)
+	ChangeEdgeAdd          = ChangeType(storage.ChangeEdgeAdd)
+	ChangeEdgeRemove       = ChangeType(storage.ChangeEdgeRemove)
+	ChangeNodeEdgesDrop    = ChangeType(storage.ChangeNodeEdgesDrop)
+	ChangeCollectionCreate = ChangeType(storage.ChangeCollectionCreate)
+	ChangeCollectionDelete = ChangeType(storage.ChangeCollectionDelete)
+	ChangeCollectionConfig = ChangeType(storage.ChangeCollectionConfig)
+	ChangeCollectionRename = ChangeType(storage.ChangeCollectionRename)
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeRecordPut:
+		return "put"
+	case ChangeRecordDelete:
+		return "delete"
+	case ChangeEdgeAdd:
+		return "edge_add"
+	case ChangeEdgeRemove:
+		return "edge_remove"
+	case ChangeNodeEdgesDrop:
+		return "node_edges_drop"
+	case ChangeCollectionCreate:
+		return "collection_create"
+	case ChangeCollectionDelete:
+		return "collection_delete"
+	case ChangeCollectionConfig:
+		return "collection_config"
+	case ChangeCollectionRename:
+		return "collection_rename"
+	default:
+		return fmt.Sprintf("ChangeType(%d)", uint8(t))
+	}
+}
+
+// EdgeChange is the edge an edge event added or removed. Weight and
+// Properties are only set for ChangeEdgeAdd.
+type EdgeChange struct {
+	Properties []byte
+	Src        uint64
+	Tgt        uint64
+	Weight     float32
+	Kind       uint8
+}
+
+// ChangeEvent is one committed mutation. CommitLSN is the LSN the writing
+// transaction's CommitReceipt reported; events of one transaction share it.
+//
+// Record events carry the version visible before the commit in Old and the
+// one after it in New, so Old is nil for an insert and New is nil for a
+// delete. A transaction that writes a record several times yields one event.
+// A ChangeCollectionRename event names the collection by its new name and
+// carries the old one in PreviousName.
+type ChangeEvent struct {
+	CommitTime   time.Time
+	Old          *Record
+	New          *Record
+	Collection   string
+	PreviousName string
+	ID           string
+	Edge         EdgeChange
+	CommitLSN    uint64
+	NodeID       uint64
+	Type         ChangeType
+}
+
+// SubscribeOptions selects the changes a subscription receives.
+type SubscribeOptions struct {
+	// Collections limits the feed to these collections. Empty means all. A
+	// rename event is delivered when either of its names is listed.
+	Collections []string
+	// FromLSN resumes the feed after this commit LSN, typically the
+	// CommitLSN of the last event the consumer processed. Zero starts at the
+	// oldest retained commit.
+	FromLSN uint64
+}
+
+// changeFeedBatch bounds how many transactions one storage read decodes
+// while holding the engine's read lock.
+const changeFeedBatch = 128
+
+// Subscribe iterates over committed changes in commit order, waiting for new
+// commits once it has caught up, until ctx is done. Iteration ends with
+// ctx's error, ErrDatabaseClosed, or ErrRetentionExpired when temporal
+// retention no longer covers the feed's position. Changes are read back from
+// durable storage, so a consumer that stores the CommitLSN of the last event
+// it handled can resume after a restart.
+func (db *Database) Subscribe(ctx context.Context, opts SubscribeOptions) iter.Seq2[ChangeEvent, error] {
+	filter := make(map[string]struct{}, len(opts.Collections))
+	for _, name := range opts.Collections {
+		filter[name] = struct{}{}
+	}
+	return func(yield func(ChangeEvent, error) bool) {
+		db.mu.RLock()
+		closed := db.closed
+		engine := db.storage
+		db.mu.RUnlock()
+		if closed {
+			yield(ChangeEvent{}, ErrDatabaseClosed)
+			return
+		}
+		reader, ok := engine.(storage.ChangeReader)
+		if !ok {
+			yield(ChangeEvent{}, fmt.Errorf("storage engine does not support change feeds"))
+			return
+		}
+		cursor := storage.WALCursor{LSN: opts.FromLSN}
+		for {
+			// Take the notification channel before reading so a commit
+			// landing after the read still wakes the wait below.
+			committed := reader.CommitNotify()
+			sets, next, err := reader.ReadChanges(ctx, cursor, changeFeedBatch)
+			if err != nil {
+				if db.isClosed() {
+					err = ErrDatabaseClosed
+				}
+				yield(ChangeEvent{}, err)
+				return
+			}
+			for _, set := range sets {
+				for _, event := range set.Events {
+					change, ok := changeEventFromStorage(set, event)
+					if !ok {
+						continue
+					}
+					if len(filter) > 0 {
+						_, ok := filter[change.Collection]
+						if !ok && change.PreviousName != "" {
+							_, ok = filter[change.PreviousName]
+						}
+						if !ok {
+							continue
+						}
+					}
+					if !yield(change, nil) {
+						return
+					}
+				}
+			}
+			cursor = next
+			if len(sets) == changeFeedBatch {
+				continue
+			}
+			select {
+			case <-ctx.Done():
+				yield(ChangeEvent{}, ctx.Err())
+				return
+			case <-committed:
+			}
+		}
+	}
+}
+
+func (db *Database) isClosed() bool {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.closed
+}
+
+// changeEventFromStorage converts a storage event, naming sharded records by
+// their parent collection. A sharded collection's DDL is reported once, for
+// its first shard.
+func changeEventFromStorage(set storage.ChangeSet, event storage.ChangeEvent) (ChangeEvent, bool) {
+	change := ChangeEvent{
+		Type:         ChangeType(event.Type),
+		CommitLSN:    set.CommitLSN,
+		Collection:   event.Collection,
+		PreviousName: event.PreviousName,
+		ID:           event.ID,
+		NodeID:       event.NodeID,
+		Old:          recordFromTemporal(event.Old),
+		New:          recordFromTemporal(event.New),
+	}
+	if set.Timestamp != 0 {
+		change.CommitTime = time.Unix(0, set.Timestamp).UTC()
+	}
+	if parent, shard, ok := parseShardName(event.Collection); ok {
+		change.Collection = parent
+		switch change.Type {
+		case ChangeCollectionCreate, ChangeCollectionDelete, ChangeCollectionConfig:
+			if shard != 0 {
+				return change, false
+			}
+		}
+	}
+	switch change.Type {
+	case ChangeEdgeAdd, ChangeEdgeRemove:
+		change.Edge = EdgeChange{
+			Src: event.Edge.Src, Tgt: event.Edge.Tgt, Weight: event.Edge.Weight,
+			Kind: event.Edge.Kind, Properties: event.Edge.Properties,
+		}
+	}
+	return change, true
+}
+
+func recordFromTemporal(record *storage.TemporalRecord) *Record {
+	if record == nil {
+		return nil
+	}
+	return &Record{
+		ID: record.ID, Vector: record.Vector, Metadata: record.Metadata,
+		Ordinal: record.Ordinal, Version: record.Version,
+	}
+}
@@ -0,0 +1,148 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// collectChanges reads n events from a subscription, failing on any error.
+func collectChanges(t *testing.T, db *Database, opts SubscribeOptions, n int) []ChangeEvent {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var events []ChangeEvent
+	for event, err := range db.Subscribe(ctx, opts) {
+		if err != nil {
+			t.Fatalf("Subscribe after %d events: %v", len(events), err)
+		}
+		events = append(events, event)
+		if len(events) == n {
+			break
+		}
+	}
+	return events
+}
+
+func TestSubscribeEmitsCommittedChangesInOrder(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "changefeed.libravdb")
+	db, err := Open(WithStoragePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := NewGraph(GraphConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer graph.Close()
+	docs, err := db.CreateCollection(ctx, "docs", WithDimension(2), WithGraph(graph))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes, err := db.CreateCollection(ctx, "notes", WithDimension(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "a", []float32{1, 0}, map[string]interface{}{"rev": "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := notes.Insert(ctx, "n", []float32{1, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "a", []float32{0, 1}, map[string]interface{}{"rev": "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "b", []float32{1, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	a, _ := db.GetNodeID(ctx, "docs", "a")
+	b, _ := db.GetNodeID(ctx, "docs", "b")
+	txn := graph.BeginTxn()
+	if err := txn.AddEdge(a, b, 1, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	events := collectChanges(t, db, SubscribeOptions{Collections: []string{"docs"}}, 6)
+	wantTypes := []ChangeType{ChangeCollectionCreate, ChangeRecordPut, ChangeRecordPut, ChangeRecordPut, ChangeEdgeAdd, ChangeRecordDelete}
+	for i, event := range events {
+		if event.Collection != "docs" || event.Type != wantTypes[i] {
+			t.Fatalf("event %d = %s %s, want %s docs", i, event.Type, event.Collection, wantTypes[i])
+		}
+		if i > 0 && event.CommitLSN <= events[i-1].CommitLSN {
+			t.Fatalf("event %d at LSN %d after %d", i, event.CommitLSN, events[i-1].CommitLSN)
+		}
+		if event.CommitTime.IsZero() {
+			t.Fatalf("event %d has no commit time", i)
+		}
+	}
+	update := events[2]
+	if update.ID != "a" || update.Old == nil || update.Old.Metadata["rev"] != "1" || update.New == nil || update.New.Metadata["rev"] != "2" {
+		t.Fatalf("update event = %+v", update)
+	}
+	if edge := events[4].Edge; edge.Src != a || edge.Tgt != b {
+		t.Fatalf("edge event = %+v, want %d->%d", edge, a, b)
+	}
+	if deleted := events[5]; deleted.ID != "a" || deleted.New != nil || deleted.Old == nil || deleted.Old.Vector[1] != 1 {
+		t.Fatalf("delete event = %+v", deleted)
+	}
+	last, err := db.LatestCommitLSN(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events[5].CommitLSN != last {
+		t.Fatalf("delete event at LSN %d, latest commit %d", events[5].CommitLSN, last)
+	}
+
+	// A caught-up subscription waits for the next commit.
+	waitCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	inserted := make(chan error, 1)
+	go func() {
+		inserted <- notes.Insert(context.Background(), "late", []float32{0, 0}, nil)
+	}()
+	for event, err := range db.Subscribe(waitCtx, SubscribeOptions{FromLSN: last}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Collection != "notes" || event.ID != "late" || event.Type != ChangeRecordPut {
+			t.Fatalf("live event = %+v", event)
+		}
+		break
+	}
+	if err := <-inserted; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The feed resumes from a stored LSN after a restart.
+	db, err = Open(WithStoragePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	resumed := collectChanges(t, db, SubscribeOptions{FromLSN: events[3].CommitLSN}, 3)
+	if resumed[0].Type != ChangeEdgeAdd || resumed[1].Type != ChangeRecordDelete || resumed[2].ID != "late" {
+		t.Fatalf("resumed feed = %+v", resumed)
+	}
+
+	cancelled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	var feedErr error
+	for _, err := range db.Subscribe(cancelled, SubscribeOptions{FromLSN: last + 1}) {
+		feedErr = err
+	}
+	if !errors.Is(feedErr, context.Canceled) {
+		t.Fatalf("cancelled subscription ended with %v", feedErr)
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"github.com/xDarkicex/libravdb/internal/filter"
 	"github.com/xDarkicex/libravdb/internal/graph"
 	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/index/hnsw"
 	"github.com/xDarkicex/libravdb/internal/memory"
 	"github.com/xDarkicex/libravdb/internal/obs"
 	"github.com/xDarkicex/libravdb/internal/optimizer"
@@ -57,8 +58,22 @@ type Collection struct {
 	jsonIndexBuiltAt       uint64
 	// jsonContainmentIndex is a rebuildable, GIN-shaped posting map. It is
 	// derived from committed row metadata; row WAL remains authoritative.
-	jsonContainmentIndex     map[string]map[string][]string
-	jsonContainmentBuiltAt   uint64
+	jsonContainmentIndex   map[string]map[string][]string
+	jsonContainmentBuiltAt uint64
+	// sparseIndexes holds the inverted lists of each sparse-vector field. They
+	// are built on first use and then maintained by committed writes.
+	sparseIndexMu sync.Mutex
+	sparseIndexes map[string]*sparseFieldIndex
+	// fullTextIndexes holds the BM25 postings of each declared full-text
+	// index by name. They are restored from the last checkpoint or built on
+	// first use, and then maintained by committed writes rather than rebuilt
+	// per mutation epoch. fullTextWrites counts writes between their storage
+	// commit and their posting update, and fullTextCoveredLSN is the last
+	// checkpoint frontier taken with none; see fullTextImages.
+	fullTextMu               sync.Mutex
+	fullTextIndexes          map[string]*fullTextIndex
+	fullTextWrites           atomic.Int64
+	fullTextCoveredLSN       atomic.Uint64
 	metadataMutationEpoch    atomic.Uint64
 	metadataLookupIndexed    atomic.Uint64
 	metadataLookupFallback   atomic.Uint64
@@ -66,6 +81,11 @@ type Collection struct {
 	metadataIndexRecords     atomic.Uint64
 	metadataLookupCandidates atomic.Uint64
 	costModel                *collectionCostModelState
+	// vectorSpaces holds the HNSW graph of each declared vector space by
+	// lower-cased name, built on first use and maintained by committed
+	// writes like the full-text indexes.
+	vectorSpaceMu sync.Mutex
+	vectorSpaces  map[string]*vectorSpaceIndex
 }
 
 // CollectionConfig holds collection-specific configuration
@@ -85,6 +105,14 @@ type CollectionConfig struct {
 	SQLIndexes             []SQLIndexDefinition           `json:"sql_indexes,omitempty"`
 	SQLIndexedFields       []string                       `json:"sql_indexed_fields,omitempty"`
 	JSONIndexes            []JSONIndexDefinition          `json:"json_indexes,omitempty"`
+	SparseVectors          map[string]int                 `json:"sparse_vectors,omitempty"` // field name -> dimension
+	FullTextIndexes        []FullTextIndexDefinition      `json:"full_text_indexes,omitempty"`
+	VectorSpaces           []VectorSpaceDefinition        `json:"vector_spaces,omitempty"`
+	GeneratedVector        *GeneratedVectorDefinition     `json:"generated_vector,omitempty"`
+	TTLField               string                         `json:"ttl_field,omitempty"`
+	DefaultTTL             time.Duration                  `json:"default_ttl,omitempty"`
+	IndexDirectory         string                         `json:"index_directory,omitempty"`
+	Int8Construction       bool                           `json:"int8_construction,omitempty"`
 	BatchConfig            BatchConfig                    `json:"batch_config,omitempty"`
 	AutoIndexThresholds    struct {
 		HNSWThreshold  int `json:"hnsw_threshold,omitempty"`
@@ -92,7 +120,9 @@ type CollectionConfig struct {
 	} `json:"auto_index_thresholds,omitempty"`
 	MemoryLimit   int64          `json:"memory_limit,omitempty"`
 	EfSearch      int            `json:"ef_search"`
+	RescoreK      int            `json:"rescore_k,omitempty"`
 	ML            float64        `json:"ml"`
+	PruneAlpha    float64        `json:"prune_alpha,omitempty"`
 	RawStoreCap   int            `json:"raw_store_cap,omitempty"`
 	IDMapCapacity int            `json:"id_map_capacity,omitempty"`
 	Metric        DistanceMetric `json:"metric"`
@@ -178,6 +208,16 @@ func (g *collectionGraph) ForEachVertexLabel(fn func(uint64, string) bool) {
 	}
 }
 
+// ExpiredEdges forwards the TTL reaper's scan to the shared graph.
+func (g *collectionGraph) ExpiredEdges(now time.Time) ([]graph.ExpiredEdge, error) {
+	if lister, ok := g.Graph.(interface {
+		ExpiredEdges(time.Time) ([]graph.ExpiredEdge, error)
+	}); ok {
+		return lister.ExpiredEdges(now)
+	}
+	return nil, nil
+}
+
 func (g *collectionGraph) ReplayEdgeAdd(src, tgt uint64, weight float32, kind uint8, properties []byte, commitLSN uint64) error {
 	target, ok := g.Graph.(storage.GraphRecoveryTarget)
 	if !ok {
@@ -305,6 +345,23 @@ func sqlIndexesFromStorage(indexes []storage.SQLIndexDefinition) []SQLIndexDefin
 	return converted
 }
 
+// namedUniqueConstraintsFromSQLIndexes restores the unique constraints that
+// CREATE UNIQUE INDEX and ALTER TABLE ... ADD UNIQUE persist as unique SQL
+// index declarations.
+func namedUniqueConstraintsFromSQLIndexes(indexes []storage.SQLIndexDefinition) map[string][]string {
+	var named map[string][]string
+	for _, definition := range indexes {
+		if !definition.Unique || definition.Name == "" || len(definition.Columns) == 0 {
+			continue
+		}
+		if named == nil {
+			named = make(map[string][]string)
+		}
+		named[definition.Name] = append([]string(nil), definition.Columns...)
+	}
+	return named
+}
+
 // JSONIndexDefinition is a durable expression-index declaration for one
 // JSON/JSONB column. Path is a PostgreSQL text-array literal such as
 // "{profile,active}". TextResult corresponds to #>>; false corresponds to
@@ -339,7 +396,12 @@ func (c *Collection) Dimension() int {
 func (c *Collection) Config() CollectionConfig {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.configLocked()
+}
 
+// configLocked returns a deep copy of the collection configuration. The
+// caller must hold c.mu.
+func (c *Collection) configLocked() CollectionConfig {
 	if c.config == nil {
 		return CollectionConfig{}
 	}
@@ -350,6 +412,10 @@ func (c *Collection) Config() CollectionConfig {
 	config.SQLIndexes = cloneSQLIndexDefinitions(c.config.SQLIndexes)
 	config.SQLIndexedFields = append([]string(nil), c.config.SQLIndexedFields...)
 	config.JSONIndexes = append([]JSONIndexDefinition(nil), c.config.JSONIndexes...)
+	config.SparseVectors = cloneSparseVectorDeclarations(c.config.SparseVectors)
+	config.FullTextIndexes = append([]FullTextIndexDefinition(nil), c.config.FullTextIndexes...)
+	config.VectorSpaces = append([]VectorSpaceDefinition(nil), c.config.VectorSpaces...)
+	config.GeneratedVector = cloneGeneratedVector(c.config.GeneratedVector)
 	config.PrimaryKeyColumns = append([]string(nil), c.config.PrimaryKeyColumns...)
 	if c.config.NamedUniqueConstraints != nil {
 		config.NamedUniqueConstraints = make(map[string][]string, len(c.config.NamedUniqueConstraints))
@@ -358,6 +424,21 @@ func (c *Collection) Config() CollectionConfig {
 		}
 	}
 
+	if c.config.ColumnConstraints != nil {
+		config.ColumnConstraints = make(map[string]uint16, len(c.config.ColumnConstraints))
+		for column, flags := range c.config.ColumnConstraints {
+			config.ColumnConstraints[column] = flags
+		}
+	}
+	if c.config.ColumnDefaults != nil {
+		config.ColumnDefaults = make(map[string]string, len(c.config.ColumnDefaults))
+		for column, value := range c.config.ColumnDefaults {
+			config.ColumnDefaults[column] = value
+		}
+	}
+	config.ForeignKeys = append([]catalog.ForeignKeyInfo(nil), c.config.ForeignKeys...)
+	config.CheckConstraints = append([]optimizer.DDLCheckConstraint(nil), c.config.CheckConstraints...)
+
 	if c.config.MetadataSchema != nil {
 		config.MetadataSchema = make(MetadataSchema, len(c.config.MetadataSchema))
 		for field, fieldType := range c.config.MetadataSchema {
@@ -647,17 +728,19 @@ func createIndexForCollection(config *CollectionConfig, provider interface {
 	switch config.IndexType {
 	case HNSW:
 		return index.NewHNSW(&index.HNSWConfig{
-			Dimension:      config.Dimension,
-			M:              config.M,
-			EfConstruction: config.EfConstruction,
-			EfSearch:       config.EfSearch,
-			ML:             config.ML,
-			Metric:         util.DistanceMetric(config.Metric),
-			Provider:       provider,
-			RawVectorStore: config.RawVectorStore,
-			RawStoreCap:    config.RawStoreCap,
-			IDMapCapacity:  config.IDMapCapacity,
-			Quantization:   config.Quantization,
+			Dimension:        config.Dimension,
+			M:                config.M,
+			EfConstruction:   config.EfConstruction,
+			EfSearch:         config.EfSearch,
+			RescoreK:         config.RescoreK,
+			ML:               config.ML,
+			Metric:           util.DistanceMetric(config.Metric),
+			Provider:         provider,
+			RawVectorStore:   config.RawVectorStore,
+			RawStoreCap:      config.RawStoreCap,
+			IDMapCapacity:    config.IDMapCapacity,
+			Quantization:     config.Quantization,
+			Int8Construction: config.Int8Construction,
 		})
 	case IVFPQ:
 		temp := &Collection{config: config}
@@ -667,6 +750,17 @@ func createIndexForCollection(config *CollectionConfig, provider interface {
 			Dimension:    config.Dimension,
 			Metric:       util.DistanceMetric(config.Metric),
 			Quantization: config.Quantization,
+			RescoreK:     config.RescoreK,
+		})
+	case DiskANN:
+		return index.NewDiskANN(&index.DiskANNConfig{
+			Quantization: config.Quantization,
+			Dir:          config.IndexDirectory,
+			Dimension:    config.Dimension,
+			R:            config.M,
+			L:            config.EfConstruction,
+			Alpha:        float32(config.PruneAlpha),
+			Metric:       util.DistanceMetric(config.Metric),
 		})
 	case BTree:
 		return index.NewBTree(&index.BTreeConfig{
@@ -705,6 +799,7 @@ const (
 	IVFPQ
 	Flat
 	BTree
+	DiskANN
 )
 
 // DefaultAutoIndexThresholds defines the default thresholds for auto-index selection.
@@ -817,6 +912,15 @@ func newCollection(ctx context.Context, name string, storageEngine storage.Engin
 		SQLIndexedFields: append([]string(nil), config.SQLIndexedFields...),
 		GraphEnabled:     config.Graph != nil,
 		GraphNamespace:   config.GraphNamespace,
+		SparseVectors:    cloneSparseVectorDeclarations(config.SparseVectors),
+		FullTextIndexes:  fullTextIndexesToStorage(config.FullTextIndexes),
+		VectorSpaces:     vectorSpacesToStorage(config.VectorSpaces),
+		GeneratedVector:  generatedVectorToStorage(config.GeneratedVector),
+		TTLField:         config.TTLField,
+		DefaultTTL:       config.DefaultTTL,
+		PruneAlpha:       config.PruneAlpha,
+		IndexDirectory:   config.IndexDirectory,
+		Int8Construction: config.Int8Construction,
 	}
 
 	// Initialize memory manager if memory management is configured
@@ -922,7 +1026,17 @@ func newCollectionFromStorage(ctx context.Context, name string, storageCollectio
 		SQLIndexedFields: append([]string(nil), engineConfig.SQLIndexedFields...),
 		Graph:            graphLayer,
 		GraphNamespace:   engineConfig.GraphNamespace,
-	}
+		SparseVectors:    cloneSparseVectorDeclarations(engineConfig.SparseVectors),
+		FullTextIndexes:  fullTextIndexesFromStorage(engineConfig.FullTextIndexes),
+		VectorSpaces:     vectorSpacesFromStorage(engineConfig.VectorSpaces),
+		GeneratedVector:  generatedVectorFromStorage(engineConfig.GeneratedVector),
+		TTLField:         engineConfig.TTLField,
+		DefaultTTL:       engineConfig.DefaultTTL,
+		PruneAlpha:       engineConfig.PruneAlpha,
+		IndexDirectory:   engineConfig.IndexDirectory,
+		Int8Construction: engineConfig.Int8Construction,
+	}
+	config.NamedUniqueConstraints = namedUniqueConstraintsFromSQLIndexes(engineConfig.SQLIndexes)
 	if config.NClusters <= 0 {
 		config.NClusters = 100
 	}
@@ -1094,8 +1208,18 @@ func newShardedCollectionFromStorage(ctx context.Context, name string, shardStor
 		SQLIndexedFields: append([]string(nil), engineConfig.SQLIndexedFields...),
 		Graph:            graphLayer,
 		GraphNamespace:   engineConfig.GraphNamespace,
+		SparseVectors:    cloneSparseVectorDeclarations(engineConfig.SparseVectors),
+		FullTextIndexes:  fullTextIndexesFromStorage(engineConfig.FullTextIndexes),
+		VectorSpaces:     vectorSpacesFromStorage(engineConfig.VectorSpaces),
+		GeneratedVector:  generatedVectorFromStorage(engineConfig.GeneratedVector),
+		TTLField:         engineConfig.TTLField,
+		DefaultTTL:       engineConfig.DefaultTTL,
+		PruneAlpha:       engineConfig.PruneAlpha,
+		IndexDirectory:   engineConfig.IndexDirectory,
+		Int8Construction: engineConfig.Int8Construction,
 		Sharded:          true, // Mark as sharded so lifecycle methods work correctly
 	}
+	config.NamedUniqueConstraints = namedUniqueConstraintsFromSQLIndexes(engineConfig.SQLIndexes)
 	if config.NClusters <= 0 {
 		config.NClusters = 100
 	}
@@ -1190,12 +1314,20 @@ func (c *Collection) rebuildIndex(ctx context.Context) error {
 
 // Insert adds or updates a vector in the collection
 func (c *Collection) Insert(ctx context.Context, id string, vector []float32, metadata map[string]interface{}) (err error) {
+	defer c.beginFullTextWrite()()
 	defer func() {
 		if err == nil {
 			c.addToMetadataIndex(id, metadata)
+			c.noteFullTextWrite(id, metadata)
+			c.noteVectorSpaceWrite(id, metadata)
+			c.noteSparseWrite(id, metadata)
 			c.markMetadataIndexDirty()
 		}
 	}()
+	// Preflight: embed an omitted generated vector from the record's text.
+	if vector, err = c.generateVector(ctx, vector, metadata); err != nil {
+		return err
+	}
 	// Preflight: validate dimension before acquiring write permit or mutex
 	if len(vector) != c.config.Dimension {
 		return fmt.Errorf("vector dimension %d does not match collection dimension %d",
@@ -1398,8 +1530,14 @@ func (c *Collection) Insert(ctx context.Context, id string, vector []float32, me
 }
 
 func (c *Collection) insertBatch(ctx context.Context, entries []*index.VectorEntry) (err error) {
+	defer c.beginFullTextWrite()()
 	defer func() {
 		if err == nil && len(entries) > 0 {
+			for _, entry := range entries {
+				c.noteFullTextWrite(entry.ID, entry.Metadata)
+				c.noteVectorSpaceWrite(entry.ID, entry.Metadata)
+				c.noteSparseWrite(entry.ID, entry.Metadata)
+			}
 			c.markMetadataIndexDirty()
 		}
 	}()
@@ -1659,12 +1797,27 @@ func (c *Collection) Update(ctx context.Context, id string, vector []float32, me
 		}
 		newMetadata[k] = cloneMetadataValue(v)
 	}
+	// A generated vector follows its source text when the caller keeps the
+	// stored vector.
+	var regenerate bool
+	if vector, regenerate, err = c.regenerateVector(ctx, vector, oldMetadata, newMetadata); err != nil {
+		return err
+	}
 
 	// Preflight: enforce NOT NULL constraints against the complete post-update
 	// row before evaluating CHECK/FK/UNIQUE constraints.
 	if err := c.validateNotNullConstraints(newMetadata); err != nil {
 		return err
 	}
+	if metadata != nil {
+		// Validation canonicalizes typed values in newMetadata; write those
+		// forms rather than the caller's originals.
+		delta := make(map[string]interface{}, len(metadata))
+		for k := range metadata {
+			delta[k] = newMetadata[k]
+		}
+		metadata = delta
+	}
 
 	// Preflight: validate CHECK constraints against post-update row.
 	if err := c.validateCheckConstraints(newMetadata); err != nil {
@@ -1686,16 +1839,23 @@ func (c *Collection) Update(ctx context.Context, id string, vector []float32, me
 	if err != nil {
 		return err
 	}
+	defer c.beginFullTextWrite()()
 	defer func() {
 		if err == nil {
 			if oldMetadata != nil {
 				c.removeFromMetadataIndex(id, oldMetadata)
 			}
 			c.addToMetadataIndex(id, newMetadata)
+			c.noteFullTextWrite(id, newMetadata)
+			c.noteVectorSpaceWrite(id, newMetadata)
+			c.noteSparseWrite(id, newMetadata)
 			c.markMetadataIndexDirty()
 			for _, op := range updateCascades {
 				c.executeCascadeMutation(ctx, op)
 			}
+			if regenerate {
+				c.asyncIndex.regenerate(id)
+			}
 		}
 	}()
 
@@ -1809,11 +1969,18 @@ func (c *Collection) updateNonSharded(ctx context.Context, id string, vector []f
 
 // Upsert writes a record regardless of whether it exists, replacing if it does.
 func (c *Collection) Upsert(ctx context.Context, id string, vector []float32, metadata map[string]interface{}) (err error) {
+	defer c.beginFullTextWrite()()
 	defer func() {
 		if err == nil {
+			c.noteFullTextWrite(id, metadata)
+			c.noteVectorSpaceWrite(id, metadata)
+			c.noteSparseWrite(id, metadata)
 			c.markMetadataIndexDirty()
 		}
 	}()
+	if vector, err = c.generateVector(ctx, vector, metadata); err != nil {
+		return err
+	}
 	if len(vector) != c.config.Dimension {
 		return fmt.Errorf("vector dimension %d does not match collection dimension %d",
 			len(vector), c.config.Dimension)
@@ -2070,9 +2237,13 @@ func (c *Collection) Delete(ctx context.Context, id string) (err error) {
 
 	var oldMetadata map[string]interface{}
 	var cascadeDeletes []cascadeOp
+	defer c.beginFullTextWrite()()
 	defer func() {
 		if err == nil {
 			c.removeFromMetadataIndex(id, oldMetadata)
+			c.noteFullTextDelete(id)
+			c.noteVectorSpaceDelete(id)
+			c.noteSparseDelete(id)
 			c.markMetadataIndexDirty()
 			// Execute cascading deletes after the parent is removed.
 			for _, op := range cascadeDeletes {
@@ -2281,6 +2452,10 @@ func isNotFoundError(err error) bool {
 
 // InsertBatch inserts multiple vectors using the public collection API.
 func (c *Collection) InsertBatch(ctx context.Context, entries []VectorEntry) error {
+	// Preflight: embed omitted generated vectors in one batch.
+	if err := c.generateVectors(ctx, entries); err != nil {
+		return err
+	}
 	// Preflight: apply DEFAULTs, then validate NOT NULL, CHECK, FK, and UNIQUE.
 	for i := range entries {
 		entries[i].Metadata = c.metadataWithDefaults(entries[i].Metadata)
@@ -2323,7 +2498,8 @@ func (c *Collection) DeleteBatch(ctx context.Context, ids []string) error {
 	return nil
 }
 
-// Get returns a persisted record by ID.
+// Get returns a persisted record by ID. A record past its TTL is reported
+// as not found even before the reaper deletes it.
 func (c *Collection) Get(ctx context.Context, id string) (Record, error) {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -2336,14 +2512,14 @@ func (c *Collection) Get(ctx context.Context, id string) (Record, error) {
 	if c.shards != nil {
 		shard := c.getShard(id)
 		entry, err := shard.storage.Get(ctx, id)
-		if err != nil {
+		if err != nil || c.recordExpired(ctx, entry.Metadata, time.Now()) {
 			return Record{}, fmt.Errorf("%w: %s", ErrRecordNotFound, id)
 		}
 		return recordFromIndexEntry(entry), nil
 	}
 
 	entry, err := c.storage.Get(ctx, id)
-	if err != nil {
+	if err != nil || c.recordExpired(ctx, entry.Metadata, time.Now()) {
 		return Record{}, fmt.Errorf("%w: %s", ErrRecordNotFound, id)
 	}
 	return recordFromIndexEntry(entry), nil
@@ -2373,7 +2549,8 @@ func (c *Collection) withCAS(ctx context.Context, fn func(tx Tx) error) error {
 	return c.db.WithTx(ctx, fn)
 }
 
-// Iterate walks all persisted records in the collection.
+// Iterate walks all persisted records in the collection, skipping records
+// past their TTL.
 func (c *Collection) Iterate(ctx context.Context, fn func(Record) error) error {
 	if fn == nil {
 		return fmt.Errorf("iterate callback cannot be nil")
@@ -2381,27 +2558,34 @@ func (c *Collection) Iterate(ctx context.Context, fn func(Record) error) error {
 
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	return c.iterateLocked(ctx, fn)
+}
 
+// iterateLocked is Iterate for callers that already hold c.mu.
+func (c *Collection) iterateLocked(ctx context.Context, fn func(Record) error) error {
 	if c.closed {
 		return ErrCollectionClosed
 	}
 
+	now := time.Now()
+	visit := func(entry *index.VectorEntry) error {
+		if c.recordExpired(ctx, entry.Metadata, now) {
+			return nil
+		}
+		return fn(recordFromIndexEntry(entry))
+	}
+
 	// Sharded path: iterate over all shards
 	if c.shards != nil {
 		for i := range c.shards {
-			err := c.shards[i].storage.Iterate(ctx, func(entry *index.VectorEntry) error {
-				return fn(recordFromIndexEntry(entry))
-			})
-			if err != nil {
+			if err := c.shards[i].storage.Iterate(ctx, visit); err != nil {
 				return err
 			}
 		}
 		return nil
 	}
 
-	return c.storage.Iterate(ctx, func(entry *index.VectorEntry) error {
-		return fn(recordFromIndexEntry(entry))
-	})
+	return c.storage.Iterate(ctx, visit)
 }
 
 // ListAll returns all persisted records in the collection.
@@ -2456,6 +2640,7 @@ func (c *Collection) applyDefaults(metadata map[string]interface{}) {
 		}
 		metadata[colName] = parseDefaultLiteral(defaultVal)
 	}
+	c.applyDefaultTTL(metadata)
 }
 
 // metadataWithDefaults returns metadata prepared for a write. It preserves
@@ -2547,6 +2732,12 @@ func (c *Collection) validateNotNullConstraints(metadata map[string]interface{})
 	if err := c.validateJSONFields(metadata); err != nil {
 		return err
 	}
+	if err := c.validateSparseVectorFields(metadata); err != nil {
+		return err
+	}
+	if err := c.validateVectorSpaceFields(metadata); err != nil {
+		return err
+	}
 	return nil
 }
 
@@ -3935,6 +4126,30 @@ func (c *Collection) hasIndexedMetadataField(field string) bool {
 // lazily after a mutation, so steady-state filtered queries do not scan every
 // vector or copy every vector payload just to construct an ordinal bitmap.
 func (c *Collection) lookupIndexedMetadata(ctx context.Context, field string, value interface{}) ([]Record, bool, error) {
+	ids, indexed, err := c.lookupIndexedMetadataIDs(ctx, field, value)
+	if !indexed || err != nil {
+		return nil, indexed, err
+	}
+	records := make([]Record, 0, len(ids))
+	for _, id := range ids {
+		record, err := c.Get(ctx, id)
+		if err != nil {
+			// A concurrent delete can invalidate a posting after the snapshot.
+			// Skipping it preserves correctness; the mutation epoch forces the
+			// next lookup to rebuild the posting lists.
+			if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			return nil, true, err
+		}
+		records = append(records, record)
+	}
+	return records, true, nil
+}
+
+// lookupIndexedMetadataIDs returns the posting list of field = value without
+// loading the records. indexed is false when field is not an indexed field.
+func (c *Collection) lookupIndexedMetadataIDs(ctx context.Context, field string, value interface{}) ([]string, bool, error) {
 	if !c.hasIndexedMetadataField(field) {
 		return nil, false, nil
 	}
@@ -3962,21 +4177,7 @@ func (c *Collection) lookupIndexedMetadata(ctx context.Context, field string, va
 	c.metadataIndexMu.Unlock()
 
 	c.metadataLookupCandidates.Add(uint64(len(ids)))
-	records := make([]Record, 0, len(ids))
-	for _, id := range ids {
-		record, err := c.Get(ctx, id)
-		if err != nil {
-			// A concurrent delete can invalidate a posting after the snapshot.
-			// Skipping it preserves correctness; the mutation epoch forces the
-			// next lookup to rebuild the posting lists.
-			if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
-				continue
-			}
-			return nil, true, err
-		}
-		records = append(records, record)
-	}
-	return records, true, nil
+	return ids, true, nil
 }
 
 func (c *Collection) rebuildMetadataIndexLocked(ctx context.Context, epoch uint64) error {
@@ -4350,6 +4551,7 @@ func (c *Collection) searchWithGraphFilterAndEf(ctx context.Context, vector []fl
 		}
 	}
 
+	publicResults = c.liveSearchResults(ctx, publicResults)
 	normalizePublicSearchResults(c.config.Metric, publicResults)
 	publicResults = selectTopKSearchResults(publicResults, k)
 
@@ -4514,6 +4716,22 @@ func (c *Collection) Stats(ctx context.Context) *CollectionStats {
 		}
 	}
 
+	if c.shards == nil {
+		if reporter, ok := c.index.(interface {
+			Int8ConstructionStats() (hnsw.Int8ConstructionStats, bool)
+		}); ok {
+			if int8Stats, enabled := reporter.Int8ConstructionStats(); enabled {
+				stats.Int8ConstructionStats = &Int8ConstructionStats{
+					Trained:      int8Stats.Trained,
+					Checks:       int8Stats.Checks,
+					Pruned:       int8Stats.Pruned,
+					Fallbacks:    int8Stats.Fallbacks,
+					FallbackRate: int8Stats.FallbackRate,
+				}
+			}
+		}
+	}
+
 	// Add optimization status
 	stats.OptimizationStatus = &OptimizationStatus{
 		InProgress:       c.optimizationInProgress,
@@ -4943,6 +5161,9 @@ func (c *Collection) Close() error {
 		}
 	}
 
+	// Vector space graphs are derived and rebuilt on open; release them.
+	c.resetVectorSpaces()
+
 	// Close shards if sharded collection
 	if c.shards != nil {
 		for i := range c.shards {
@@ -5259,6 +5480,10 @@ func (config *CollectionConfig) validate() error {
 		return fmt.Errorf("EfSearch must be positive, got %d", config.EfSearch)
 	}
 
+	if err := config.resolveVectorSpaces(); err != nil {
+		return err
+	}
+
 	switch config.RawVectorStore {
 	case "", "memory", "slabby":
 	default:
@@ -5274,6 +5499,20 @@ func (config *CollectionConfig) validate() error {
 			return fmt.Errorf("invalid quantization config: %w", err)
 		}
 	}
+	if config.RescoreK < 0 {
+		return fmt.Errorf("rescore k must be non-negative, got %d", config.RescoreK)
+	}
+	if config.Int8Construction {
+		if config.IndexType != HNSW {
+			return fmt.Errorf("int8 construction requires an HNSW index, got %v", config.IndexType)
+		}
+		if config.Quantization != nil {
+			return fmt.Errorf("int8 construction cannot be combined with quantization")
+		}
+		if config.Metric != L2Distance {
+			return fmt.Errorf("int8 construction requires the L2 metric")
+		}
+	}
 
 	// Validate memory configuration
 	if config.MemoryLimit < 0 {
@@ -463,6 +463,90 @@ func TestWithFSQQuantization(t *testing.T) {
 	}
 }
 
+func TestWithBinaryQuantization(t *testing.T) {
+	config := &CollectionConfig{
+		Dimension:      128,
+		Metric:         L2Distance,
+		IndexType:      HNSW,
+		M:              16,
+		EfConstruction: 100,
+		EfSearch:       64,
+		BatchConfig:    DefaultBatchConfig(),
+	}
+
+	if err := WithBinaryQuantization(quant.BinaryThresholdSign, true, 200)(config); err != nil {
+		t.Fatalf("WithBinaryQuantization: %v", err)
+	}
+	if config.Quantization == nil {
+		t.Fatal("expected quantization config")
+	}
+	if got := config.Quantization.Type.String(); got != "binary" {
+		t.Fatalf("quantization type got %s want binary", got)
+	}
+	if config.Quantization.Threshold != quant.BinaryThresholdSign || !config.Quantization.Rotation {
+		t.Fatalf("quantization config got %+v", config.Quantization)
+	}
+	if config.RescoreK != 200 {
+		t.Fatalf("rescore k got %d want 200", config.RescoreK)
+	}
+	if err := config.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	if err := WithBinaryQuantization("median", false, 10)(config); err == nil {
+		t.Fatal("expected unknown threshold to be rejected")
+	}
+	if err := WithBinaryQuantization(quant.BinaryThresholdMean, false, -1)(config); err == nil {
+		t.Fatal("expected negative rescore k to be rejected")
+	}
+}
+
+func TestWithHNSWInt8Construction(t *testing.T) {
+	newConfig := func() *CollectionConfig {
+		return &CollectionConfig{
+			Dimension:      64,
+			Metric:         L2Distance,
+			IndexType:      HNSW,
+			M:              16,
+			EfConstruction: 100,
+			EfSearch:       64,
+			BatchConfig:    DefaultBatchConfig(),
+		}
+	}
+
+	config := newConfig()
+	if err := WithHNSWInt8Construction()(config); err != nil {
+		t.Fatalf("WithHNSWInt8Construction: %v", err)
+	}
+	if !config.Int8Construction {
+		t.Fatal("expected int8 construction to be enabled")
+	}
+	if err := config.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	cosine := newConfig()
+	cosine.Metric = CosineDistance
+	cosine.Int8Construction = true
+	if err := cosine.validate(); err == nil {
+		t.Fatal("expected cosine metric to be rejected")
+	}
+
+	quantized := newConfig()
+	quantized.Int8Construction = true
+	quantized.Quantization = quant.DefaultConfig(quant.ScalarQuantization)
+	if err := quantized.validate(); err == nil {
+		t.Fatal("expected quantization to be rejected")
+	}
+
+	flat := newConfig()
+	flat.IndexType = Flat
+	flat.Int8Construction = true
+	if err := flat.validate(); err == nil {
+		t.Fatal("expected non-HNSW index to be rejected")
+	}
+}
+
 func TestBackwardCompatibility(t *testing.T) {
 	// Test that existing configurations still work without new fields
 	config := &CollectionConfig{
@@ -0,0 +1,423 @@
+package libravdb
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/xDarkicex/lexer"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
+)
+
+// Provisional selectivities used when a relation has no analyzed statistics
+// snapshot, or the snapshot does not cover the referenced field. They match
+// the assumptions hybridCardinalityEstimate reports for the same case.
+const (
+	estimatorEqualitySelectivity = 0.10
+	estimatorOtherSelectivity    = 0.50
+	estimatorFullTextSelectivity = 0.10
+	estimatorGraphBranching      = 5.0
+	estimatorDefaultDistinct     = 200
+	estimatorMaxGraphHops        = 10
+	// estimatorMinPatternSamples is how many executions of a graph pattern
+	// must have been observed before their mean fanout replaces the
+	// branching-factor model.
+	estimatorMinPatternSamples = 3
+)
+
+const (
+	estimateSourceStatistics = "analyzed_collection_statistics"
+	estimateSourceHeuristic  = "provisional_heuristic"
+)
+
+// cardinalityEstimator derives operator row estimates from the validated
+// CostModelStatistics snapshot of each relation a plan touches. It never
+// scans records: relations without a fresh snapshot fall back to the live row
+// count and the provisional selectivities above, and the estimate source
+// records that the fallback was used.
+type cardinalityEstimator struct {
+	ctx       context.Context
+	db        *Database
+	relations map[string]*relationEstimate
+	heuristic bool
+}
+
+// relationEstimate is the per-collection view the estimator works from.
+// stats is nil when the collection has never been analyzed or has been
+// written since.
+type relationEstimate struct {
+	name  string
+	col   *Collection
+	rows  float64
+	stats *CostModelStatistics
+}
+
+func newCardinalityEstimator(ctx context.Context, db *Database) *cardinalityEstimator {
+	return &cardinalityEstimator{ctx: ctx, db: db, relations: make(map[string]*relationEstimate)}
+}
+
+// source reports whether every relation consulted so far had analyzed
+// statistics.
+func (e *cardinalityEstimator) source() string {
+	if e.heuristic || len(e.relations) == 0 {
+		return estimateSourceHeuristic
+	}
+	return estimateSourceStatistics
+}
+
+func (e *cardinalityEstimator) relation(name string) *relationEstimate {
+	key := strings.ToLower(name)
+	if r, ok := e.relations[key]; ok {
+		return r
+	}
+	r := &relationEstimate{name: name}
+	if col, err := e.db.GetCollection(name); err == nil {
+		r.col = col
+		if stats, ok := col.costModel.snapshot(); ok {
+			r.stats = stats
+			r.rows = float64(stats.RowCount)
+		} else if count, err := col.Count(e.ctx); err == nil {
+			r.rows = float64(count)
+		}
+	}
+	if r.stats == nil {
+		e.heuristic = true
+	}
+	e.relations[key] = r
+	return r
+}
+
+// conjunctionSelectivity assumes the predicates are independent.
+func (r *relationEstimate) conjunctionSelectivity(predicates []optimizer.RelationalPredicate) float64 {
+	selectivity := 1.0
+	for _, predicate := range predicates {
+		selectivity *= r.predicateSelectivity(predicate)
+	}
+	return selectivity
+}
+
+// alternativesSelectivity combines OR-ed clauses as independent events.
+func (r *relationEstimate) alternativesSelectivity(alternatives optimizer.PredicateAlternatives) float64 {
+	miss := 1.0
+	for _, clause := range alternatives {
+		miss *= 1 - r.conjunctionSelectivity(clause)
+	}
+	return 1 - miss
+}
+
+func (r *relationEstimate) planSelectivity(plan *optimizer.PhysicalPlan) float64 {
+	if len(plan.PredicateAlternatives) > 0 {
+		return r.alternativesSelectivity(plan.PredicateAlternatives)
+	}
+	return r.conjunctionSelectivity(plan.Predicates)
+}
+
+func (r *relationEstimate) fieldStats(column string) (CostModelFieldStats, bool) {
+	if r.stats == nil {
+		return CostModelFieldStats{}, false
+	}
+	if stats, ok := r.stats.Fields[column]; ok {
+		return stats, true
+	}
+	for name, stats := range r.stats.Fields {
+		if strings.EqualFold(name, column) {
+			return stats, true
+		}
+	}
+	return CostModelFieldStats{}, false
+}
+
+// nonNullFraction is the share of rows holding a non-NULL value. Analyzed
+// statistics only observe fields a record carries, so a field absent from
+// the snapshot is NULL everywhere.
+func (r *relationEstimate) nonNullFraction(column string) float64 {
+	if strings.EqualFold(column, "id") {
+		return 1
+	}
+	if r.stats == nil {
+		return 1
+	}
+	if r.rows <= 0 {
+		return 0
+	}
+	fs, ok := r.fieldStats(column)
+	if !ok {
+		return 0
+	}
+	return clampFraction(float64(fs.Count-fs.NullCount) / r.rows)
+}
+
+func (r *relationEstimate) predicateSelectivity(p optimizer.RelationalPredicate) float64 {
+	nonNull := r.nonNullFraction(p.Column)
+	switch p.NullTest {
+	case optimizer.NullTestIsNull:
+		return 1 - nonNull
+	case optimizer.NullTestNotNull:
+		return nonNull
+	}
+	if p.ValueIsNull {
+		return 0
+	}
+	var selectivity float64
+	switch {
+	case p.InList || len(p.InValues) > 0:
+		for _, value := range p.InValues {
+			if !value.IsNull() {
+				selectivity += r.equalitySelectivity(p.Column, value)
+			}
+		}
+		selectivity = math.Min(selectivity, nonNull)
+	case p.Like || p.ILike:
+		pattern := p.PredicateValue()
+		if !strings.ContainsAny(string(pattern.BytesData), "%_") && !p.ILike {
+			selectivity = r.equalitySelectivity(p.Column, pattern)
+		} else {
+			selectivity = estimatorEqualitySelectivity * nonNull
+		}
+	case p.Operator == uint8(lexer.KindEquals):
+		selectivity = r.equalitySelectivity(p.Column, p.PredicateValue())
+	case p.Operator == uint8(lexer.KindNotEqual):
+		selectivity = nonNull - r.equalitySelectivity(p.Column, p.PredicateValue())
+	case p.Operator == uint8(lexer.KindLessThan), p.Operator == uint8(lexer.KindLessEqual):
+		selectivity = r.rangeSelectivity(p.Column, p.PredicateValue(), true)
+	case p.Operator == uint8(lexer.KindGreaterThan), p.Operator == uint8(lexer.KindGreaterEqual):
+		selectivity = r.rangeSelectivity(p.Column, p.PredicateValue(), false)
+	default:
+		selectivity = estimatorOtherSelectivity * nonNull
+	}
+	if p.Not {
+		selectivity = nonNull - selectivity
+	}
+	return clampFraction(selectivity)
+}
+
+// equalitySelectivity prefers the exact heavy-hitter frequency, then spreads
+// the remaining non-NULL rows uniformly over the remaining distinct values.
+func (r *relationEstimate) equalitySelectivity(column string, value optimizer.ScalarValue) float64 {
+	if strings.EqualFold(column, "id") {
+		if r.rows <= 0 {
+			return 0
+		}
+		return 1 / r.rows
+	}
+	fs, ok := r.fieldStats(column)
+	if !ok || r.rows <= 0 {
+		if r.stats != nil {
+			return 0
+		}
+		return estimatorEqualitySelectivity
+	}
+	for _, key := range costModelValueKeys(value) {
+		if count, ok := fs.TopValues[key]; ok {
+			return clampFraction(float64(count) / r.rows)
+		}
+	}
+	var topRows uint64
+	for _, count := range fs.TopValues {
+		topRows += count
+	}
+	nonNull := fs.Count - fs.NullCount
+	remainingDistinct := int64(fs.Distinct) - int64(len(fs.TopValues))
+	if remainingDistinct <= 0 || topRows >= nonNull {
+		// The heavy-hitter summary tracked every value it saw, so a value
+		// missing from it does not occur.
+		return 0
+	}
+	return clampFraction(float64(nonNull-topRows) / float64(remainingDistinct) / r.rows)
+}
+
+// rangeSelectivity interpolates linearly inside the histogram bucket holding
+// the bound. below selects values under the bound; otherwise values above.
+func (r *relationEstimate) rangeSelectivity(column string, value optimizer.ScalarValue, below bool) float64 {
+	if strings.EqualFold(column, "id") {
+		return estimatorOtherSelectivity
+	}
+	fs, ok := r.fieldStats(column)
+	bound, numeric := scalarNumericValue(value)
+	if !ok || !numeric || len(fs.Histogram) == 0 || r.rows <= 0 {
+		if r.stats != nil && !ok {
+			return 0
+		}
+		return estimatorOtherSelectivity * r.nonNullFraction(column)
+	}
+	var total, under float64
+	for _, bucket := range fs.Histogram {
+		count := float64(bucket.Count)
+		total += count
+		switch {
+		case bound >= bucket.Upper:
+			under += count
+		case bound <= bucket.Lower:
+		default:
+			under += count * (bound - bucket.Lower) / (bucket.Upper - bucket.Lower)
+		}
+	}
+	if below {
+		return clampFraction(under / r.rows)
+	}
+	return clampFraction((total - under) / r.rows)
+}
+
+// distinct estimates the number of distinct non-NULL values of column.
+func (r *relationEstimate) distinct(column string) float64 {
+	if strings.EqualFold(column, "id") {
+		return math.Max(r.rows, 1)
+	}
+	if fs, ok := r.fieldStats(column); ok && fs.Distinct > 0 {
+		return math.Max(1, math.Min(float64(fs.Distinct), r.rows))
+	}
+	if r.stats != nil {
+		return 1
+	}
+	return math.Max(1, math.Min(estimatorDefaultDistinct, r.rows))
+}
+
+// fullTextSelectivity reads document frequencies from a built full-text
+// index over the predicate's column. EXPLAIN never builds an index, so an
+// index that has not been used yet contributes the provisional selectivity.
+func (r *relationEstimate) fullTextSelectivity(predicate optimizer.FTSPredicate) float64 {
+	if r.col != nil && predicate.Column != "" && r.rows > 0 {
+		if index := r.col.fullTextIndexFor(predicate.Column, predicate.Config); index != nil {
+			root := parseFTSQueryConfig(predicate.Query, predicate.QueryMode, index.definition.Config)
+			if matches, ok := index.estimateMatches(root); ok {
+				return clampFraction(matches / r.rows)
+			}
+		}
+	}
+	return estimatorFullTextSelectivity
+}
+
+func (r *relationEstimate) fullTextConjunctionSelectivity(predicates []optimizer.FTSPredicate) float64 {
+	selectivity := 1.0
+	for _, predicate := range predicates {
+		selectivity *= r.fullTextSelectivity(predicate)
+	}
+	return selectivity
+}
+
+// branching is the average number of edges leaving a vertex. Analyzed
+// statistics win; otherwise the graph's live edge counters are used.
+func (r *relationEstimate) branching() float64 {
+	if r.stats != nil && r.stats.Graph.VertexCount > 0 {
+		return r.stats.Graph.AverageBranching
+	}
+	if r.col != nil && r.rows > 0 {
+		if g := r.col.GetGraph(); g != nil {
+			stats := g.Stats()
+			return float64(stats.EdgesAdded-stats.EdgesRemoved) / r.rows
+		}
+	}
+	return estimatorGraphBranching
+}
+
+// labelFraction is the share of vertices carrying every label. Labels are
+// assumed independent; a label the snapshot never observed matches nothing.
+func (r *relationEstimate) labelFraction(labels []string) float64 {
+	fraction := 1.0
+	for _, label := range labels {
+		if r.stats == nil || r.stats.Graph.VertexCount == 0 {
+			fraction *= estimatorEqualitySelectivity
+			continue
+		}
+		fraction *= clampFraction(float64(r.stats.Graph.LabelCounts[label]) / float64(r.stats.Graph.VertexCount))
+	}
+	return fraction
+}
+
+// patternFanout returns the mean number of vertices one seed reached over
+// the recorded executions of plan's graph pattern.
+func (r *relationEstimate) patternFanout(plan *optimizer.PhysicalPlan) (float64, bool) {
+	if r.stats == nil {
+		return 0, false
+	}
+	sample, ok := r.stats.Graph.PatternSamples[costModelGraphPatternKey(plan)]
+	if !ok || sample.Observations < estimatorMinPatternSamples || sample.Seeds == 0 {
+		return 0, false
+	}
+	return float64(sample.Vertices) / float64(sample.Seeds), true
+}
+
+// graphFanout estimates how many vertices one seed reaches through edges,
+// summing every admissible hop count of each band. The result is bounded by
+// the relation, since a traversal reports each vertex once per seed.
+func (r *relationEstimate) graphFanout(edges []optimizer.GraphEdgePlan) float64 {
+	if len(edges) == 0 {
+		return 1
+	}
+	branching := r.branching()
+	fanout := 1.0
+	for _, edge := range edges {
+		degree := branching
+		if edge.Direction == 0 {
+			degree *= 2
+		}
+		minHops, maxHops := int(edge.QuantMin), int(edge.QuantMax)
+		if maxHops == 0 {
+			if minHops == 0 {
+				minHops, maxHops = 1, 1
+			} else {
+				maxHops = estimatorMaxGraphHops
+			}
+		}
+		maxHops = min(maxHops, estimatorMaxGraphHops)
+		reach := 0.0
+		for hops := minHops; hops <= maxHops; hops++ {
+			reach += math.Pow(degree, float64(hops))
+		}
+		fanout *= reach
+	}
+	if r.rows > 0 {
+		fanout = math.Min(fanout, r.rows)
+	}
+	return fanout
+}
+
+// equiJoinRows applies the textbook containment assumption: every key of the
+// side with fewer distinct values finds a partner on the other side.
+func equiJoinRows(leftRows, rightRows, leftDistinct, rightDistinct float64) float64 {
+	return leftRows * rightRows / math.Max(1, math.Max(leftDistinct, rightDistinct))
+}
+
+// costModelValueKeys returns the heavy-hitter keys a predicate value may
+// have been recorded under. Numeric columns are keyed by their stored Go
+// type, which the predicate literal does not reveal.
+func costModelValueKeys(value optimizer.ScalarValue) []string {
+	switch value.Kind {
+	case optimizer.ScalarInt:
+		return []string{costModelMetadataKey(value.Int), costModelMetadataKey(float64(value.Int))}
+	case optimizer.ScalarFloat:
+		keys := []string{costModelMetadataKey(value.Float)}
+		if value.Float == math.Trunc(value.Float) {
+			keys = append(keys, costModelMetadataKey(int64(value.Float)))
+		}
+		return keys
+	case optimizer.ScalarBool:
+		return []string{costModelMetadataKey(value.Bool)}
+	default:
+		return []string{costModelMetadataKey(string(value.Bytes()))}
+	}
+}
+
+func scalarNumericValue(value optimizer.ScalarValue) (float64, bool) {
+	switch value.Kind {
+	case optimizer.ScalarInt:
+		return float64(value.Int), true
+	case optimizer.ScalarFloat:
+		return value.Float, true
+	case optimizer.ScalarString, optimizer.ScalarBytes:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(string(value.BytesData)), 64)
+		return parsed, err == nil
+	}
+	return 0, false
+}
+
+func clampFraction(value float64) float64 {
+	if math.IsNaN(value) || value < 0 {
+		return 0
+	}
+	if value > 1 {
+		return 1
+	}
+	return value
+}
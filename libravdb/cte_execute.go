@@ -531,6 +531,9 @@ func selectHasDerivedRelation(doc *parser.QueryDoc, stmt *parser.SelectStmt) boo
 	return false
 }
 
+// executeGenericCTE runs a SELECT on the query-local evaluator after binding
+// its CTEs. A query that reads a plain view takes the same route with or
+// without a WITH clause; virtualSourceRows resolves the view.
 func (db *Database) executeGenericCTE(ctx context.Context, src []byte, doc *parser.QueryDoc, params *optimizer.ParameterSet, legacy QueryParams, config *SessionConfig) (*SearchResults, error) {
 	ctx, jsonRuntime := withSQLJSONRuntime(ctx)
 	defer jsonRuntime.Free()
@@ -539,39 +542,53 @@ func (db *Database) executeGenericCTE(ctx context.Context, src []byte, doc *pars
 		return nil, fmt.Errorf("generic CTE has no outer SELECT")
 	}
 	outer := &doc.SelectStmts[root]
-	if outer.CTEsCount <= 0 || outer.CTEsStart < 0 || outer.CTEsStart+outer.CTEsCount > int32(len(doc.CTEs)) {
-		return nil, fmt.Errorf("invalid CTE range")
+	cteCtx := withViewScope(withVirtualCTEs(ctx, make(virtualCTEEnv, outer.CTEsCount), config))
+	if err := db.evaluateVirtualCTEs(cteCtx, src, doc, outer, params, legacy); err != nil {
+		return nil, err
 	}
-	env := make(virtualCTEEnv, outer.CTEsCount)
-	cteCtx := withVirtualCTEs(ctx, env, config)
-	for i := int32(0); i < outer.CTEsCount; i++ {
-		cte := &doc.CTEs[outer.CTEsStart+i]
+	rows, columns, err := db.evaluateVirtualSelectRows(cteCtx, src, doc, outer, nil, params, legacy)
+	if err != nil {
+		return nil, err
+	}
+	return finishVirtualRows(db, doc, src, outer, rows, columns, params), nil
+}
+
+// evaluateVirtualCTEs evaluates the WITH list of stmt, in order, into the
+// CTE environment of ctx.
+func (db *Database) evaluateVirtualCTEs(ctx context.Context, src []byte, doc *parser.QueryDoc, stmt *parser.SelectStmt, params *optimizer.ParameterSet, legacy QueryParams) error {
+	if stmt.CTEsCount == 0 {
+		return nil
+	}
+	if stmt.CTEsCount < 0 || stmt.CTEsStart < 0 || stmt.CTEsStart+stmt.CTEsCount > int32(len(doc.CTEs)) {
+		return fmt.Errorf("invalid CTE range")
+	}
+	env := virtualCTEsFromContext(ctx)
+	for i := int32(0); i < stmt.CTEsCount; i++ {
+		cte := &doc.CTEs[stmt.CTEsStart+i]
 		name := sourceSpan(src, cte.NameStart, cte.NameEnd)
 		if cte.Body.Kind != parser.NodeKindSelectStmt || cte.Body.ID < 0 || int(cte.Body.ID) >= len(doc.SelectStmts) {
-			return nil, fmt.Errorf("CTE %q is not a relational SELECT", name)
+			return fmt.Errorf("CTE %q is not a relational SELECT", name)
 		}
 		bodyStmt := &doc.SelectStmts[cte.Body.ID]
 		var rows []virtualSQLRow
 		var err error
-		if cte.Recursive {
-			rows, err = db.evaluateRecursiveCTE(cteCtx, src, doc, bodyStmt, virtualCTEName(name), params, legacy)
+		// WITH RECURSIVE applies to the whole list; as in PostgreSQL, a CTE
+		// whose body is not a UNION is evaluated as an ordinary one.
+		if cte.Recursive && bodyStmt.SetOp == parser.SetOpUnion {
+			rows, err = db.evaluateRecursiveCTE(ctx, src, doc, bodyStmt, virtualCTEName(name), params, legacy)
 		} else {
 			var columns []string
-			rows, columns, err = db.evaluateVirtualSelectRows(cteCtx, src, doc, bodyStmt, nil, params, legacy)
+			rows, columns, err = db.evaluateVirtualSelectRows(ctx, src, doc, bodyStmt, nil, params, legacy)
 			if err == nil {
-				rows, err = db.applyVirtualSelectClauses(cteCtx, src, doc, bodyStmt, rows, columns, params, legacy)
+				rows, err = db.applyVirtualSelectClauses(ctx, src, doc, bodyStmt, rows, columns, params, legacy)
 			}
 		}
 		if err != nil {
-			return nil, fmt.Errorf("execute CTE %q: %w", name, err)
+			return fmt.Errorf("execute CTE %q: %w", name, err)
 		}
 		env[virtualCTEName(name)] = rows
 	}
-	rows, columns, err := db.evaluateVirtualSelectRows(cteCtx, src, doc, outer, nil, params, legacy)
-	if err != nil {
-		return nil, err
-	}
-	return finishVirtualRows(db, doc, src, outer, rows, columns, params), nil
+	return nil
 }
 
 const maxRecursiveCTEIterations = 10000
@@ -1013,22 +1030,19 @@ func (db *Database) virtualSourceRows(ctx context.Context, src []byte, doc *pars
 	table := sourceSpan(src, t.Start, t.End)
 	if env := virtualCTEsFromContext(ctx); env != nil {
 		if cteRows, ok := env[virtualCTEName(table)]; ok {
-			alias := sourceSpan(src, t.Alias, t.AliasEnd)
-			if alias == "" {
-				alias = table
-			}
-			rows := make([]virtualSQLRow, 0, len(cteRows))
-			for _, cteRow := range cteRows {
-				row := virtualSQLRow{ID: cteRow.ID, Values: cloneVisibleVirtualValues(cteRow)}
-				qualifyVirtualRow(&row, alias)
-				if outer != nil {
-					row = overlayVirtualRow(*outer, row)
-				}
-				rows = append(rows, row)
-			}
-			return rows, nil
+			return virtualRelationRows(src, t, table, cteRows, outer), nil
 		}
 	}
+	if view, ok := db.lookupView(table); ok && !view.Materialized {
+		if t.Temporal || t.TemporalLSN {
+			return nil, fmt.Errorf("view %q cannot be read AS OF a snapshot", view.Name)
+		}
+		viewRows, err := db.viewRows(ctx, view)
+		if err != nil {
+			return nil, err
+		}
+		return virtualRelationRows(src, t, view.Name, viewRows, outer), nil
+	}
 	col, err := db.GetCollection(table)
 	if err != nil {
 		return nil, err
@@ -1068,6 +1082,25 @@ func (db *Database) virtualSourceRows(ctx context.Context, src []byte, doc *pars
 	return rows, nil
 }
 
+// virtualRelationRows binds the rows of a CTE or view to the relation t,
+// qualified by its alias or, without one, by name.
+func virtualRelationRows(src []byte, t *parser.TableExpr, name string, relation []virtualSQLRow, outer *virtualSQLRow) []virtualSQLRow {
+	alias := sourceSpan(src, t.Alias, t.AliasEnd)
+	if alias == "" {
+		alias = name
+	}
+	rows := make([]virtualSQLRow, 0, len(relation))
+	for _, relationRow := range relation {
+		row := virtualSQLRow{ID: relationRow.ID, Values: cloneVisibleVirtualValues(relationRow)}
+		qualifyVirtualRow(&row, alias)
+		if outer != nil {
+			row = overlayVirtualRow(*outer, row)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
 // virtualTemporalSourceRows materializes a table source at its AS OF
 // TIMESTAMP or AS OF LSN snapshot. This is intentionally query-local so a temporal source
 // inside a CTE can be bounded before its rows feed an outer aggregate.
@@ -1418,7 +1451,7 @@ func (db *Database) projectVirtualRows(ctx context.Context, src []byte, doc *par
 		return rows, nil, nil
 	}
 	if virtualSelectHasWindow(doc, stmt) {
-		if virtualSelectHasAggregate(doc, stmt) || virtualSelectHasCollectionAggregate(src, doc, stmt) {
+		if virtualSelectHasAggregate(doc, stmt) || virtualSelectHasCollectionAggregate(src, doc, stmt) || db.virtualSelectHasUserAggregate(src, doc, stmt) {
 			var projected []virtualSQLRow
 			var err error
 			if len(stmt.GroupBy) > 0 {
@@ -1433,7 +1466,7 @@ func (db *Database) projectVirtualRows(ctx context.Context, src []byte, doc *par
 		}
 		return db.projectVirtualWindowRows(ctx, src, doc, stmt, rows, params, legacy)
 	}
-	if virtualSelectHasAggregate(doc, stmt) || virtualSelectHasCollectionAggregate(src, doc, stmt) {
+	if virtualSelectHasAggregate(doc, stmt) || virtualSelectHasCollectionAggregate(src, doc, stmt) || db.virtualSelectHasUserAggregate(src, doc, stmt) {
 		if len(stmt.GroupBy) > 0 {
 			return db.projectVirtualGroupedAggregateRows(ctx, src, doc, stmt, rows, params, legacy)
 		}
@@ -2015,7 +2048,7 @@ func (db *Database) projectVirtualAggregateRows(ctx context.Context, src []byte,
 				values[name] = nil
 			}
 		case parser.NodeKindFunctionExpr:
-			if !virtualFunctionNameIsCollectionAggregate(src, doc, projection.Expr) {
+			if !db.virtualFunctionIsAggregate(src, doc, projection.Expr) {
 				return nil, nil, fmt.Errorf("aggregate virtual SELECT supports aggregate and window projections only")
 			}
 			name, err := virtualProjectionName(src, doc, projection)
@@ -2110,7 +2143,7 @@ func (db *Database) projectVirtualGroupedAggregateRows(ctx context.Context, src
 					havingRow.Values[sourceSpan(src, projection.Alias, projection.AliasEnd)] = value
 				}
 			case parser.NodeKindFunctionExpr:
-				if !virtualFunctionNameIsCollectionAggregate(src, doc, projection.Expr) {
+				if !db.virtualFunctionIsAggregate(src, doc, projection.Expr) {
 					continue
 				}
 				value, err := db.evaluateVirtualCollectionAggregate(ctx, src, doc, projection.Expr, group.rows, params, legacy)
@@ -2163,7 +2196,7 @@ func (db *Database) projectVirtualGroupedAggregateRows(ctx context.Context, src
 			case parser.NodeKindBinaryExpr:
 				value, ok, err = db.virtualExprValue(ctx, src, doc, projection.Expr, havingRow, params, legacy)
 			case parser.NodeKindFunctionExpr:
-				if virtualFunctionNameIsCollectionAggregate(src, doc, projection.Expr) {
+				if db.virtualFunctionIsAggregate(src, doc, projection.Expr) {
 					value, err = db.evaluateVirtualCollectionAggregate(ctx, src, doc, projection.Expr, group.rows, params, legacy)
 					ok = true
 				} else if projection.Expr.ID >= 0 && int(projection.Expr.ID) < len(doc.FunctionExprs) && doc.FunctionExprs[projection.Expr.ID].HasWindow {
@@ -2409,6 +2442,9 @@ func (db *Database) evaluateVirtualAggregate(ctx context.Context, src []byte, do
 }
 
 func (db *Database) evaluateVirtualCollectionAggregate(ctx context.Context, src []byte, doc *parser.QueryDoc, ref parser.NodeRef, rows []virtualSQLRow, params *optimizer.ParameterSet, legacy QueryParams) (interface{}, error) {
+	if userFn, ok := db.aggregateFunctionCall(src, doc, ref); ok {
+		return db.evaluateUserAggregate(ctx, src, doc, userFn, ref, rows, params, legacy)
+	}
 	if !virtualFunctionNameIsCollectionAggregate(src, doc, ref) {
 		return nil, fmt.Errorf("unsupported collection aggregate")
 	}
@@ -3034,6 +3070,10 @@ func (db *Database) virtualExprValue(ctx context.Context, src []byte, doc *parse
 		if fn.NameEnd > uint32(len(src)) {
 			return nil, false, fmt.Errorf("invalid function name span")
 		}
+		userFn, isUserFn := db.functionCall(src, doc, ref)
+		if isUserFn && userFn.Aggregate {
+			return nil, false, fmt.Errorf("aggregate function %s must be a top-level projection", userFn.Name)
+		}
 		args := make([]interface{}, 0, fn.ArgsCount)
 		for i := int32(0); i < fn.ArgsCount; i++ {
 			if fn.ArgsStart+i < 0 || int(fn.ArgsStart+i) >= len(doc.FunctionArgs) {
@@ -3044,12 +3084,27 @@ func (db *Database) virtualExprValue(ctx context.Context, src []byte, doc *parse
 				return nil, false, err
 			}
 			if !ok {
-				return nil, false, nil
+				// Registered functions see a missing value as NULL.
+				if !isUserFn {
+					return nil, false, nil
+				}
+				value = nil
 			}
 			args = append(args, value)
 		}
+		if isUserFn {
+			value, err := userFn.callScalar(ctx, args)
+			if err != nil {
+				return nil, false, err
+			}
+			return value, true, nil
+		}
 		name := sourceSpan(src, fn.NameStart, fn.NameEnd)
 		switch {
+		case strings.EqualFold(name, "embed"):
+			return db.virtualEmbed(ctx, src, doc, ref, args)
+		case strings.EqualFold(name, "mmr"):
+			return nil, false, optimizer.ErrMMRPlacement
 		case strings.EqualFold(name, "now"):
 			if len(args) != 0 {
 				return nil, false, fmt.Errorf("NOW() does not accept arguments")
@@ -3747,6 +3802,9 @@ func virtualProjectionTypes(db *Database, doc *parser.QueryDoc, src []byte, stmt
 				"json_populate_record", "jsonb_populate_record", "to_json", "to_jsonb":
 				types[i] = catalog.TypeJSONB
 			}
+			if userFn, ok := db.functionCall(src, doc, projection.Expr); ok {
+				types[i] = sqlFunctionCatalogType(userFn)
+			}
 		}
 		if projection.Expr.Kind != parser.NodeKindBinaryExpr || projection.Expr.ID < 0 || int(projection.Expr.ID) >= len(doc.BinaryExprs) {
 			continue
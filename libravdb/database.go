@@ -17,6 +17,7 @@ import (
 	"github.com/xDarkicex/libravdb/internal/catalog"
 	"github.com/xDarkicex/libravdb/internal/index"
 	"github.com/xDarkicex/libravdb/internal/obs"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
 	"github.com/xDarkicex/libravdb/internal/quant"
 	"github.com/xDarkicex/libravdb/internal/storage"
 	"github.com/xDarkicex/libravdb/internal/storage/singlefile"
@@ -46,6 +47,7 @@ type Database struct {
 	costModelStats    *costModelStats
 	activeSnaps       activeSnapshots
 	temporalCache     *temporalIndexCache
+	ttlReaper         *ttlReaper
 	sqlPlanCache      *sqlPlanCache
 	sqlStats          *sqlStatsCounters
 	catalogGeneration atomic.Uint64
@@ -53,8 +55,21 @@ type Database struct {
 	autoIncrementNext map[string]uint64
 	defaultGraphMu    sync.Mutex
 	defaultGraph      Graph
-	mu                sync.RWMutex
-	closed            bool
+	// views caches the durable view registry by lower-cased name; see
+	// sql_view.go. viewDDLMu serializes view DDL.
+	viewsMu   sync.RWMutex
+	views     map[string]View
+	viewDDLMu sync.Mutex
+	// functions holds Go functions registered for SQL by lower-cased name;
+	// see sql_function.go.
+	functionsMu sync.RWMutex
+	functions   map[string]*sqlFunction
+	// embedders holds registered embedding models by lower-cased name; see
+	// embed.go.
+	embeddersMu sync.RWMutex
+	embedders   map[string]Embedder
+	mu          sync.RWMutex
+	closed      bool
 }
 
 // Config holds database-wide configuration
@@ -71,8 +86,12 @@ type Config struct {
 	Durability           DurabilityMode
 	Temporal             TemporalConfig
 	TemporalANN          TemporalANNConfig
+	TTLReapInterval      time.Duration
 	maxWritesExplicit    bool
 	writeQueueExplicit   bool
+	replica              bool
+	readOnly             bool
+	encryption           KeyProvider
 }
 
 // DurabilityMode controls when a successful write may be acknowledged.
@@ -112,6 +131,7 @@ func Open(opts ...Option) (*Database, error) {
 		AsyncIndexQueueDepth: 0,
 		AsyncIndexWorkers:    min(4, runtime.GOMAXPROCS(0)),
 		Durability:           DurabilitySynchronous,
+		TTLReapInterval:      defaultTTLReapInterval,
 	}
 
 	// Apply options
@@ -128,8 +148,10 @@ func Open(opts ...Option) (*Database, error) {
 			config.MaxWriteQueueDepth = config.AsyncIndexQueueDepth
 		}
 	}
-	if err := recoverMigrate(config.StoragePath); err != nil {
-		return nil, fmt.Errorf("recover interrupted migration: %w", err)
+	if !config.readOnly {
+		if err := recoverMigrate(config.StoragePath); err != nil {
+			return nil, fmt.Errorf("recover interrupted migration: %w", err)
+		}
 	}
 
 	// Create the index persistence bridge so persisted indexes can be
@@ -142,10 +164,19 @@ func Open(opts ...Option) (*Database, error) {
 	if config.AsyncIndexQueueDepth > 0 {
 		storageOptions = append(storageOptions, singlefile.WithWALGroupCommitTarget(min(28, config.MaxConcurrentWrites), 5*time.Millisecond))
 	}
+	if config.replica {
+		storageOptions = append(storageOptions, singlefile.WithReplica())
+	}
+	if config.readOnly {
+		storageOptions = append(storageOptions, singlefile.WithReadOnly())
+	}
+	if config.encryption != nil {
+		storageOptions = append(storageOptions, singlefile.WithEncryption(config.encryption))
+	}
 	storageEngine, err := singlefile.New(config.StoragePath, storageOptions...)
 
 	if err != nil {
-		if errors.Is(err, storage.ErrV1FormatMigrationRequired) {
+		if errors.Is(err, storage.ErrV1FormatMigrationRequired) && !config.readOnly {
 			if err := Migrate(context.Background(), config.StoragePath); err != nil {
 				bridge.closeCachedIndexes()
 				return nil, fmt.Errorf("auto-migration failed: %w", err)
@@ -248,6 +279,11 @@ func Open(opts ...Option) (*Database, error) {
 	// SQL plans be reused only while the schema they were bound against is
 	// still current.
 	db.catalogGeneration.Store(1)
+	if err := db.loadViews(); err != nil {
+		_ = storageEngine.Close()
+		bridge.closeCachedIndexes()
+		return nil, err
+	}
 
 	// Wire the bridge back to the database so SerializeIndex can access
 	// collection indexes during checkpoint.
@@ -314,6 +350,9 @@ func Open(opts ...Option) (*Database, error) {
 	if db.config.TemporalANN.MaxBytes > 0 || db.config.TemporalANN.MaxEntries > 0 {
 		db.temporalCache = newTemporalIndexCache(db, db.config.TemporalANN.MaxBytes, db.config.TemporalANN.MaxEntries)
 	}
+	if !config.readOnly && !config.replica && config.TTLReapInterval > 0 {
+		db.startTTLReaper(config.TTLReapInterval)
+	}
 
 	return db, nil
 }
@@ -596,7 +635,7 @@ func (db *Database) registerCollectionInCatalog(name string, config *CollectionC
 		}
 		// Register CHECK constraints in the catalog.
 		for _, chk := range config.CheckConstraints {
-			builder.AddCheckConstraint(name, chk.Expression, chk.ColumnName)
+			builder.AddNamedCheckConstraint(name, chk.Name, chk.Expression, chk.ColumnName)
 		}
 		// Register column DEFAULTs in the catalog.
 		for colName, defaultVal := range config.ColumnDefaults {
@@ -705,6 +744,131 @@ func (db *Database) vectorColumnName(name string) string {
 	return ""
 }
 
+// catalogRelationalSchema is the part of a table's relational schema that
+// only the SQL catalog persists. The physical collection config carries
+// columns and index declarations, but not constraints or DEFAULT values.
+type catalogRelationalSchema struct {
+	columnFlags    map[uint64]uint16 // column name hash -> ColFlag* bits
+	columnDefaults map[string]string
+	checks         []optimizer.DDLCheckConstraint
+	foreignKeys    []catalog.ForeignKeyInfo
+}
+
+// graphNodesColumns are the GRAPH_NODES columns a foreign key may reference.
+var graphNodesColumns = []string{"id", "collection", "record_id"}
+
+// relationalSchemaFromCatalog reads the constraints and defaults of table
+// name from cat. tableNames resolves foreign key target hashes to table
+// names; a foreign key whose target cannot be resolved is omitted. ok is
+// false when the catalog has no such table.
+func relationalSchemaFromCatalog(cat *catalog.Catalog, name string, tableNames map[uint64]string) (catalogRelationalSchema, bool) {
+	var schema catalogRelationalSchema
+	if cat == nil {
+		return schema, false
+	}
+	table, err := cat.GetTable(catalog.HashIdentifier(name))
+	if err != nil {
+		return schema, false
+	}
+	columns := cat.AllColumns(table)
+	columnNames := make(map[uint64]string, len(columns))
+	schema.columnFlags = make(map[uint64]uint16, len(columns))
+	for _, column := range columns {
+		columnNames[column.NameHash] = column.Name
+		if column.Flags != 0 {
+			schema.columnFlags[column.NameHash] = column.Flags
+		}
+	}
+	for colHash, value := range cat.DefaultValuesForTable(table.NameHash) {
+		if columnName := columnNames[colHash]; columnName != "" {
+			if schema.columnDefaults == nil {
+				schema.columnDefaults = make(map[string]string)
+			}
+			schema.columnDefaults[columnName] = value
+		}
+	}
+	for _, chk := range cat.CheckConstraintsForTable(table.NameHash) {
+		schema.checks = append(schema.checks, optimizer.DDLCheckConstraint{
+			Name:       cat.CheckName(chk),
+			Expression: cat.CheckExpr(chk),
+			ColumnName: columnNames[chk.ColHash],
+		})
+	}
+	for i, group := range cat.ForeignKeyGroupsForTable(table.NameHash) {
+		targetTable := tableNames[group.TargetTableHash]
+		if targetTable == "" {
+			continue
+		}
+		targetColumns := make(map[uint64]string)
+		if target, err := cat.GetTable(group.TargetTableHash); err == nil {
+			for _, column := range cat.AllColumns(target) {
+				targetColumns[column.NameHash] = column.Name
+			}
+		} else {
+			for _, column := range graphNodesColumns {
+				targetColumns[catalog.HashIdentifier(column)] = column
+			}
+		}
+		constraintName := cat.ForeignKeyName(group.Pairs[0])
+		if constraintName == "" {
+			// Catalogs written before constraint names were stored keep only
+			// the name hash; use the CREATE TABLE naming for unnamed FKs.
+			constraintName = fmt.Sprintf("__fk_%s_%d", name, i)
+		}
+		pairs := make([]catalog.ForeignKeyInfo, 0, len(group.Pairs))
+		for _, pair := range group.Pairs {
+			sourceColumn, targetColumn := columnNames[pair.SourceColHash], targetColumns[pair.TargetColHash]
+			if sourceColumn == "" || targetColumn == "" {
+				pairs = nil
+				break
+			}
+			pairs = append(pairs, catalog.ForeignKeyInfo{
+				Name:         constraintName,
+				SourceTable:  name,
+				SourceColumn: sourceColumn,
+				TargetTable:  targetTable,
+				TargetColumn: targetColumn,
+				OnDelete:     group.OnDelete,
+				OnUpdate:     group.OnUpdate,
+			})
+		}
+		schema.foreignKeys = append(schema.foreignKeys, pairs...)
+	}
+	return schema, true
+}
+
+// applyTo replaces the catalog-persisted declarations of config. Column
+// flags are kept only for declared metadata columns, matching what CREATE
+// TABLE records in ColumnConstraints.
+func (s catalogRelationalSchema) applyTo(config *CollectionConfig) {
+	if config == nil {
+		return
+	}
+	config.ColumnConstraints = nil
+	for columnName := range config.MetadataSchema {
+		if flags := s.columnFlags[catalog.HashIdentifier(columnName)]; flags != 0 {
+			if config.ColumnConstraints == nil {
+				config.ColumnConstraints = make(map[string]uint16)
+			}
+			config.ColumnConstraints[columnName] = flags
+		}
+	}
+	config.ColumnDefaults = s.columnDefaults
+	config.CheckConstraints = s.checks
+	config.ForeignKeys = s.foreignKeys
+}
+
+// catalogTableNames maps table name hashes to the given names and to the
+// GRAPH_NODES system table, for resolving foreign key targets.
+func catalogTableNames(names []string) map[uint64]string {
+	tableNames := make(map[uint64]string, len(names)+1)
+	tableNames[catalog.HashIdentifier("GRAPH_NODES")] = "GRAPH_NODES"
+	for _, name := range names {
+		tableNames[catalog.HashIdentifier(name)] = name
+	}
+	return tableNames
+}
+
 // metadataFieldToCatalogType maps a metadata FieldType to a catalog column type.
 func metadataFieldToCatalogType(ft FieldType) uint16 {
 	switch ft {
@@ -1318,45 +1482,14 @@ func (db *Database) loadExistingCollections(ctx context.Context, bridge *indexPe
 		loadedParents[name] = true
 	}
 
+	loadedNames := make([]string, 0, len(loadedCollections))
+	for name := range loadedCollections {
+		loadedNames = append(loadedNames, name)
+	}
+	tableNames := catalogTableNames(loadedNames)
 	db.mu.Lock()
 	for name, collection := range loadedCollections {
-		// JSON index definitions live in the durable catalog rather than the
-		// physical collection config. Hydrate them before the first query so the
-		// derived inverted postings can rebuild lazily from visible records.
-		if db.catalog != nil {
-			if table, err := db.catalog.GetTable(catalog.HashIdentifier(name)); err == nil {
-				columns := db.catalog.AllColumns(table)
-				for _, idx := range db.catalog.JSONIndexesForTable(table.NameHash) {
-					columnName := ""
-					for _, column := range columns {
-						if column.NameHash == idx.ColumnHash {
-							columnName = column.Name
-							break
-						}
-					}
-					if columnName == "" {
-						continue
-					}
-					collection.config.JSONIndexes = append(collection.config.JSONIndexes, JSONIndexDefinition{
-						Name:   db.catalog.JSONIndexName(idx),
-						Column: columnName,
-						Path:   db.catalog.JSONIndexPath(idx), TextResult: idx.TextResult != 0,
-					})
-				}
-			}
-		}
-		db.collections[name] = collection
-		// The persisted catalog is authoritative for SQL schema metadata. A
-		// storage collection config contains physical index settings but does
-		// not carry relational columns, composite PK order, or FK definitions;
-		// rebuilding the catalog here would silently erase those definitions
-		// on every reopen. Register only legacy collections that have no table
-		// entry yet.
-		if db.catalog == nil {
-			db.registerCollectionInCatalog(name, collection.config)
-		} else if _, err := db.catalog.GetTable(catalog.HashIdentifier(name)); err != nil {
-			db.registerCollectionInCatalog(name, collection.config)
-		}
+		db.adoptLoadedCollectionLocked(name, collection, tableNames)
 	}
 	db.mu.Unlock()
 	if bridge != nil {
@@ -1366,6 +1499,55 @@ func (db *Database) loadExistingCollections(ctx context.Context, bridge *indexPe
 	return nil
 }
 
+// adoptLoadedCollectionLocked hydrates a collection loaded from storage with
+// the catalog-only parts of its definition and publishes it. tableNames maps
+// catalog table hashes to the loaded collection names. Caller must hold
+// db.mu.
+func (db *Database) adoptLoadedCollectionLocked(name string, collection *Collection, tableNames map[uint64]string) {
+	// Constraints and defaults are likewise catalog-only; hydrating them
+	// keeps pg_constraint reflection and ALTER TABLE working after reopen.
+	if schema, ok := relationalSchemaFromCatalog(db.catalog, name, tableNames); ok {
+		schema.applyTo(collection.config)
+	}
+	// JSON index definitions live in the durable catalog rather than the
+	// physical collection config. Hydrate them before the first query so the
+	// derived inverted postings can rebuild lazily from visible records.
+	if db.catalog != nil {
+		if table, err := db.catalog.GetTable(catalog.HashIdentifier(name)); err == nil {
+			columns := db.catalog.AllColumns(table)
+			for _, idx := range db.catalog.JSONIndexesForTable(table.NameHash) {
+				columnName := ""
+				for _, column := range columns {
+					if column.NameHash == idx.ColumnHash {
+						columnName = column.Name
+						break
+					}
+				}
+				if columnName == "" {
+					continue
+				}
+				collection.config.JSONIndexes = append(collection.config.JSONIndexes, JSONIndexDefinition{
+					Name:   db.catalog.JSONIndexName(idx),
+					Column: columnName,
+					Path:   db.catalog.JSONIndexPath(idx), TextResult: idx.TextResult != 0,
+				})
+			}
+		}
+	}
+	db.collections[name] = collection
+	// The persisted catalog is authoritative for SQL schema metadata. A
+	// storage collection config contains physical index settings but does
+	// not carry relational columns, composite PK order, or FK definitions;
+	// rebuilding the catalog here would silently erase those definitions
+	// on every reopen. Register only legacy collections that have no table
+	// entry yet.
+	if db.catalog == nil {
+		db.registerCollectionInCatalog(name, collection.config)
+	} else if _, err := db.catalog.GetTable(catalog.HashIdentifier(name)); err != nil {
+		db.registerCollectionInCatalog(name, collection.config)
+	}
+}
+
 func (db *Database) loadCollectionFromStorage(ctx context.Context, name string, engine interface {
 	GetCollectionWithConfig(name string) (storage.Collection, *storage.CollectionConfig, error)
 }, bridge *indexPersistenceBridge) (*Collection, error) {
@@ -1440,6 +1622,9 @@ func (db *Database) loadCollectionFromStorage(ctx context.Context, name string,
 	if err != nil {
 		return nil, fmt.Errorf("failed to create collection from storage: %w", err)
 	}
+	if bridge != nil {
+		collection.adoptFullTextIndexes(bridge.takeFullTextIndexes(name))
+	}
 	collection.db = db
 	if g := collection.GetGraph(); g != nil {
 		collection.SetGraph(g)
@@ -1479,8 +1664,38 @@ func defaultMaxConcurrentWrites() int {
 	return procs
 }
 
+// flushRegeneration waits for every collection's asynchronous indexer to
+// write the generated vectors updates left to it.
+func (db *Database) flushRegeneration(ctx context.Context) error {
+	db.mu.RLock()
+	if db.closed {
+		db.mu.RUnlock()
+		return nil
+	}
+	queues := make([]*asyncIndexQueue, 0, len(db.collections))
+	for _, collection := range db.collections {
+		if collection.asyncIndex != nil {
+			queues = append(queues, collection.asyncIndex)
+		}
+	}
+	db.mu.RUnlock()
+	var errs []error
+	for _, queue := range queues {
+		if err := queue.flushRegeneration(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Close gracefully shuts down the database
 func (db *Database) Close() error {
+	// The reaper takes db.mu for each pass, so it is stopped first.
+	db.stopTTLReaper()
+	// Generated vectors left to an asynchronous indexer are written through
+	// Collection.Update, which takes the locks held below.
+	regenerateErr := db.flushRegeneration(context.Background())
+
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
@@ -1489,6 +1704,9 @@ func (db *Database) Close() error {
 	}
 
 	var errors []error
+	if regenerateErr != nil {
+		errors = append(errors, fmt.Errorf("generated vector regeneration: %w", regenerateErr))
+	}
 
 	// Stop the background health monitor before tearing down collections
 	// so health checks don't access closing state.
@@ -1544,6 +1762,25 @@ func (db *Database) Vacuum(ctx context.Context) error {
 	return fmt.Errorf("underlying storage engine does not support Vacuum")
 }
 
+// Checkpoint writes a storage snapshot covering every committed write, so a
+// later Open has no WAL to replay. Close also checkpoints, but rebuilds
+// indexes on the next Open; an explicit Checkpoint before Close persists them.
+func (db *Database) Checkpoint(ctx context.Context) error {
+	db.mu.RLock()
+	closed := db.closed
+	db.mu.RUnlock()
+	if closed {
+		return ErrDatabaseClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if c, ok := db.storage.(interface{ Checkpoint() error }); ok {
+		return c.Checkpoint()
+	}
+	return fmt.Errorf("underlying storage engine does not support Checkpoint")
+}
+
 // Backup creates a point-in-time copy of the database to the specified destination
 // path. It uses a non-blocking fast-forward design to ensure the copy is consistent
 // without interrupting active database operations.
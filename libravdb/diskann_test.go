@@ -0,0 +1,104 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestDiskANNCollectionSurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	dbPath := testDBPath(t)
+	nodeDir := t.TempDir()
+
+	db, err := Open(WithStoragePath(dbPath))
+	if err != nil {
+		t.Fatalf("new database: %v", err)
+	}
+	collection, err := db.CreateCollection(ctx, "docs",
+		WithDimension(8),
+		WithDiskANN(16, 32, 1.2),
+		WithDiskANNDirectory(nodeDir),
+	)
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	vectors := make(map[string][]float32, 300)
+	entries := make([]VectorEntry, 0, 300)
+	for i := 0; i < 300; i++ {
+		vector := make([]float32, 8)
+		for j := range vector {
+			vector[j] = rng.Float32()*2 - 1
+		}
+		id := fmt.Sprintf("doc-%d", i)
+		vectors[id] = vector
+		entries = append(entries, VectorEntry{ID: id, Vector: vector})
+	}
+	if err := collection.InsertBatch(ctx, entries); err != nil {
+		t.Fatalf("insert batch: %v", err)
+	}
+	if err := collection.Delete(ctx, "doc-0"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	assertNearest := func(c *Collection) {
+		t.Helper()
+		for _, id := range []string{"doc-1", "doc-150", "doc-299"} {
+			results, err := c.Search(ctx, vectors[id], 3)
+			if err != nil {
+				t.Fatalf("search %s: %v", id, err)
+			}
+			if len(results.Results) == 0 || results.Results[0].ID != id {
+				t.Fatalf("search %s returned %v", id, mmrIDs(results.Results))
+			}
+		}
+		results, err := c.Search(ctx, vectors["doc-0"], 5)
+		if err != nil {
+			t.Fatalf("search deleted vector: %v", err)
+		}
+		for _, result := range results.Results {
+			if result.ID == "doc-0" {
+				t.Fatal("deleted record returned")
+			}
+		}
+	}
+	assertNearest(collection)
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("close database: %v", err)
+	}
+	reopened, err := Open(WithStoragePath(dbPath))
+	if err != nil {
+		t.Fatalf("reopen database: %v", err)
+	}
+	defer reopened.Close()
+
+	reloaded, err := reopened.GetCollection("docs")
+	if err != nil {
+		t.Fatalf("get collection: %v", err)
+	}
+	config := reloaded.config
+	if config.IndexType != DiskANN || config.M != 16 || config.EfConstruction != 32 || config.PruneAlpha != 1.2 || config.IndexDirectory != nodeDir {
+		t.Fatalf("reopened config = %v R=%d L=%d alpha=%v dir=%q", config.IndexType, config.M, config.EfConstruction, config.PruneAlpha, config.IndexDirectory)
+	}
+	assertNearest(reloaded)
+}
+
+func TestWithDiskANNRejectsInvalidParameters(t *testing.T) {
+	for _, tc := range []struct {
+		r, l  int
+		alpha float64
+	}{
+		{1, 32, 1.2},
+		{16, 0, 1.2},
+		{16, 32, 0.5},
+	} {
+		var config CollectionConfig
+		if err := WithDiskANN(tc.r, tc.l, tc.alpha)(&config); err == nil {
+			t.Fatalf("WithDiskANN(%d, %d, %v) accepted", tc.r, tc.l, tc.alpha)
+		}
+	}
+}
@@ -948,19 +948,25 @@ func recordMetaToString(v interface{}) string {
 // scoreAndSelectTopK scores candidates using the collection's configured
 // distance metric and returns the top-k by descending similarity.
 func scoreAndSelectTopK(col *Collection, candidates []Record, queryVec []float32, k int) *SearchResults {
+	return scoreRecordsTopK(col.config.Metric, candidates, queryVec, k)
+}
+
+// scoreRecordsTopK scores each candidate's Vector against queryVec under
+// metric and returns the top-k by descending public score.
+func scoreRecordsTopK(metric DistanceMetric, candidates []Record, queryVec []float32, k int) *SearchResults {
 	type scored struct {
 		id    string
 		score float32
 	}
 	entries := make([]scored, 0, len(candidates))
-	indexQuery := vectorForIndex(col.config.Metric, queryVec)
+	indexQuery := vectorForIndex(metric, queryVec)
 	for _, rec := range candidates {
 		if len(rec.Vector) == 0 || len(queryVec) == 0 || len(rec.Vector) != len(queryVec) {
 			continue
 		}
-		indexVector := vectorForIndex(col.config.Metric, rec.Vector)
+		indexVector := vectorForIndex(metric, rec.Vector)
 		var rawDistance float32
-		switch col.config.Metric {
+		switch metric {
 		case L2Distance:
 			rawDistance = util.L2Distance_func(indexQuery, indexVector)
 		case InnerProduct:
@@ -970,7 +976,7 @@ func scoreAndSelectTopK(col *Collection, candidates []Record, queryVec []float32
 		default:
 			rawDistance = util.CosineDistance_func(indexQuery, indexVector)
 		}
-		entries = append(entries, scored{id: rec.ID, score: publicScore(col.config.Metric, rawDistance)})
+		entries = append(entries, scored{id: rec.ID, score: publicScore(metric, rawDistance)})
 	}
 	sort.Slice(entries, func(i, j int) bool {
 		return entries[i].score > entries[j].score
@@ -1038,6 +1044,10 @@ func (e *Executor) executeHybrid(ctx context.Context, plan *optimizer.PhysicalPl
 	default:
 		return nil, fmt.Errorf("unknown dispatch plan: %v", chosen)
 	}
+	if err == nil && results != nil {
+		trackSQLOperatorRows(ctx, explainKeyCandidates, metrics.ActConjunctionCandidates)
+		trackSQLOperatorRows(ctx, explainKeyVector, len(results.Results))
+	}
 
 	// If a transition downgraded the effective contract, record it.
 	if metrics.TransitionCount > 0 && metrics.EffectiveContract == optimizer.RecallExact {
@@ -0,0 +1,524 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/xDarkicex/lexer/parser"
+	"github.com/xDarkicex/libravdb/internal/catalog"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// Embedder turns text into dense vectors. Registered embedders back the
+// EMBED('model', text) SQL function and generated vector columns, so vectors
+// are computed next to the data they describe instead of by every caller.
+type Embedder interface {
+	// Dimension reports the length of every vector Embed returns.
+	Dimension() int
+	// Embed returns one vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+}
+
+// RegisterEmbedder makes e available to SQL and to generated vector columns
+// under model. Model names are case-insensitive. Registering an existing
+// name replaces the earlier embedder.
+//
+// Embedders are runtime state, like registered functions: a database
+// reopened with generated vector columns must register their models again
+// before writing to those collections.
+func (db *Database) RegisterEmbedder(model string, e Embedder) error {
+	key := strings.ToLower(strings.TrimSpace(model))
+	if key == "" {
+		return fmt.Errorf("embedding model name must not be empty")
+	}
+	if e == nil {
+		return fmt.Errorf("embedding model %q has no embedder", model)
+	}
+	if dimension := e.Dimension(); dimension <= 0 {
+		return fmt.Errorf("embedding model %q: dimension must be positive, got %d", model, dimension)
+	}
+	db.embeddersMu.Lock()
+	defer db.embeddersMu.Unlock()
+	if db.embedders == nil {
+		db.embedders = make(map[string]Embedder)
+	}
+	db.embedders[key] = e
+	return nil
+}
+
+// Embed embeds one text with a registered model.
+func (db *Database) Embed(ctx context.Context, model, text string) ([]float32, error) {
+	vectors, err := db.embedTexts(ctx, model, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+func (db *Database) embedder(model string) (Embedder, error) {
+	db.embeddersMu.RLock()
+	e, ok := db.embedders[strings.ToLower(strings.TrimSpace(model))]
+	db.embeddersMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("embedding model %q is not registered", model)
+	}
+	return e, nil
+}
+
+// embedTexts embeds texts in one call and checks the embedder kept its
+// contract, so a misbehaving model cannot write vectors of the wrong shape.
+func (db *Database) embedTexts(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	e, err := db.embedder(model)
+	if err != nil {
+		return nil, err
+	}
+	vectors, err := e.Embed(ctx, texts)
+	if err != nil {
+		return nil, fmt.Errorf("embedding model %q: %w", model, err)
+	}
+	if len(vectors) != len(texts) {
+		return nil, fmt.Errorf("embedding model %q returned %d vectors for %d texts", model, len(vectors), len(texts))
+	}
+	dimension := e.Dimension()
+	for _, vector := range vectors {
+		if len(vector) != dimension {
+			return nil, fmt.Errorf("embedding model %q returned a %d-dimensional vector, want %d", model, len(vector), dimension)
+		}
+	}
+	return vectors, nil
+}
+
+// NewHashEmbedder returns a deterministic, dependency-free Embedder for tests
+// and local development. Each lower-cased alphanumeric token is hashed into
+// one signed bucket and the result is L2-normalised, so texts sharing words
+// are close under cosine and L2 distance. It carries no semantics beyond
+// token overlap; production deployments register a real model.
+func NewHashEmbedder(dimension int) Embedder {
+	return hashEmbedder{dimension: dimension}
+}
+
+type hashEmbedder struct {
+	dimension int
+}
+
+func (h hashEmbedder) Dimension() int { return h.dimension }
+
+func (h hashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if h.dimension <= 0 {
+		return nil, fmt.Errorf("hash embedder dimension must be positive, got %d", h.dimension)
+	}
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vector := make([]float32, h.dimension)
+		tokens := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, token := range tokens {
+			hash := fnv.New64a()
+			_, _ = hash.Write([]byte(token))
+			sum := hash.Sum64()
+			if sum>>63 == 1 {
+				vector[sum%uint64(h.dimension)]--
+			} else {
+				vector[sum%uint64(h.dimension)]++
+			}
+		}
+		var norm float64
+		for _, value := range vector {
+			norm += float64(value) * float64(value)
+		}
+		if norm > 0 {
+			scale := float32(1 / math.Sqrt(norm))
+			for j := range vector {
+				vector[j] *= scale
+			}
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// embedScope memoizes the EMBED calls of one statement, so a call that is
+// planned more than once or evaluated for every row of a query-local
+// relation reaches its model once.
+type embedScope struct {
+	mu      sync.Mutex
+	vectors map[[2]string][]float32
+}
+
+type embedScopeKey struct{}
+
+// withEmbedScope gives a statement its EMBED memo. A nested statement shares
+// its parent's, since it runs with the same parameters.
+func withEmbedScope(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(embedScopeKey{}).(*embedScope); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, embedScopeKey{}, &embedScope{vectors: make(map[[2]string][]float32)})
+}
+
+// embedSQL evaluates EMBED(model, text) for the statement running in ctx.
+func (db *Database) embedSQL(ctx context.Context, model, text string) ([]float32, error) {
+	scope, _ := ctx.Value(embedScopeKey{}).(*embedScope)
+	key := [2]string{strings.ToLower(strings.TrimSpace(model)), text}
+	if scope != nil {
+		scope.mu.Lock()
+		vector, ok := scope.vectors[key]
+		scope.mu.Unlock()
+		if ok {
+			return vector, nil
+		}
+	}
+	vectors, err := db.embedTexts(ctx, model, []string{text})
+	if err != nil {
+		return nil, err
+	}
+	if scope != nil {
+		scope.mu.Lock()
+		scope.vectors[key] = vectors[0]
+		scope.mu.Unlock()
+	}
+	return vectors[0], nil
+}
+
+// newSQLOptimizer returns an optimizer for a statement running in ctx. EMBED
+// calls are evaluated as the statement is planned, with its bound
+// parameters; the statement text and therefore its plan cache key are left
+// unchanged.
+func (db *Database) newSQLOptimizer(ctx context.Context, cat *catalog.Catalog) *optimizer.Optimizer {
+	opt := optimizer.NewOptimizer(cat)
+	opt.SetEmbedder(func(model, text string) ([]float32, error) {
+		return db.embedSQL(ctx, model, text)
+	})
+	return opt
+}
+
+// virtualEmbed evaluates EMBED for the query-local row evaluator, whose
+// arguments have already been evaluated.
+func (db *Database) virtualEmbed(ctx context.Context, src []byte, doc *parser.QueryDoc, ref parser.NodeRef, args []interface{}) (interface{}, bool, error) {
+	if err := optimizer.CheckEmbedCall(doc, src, ref); err != nil {
+		return nil, false, err
+	}
+	if args[0] == nil {
+		return nil, false, fmt.Errorf("EMBED: model name must not be NULL")
+	}
+	model, ok := args[0].(string)
+	if !ok {
+		return nil, false, fmt.Errorf("EMBED: model name must be text, got %T", args[0])
+	}
+	if args[1] == nil {
+		return nil, true, nil
+	}
+	text, ok := args[1].(string)
+	if !ok {
+		return nil, false, fmt.Errorf("EMBED: text must be text, got %T", args[1])
+	}
+	vector, err := db.embedSQL(ctx, model, text)
+	if err != nil {
+		return nil, false, fmt.Errorf("EMBED: %w", err)
+	}
+	return vector, true, nil
+}
+
+// GeneratedVectorDefinition declares that a collection's primary vector is
+// generated from its metadata: Expression is evaluated per record and
+// embedded with Model. Column is the SQL name of the vector column.
+//
+// Expression concatenates columns and string literals with ||, for example
+// title || ' ' || body. A NULL or missing column contributes empty text, as in
+// concat(). Writes that omit the vector embed the expression, and updates
+// embed it again only when its text changes. Without asynchronous indexing
+// the vector is computed inside the write or transaction, so a committed
+// record never pairs new text with a stale vector. With it, an update keeps
+// the stored vector and the asynchronous index queue embeds and writes the
+// new one after the update commits, as it indexes inserts after they commit;
+// FlushIndex and Close wait for it and IndexingStats counts it. After a
+// crash, a record whose regeneration had not run keeps its previous vector
+// until its text changes again.
+type GeneratedVectorDefinition struct {
+	Column     string `json:"column"`
+	Expression string `json:"expression"`
+	Model      string `json:"model"`
+}
+
+// WithGeneratedVector generates the collection's vector from expression with
+// the embedding model registered under model; see GeneratedVectorDefinition.
+func WithGeneratedVector(column, expression, model string) CollectionOption {
+	return func(c *CollectionConfig) error {
+		definition := GeneratedVectorDefinition{
+			Column:     strings.TrimSpace(column),
+			Expression: strings.TrimSpace(expression),
+			Model:      strings.TrimSpace(model),
+		}
+		if definition.Column == "" {
+			return fmt.Errorf("generated vector column name must not be empty")
+		}
+		if definition.Model == "" {
+			return fmt.Errorf("generated vector column %q requires an embedding model", definition.Column)
+		}
+		if _, err := parseGeneratedVectorExpression(definition.Expression); err != nil {
+			return fmt.Errorf("generated vector column %q: %w", definition.Column, err)
+		}
+		c.GeneratedVector = &definition
+		return nil
+	}
+}
+
+func generatedVectorToStorage(definition *GeneratedVectorDefinition) *storage.GeneratedVectorDefinition {
+	if definition == nil {
+		return nil
+	}
+	return &storage.GeneratedVectorDefinition{Column: definition.Column, Expression: definition.Expression, Model: definition.Model}
+}
+
+func generatedVectorFromStorage(definition *storage.GeneratedVectorDefinition) *GeneratedVectorDefinition {
+	if definition == nil {
+		return nil
+	}
+	return &GeneratedVectorDefinition{Column: definition.Column, Expression: definition.Expression, Model: definition.Model}
+}
+
+func cloneGeneratedVector(definition *GeneratedVectorDefinition) *GeneratedVectorDefinition {
+	if definition == nil {
+		return nil
+	}
+	clone := *definition
+	return &clone
+}
+
+// generatedVectorOperand is a column reference or a literal of a generated
+// vector expression.
+type generatedVectorOperand struct {
+	column  string
+	literal string
+}
+
+// parseGeneratedVectorExpression splits a generated vector expression into
+// its || operands. Parentheses may group the whole expression or an operand.
+func parseGeneratedVectorExpression(expression string) ([]generatedVectorOperand, error) {
+	tokens, err := optimizer.LexSQLTokens([]byte(expression), true)
+	if err != nil {
+		return nil, fmt.Errorf("generated vector expression: %w", err)
+	}
+	var operands []generatedVectorOperand
+	expectOperand := true
+	for _, token := range tokens {
+		if token.Kind == optimizer.SQLTokenPunct && (token.Text == "(" || token.Text == ")") {
+			continue
+		}
+		if token.Kind == optimizer.SQLTokenEOF {
+			break
+		}
+		if !expectOperand {
+			if token.Kind != optimizer.SQLTokenPunct || token.Text != "||" {
+				return nil, fmt.Errorf("generated vector expression: expected || before %q", expression[token.Start:token.End])
+			}
+			expectOperand = true
+			continue
+		}
+		switch token.Kind {
+		case optimizer.SQLTokenWord, optimizer.SQLTokenQuoted:
+			operands = append(operands, generatedVectorOperand{column: token.Text})
+		case optimizer.SQLTokenString, optimizer.SQLTokenNumber:
+			operands = append(operands, generatedVectorOperand{literal: token.Text})
+		default:
+			return nil, fmt.Errorf("generated vector expression: unexpected %q; only columns and literals joined by || are supported", expression[token.Start:token.End])
+		}
+		expectOperand = false
+	}
+	if len(operands) == 0 || expectOperand {
+		return nil, fmt.Errorf("generated vector expression %q is incomplete", expression)
+	}
+	return operands, nil
+}
+
+// sourceColumns returns the columns a generated vector is read from.
+func (d *GeneratedVectorDefinition) sourceColumns() []string {
+	operands, _ := parseGeneratedVectorExpression(d.Expression)
+	var columns []string
+	for _, operand := range operands {
+		if operand.column != "" {
+			columns = append(columns, operand.column)
+		}
+	}
+	return columns
+}
+
+// text evaluates the expression against one record's metadata.
+func (d *GeneratedVectorDefinition) text(metadata map[string]interface{}) (string, error) {
+	operands, err := parseGeneratedVectorExpression(d.Expression)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for _, operand := range operands {
+		if operand.column == "" {
+			b.WriteString(operand.literal)
+			continue
+		}
+		value, ok := metadata[operand.column]
+		if !ok {
+			for key, candidate := range metadata {
+				if strings.EqualFold(key, operand.column) {
+					value = candidate
+					break
+				}
+			}
+		}
+		if value != nil {
+			b.WriteString(recordMetaToString(value))
+		}
+	}
+	return b.String(), nil
+}
+
+func (c *Collection) generatedVector() *GeneratedVectorDefinition {
+	if c == nil || c.config == nil {
+		return nil
+	}
+	return c.config.GeneratedVector
+}
+
+// embedGenerated embeds the generated expression of each metadata image.
+func (c *Collection) embedGenerated(ctx context.Context, definition *GeneratedVectorDefinition, images []map[string]interface{}) ([][]float32, error) {
+	if c.db == nil {
+		return nil, fmt.Errorf("generated vector column %q requires a database", definition.Column)
+	}
+	texts := make([]string, len(images))
+	for i, metadata := range images {
+		text, err := definition.text(metadata)
+		if err != nil {
+			return nil, err
+		}
+		texts[i] = text
+	}
+	vectors, err := c.db.embedTexts(ctx, definition.Model, texts)
+	if err != nil {
+		return nil, fmt.Errorf("generated vector column %q: %w", definition.Column, err)
+	}
+	return vectors, nil
+}
+
+// generateVector returns the vector to insert with metadata. A supplied
+// vector is kept; a nil one is generated from the record's text, with
+// column DEFAULTs applied first so they contribute like stored values.
+func (c *Collection) generateVector(ctx context.Context, vector []float32, metadata map[string]interface{}) ([]float32, error) {
+	definition := c.generatedVector()
+	if definition == nil || vector != nil {
+		return vector, nil
+	}
+	vectors, err := c.embedGenerated(ctx, definition, []map[string]interface{}{c.metadataWithDefaults(metadata)})
+	if err != nil {
+		return nil, err
+	}
+	return vectors[0], nil
+}
+
+// generateVectors fills every nil vector of a batch in one embedding call.
+func (c *Collection) generateVectors(ctx context.Context, entries []VectorEntry) error {
+	definition := c.generatedVector()
+	if definition == nil {
+		return nil
+	}
+	var pending []int
+	var images []map[string]interface{}
+	for i := range entries {
+		if entries[i].Vector == nil {
+			pending = append(pending, i)
+			images = append(images, c.metadataWithDefaults(entries[i].Metadata))
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	vectors, err := c.embedGenerated(ctx, definition, images)
+	if err != nil {
+		return err
+	}
+	for i, entryIndex := range pending {
+		entries[entryIndex].Vector = vectors[i]
+	}
+	return nil
+}
+
+// regenerateVector returns the vector an update of oldMetadata to
+// newMetadata must write. A supplied vector is kept; otherwise the vector is
+// embedded again only when the generated text changed, and nil (keep the
+// stored vector) is returned when it did not.
+//
+// A collection with an asynchronous indexer does not embed inside the
+// update: regenerate reports that the caller must hand id to the indexer
+// once the update commits, and the stored vector is kept until the indexer
+// has written the new one.
+func (c *Collection) regenerateVector(ctx context.Context, vector []float32, oldMetadata, newMetadata map[string]interface{}) (_ []float32, regenerate bool, _ error) {
+	definition := c.generatedVector()
+	if definition == nil || vector != nil {
+		return vector, false, nil
+	}
+	oldText, err := definition.text(oldMetadata)
+	if err != nil {
+		return nil, false, err
+	}
+	newText, err := definition.text(newMetadata)
+	if err != nil {
+		return nil, false, err
+	}
+	if oldText == newText {
+		return nil, false, nil
+	}
+	if c.asyncIndex != nil {
+		return nil, true, nil
+	}
+	vectors, err := c.embedGenerated(ctx, definition, []map[string]interface{}{newMetadata})
+	if err != nil {
+		return nil, false, err
+	}
+	return vectors[0], false, nil
+}
+
+// regenerateVectors embeds the generated vectors of ids again for the
+// asynchronous indexer, in one embedding call, and writes them. A record
+// deleted since is skipped. One whose text changes again meanwhile was
+// queued again by that update, so the last write's text wins.
+func (c *Collection) regenerateVectors(ctx context.Context, ids []string) error {
+	definition := c.generatedVector()
+	if definition == nil {
+		return nil
+	}
+	live := make([]string, 0, len(ids))
+	images := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range ids {
+		record, err := c.Get(ctx, id)
+		if errors.Is(err, ErrRecordNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		live = append(live, id)
+		images = append(images, record.Metadata)
+	}
+	if len(live) == 0 {
+		return nil
+	}
+	vectors, err := c.embedGenerated(ctx, definition, images)
+	if err != nil {
+		return err
+	}
+	for i, id := range live {
+		if err := c.Update(ctx, id, vectors[i], nil); err != nil && !errors.Is(err, ErrRecordNotFound) {
+			return fmt.Errorf("generated vector column %q: write %s: %w", definition.Column, id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+package libravdb
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// countingEmbedder records how many texts reach the wrapped embedder.
+type countingEmbedder struct {
+	Embedder
+	texts []string
+}
+
+func (c *countingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	c.texts = append(c.texts, texts...)
+	return c.Embedder.Embed(ctx, texts)
+}
+
+func sameVector(a, b []float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHashEmbedder(t *testing.T) {
+	ctx := context.Background()
+	e := NewHashEmbedder(64)
+	vectors, err := e.Embed(ctx, []string{"Vector search in Go", "vector SEARCH, in go!", "graph traversal", ""})
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if !sameVector(vectors[0], vectors[1]) {
+		t.Fatal("case and punctuation changed the embedding")
+	}
+	var norm float64
+	for _, value := range vectors[0] {
+		norm += float64(value) * float64(value)
+	}
+	if math.Abs(norm-1) > 1e-5 {
+		t.Fatalf("norm = %v, want 1", norm)
+	}
+	for _, value := range vectors[3] {
+		if value != 0 {
+			t.Fatalf("empty text embedding = %v, want zero vector", vectors[3])
+		}
+	}
+	if _, err := NewHashEmbedder(0).Embed(ctx, []string{"x"}); err == nil {
+		t.Fatal("zero-dimension hash embedder embedded text")
+	}
+}
+
+func TestEmbedFunctionInInsertAndVectorDistance(t *testing.T) {
+	ctx := context.Background()
+	db := openTempDB(t, "embed_sql")
+	defer db.Close()
+	if err := db.RegisterEmbedder("Mini", NewHashEmbedder(32)); err != nil {
+		t.Fatalf("RegisterEmbedder: %v", err)
+	}
+
+	exec(t, db, `CREATE TABLE docs (id TEXT PRIMARY KEY, embedding VECTOR(32))`)
+	exec(t, db, `INSERT INTO docs (id, embedding) VALUES ('go', EMBED('mini', 'go channels and goroutines'))`)
+	exec(t, db, `INSERT INTO docs (id, embedding) VALUES ('sql', embed('MINI', 'sql joins and indexes'))`)
+	if _, err := db.QueryWithParams(ctx, `INSERT INTO docs (id, embedding) VALUES ('graph', EMBED('mini', $text))`,
+		QueryParams{"text": "graph traversal and shortest paths"}); err != nil {
+		t.Fatalf("INSERT with parameter: %v", err)
+	}
+
+	col, err := db.GetCollection("docs")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	stored, err := col.Get(ctx, "sql")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	want, err := db.Embed(ctx, "mini", "sql joins and indexes")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if !sameVector(stored.Vector, want) {
+		t.Fatalf("stored vector differs from db.Embed")
+	}
+
+	result, err := db.QueryWithParams(ctx,
+		`SELECT id FROM docs ORDER BY VECTOR_DISTANCE(embedding, EMBED('mini', $q)) ASC LIMIT 1`,
+		QueryParams{"q": "shortest paths in a graph"})
+	if err != nil {
+		t.Fatalf("VECTOR_DISTANCE with EMBED: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].ID != "graph" {
+		t.Fatalf("nearest = %#v, want graph", result.Results)
+	}
+	// The query-local evaluator embeds the same call once per statement, not
+	// once per row.
+	counter := &countingEmbedder{Embedder: NewHashEmbedder(32)}
+	if err := db.RegisterEmbedder("mini", counter); err != nil {
+		t.Fatalf("RegisterEmbedder: %v", err)
+	}
+	result, err = db.QueryWithParams(ctx,
+		`WITH d AS (SELECT id, embedding FROM docs) SELECT id FROM d ORDER BY VECTOR_DISTANCE(embedding, EMBED('mini', $q)) ASC LIMIT 1`,
+		QueryParams{"q": "sql indexes"})
+	if err != nil {
+		t.Fatalf("EMBED over a CTE: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].ID != "sql" || len(counter.texts) != 1 {
+		t.Fatalf("nearest = %#v after embedding %q, want sql after one text", result.Results, counter.texts)
+	}
+	exec(t, db, `UPDATE docs SET embedding = EMBED('mini', 'graph traversal') WHERE id = 'go'`)
+	stored, err = col.Get(ctx, "go")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if want, _ = db.Embed(ctx, "mini", "graph traversal"); !sameVector(stored.Vector, want) {
+		t.Fatalf("UPDATE SET EMBED did not store the embedding")
+	}
+
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY VECTOR_DISTANCE(embedding, EMBED('large', 'x')) LIMIT 1`, `embedding model "large" is not registered`)
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY VECTOR_DISTANCE(embedding, EMBED('mini', id)) LIMIT 1`, "generated vector column")
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY VECTOR_DISTANCE(embedding, EMBED('mini')) LIMIT 1`, "EMBED expects 2 arguments")
+	// A string that merely mentions EMBED( is left alone.
+	exec(t, db, `CREATE TABLE notes (id TEXT PRIMARY KEY, note TEXT)`)
+	exec(t, db, `INSERT INTO notes (id, note) VALUES ('n', 'call EMBED(model, text)')`)
+	if got := alterField(t, db, "notes", "n", "note"); got != "call EMBED(model, text)" {
+		t.Fatalf("note = %v", got)
+	}
+	if err := db.RegisterScalarFunction("embed", nil, StringField, func(context.Context, []interface{}) (interface{}, error) { return nil, nil }); err == nil {
+		t.Fatal("registered a function named embed")
+	}
+}
+
+func TestGeneratedVectorColumn(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "generated.libravdb")
+	db, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	model := &countingEmbedder{Embedder: NewHashEmbedder(16)}
+	if err := db.RegisterEmbedder("mini", model); err != nil {
+		t.Fatalf("RegisterEmbedder: %v", err)
+	}
+
+	alterMustFail(t, db, `CREATE TABLE bad (id TEXT PRIMARY KEY, body TEXT, embedding VECTOR(8) GENERATED FROM (body) USING mini)`, "produces 16-dimensional vectors")
+	alterMustFail(t, db, `CREATE TABLE bad (id TEXT PRIMARY KEY, body TEXT, embedding VECTOR(16) GENERATED FROM (body) USING large)`, `"large" is not registered`)
+	alterMustFail(t, db, `CREATE TABLE bad (id TEXT PRIMARY KEY, body TEXT GENERATED FROM (id) USING mini, embedding VECTOR(16))`, "must be the table's VECTOR column")
+
+	exec(t, db, `CREATE TABLE notes (
+		id TEXT PRIMARY KEY,
+		title TEXT,
+		body TEXT,
+		views BIGINT,
+		embedding VECTOR(16) GENERATED FROM (title || ' ' || body) USING mini
+	)`)
+	exec(t, db, `INSERT INTO notes (id, title, body, views) VALUES ('a', 'graph', 'shortest paths', 1)`)
+	exec(t, db, `INSERT INTO notes (id, title) VALUES ('b', 'vector')`)
+	col, err := db.GetCollection("notes")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	if err := col.Insert(ctx, "c", nil, map[string]interface{}{"title": "sql", "body": "window functions"}); err != nil {
+		t.Fatalf("native Insert: %v", err)
+	}
+	checkVector := func(id, text string) {
+		t.Helper()
+		rec, err := col.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+		want, err := db.Embed(ctx, "mini", text)
+		if err != nil {
+			t.Fatalf("Embed: %v", err)
+		}
+		if !sameVector(rec.Vector, want) {
+			t.Fatalf("vector of %s does not embed %q", id, text)
+		}
+	}
+	checkVector("a", "graph shortest paths")
+	// A NULL source column contributes empty text.
+	checkVector("b", "vector ")
+	checkVector("c", "sql window functions")
+
+	// Only a change to the source text embeds again.
+	model.texts = nil
+	exec(t, db, `UPDATE notes SET views = 2 WHERE id = 'a'`)
+	if len(model.texts) != 0 {
+		t.Fatalf("unrelated UPDATE embedded %q", model.texts)
+	}
+	exec(t, db, `UPDATE notes SET body = 'nearest neighbours' WHERE id = 'a'`)
+	if len(model.texts) != 1 || model.texts[0] != "graph nearest neighbours" {
+		t.Fatalf("UPDATE embedded %q, want one text", model.texts)
+	}
+	checkVector("a", "graph nearest neighbours")
+	if err := col.Update(ctx, "c", nil, map[string]interface{}{"title": "postgres"}); err != nil {
+		t.Fatalf("native Update: %v", err)
+	}
+	checkVector("c", "postgres window functions")
+
+	result, err := db.Query(ctx, `SELECT id FROM notes ORDER BY VECTOR_DISTANCE(embedding, EMBED('mini', 'nearest neighbours of a graph')) ASC LIMIT 1`)
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(result.Results) != 1 || result.Results[0].ID != "a" {
+		t.Fatalf("nearest = %#v, want a", result.Results)
+	}
+
+	alterMustFail(t, db, `INSERT INTO notes (id, title, embedding) VALUES ('d', 'x', '[1,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]')`, `cannot insert a non-DEFAULT value into column "embedding"`)
+	alterMustFail(t, db, `UPDATE notes SET embedding = '[1,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0]' WHERE id = 'a'`, `column "embedding" can only be updated to DEFAULT`)
+	alterMustFail(t, db, `ALTER TABLE notes ALTER COLUMN body TYPE BIGINT`, "used by generated vector column")
+	exec(t, db, `ALTER TABLE notes RENAME COLUMN body TO content`)
+	if generated := col.Config().GeneratedVector; generated == nil || generated.Expression != "title || ' ' || content" {
+		t.Fatalf("generated vector after rename = %#v", generated)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	db, err = Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer db.Close()
+	col, err = db.GetCollection("notes")
+	if err != nil {
+		t.Fatalf("GetCollection after reopen: %v", err)
+	}
+	generated := col.Config().GeneratedVector
+	if generated == nil || generated.Column != "embedding" || generated.Model != "mini" || generated.Expression != "title || ' ' || content" {
+		t.Fatalf("generated vector after reopen = %#v", generated)
+	}
+	alterMustFail(t, db, `INSERT INTO notes (id, title) VALUES ('e', 'late')`, `embedding model "mini" is not registered`)
+	if err := db.RegisterEmbedder("mini", NewHashEmbedder(16)); err != nil {
+		t.Fatalf("RegisterEmbedder after reopen: %v", err)
+	}
+	exec(t, db, `INSERT INTO notes (id, title, content) VALUES ('e', 'late', 'arrival')`)
+	checkVector = func(id, text string) {
+		t.Helper()
+		rec, err := col.Get(ctx, id)
+		if err != nil {
+			t.Fatalf("Get %s: %v", id, err)
+		}
+		want, _ := db.Embed(ctx, "mini", text)
+		if !sameVector(rec.Vector, want) {
+			t.Fatalf("vector of %s does not embed %q", id, text)
+		}
+	}
+	checkVector("e", "late arrival")
+}
+
+func TestGeneratedVectorRegeneratesThroughAsyncIndexer(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(filepath.Join(t.TempDir(), "regenerate.libravdb")), WithMetrics(false), WithAsyncIndexing(64, 1))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	model := &countingEmbedder{Embedder: NewHashEmbedder(16)}
+	if err := db.RegisterEmbedder("mini", model); err != nil {
+		t.Fatalf("RegisterEmbedder: %v", err)
+	}
+	exec(t, db, `CREATE TABLE notes (id TEXT PRIMARY KEY, body TEXT, embedding VECTOR(16) GENERATED FROM (body) USING mini)`)
+	exec(t, db, `INSERT INTO notes (id, body) VALUES ('a', 'graph')`)
+	col, err := db.GetCollection("notes")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	if col.asyncIndex == nil {
+		t.Fatal("collection has no asynchronous indexer")
+	}
+	if err := col.FlushIndex(ctx); err != nil {
+		t.Fatalf("FlushIndex: %v", err)
+	}
+
+	model.texts = nil
+	exec(t, db, `UPDATE notes SET body = 'vector search' WHERE id = 'a'`)
+	if err := col.FlushIndex(ctx); err != nil {
+		t.Fatalf("FlushIndex: %v", err)
+	}
+	if len(model.texts) != 1 || model.texts[0] != "vector search" {
+		t.Fatalf("regeneration embedded %q, want one text", model.texts)
+	}
+	rec, err := col.Get(ctx, "a")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	want, err := db.Embed(ctx, "mini", "vector search")
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if !sameVector(rec.Vector, want) {
+		t.Fatal("regenerated vector does not embed the updated text")
+	}
+	if stats := col.IndexingStats(); stats.Regenerating != 0 || stats.Failed {
+		t.Fatalf("IndexingStats = %+v", stats)
+	}
+}
+
+func TestParseGeneratedVectorExpression(t *testing.T) {
+	operands, err := parseGeneratedVectorExpression(`("Body") || '!'`)
+	if err != nil || len(operands) != 2 || operands[0].column != "Body" || operands[1].literal != "!" {
+		t.Fatalf("operands = %#v, %v", operands, err)
+	}
+	for _, bad := range []string{"title body", "title ||", "upper(title)", "title + body"} {
+		if _, err := parseGeneratedVectorExpression(bad); err == nil {
+			t.Fatalf("expression %q was accepted", bad)
+		}
+	}
+}
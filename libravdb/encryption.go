@@ -0,0 +1,45 @@
+package libravdb
+
+import (
+	"fmt"
+
+	"github.com/xDarkicex/libravdb/internal/storage/singlefile"
+)
+
+// KeyProvider supplies the AES keys used by WithEncryptionKey. CurrentKey
+// names the key new data is sealed with; Key looks up any key by ID so data
+// sealed before a rotation stays readable until Vacuum rewrites it.
+type KeyProvider = singlefile.KeyProvider
+
+var (
+	// ErrEncryptionKeyRequired reports an encrypted database opened without
+	// WithEncryptionKey.
+	ErrEncryptionKeyRequired = singlefile.ErrEncryptionKeyRequired
+	// ErrNotEncrypted reports a plaintext database opened with
+	// WithEncryptionKey.
+	ErrNotEncrypted = singlefile.ErrNotEncrypted
+	// ErrDecryptionFailed reports data that does not authenticate under the
+	// provided keys: a wrong key or a modified file.
+	ErrDecryptionFailed = singlefile.ErrDecryptionFailed
+)
+
+// StaticKey returns a KeyProvider holding one AES-128, AES-192 or AES-256
+// key.
+func StaticKey(key []byte) KeyProvider {
+	return singlefile.StaticKey(key)
+}
+
+// WithEncryptionKey encrypts the database file at rest with AES-GCM. The
+// option must be given when the database is created and on every later
+// open. Backups of an encrypted database are encrypted with the same keys.
+// To rotate keys, make the new key current in the provider and call Vacuum,
+// which rewrites the file under it.
+func WithEncryptionKey(provider KeyProvider) Option {
+	return func(c *Config) error {
+		if provider == nil {
+			return fmt.Errorf("encryption key provider cannot be nil")
+		}
+		c.encryption = provider
+		return nil
+	}
+}
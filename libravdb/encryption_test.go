@@ -0,0 +1,57 @@
+package libravdb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncryptionKeyProtectsDatabaseFile(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "sealed.libravdb")
+	key := StaticKey(bytes.Repeat([]byte{7}, 32))
+	db, err := Open(WithStoragePath(path), WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := db.CreateCollection(ctx, "docs", WithDimension(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "a", []float32{1, 0}, map[string]interface{}{"owner": "customer-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("customer-secret")) {
+		t.Fatal("database file contains plaintext metadata")
+	}
+
+	if _, err := Open(WithStoragePath(path)); !errors.Is(err, ErrEncryptionKeyRequired) {
+		t.Fatalf("Open without key: %v, want ErrEncryptionKeyRequired", err)
+	}
+	db, err = Open(WithStoragePath(path), WithEncryptionKey(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	docs, err = db.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := docs.Get(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Metadata["owner"] != "customer-secret" {
+		t.Fatalf("record metadata = %v", record.Metadata)
+	}
+}
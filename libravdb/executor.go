@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	apexjson "github.com/xDarkicex/apexJSON/v2"
 	"github.com/xDarkicex/lexer"
@@ -32,6 +34,9 @@ func recordsVisibleInContext(ctx context.Context, col *Collection) ([]Record, er
 	)
 	if epoch := epochFromContext(ctx); epoch != nil {
 		records, err = epoch.ListRecords(ctx, col.name)
+		if err == nil {
+			records = col.liveRecords(ctx, records)
+		}
 	} else if tx := transactionFromContext(ctx); tx != nil {
 		records, err = tx.visibleRecords(ctx, col.name)
 	} else {
@@ -128,6 +133,11 @@ func (e *Executor) executeTemporal(ctx context.Context, plan *optimizer.Physical
 		return e.executeMultiModalAtLSN(ctx, plan)
 	case plan.Kind == optimizer.QueryKindGraph:
 		return e.executeGraphAtLSN(ctx, plan)
+	case len(plan.GraphJoins) == 1 && plan.GraphJoins[0].Shortest:
+		if graphPlanHasPathProjection(plan) {
+			return e.executePathGraphJoin(ctx, plan)
+		}
+		return e.executeShortestGraphJoin(ctx, plan)
 	case len(plan.GraphJoins) > 0:
 		return e.executeGraphJoinAtLSN(ctx, plan)
 	case plan.Kind == optimizer.QueryKindRelational:
@@ -706,7 +716,9 @@ func (e *Executor) executeLiveScored(ctx context.Context, plan *optimizer.Physic
 	centralityMap := make(map[string]float64, len(candidates))
 	for id := range candidates {
 		if nodeID, err := e.db.GetNodeID(ctx, col.name, id); err == nil {
-			centralityMap[id] = col.graph.GraphCentrality(nodeID)
+			if centralityMap[id], err = col.graph.NodeCentrality(nodeID); err != nil {
+				return nil, err
+			}
 		}
 	}
 	distFn, _ := util.GetDistanceFunc(util.DistanceMetric(col.config.Metric))
@@ -748,12 +760,13 @@ func (e *Executor) executeMultiModalAtLSN(ctx context.Context, plan *optimizer.P
 			if nodeID, err := e.db.GetNodeID(ctx, col.name, id); err == nil {
 				// Temporal: use CentralityAtLSN for historical snapshots.
 				if snapshotLSN != 0 {
-					if g, ok := col.graph.(interface{ CentralityAtLSN(uint64, uint64) float64 }); ok {
-						centralityMap[id] = g.CentralityAtLSN(nodeID, snapshotLSN)
-						continue
-					}
+					centralityMap[id], err = col.graph.NodeCentralityAtLSN(nodeID, snapshotLSN)
+				} else {
+					centralityMap[id], err = col.graph.NodeCentrality(nodeID)
+				}
+				if err != nil {
+					return nil, err
 				}
-				centralityMap[id] = col.graph.GraphCentrality(nodeID)
 			}
 		}
 		distFn, _ := util.GetDistanceFunc(util.DistanceMetric(col.config.Metric))
@@ -1555,6 +1568,7 @@ func (e *Executor) executeKNN(ctx context.Context, plan *optimizer.PhysicalPlan)
 			results = filterByPredicates(results, plan.Predicates)
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyVector, len(results.Results))
 
 	return results, nil
 }
@@ -1593,6 +1607,7 @@ func (e *Executor) executeVectorProjection(ctx context.Context, plan *optimizer.
 		}
 		out.Results = append(out.Results, sr)
 	}
+	trackSQLOperatorRows(ctx, explainKeyVector, len(out.Results))
 	out.Total = len(out.Results)
 	out.Columns = plan.Projections
 	if plan.OrderBy != "" {
@@ -2078,6 +2093,7 @@ func (e *Executor) executeGraph(ctx context.Context, plan *optimizer.PhysicalPla
 			seeds = append(seeds, nodeID)
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(seeds))
 	if len(seeds) == 0 {
 		return nil, errors.New(
 			"graph query requires either WHERE a.id = N (explicit seed), " +
@@ -2314,6 +2330,7 @@ func (e *Executor) executeGraph(ctx context.Context, plan *optimizer.PhysicalPla
 			}
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyGraph, len(results.Results))
 	results.Total = len(results.Results)
 	return results, nil
 }
@@ -2332,6 +2349,9 @@ func (e *Executor) executeRelational(ctx context.Context, plan *optimizer.Physic
 		return e.executeRelationalFullScan(ctx, col, plan)
 	}
 	if len(plan.FTSPredicates) > 0 {
+		if results, ok, err := e.executeRelationalFullTextLookup(ctx, col, plan); ok || err != nil {
+			return results, err
+		}
 		return e.executeRelationalFullScan(ctx, col, plan)
 	}
 	if len(plan.PredicateAlternatives) > 0 {
@@ -2390,6 +2410,7 @@ func (e *Executor) executeRelational(ctx context.Context, plan *optimizer.Physic
 			ord, ver, _ := btree.DecodeValue(val)
 			results = append(results, &SearchResult{ID: string(key), Version: uint64(ver), Score: 1.0, Ordinal: ord})
 		}
+		trackSQLOperatorRows(ctx, explainKeyScan, len(results))
 		return e.buildSelectResults(ctx, col, results, plan), nil
 	}
 	for _, pred := range plan.Predicates {
@@ -2461,6 +2482,7 @@ func (e *Executor) executeRelational(ctx context.Context, plan *optimizer.Physic
 
 		advance()
 	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(results))
 
 	return e.buildSelectResults(ctx, col, results, plan), nil
 }
@@ -2557,11 +2579,13 @@ func (e *Executor) executeVectorOperatorSQL(ctx context.Context, plan *optimizer
 				records = append(records, record)
 			}
 		}
+		trackSQLOperatorRows(ctx, explainKeyVector, len(records))
 	} else {
 		records, err = recordsVisibleInContext(ctx, col)
 		if err != nil {
 			return nil, err
 		}
+		trackSQLOperatorRows(ctx, explainKeyScan, len(records))
 	}
 
 	type scoredRecord struct {
@@ -2579,6 +2603,7 @@ func (e *Executor) executeVectorOperatorSQL(ctx context.Context, plan *optimizer
 		}
 		scored = append(scored, scoredRecord{record: record, score: score})
 	}
+	trackSQLOperatorRows(ctx, explainKeyFilter, len(scored))
 	if plan.HasVectorOperatorOrder {
 		sort.SliceStable(scored, func(i, j int) bool {
 			if scored[i].score == scored[j].score {
@@ -2642,6 +2667,7 @@ func (e *Executor) buildSelectResults(ctx context.Context, col *Collection, resu
 	}
 	if plan.Distinct {
 		out.Results = distinctSearchResults(out.Results, plan.Projections)
+		trackSQLOperatorRows(ctx, explainKeyDistinct, len(out.Results))
 	}
 	out.Total = len(out.Results)
 	out.Columns = columns
@@ -2811,7 +2837,7 @@ func (e *Executor) attachMetadata(ctx context.Context, col *Collection, sr *Sear
 		}
 		for _, fts := range plan.FTSRankProjections {
 			if value, ok := recordMetadataValue(rec.Metadata, fts.TextColumn); ok && value != nil {
-				sr.Metadata[fts.Name] = ftsRankText(recordMetaToString(value), fts.TextQuery, "plain")
+				sr.Metadata[fts.Name] = col.fullTextRank(ctx, fts.TextColumn, recordMetaToString(value), fts.TextQuery)
 			} else {
 				sr.Metadata[fts.Name] = float64(0)
 			}
@@ -2994,7 +3020,8 @@ func (e *Executor) nextAutoIncrementID(ctx context.Context, table string, col *C
 	next, initialized := e.db.autoIncrementNext[key]
 	if !initialized {
 		next = 1
-		records, err := col.ListAll(ctx)
+		// Expired rows still hold their ids until the reaper deletes them.
+		records, err := col.ListAll(withExpiredRecords(ctx))
 		if err != nil {
 			return "", err
 		}
@@ -3084,6 +3111,11 @@ func (e *Executor) executeInsert(ctx context.Context, plan *optimizer.PhysicalPl
 	}
 	autoID := autoIncrementID(col)
 
+	// A generated vector column is always computed from its source text.
+	if generated := col.generatedVector(); generated != nil && containsFold(plan.InsertColumns, generated.Column) {
+		return nil, fmt.Errorf("cannot insert a non-DEFAULT value into column %q", generated.Column)
+	}
+
 	// Guardrail: metadata-only collections reject vector columns
 	if col.Dimension() == 0 {
 		for _, c := range plan.InsertColumns {
@@ -3713,30 +3745,47 @@ func evalConflictExpr(plan *optimizer.PhysicalPlan, root int32, current Record,
 			}
 			return time.Now().UTC().Format(time.RFC3339Nano), false, nil
 		}
-		if !strings.EqualFold(expr.Function, "NULLIF") {
-			return "", false, fmt.Errorf("unsupported function %q", expr.Function)
-		}
 		left, leftNull, err := evalConflictExpr(plan, expr.Left, current, proposed)
 		if err != nil {
 			return "", false, err
 		}
-		right, rightNull, err := evalConflictExpr(plan, expr.Right, current, proposed)
-		if err != nil {
-			return "", false, err
+		right, rightNull := "", true
+		if expr.Right >= 0 {
+			if right, rightNull, err = evalConflictExpr(plan, expr.Right, current, proposed); err != nil {
+				return "", false, err
+			}
 		}
-		if leftNull {
-			return "", true, nil
+		switch strings.ToUpper(expr.Function) {
+		case "NULLIF":
+			if leftNull {
+				return "", true, nil
+			}
+			if !rightNull && left == right {
+				return "", true, nil
+			}
+			return left, false, nil
+		case "COALESCE":
+			if !leftNull {
+				return left, false, nil
+			}
+			return right, rightNull, nil
 		}
-		if !rightNull && left == right {
+		// The remaining scalar functions are strict: NULL in, NULL out.
+		if leftNull || expr.Right >= 0 && rightNull {
 			return "", true, nil
 		}
-		return left, false, nil
+		return evalConflictScalarFunction(expr.Function, left, right, expr.Right >= 0)
 	case optimizer.ConflictExprCast:
 		value, isNull, err := evalConflictExpr(plan, expr.Left, current, proposed)
 		if err != nil || isNull {
 			return value, isNull, err
 		}
 		typ := strings.ToLower(strings.TrimSpace(expr.Type))
+		// Type modifiers such as numeric(10,2) or varchar(20) do not change
+		// how a value is converted.
+		if open := strings.IndexByte(typ, '('); open >= 0 {
+			typ = strings.TrimSpace(typ[:open])
+		}
 		switch typ {
 		case "text", "varchar", "character varying", "char", "string", "json", "jsonb":
 			return value, false, nil
@@ -3933,6 +3982,61 @@ func evalConflictBinary(left, right string, operator uint8) (string, bool, error
 	return strconv.FormatFloat(value, 'f', -1, 64), false, nil
 }
 
+// evalConflictScalarFunction evaluates the single-value scalar functions
+// accepted in assignment expressions. Numbers keep the integer/float text
+// form evalConflictBinary produces.
+func evalConflictScalarFunction(name, arg, second string, hasSecond bool) (string, bool, error) {
+	switch strings.ToUpper(name) {
+	case "LOWER":
+		return strings.ToLower(arg), false, nil
+	case "UPPER":
+		return strings.ToUpper(arg), false, nil
+	case "TRIM":
+		return strings.TrimSpace(arg), false, nil
+	case "LENGTH":
+		return strconv.Itoa(utf8.RuneCountInString(arg)), false, nil
+	}
+	trimmed := strings.TrimSpace(arg)
+	if n, err := strconv.ParseInt(trimmed, 10, 64); err == nil {
+		switch strings.ToUpper(name) {
+		case "ABS":
+			if n == math.MinInt64 {
+				return "", false, fmt.Errorf("integer out of range")
+			}
+			if n < 0 {
+				n = -n
+			}
+			return strconv.FormatInt(n, 10), false, nil
+		case "FLOOR", "CEIL", "CEILING":
+			return strconv.FormatInt(n, 10), false, nil
+		}
+	}
+	f, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil {
+		return "", false, fmt.Errorf("function %s requires a numeric argument, got %q", strings.ToLower(name), arg)
+	}
+	switch strings.ToUpper(name) {
+	case "ABS":
+		f = math.Abs(f)
+	case "FLOOR":
+		f = math.Floor(f)
+	case "CEIL", "CEILING":
+		f = math.Ceil(f)
+	case "ROUND":
+		places := int64(0)
+		if hasSecond {
+			if places, err = strconv.ParseInt(strings.TrimSpace(second), 10, 64); err != nil {
+				return "", false, fmt.Errorf("round precision must be an integer, got %q", second)
+			}
+		}
+		pow := math.Pow10(int(places))
+		f = math.Round(f*pow) / pow
+	default:
+		return "", false, fmt.Errorf("unsupported function %q", name)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), false, nil
+}
+
 func formatConflictVector(vector []float32) string {
 	var b strings.Builder
 	b.WriteByte('[')
@@ -4064,11 +4168,13 @@ func (e *Executor) executeAggregate(ctx context.Context, plan *optimizer.Physica
 	}
 	// COUNT(*) over the live committed collection is maintained by storage as
 	// LiveCount. Use it when there is no filter, grouping, or DISTINCT modifier
-	// instead of cloning every record merely to count it.
+	// instead of cloning every record merely to count it. LiveCount includes
+	// expired rows the reaper has not reached, so TTL collections count rows.
 	if plan.AggregateFunc == 0 && plan.AggregateColumn == "" && !plan.AggregateDistinct &&
 		len(plan.Predicates) == 0 && len(plan.PredicateAlternatives) == 0 && len(plan.FTSPredicates) == 0 &&
 		len(plan.GroupByColumns) == 0 && !plan.HavingAggregate && plan.HavingExpr == "" &&
-		epochFromContext(ctx) == nil && transactionFromContext(ctx) == nil && plan.SnapshotLSN == 0 {
+		epochFromContext(ctx) == nil && transactionFromContext(ctx) == nil && plan.SnapshotLSN == 0 &&
+		col.ttlField() == "" {
 		count, err := col.Count(ctx)
 		if err != nil {
 			return nil, err
@@ -4574,11 +4680,29 @@ func sqlTypeToFieldType(sqlType string) (FieldType, bool) {
 		return JSONField, true
 	case "JSONB":
 		return JSONBField, true
+	case "SPARSEVEC":
+		// Sparse vectors are stored in their canonical sparsevec text form;
+		// the declared dimension is carried by WithSparseVector.
+		return StringField, true
 	default:
 		return StringField, false
 	}
 }
 
+// sparseVectorColumnDimension extracts n from a SPARSEVEC(n) column type.
+func sparseVectorColumnDimension(column, sqlType string) (int, error) {
+	open := strings.IndexByte(sqlType, '(')
+	end := strings.LastIndexByte(sqlType, ')')
+	if open < 0 || end < open {
+		return 0, fmt.Errorf("SPARSEVEC column %q requires a dimension, e.g. SPARSEVEC(30522)", column)
+	}
+	dimension, err := strconv.Atoi(strings.TrimSpace(sqlType[open+1 : end]))
+	if err != nil || dimension <= 0 {
+		return 0, fmt.Errorf("SPARSEVEC column %q has invalid dimension %q", column, strings.TrimSpace(sqlType[open+1:end]))
+	}
+	return dimension, nil
+}
+
 func sqlBaseTypeName(sqlType string) string {
 	typeName := strings.ToUpper(strings.TrimSpace(sqlType))
 	if paramStart := strings.IndexByte(typeName, '('); paramStart >= 0 {
@@ -4607,6 +4731,32 @@ func catalogTypeToFieldType(typ uint16) FieldType {
 	}
 }
 
+// foreignKeyReferrers lists the tables whose foreign keys reference table,
+// which keep it from being dropped.
+func (db *Database) foreignKeyReferrers(table string) []string {
+	tableHash := catalog.HashIdentifier(table)
+	db.mu.RLock()
+	fks := db.catalog.ForeignKeysToTable(tableHash)
+	collections := db.collections
+	db.mu.RUnlock()
+	if len(fks) == 0 {
+		return nil
+	}
+	refs := make(map[string]bool, len(fks))
+	for _, fk := range fks {
+		for name := range collections {
+			if catalog.HashIdentifier(name) == fk.SourceTableHash {
+				refs[name] = true
+			}
+		}
+	}
+	refNames := make([]string, 0, len(refs))
+	for n := range refs {
+		refNames = append(refNames, n)
+	}
+	return refNames
+}
+
 // executeDDL handles CREATE TABLE, DROP TABLE, CREATE INDEX.
 func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan) (*SearchResults, error) {
 	switch plan.DDLKind {
@@ -4623,6 +4773,8 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 		var schema MetadataSchema
 		var vectorCount int
 		var vectorColumnName string
+		var vectorDimension int
+		var sparseColumns map[string]int
 		primaryKeyColumns := append([]string(nil), plan.DDLPrimaryKeyColumns...)
 		columnConstraints := map[string]uint16{
 			"id": catalog.ColFlagPrimaryKey | catalog.ColFlagNotNull,
@@ -4642,7 +4794,8 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 						plan.DDLTableName)
 				}
 				vectorColumnName = col.Name
-				opts = []CollectionOption{WithDimension(int(col.VectorDimension))}
+				vectorDimension = int(col.VectorDimension)
+				opts = []CollectionOption{WithDimension(vectorDimension)}
 				continue
 			}
 			// Reject bare VECTOR without a dimension.
@@ -4673,6 +4826,16 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 			if ft, ok := sqlTypeToFieldType(col.Type); ok {
 				schema[col.Name] = ft
 			}
+			if sqlBaseTypeName(col.Type) == "SPARSEVEC" {
+				dimension, err := sparseVectorColumnDimension(col.Name, col.Type)
+				if err != nil {
+					return nil, err
+				}
+				if sparseColumns == nil {
+					sparseColumns = make(map[string]int)
+				}
+				sparseColumns[col.Name] = dimension
+			}
 		}
 		if len(primaryKeyColumns) > 0 {
 			seenPK := make(map[string]struct{}, len(primaryKeyColumns))
@@ -4704,6 +4867,9 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 		if len(schema) > 0 {
 			opts = append(opts, WithMetadataSchema(schema))
 		}
+		for name, dimension := range sparseColumns {
+			opts = append(opts, WithSparseVector(name, dimension))
+		}
 		if len(columnConstraints) > 0 {
 			opts = append(opts, WithColumnConstraints(columnConstraints))
 		}
@@ -4839,6 +5005,20 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 				}
 			}
 		}
+		if generated := plan.DDLGeneratedVector; generated != nil {
+			if !strings.EqualFold(generated.Column, vectorColumnName) {
+				return nil, fmt.Errorf("generated column %q must be the table's VECTOR column", generated.Column)
+			}
+			embedder, err := e.db.embedder(generated.Model)
+			if err != nil {
+				return nil, err
+			}
+			if dimension := embedder.Dimension(); dimension != vectorDimension {
+				return nil, fmt.Errorf("embedding model %q produces %d-dimensional vectors, but column %q is VECTOR(%d)",
+					generated.Model, dimension, vectorColumnName, vectorDimension)
+			}
+			opts = append(opts, WithGeneratedVector(vectorColumnName, generated.Expression, generated.Model))
+		}
 		if graphLayer != nil {
 			opts = append(opts, WithGraph(graphLayer))
 			opts = append(opts, withGraphNamespace(defaultGraphNamespace))
@@ -4868,28 +5048,10 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 		return &SearchResults{}, nil
 
 	case 1: // DROP TABLE
-		tableHash := catalog.HashIdentifier(plan.DDLTableName)
-		e.db.mu.RLock()
-		fks := e.db.catalog.ForeignKeysToTable(tableHash)
-		collections := e.db.collections
-		e.db.mu.RUnlock()
-		if len(fks) > 0 {
-			// Build a list of referencing tables for the error.
-			refs := make(map[string]bool, len(fks))
-			for _, fk := range fks {
-				for name := range collections {
-					if catalog.HashIdentifier(name) == fk.SourceTableHash {
-						refs[name] = true
-					}
-				}
-			}
-			refNames := make([]string, 0, len(refs))
-			for n := range refs {
-				refNames = append(refNames, n)
-			}
+		if refs := e.db.foreignKeyReferrers(plan.DDLTableName); len(refs) > 0 {
 			return nil, fmt.Errorf(
 				"cannot drop table %q: foreign key constraints in %v reference it",
-				plan.DDLTableName, refNames)
+				plan.DDLTableName, refs)
 		}
 		if err := e.db.DeleteCollection(ctx, plan.DDLTableName); err != nil {
 			return nil, err
@@ -4904,6 +5066,16 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 			}
 			return nil, fmt.Errorf("CREATE INDEX: table %q not found", plan.DDLTableName)
 		}
+		switch plan.DDLIndexMethod {
+		case "", "btree", "hash":
+		case "fts":
+			if err := e.persistFullTextIndex(ctx, col, plan); err != nil {
+				return nil, err
+			}
+			return &SearchResults{}, nil
+		default:
+			return nil, fmt.Errorf("CREATE INDEX: unsupported index method %q", plan.DDLIndexMethod)
+		}
 		if plan.DDLUnique {
 			columns := append([]string(nil), plan.DDLIndexColumns...)
 			if len(columns) == 0 && plan.DDLColName != "" {
@@ -5041,41 +5213,29 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 					e.db.registerCollectionInCatalog(collectionName, &cfg)
 				}
 
-				filteredSQL := cfg.SQLIndexes[:0]
-				removedSQL := false
-				for _, index := range cfg.SQLIndexes {
+				filteredFTS := make([]FullTextIndexDefinition, 0, len(cfg.FullTextIndexes))
+				removedFTS := false
+				for _, index := range cfg.FullTextIndexes {
 					if strings.EqualFold(index.Name, plan.DDLIndexName) {
-						removedSQL = true
+						removedFTS = true
 						continue
 					}
-					filteredSQL = append(filteredSQL, index)
-				}
-				if removedSQL {
-					cfg.SQLIndexes = cloneSQLIndexDefinitions(filteredSQL)
-					remainingFields := make(map[string]struct{})
-					for _, index := range cfg.SQLIndexes {
-						for _, column := range index.Columns {
-							remainingFields[strings.ToLower(column)] = struct{}{}
-						}
-					}
-					for _, sqlField := range cfg.SQLIndexedFields {
-						if _, stillUsed := remainingFields[strings.ToLower(sqlField)]; stillUsed {
-							continue
-						}
-						for i, indexed := range cfg.IndexedFields {
-							if strings.EqualFold(indexed, sqlField) {
-								cfg.IndexedFields = append(cfg.IndexedFields[:i], cfg.IndexedFields[i+1:]...)
-								break
-							}
-						}
-					}
-					filteredSQLFields := cfg.SQLIndexedFields[:0]
-					for _, field := range cfg.SQLIndexedFields {
-						if _, stillUsed := remainingFields[strings.ToLower(field)]; stillUsed {
-							filteredSQLFields = append(filteredSQLFields, field)
+					filteredFTS = append(filteredFTS, index)
+				}
+				if removedFTS {
+					if stored != nil && hasUpdater {
+						stored.FullTextIndexes = fullTextIndexesToStorage(filteredFTS)
+						if err := storedUpdater.UpdateCollectionConfig(ctx, collectionName, stored); err != nil {
+							return nil, err
 						}
 					}
-					cfg.SQLIndexedFields = append([]string(nil), filteredSQLFields...)
+					col.mu.Lock()
+					col.config.FullTextIndexes = filteredFTS
+					col.mu.Unlock()
+					col.resetFullTextIndexes()
+				}
+
+				if removeSQLIndexDefinition(&cfg, plan.DDLIndexName) {
 					if stored != nil && hasUpdater {
 						stored.SQLIndexes = make([]storage.SQLIndexDefinition, len(cfg.SQLIndexes))
 						for i, index := range cfg.SQLIndexes {
@@ -5102,7 +5262,10 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 		}
 		return &SearchResults{}, nil
 
-	case 4: // ALTER TABLE ADD/DROP COLUMN
+	case 4: // ALTER TABLE
+		if len(plan.DDLAlterActions) > 0 {
+			return e.db.executeAlterTableActions(ctx, plan)
+		}
 		col, err := e.db.GetCollection(plan.DDLTableName)
 		if err != nil {
 			return nil, fmt.Errorf("ALTER TABLE: table %q not found", plan.DDLTableName)
@@ -5228,11 +5391,58 @@ func (e *Executor) executeDDL(ctx context.Context, plan *optimizer.PhysicalPlan)
 		e.db.registerCollectionInCatalog(plan.DDLTableName, &updatedCfg)
 		return &SearchResults{}, nil
 
+	case 6, 7, 8: // CREATE VIEW, DROP VIEW, REFRESH MATERIALIZED VIEW
+		return e.db.executeViewDDL(ctx, plan)
+
 	default:
 		return nil, fmt.Errorf("unknown DDL kind %d", plan.DDLKind)
 	}
 }
 
+// removeSQLIndexDefinition deletes the SQL index declaration called name
+// from cfg, together with the metadata postings no remaining SQL index
+// needs. It reports whether a declaration was removed.
+func removeSQLIndexDefinition(cfg *CollectionConfig, name string) bool {
+	filteredSQL := cfg.SQLIndexes[:0]
+	removedSQL := false
+	for _, index := range cfg.SQLIndexes {
+		if strings.EqualFold(index.Name, name) {
+			removedSQL = true
+			continue
+		}
+		filteredSQL = append(filteredSQL, index)
+	}
+	if !removedSQL {
+		return false
+	}
+	cfg.SQLIndexes = cloneSQLIndexDefinitions(filteredSQL)
+	remainingFields := make(map[string]struct{})
+	for _, index := range cfg.SQLIndexes {
+		for _, column := range index.Columns {
+			remainingFields[strings.ToLower(column)] = struct{}{}
+		}
+	}
+	for _, sqlField := range cfg.SQLIndexedFields {
+		if _, stillUsed := remainingFields[strings.ToLower(sqlField)]; stillUsed {
+			continue
+		}
+		for i, indexed := range cfg.IndexedFields {
+			if strings.EqualFold(indexed, sqlField) {
+				cfg.IndexedFields = append(cfg.IndexedFields[:i], cfg.IndexedFields[i+1:]...)
+				break
+			}
+		}
+	}
+	filteredSQLFields := cfg.SQLIndexedFields[:0]
+	for _, field := range cfg.SQLIndexedFields {
+		if _, stillUsed := remainingFields[strings.ToLower(field)]; stillUsed {
+			filteredSQLFields = append(filteredSQLFields, field)
+		}
+	}
+	cfg.SQLIndexedFields = append([]string(nil), filteredSQLFields...)
+	return true
+}
+
 // persistSQLIndexFields routes ordinary SQL secondary-index declarations to
 // the same durable metadata posting machinery used by WithIndexedFields. The
 // posting lists remain derived state; only the declaration is WAL-persisted.
@@ -5314,6 +5524,83 @@ func (e *Executor) persistSQLIndexFields(ctx context.Context, col *Collection, i
 	return nil
 }
 
+// persistFullTextIndex records a CREATE INDEX ... USING fts declaration in
+// the durable collection config. WITH (config, k1, b) override the defaults.
+// The postings are built from the table's rows on first use.
+func (e *Executor) persistFullTextIndex(ctx context.Context, col *Collection, plan *optimizer.PhysicalPlan) error {
+	columns := plan.DDLIndexColumns
+	if len(columns) == 0 && plan.DDLColName != "" {
+		columns = []string{plan.DDLColName}
+	}
+	if len(columns) != 1 {
+		return fmt.Errorf("CREATE INDEX: a full-text index covers exactly one column")
+	}
+	if plan.DDLUnique {
+		return fmt.Errorf("CREATE INDEX: a full-text index cannot be UNIQUE")
+	}
+	if !e.sqlCollectionColumnExists(col.name, columns[0]) {
+		return fmt.Errorf("CREATE INDEX: column %q does not exist on table %q", columns[0], col.name)
+	}
+	definition := FullTextIndexDefinition{Name: plan.DDLIndexName, Field: columns[0], K1: defaultBM25K1, B: defaultBM25B}
+	for name, value := range plan.DDLIndexOptions {
+		switch name {
+		case "config":
+			definition.Config = value
+		case "k1", "b":
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("CREATE INDEX: invalid %s value %q", name, value)
+			}
+			if name == "k1" {
+				definition.K1 = parsed
+			} else {
+				definition.B = parsed
+			}
+		default:
+			return fmt.Errorf("CREATE INDEX: unsupported full-text index parameter %q", name)
+		}
+	}
+	if definition.K1 == 0 {
+		return fmt.Errorf("CREATE INDEX: k1 must be positive")
+	}
+	definition, err := normalizeFullTextIndexDefinition(definition)
+	if err != nil {
+		return fmt.Errorf("CREATE INDEX: %w", err)
+	}
+
+	reader, ok := e.db.storage.(interface {
+		GetCollectionWithConfig(name string) (storage.Collection, *storage.CollectionConfig, error)
+	})
+	updater, okUpdater := e.db.storage.(storage.CollectionConfigStore)
+	if !ok || !okUpdater {
+		return fmt.Errorf("CREATE INDEX: storage engine does not support durable collection declarations")
+	}
+	_, stored, err := reader.GetCollectionWithConfig(col.name)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return fmt.Errorf("CREATE INDEX: collection %q has no persisted configuration", col.name)
+	}
+	for _, existing := range fullTextIndexesFromStorage(stored.FullTextIndexes) {
+		if strings.EqualFold(existing.Name, definition.Name) {
+			if existing != definition {
+				return fmt.Errorf("CREATE INDEX %q already exists with a different definition", definition.Name)
+			}
+			return nil
+		}
+	}
+	stored.FullTextIndexes = append(stored.FullTextIndexes, fullTextIndexesToStorage([]FullTextIndexDefinition{definition})...)
+	if err := updater.UpdateCollectionConfig(ctx, col.name, stored); err != nil {
+		return err
+	}
+	col.mu.Lock()
+	col.config.FullTextIndexes = fullTextIndexesFromStorage(stored.FullTextIndexes)
+	col.mu.Unlock()
+	col.resetFullTextIndexes()
+	return nil
+}
+
 func (e *Executor) sqlCollectionColumnExists(table, column string) bool {
 	e.db.mu.RLock()
 	cat := e.db.catalog
@@ -5395,6 +5682,11 @@ func (e *Executor) executeUpdate(ctx context.Context, plan *optimizer.PhysicalPl
 // jsonb_typeof(payload->'career') = 'string' that require expression-aware
 // row evaluation instead of the column-predicate fast path.
 func (e *Executor) executeUpdateRows(ctx context.Context, plan *optimizer.PhysicalPlan, results *SearchResults) (*SearchResults, error) {
+	if col, err := e.db.GetCollection(plan.CollectionName); err == nil {
+		if generated := col.generatedVector(); generated != nil && containsFold(plan.SetColumns, generated.Column) {
+			return nil, fmt.Errorf("column %q can only be updated to DEFAULT", generated.Column)
+		}
+	}
 	if len(results.Results) == 0 {
 		if hasReturning(plan) {
 			return materializeReturning(plan, nil), nil
@@ -5821,9 +6113,80 @@ func (e *Executor) executeRelationalFullScan(ctx context.Context, col *Collectio
 		}
 		results = append(results, &SearchResult{ID: rec.ID, Score: 1.0, Metadata: rec.Metadata, Ordinal: rec.Ordinal})
 	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(records))
+	trackSQLOperatorRows(ctx, explainKeyFilter, len(results))
 	return e.buildSelectResults(ctx, col, results, plan), nil
 }
 
+// executeRelationalFullTextLookup answers @@ predicates over columns with a
+// full-text index from the posting lists instead of scanning every row.
+// Candidates are rechecked against the exact scan-time predicate, so the
+// result set is identical to the full scan. ok is false when no predicate can
+// use an index or the context reads an uncommitted overlay the postings do
+// not describe.
+func (e *Executor) executeRelationalFullTextLookup(ctx context.Context, col *Collection, plan *optimizer.PhysicalPlan) (*SearchResults, bool, error) {
+	if plan.SnapshotLSN != 0 || transactionFromContext(ctx) != nil {
+		return nil, false, nil
+	}
+	var candidates map[string]struct{}
+	for _, predicate := range plan.FTSPredicates {
+		if predicate.Column == "" {
+			continue
+		}
+		index := col.fullTextIndexFor(predicate.Column, predicate.Config)
+		if index == nil {
+			continue
+		}
+		if err := index.ensureBuilt(ctx, col); err != nil {
+			return nil, true, err
+		}
+		ids := index.lookup(parseFTSQueryConfig(predicate.Query, predicate.QueryMode, index.definition.Config))
+		trackSQLIndexHit(ctx, 1)
+		next := make(map[string]struct{}, len(ids))
+		for _, id := range ids {
+			if candidates == nil {
+				next[id] = struct{}{}
+			} else if _, ok := candidates[id]; ok {
+				next[id] = struct{}{}
+			}
+		}
+		candidates = next
+	}
+	if candidates == nil {
+		return nil, false, nil
+	}
+
+	records := make([]Record, 0, len(candidates))
+	for id := range candidates {
+		record, err := col.Get(ctx, id)
+		if err != nil {
+			if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			return nil, true, err
+		}
+		records = append(records, record)
+	}
+	trackSQLRowsExamined(ctx, len(records))
+	// Match the full scan's ordinal order for queries without ORDER BY.
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Ordinal != records[j].Ordinal {
+			return records[i].Ordinal < records[j].Ordinal
+		}
+		return records[i].ID < records[j].ID
+	})
+	var results []*SearchResult
+	for _, rec := range records {
+		if !planMatchesRecord(plan, rec) || !recordMatchesFTSPredicates(rec, plan.FTSPredicates) {
+			continue
+		}
+		results = append(results, &SearchResult{ID: rec.ID, Score: 1.0, Metadata: rec.Metadata, Ordinal: rec.Ordinal})
+	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(records))
+	trackSQLOperatorRows(ctx, explainKeyFilter, len(results))
+	return e.buildSelectResults(ctx, col, results, plan), true, nil
+}
+
 func (e *Executor) executeJoin(ctx context.Context, plan *optimizer.PhysicalPlan) (*SearchResults, error) {
 	// Graph joins (JOIN MATCH) take precedence: every row of the left
 	// collection seeds a BFS traversal over the match-path edges.
@@ -5846,13 +6209,14 @@ func (e *Executor) executeJoin(ctx context.Context, plan *optimizer.PhysicalPlan
 	if len(plan.Joins) > 0 && plan.Joins[0].LeftAlias != "" {
 		leftAlias = plan.Joins[0].LeftAlias
 	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(leftRecords))
 	rows := make([]sqlJoinRow, 0, len(leftRecords))
 	for i := range leftRecords {
 		record := &leftRecords[i]
 		rows = append(rows, sqlJoinRow{Sources: map[string]*Record{leftAlias: record}, Schemas: map[string][]string{leftAlias: collectionColumns(leftCol)}, BaseAlias: leftAlias})
 	}
 
-	for _, join := range plan.Joins {
+	for i, join := range plan.Joins {
 		rightCol, err := e.db.GetCollection(join.CollectionName)
 		if err != nil {
 			return nil, err
@@ -5861,7 +6225,9 @@ func (e *Executor) executeJoin(ctx context.Context, plan *optimizer.PhysicalPlan
 		if err != nil {
 			return nil, err
 		}
+		trackSQLOperatorRows(ctx, explainOperatorKey(explainKeyScan, i+1), len(rightRecords))
 		rows = applyRelationalJoin(rows, rightRecords, collectionColumns(rightCol), join)
+		trackSQLOperatorRows(ctx, explainOperatorKey(explainKeyJoin, i), len(rows))
 	}
 
 	results := make([]*SearchResult, 0, len(rows))
@@ -5881,9 +6247,11 @@ func (e *Executor) executeJoin(ctx context.Context, plan *optimizer.PhysicalPlan
 		}
 		results = append(results, result)
 	}
+	trackSQLOperatorRows(ctx, explainKeyFilter, len(results))
 	out := &SearchResults{Results: results, Total: len(results), Columns: plan.Projections}
 	if plan.Distinct {
 		out.Results = distinctSearchResults(out.Results, plan.Projections)
+		trackSQLOperatorRows(ctx, explainKeyDistinct, len(out.Results))
 	}
 	if plan.OrderBy != "" {
 		e.applyOrderBy(out, plan)
@@ -6381,6 +6749,9 @@ func (e *Executor) executeGraphJoin(ctx context.Context, plan *optimizer.Physica
 	}
 	// Epoch guard: route JOIN MATCH through epoch-aware path when inside an epoch.
 	if epoch := epochFromContext(ctx); epoch != nil {
+		if graphPlanHasWeightedPath(plan) {
+			return nil, fmt.Errorf("weightedShortestPath is not supported inside an epoch transaction")
+		}
 		if graphPlanHasPathProjection(plan) {
 			return e.executePathGraphJoinEpoch(ctx, plan, epoch)
 		}
@@ -6444,6 +6815,7 @@ func (e *Executor) executeGraphJoin(ctx context.Context, plan *optimizer.Physica
 	if err != nil {
 		return nil, err
 	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(leftRecords))
 	recordsByID := make(map[string]Record, len(leftRecords))
 	for i := range leftRecords {
 		recordsByID[leftRecords[i].ID] = leftRecords[i]
@@ -6581,6 +6953,7 @@ func (e *Executor) executeGraphJoin(ctx context.Context, plan *optimizer.Physica
 			break
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyGraph, len(results))
 	out := &SearchResults{Results: results, Total: len(results), Columns: plan.Projections, ColumnTypes: graphProjectionColumnTypes(plan)}
 	if plan.Distinct {
 		out.Results = distinctSearchResults(out.Results, plan.Projections)
@@ -6612,7 +6985,7 @@ func (e *Executor) executeShortestGraphJoin(ctx context.Context, plan *optimizer
 	if g == nil {
 		return nil, fmt.Errorf("collection %q has no graph", plan.CollectionName)
 	}
-	records, err := recordsVisibleInContext(ctx, col)
+	records, neighbors, err := graphJoinPathSource(ctx, col, g, plan.SnapshotLSN)
 	if err != nil {
 		return nil, err
 	}
@@ -6626,6 +6999,7 @@ func (e *Executor) executeShortestGraphJoin(ctx context.Context, plan *optimizer
 	}
 	sourcePredicates := graphJoinSourcePredicates(plan.Predicates, join, plan.CollectionName)
 	terminalPredicates := graphJoinTerminalPredicates(plan.Predicates, join)
+	target := e.weightedPathTargetFor(ctx, plan, join, g, records, recordsByID)
 	results := make([]*SearchResult, 0)
 	for _, source := range records {
 		if len(sourcePredicates) > 0 && !recordMatchesPredicatesTracked(ctx, source, sourcePredicates) {
@@ -6635,9 +7009,7 @@ func (e *Executor) executeShortestGraphJoin(ctx context.Context, plan *optimizer
 		if lookupErr != nil || !graphLabelsMatch(g, sourceNode, join.SeedLabels) {
 			continue
 		}
-		states, pathErr := collectGraphJoinPaths(sourceNode, edges, join.MaxHops, func(nodeID uint64, direction int8) ([]graph.EdgeView, error) {
-			return graphPatternNeighbors(g, nodeID, direction)
-		})
+		states, pathErr := graphJoinPaths(join, sourceNode, edges, neighbors, target)
 		if pathErr != nil {
 			return nil, pathErr
 		}
@@ -6686,6 +7058,9 @@ func (e *Executor) executeGraphPatternComprehension(ctx context.Context, plan *o
 	if len(plan.GraphJoins) == 0 {
 		return nil, fmt.Errorf("pattern comprehension requires a MATCH source")
 	}
+	if graphPlanHasWeightedPath(plan) {
+		return nil, fmt.Errorf("weightedShortestPath must bind a MATCH path, e.g. MATCH p = weightedShortestPath(...)")
+	}
 	join := plan.GraphJoins[0]
 	col, err := e.db.GetCollection(plan.CollectionName)
 	if err != nil {
@@ -6868,6 +7243,7 @@ func (e *Executor) executeCommonNeighborGraphJoin(ctx context.Context, plan *opt
 	if err != nil {
 		return nil, err
 	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(records))
 	if len(records) == 0 {
 		return materializeChainedGraphJoinRows(nil, plan), nil
 	}
@@ -6957,6 +7333,7 @@ func (e *Executor) executeCommonNeighborGraphJoin(ctx context.Context, plan *opt
 			}
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyGraph, len(rows))
 	return materializeChainedGraphJoinRows(rows, plan), nil
 }
 
@@ -7414,6 +7791,7 @@ func (e *Executor) executeCommonNeighborGraphJoinAtLSN(ctx context.Context, plan
 			}
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyGraph, len(rows))
 	return materializeChainedGraphJoinRows(rows, plan), nil
 }
 
@@ -7510,6 +7888,7 @@ func (e *Executor) executeCommonNeighborGraphJoinEpoch(ctx context.Context, plan
 			}
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyGraph, len(rows))
 	return materializeChainedGraphJoinRows(rows, plan), nil
 }
 
@@ -7620,6 +7999,7 @@ func (e *Executor) executeChainedGraphJoin(ctx context.Context, plan *optimizer.
 	if err != nil {
 		return nil, err
 	}
+	trackSQLOperatorRows(ctx, explainKeyScan, len(leftRecords))
 
 	rows := make([]chainedGraphJoinRow, 0, len(leftRecords))
 	first := plan.GraphJoins[0]
@@ -7729,6 +8109,7 @@ func (e *Executor) executeChainedGraphJoin(ctx context.Context, plan *optimizer.
 		}
 	}
 
+	trackSQLOperatorRows(ctx, explainKeyGraph, len(rows))
 	return materializeChainedGraphJoinRows(rows, plan), nil
 }
 
@@ -7881,6 +8262,7 @@ func (e *Executor) executeChainedGraphJoinEpoch(ctx context.Context, plan *optim
 			return &SearchResults{}, nil
 		}
 	}
+	trackSQLOperatorRows(ctx, explainKeyGraph, len(rows))
 	return materializeChainedGraphJoinRows(rows, plan), nil
 }
 
@@ -8125,6 +8507,7 @@ type graphPathTraversalState struct {
 	step  int
 	nodes []uint64
 	edges []graph.EdgeView
+	cost  float64
 }
 
 type graphPathNeighborFunc func(nodeID uint64, direction int8) ([]graph.EdgeView, error)
@@ -8145,6 +8528,20 @@ func graphPlanHasPathProjection(plan *optimizer.PhysicalPlan) bool {
 	return false
 }
 
+// graphPlanHasWeightedPath reports whether one of plan's graph joins was
+// compiled from weightedShortestPath.
+func graphPlanHasWeightedPath(plan *optimizer.PhysicalPlan) bool {
+	if plan == nil {
+		return false
+	}
+	for _, join := range plan.GraphJoins {
+		if join.Weighted != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // collectGraphJoinPaths is the path-preserving counterpart to BFSPattern. It
 // uses the same band transition rules and per-(node, band, step) cycle guard,
 // but keeps the first deterministic predecessor chain for each terminal. The
@@ -8229,6 +8626,7 @@ func graphPathToPublic(ctx context.Context, db *Database, state graphPathTravers
 		Nodes:       make([]string, 0, len(state.nodes)),
 		EdgeTypes:   make([]string, 0, len(state.edges)),
 		EdgeWeights: make([]float32, 0, len(state.edges)),
+		Cost:        state.cost,
 	}
 	for _, nodeID := range state.nodes {
 		_, recordID, err := db.ResolveNodeID(ctx, nodeID)
@@ -8274,7 +8672,7 @@ func (e *Executor) executePathGraphJoin(ctx context.Context, plan *optimizer.Phy
 	for i, edge := range join.GraphEdges {
 		edges[i] = graphEdgePlanForTraversal(edge)
 	}
-	records, err := recordsVisibleInContext(ctx, leftCol)
+	records, neighbors, err := graphJoinPathSource(ctx, leftCol, g, plan.SnapshotLSN)
 	if err != nil {
 		return nil, err
 	}
@@ -8285,6 +8683,7 @@ func (e *Executor) executePathGraphJoin(ctx context.Context, plan *optimizer.Phy
 	allowedLabels := graphJoinTerminalLabelSet(g, join)
 	sourcePredicates := graphJoinSourcePredicates(plan.Predicates, join, plan.CollectionName)
 	terminalPredicates := graphJoinTerminalPredicates(plan.Predicates, join)
+	target := e.weightedPathTargetFor(ctx, plan, join, g, records, recordsByID)
 	results := make([]*SearchResult, 0)
 	for _, source := range records {
 		if len(sourcePredicates) > 0 && !recordMatchesPredicatesTracked(ctx, source, sourcePredicates) {
@@ -8300,9 +8699,7 @@ func (e *Executor) executePathGraphJoin(ctx context.Context, plan *optimizer.Phy
 			}
 			continue
 		}
-		states, err := collectGraphJoinPaths(sourceNode, edges, join.MaxHops, func(nodeID uint64, direction int8) ([]graph.EdgeView, error) {
-			return graphPatternNeighbors(g, nodeID, direction)
-		})
+		states, err := graphJoinPaths(join, sourceNode, edges, neighbors, target)
 		if err != nil {
 			return nil, err
 		}
@@ -8998,7 +9395,9 @@ func (e *Executor) materializeSystemTableRows(ctx context.Context, tableName str
 		return e.materializePgNamespace(ctx)
 	case "pg_indexes":
 		return e.materializePgIndexes(ctx)
-	case "pg_range", "pg_proc", "pg_constraint", "pg_index", "pg_attrdef":
+	case "pg_proc":
+		return e.materializePgProc(ctx)
+	case "pg_range", "pg_constraint", "pg_index", "pg_attrdef":
 		return []*SearchResult{}, nil
 	case "graph_nodes":
 		return e.materializeGraphNodes(ctx)
@@ -9007,18 +9406,24 @@ func (e *Executor) materializeSystemTableRows(ctx context.Context, tableName str
 	}
 }
 
-// materializePgClass returns one row per real user collection.
+// materializePgClass returns one row per real user collection and one per
+// view. Materialized views are collections reported with relkind "m".
 func (e *Executor) materializePgClass(ctx context.Context) ([]*SearchResult, error) {
 	names, err := e.db.ListCollectionsWithContext(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("pg_class: listing collections: %w", err)
 	}
-	rows := make([]*SearchResult, 0, len(names))
+	views := e.db.ListViews()
+	rows := make([]*SearchResult, 0, len(names)+len(views))
 	for i, name := range names {
 		var rowCount int64
 		if col, colErr := e.db.GetCollection(name); colErr == nil {
 			rowCount = int64(col.Stats(ctx).LiveRecordCount)
 		}
+		relkind := "r"
+		if view, ok := e.db.lookupView(name); ok && view.Materialized {
+			relkind = "m"
+		}
 		rows = append(rows, &SearchResult{
 			ID:    name,
 			Score: 1.0,
@@ -9026,11 +9431,27 @@ func (e *Executor) materializePgClass(ctx context.Context) ([]*SearchResult, err
 				"oid":          int64(100 + i),
 				"relname":      name,
 				"relnamespace": int64(0),
-				"relkind":      "r",
+				"relkind":      relkind,
 				"reltuples":    float64(rowCount),
 			},
 		})
 	}
+	for _, view := range views {
+		if view.Materialized {
+			continue
+		}
+		rows = append(rows, &SearchResult{
+			ID:    view.Name,
+			Score: 1.0,
+			Metadata: map[string]interface{}{
+				"oid":          int64(100 + len(rows)),
+				"relname":      view.Name,
+				"relnamespace": int64(0),
+				"relkind":      "v",
+				"reltuples":    float64(0),
+			},
+		})
+	}
 	return rows, nil
 }
 
@@ -9279,6 +9700,38 @@ func (e *Executor) materializePgType(ctx context.Context) ([]*SearchResult, erro
 	return rows, nil
 }
 
+// materializePgProc returns one pg_proc row per function registered with
+// RegisterScalarFunction or RegisterAggregateFunction. OIDs start at 16384,
+// PostgreSQL's first user-object OID, so they cannot collide with pg_class.
+func (e *Executor) materializePgProc(_ context.Context) ([]*SearchResult, error) {
+	functions := e.db.ListSQLFunctions()
+	rows := make([]*SearchResult, 0, len(functions))
+	for i, fn := range functions {
+		argTypes := make([]string, len(fn.ArgTypes))
+		for j, typ := range fn.ArgTypes {
+			argTypes[j] = strconv.FormatInt(sqlFunctionTypeOID(typ), 10)
+		}
+		kind := "f"
+		if fn.Aggregate {
+			kind = "a"
+		}
+		rows = append(rows, &SearchResult{
+			ID:    fn.Name,
+			Score: 1.0,
+			Metadata: map[string]interface{}{
+				"oid":          int64(16384 + i),
+				"proname":      fn.Name,
+				"pronamespace": int64(2200),
+				"prokind":      kind,
+				"pronargs":     int64(len(fn.ArgTypes)),
+				"prorettype":   sqlFunctionTypeOID(fn.ReturnType),
+				"proargtypes":  strings.Join(argTypes, " "),
+			},
+		})
+	}
+	return rows, nil
+}
+
 // materializePgNamespace returns the three standard PostgreSQL namespaces.
 func (e *Executor) materializePgNamespace(ctx context.Context) ([]*SearchResult, error) {
 	return []*SearchResult{
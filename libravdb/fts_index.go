@@ -0,0 +1,1009 @@
+package libravdb
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+const (
+	defaultFullTextConfig = "simple"
+	defaultBM25K1         = 1.2
+	defaultBM25B          = 0.75
+)
+
+// FullTextIndexDefinition declares a BM25 full-text index over one text
+// field. Config names the text search configuration ("simple" or "english")
+// used to analyze both documents and queries; K1 and B are the BM25
+// term-frequency saturation and length-normalization parameters. A
+// definition with neither K1 nor B set uses k1=1.2, b=0.75; once K1 is set, B
+// is taken literally, so b=0 disables length normalization.
+//
+// The declaration is persisted with the collection. Posting lists and term
+// statistics are built from the WAL-logged records on first use, maintained by
+// every committed write afterwards, and checkpointed with the collection's
+// index; recovery restores the checkpoint and replays later WAL writes into
+// it instead of analyzing every record again.
+type FullTextIndexDefinition struct {
+	Name   string  `json:"name"`
+	Field  string  `json:"field"`
+	Config string  `json:"config,omitempty"`
+	K1     float64 `json:"k1,omitempty"`
+	B      float64 `json:"b,omitempty"`
+}
+
+// FullTextIndexStats reports the term statistics of one full-text index.
+type FullTextIndexStats struct {
+	Name          string  `json:"name"`
+	Field         string  `json:"field"`
+	Config        string  `json:"config"`
+	Documents     int     `json:"documents"`
+	Terms         int     `json:"terms"`
+	AverageLength float64 `json:"average_length"`
+}
+
+// WithFullTextIndex declares a BM25 full-text index named <field>_fts over
+// field, analyzed with the given text search configuration ("" selects
+// "simple") and the default BM25 parameters.
+func WithFullTextIndex(field, config string) CollectionOption {
+	return WithFullTextIndexes(FullTextIndexDefinition{Field: field, Config: config})
+}
+
+// WithFullTextIndexes declares BM25 full-text indexes with explicit names
+// and parameters.
+func WithFullTextIndexes(indexes ...FullTextIndexDefinition) CollectionOption {
+	return func(c *CollectionConfig) error {
+		for _, index := range indexes {
+			normalized, err := normalizeFullTextIndexDefinition(index)
+			if err != nil {
+				return err
+			}
+			for _, existing := range c.FullTextIndexes {
+				if strings.EqualFold(existing.Name, normalized.Name) {
+					return fmt.Errorf("full-text index %q is declared more than once", normalized.Name)
+				}
+			}
+			c.FullTextIndexes = append(c.FullTextIndexes, normalized)
+		}
+		return nil
+	}
+}
+
+// normalizeFullTextIndexDefinition validates a declaration and fills in the
+// default name, configuration, and BM25 parameters.
+func normalizeFullTextIndexDefinition(index FullTextIndexDefinition) (FullTextIndexDefinition, error) {
+	index.Field = strings.TrimSpace(index.Field)
+	if index.Field == "" {
+		return FullTextIndexDefinition{}, fmt.Errorf("full-text index requires a field")
+	}
+	index.Name = strings.TrimSpace(index.Name)
+	if index.Name == "" {
+		index.Name = index.Field + "_fts"
+	}
+	index.Config = strings.ToLower(strings.TrimSpace(index.Config))
+	switch index.Config {
+	case "":
+		index.Config = defaultFullTextConfig
+	case "pg_catalog.english":
+		index.Config = "english"
+	case "simple", "english", "english_stem":
+	default:
+		return FullTextIndexDefinition{}, fmt.Errorf("full-text index %q: unsupported text search configuration %q", index.Name, index.Config)
+	}
+	if index.K1 == 0 && index.B == 0 {
+		index.K1, index.B = defaultBM25K1, defaultBM25B
+	} else if index.K1 == 0 {
+		index.K1 = defaultBM25K1
+	}
+	if index.K1 < 0 || math.IsNaN(index.K1) || math.IsInf(index.K1, 0) {
+		return FullTextIndexDefinition{}, fmt.Errorf("full-text index %q: k1 must be a non-negative number, got %v", index.Name, index.K1)
+	}
+	if index.B < 0 || index.B > 1 || math.IsNaN(index.B) {
+		return FullTextIndexDefinition{}, fmt.Errorf("full-text index %q: b must be between 0 and 1, got %v", index.Name, index.B)
+	}
+	return index, nil
+}
+
+// ftsAnalyzer maps a text search configuration to the dictionary that
+// normalizeFTSTermConfig applies, so equivalent configurations share an index.
+func ftsAnalyzer(config string) string {
+	if strings.EqualFold(config, "english") || strings.EqualFold(config, "english_stem") {
+		return "english"
+	}
+	return defaultFullTextConfig
+}
+
+func fullTextIndexesToStorage(indexes []FullTextIndexDefinition) []storage.FullTextIndexDefinition {
+	if len(indexes) == 0 {
+		return nil
+	}
+	converted := make([]storage.FullTextIndexDefinition, len(indexes))
+	for i, index := range indexes {
+		converted[i] = storage.FullTextIndexDefinition{
+			Name: index.Name, Field: index.Field, Config: index.Config, K1: index.K1, B: index.B,
+		}
+	}
+	return converted
+}
+
+func fullTextIndexesFromStorage(indexes []storage.FullTextIndexDefinition) []FullTextIndexDefinition {
+	if len(indexes) == 0 {
+		return nil
+	}
+	converted := make([]FullTextIndexDefinition, len(indexes))
+	for i, index := range indexes {
+		converted[i] = FullTextIndexDefinition{
+			Name: index.Name, Field: index.Field, Config: index.Config, K1: index.K1, B: index.B,
+		}
+	}
+	return converted
+}
+
+type fullTextIndexState uint8
+
+const (
+	fullTextIndexUnbuilt fullTextIndexState = iota
+	fullTextIndexBuilding
+	fullTextIndexReady
+)
+
+// fullTextIndex is the in-memory BM25 inverted index behind one declared
+// full-text index. It is built from storage on first use. Writers never wait
+// for a build: while one is running their changes are queued and replayed
+// over the freshly built postings, so a write committed at any point during
+// the scan is reflected exactly once, in commit order.
+type fullTextIndex struct {
+	definition FullTextIndexDefinition
+
+	// buildMu serializes builds and is never taken by writers, which may
+	// hold collection locks that a build's storage scan needs.
+	buildMu sync.Mutex
+
+	mu      sync.RWMutex
+	state   fullTextIndexState
+	pending []fullTextIndexWrite
+	data    fullTextPostings
+}
+
+// fullTextPostings holds the posting lists and the statistics BM25 needs:
+// per-document lengths, per-term document frequencies (the posting list
+// sizes), and the total length behind the average.
+type fullTextPostings struct {
+	docs        map[string]fullTextDocument
+	postings    map[string]map[string]uint32 // term -> id -> term frequency
+	totalLength uint64
+}
+
+type fullTextDocument struct {
+	length uint32
+	terms  []string
+}
+
+// fullTextIndexWrite is one analyzed committed write. A nil terms map
+// removes the document.
+type fullTextIndexWrite struct {
+	id     string
+	terms  map[string]uint32
+	length uint32
+}
+
+func newFullTextPostings() fullTextPostings {
+	return fullTextPostings{
+		docs:     make(map[string]fullTextDocument),
+		postings: make(map[string]map[string]uint32),
+	}
+}
+
+func (p *fullTextPostings) apply(write fullTextIndexWrite) {
+	if old, ok := p.docs[write.id]; ok {
+		for _, term := range old.terms {
+			ids := p.postings[term]
+			delete(ids, write.id)
+			if len(ids) == 0 {
+				delete(p.postings, term)
+			}
+		}
+		p.totalLength -= uint64(old.length)
+		delete(p.docs, write.id)
+	}
+	if len(write.terms) == 0 {
+		return
+	}
+	doc := fullTextDocument{length: write.length, terms: make([]string, 0, len(write.terms))}
+	for term, tf := range write.terms {
+		ids := p.postings[term]
+		if ids == nil {
+			ids = make(map[string]uint32)
+			p.postings[term] = ids
+		}
+		ids[write.id] = tf
+		doc.terms = append(doc.terms, term)
+	}
+	p.docs[write.id] = doc
+	p.totalLength += uint64(doc.length)
+}
+
+func (p *fullTextPostings) averageLength() float64 {
+	if len(p.docs) == 0 {
+		return 0
+	}
+	return float64(p.totalLength) / float64(len(p.docs))
+}
+
+// analyze tokenizes the indexed field of one record. Records without the
+// field, or whose text has no indexable terms, are removed from the index.
+func (x *fullTextIndex) analyze(id string, metadata map[string]interface{}) fullTextIndexWrite {
+	write := fullTextIndexWrite{id: id}
+	value, ok := recordMetadataValue(metadata, x.definition.Field)
+	if !ok || value == nil {
+		return write
+	}
+	vector := buildFTSVectorConfig(recordMetaToString(value), x.definition.Config)
+	if len(vector.terms) == 0 {
+		return write
+	}
+	write.terms = make(map[string]uint32, len(vector.terms))
+	for term, positions := range vector.terms {
+		write.terms[term] = uint32(len(positions))
+		write.length += uint32(len(positions))
+	}
+	return write
+}
+
+func (x *fullTextIndex) record(write fullTextIndexWrite) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	switch x.state {
+	case fullTextIndexReady:
+		x.data.apply(write)
+	case fullTextIndexBuilding:
+		x.pending = append(x.pending, write)
+	}
+}
+
+func (x *fullTextIndex) tracking() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.state != fullTextIndexUnbuilt
+}
+
+// ensureBuilt scans the collection once and publishes the postings.
+func (x *fullTextIndex) ensureBuilt(ctx context.Context, c *Collection) error {
+	x.mu.RLock()
+	ready := x.state == fullTextIndexReady
+	x.mu.RUnlock()
+	if ready {
+		return nil
+	}
+	x.buildMu.Lock()
+	defer x.buildMu.Unlock()
+
+	x.mu.Lock()
+	if x.state == fullTextIndexReady {
+		x.mu.Unlock()
+		return nil
+	}
+	x.state = fullTextIndexBuilding
+	x.pending = nil
+	x.mu.Unlock()
+
+	fresh := newFullTextPostings()
+	err := c.Iterate(withExpiredRecords(ctx), func(record Record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fresh.apply(x.analyze(record.ID, record.Metadata))
+		return nil
+	})
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err != nil {
+		x.state = fullTextIndexUnbuilt
+		x.pending = nil
+		return fmt.Errorf("build full-text index %q: %w", x.definition.Name, err)
+	}
+	for _, write := range x.pending {
+		fresh.apply(write)
+	}
+	x.pending = nil
+	x.data = fresh
+	x.state = fullTextIndexReady
+	return nil
+}
+
+// fullTextImageVersion is the format of a checkpointed full-text index.
+const fullTextImageVersion = 1
+
+// image encodes a built index for a checkpoint: its definition, the
+// vocabulary, and each document's length and term frequencies. Restoring it
+// rebuilds the postings and statistics without analyzing any text. ok is
+// false while the index is not built.
+func (x *fullTextIndex) image() (image []byte, ok bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.state != fullTextIndexReady {
+		return nil, false
+	}
+	buf := binary.AppendUvarint(nil, fullTextImageVersion)
+	for _, text := range []string{x.definition.Name, x.definition.Field, x.definition.Config} {
+		buf = appendImageString(buf, text)
+	}
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(x.definition.K1))
+	buf = binary.LittleEndian.AppendUint64(buf, math.Float64bits(x.definition.B))
+	terms := make(map[string]uint64, len(x.data.postings))
+	buf = binary.AppendUvarint(buf, uint64(len(x.data.postings)))
+	for term := range x.data.postings {
+		terms[term] = uint64(len(terms))
+		buf = appendImageString(buf, term)
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(x.data.docs)))
+	for id, doc := range x.data.docs {
+		buf = appendImageString(buf, id)
+		buf = binary.AppendUvarint(buf, uint64(doc.length))
+		buf = binary.AppendUvarint(buf, uint64(len(doc.terms)))
+		for _, term := range doc.terms {
+			buf = binary.AppendUvarint(buf, terms[term])
+			buf = binary.AppendUvarint(buf, uint64(x.data.postings[term][id]))
+		}
+	}
+	return buf, true
+}
+
+func appendImageString(buf []byte, text string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(text)))
+	return append(buf, text...)
+}
+
+// restoreFullTextIndex decodes an image into a ready index.
+func restoreFullTextIndex(image []byte) (*fullTextIndex, error) {
+	r := imageReader{data: image}
+	if version := r.uvarint(); r.err == nil && version != fullTextImageVersion {
+		return nil, fmt.Errorf("unsupported full-text image version %d", version)
+	}
+	var definition FullTextIndexDefinition
+	definition.Name, definition.Field, definition.Config = r.string(), r.string(), r.string()
+	definition.K1, definition.B = r.float64(), r.float64()
+	vocabulary := make([]string, r.count())
+	for i := range vocabulary {
+		vocabulary[i] = r.string()
+	}
+	data := newFullTextPostings()
+	for docs := r.count(); docs > 0 && r.err == nil; docs-- {
+		write := fullTextIndexWrite{id: r.string(), length: uint32(r.uvarint())}
+		terms := r.count()
+		write.terms = make(map[string]uint32, terms)
+		for ; terms > 0 && r.err == nil; terms-- {
+			term := r.uvarint()
+			tf := r.uvarint()
+			if term >= uint64(len(vocabulary)) {
+				r.fail()
+				break
+			}
+			write.terms[vocabulary[term]] = uint32(tf)
+		}
+		data.apply(write)
+	}
+	if r.err == nil && len(r.data) != 0 {
+		r.fail()
+	}
+	if r.err != nil {
+		return nil, fmt.Errorf("decode full-text index image: %w", r.err)
+	}
+	return &fullTextIndex{definition: definition, state: fullTextIndexReady, data: data}, nil
+}
+
+// imageReader decodes checkpoint images, recording the first error.
+type imageReader struct {
+	data []byte
+	err  error
+}
+
+func (r *imageReader) fail() {
+	if r.err == nil {
+		r.err = errors.New("truncated or corrupt image")
+	}
+	r.data = nil
+}
+
+func (r *imageReader) uvarint() uint64 {
+	value, n := binary.Uvarint(r.data)
+	if n <= 0 {
+		r.fail()
+		return 0
+	}
+	r.data = r.data[n:]
+	return value
+}
+
+// count reads a length that must not exceed the remaining bytes, so corrupt
+// images cannot force large allocations.
+func (r *imageReader) count() int {
+	n := r.uvarint()
+	if n > uint64(len(r.data)) {
+		r.fail()
+		return 0
+	}
+	return int(n)
+}
+
+func (r *imageReader) string() string {
+	n := r.count()
+	if r.err != nil {
+		return ""
+	}
+	text := string(r.data[:n])
+	r.data = r.data[n:]
+	return text
+}
+
+func (r *imageReader) float64() float64 {
+	if len(r.data) < 8 {
+		r.fail()
+		return 0
+	}
+	value := math.Float64frombits(binary.LittleEndian.Uint64(r.data))
+	r.data = r.data[8:]
+	return value
+}
+
+// lookup returns the IDs of every document that can satisfy root with a
+// positive rank. The set may include false positives (phrases are checked
+// for term presence only) and must be rechecked against the record text.
+func (x *fullTextIndex) lookup(root *ftsQueryNode) []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	set, bounded := x.data.candidates(root)
+	if !bounded {
+		// A negation alone does not bound the matches, but a positive rank
+		// still requires at least one non-negated query term.
+		set = make(map[string]struct{})
+		x.data.collectPositive(root, set)
+	}
+	ids := make([]string, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// estimateMatches predicts how many documents satisfy root from posting list
+// sizes alone, treating terms as independent. ok is false until the index
+// has been built; estimation never starts a build.
+func (x *fullTextIndex) estimateMatches(root *ftsQueryNode) (float64, bool) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.state != fullTextIndexReady {
+		return 0, false
+	}
+	documents := float64(len(x.data.docs))
+	if documents == 0 {
+		return 0, true
+	}
+	return documents * x.data.matchFraction(root, documents), true
+}
+
+func (p *fullTextPostings) matchFraction(node *ftsQueryNode, documents float64) float64 {
+	if node == nil {
+		return 0
+	}
+	switch node.kind {
+	case ftsQueryTerm:
+		frequency := len(p.postings[node.term])
+		if node.prefix {
+			for term, ids := range p.postings {
+				if term != node.term && strings.HasPrefix(term, node.term) {
+					frequency += len(ids)
+				}
+			}
+		}
+		return math.Min(1, float64(frequency)/documents)
+	case ftsQueryPhrase:
+		fraction := 1.0
+		for _, term := range node.phrase {
+			fraction *= float64(len(p.postings[term])) / documents
+		}
+		return fraction
+	case ftsQueryAnd:
+		return p.matchFraction(node.left, documents) * p.matchFraction(node.right, documents)
+	case ftsQueryOr:
+		left, right := p.matchFraction(node.left, documents), p.matchFraction(node.right, documents)
+		return left + right - left*right
+	case ftsQueryNot:
+		return 1 - p.matchFraction(node.left, documents)
+	}
+	return 0
+}
+
+func (p *fullTextPostings) termPostings(node *ftsQueryNode, out map[string]struct{}) {
+	for id := range p.postings[node.term] {
+		out[id] = struct{}{}
+	}
+	if !node.prefix {
+		return
+	}
+	for term, ids := range p.postings {
+		if term != node.term && strings.HasPrefix(term, node.term) {
+			for id := range ids {
+				out[id] = struct{}{}
+			}
+		}
+	}
+}
+
+// candidates returns a superset of the documents matching node. bounded is
+// false when node admits documents containing none of its terms.
+func (p *fullTextPostings) candidates(node *ftsQueryNode) (map[string]struct{}, bool) {
+	if node == nil {
+		return map[string]struct{}{}, true
+	}
+	switch node.kind {
+	case ftsQueryTerm:
+		set := make(map[string]struct{})
+		p.termPostings(node, set)
+		return set, true
+	case ftsQueryPhrase:
+		var set map[string]struct{}
+		for _, term := range node.phrase {
+			next := make(map[string]struct{})
+			for id := range p.postings[term] {
+				if set == nil {
+					next[id] = struct{}{}
+				} else if _, ok := set[id]; ok {
+					next[id] = struct{}{}
+				}
+			}
+			set = next
+			if len(set) == 0 {
+				break
+			}
+		}
+		if set == nil {
+			set = map[string]struct{}{}
+		}
+		return set, true
+	case ftsQueryAnd:
+		left, leftBounded := p.candidates(node.left)
+		right, rightBounded := p.candidates(node.right)
+		switch {
+		case leftBounded && rightBounded:
+			if len(right) < len(left) {
+				left, right = right, left
+			}
+			for id := range left {
+				if _, ok := right[id]; !ok {
+					delete(left, id)
+				}
+			}
+			return left, true
+		case leftBounded:
+			return left, true
+		case rightBounded:
+			return right, true
+		}
+		return nil, false
+	case ftsQueryOr:
+		left, leftBounded := p.candidates(node.left)
+		right, rightBounded := p.candidates(node.right)
+		if !leftBounded || !rightBounded {
+			return nil, false
+		}
+		for id := range right {
+			left[id] = struct{}{}
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+func (p *fullTextPostings) collectPositive(node *ftsQueryNode, out map[string]struct{}) {
+	if node == nil {
+		return
+	}
+	switch node.kind {
+	case ftsQueryTerm:
+		p.termPostings(node, out)
+	case ftsQueryPhrase:
+		for _, term := range node.phrase {
+			for id := range p.postings[term] {
+				out[id] = struct{}{}
+			}
+		}
+	case ftsQueryAnd, ftsQueryOr:
+		p.collectPositive(node.left, out)
+		p.collectPositive(node.right, out)
+	}
+}
+
+// rankText scores text against root with BM25 under the index's corpus
+// statistics. Text that does not match root scores zero. Only terms outside
+// negations contribute; a prefix term contributes every matching term.
+func (x *fullTextIndex) rankText(text string, root *ftsQueryNode) float64 {
+	vector := buildFTSVectorConfig(text, x.definition.Config)
+	if root == nil || !ftsQueryMatches(vector, root) {
+		return 0
+	}
+	frequencies := make(map[string]uint32, len(vector.terms))
+	var length uint32
+	for term, positions := range vector.terms {
+		frequencies[term] = uint32(len(positions))
+		length += uint32(len(positions))
+	}
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.bm25Locked(frequencies, length, root)
+}
+
+func (x *fullTextIndex) bm25Locked(frequencies map[string]uint32, length uint32, root *ftsQueryNode) float64 {
+	terms := make(map[string]struct{})
+	collectBM25Terms(root, frequencies, terms)
+	documents := float64(len(x.data.docs))
+	averageLength := x.data.averageLength()
+	if averageLength == 0 {
+		averageLength = float64(length)
+	}
+	k1, b := x.definition.K1, x.definition.B
+	norm := k1 * (1 - b + b*float64(length)/averageLength)
+	// Sum in term order so equal documents always receive identical scores.
+	ordered := make([]string, 0, len(terms))
+	for term := range terms {
+		ordered = append(ordered, term)
+	}
+	sort.Strings(ordered)
+	var score float64
+	for _, term := range ordered {
+		tf := float64(frequencies[term])
+		if tf == 0 {
+			continue
+		}
+		df := float64(len(x.data.postings[term]))
+		idf := math.Log(1 + (documents-df+0.5)/(df+0.5))
+		if idf < 0 {
+			idf = 0
+		}
+		score += idf * tf * (k1 + 1) / (tf + norm)
+	}
+	return score
+}
+
+func collectBM25Terms(node *ftsQueryNode, frequencies map[string]uint32, out map[string]struct{}) {
+	if node == nil {
+		return
+	}
+	switch node.kind {
+	case ftsQueryTerm:
+		if frequencies[node.term] > 0 {
+			out[node.term] = struct{}{}
+		}
+		if node.prefix {
+			for term := range frequencies {
+				if strings.HasPrefix(term, node.term) {
+					out[term] = struct{}{}
+				}
+			}
+		}
+	case ftsQueryPhrase:
+		for _, term := range node.phrase {
+			out[term] = struct{}{}
+		}
+	case ftsQueryAnd, ftsQueryOr:
+		collectBM25Terms(node.left, frequencies, out)
+		collectBM25Terms(node.right, frequencies, out)
+	}
+}
+
+// ftsFrequenciesMatch evaluates node against term frequencies alone; a
+// phrase matches when all of its terms occur, so callers recheck phrases.
+func ftsFrequenciesMatch(frequencies map[string]uint32, node *ftsQueryNode) bool {
+	if node == nil {
+		return false
+	}
+	switch node.kind {
+	case ftsQueryTerm:
+		if frequencies[node.term] > 0 {
+			return true
+		}
+		if node.prefix {
+			for term := range frequencies {
+				if strings.HasPrefix(term, node.term) {
+					return true
+				}
+			}
+		}
+		return false
+	case ftsQueryPhrase:
+		for _, term := range node.phrase {
+			if frequencies[term] == 0 {
+				return false
+			}
+		}
+		return len(node.phrase) > 0
+	case ftsQueryAnd:
+		return ftsFrequenciesMatch(frequencies, node.left) && ftsFrequenciesMatch(frequencies, node.right)
+	case ftsQueryOr:
+		return ftsFrequenciesMatch(frequencies, node.left) || ftsFrequenciesMatch(frequencies, node.right)
+	case ftsQueryNot:
+		return !ftsFrequenciesMatch(frequencies, node.left)
+	}
+	return false
+}
+
+func ftsQueryHasPhrase(node *ftsQueryNode) bool {
+	if node == nil {
+		return false
+	}
+	if node.kind == ftsQueryPhrase {
+		return true
+	}
+	return ftsQueryHasPhrase(node.left) || ftsQueryHasPhrase(node.right)
+}
+
+type fullTextHit struct {
+	id    string
+	score float64
+}
+
+// rankCandidates scores candidates from the stored term frequencies,
+// dropping documents that do not match root, best first.
+func (x *fullTextIndex) rankCandidates(ids []string, root *ftsQueryNode) []fullTextHit {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	hits := make([]fullTextHit, 0, len(ids))
+	for _, id := range ids {
+		doc, ok := x.data.docs[id]
+		if !ok {
+			continue
+		}
+		frequencies := make(map[string]uint32, len(doc.terms))
+		for _, term := range doc.terms {
+			frequencies[term] = x.data.postings[term][id]
+		}
+		if !ftsFrequenciesMatch(frequencies, root) {
+			continue
+		}
+		if score := x.bm25Locked(frequencies, doc.length, root); score > 0 {
+			hits = append(hits, fullTextHit{id: id, score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].id < hits[j].id
+	})
+	return hits
+}
+
+func (x *fullTextIndex) stats() FullTextIndexStats {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return FullTextIndexStats{
+		Name:          x.definition.Name,
+		Field:         x.definition.Field,
+		Config:        x.definition.Config,
+		Documents:     len(x.data.docs),
+		Terms:         len(x.data.postings),
+		AverageLength: x.data.averageLength(),
+	}
+}
+
+// fullTextIndexFor returns the full-text index over field whose analyzer
+// matches config, or the first index over field when config is empty.
+func (c *Collection) fullTextIndexFor(field, config string) *fullTextIndex {
+	if c == nil || c.config == nil || len(c.config.FullTextIndexes) == 0 {
+		return nil
+	}
+	for _, definition := range c.config.FullTextIndexes {
+		if !strings.EqualFold(definition.Field, field) {
+			continue
+		}
+		if config != "" && ftsAnalyzer(config) != ftsAnalyzer(definition.Config) {
+			continue
+		}
+		c.fullTextMu.Lock()
+		defer c.fullTextMu.Unlock()
+		if c.fullTextIndexes == nil {
+			c.fullTextIndexes = make(map[string]*fullTextIndex)
+		}
+		index := c.fullTextIndexes[definition.Name]
+		if index == nil || index.definition != definition {
+			index = &fullTextIndex{definition: definition}
+			c.fullTextIndexes[definition.Name] = index
+		}
+		return index
+	}
+	return nil
+}
+
+// resetFullTextIndexes drops all derived postings after the declarations
+// change; they are rebuilt on next use.
+func (c *Collection) resetFullTextIndexes() {
+	c.fullTextMu.Lock()
+	c.fullTextIndexes = nil
+	c.fullTextMu.Unlock()
+}
+
+func (c *Collection) trackedFullTextIndexes() []*fullTextIndex {
+	if c == nil {
+		return nil
+	}
+	c.fullTextMu.Lock()
+	defer c.fullTextMu.Unlock()
+	if len(c.fullTextIndexes) == 0 {
+		return nil
+	}
+	indexes := make([]*fullTextIndex, 0, len(c.fullTextIndexes))
+	for _, index := range c.fullTextIndexes {
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// beginFullTextWrite marks a write that commits to storage and then updates
+// the full-text postings; calling the result ends it. A checkpoint taken
+// while none is between the two has postings that hold every committed
+// write; see fullTextImages.
+func (c *Collection) beginFullTextWrite() func() {
+	c.fullTextWrites.Add(1)
+	return func() { c.fullTextWrites.Add(-1) }
+}
+
+// fullTextImages returns the checkpoint image of every built full-text index,
+// keyed by derived-index name, and the frontier they cover. With no write in
+// flight that is checkpointLSN, and it is remembered: a write in flight may
+// have committed without reaching the postings yet, so the images then cover
+// only the last such frontier, and recovery reapplies the records written
+// after it. A write that begins after the check commits after the
+// checkpoint frontier, and recovery replays it over whatever part of it an
+// image holds.
+func (c *Collection) fullTextImages(checkpointLSN uint64) (map[string][]byte, uint64) {
+	if c.fullTextWrites.Load() == 0 {
+		for {
+			covered := c.fullTextCoveredLSN.Load()
+			if covered >= checkpointLSN || c.fullTextCoveredLSN.CompareAndSwap(covered, checkpointLSN) {
+				break
+			}
+		}
+	}
+	coveredLSN := min(c.fullTextCoveredLSN.Load(), checkpointLSN)
+	var images map[string][]byte
+	for _, index := range c.trackedFullTextIndexes() {
+		image, ok := index.image()
+		if !ok {
+			continue
+		}
+		if images == nil {
+			images = make(map[string][]byte)
+		}
+		images[fullTextDerivedPrefix+index.definition.Name] = image
+	}
+	return images, coveredLSN
+}
+
+// fullTextDerivedPrefix names full-text images among a collection's derived
+// indexes.
+const fullTextDerivedPrefix = "fts:"
+
+// adoptFullTextIndexes installs indexes restored at open whose definitions
+// are still declared; the others are dropped and rebuilt on first use.
+func (c *Collection) adoptFullTextIndexes(restored []*fullTextIndex) {
+	if len(restored) == 0 {
+		return
+	}
+	c.fullTextMu.Lock()
+	defer c.fullTextMu.Unlock()
+	for _, index := range restored {
+		for _, definition := range c.config.FullTextIndexes {
+			if definition != index.definition {
+				continue
+			}
+			if c.fullTextIndexes == nil {
+				c.fullTextIndexes = make(map[string]*fullTextIndex)
+			}
+			c.fullTextIndexes[definition.Name] = index
+		}
+	}
+}
+
+// noteFullTextWrite applies a committed insert or update to every built
+// full-text index. metadata must be the record's complete metadata.
+func (c *Collection) noteFullTextWrite(id string, metadata map[string]interface{}) {
+	for _, index := range c.trackedFullTextIndexes() {
+		if index.tracking() {
+			index.record(index.analyze(id, metadata))
+		}
+	}
+}
+
+// noteFullTextDelete removes a committed delete from every built full-text
+// index.
+func (c *Collection) noteFullTextDelete(id string) {
+	for _, index := range c.trackedFullTextIndexes() {
+		index.record(fullTextIndexWrite{id: id})
+	}
+}
+
+// fullTextRank computes FTS_RANK for text in column: BM25 when the column
+// carries a full-text index, the scan-time rank otherwise.
+func (c *Collection) fullTextRank(ctx context.Context, column, text, query string) float64 {
+	if index := c.fullTextIndexFor(column, ""); index != nil {
+		if err := index.ensureBuilt(ctx, c); err == nil {
+			return index.rankText(text, parseFTSQueryConfig(query, "plain", index.definition.Config))
+		}
+	}
+	return ftsRankText(text, query, "plain")
+}
+
+// SearchFullText returns the k records whose field best matches query under
+// BM25, best first. query uses web search syntax: bare words are required,
+// "quoted text" is a phrase, OR separates alternatives, and a leading - negates
+// a term. field must carry a full-text index.
+func (c *Collection) SearchFullText(ctx context.Context, field, query string, k int) (*SearchResults, error) {
+	start := time.Now()
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	index := c.fullTextIndexFor(field, "")
+	if index == nil {
+		return nil, fmt.Errorf("field %q has no full-text index", field)
+	}
+	if err := index.ensureBuilt(ctx, c); err != nil {
+		return nil, err
+	}
+	root := parseFTSQueryConfig(query, "web", index.definition.Config)
+	hits := index.rankCandidates(index.lookup(root), root)
+	recheck := ftsQueryHasPhrase(root)
+
+	results := make([]*SearchResult, 0, min(k, len(hits)))
+	for _, hit := range hits {
+		if len(results) == k {
+			break
+		}
+		record, err := c.Get(ctx, hit.id)
+		if err != nil {
+			// A concurrent delete can remove a record after the postings
+			// were read; its delete hook retires the posting.
+			if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if recheck {
+			value, ok := recordMetadataValue(record.Metadata, index.definition.Field)
+			if !ok || value == nil || !ftsQueryMatches(buildFTSVectorConfig(recordMetaToString(value), index.definition.Config), root) {
+				continue
+			}
+		}
+		results = append(results, &SearchResult{
+			ID:       record.ID,
+			Score:    float32(hit.score),
+			Vector:   record.Vector,
+			Metadata: record.Metadata,
+			Version:  record.Version,
+			Ordinal:  record.Ordinal,
+		})
+	}
+	return &SearchResults{Results: results, Took: time.Since(start), Total: len(results)}, nil
+}
+
+// FullTextIndexStats returns the document count, vocabulary size, and
+// average document length of the full-text index over field.
+func (c *Collection) FullTextIndexStats(ctx context.Context, field string) (FullTextIndexStats, error) {
+	index := c.fullTextIndexFor(field, "")
+	if index == nil {
+		return FullTextIndexStats{}, fmt.Errorf("field %q has no full-text index", field)
+	}
+	if err := index.ensureBuilt(ctx, c); err != nil {
+		return FullTextIndexStats{}, err
+	}
+	return index.stats(), nil
+}
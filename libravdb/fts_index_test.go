@@ -0,0 +1,512 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/storage/singlefile"
+)
+
+var ftsTestVocabulary = []string{
+	"security", "incident", "response", "vector", "search", "garden", "notes",
+	"graph", "index", "latency", "replica", "backup", "storage", "query",
+}
+
+func randomFTSDocument(rng *rand.Rand) string {
+	words := make([]string, 3+rng.Intn(12))
+	for i := range words {
+		words[i] = ftsTestVocabulary[rng.Intn(len(ftsTestVocabulary))]
+	}
+	return strings.Join(words, " ")
+}
+
+// bruteForceBM25 ranks documents for a conjunctive query with the textbook
+// BM25 formula over the whole corpus.
+func bruteForceBM25(docs map[string]string, query string, k1, b float64) []fullTextHit {
+	terms := make(map[string][]int)
+	lengths := make(map[string]int, len(docs))
+	tfs := make(map[string]map[string]int, len(docs))
+	var total int
+	for id, text := range docs {
+		vector := buildFTSVectorConfig(text, "simple")
+		tfs[id] = make(map[string]int)
+		for term, positions := range vector.terms {
+			tfs[id][term] = len(positions)
+			lengths[id] += len(positions)
+			terms[term] = append(terms[term], 0)
+		}
+		total += lengths[id]
+	}
+	n := float64(len(docs))
+	avgdl := float64(total) / n
+	queryTerms := strings.Fields(query)
+	sort.Strings(queryTerms)
+	var hits []fullTextHit
+	for id := range docs {
+		var score float64
+		matched := true
+		for _, term := range queryTerms {
+			tf := float64(tfs[id][term])
+			if tf == 0 {
+				matched = false
+				break
+			}
+			df := float64(len(terms[term]))
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			score += idf * tf * (k1 + 1) / (tf + k1*(1-b+b*float64(lengths[id])/avgdl))
+		}
+		if matched {
+			hits = append(hits, fullTextHit{id: id, score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].id < hits[j].id
+	})
+	return hits
+}
+
+func TestSearchFullTextMatchesBruteForceBM25(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(t.TempDir()+"/fts_index.libravdb"), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	col, err := db.CreateCollection(ctx, "documents", WithMetadataOnly(),
+		WithMetadataSchema(MetadataSchema{"content": StringField}),
+		WithFullTextIndexes(FullTextIndexDefinition{Field: "content", K1: 1.5, B: 0.6}))
+	if err != nil {
+		t.Fatalf("CreateCollection: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(17))
+	docs := make(map[string]string, 300)
+	for i := 0; i < 300; i++ {
+		id := fmt.Sprintf("d%03d", i)
+		docs[id] = randomFTSDocument(rng)
+		if err := col.Insert(ctx, id, nil, map[string]interface{}{"content": docs[id]}); err != nil {
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+
+	check := func(query string) {
+		t.Helper()
+		want := bruteForceBM25(docs, query, 1.5, 0.6)
+		if len(want) > 10 {
+			want = want[:10]
+		}
+		results, err := col.SearchFullText(ctx, "content", query, 10)
+		if err != nil {
+			t.Fatalf("SearchFullText(%q): %v", query, err)
+		}
+		if len(results.Results) != len(want) {
+			t.Fatalf("SearchFullText(%q) returned %d results, want %d", query, len(results.Results), len(want))
+		}
+		for i, result := range results.Results {
+			if result.ID != want[i].id || math.Abs(float64(result.Score)-want[i].score) > 1e-4 {
+				t.Fatalf("SearchFullText(%q) rank %d = %s (%v), want %s (%v)", query, i, result.ID, result.Score, want[i].id, want[i].score)
+			}
+		}
+	}
+	check("security")
+	check("vector search")
+	check("replica backup storage")
+
+	stats, err := col.FullTextIndexStats(ctx, "content")
+	if err != nil {
+		t.Fatalf("FullTextIndexStats: %v", err)
+	}
+	if stats.Name != "content_fts" || stats.Documents != 300 || stats.Terms != len(ftsTestVocabulary) {
+		t.Fatalf("stats = %+v", stats)
+	}
+
+	// Committed writes keep the postings current without a rebuild: direct
+	// updates and deletes, and a transaction mixing both.
+	docs["d000"] = "security security security"
+	if err := col.Update(ctx, "d000", nil, map[string]interface{}{"content": docs["d000"]}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if err := col.Delete(ctx, "d001"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	delete(docs, "d001")
+	if err := db.WithTx(ctx, func(tx Tx) error {
+		docs["t000"] = "incident response latency"
+		if err := tx.Insert(ctx, "documents", "t000", nil, map[string]interface{}{"content": docs["t000"]}); err != nil {
+			return err
+		}
+		delete(docs, "d002")
+		return tx.Delete(ctx, "documents", "d002")
+	}); err != nil {
+		t.Fatalf("WithTx: %v", err)
+	}
+	check("security")
+	check("incident response")
+	if stats, _ := col.FullTextIndexStats(ctx, "content"); stats.Documents != len(docs) {
+		t.Fatalf("stats after writes = %+v, want %d documents", stats, len(docs))
+	}
+
+	if _, err := col.SearchFullText(ctx, "missing", "security", 3); err == nil {
+		t.Fatal("SearchFullText accepted a field without a full-text index")
+	}
+}
+
+func TestFullTextIndexRestoresFromCheckpointAndReplaysWAL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "fts-source.libravdb")
+	copyPath := filepath.Join(dir, "fts-copy.libravdb")
+	db, err := Open(WithStoragePath(sourcePath), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	col, err := db.CreateCollection(ctx, "documents", WithDimension(2), WithFlat(),
+		WithMetadataSchema(MetadataSchema{"content": StringField}),
+		WithFullTextIndexes(FullTextIndexDefinition{Field: "content", K1: 1.5, B: 0.6}))
+	if err != nil {
+		_ = db.Close()
+		t.Fatalf("CreateCollection: %v", err)
+	}
+	rng := rand.New(rand.NewSource(29))
+	docs := make(map[string]string, 60)
+	for i := 0; i < 60; i++ {
+		id := fmt.Sprintf("d%03d", i)
+		docs[id] = randomFTSDocument(rng)
+		if err := col.Insert(ctx, id, []float32{1, float32(i)}, map[string]interface{}{"content": docs[id]}); err != nil {
+			_ = db.Close()
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+	// The postings are checkpointed once built.
+	if _, err := col.SearchFullText(ctx, "content", "security", 5); err != nil {
+		_ = db.Close()
+		t.Fatalf("SearchFullText: %v", err)
+	}
+	if err := db.Checkpoint(ctx); err != nil {
+		_ = db.Close()
+		t.Fatalf("Checkpoint: %v", err)
+	}
+
+	// Writes after the checkpoint are only in the WAL.
+	docs["d000"] = "security security garden"
+	if err := col.Update(ctx, "d000", []float32{1, 0}, map[string]interface{}{"content": docs["d000"]}); err != nil {
+		_ = db.Close()
+		t.Fatalf("Update: %v", err)
+	}
+	if err := col.Delete(ctx, "d001"); err != nil {
+		_ = db.Close()
+		t.Fatalf("Delete: %v", err)
+	}
+	delete(docs, "d001")
+	docs["n000"] = "replica backup security"
+	if err := col.Insert(ctx, "n000", []float32{0, 1}, map[string]interface{}{"content": docs["n000"]}); err != nil {
+		_ = db.Close()
+		t.Fatalf("Insert n000: %v", err)
+	}
+	copyFile(t, sourcePath, copyPath)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	recovered, err := Open(WithStoragePath(copyPath), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open copy: %v", err)
+	}
+	defer recovered.Close()
+	col, err = recovered.GetCollection("documents")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	if index := col.fullTextIndexFor("content", ""); index == nil || !index.tracking() {
+		t.Fatal("full-text index was not restored from the checkpoint")
+	}
+	engine, ok := recovered.storage.(*singlefile.Engine)
+	if !ok {
+		t.Fatal("recovered storage is not single-file engine")
+	}
+	if stats := engine.RecoveryStats(); stats.RestoredDerivedIndexes != 1 || stats.DroppedDerivedIndexes != 0 {
+		t.Fatalf("recovery stats = %+v, want one restored full-text index", stats)
+	}
+	for _, query := range []string{"security", "replica backup", "garden"} {
+		want := bruteForceBM25(docs, query, 1.5, 0.6)
+		if len(want) > 10 {
+			want = want[:10]
+		}
+		results, err := col.SearchFullText(ctx, "content", query, 10)
+		if err != nil {
+			t.Fatalf("SearchFullText(%q): %v", query, err)
+		}
+		if len(results.Results) != len(want) {
+			t.Fatalf("SearchFullText(%q) returned %d results, want %d", query, len(results.Results), len(want))
+		}
+		for i, result := range results.Results {
+			if result.ID != want[i].id || math.Abs(float64(result.Score)-want[i].score) > 1e-4 {
+				t.Fatalf("SearchFullText(%q) rank %d = %s (%v), want %s (%v)", query, i, result.ID, result.Score, want[i].id, want[i].score)
+			}
+		}
+	}
+	if stats, _ := col.FullTextIndexStats(ctx, "content"); stats.Documents != len(docs) {
+		t.Fatalf("stats after recovery = %+v, want %d documents", stats, len(docs))
+	}
+}
+
+func TestFullTextCheckpointDuringWriteKeepsCommittedWrites(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	sourcePath := filepath.Join(dir, "fts-inflight-source.libravdb")
+	copyPath := filepath.Join(dir, "fts-inflight-copy.libravdb")
+	db, err := Open(WithStoragePath(sourcePath), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	col, err := db.CreateCollection(ctx, "documents", WithDimension(2), WithFlat(),
+		WithMetadataSchema(MetadataSchema{"content": StringField}),
+		WithFullTextIndexes(FullTextIndexDefinition{Field: "content", K1: 1.5, B: 0.6}))
+	if err != nil {
+		_ = db.Close()
+		t.Fatalf("CreateCollection: %v", err)
+	}
+	docs := map[string]string{"d0": "garden hose", "d1": "security audit"}
+	for id, content := range docs {
+		if err := col.Insert(ctx, id, []float32{1, 0}, map[string]interface{}{"content": content}); err != nil {
+			_ = db.Close()
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+	if _, err := col.SearchFullText(ctx, "content", "garden", 5); err != nil {
+		_ = db.Close()
+		t.Fatalf("SearchFullText: %v", err)
+	}
+	if err := db.Checkpoint(ctx); err != nil {
+		_ = db.Close()
+		t.Fatalf("Checkpoint: %v", err)
+	}
+
+	// Leave d0's update committed but not yet in the postings, as a write
+	// in flight during the next checkpoint would.
+	end := col.beginFullTextWrite()
+	docs["d0"] = "security garden"
+	if err := col.Update(ctx, "d0", []float32{1, 0}, map[string]interface{}{"content": docs["d0"]}); err != nil {
+		end()
+		_ = db.Close()
+		t.Fatalf("Update: %v", err)
+	}
+	index := col.fullTextIndexFor("content", "")
+	index.record(index.analyze("d0", map[string]interface{}{"content": "garden hose"}))
+	err = db.Checkpoint(ctx)
+	end()
+	if err != nil {
+		_ = db.Close()
+		t.Fatalf("Checkpoint: %v", err)
+	}
+	copyFile(t, sourcePath, copyPath)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	recovered, err := Open(WithStoragePath(copyPath), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open copy: %v", err)
+	}
+	defer recovered.Close()
+	col, err = recovered.GetCollection("documents")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	engine, ok := recovered.storage.(*singlefile.Engine)
+	if !ok {
+		t.Fatal("recovered storage is not single-file engine")
+	}
+	if stats := engine.RecoveryStats(); stats.RestoredDerivedIndexes != 1 {
+		t.Fatalf("recovery stats = %+v, want one restored full-text index", stats)
+	}
+	want := bruteForceBM25(docs, "security", 1.5, 0.6)
+	results, err := col.SearchFullText(ctx, "content", "security", 10)
+	if err != nil {
+		t.Fatalf("SearchFullText: %v", err)
+	}
+	if len(results.Results) != len(want) {
+		t.Fatalf("SearchFullText returned %d results, want %d", len(results.Results), len(want))
+	}
+	for i, result := range results.Results {
+		if result.ID != want[i].id || math.Abs(float64(result.Score)-want[i].score) > 1e-4 {
+			t.Fatalf("rank %d = %s (%v), want %s (%v)", i, result.ID, result.Score, want[i].id, want[i].score)
+		}
+	}
+}
+
+func TestSearchFullTextQuerySyntax(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(t.TempDir()+"/fts_syntax.libravdb"), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	col, err := db.CreateCollection(ctx, "documents", WithMetadataOnly(),
+		WithMetadataSchema(MetadataSchema{"content": StringField}),
+		WithFullTextIndex("content", "english"))
+	if err != nil {
+		t.Fatalf("CreateCollection: %v", err)
+	}
+	for id, content := range map[string]string{
+		"d1": "security incident response",
+		"d2": "incident security review",
+		"d3": "gardening notes",
+		"d4": "securing the garden",
+	} {
+		if err := col.Insert(ctx, id, nil, map[string]interface{}{"content": content}); err != nil {
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+	for query, want := range map[string]string{
+		`"security incident"`:  "d1",
+		`security -response`:   "d2",
+		`gardens OR gardening`: "d3,d4",
+		`the`:                  "",
+	} {
+		results, err := col.SearchFullText(ctx, "content", query, 10)
+		if err != nil {
+			t.Fatalf("SearchFullText(%q): %v", query, err)
+		}
+		ids := make([]string, 0, len(results.Results))
+		for _, result := range results.Results {
+			ids = append(ids, result.ID)
+		}
+		sort.Strings(ids)
+		if got := strings.Join(ids, ","); got != want {
+			t.Fatalf("SearchFullText(%q) = %q, want %q", query, got, want)
+		}
+	}
+	if _, err := db.CreateCollection(ctx, "bad", WithMetadataOnly(), WithFullTextIndex("content", "klingon")); err == nil {
+		t.Fatal("WithFullTextIndex accepted an unknown configuration")
+	}
+	if _, err := db.CreateCollection(ctx, "bad", WithMetadataOnly(),
+		WithFullTextIndexes(FullTextIndexDefinition{Field: "content", K1: 1, B: 2})); err == nil {
+		t.Fatal("WithFullTextIndexes accepted b > 1")
+	}
+}
+
+func TestSQL_FullTextIndexLookupAndRank(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/fts_sql.libravdb"
+	db, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, err := db.Query(ctx, `CREATE TABLE docs (id TEXT PRIMARY KEY, content TEXT)`); err != nil {
+		db.Close()
+		t.Fatalf("CREATE TABLE: %v", err)
+	}
+	rng := rand.New(rand.NewSource(3))
+	for i := 0; i < 200; i++ {
+		if _, err := db.QueryWithParams(ctx, "INSERT INTO docs (id, content) VALUES ($1, $2)",
+			QueryParams{"1": fmt.Sprintf("d%03d", i), "2": randomFTSDocument(rng)}); err != nil {
+			db.Close()
+			t.Fatalf("INSERT %d: %v", i, err)
+		}
+	}
+	if _, err := db.Query(ctx, `INSERT INTO docs (id, content) VALUES ('rare', 'zebra security')`); err != nil {
+		db.Close()
+		t.Fatalf("INSERT rare: %v", err)
+	}
+
+	const predicate = "SELECT id FROM docs WHERE to_tsvector(content) @@ to_tsquery('zebra | (secur:* & !incident)') ORDER BY id"
+	scanned, err := db.Query(ctx, predicate)
+	if err != nil {
+		db.Close()
+		t.Fatalf("scan query: %v", err)
+	}
+
+	if _, err := db.Query(ctx, `CREATE INDEX docs_content_fts ON docs USING fts (content) WITH (k1 = 1.2, b = 0.75)`); err != nil {
+		db.Close()
+		t.Fatalf("CREATE INDEX USING fts: %v", err)
+	}
+	col, err := db.GetCollection("docs")
+	if err != nil {
+		db.Close()
+		t.Fatalf("GetCollection: %v", err)
+	}
+	if got := col.Config().FullTextIndexes; len(got) != 1 || got[0].Name != "docs_content_fts" || got[0].Config != "simple" {
+		db.Close()
+		t.Fatalf("FullTextIndexes = %#v", got)
+	}
+
+	db.ResetSQLStats()
+	indexed, err := db.Query(ctx, predicate)
+	if err != nil {
+		db.Close()
+		t.Fatalf("indexed query: %v", err)
+	}
+	if fmt.Sprint(orderedResultIDs(indexed)) != fmt.Sprint(orderedResultIDs(scanned)) {
+		db.Close()
+		t.Fatalf("indexed rows %v differ from scanned rows %v", orderedResultIDs(indexed), orderedResultIDs(scanned))
+	}
+	if stats := db.SQLStats(); stats.IndexHits == 0 || stats.RowsExamined >= 201 {
+		db.Close()
+		t.Fatalf("indexed @@ stats = %+v, want an index probe and fewer rows than a scan", stats)
+	}
+
+	db.ResetSQLStats()
+	rare, err := db.Query(ctx, `SELECT id FROM docs WHERE to_tsvector(content) @@ to_tsquery('zebra')`)
+	if err != nil || len(rare.Results) != 1 || rare.Results[0].ID != "rare" {
+		db.Close()
+		t.Fatalf("rare-term lookup = %+v, %v", rare, err)
+	}
+	if stats := db.SQLStats(); stats.RowsExamined != 1 {
+		db.Close()
+		t.Fatalf("rare-term lookup examined %d rows, want 1", stats.RowsExamined)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// The declaration survives reopen; FTS_RANK on the indexed column is BM25.
+	reopened, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	col, err = reopened.GetCollection("docs")
+	if err != nil {
+		t.Fatalf("GetCollection after reopen: %v", err)
+	}
+	if got := col.Config().FullTextIndexes; len(got) != 1 || got[0].K1 != 1.2 || got[0].B != 0.75 {
+		t.Fatalf("FullTextIndexes after reopen = %#v", got)
+	}
+	ranked, err := reopened.Query(ctx, `SELECT id, FTS_RANK(content, 'zebra security') AS score FROM docs WHERE id = 'rare'`)
+	if err != nil || len(ranked.Results) != 1 {
+		t.Fatalf("FTS_RANK query = %+v, %v", ranked, err)
+	}
+	index := col.fullTextIndexFor("content", "")
+	want := index.rankText("zebra security", parseFTSQueryConfig("zebra security", "plain", "simple"))
+	if score, ok := ranked.Results[0].Metadata["score"].(float64); !ok || want <= 0 || math.Abs(score-want) > 1e-9 {
+		t.Fatalf("FTS_RANK = %#v, want BM25 %v", ranked.Results[0].Metadata["score"], want)
+	}
+
+	if _, err := reopened.Query(ctx, `DROP INDEX docs_content_fts`); err != nil {
+		t.Fatalf("DROP INDEX: %v", err)
+	}
+	if got := col.Config().FullTextIndexes; len(got) != 0 {
+		t.Fatalf("FullTextIndexes after DROP INDEX = %#v", got)
+	}
+	if _, err := reopened.Query(ctx, `CREATE INDEX docs_bad ON docs USING fts (content) WITH (stemmer = 'porter')`); err == nil {
+		t.Fatal("CREATE INDEX accepted an unknown full-text parameter")
+	}
+}
+
+func orderedResultIDs(results *SearchResults) []string {
+	ids := make([]string, 0, len(results.Results))
+	for _, result := range results.Results {
+		ids = append(ids, result.ID)
+	}
+	return ids
+}
@@ -34,6 +34,11 @@ type Graph interface {
 	// Traversal.
 	BFS(start uint64, maxDepth int, visit graph.VisitAction, bitset *graph.Bitset, frontier *graph.FrontierBuf) error
 	BFSPattern(start uint64, edges []EdgePlan, maxDepth int, visit graph.VisitAction, bitset *graph.Bitset, frontier *graph.FrontierBuf) error
+	// ShortestWeightedPath returns the cheapest start→target path under
+	// opts (Dijkstra, or A* with opts.Heuristic), or ErrNoPath.
+	// ShortestWeightedPaths returns the cheapest path to every reachable node.
+	ShortestWeightedPath(start, target uint64, opts WeightedPathOptions) (WeightedPath, error)
+	ShortestWeightedPaths(start uint64, opts WeightedPathOptions) (map[uint64]WeightedPath, error)
 
 	// Pool management (caller-managed zero-alloc BFS).
 	GetBitset() (*graph.Bitset, error)
@@ -48,11 +53,22 @@ type Graph interface {
 
 	// Lifecycle.
 	Stats() graph.GraphStats
+	// GraphCentrality and CentralityAtLSN return PageRank normalized to the
+	// top-ranked node, read in O(1) from the published or per-snapshot
+	// vector; NodeCentrality and NodeCentralityAtLSN also report why a
+	// vector could not be computed. A background maintainer republishes the
+	// live vector after graph commits so reads rarely compute it themselves;
+	// RefreshPageRank publishes synchronously. PersonalizedPageRank teleports
+	// to seeds.
 	GraphCentrality(nodeID uint64) float64
 	CentralityAtLSN(nodeID uint64, snapshotLSN uint64) float64
-	// RecordPageRankPublication publishes maintenance metadata for a derived
-	// PageRank vector. It does not compute PageRank; callers should invoke this
-	// after atomically publishing their own vector.
+	NodeCentrality(nodeID uint64) (float64, error)
+	NodeCentralityAtLSN(nodeID uint64, snapshotLSN uint64) (float64, error)
+	RefreshPageRank() (*PageRankVector, error)
+	PageRankAtLSN(snapshotLSN uint64) (*PageRankVector, error)
+	PersonalizedPageRank(seeds []uint64, opts PageRankOptions) (*PageRankVector, error)
+	// RecordPageRankPublication publishes maintenance metadata for a PageRank
+	// vector computed outside the graph. RefreshPageRank records its own.
 	RecordPageRankPublication(snapshotLSN uint64, duration time.Duration)
 	Close() error
 }
@@ -80,6 +96,25 @@ type FrontierBuf = graph.FrontierBuf
 // EdgePlan describes a single edge band in a BFSPattern traversal.
 type EdgePlan = graph.EdgePlan
 
+// WeightedPathOptions configures a weighted shortest-path search: the
+// traversable edges and hop bounds, the cost source (edge weight or a numeric
+// edge property), an optional snapshot LSN, and an optional A* heuristic.
+type WeightedPathOptions = graph.WeightedPathOptions
+
+// WeightedPath is a cheapest path over node IDs with its accumulated cost.
+type WeightedPath = graph.WeightedPath
+
+// PageRankOptions configures a PageRank computation: damping, convergence,
+// edge kinds, and an optional snapshot LSN.
+type PageRankOptions = graph.PageRankOptions
+
+// PageRankVector is an immutable PageRank result keyed by node ID.
+type PageRankVector = graph.PageRankVector
+
+// ErrNoPath is returned by Graph.ShortestWeightedPath when no path satisfying
+// the options connects the requested nodes.
+var ErrNoPath = graph.ErrNoPath
+
 // VisitAction is invoked for each node during BFS traversal.
 type VisitAction = graph.VisitAction
 
@@ -151,3 +151,64 @@ func TestGraphCentrality_Temporal(t *testing.T) {
 		t.Errorf("T2: centrality should be 0 (edge removed), got %.4f", c2)
 	}
 }
+
+// TestGraphCentrality_PageRank verifies that centrality follows PageRank
+// rather than inbound degree and that personalized PageRank maps back to
+// record IDs.
+func TestGraphCentrality_PageRank(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(t.TempDir()+"/centrality_pagerank.libravdb"), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	gr, err := NewGraph(GraphConfig{})
+	if err != nil {
+		t.Fatalf("NewGraph: %v", err)
+	}
+	defer gr.Close()
+	col, err := db.CreateCollection(ctx, "docs", WithDimension(3), WithGraph(gr))
+	if err != nil {
+		t.Fatalf("CreateCollection: %v", err)
+	}
+	for _, id := range []string{"hub", "leaf1", "leaf2", "leaf3", "endorsed", "island"} {
+		if err := col.Insert(ctx, id, []float32{1, 0, 0}, nil); err != nil {
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+	node := func(id string) uint64 { return mustNodeID(t, db, ctx, "docs", id) }
+
+	// "endorsed" has a single inbound edge, from the hub that three leaves
+	// point at. Inbound degree ranks the hub far ahead; PageRank passes most
+	// of the hub's rank on to "endorsed".
+	txn := gr.BeginTxn()
+	for _, edge := range [][2]string{{"leaf1", "hub"}, {"leaf2", "hub"}, {"leaf3", "hub"}, {"hub", "endorsed"}} {
+		if err := txn.AddEdge(node(edge[0]), node(edge[1]), 1, 1); err != nil {
+			t.Fatalf("AddEdge: %v", err)
+		}
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	hub, endorsed := gr.GraphCentrality(node("hub")), gr.GraphCentrality(node("endorsed"))
+	if endorsed != 1 || hub <= gr.GraphCentrality(node("leaf1")) || hub >= endorsed {
+		t.Fatalf("centrality hub=%.4f endorsed=%.4f", hub, endorsed)
+	}
+	if gr.GraphCentrality(node("island")) != 0 {
+		t.Fatal("node without edges has non-zero centrality")
+	}
+	if stats := gr.Stats(); !stats.PageRankAvailable || stats.PageRankStale {
+		t.Fatalf("PageRank publication stats = %+v", stats)
+	}
+
+	scores, err := col.PersonalizedPageRank(ctx, []string{"leaf1"}, PageRankOptions{})
+	if err != nil {
+		t.Fatalf("PersonalizedPageRank: %v", err)
+	}
+	if len(scores) != 3 || scores["leaf1"] <= 0 || scores["hub"] <= 0 || scores["endorsed"] <= 0 {
+		t.Fatalf("personalized scores = %v, want leaf1 -> hub -> endorsed only", scores)
+	}
+	if _, err := col.PersonalizedPageRank(ctx, []string{"missing"}, PageRankOptions{}); err == nil {
+		t.Fatal("PersonalizedPageRank accepted an unknown seed")
+	}
+}
@@ -0,0 +1,45 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// PersonalizedPageRank ranks this collection's records by their proximity to
+// the seed records: the stationary distribution of a walk over the
+// collection's graph that restarts at a uniformly chosen seed. Records the
+// walk cannot reach are omitted. Nodes of other collections sharing the graph
+// take part in the walk but are not returned.
+func (c *Collection) PersonalizedPageRank(ctx context.Context, seedIDs []string, opts PageRankOptions) (map[string]float64, error) {
+	g := c.GetGraph()
+	if g == nil {
+		return nil, fmt.Errorf("collection %q has no graph", c.name)
+	}
+	if len(seedIDs) == 0 {
+		return nil, fmt.Errorf("personalized PageRank requires at least one seed")
+	}
+	seeds := make([]uint64, 0, len(seedIDs))
+	for _, id := range seedIDs {
+		nodeID, err := c.db.GetNodeID(ctx, c.name, id)
+		if err != nil {
+			return nil, fmt.Errorf("seed %q: %w", id, err)
+		}
+		seeds = append(seeds, nodeID)
+	}
+	vector, err := g.PersonalizedPageRank(seeds, opts)
+	if err != nil {
+		return nil, err
+	}
+	scores := make(map[string]float64, vector.Len())
+	for nodeID, score := range vector.Scores() {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		collection, recordID, err := c.db.ResolveNodeID(ctx, nodeID)
+		if err != nil || collection != c.name {
+			continue
+		}
+		scores[recordID] = score
+	}
+	return scores, nil
+}
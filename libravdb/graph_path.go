@@ -0,0 +1,206 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/xDarkicex/libravdb/internal/graph"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+// VectorDistanceHeuristic returns an A* heuristic for
+// Graph.ShortestWeightedPath that estimates the remaining cost from a node as
+// scale times the Euclidean distance between that node's vector and the
+// vector of targetID. Nodes outside this collection, or without a vector,
+// estimate zero. The heuristic is admissible only when scale times the
+// distance between any two records never exceeds the cheapest path between
+// them; choose scale from the edge cost model.
+func (c *Collection) VectorDistanceHeuristic(ctx context.Context, targetID string, scale float64) (func(nodeID uint64) float64, error) {
+	if scale < 0 || math.IsNaN(scale) || math.IsInf(scale, 0) {
+		return nil, fmt.Errorf("heuristic scale must be a non-negative number, got %g", scale)
+	}
+	target, err := c.Get(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if len(target.Vector) == 0 {
+		return nil, fmt.Errorf("record %q has no vector", targetID)
+	}
+	cache := make(map[uint64]float64)
+	return func(nodeID uint64) float64 {
+		if estimate, ok := cache[nodeID]; ok {
+			return estimate
+		}
+		estimate := 0.0
+		if collection, recordID, err := c.db.ResolveNodeID(ctx, nodeID); err == nil && collection == c.name {
+			if record, err := c.Get(ctx, recordID); err == nil {
+				estimate = scaledVectorDistance(record.Vector, target.Vector, scale)
+			}
+		}
+		cache[nodeID] = estimate
+		return estimate
+	}, nil
+}
+
+func scaledVectorDistance(a, b []float32, scale float64) float64 {
+	if scale == 0 || len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	return scale * math.Sqrt(float64(util.L2Distance_func(a, b)))
+}
+
+// graphJoinPathSource returns the rows and adjacency read by a
+// path-preserving graph join: the historical view for AS OF plans, otherwise
+// the rows visible in ctx and the live graph.
+func graphJoinPathSource(ctx context.Context, col *Collection, g Graph, snapshotLSN uint64) ([]Record, graphPathNeighborFunc, error) {
+	if snapshotLSN == 0 {
+		records, err := recordsVisibleInContext(ctx, col)
+		if err != nil {
+			return nil, nil, err
+		}
+		return records, func(nodeID uint64, direction int8) ([]graph.EdgeView, error) {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			return graphPatternNeighbors(g, nodeID, direction)
+		}, nil
+	}
+	temporalGraph, ok := g.(temporalGraphNeighbor)
+	if !ok {
+		return nil, nil, fmt.Errorf("collection %q graph does not support temporal traversal", col.name)
+	}
+	records := make([]Record, 0)
+	if err := col.ListVisibleAtLSN(ctx, snapshotLSN, func(record *Record) bool {
+		if record != nil {
+			records = append(records, *record)
+		}
+		return ctx.Err() == nil
+	}); err != nil {
+		return nil, nil, err
+	}
+	trackSQLRowsExamined(ctx, len(records))
+	return records, func(nodeID uint64, direction int8) ([]graph.EdgeView, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if direction > 0 {
+			return temporalGraph.NeighborsAtLSNWithProperties(nodeID, snapshotLSN)
+		}
+		if direction < 0 {
+			return temporalGraph.InboundNeighborsAtLSNWithProperties(nodeID, snapshotLSN)
+		}
+		outbound, err := temporalGraph.NeighborsAtLSNWithProperties(nodeID, snapshotLSN)
+		if err != nil {
+			return nil, err
+		}
+		inbound, err := temporalGraph.InboundNeighborsAtLSNWithProperties(nodeID, snapshotLSN)
+		if err != nil {
+			return nil, err
+		}
+		return append(outbound, inbound...), nil
+	}, nil
+}
+
+// weightedPathTarget pins a weighted shortestPath join to its only terminal
+// candidate so the search can stop there and use the A* heuristic.
+type weightedPathTarget struct {
+	node      uint64
+	heuristic func(nodeID uint64) float64
+}
+
+// weightedPathTargetFor resolves the A* target of a weighted shortestPath
+// join. A* is an optimization over Dijkstra with the same result, so it is
+// used only when the heuristic is requested and the terminal predicates leave
+// exactly one record with a vector; otherwise the search runs single-source.
+func (e *Executor) weightedPathTargetFor(ctx context.Context, plan *optimizer.PhysicalPlan, join optimizer.GraphJoinPlan, g Graph, records []Record, recordsByID map[string]Record) *weightedPathTarget {
+	cost := join.Weighted
+	if cost == nil || !join.Shortest || cost.HeuristicScale <= 0 {
+		return nil
+	}
+	terminalPredicates := graphJoinTerminalPredicates(plan.Predicates, join)
+	if len(terminalPredicates) == 0 {
+		return nil
+	}
+	var target *Record
+	var targetNode uint64
+	for i := range records {
+		if !recordMatchesPredicates(records[i], terminalPredicates) {
+			continue
+		}
+		node, err := e.db.GetNodeID(ctx, plan.CollectionName, records[i].ID)
+		if err != nil || !graphLabelsMatch(g, node, join.TerminalLabels) {
+			continue
+		}
+		if target != nil {
+			return nil
+		}
+		target, targetNode = &records[i], node
+	}
+	if target == nil || len(target.Vector) == 0 {
+		return nil
+	}
+	targetVector := target.Vector
+	return &weightedPathTarget{
+		node: targetNode,
+		heuristic: func(nodeID uint64) float64 {
+			collection, recordID, err := e.db.ResolveNodeID(ctx, nodeID)
+			if err != nil || collection != plan.CollectionName {
+				return 0
+			}
+			record, ok := recordsByID[recordID]
+			if !ok {
+				return 0
+			}
+			return scaledVectorDistance(record.Vector, targetVector, cost.HeuristicScale)
+		},
+	}
+}
+
+// graphJoinPaths expands one anchor of a path-preserving graph join. A
+// shortestPath join compiled from weightedShortestPath() keeps the cheapest
+// path to each terminal under the join's cost model; every other join
+// keeps the hop-ordered paths of collectGraphJoinPaths.
+func graphJoinPaths(join optimizer.GraphJoinPlan, sourceNode uint64, edges []EdgePlan, neighbors graphPathNeighborFunc, target *weightedPathTarget) (map[uint64]graphPathTraversalState, error) {
+	if join.Weighted == nil || !join.Shortest {
+		return collectGraphJoinPaths(sourceNode, edges, join.MaxHops, neighbors)
+	}
+	if len(edges) != 1 {
+		return nil, fmt.Errorf("weightedShortestPath requires a single edge pattern, e.g. (a)-[:ROAD*1..8]->(b)")
+	}
+	opts := graph.WeightedPathOptions{Edge: edges[0], CostProperty: join.Weighted.CostProperty}
+	if join.MaxHops > 0 && join.MaxHops < opts.Edge.Max {
+		opts.Edge.Max = join.MaxHops
+	}
+	var paths map[uint64]graph.WeightedPath
+	if target != nil {
+		opts.Heuristic = target.heuristic
+		path, err := graph.ShortestWeightedPathTo(sourceNode, target.node, opts, graph.NeighborSource(neighbors))
+		if err != nil && !errors.Is(err, graph.ErrNoPath) {
+			return nil, err
+		}
+		paths = make(map[uint64]graph.WeightedPath, 1)
+		if err == nil {
+			paths[target.node] = path
+		}
+	} else {
+		var err error
+		paths, err = graph.ShortestWeightedPaths(sourceNode, opts, graph.NeighborSource(neighbors))
+		if err != nil {
+			return nil, err
+		}
+	}
+	states := make(map[uint64]graphPathTraversalState, len(paths))
+	for node, path := range paths {
+		states[node] = graphPathTraversalState{
+			node:  node,
+			step:  len(path.Edges),
+			nodes: path.Nodes,
+			edges: path.Edges,
+			cost:  path.Cost,
+		}
+	}
+	return states, nil
+}
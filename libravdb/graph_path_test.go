@@ -0,0 +1,255 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/graph"
+)
+
+// weightedRouteFixture builds a small road network:
+//
+//	a -> b -> c -> d   weight 1 each, cost 1 each
+//	a -> d             weight 5, cost 10 (fewest hops)
+//	a -> e -> d        weight 1+3, cost 0.5+0.5 (cheapest by cost)
+//	a -> d ferry       weight 0.1, a different edge kind
+//
+// and returns the database, graph, and the commit LSN of that network.
+func weightedRouteFixture(t *testing.T, name string) (*Database, *Collection, Graph, uint64) {
+	t.Helper()
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(":memory:"+name), WithMetrics(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	gr, err := NewGraph(GraphConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { gr.Close() })
+	if !RegisterEdgeKind("WEIGHTED_ROUTE", 212) && ResolveEdgeKind("WEIGHTED_ROUTE") != 212 {
+		t.Fatal("register WEIGHTED_ROUTE")
+	}
+	if !RegisterEdgeKind("WEIGHTED_FERRY", 213) && ResolveEdgeKind("WEIGHTED_FERRY") != 213 {
+		t.Fatal("register WEIGHTED_FERRY")
+	}
+	places, err := db.CreateCollection(ctx, "places", WithDimension(2), WithGraph(gr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, vector := range map[string][]float32{
+		"a": {0, 0}, "b": {1, 0}, "c": {2, 0}, "d": {3, 0}, "e": {1.5, 1},
+	} {
+		if err := places.Insert(ctx, id, vector, map[string]interface{}{"name": id}); err != nil {
+			t.Fatalf("insert %s: %v", id, err)
+		}
+	}
+	node := func(id string) uint64 { return mustNodeID(t, db, ctx, "places", id) }
+	txn := gr.BeginTxn()
+	for _, edge := range []struct {
+		src, tgt string
+		weight   float32
+		kind     uint8
+		cost     float64
+	}{
+		{"a", "b", 1, 212, 1},
+		{"b", "c", 1, 212, 1},
+		{"c", "d", 1, 212, 1},
+		{"a", "d", 5, 212, 10},
+		{"a", "e", 1, 212, 0.5},
+		{"e", "d", 3, 212, 0.5},
+		{"a", "d", 0.1, 213, 0.01},
+	} {
+		if err := txn.AddEdgeWithProperties(node(edge.src), node(edge.tgt), edge.weight, edge.kind, map[string]interface{}{"cost": edge.cost}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	lsn, err := db.LatestCommitLSN(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, places, gr, lsn
+}
+
+func singleGraphPath(t *testing.T, rows *SearchResults, column string) GraphPath {
+	t.Helper()
+	if rows.Total != 1 || len(rows.Results) != 1 {
+		t.Fatalf("rows=%d, want one path: %#v", rows.Total, rows.Results)
+	}
+	path, ok := rows.Results[0].Metadata[column].(GraphPath)
+	if !ok {
+		t.Fatalf("%s type=%T value=%#v", column, rows.Results[0].Metadata[column], rows.Results[0].Metadata[column])
+	}
+	return path
+}
+
+func TestSQL_WeightedShortestPathByWeightAndProperty(t *testing.T) {
+	ctx := context.Background()
+	db, _, _, _ := weightedRouteFixture(t, "weighted-shortest-path")
+
+	hops, err := db.Query(ctx, `MATCH p = shortestPath((src)-[:WEIGHTED_ROUTE*1..4]->(dst))
+WHERE src.id = 'a' AND dst.id = 'd'
+RETURN p`)
+	if err != nil {
+		t.Fatalf("shortestPath: %v", err)
+	}
+	if path := singleGraphPath(t, hops, "p"); !reflect.DeepEqual(path.Nodes, []string{"a", "d"}) || path.Cost != 0 {
+		t.Fatalf("hop-counted path=%+v, want [a d] without cost", path)
+	}
+
+	for _, tc := range []struct {
+		name  string
+		query string
+		nodes []string
+		cost  float64
+	}{
+		{
+			name: "weight",
+			query: `MATCH p = weightedShortestPath((src)-[:WEIGHTED_ROUTE*1..4]->(dst), 'weight')
+WHERE src.id = 'a' AND dst.id = 'd'
+RETURN p`,
+			nodes: []string{"a", "b", "c", "d"},
+			cost:  3,
+		},
+		{
+			name: "property",
+			query: `MATCH p = weightedShortestPath((src)-[r:WEIGHTED_ROUTE*1..4]->(dst), 'r.cost')
+WHERE src.id = 'a' AND dst.id = 'd'
+RETURN p`,
+			nodes: []string{"a", "e", "d"},
+			cost:  1,
+		},
+		{
+			name: "A*",
+			query: `MATCH p = weightedShortestPath((src)-[:WEIGHTED_ROUTE*1..4]->(dst), 'cost', 0.1)
+WHERE src.id = 'a' AND dst.id = 'd'
+RETURN p`,
+			nodes: []string{"a", "e", "d"},
+			cost:  1,
+		},
+		{
+			name: "hop bound",
+			query: `MATCH p = weightedShortestPath((src)-[:WEIGHTED_ROUTE*1..2]->(dst), 'weight')
+WHERE src.id = 'a' AND dst.id = 'd'
+RETURN p`,
+			nodes: []string{"a", "e", "d"},
+			cost:  4,
+		},
+		{
+			name: "any kind",
+			query: `MATCH p = weightedShortestPath((src)-[*1..4]->(dst), 'weight')
+WHERE src.id = 'a' AND dst.id = 'd'
+RETURN p`,
+			nodes: []string{"a", "d"},
+			cost:  float64(float32(0.1)),
+		},
+	} {
+		rows, err := db.Query(ctx, tc.query)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		path := singleGraphPath(t, rows, "p")
+		if !reflect.DeepEqual(path.Nodes, tc.nodes) || path.Cost != tc.cost {
+			t.Fatalf("%s path=%+v, want %v cost %v", tc.name, path, tc.nodes, tc.cost)
+		}
+	}
+
+	// Without a terminal predicate every reachable terminal gets its cheapest
+	// path.
+	rows, err := db.Query(ctx, `MATCH p = weightedShortestPath((src)-[:WEIGHTED_ROUTE*1..4]->(dst), 'cost')
+WHERE src.id = 'a'
+RETURN dst.id AS target_id, p`)
+	if err != nil {
+		t.Fatalf("single-source weighted path: %v", err)
+	}
+	costs := make(map[string]float64, len(rows.Results))
+	for _, row := range rows.Results {
+		costs[row.Metadata["target_id"].(string)] = row.Metadata["p"].(GraphPath).Cost
+	}
+	if want := map[string]float64{"b": 1, "c": 2, "d": 1, "e": 0.5}; !reflect.DeepEqual(costs, want) {
+		t.Fatalf("single-source costs=%v, want %v", costs, want)
+	}
+
+	if _, err := db.Query(ctx, `MATCH (src)
+RETURN weightedShortestPath((src)-[*1..3]->(dst), 'cost') AS path`); err == nil {
+		t.Fatal("weightedShortestPath accepted as a pattern expression")
+	}
+}
+
+func TestSQL_WeightedShortestPathAsOfLSN(t *testing.T) {
+	ctx := context.Background()
+	db, _, gr, snapshot := weightedRouteFixture(t, "weighted-shortest-path-lsn")
+	b := mustNodeID(t, db, ctx, "places", "b")
+	d := mustNodeID(t, db, ctx, "places", "d")
+	txn := gr.BeginTxn()
+	if err := txn.AddEdgeWithProperties(b, d, 0.5, 212, map[string]interface{}{"cost": 0.1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := db.Query(ctx, `SELECT p
+FROM places src
+JOIN MATCH p = weightedShortestPath((src)-[:WEIGHTED_ROUTE*1..4]->(dst), 'weight')
+WHERE src.id = 'a' AND dst.id = 'd'`)
+	if err != nil {
+		t.Fatalf("live weighted path: %v", err)
+	}
+	if path := singleGraphPath(t, live, "p"); !reflect.DeepEqual(path.Nodes, []string{"a", "b", "d"}) || path.Cost != 1.5 {
+		t.Fatalf("live path=%+v, want [a b d] cost 1.5", path)
+	}
+	historical, err := db.QueryWithParams(ctx, `SELECT p
+FROM places AS OF LSN $snapshot src
+JOIN MATCH p = weightedShortestPath((src)-[:WEIGHTED_ROUTE*1..4]->(dst), 'weight')
+WHERE src.id = 'a' AND dst.id = 'd'`, QueryParams{"snapshot": snapshot})
+	if err != nil {
+		t.Fatalf("AS OF LSN weighted path: %v", err)
+	}
+	if path := singleGraphPath(t, historical, "p"); !reflect.DeepEqual(path.Nodes, []string{"a", "b", "c", "d"}) || path.Cost != 3 {
+		t.Fatalf("AS OF LSN path=%+v, want [a b c d] cost 3", path)
+	}
+}
+
+func TestGraphShortestWeightedPathWithVectorHeuristic(t *testing.T) {
+	ctx := context.Background()
+	db, places, gr, snapshot := weightedRouteFixture(t, "weighted-shortest-path-native")
+	a := mustNodeID(t, db, ctx, "places", "a")
+	d := mustNodeID(t, db, ctx, "places", "d")
+	routes := EdgePlan{Dir: 1, KindSet: graph.NewKindSet(212), Min: 1}
+
+	heuristic, err := places.VectorDistanceHeuristic(ctx, "d", 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dijkstra, err := gr.ShortestWeightedPath(a, d, WeightedPathOptions{Edge: routes, CostProperty: "cost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	astar, err := gr.ShortestWeightedPath(a, d, WeightedPathOptions{Edge: routes, CostProperty: "cost", Heuristic: heuristic})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dijkstra.Nodes, astar.Nodes) || dijkstra.Cost != 1 || astar.Cost != 1 || len(astar.Nodes) != 3 {
+		t.Fatalf("dijkstra=%+v A*=%+v, want the a->e->d route at cost 1", dijkstra, astar)
+	}
+	historical, err := gr.ShortestWeightedPath(a, d, WeightedPathOptions{Edge: routes, SnapshotLSN: snapshot})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if historical.Cost != 3 || len(historical.Nodes) != 4 {
+		t.Fatalf("historical path=%+v, want four nodes at cost 3", historical)
+	}
+	if _, err := gr.ShortestWeightedPath(d, a, WeightedPathOptions{Edge: routes}); !errors.Is(err, ErrNoPath) {
+		t.Fatalf("reverse route err=%v, want ErrNoPath", err)
+	}
+	if _, err := places.VectorDistanceHeuristic(ctx, "d", -1); err == nil {
+		t.Fatal("negative heuristic scale accepted")
+	}
+}
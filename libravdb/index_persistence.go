@@ -3,10 +3,13 @@ package libravdb
 import (
 	"context"
 	"fmt"
+	"io"
+	"strings"
 	"sync"
 
 	"github.com/xDarkicex/libravdb/internal/index"
 	"github.com/xDarkicex/libravdb/internal/storage"
+	"github.com/xDarkicex/libravdb/internal/storage/singlefile"
 )
 
 // indexPersistenceBridge implements singlefile.IndexSnapshotProvider to bridge
@@ -15,7 +18,10 @@ type indexPersistenceBridge struct {
 	engine storage.Engine
 	db     *Database
 	cache  map[string]index.Index
-	mu     sync.Mutex
+	// fullText holds the full-text indexes restored from the checkpoint,
+	// advanced by replayed WAL writes until their collection is opened.
+	fullText map[string][]*fullTextIndex
+	mu       sync.Mutex
 }
 
 func (b *indexPersistenceBridge) SetEngine(e storage.Engine) {
@@ -40,6 +46,29 @@ func (b *indexPersistenceBridge) closeCachedIndexes() {
 		}
 		delete(b.cache, name)
 	}
+	b.fullText = nil
+	b.mu.Unlock()
+}
+
+// takeFullTextIndexes returns and removes the full-text indexes restored for
+// the given collection.
+func (b *indexPersistenceBridge) takeFullTextIndexes(name string) []*fullTextIndex {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	restored := b.fullText[name]
+	delete(b.fullText, name)
+	return restored
+}
+
+func (b *indexPersistenceBridge) restoredFullText(name string) []*fullTextIndex {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.fullText[name]
+}
+
+func (b *indexPersistenceBridge) dropFullText(name string) {
+	b.mu.Lock()
+	delete(b.fullText, name)
 	b.mu.Unlock()
 }
 
@@ -56,6 +85,33 @@ func (b *indexPersistenceBridge) SerializeIndex(collectionName string) ([]byte,
 // frontier represented by that image. Async HNSW workers are paused only for
 // the serialization window; WAL admission remains independent and bounded.
 func (b *indexPersistenceBridge) SerializeIndexAt(collectionName string, checkpointLSN uint64) ([]byte, uint64, error) {
+	return b.serializeIndexAt(collectionName, checkpointLSN, func(idx index.Index) ([]byte, error) {
+		return idx.SerializeToBytes()
+	})
+}
+
+// extentIndex is implemented by indexes whose bulk region is streamed into
+// the database file instead of serialized in memory.
+type extentIndex interface {
+	SerializeExtent(region io.Writer) ([]byte, error)
+	DeserializeExtent(ctx context.Context, image []byte, region io.ReaderAt, size int64) error
+}
+
+// SerializeIndexExtentAt is SerializeIndexAt for indexes that stream their
+// region into extent; other indexes serialize to bytes as before.
+func (b *indexPersistenceBridge) SerializeIndexExtentAt(collectionName string, checkpointLSN uint64, extent io.Writer) ([]byte, uint64, bool, error) {
+	streamed := false
+	indexBytes, appliedLSN, err := b.serializeIndexAt(collectionName, checkpointLSN, func(idx index.Index) ([]byte, error) {
+		if x, ok := idx.(extentIndex); ok {
+			streamed = true
+			return x.SerializeExtent(extent)
+		}
+		return idx.SerializeToBytes()
+	})
+	return indexBytes, appliedLSN, streamed && indexBytes != nil, err
+}
+
+func (b *indexPersistenceBridge) serializeIndexAt(collectionName string, checkpointLSN uint64, serialize func(index.Index) ([]byte, error)) ([]byte, uint64, error) {
 	b.mu.Lock()
 	db := b.db
 	b.mu.Unlock()
@@ -87,10 +143,10 @@ func (b *indexPersistenceBridge) SerializeIndexAt(collectionName string, checkpo
 			// Omit the image so recovery rebuilds from checkpoint records.
 			return nil, checkpointLSN, nil
 		}
-		indexBytes, err := idx.SerializeToBytes()
+		indexBytes, err := serialize(idx)
 		return indexBytes, appliedLSN, err
 	}
-	indexBytes, err := idx.SerializeToBytes()
+	indexBytes, err := serialize(idx)
 	return indexBytes, checkpointLSN, err
 }
 
@@ -110,8 +166,36 @@ func (b *indexPersistenceBridge) DeserializeIndex(collectionName string, indexBy
 	return nil
 }
 
-// RebuildIndex rebuilds a collection's index from storage records.
+// DeserializeIndexExtent restores a collection's index from an image whose
+// region stays in the database file.
+func (b *indexPersistenceBridge) DeserializeIndexExtent(collectionName string, indexBytes []byte, extent singlefile.IndexExtent, config *storage.CollectionConfig) error {
+	idx, err := b.createIndexFromEngineConfig(config)
+	if err != nil {
+		extent.Close()
+		return fmt.Errorf("deserialize: create index for %s: %w", collectionName, err)
+	}
+	x, ok := idx.(extentIndex)
+	if !ok {
+		extent.Close()
+		idx.Close()
+		return fmt.Errorf("deserialize: index for %s cannot read an extent", collectionName)
+	}
+	if err := x.DeserializeExtent(context.Background(), indexBytes, extent, extent.Size()); err != nil {
+		idx.Close()
+		return fmt.Errorf("deserialize: load index for %s: %w", collectionName, err)
+	}
+	b.mu.Lock()
+	b.cache[collectionName] = idx
+	b.mu.Unlock()
+	return nil
+}
+
+// RebuildIndex rebuilds a collection's index from storage records. Restored
+// full-text indexes are dropped with the old index, because recovery stops
+// replaying writes into a collection it rebuilds; they are built again on
+// first use.
 func (b *indexPersistenceBridge) RebuildIndex(collectionName string, config *storage.CollectionConfig) error {
+	b.dropFullText(collectionName)
 	idx, err := b.createIndexFromEngineConfig(config)
 	if err != nil {
 		return fmt.Errorf("rebuild: create index for %s: %w", collectionName, err)
@@ -192,6 +276,9 @@ func (b *indexPersistenceBridge) ApplyIndexPut(
 	if err := idx.Insert(context.Background(), entryForIndex(DistanceMetric(config.Metric), entry)); err != nil {
 		return fmt.Errorf("insert recovered index entry %s/%s: %w", collectionName, entry.ID, err)
 	}
+	for _, restored := range b.restoredFullText(collectionName) {
+		restored.record(restored.analyze(entry.ID, entry.Metadata))
+	}
 	return nil
 }
 
@@ -213,6 +300,9 @@ func (b *indexPersistenceBridge) ApplyIndexDelete(
 	if err := deleteIndexEntry(context.Background(), idx, id, ordinal); err != nil {
 		return fmt.Errorf("delete recovered index entry %s/%s: %w", collectionName, id, err)
 	}
+	for _, restored := range b.restoredFullText(collectionName) {
+		restored.record(fullTextIndexWrite{id: id})
+	}
 	return nil
 }
 
@@ -234,7 +324,56 @@ func (b *indexPersistenceBridge) DiscardIndex(collectionName string) {
 		previous.Close()
 		delete(b.cache, collectionName)
 	}
+	delete(b.fullText, collectionName)
+	b.mu.Unlock()
+}
+
+// SerializeDerivedIndexes checkpoints the built full-text indexes of a
+// collection. Replicas omit them: a replica updates its postings after the
+// engine has applied a primary transaction, outside the writes that
+// beginFullTextWrite tracks.
+func (b *indexPersistenceBridge) SerializeDerivedIndexes(collectionName string, checkpointLSN uint64) (map[string][]byte, uint64, error) {
+	b.mu.Lock()
+	db := b.db
 	b.mu.Unlock()
+	if db == nil || db.config.replica {
+		return nil, checkpointLSN, nil
+	}
+	db.mu.RLock()
+	col, ok := db.collections[collectionName]
+	db.mu.RUnlock()
+	if !ok || col == nil {
+		return nil, checkpointLSN, nil
+	}
+	images, appliedLSN := col.fullTextImages(checkpointLSN)
+	return images, appliedLSN, nil
+}
+
+// RestoreDerivedIndex restores one checkpointed full-text index and applies
+// the records written after its frontier. It is adopted when its collection
+// opens, if the collection still declares it.
+func (b *indexPersistenceBridge) RestoreDerivedIndex(collectionName, derivedName string, image []byte, changed map[string]map[string]interface{}) error {
+	name, ok := strings.CutPrefix(derivedName, fullTextDerivedPrefix)
+	if !ok {
+		return fmt.Errorf("unknown derived index %q for %s", derivedName, collectionName)
+	}
+	restored, err := restoreFullTextIndex(image)
+	if err != nil {
+		return fmt.Errorf("restore full-text index %s/%s: %w", collectionName, name, err)
+	}
+	if restored.definition.Name != name {
+		return fmt.Errorf("restore full-text index %s/%s: image holds %q", collectionName, name, restored.definition.Name)
+	}
+	for id, metadata := range changed {
+		restored.record(restored.analyze(id, metadata))
+	}
+	b.mu.Lock()
+	if b.fullText == nil {
+		b.fullText = make(map[string][]*fullTextIndex)
+	}
+	b.fullText[collectionName] = append(b.fullText[collectionName], restored)
+	b.mu.Unlock()
+	return nil
 }
 
 // IndexTypeVersion returns the index type code and format version.
@@ -299,18 +438,21 @@ func (b *indexPersistenceBridge) SnapshotVectors(ctx context.Context) error {
 // with all data up front and the index owns its vector store internally.
 func (b *indexPersistenceBridge) createIndexFromEngineConfig(config *storage.CollectionConfig) (index.Index, error) {
 	libraConfig := &CollectionConfig{
-		Dimension:      config.Dimension,
-		Metric:         DistanceMetric(config.Metric),
-		IndexType:      IndexType(config.IndexType),
-		M:              config.M,
-		EfConstruction: config.EfConstruction,
-		EfSearch:       config.EfSearch,
-		ML:             config.ML,
-		NClusters:      config.NClusters,
-		NProbes:        config.NProbes,
-		RawVectorStore: config.RawVectorStore,
-		RawStoreCap:    config.RawStoreCap,
-		IDMapCapacity:  config.IDMapCapacity,
+		Dimension:        config.Dimension,
+		Metric:           DistanceMetric(config.Metric),
+		IndexType:        IndexType(config.IndexType),
+		M:                config.M,
+		EfConstruction:   config.EfConstruction,
+		EfSearch:         config.EfSearch,
+		ML:               config.ML,
+		NClusters:        config.NClusters,
+		NProbes:          config.NProbes,
+		RawVectorStore:   config.RawVectorStore,
+		RawStoreCap:      config.RawStoreCap,
+		IDMapCapacity:    config.IDMapCapacity,
+		PruneAlpha:       config.PruneAlpha,
+		IndexDirectory:   config.IndexDirectory,
+		Int8Construction: config.Int8Construction,
 	}
 	return createIndexForCollection(libraConfig, nil)
 }
@@ -0,0 +1,209 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/index/ivfpq"
+)
+
+const (
+	defaultRetrainSamplePerCluster = 64
+	defaultRetrainMinSample        = 4096
+	defaultRetrainMaxSizeSkew      = 4.0
+	defaultRetrainMaxErrorGrowth   = 1.5
+
+	// retrainSampleSeed keeps reservoir sampling, and therefore the
+	// retrained centroids, reproducible for an unchanged collection.
+	retrainSampleSeed = 42
+)
+
+// retrainableIndex is implemented by indexes that support online retraining.
+type retrainableIndex interface {
+	Drift(ctx context.Context, sample [][]float32) (*ivfpq.DriftReport, error)
+	Retrain(ctx context.Context, sample [][]float32, lookup ivfpq.VectorLookup) error
+	Rebalance(ctx context.Context, lookup ivfpq.VectorLookup, opts ivfpq.RebalanceOptions) (int, error)
+}
+
+// IndexDrift samples canonical storage and reports how far the collection's
+// IVF-PQ index has drifted from the state it was trained on.
+func (c *Collection) IndexDrift(ctx context.Context, sampleSize int) (*IndexDrift, error) {
+	c.mu.RLock()
+	idx, err := c.retrainableIndexLocked()
+	c.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	sample, err := c.retrainSample(ctx, sampleSize)
+	if err != nil {
+		return nil, err
+	}
+	report, err := idx.Drift(ctx, sample)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure index drift: %w", err)
+	}
+	return indexDriftFromReport(report), nil
+}
+
+// RetrainIndex measures drift on a sample from canonical storage and, when it
+// exceeds the configured thresholds, trains new coarse centroids and PQ
+// codebooks or rebalances oversized clusters. The replacement is built next
+// to the live index and swapped in atomically, so searches and writes are not
+// blocked while it is staged.
+func (c *Collection) RetrainIndex(ctx context.Context, opts *RetrainOptions) (*RetrainResult, error) {
+	if opts == nil {
+		opts = &RetrainOptions{}
+	}
+	maxSkew := opts.MaxSizeSkew
+	if maxSkew <= 0 {
+		maxSkew = defaultRetrainMaxSizeSkew
+	}
+	maxGrowth := opts.MaxErrorGrowth
+	if maxGrowth <= 0 {
+		maxGrowth = defaultRetrainMaxErrorGrowth
+	}
+
+	c.mu.Lock()
+	idx, err := c.retrainableIndexLocked()
+	if err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	if c.optimizationInProgress {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("optimization already in progress")
+	}
+	c.optimizationInProgress = true
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.optimizationInProgress = false
+		c.mu.Unlock()
+	}()
+
+	sample, err := c.retrainSample(ctx, opts.SampleSize)
+	if err != nil {
+		return nil, err
+	}
+	report, err := idx.Drift(ctx, sample)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure index drift: %w", err)
+	}
+	result := &RetrainResult{Before: indexDriftFromReport(report)}
+
+	lookup := c.retrainLookup()
+	switch {
+	case opts.RebalanceOnly:
+		splits, err := idx.Rebalance(ctx, lookup, ivfpq.RebalanceOptions{SplitFactor: maxSkew})
+		if err != nil {
+			return nil, fmt.Errorf("failed to rebalance index: %w", err)
+		}
+		result.Splits = splits
+	case opts.Force || report.ErrorGrowth() > maxGrowth || report.SizeSkew > maxSkew:
+		if err := idx.Retrain(ctx, sample, lookup); err != nil {
+			return nil, fmt.Errorf("failed to retrain index: %w", err)
+		}
+		result.Retrained = true
+	default:
+		return result, nil
+	}
+
+	after, err := idx.Drift(ctx, sample)
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure index drift: %w", err)
+	}
+	result.After = indexDriftFromReport(after)
+
+	c.mu.Lock()
+	c.lastOptimization = time.Now()
+	c.mu.Unlock()
+	return result, nil
+}
+
+// retrainableIndexLocked returns the collection index when it supports
+// online retraining. The caller must hold c.mu.
+func (c *Collection) retrainableIndexLocked() (retrainableIndex, error) {
+	if c.closed {
+		return nil, ErrCollectionClosed
+	}
+	if c.shards != nil {
+		return nil, fmt.Errorf("RetrainIndex is not supported for sharded collections")
+	}
+	idx, ok := c.index.(retrainableIndex)
+	if !ok {
+		return nil, fmt.Errorf("RetrainIndex requires an IVF-PQ index, collection uses %s", c.config.IndexType)
+	}
+	return idx, nil
+}
+
+// retrainSample reservoir-samples index-space vectors from canonical storage.
+func (c *Collection) retrainSample(ctx context.Context, size int) ([][]float32, error) {
+	if size <= 0 {
+		size = max(defaultRetrainSamplePerCluster*c.ivfpqConfig().NClusters, defaultRetrainMinSample)
+	}
+	metric := c.config.Metric
+	rng := rand.New(rand.NewSource(retrainSampleSeed))
+	sample := make([][]float32, 0, min(size, defaultRetrainMinSample))
+	seen := 0
+	err := c.storage.Iterate(ctx, func(entry *index.VectorEntry) error {
+		seen++
+		slot := len(sample)
+		if slot >= size {
+			slot = rng.Intn(seen)
+			if slot >= size {
+				return nil
+			}
+		}
+		vector := vectorForIndex(metric, entry.Vector)
+		if slot == len(sample) {
+			sample = append(sample, vector)
+		} else {
+			sample[slot] = vector
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample storage: %w", err)
+	}
+	return sample, nil
+}
+
+// retrainLookup reads canonical vectors by ordinal for re-encoding. Without
+// an ordinal provider the index re-encodes records from their current codes.
+func (c *Collection) retrainLookup() ivfpq.VectorLookup {
+	provider, ok := c.storage.(interface {
+		GetByOrdinal(uint32) ([]float32, error)
+	})
+	if !ok {
+		return nil
+	}
+	metric := c.config.Metric
+	return func(ordinal uint32) ([]float32, error) {
+		vector, err := provider.GetByOrdinal(ordinal)
+		if err != nil {
+			return nil, err
+		}
+		return vectorForIndex(metric, vector), nil
+	}
+}
+
+func indexDriftFromReport(report *ivfpq.DriftReport) *IndexDrift {
+	return &IndexDrift{
+		Generation:               report.Generation,
+		Clusters:                 report.Clusters,
+		Vectors:                  report.Vectors,
+		EmptyClusters:            report.EmptyClusters,
+		MinClusterSize:           report.MinClusterSize,
+		MaxClusterSize:           report.MaxClusterSize,
+		SizeSkew:                 report.SizeSkew,
+		CoarseError:              report.CoarseError,
+		QuantizationError:        report.QuantizationError,
+		TrainedCoarseError:       report.TrainedCoarseError,
+		TrainedQuantizationError: report.TrainedQuantizationError,
+		ErrorGrowth:              report.ErrorGrowth(),
+	}
+}
@@ -0,0 +1,118 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func retrainTestEntries(rng *rand.Rand, prefix string, count int, offset float32) []VectorEntry {
+	centers := make([][]float32, 4)
+	for i := range centers {
+		centers[i] = make([]float32, 8)
+		for d := range centers[i] {
+			centers[i][d] = float32(rng.NormFloat64()) + offset
+		}
+	}
+	entries := make([]VectorEntry, count)
+	for i := range entries {
+		center := centers[i%len(centers)]
+		vector := make([]float32, len(center))
+		for d := range vector {
+			vector[d] = center[d] + float32(rng.NormFloat64())*0.2
+		}
+		entries[i] = VectorEntry{ID: fmt.Sprintf("%s-%d", prefix, i), Vector: vector}
+	}
+	return entries
+}
+
+func TestRetrainIndexSwapsDriftedIVFPQ(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(testDBPath(t)))
+	if err != nil {
+		t.Fatalf("new database: %v", err)
+	}
+	defer db.Close()
+
+	collection, err := db.CreateCollection(ctx, "drift",
+		WithDimension(8),
+		WithMetric(L2Distance),
+		WithIVFPQ(8, 2),
+	)
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(5))
+	if err := collection.InsertBatch(ctx, retrainTestEntries(rng, "old", 200, 0)); err != nil {
+		t.Fatalf("insert original batch: %v", err)
+	}
+	grown := retrainTestEntries(rng, "new", 2000, 6)
+	if err := collection.InsertBatch(ctx, grown); err != nil {
+		t.Fatalf("insert grown batch: %v", err)
+	}
+
+	drift, err := collection.IndexDrift(ctx, 0)
+	if err != nil {
+		t.Fatalf("index drift: %v", err)
+	}
+	if drift.Vectors != 2200 || drift.ErrorGrowth < 2 {
+		t.Fatalf("drift was not detected: %+v", drift)
+	}
+
+	result, err := collection.RetrainIndex(ctx, nil)
+	if err != nil {
+		t.Fatalf("retrain index: %v", err)
+	}
+	if !result.Retrained || result.After == nil {
+		t.Fatalf("retrain result = %+v", result)
+	}
+	if result.After.Generation == result.Before.Generation || result.After.Vectors != 2200 {
+		t.Fatalf("retrain did not publish a new generation: before=%+v after=%+v", result.Before, result.After)
+	}
+	if result.After.ErrorGrowth > 1.2 || result.After.CoarseError >= result.Before.CoarseError {
+		t.Fatalf("retrain did not reduce error: before=%+v after=%+v", result.Before, result.After)
+	}
+
+	for _, entry := range []VectorEntry{grown[0], grown[777], grown[1999]} {
+		results, err := collection.Search(ctx, entry.Vector, 5)
+		if err != nil {
+			t.Fatalf("search %s: %v", entry.ID, err)
+		}
+		if len(results.Results) == 0 || results.Results[0].ID != entry.ID {
+			t.Fatalf("search %s returned %v", entry.ID, mmrIDs(results.Results))
+		}
+	}
+
+	again, err := collection.RetrainIndex(ctx, nil)
+	if err != nil {
+		t.Fatalf("second retrain: %v", err)
+	}
+	if again.Retrained || again.After != nil {
+		t.Fatalf("retrain ran without drift: %+v", again)
+	}
+}
+
+func TestRetrainIndexRequiresIVFPQ(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(testDBPath(t)))
+	if err != nil {
+		t.Fatalf("new database: %v", err)
+	}
+	defer db.Close()
+
+	collection, err := db.CreateCollection(ctx, "graph",
+		WithDimension(8),
+		WithHNSW(16, 100, 64),
+	)
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+	if _, err := collection.RetrainIndex(ctx, nil); err == nil {
+		t.Fatal("RetrainIndex accepted an HNSW collection")
+	}
+	if status := collection.GetOptimizationStatus(); status.InProgress {
+		t.Fatal("rejected retrain left optimization in progress")
+	}
+}
@@ -0,0 +1,81 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestInt8ConstructionCollectionSurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	dbPath := testDBPath(t)
+
+	db, err := Open(WithStoragePath(dbPath))
+	if err != nil {
+		t.Fatalf("new database: %v", err)
+	}
+	collection, err := db.CreateCollection(ctx, "docs",
+		WithDimension(16),
+		WithMetric(L2Distance),
+		WithHNSW(16, 100, 64),
+		WithHNSWInt8Construction(),
+	)
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	vectors := make(map[string][]float32, 600)
+	entries := make([]VectorEntry, 0, 600)
+	for i := 0; i < 600; i++ {
+		vector := make([]float32, 16)
+		for j := range vector {
+			vector[j] = rng.Float32()*2 - 1
+		}
+		id := fmt.Sprintf("doc-%d", i)
+		vectors[id] = vector
+		entries = append(entries, VectorEntry{ID: id, Vector: vector})
+	}
+	if err := collection.InsertBatch(ctx, entries); err != nil {
+		t.Fatalf("insert batch: %v", err)
+	}
+
+	assertNearest := func(c *Collection) {
+		t.Helper()
+		for _, id := range []string{"doc-1", "doc-300", "doc-599"} {
+			results, err := c.Search(ctx, vectors[id], 3)
+			if err != nil {
+				t.Fatalf("search %s: %v", id, err)
+			}
+			if len(results.Results) == 0 || results.Results[0].ID != id {
+				t.Fatalf("search %s returned %v", id, mmrIDs(results.Results))
+			}
+		}
+		stats := c.Stats(ctx).Int8ConstructionStats
+		if stats == nil || !stats.Trained {
+			t.Fatalf("int8 construction stats = %+v", stats)
+		}
+	}
+	assertNearest(collection)
+	if stats := collection.Stats(ctx).Int8ConstructionStats; stats.Checks == 0 || stats.Pruned+stats.Fallbacks != stats.Checks {
+		t.Fatalf("int8 screening was not recorded: %+v", stats)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	reopened, err := Open(WithStoragePath(dbPath))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	restored, err := reopened.GetCollection("docs")
+	if err != nil {
+		t.Fatalf("get collection: %v", err)
+	}
+	if !restored.config.Int8Construction {
+		t.Fatal("int8 construction was not restored")
+	}
+	assertNearest(restored)
+}
@@ -0,0 +1,324 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/xDarkicex/libravdb/internal/optimizer"
+)
+
+// defaultMMRFetchFactor sizes the candidate pool re-ranked by maximal marginal
+// relevance when the caller does not choose one.
+const defaultMMRFetchFactor = 4
+
+// mmrOptions configures maximal marginal relevance re-ranking for a
+// QueryBuilder.
+type mmrOptions struct {
+	enabled          bool
+	lambda           float64
+	fetchK           int
+	assignments      []LeidenAssignment
+	indexCommunities bool
+}
+
+// WithMMR re-ranks the query's results by maximal marginal relevance. The
+// query first fetches fetchK candidates, then repeatedly selects the candidate
+// maximising
+//
+//	lambda*sim(query, candidate) - (1-lambda)*max sim(candidate, selected)
+//
+// until Limit results are chosen. Similarities are cosine similarities of the
+// stored canonical vectors, whatever the collection's metric, and each
+// result keeps its original Score. lambda must be in [0, 1]: 1 is plain
+// relevance order and 0 is maximal diversity. A non-positive fetchK selects
+// four times the limit.
+func (qb *QueryBuilder) WithMMR(lambda float64, fetchK int) *QueryBuilder {
+	if qb.mmr == nil {
+		qb.mmr = &mmrOptions{}
+	}
+	qb.mmr.enabled = true
+	qb.mmr.lambda = lambda
+	qb.mmr.fetchK = fetchK
+	return qb
+}
+
+// WithMMRCommunities also diversifies WithMMR results by graph community:
+// a candidate in the same community as an already selected result is
+// treated as a duplicate of it. Assignments are keyed by graph node ID, as
+// returned by EpochLeidenResult.Assignments; records without a graph node or
+// an assignment are diversified by vector alone. It has no effect without
+// WithMMR.
+func (qb *QueryBuilder) WithMMRCommunities(assignments []LeidenAssignment) *QueryBuilder {
+	if qb.mmr == nil {
+		qb.mmr = &mmrOptions{}
+	}
+	qb.mmr.assignments = assignments
+	return qb
+}
+
+// WithMMRIndexCommunities is WithMMRCommunities using the HNSW communities
+// computed by the last Database.Maintain run on the collection.
+func (qb *QueryBuilder) WithMMRIndexCommunities() *QueryBuilder {
+	if qb.mmr == nil {
+		qb.mmr = &mmrOptions{}
+	}
+	qb.mmr.indexCommunities = true
+	return qb
+}
+
+func validateMMRLambda(lambda float64) error {
+	if math.IsNaN(lambda) || lambda < 0 || lambda > 1 {
+		return fmt.Errorf("MMR lambda must be between 0 and 1, got %v", lambda)
+	}
+	return nil
+}
+
+// mmrFetchDepth returns how many candidates to re-rank for limit results.
+func mmrFetchDepth(fetchK, limit int) int {
+	if fetchK <= 0 {
+		fetchK = defaultMMRFetchFactor * limit
+	}
+	return max(fetchK, limit)
+}
+
+// searchMMR fetches the oversampled candidate set and re-ranks it down to
+// the query limit.
+func (qb *QueryBuilder) searchMMR() (*SearchResults, error) {
+	if err := validateMMRLambda(qb.mmr.lambda); err != nil {
+		return nil, err
+	}
+	if len(qb.spaceVectors) > 0 {
+		return nil, fmt.Errorf("MMR cannot be combined with vector space queries")
+	}
+	communityOf, err := qb.mmrCommunities()
+	if err != nil {
+		return nil, err
+	}
+	limit := qb.limit
+	qb.limit = mmrFetchDepth(qb.mmr.fetchK, limit)
+	defer func() { qb.limit = limit }()
+
+	candidates, err := qb.searchCandidates()
+	if err != nil {
+		return nil, err
+	}
+	results := candidates.Results
+	if qb.thresholdSet {
+		results = qb.applyThreshold(results)
+	}
+	if err := hydrateMMRVectors(qb.ctx, qb.collection, results); err != nil {
+		return nil, err
+	}
+	results = selectMMR(qb.vector, results, limit, qb.mmr.lambda, communityOf)
+	return &SearchResults{Results: results, Took: candidates.Took, Total: len(results)}, nil
+}
+
+// mmrCommunities builds the community lookup requested by
+// WithMMRCommunities or WithMMRIndexCommunities, or nil when neither was.
+func (qb *QueryBuilder) mmrCommunities() (func(*SearchResult) (uint64, bool), error) {
+	return mmrCommunityLookup(qb.ctx, qb.collection, qb.mmr)
+}
+
+// mmrCommunityLookup builds the community lookup of opts for col. SQL
+// ORDER BY MMR(..., 'index') selects the index communities.
+func mmrCommunityLookup(ctx context.Context, col *Collection, opts *mmrOptions) (func(*SearchResult) (uint64, bool), error) {
+	switch {
+	case opts.indexCommunities:
+		type communityIndex interface {
+			CommunityOf(id string) (uint32, bool)
+		}
+		idx, ok := col.GetIndex().(communityIndex)
+		if !ok {
+			return nil, fmt.Errorf("MMR index communities require an HNSW index")
+		}
+		return func(result *SearchResult) (uint64, bool) {
+			community, ok := idx.CommunityOf(result.ID)
+			return uint64(community), ok
+		}, nil
+	case opts.assignments != nil:
+		if col.db == nil {
+			return nil, fmt.Errorf("MMR graph communities require a database-backed collection")
+		}
+		communities := make(map[uint64]uint64, len(opts.assignments))
+		for _, assignment := range opts.assignments {
+			communities[assignment.NodeID] = assignment.CommunityID
+		}
+		db, name := col.db, col.name
+		return func(result *SearchResult) (uint64, bool) {
+			nodeID, err := db.GetNodeID(ctx, name, result.ID)
+			if err != nil {
+				return 0, false
+			}
+			community, ok := communities[nodeID]
+			return community, ok
+		}, nil
+	}
+	return nil, nil
+}
+
+// hydrateMMRVectors loads the canonical vector of every result that arrived
+// without one.
+func hydrateMMRVectors(ctx context.Context, col *Collection, results []*SearchResult) error {
+	for _, result := range results {
+		if result == nil || len(result.Vector) > 0 || result.ID == "" {
+			continue
+		}
+		record, err := col.Get(ctx, result.ID)
+		if err != nil {
+			if errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			return fmt.Errorf("load vector for MMR candidate %q: %w", result.ID, err)
+		}
+		result.Vector = record.Vector
+	}
+	return nil
+}
+
+// selectMMR greedily picks up to k results by maximal marginal relevance.
+// Candidates are expected in relevance order, which also breaks ties. A
+// candidate without a usable vector has the lowest possible relevance and no
+// similarity to anything else.
+func selectMMR(query []float32, candidates []*SearchResult, k int, lambda float64, communityOf func(*SearchResult) (uint64, bool)) []*SearchResult {
+	if k <= 0 {
+		return nil
+	}
+	type mmrCandidate struct {
+		result       *SearchResult
+		unit         []float64
+		relevance    float64
+		redundancy   float64
+		community    uint64
+		hasCommunity bool
+		taken        bool
+	}
+	queryUnit := unitVector(query)
+	pool := make([]mmrCandidate, 0, len(candidates))
+	for _, result := range candidates {
+		if result == nil {
+			continue
+		}
+		c := mmrCandidate{result: result, relevance: -1, redundancy: -1}
+		if len(result.Vector) == len(query) {
+			c.unit = unitVector(result.Vector)
+		}
+		if c.unit != nil && queryUnit != nil {
+			c.relevance = dotFloat64(queryUnit, c.unit)
+		}
+		if communityOf != nil {
+			c.community, c.hasCommunity = communityOf(result)
+		}
+		pool = append(pool, c)
+	}
+	if k > len(pool) {
+		k = len(pool)
+	}
+
+	selected := make([]*SearchResult, 0, k)
+	for len(selected) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range pool {
+			c := &pool[i]
+			if c.taken {
+				continue
+			}
+			score := c.relevance
+			if len(selected) > 0 {
+				score = lambda*c.relevance - (1-lambda)*c.redundancy
+			}
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+		if best < 0 {
+			break
+		}
+		chosen := &pool[best]
+		chosen.taken = true
+		selected = append(selected, chosen.result)
+		for i := range pool {
+			c := &pool[i]
+			if c.taken {
+				continue
+			}
+			if c.unit != nil && chosen.unit != nil {
+				c.redundancy = math.Max(c.redundancy, dotFloat64(c.unit, chosen.unit))
+			}
+			if c.hasCommunity && chosen.hasCommunity && c.community == chosen.community {
+				c.redundancy = 1
+			}
+		}
+	}
+	return selected
+}
+
+func unitVector(vector []float32) []float64 {
+	var norm float64
+	for _, x := range vector {
+		norm += float64(x) * float64(x)
+	}
+	if norm == 0 || math.IsNaN(norm) || math.IsInf(norm, 0) {
+		return nil
+	}
+	norm = math.Sqrt(norm)
+	unit := make([]float64, len(vector))
+	for i, x := range vector {
+		unit[i] = float64(x) / norm
+	}
+	return unit
+}
+
+func dotFloat64(a, b []float64) float64 {
+	var sum float64
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+// executeSQLPlan executes a physical plan, applying its ORDER BY MMR
+// re-rank. The nearest-neighbour plan is widened to the candidate pool with
+// its OFFSET removed, re-ranked to OFFSET+LIMIT rows, and the OFFSET is then
+// applied to the diversified order.
+func (db *Database) executeSQLPlan(ctx context.Context, plan *optimizer.PhysicalPlan) (*SearchResults, error) {
+	if plan == nil || plan.MMR == nil {
+		return newExecutor(db).Execute(ctx, plan)
+	}
+	if len(plan.Joins) > 0 || len(plan.GraphJoins) > 0 || plan.HasGraphTraversal || plan.HasRRF {
+		return nil, fmt.Errorf("MMR is only supported in the ORDER BY of a single-table vector query")
+	}
+	if plan.Limit <= 0 {
+		return nil, fmt.Errorf("MMR ordering requires a LIMIT")
+	}
+	col, err := db.GetCollection(plan.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+	communityOf, err := mmrCommunityLookup(ctx, col, &mmrOptions{indexCommunities: plan.MMR.IndexCommunities})
+	if err != nil {
+		return nil, err
+	}
+	want := plan.Limit + plan.Offset
+	widened := *plan
+	widened.MMR = nil
+	widened.Limit = mmrFetchDepth(plan.MMR.FetchK, want)
+	widened.Offset = 0
+	candidates, err := newExecutor(db).Execute(ctx, &widened)
+	if err != nil {
+		return nil, err
+	}
+	if err := hydrateMMRVectors(ctx, col, candidates.Results); err != nil {
+		return nil, err
+	}
+	results := selectMMR(plan.QueryVector, candidates.Results, want, plan.MMR.Lambda, communityOf)
+	if plan.Offset >= len(results) {
+		results = nil
+	} else {
+		results = results[plan.Offset:]
+	}
+	candidates.Results = results
+	candidates.Total = len(results)
+	return candidates, nil
+}
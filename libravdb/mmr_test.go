@@ -0,0 +1,206 @@
+package libravdb
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func mmrIDs(results []*SearchResult) string {
+	ids := make([]string, len(results))
+	for i, result := range results {
+		ids[i] = result.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func mmrQueryIDs(t *testing.T, db *Database, sql string) string {
+	t.Helper()
+	results, err := db.Query(context.Background(), sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	return mmrIDs(results.Results)
+}
+
+func TestSelectMMRTradesRelevanceForDiversity(t *testing.T) {
+	candidates := []*SearchResult{
+		{ID: "x", Vector: []float32{1, 0}, Score: 0.9},
+		{ID: "y", Vector: []float32{0.8, 0.6}, Score: 0.8},
+		{ID: "z", Vector: []float32{0.6, -0.8}, Score: 0.7},
+	}
+	query := []float32{1, 0}
+	sameCommunity := func(result *SearchResult) (uint64, bool) {
+		if result.ID == "z" {
+			return 2, true
+		}
+		return 1, true
+	}
+	for _, tc := range []struct {
+		lambda      float64
+		k           int
+		communityOf func(*SearchResult) (uint64, bool)
+		want        string
+	}{
+		{1, 3, nil, "x,y,z"},
+		{0.6, 2, nil, "x,y"},
+		{0.6, 2, sameCommunity, "x,z"},
+		{0, 2, nil, "x,z"},
+		{0.6, 10, nil, "x,y,z"},
+	} {
+		got := selectMMR(query, candidates, tc.k, tc.lambda, tc.communityOf)
+		if ids := mmrIDs(got); ids != tc.want {
+			t.Fatalf("selectMMR(lambda=%v, k=%d) = %s, want %s", tc.lambda, tc.k, ids, tc.want)
+		}
+	}
+	if candidates[1].Score != 0.8 {
+		t.Fatal("selectMMR rewrote a result score")
+	}
+}
+
+func seedMMRDocs(t *testing.T, col *Collection) {
+	t.Helper()
+	ctx := context.Background()
+	for _, doc := range []struct {
+		id     string
+		vector []float32
+	}{
+		{"a1", []float32{1, 0, 0}},
+		{"a2", []float32{0.99, 0.05, 0}},
+		{"a3", []float32{0.98, 0, 0.05}},
+		{"b", []float32{0.6, 0.8, 0}},
+		{"c", []float32{0.5, 0, 0.866}},
+	} {
+		if err := col.Insert(ctx, doc.id, normalize(doc.vector), nil); err != nil {
+			t.Fatalf("Insert(%s): %v", doc.id, err)
+		}
+	}
+}
+
+func TestQueryBuilderWithMMR(t *testing.T) {
+	ctx := context.Background()
+	db := openTempDB(t, "mmr_builder")
+	defer db.Close()
+	col, err := db.CreateCollection(ctx, "docs", WithDimension(3), WithFlat())
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedMMRDocs(t, col)
+	query := []float32{1, 0, 0}
+
+	plain, err := col.Query(ctx).WithVector(query).Limit(3).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := mmrIDs(plain.Results); ids != "a1,a2,a3" {
+		t.Fatalf("plain results = %s", ids)
+	}
+	diverse, err := col.Query(ctx).WithVector(query).Limit(3).WithMMR(0.3, 0).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := mmrIDs(diverse.Results); ids != "a1,c,b" {
+		t.Fatalf("MMR results = %s, want a1,c,b", ids)
+	}
+	if diverse.Results[0].Score != plain.Results[0].Score {
+		t.Fatalf("MMR changed the score of a1: %v != %v", diverse.Results[0].Score, plain.Results[0].Score)
+	}
+	// A candidate pool no larger than the limit leaves nothing to diversify.
+	narrow, err := col.Query(ctx).WithVector(query).Limit(3).WithMMR(0.3, 3).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(narrow) != 3 || narrow[0].ID != "a1" || narrow[1].ID != "a2" && narrow[1].ID != "a3" {
+		t.Fatalf("MMR over the top 3 = %+v", narrow)
+	}
+
+	if _, err := col.Query(ctx).WithVector(query).Limit(3).WithMMR(1.5, 0).Execute(); err == nil || !strings.Contains(err.Error(), "between 0 and 1") {
+		t.Fatalf("lambda 1.5 error = %v", err)
+	}
+	if _, err := col.Query(ctx).WithVector(query).Limit(3).WithMMR(0.5, 0).WithMMRIndexCommunities().Execute(); err == nil || !strings.Contains(err.Error(), "HNSW") {
+		t.Fatalf("index communities on a flat index error = %v", err)
+	}
+}
+
+func TestQueryBuilderMMRCommunities(t *testing.T) {
+	ctx := context.Background()
+	db := openTempDB(t, "mmr_communities")
+	defer db.Close()
+	g, err := NewGraph(GraphConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	col, err := db.CreateCollection(ctx, "docs", WithDimension(3), WithFlat(), WithGraph(g))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedMMRDocs(t, col)
+	var assignments []LeidenAssignment
+	for id, community := range map[string]uint64{"a1": 1, "b": 1, "c": 1, "a2": 2, "a3": 2} {
+		nodeID, err := db.GetNodeID(ctx, "docs", id)
+		if err != nil {
+			t.Fatalf("GetNodeID(%s): %v", id, err)
+		}
+		assignments = append(assignments, LeidenAssignment{NodeID: nodeID, CommunityID: community})
+	}
+
+	// By vector alone c is the most novel second result, but it shares a1's
+	// community, so one of the near duplicates from the other community wins.
+	query := []float32{1, 0, 0}
+	results, err := col.Query(ctx).WithVector(query).Limit(2).WithMMR(0.3, 0).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := mmrIDs(results.Results); ids != "a1,c" {
+		t.Fatalf("MMR results = %s, want a1,c", ids)
+	}
+	results, err = col.Query(ctx).WithVector(query).Limit(2).WithMMR(0.3, 0).WithMMRCommunities(assignments).Execute()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := mmrIDs(results.Results); ids != "a1,a2" && ids != "a1,a3" {
+		t.Fatalf("community MMR results = %s, want a1 and a near duplicate", ids)
+	}
+}
+
+func TestSQLOrderByMMR(t *testing.T) {
+	ctx := context.Background()
+	db := openTempDB(t, "mmr_sql")
+	defer db.Close()
+	exec(t, db, `CREATE TABLE docs (id TEXT PRIMARY KEY, embedding VECTOR(3))`)
+	seedMMRDocs(t, getColl(t, db, "docs"))
+
+	if got := mmrQueryIDs(t, db, `SELECT id FROM docs ORDER BY VECTOR_DISTANCE(embedding, '[1,0,0]') LIMIT 3`); got != "a1,a2,a3" {
+		t.Fatalf("nearest = %s", got)
+	}
+	if got := mmrQueryIDs(t, db, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]', 0.3) LIMIT 3`); got != "a1,c,b" {
+		t.Fatalf("MMR order = %s, want a1,c,b", got)
+	}
+	if got := mmrQueryIDs(t, db, `SELECT id FROM docs ORDER BY mmr(embedding, '[1,0,0]', 0.3, 5) ASC LIMIT 2 OFFSET 1`); got != "c,b" {
+		t.Fatalf("MMR with OFFSET = %s, want c,b", got)
+	}
+	result, err := db.QueryWithParams(ctx, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]', $lambda) LIMIT 3`, QueryParams{"lambda": 0.3})
+	if err != nil {
+		t.Fatalf("MMR with a parameter: %v", err)
+	}
+	if ids := mmrIDs(result.Results); ids != "a1,c,b" {
+		t.Fatalf("parameterised MMR order = %s", ids)
+	}
+
+	// Without a maintenance run no record has an index community, so the
+	// order is the vector-only one.
+	if got := mmrQueryIDs(t, db, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]', 0.3, NULL, 'index') LIMIT 3`); got != "a1,c,b" {
+		t.Fatalf("MMR with index communities = %s, want a1,c,b", got)
+	}
+	if err := db.RegisterScalarFunction("mmr", nil, FloatField, func(context.Context, []interface{}) (interface{}, error) { return nil, nil }); err == nil {
+		t.Fatal("registering a function named mmr succeeded")
+	}
+
+	alterMustFail(t, db, `SELECT id, MMR(embedding, '[1,0,0]', 0.3) FROM docs LIMIT 3`, "ORDER BY expression")
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]', 0.3, 5, 'graph') LIMIT 3`, "'index' or 'none'")
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]', 2) LIMIT 3`, "between 0 and 1")
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]') LIMIT 3`, "MMR expects")
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]', 0.5, 0) LIMIT 3`, "fetch_k")
+	alterMustFail(t, db, `SELECT id FROM docs ORDER BY MMR(embedding, '[1,0,0]', 0.5) DESC LIMIT 3`, "DESC")
+}
@@ -246,6 +246,19 @@ func WithFastHNSW() CollectionOption {
 	return WithHNSW(16, 100, 50)
 }
 
+// WithHNSWInt8Construction screens HNSW construction and search candidates
+// with 8-bit scalar codes. Each code carries its exact reconstruction error,
+// so a candidate is skipped only when a triangle-inequality lower bound
+// proves it cannot enter the beam; all others are scored in FP32 and the
+// resulting graph and recall match an unscreened build. It requires the L2
+// metric and cannot be combined with quantization.
+func WithHNSWInt8Construction() CollectionOption {
+	return func(c *CollectionConfig) error {
+		c.Int8Construction = true
+		return nil
+	}
+}
+
 // WithRawVectorStoreMemory keeps raw vector payloads in the default in-memory store.
 func WithRawVectorStoreMemory() CollectionOption {
 	return func(c *CollectionConfig) error {
@@ -380,6 +393,34 @@ func WithFSQQuantization(bits int, trainRatio float64, levels ...int) Collection
 	}
 }
 
+// WithBinaryQuantization enables 1-bit binary quantization. Each dimension is
+// stored as a single bit relative to threshold (quant.BinaryThresholdMean or
+// quant.BinaryThresholdSign), optionally after a random orthogonal rotation
+// that spreads variance evenly across bits. Searches scan the binary codes
+// and rescore the best rescoreK candidates with full-precision vectors. With
+// a rescoreK of zero, HNSW rescores its whole search beam and Flat keeps its
+// exact scan.
+func WithBinaryQuantization(threshold string, rotate bool, rescoreK int) CollectionOption {
+	return func(c *CollectionConfig) error {
+		if rescoreK < 0 {
+			return fmt.Errorf("rescore k must be non-negative, got %d", rescoreK)
+		}
+		config := &quant.QuantizationConfig{
+			Type:       quant.BinaryQuantization,
+			Bits:       1,
+			TrainRatio: 1.0,
+			Threshold:  threshold,
+			Rotation:   rotate,
+		}
+		if err := config.Validate(); err != nil {
+			return fmt.Errorf("invalid binary quantization config: %w", err)
+		}
+		c.Quantization = config
+		c.RescoreK = rescoreK
+		return nil
+	}
+}
+
 // WithFlat configures the collection to use a Flat (brute-force) index
 func WithFlat() CollectionOption {
 	return func(c *CollectionConfig) error {
@@ -404,6 +445,41 @@ func WithIVFPQ(nClusters, nProbes int) CollectionOption {
 	}
 }
 
+// WithDiskANN configures the collection to use a DiskANN index: a Vamana
+// graph whose full-precision vectors and adjacency live on disk while
+// searches navigate PQ codes held in memory. r bounds the out-degree of
+// every node, l is the search list size used by inserts and queries, and
+// alpha (at least 1) is the pruning ratio that keeps long-range edges.
+func WithDiskANN(r, l int, alpha float64) CollectionOption {
+	return func(c *CollectionConfig) error {
+		if r < 2 {
+			return fmt.Errorf("DiskANN degree R must be at least 2")
+		}
+		if l <= 0 {
+			return fmt.Errorf("DiskANN list size L must be positive")
+		}
+		if alpha < 1 {
+			return fmt.Errorf("DiskANN alpha must be at least 1")
+		}
+		c.IndexType = DiskANN
+		c.M = r
+		c.EfConstruction = l
+		c.EfSearch = l
+		c.PruneAlpha = alpha
+		return nil
+	}
+}
+
+// WithDiskANNDirectory sets the directory of the DiskANN node file. The
+// default is the system temporary directory; point it at a local SSD for
+// collections larger than memory.
+func WithDiskANNDirectory(dir string) CollectionOption {
+	return func(c *CollectionConfig) error {
+		c.IndexDirectory = dir
+		return nil
+	}
+}
+
 // WithAutoIndexSelection enables automatic index type selection based on collection size.
 // Small collections (<2000 vectors) use Flat, medium collections use HNSW, large collections use IVF-PQ.
 // The thresholds can be customized via WithAutoIndexThresholds.
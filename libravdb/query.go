@@ -20,6 +20,9 @@ type QueryBuilder struct {
 	thresholdSet bool
 	efSearch     int // Override collection default
 	graphFilter  GraphFilter
+	spaceVectors []spaceQuery
+	rrfK         float64
+	mmr          *mmrOptions
 }
 
 // Filter represents a metadata filter condition (deprecated, use filter package)
@@ -83,6 +86,23 @@ func (qb *QueryBuilder) WithVector(vector []float32) *QueryBuilder {
 	return qb
 }
 
+// WithSpaceVector adds a query vector for a named vector space declared with
+// WithVectorSpace. A query with a single vector searches that space directly.
+// Several vectors, including one set by WithVector, are ranked independently
+// and fused by reciprocal-rank fusion, in which case SearchResult.Score and
+// WithThreshold refer to the fused score.
+func (qb *QueryBuilder) WithSpaceVector(space string, vector []float32) *QueryBuilder {
+	qb.spaceVectors = append(qb.spaceVectors, spaceQuery{space: space, vector: append([]float32(nil), vector...)})
+	return qb
+}
+
+// WithRRFK sets the reciprocal-rank fusion constant used when a query fuses
+// several vectors. Non-positive values select the default of 60.
+func (qb *QueryBuilder) WithRRFK(k float64) *QueryBuilder {
+	qb.rrfK = k
+	return qb
+}
+
 // WithFilter adds a metadata filter using the new filter system
 func (qb *QueryBuilder) WithFilter(f filter.Filter) *QueryBuilder {
 	qb.filters = append(qb.filters, f)
@@ -363,7 +383,7 @@ func (qb *QueryBuilder) WithGraphFilter(filter GraphFilter) *QueryBuilder {
 
 // Execute runs the query and returns results
 func (qb *QueryBuilder) Execute() (*SearchResults, error) {
-	if qb.vector == nil {
+	if qb.vector == nil && len(qb.spaceVectors) == 0 {
 		return nil, fmt.Errorf("query vector is required")
 	}
 
@@ -371,33 +391,11 @@ func (qb *QueryBuilder) Execute() (*SearchResults, error) {
 		return nil, fmt.Errorf("limit must be positive, got %d", qb.limit)
 	}
 
-	// Optimize filters before execution
-	optimizedFilters := qb.optimizeFilters()
-
-	// Wrap graph filter with threshold filter if threshold is set
-	var execFilter GraphFilter = qb.graphFilter
-	if qb.thresholdSet {
-		execFilter = &thresholdGraphFilter{
-			base:      qb.graphFilter,
-			threshold: qb.threshold,
-		}
-	}
-
-	// Get initial search results from vector index
-	result, err := qb.collection.searchWithGraphFilterAndEf(qb.ctx, qb.vector, qb.getSearchLimit(), qb.efSearch, execFilter)
+	result, err := qb.search()
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply metadata filters if present
-	if len(optimizedFilters) > 0 {
-		filteredResults, err := qb.applyFilters(result.Results, optimizedFilters)
-		if err != nil {
-			return nil, fmt.Errorf("failed to apply filters: %w", err)
-		}
-		result.Results = filteredResults
-	}
-
 	// Apply threshold filtering
 	if qb.thresholdSet {
 		result.Results = qb.applyThreshold(result.Results)
@@ -415,11 +413,47 @@ func (qb *QueryBuilder) Execute() (*SearchResults, error) {
 	}, nil
 }
 
+// search returns the filtered vector candidates for Execute and List,
+// re-ranked by WithMMR when it is set.
+func (qb *QueryBuilder) search() (*SearchResults, error) {
+	if qb.mmr != nil && qb.mmr.enabled {
+		return qb.searchMMR()
+	}
+	return qb.searchCandidates()
+}
+
+// searchCandidates runs the vector search itself. Metadata filters are
+// checked by the index as it reaches each candidate, so the result set is not
+// capped by a post-filtered oversample.
+func (qb *QueryBuilder) searchCandidates() (*SearchResults, error) {
+	if len(qb.spaceVectors) > 0 {
+		return qb.searchSpaces()
+	}
+	optimizedFilters := qb.optimizeFilters()
+	if len(optimizedFilters) > 0 {
+		prefilter, err := qb.planPrefilter(optimizedFilters)
+		if err != nil {
+			return nil, err
+		}
+		return qb.searchPrefiltered(prefilter, optimizedFilters)
+	}
+
+	// Wrap graph filter with threshold filter if threshold is set
+	var execFilter GraphFilter = qb.graphFilter
+	if qb.thresholdSet {
+		execFilter = &thresholdGraphFilter{
+			base:      qb.graphFilter,
+			threshold: qb.threshold,
+		}
+	}
+	return qb.collection.searchWithGraphFilterAndEf(qb.ctx, qb.vector, qb.limit, qb.efSearch, execFilter)
+}
+
 // List executes a metadata-only or vector-backed query and returns stable record rows.
 // When no vector is provided, it scans the collection and applies metadata filters and limit.
 func (qb *QueryBuilder) List() ([]Record, error) {
 	var records []Record
-	if qb.vector == nil {
+	if qb.vector == nil && len(qb.spaceVectors) == 0 {
 		all, err := qb.collection.ListAll(qb.ctx)
 		if err != nil {
 			return nil, err
@@ -430,7 +464,7 @@ func (qb *QueryBuilder) List() ([]Record, error) {
 			return nil, fmt.Errorf("limit must be positive, got %d", qb.limit)
 		}
 
-		results, err := qb.collection.searchWithGraphFilterAndEf(qb.ctx, qb.vector, qb.getSearchLimit(), qb.efSearch, qb.graphFilter)
+		results, err := qb.search()
 		if err != nil {
 			return nil, err
 		}
@@ -576,32 +610,6 @@ func (qb *QueryBuilder) applyFiltersToRecords(records []Record, filters []filter
 	return filteredRecords, nil
 }
 
-// getSearchLimit calculates the search limit to use for the initial vector search
-// This accounts for potential filtering that might reduce the result set
-func (qb *QueryBuilder) getSearchLimit() int {
-	if len(qb.filters) == 0 {
-		return qb.limit
-	}
-
-	// Estimate the selectivity of all filters combined
-	combinedSelectivity := 1.0
-	for _, f := range qb.filters {
-		combinedSelectivity *= f.EstimateSelectivity()
-	}
-
-	// Increase search limit to account for filtering
-	// Use a minimum multiplier to ensure we get enough candidates
-	multiplier := 1.0 / combinedSelectivity
-	if multiplier < 2.0 {
-		multiplier = 2.0
-	}
-	if multiplier > 10.0 {
-		multiplier = 10.0
-	}
-
-	return int(float64(qb.limit) * multiplier)
-}
-
 func (qb *QueryBuilder) applyFilterEntries(entries []*filter.VectorEntry, filters []filter.Filter) ([]*filter.VectorEntry, error) {
 	filterEntries := entries
 	for _, f := range filters {
@@ -0,0 +1,429 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/filter"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// queryPrefilter is the plan for QueryBuilder metadata filters. Filters are
+// not evaluated up front: the index asks a metadataGraphFilter about each
+// candidate it reaches, which reads only that record's metadata. An equality
+// filter on a WithIndexedFields field first narrows admission to its posting
+// list, and a selective enough filter is answered by scoring the qualifying
+// records exactly instead.
+type queryPrefilter struct {
+	// postings holds the ordinals of an indexed equality filter's posting
+	// list, and postingIDs its record IDs; both are nil without one.
+	postings   *ordinalBitmap
+	postingIDs []string
+	// matched bounds the number of qualifying records: the posting list
+	// length, or the estimated matches of unindexed filters.
+	matched     int
+	selectivity float64
+	corpusSize  int
+}
+
+// exact reports whether scoring every qualifying record is cheaper and more
+// reliable than a filtered graph traversal. A posting list is scored record
+// by record, so its length decides; unindexed filters can only be answered
+// exactly by scanning the collection, which is worth it only when the
+// filters are too selective for traversal to find the limit. The thresholds
+// are the same provisional defaults the SQL hybrid dispatcher uses for its
+// ExactCandidateScan operator.
+func (p *queryPrefilter) exact() bool {
+	if p.postings == nil {
+		return p.selectivity <= exactCandidateFraction
+	}
+	if p.matched > exactCandidateCap {
+		return false
+	}
+	if p.matched <= exactCandidateCap/10 {
+		return true
+	}
+	return p.selectivity <= exactCandidateFraction
+}
+
+// planPrefilter estimates how many records qualify for filters. Only
+// top-level equality filters are eligible for posting lookups because any
+// other shape may admit records outside one posting. The posting list is
+// resolved to ordinals without loading any record.
+func (qb *QueryBuilder) planPrefilter(filters []filter.Filter) (*queryPrefilter, error) {
+	col := qb.collection
+	p := &queryPrefilter{corpusSize: col.countRecords(), selectivity: 1}
+	for _, f := range filters {
+		equality, ok := f.(*filter.EqualityFilter)
+		if !ok {
+			continue
+		}
+		ids, indexed, err := col.lookupIndexedMetadataIDs(qb.ctx, equality.Field, equality.Value)
+		if err != nil {
+			return nil, err
+		}
+		if !indexed {
+			continue
+		}
+		postings, err := col.postingBitmap(qb.ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		p.postings, p.postingIDs, p.matched = postings, ids, len(ids)
+		if p.corpusSize > 0 {
+			p.selectivity = float64(len(ids)) / float64(p.corpusSize)
+		}
+		postings.selectivity = p.selectivity
+		return p, nil
+	}
+	for _, f := range filters {
+		p.selectivity *= f.EstimateSelectivity()
+	}
+	p.matched = int(p.selectivity * float64(p.corpusSize))
+	return p, nil
+}
+
+// postingBitmap maps posting list IDs to their shard-local ordinals. IDs
+// deleted since the posting list was built are skipped.
+func (c *Collection) postingBitmap(ctx context.Context, ids []string) (*ordinalBitmap, error) {
+	membership := &mapMembership{m: make(map[uint32]bool, len(ids))}
+	var byMembership []ordinalMembership
+	if c.shards != nil {
+		byMembership = make([]ordinalMembership, len(c.shards))
+		for i := range byMembership {
+			byMembership[i] = &mapMembership{m: make(map[uint32]bool)}
+		}
+	}
+	for _, id := range ids {
+		ordinal, err := c.getOrdinal(ctx, id)
+		if err != nil {
+			if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		if byMembership != nil {
+			byMembership[shardForID(id)].set(ordinal)
+		} else {
+			membership.set(ordinal)
+		}
+	}
+	var global ordinalMembership = membership
+	if byMembership != nil {
+		global = emptyMembership{}
+	}
+	return &ordinalBitmap{membership: global, byMembership: byMembership}, nil
+}
+
+// searchPrefiltered runs a filtered top-k query. Selective filters are scored
+// exactly; broader filters run the ANN search with the filters checked as the
+// index reaches each candidate and a selectivity-aware ef. If traversal comes
+// up short, the selectivity it observed decides between one wider traversal
+// and an exact scan, so a selective filter never silently loses recall.
+func (qb *QueryBuilder) searchPrefiltered(prefilter *queryPrefilter, filters []filter.Filter) (*SearchResults, error) {
+	if prefilter.postings != nil && prefilter.matched == 0 {
+		return &SearchResults{Results: []*SearchResult{}}, nil
+	}
+	if prefilter.exact() {
+		return qb.scanPrefiltered(prefilter, filters)
+	}
+
+	selectivity := prefilter.selectivity
+	var took time.Duration
+	for attempt := 0; attempt < 2; attempt++ {
+		ef := computeBinomialStart(qb.limit, selectivity, iterativeDefaultEpsilon)
+		if qb.efSearch > ef {
+			ef = qb.efSearch
+		}
+		admission := qb.collection.newMetadataGraphFilter(qb.ctx, filters, prefilter.postings, qb.graphFilter, selectivity)
+		var execFilter GraphFilter = admission
+		if qb.thresholdSet {
+			execFilter = &thresholdGraphFilter{base: admission, threshold: qb.threshold}
+		}
+		result, err := qb.collection.searchWithGraphFilterAndEf(qb.ctx, qb.vector, qb.limit, ef, execFilter)
+		if err != nil {
+			return nil, err
+		}
+		if err := admission.err(); err != nil {
+			return nil, fmt.Errorf("failed to apply filters: %w", err)
+		}
+		took += result.Took
+
+		// A record can change between admission and hydration; re-check the
+		// returned rows so an update cannot surface a record that no longer
+		// qualifies.
+		filtered, err := qb.applyFilters(result.Results, filters)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply filters: %w", err)
+		}
+		result.Results = filtered
+		if len(result.Results) >= qb.limit || prefilter.postings != nil && len(result.Results) >= prefilter.matched {
+			result.Took = took
+			return result, nil
+		}
+
+		observed := admission.observedSelectivity()
+		if prefilter.postings != nil {
+			observed *= prefilter.selectivity
+		}
+		if observed <= exactCandidateFraction || computeBinomialStart(qb.limit, observed, iterativeDefaultEpsilon) <= ef {
+			break
+		}
+		selectivity = observed
+	}
+	exact, err := qb.scanPrefiltered(prefilter, filters)
+	if err != nil {
+		return nil, err
+	}
+	exact.Took += took
+	return exact, nil
+}
+
+// scanPrefiltered computes the exact top-k over the qualifying records using
+// the same public score as the index search path.
+func (qb *QueryBuilder) scanPrefiltered(prefilter *queryPrefilter, filters []filter.Filter) (*SearchResults, error) {
+	candidates, err := qb.qualifyingRecords(prefilter, filters)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]Record, 0, len(candidates))
+	for _, record := range candidates {
+		records = append(records, record)
+	}
+	scored := scoreAndSelectTopK(qb.collection, records, qb.vector, qb.limit)
+	for _, result := range scored.Results {
+		record := candidates[result.ID]
+		result.Vector = record.Vector
+		result.Metadata = record.Metadata
+		result.Version = record.Version
+	}
+	return scored, nil
+}
+
+// qualifyingRecords loads the records that satisfy filters and the caller's
+// GraphFilter. Only exact scoring loads candidate records: the posting list
+// when there is one, otherwise the whole collection.
+func (qb *QueryBuilder) qualifyingRecords(prefilter *queryPrefilter, filters []filter.Filter) (map[string]Record, error) {
+	col := qb.collection
+	var records []Record
+	if prefilter.postings != nil {
+		records = make([]Record, 0, len(prefilter.postingIDs))
+		for _, id := range prefilter.postingIDs {
+			record, err := col.Get(qb.ctx, id)
+			if err != nil {
+				if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			records = append(records, record)
+		}
+	} else {
+		var err error
+		if records, err = col.ListAll(qb.ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	entries, err := qb.applyFilterEntries(filterEntriesFromRecords(records), filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply filters: %w", err)
+	}
+	byID := make(map[string]Record, len(records))
+	for _, record := range records {
+		byID[record.ID] = record
+	}
+	var shardFilters []GraphFilter
+	if col.shards != nil && qb.graphFilter != nil {
+		shardFilters = make([]GraphFilter, len(col.shards))
+		for i := range shardFilters {
+			shardFilters[i] = qb.graphFilter
+			if factory, ok := qb.graphFilter.(interface{ ForShard(int) GraphFilter }); ok {
+				shardFilters[i] = factory.ForShard(i)
+			}
+		}
+	}
+	candidates := make(map[string]Record, len(entries))
+	for _, entry := range entries {
+		record, ok := byID[entry.ID]
+		if !ok {
+			continue
+		}
+		graphFilter := qb.graphFilter
+		if shardFilters != nil {
+			graphFilter = shardFilters[shardForID(record.ID)]
+		}
+		if graphFilter != nil && !graphFilter.Test(uint64(record.Ordinal)) {
+			continue
+		}
+		candidates[record.ID] = record
+	}
+	return candidates, nil
+}
+
+// ordinalMetadataProvider is the narrow storage lookup used to evaluate
+// metadata filters during traversal without cloning vectors. Storage engines
+// that do not provide it resolve the ID and load the record instead.
+type ordinalMetadataProvider interface {
+	GetMetadataByOrdinal(context.Context, uint32) (string, map[string]interface{}, error)
+}
+
+// metadataGraphFilter admits an index candidate when its record satisfies
+// the QueryBuilder filters. Each ordinal is decided once per search. One
+// filter serves one search goroutine; the sharded search takes a separate
+// filter per shard from ForShard, and the shards share only the counters.
+type metadataGraphFilter struct {
+	ctx         context.Context
+	col         *Collection
+	store       storage.Collection
+	shardStores []storage.Collection
+	filters     []filter.Filter
+	postings    GraphFilter
+	base        GraphFilter
+	selectivity float64
+	decided     map[uint32]bool
+	stats       *metadataFilterStats
+}
+
+type metadataFilterStats struct {
+	tested   atomic.Int64
+	admitted atomic.Int64
+	mu       sync.Mutex
+	firstErr error
+}
+
+func (c *Collection) newMetadataGraphFilter(ctx context.Context, filters []filter.Filter, postings *ordinalBitmap, base GraphFilter, selectivity float64) *metadataGraphFilter {
+	c.mu.RLock()
+	store := c.storage
+	var shardStores []storage.Collection
+	if c.shards != nil {
+		shardStores = make([]storage.Collection, len(c.shards))
+		for i := range c.shards {
+			shardStores[i] = c.shards[i].storage
+		}
+	}
+	c.mu.RUnlock()
+
+	f := &metadataGraphFilter{
+		ctx:         ctx,
+		col:         c,
+		store:       store,
+		shardStores: shardStores,
+		filters:     filters,
+		base:        base,
+		selectivity: selectivity,
+		decided:     make(map[uint32]bool),
+		stats:       &metadataFilterStats{},
+	}
+	if postings != nil {
+		f.postings = postings
+	}
+	return f
+}
+
+func (f *metadataGraphFilter) Test(idx uint64) bool {
+	ordinal := uint32(idx)
+	if admitted, ok := f.decided[ordinal]; ok {
+		return admitted
+	}
+	admitted := f.admit(ordinal)
+	f.decided[ordinal] = admitted
+	return admitted
+}
+
+func (f *metadataGraphFilter) admit(ordinal uint32) bool {
+	if f.base != nil && !f.base.Test(uint64(ordinal)) {
+		return false
+	}
+	if f.postings != nil && !f.postings.Test(uint64(ordinal)) {
+		return false
+	}
+	if f.store == nil {
+		return false
+	}
+	f.stats.tested.Add(1)
+	var id string
+	var metadata map[string]interface{}
+	if provider, ok := f.store.(ordinalMetadataProvider); ok {
+		var err error
+		if id, metadata, err = provider.GetMetadataByOrdinal(f.ctx, ordinal); err != nil {
+			// The index can still hold an ordinal whose record was deleted.
+			return false
+		}
+	} else {
+		var err error
+		if id, err = f.store.GetIDByOrdinal(f.ctx, ordinal); err != nil {
+			return false
+		}
+		entry, err := f.store.Get(f.ctx, id)
+		if err != nil {
+			return false
+		}
+		metadata = entry.Metadata
+	}
+	if f.col.recordExpired(f.ctx, metadata, time.Now()) {
+		return false
+	}
+	entries := []*filter.VectorEntry{{ID: id, Metadata: metadata}}
+	for _, predicate := range f.filters {
+		var err error
+		if entries, err = predicate.Apply(f.ctx, entries); err != nil {
+			f.stats.fail(err)
+			return false
+		}
+		if len(entries) == 0 {
+			return false
+		}
+	}
+	f.stats.admitted.Add(1)
+	return true
+}
+
+// Selectivity lets HNSW enable its sparse-filter rescue for a selective
+// metadata filter, as it does for an ordinal bitmap.
+func (f *metadataGraphFilter) Selectivity() float64 { return f.selectivity }
+
+// ForShard binds the filter to one shard's storage and ordinal space.
+func (f *metadataGraphFilter) ForShard(shard int) GraphFilter {
+	shardFilter := *f
+	shardFilter.decided = make(map[uint32]bool)
+	shardFilter.store = nil
+	if shard >= 0 && shard < len(f.shardStores) {
+		shardFilter.store = f.shardStores[shard]
+	}
+	if factory, ok := f.postings.(interface{ ForShard(int) GraphFilter }); ok {
+		shardFilter.postings = factory.ForShard(shard)
+	}
+	if factory, ok := f.base.(interface{ ForShard(int) GraphFilter }); ok {
+		shardFilter.base = factory.ForShard(shard)
+	}
+	return &shardFilter
+}
+
+// observedSelectivity is the fraction of evaluated candidates that
+// qualified, or 0 when traversal evaluated none.
+func (f *metadataGraphFilter) observedSelectivity() float64 {
+	tested := f.stats.tested.Load()
+	if tested == 0 {
+		return 0
+	}
+	return float64(f.stats.admitted.Load()) / float64(tested)
+}
+
+func (f *metadataGraphFilter) err() error {
+	f.stats.mu.Lock()
+	defer f.stats.mu.Unlock()
+	return f.stats.firstErr
+}
+
+func (s *metadataFilterStats) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.firstErr == nil {
+		s.firstErr = err
+	}
+}
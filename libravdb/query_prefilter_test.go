@@ -0,0 +1,207 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/filter"
+)
+
+func seedPrefilterCollection(t *testing.T, opts ...CollectionOption) (*Collection, [][]float32) {
+	t.Helper()
+	db, err := Open(WithStoragePath(testDBPath(t)))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx := context.Background()
+	opts = append([]CollectionOption{WithDimension(8), WithMetric(L2Distance)}, opts...)
+	col, err := db.CreateCollection(ctx, "tenants", opts...)
+	if err != nil {
+		t.Fatalf("create collection: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(7))
+	vectors := make([][]float32, 0, 1200)
+	for i := 0; i < 1200; i++ {
+		vector := make([]float32, 8)
+		for d := range vector {
+			vector[d] = rng.Float32()
+		}
+		vectors = append(vectors, vector)
+		tenant := fmt.Sprintf("t%d", i%4)
+		if i%100 == 0 {
+			// 12 of 1200 rows (1%) belong to the rare tenant.
+			tenant = "rare"
+		}
+		if err := col.Insert(ctx, fmt.Sprintf("r%04d", i), vector, map[string]interface{}{
+			"tenant": tenant,
+			"rank":   i,
+		}); err != nil {
+			t.Fatalf("insert %d: %v", i, err)
+		}
+	}
+	return col, vectors
+}
+
+func bruteForcePrefilterIDs(vectors [][]float32, query []float32, k int, keep func(int) bool) []string {
+	type scored struct {
+		id   string
+		dist float32
+	}
+	var all []scored
+	for i, vector := range vectors {
+		if !keep(i) {
+			continue
+		}
+		var dist float32
+		for d := range vector {
+			diff := vector[d] - query[d]
+			dist += diff * diff
+		}
+		all = append(all, scored{id: fmt.Sprintf("r%04d", i), dist: dist})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].dist < all[j].dist })
+	if len(all) > k {
+		all = all[:k]
+	}
+	ids := make([]string, len(all))
+	for i, s := range all {
+		ids[i] = s.id
+	}
+	return ids
+}
+
+func TestQueryBuilderPrefilterSelectiveFilterReturnsFullLimit(t *testing.T) {
+	for _, indexed := range []bool{false, true} {
+		t.Run(fmt.Sprintf("indexed=%v", indexed), func(t *testing.T) {
+			var opts []CollectionOption
+			if indexed {
+				opts = append(opts, WithIndexedFields("tenant"))
+			}
+			col, vectors := seedPrefilterCollection(t, opts...)
+			query := vectors[3]
+
+			results, err := col.Query(context.Background()).
+				WithVector(query).
+				Eq("tenant", "rare").
+				Limit(10).
+				Execute()
+			if err != nil {
+				t.Fatalf("execute: %v", err)
+			}
+			want := bruteForcePrefilterIDs(vectors, query, 10, func(i int) bool { return i%100 == 0 })
+			if len(results.Results) != len(want) {
+				t.Fatalf("got %d results, want %d", len(results.Results), len(want))
+			}
+			for i, result := range results.Results {
+				if result.ID != want[i] {
+					t.Fatalf("result %d = %s, want %s", i, result.ID, want[i])
+				}
+				if result.Metadata["tenant"] != "rare" {
+					t.Fatalf("result %s has tenant %v", result.ID, result.Metadata["tenant"])
+				}
+				if result.Version == 0 || len(result.Vector) != 8 {
+					t.Fatalf("result %s was not hydrated: %+v", result.ID, result)
+				}
+			}
+		})
+	}
+}
+
+func TestQueryBuilderPrefilterBroadFilterUsesIndexTraversal(t *testing.T) {
+	col, vectors := seedPrefilterCollection(t)
+	query := vectors[11]
+
+	results, err := col.Query(context.Background()).
+		WithVector(query).
+		Eq("tenant", "t1").
+		Lt("rank", 1000).
+		Limit(25).
+		Execute()
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(results.Results) != 25 {
+		t.Fatalf("got %d results, want 25", len(results.Results))
+	}
+	for _, result := range results.Results {
+		if result.Metadata["tenant"] != "t1" {
+			t.Fatalf("result %s has tenant %v", result.ID, result.Metadata["tenant"])
+		}
+	}
+}
+
+func TestQueryBuilderPrefilterNoMatches(t *testing.T) {
+	col, vectors := seedPrefilterCollection(t)
+	results, err := col.Query(context.Background()).
+		WithVector(vectors[0]).
+		Eq("tenant", "missing").
+		Limit(5).
+		Execute()
+	if err != nil {
+		t.Fatalf("execute: %v", err)
+	}
+	if len(results.Results) != 0 {
+		t.Fatalf("got %d results, want none", len(results.Results))
+	}
+}
+
+func TestMetadataGraphFilterChecksCandidatesOnce(t *testing.T) {
+	col, _ := seedPrefilterCollection(t)
+	ctx := context.Background()
+	rare, err := col.Get(ctx, "r0100")
+	if err != nil {
+		t.Fatal(err)
+	}
+	common, err := col.Get(ctx, "r0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	admission := col.newMetadataGraphFilter(ctx, []filter.Filter{filter.NewEqualityFilter("tenant", "rare")}, nil, nil, 0.01)
+	if !admission.Test(uint64(rare.Ordinal)) || admission.Test(uint64(common.Ordinal)) {
+		t.Fatal("metadata filter admitted the wrong candidates")
+	}
+	if !admission.Test(uint64(rare.Ordinal)) {
+		t.Fatal("a repeated test changed its decision")
+	}
+	if got := admission.observedSelectivity(); got != 0.5 {
+		t.Fatalf("observed selectivity = %v, want 0.5 over two evaluated candidates", got)
+	}
+	if admission.Test(1 << 30) {
+		t.Fatal("an unknown ordinal was admitted")
+	}
+}
+
+func TestQueryPrefilterExactThresholds(t *testing.T) {
+	cases := []struct {
+		indexed     bool
+		matched     int
+		selectivity float64
+		want        bool
+	}{
+		{indexed: true, matched: 10, selectivity: 0.5, want: true},
+		{indexed: true, matched: exactCandidateCap / 10, selectivity: 0.9, want: true},
+		{indexed: true, matched: exactCandidateCap / 2, selectivity: exactCandidateFraction / 2, want: true},
+		{indexed: true, matched: exactCandidateCap / 2, selectivity: 0.25, want: false},
+		{indexed: true, matched: exactCandidateCap + 1, selectivity: 0.001, want: false},
+		// Unindexed filters are only scanned when they are very selective,
+		// however small the estimated match count.
+		{matched: 10, selectivity: 0.1, want: false},
+		{matched: exactCandidateCap * 10, selectivity: exactCandidateFraction / 2, want: true},
+	}
+	for _, tc := range cases {
+		p := &queryPrefilter{matched: tc.matched, selectivity: tc.selectivity}
+		if tc.indexed {
+			p.postings = &ordinalBitmap{selectivity: tc.selectivity}
+		}
+		if got := p.exact(); got != tc.want {
+			t.Fatalf("exact(indexed=%v, matched=%d, selectivity=%v) = %v, want %v", tc.indexed, tc.matched, tc.selectivity, got, tc.want)
+		}
+	}
+}
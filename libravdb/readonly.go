@@ -0,0 +1,26 @@
+package libravdb
+
+import "github.com/xDarkicex/libravdb/internal/storage/singlefile"
+
+var (
+	// ErrReadOnly is returned by writes against a database opened with
+	// WithReadOnly.
+	ErrReadOnly = singlefile.ErrReadOnly
+	// ErrDatabaseLocked reports a database file another process, or another
+	// Database in this process, holds open in a conflicting mode. A writer
+	// excludes everyone else; readers only exclude writers.
+	ErrDatabaseLocked = singlefile.ErrDatabaseLocked
+)
+
+// WithReadOnly opens an existing database without modifying it. Any number
+// of read-only Databases may share the file, but not with a writer: Open
+// fails with ErrDatabaseLocked while a writer has it open, and a writer's
+// Open fails while readers do. Recovery replays the WAL in memory only.
+// Writes, DDL and Vacuum fail with ErrReadOnly; Backup works. A database
+// that still needs a format migration cannot be opened read-only.
+func WithReadOnly() Option {
+	return func(c *Config) error {
+		c.readOnly = true
+		return nil
+	}
+}
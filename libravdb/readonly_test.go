@@ -0,0 +1,55 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadOnlyOpenSharesFileWithReaders(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "shared.libravdb")
+	db, err := Open(WithStoragePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := db.CreateCollection(ctx, "docs", WithDimension(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "a", []float32{1, 0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(WithStoragePath(path), WithReadOnly()); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("read-only Open beside a writer: %v, want ErrDatabaseLocked", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := Open(WithStoragePath(path), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer first.Close()
+	second, err := Open(WithStoragePath(path), WithReadOnly())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer second.Close()
+	if _, err := Open(WithStoragePath(path)); !errors.Is(err, ErrDatabaseLocked) {
+		t.Fatalf("writer Open beside readers: %v, want ErrDatabaseLocked", err)
+	}
+
+	docs, err = second.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := docs.Get(ctx, "a"); err != nil {
+		t.Fatalf("Get on read-only database: %v", err)
+	}
+	if err := docs.Insert(ctx, "b", []float32{0, 1}, nil); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("Insert on read-only database: %v, want ErrReadOnly", err)
+	}
+}
@@ -0,0 +1,486 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/catalog"
+	"github.com/xDarkicex/libravdb/internal/storage"
+	"github.com/xDarkicex/libravdb/internal/storage/singlefile"
+)
+
+// WALTransaction is one committed transaction in shipping form: the WAL
+// frames as the primary wrote them, from TxBegin through TxCommit, with every
+// checksum verified. Frames of an encrypted primary are shipped decrypted;
+// secure the transport.
+type WALTransaction = storage.WALTransaction
+
+var (
+	// ErrWALTruncated reports that the WAL a follower asked for was folded
+	// into a snapshot by compaction or backup. Re-seed the follower from a
+	// fresh backup.
+	ErrWALTruncated = singlefile.ErrWALTruncated
+	// ErrReplicaReadOnly is returned by writes against a Replica.
+	ErrReplicaReadOnly = singlefile.ErrReplicaReadOnly
+)
+
+// walStreamBatch bounds how many transactions one storage read collects
+// while holding the engine's read lock.
+const walStreamBatch = 256
+
+// WALStream iterates over the transactions committed after fromLSN in commit
+// order. It ends once it reaches the end of the log, including transactions
+// that commit while it runs; call it again with the last CommitLSN to
+// continue. Iteration stops at the first error, such as ErrWALTruncated when
+// fromLSN predates the retained WAL.
+func (db *Database) WALStream(ctx context.Context, fromLSN uint64) iter.Seq2[WALTransaction, error] {
+	return func(yield func(WALTransaction, error) bool) {
+		db.mu.RLock()
+		closed := db.closed
+		engine := db.storage
+		db.mu.RUnlock()
+		if closed {
+			yield(WALTransaction{}, ErrDatabaseClosed)
+			return
+		}
+		reader, ok := engine.(storage.WALReader)
+		if !ok {
+			yield(WALTransaction{}, fmt.Errorf("storage engine does not support WAL shipping"))
+			return
+		}
+		cursor := storage.WALCursor{LSN: fromLSN}
+		for {
+			batch, next, err := reader.ReadWAL(ctx, cursor, walStreamBatch)
+			if err != nil {
+				yield(WALTransaction{}, err)
+				return
+			}
+			for _, tx := range batch {
+				if !yield(tx, nil) {
+					return
+				}
+			}
+			if len(batch) < walStreamBatch {
+				return
+			}
+			cursor = next
+		}
+	}
+}
+
+// ReplicationSource is the primary a Replica follows. *Database implements
+// it directly; a follower in another process can implement it over any
+// transport that carries backups and WALTransaction values.
+type ReplicationSource interface {
+	WALStream(ctx context.Context, fromLSN uint64) iter.Seq2[WALTransaction, error]
+	Backup(ctx context.Context, destPath string) error
+}
+
+// Replica is a read-only database that follows a primary by applying its
+// committed WAL transactions. Reads go through the embedded Database;
+// writes fail with ErrReplicaReadOnly. ALTER TABLE commits the SQL catalog
+// with its transaction; other catalog metadata that is not carried in the
+// WAL, such as constraints declared by CREATE TABLE after the replica was
+// seeded, is only refreshed by re-seeding.
+type Replica struct {
+	*Database
+	source  ReplicationSource
+	applier storage.WALApplier
+	applyMu sync.Mutex
+	// unapplied holds the changes of transactions storage applied but the
+	// runtime state did not take in; CatchUp re-derives them from storage
+	// before it applies anything else.
+	unapplied []storage.WALChange
+}
+
+// OpenReplica opens the replica stored at path, seeding it from a backup of
+// source first when the file does not exist yet. Call CatchUp or Follow to
+// apply the primary's later transactions.
+func OpenReplica(path string, source ReplicationSource, opts ...Option) (*Replica, error) {
+	if source == nil {
+		return nil, fmt.Errorf("replication source must not be nil")
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := source.Backup(context.Background(), path); err != nil {
+			return nil, fmt.Errorf("seed replica: %w", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("stat replica: %w", err)
+	}
+	opts = append(append([]Option(nil), opts...), WithStoragePath(path), func(c *Config) error {
+		c.replica = true
+		return nil
+	})
+	db, err := Open(opts...)
+	if err != nil {
+		return nil, err
+	}
+	applier, ok := db.storage.(storage.WALApplier)
+	if !ok {
+		_ = db.Close()
+		return nil, fmt.Errorf("storage engine does not support WAL replication")
+	}
+	return &Replica{Database: db, source: source, applier: applier}, nil
+}
+
+// AppliedLSN returns the LSN through which the replica holds every committed
+// primary transaction; it is the position CatchUp resumes from. After a
+// failed CatchUp, reads may lag it until a later CatchUp succeeds.
+func (r *Replica) AppliedLSN() uint64 {
+	return r.applier.AppliedLSN()
+}
+
+// CatchUp applies every transaction the primary committed after AppliedLSN
+// and returns how many were applied. Storage takes in a transaction before
+// the indexes and other runtime state derived from it; if that second step
+// fails, the collections the transaction touched are loaded from storage
+// again, here or at the next CatchUp, and handles obtained before are closed.
+func (r *Replica) CatchUp(ctx context.Context) (int, error) {
+	r.applyMu.Lock()
+	defer r.applyMu.Unlock()
+	if err := r.rederive(ctx); err != nil {
+		return 0, fmt.Errorf("re-derive replicated state: %w", err)
+	}
+	applied := 0
+	for tx, err := range r.source.WALStream(ctx, r.applier.AppliedLSN()) {
+		if err != nil {
+			return applied, err
+		}
+		changes, err := r.applier.ApplyWAL(ctx, tx)
+		if err != nil {
+			return applied, fmt.Errorf("apply transaction %d (commit LSN %d): %w", tx.TxID, tx.CommitLSN, err)
+		}
+		if err := r.Database.applyReplicatedChanges(ctx, changes); err != nil {
+			// Storage holds the transaction durably and the stream resumes
+			// after it, so it is not applied again. Rebuild the runtime
+			// state it touched from storage instead.
+			r.unapplied = append(r.unapplied, changes...)
+			if rerr := r.rederive(ctx); rerr != nil {
+				return applied, fmt.Errorf("apply transaction %d (commit LSN %d): %w; re-derive: %w", tx.TxID, tx.CommitLSN, err, rerr)
+			}
+		}
+		applied++
+	}
+	return applied, nil
+}
+
+// rederive rebuilds the runtime state of unapplied transactions. Caller must
+// hold r.applyMu.
+func (r *Replica) rederive(ctx context.Context) error {
+	if len(r.unapplied) == 0 {
+		return nil
+	}
+	if err := r.Database.rederiveReplicatedChanges(ctx, r.unapplied); err != nil {
+		return err
+	}
+	r.unapplied = nil
+	return nil
+}
+
+// Follow calls CatchUp every interval until ctx is done, returning the
+// context's error, or the first CatchUp error.
+func (r *Replica) Follow(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("follow interval must be positive")
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.CatchUp(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyReplicatedChanges brings the runtime state derived from storage up to
+// date with one applied transaction: collections, vector indexes, metadata
+// and full-text postings, and edge-kind registrations. Graph topology is
+// already current; the engine routes graph frames to the recovery targets.
+func (db *Database) applyReplicatedChanges(ctx context.Context, changes []storage.WALChange) error {
+	engine, ok := db.storage.(interface {
+		GetCollectionWithConfig(name string) (storage.Collection, *storage.CollectionConfig, error)
+	})
+	if !ok {
+		return fmt.Errorf("storage engine does not expose collection configs")
+	}
+	for _, change := range changes {
+		name := change.Collection
+		shardIdx := -1
+		if parent, idx, ok := parseShardName(name); ok {
+			name, shardIdx = parent, idx
+		}
+		switch change.Type {
+		case storage.WALChangeEdgeKind:
+			if !RegisterEdgeKindWithDirection(change.EdgeKind, change.EdgeKindDef.Kind, change.EdgeKindDef.Undirected) {
+				return fmt.Errorf("replicated edge kind %q=%d: runtime registry conflict", change.EdgeKind, change.EdgeKindDef.Kind)
+			}
+			db.mu.RLock()
+			for _, collection := range db.collections {
+				if g := collection.GetGraph(); g != nil {
+					g.SetEdgeKindDirection(change.EdgeKindDef.Kind, change.EdgeKindDef.Undirected)
+				}
+			}
+			db.mu.RUnlock()
+		case storage.WALChangeCollectionCreate:
+			// Sharded collections are created one shard per transaction;
+			// attach the parent once its last shard exists.
+			if shardIdx >= 0 && shardIdx != shardCount-1 {
+				continue
+			}
+			if err := db.attachReplicatedCollection(ctx, name, engine); err != nil {
+				return err
+			}
+		case storage.WALChangeCollectionDelete:
+			if shardIdx > 0 {
+				continue
+			}
+			db.mu.Lock()
+			collection := db.collections[name]
+			delete(db.collections, name)
+			db.mu.Unlock()
+			if collection != nil {
+				if err := collection.Close(); err != nil {
+					return fmt.Errorf("close replicated collection %s: %w", name, err)
+				}
+			}
+		case storage.WALChangeCollectionConfig:
+			collection := db.replicatedCollection(name)
+			if collection == nil {
+				continue
+			}
+			handle, stored, err := engine.GetCollectionWithConfig(change.Collection)
+			if err != nil {
+				return err
+			}
+			_ = handle.Close()
+			collection.refreshReplicatedConfig(stored)
+			db.registerCollectionInCatalog(name, collection.config)
+		case storage.WALChangeView:
+			// The replicated frames have already updated the view registry.
+			if err := db.loadViews(); err != nil {
+				return err
+			}
+		case storage.WALChangeCatalog:
+			// The primary committed its SQL catalog with the transaction;
+			// adopt it verbatim rather than rebuilding it from configs.
+			source, ok := db.storage.(interface{ CatalogData() []byte })
+			if !ok {
+				continue
+			}
+			cat, err := catalog.Load(source.CatalogData(), db.quantRegistry)
+			if err != nil {
+				return fmt.Errorf("load replicated catalog: %w", err)
+			}
+			db.mu.Lock()
+			db.catalog = cat
+			db.catalogGeneration.Add(1)
+			collections := make(map[string]*Collection, len(db.collections))
+			names := make([]string, 0, len(db.collections))
+			for collectionName, collection := range db.collections {
+				collections[collectionName] = collection
+				names = append(names, collectionName)
+			}
+			db.mu.Unlock()
+			tableNames := catalogTableNames(names)
+			for collectionName, collection := range collections {
+				schema, ok := relationalSchemaFromCatalog(cat, collectionName, tableNames)
+				if !ok {
+					continue
+				}
+				collection.mu.Lock()
+				schema.applyTo(collection.config)
+				collection.mu.Unlock()
+			}
+		case storage.WALChangeRecord:
+			collection := db.replicatedCollection(name)
+			if collection == nil {
+				continue
+			}
+			if err := collection.applyReplicatedRecord(ctx, shardIdx, change); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// rederiveReplicatedChanges repairs runtime state that applyReplicatedChanges
+// left partly updated. Storage already holds the changes, so every collection
+// they touched is closed and loaded from storage again, the way Open loads
+// it; the remaining changes only reload or re-register state and are applied
+// again.
+func (db *Database) rederiveReplicatedChanges(ctx context.Context, changes []storage.WALChange) error {
+	engine, ok := db.storage.(interface {
+		ListCollections() ([]string, error)
+		GetCollectionWithConfig(name string) (storage.Collection, *storage.CollectionConfig, error)
+	})
+	if !ok {
+		return fmt.Errorf("storage engine does not expose collection configs")
+	}
+	reload := make(map[string]bool)
+	var rest []storage.WALChange
+	for _, change := range changes {
+		switch change.Type {
+		case storage.WALChangeCollectionCreate, storage.WALChangeCollectionDelete,
+			storage.WALChangeCollectionConfig, storage.WALChangeRecord:
+			name := change.Collection
+			if parent, _, ok := parseShardName(name); ok {
+				name = parent
+			}
+			reload[name] = true
+		default:
+			rest = append(rest, change)
+		}
+	}
+	names, err := engine.ListCollections()
+	if err != nil {
+		return fmt.Errorf("list collections: %w", err)
+	}
+	stored := make(map[string]bool, len(names))
+	for _, name := range names {
+		stored[name] = true
+	}
+	for name := range reload {
+		db.mu.Lock()
+		collection := db.collections[name]
+		delete(db.collections, name)
+		db.mu.Unlock()
+		if collection != nil {
+			if err := collection.Close(); err != nil {
+				return fmt.Errorf("close replicated collection %s: %w", name, err)
+			}
+		}
+		if !stored[name] && !stored[shardName(name, shardCount-1)] {
+			continue
+		}
+		collection, err := db.loadCollectionFromStorage(ctx, name, engine, nil)
+		if err != nil {
+			return fmt.Errorf("reload replicated collection %s: %w", name, err)
+		}
+		db.mu.Lock()
+		loaded := make([]string, 0, len(db.collections)+1)
+		for collectionName := range db.collections {
+			loaded = append(loaded, collectionName)
+		}
+		db.adoptLoadedCollectionLocked(name, collection, catalogTableNames(append(loaded, name)))
+		db.mu.Unlock()
+		if walWriter, ok := db.storage.(storage.GraphWALWriter); ok {
+			db.wireGraphWAL(collection, walWriter)
+		}
+	}
+	return db.applyReplicatedChanges(ctx, rest)
+}
+
+func (db *Database) replicatedCollection(name string) *Collection {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return db.collections[name]
+}
+
+// attachReplicatedCollection loads a collection created on the primary and
+// wires it the way CreateCollection does. Loading attaches the graph as a
+// recovery target, which replays graph frames deferred until it existed.
+func (db *Database) attachReplicatedCollection(ctx context.Context, name string, engine interface {
+	GetCollectionWithConfig(name string) (storage.Collection, *storage.CollectionConfig, error)
+}) error {
+	if db.replicatedCollection(name) != nil {
+		return nil
+	}
+	collection, err := db.loadCollectionFromStorage(ctx, name, engine, nil)
+	if err != nil {
+		return fmt.Errorf("load replicated collection %s: %w", name, err)
+	}
+	db.mu.Lock()
+	db.collections[name] = collection
+	db.mu.Unlock()
+	db.registerCollectionInCatalog(name, collection.config)
+	if walWriter, ok := db.storage.(storage.GraphWALWriter); ok {
+		db.wireGraphWAL(collection, walWriter)
+	}
+	return nil
+}
+
+// refreshReplicatedConfig adopts the declarative parts of a replicated
+// collection config and drops the postings derived from the old one.
+func (c *Collection) refreshReplicatedConfig(stored *storage.CollectionConfig) {
+	c.mu.Lock()
+	c.config.MetadataSchema = metadataSchemaFromStorage(stored.MetadataSchema)
+	c.config.IndexedFields = append([]string(nil), stored.IndexedFields...)
+	c.config.SQLIndexes = sqlIndexesFromStorage(stored.SQLIndexes)
+	c.config.NamedUniqueConstraints = namedUniqueConstraintsFromSQLIndexes(stored.SQLIndexes)
+	c.config.SQLIndexedFields = append([]string(nil), stored.SQLIndexedFields...)
+	c.config.SparseVectors = cloneSparseVectorDeclarations(stored.SparseVectors)
+	c.config.FullTextIndexes = fullTextIndexesFromStorage(stored.FullTextIndexes)
+	c.config.VectorSpaces = vectorSpacesFromStorage(stored.VectorSpaces)
+	c.config.GeneratedVector = generatedVectorFromStorage(stored.GeneratedVector)
+	c.config.TTLField = stored.TTLField
+	c.config.DefaultTTL = stored.DefaultTTL
+	c.mu.Unlock()
+	c.metadataIndexMu.Lock()
+	c.metadataIndex = nil
+	c.metadataIndexBuiltAt = 0
+	c.metadataIndexMu.Unlock()
+	c.resetFullTextIndexes()
+	c.resetVectorSpaces()
+	c.resetSparseIndexes()
+	c.markMetadataIndexDirty()
+}
+
+// applyReplicatedRecord replaces a record's index entry with the row storage
+// now holds, or removes it when the transaction deleted the row.
+func (c *Collection) applyReplicatedRecord(ctx context.Context, shardIdx int, change storage.WALChange) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.closed {
+		return nil
+	}
+	rows, idx := c.storage, c.index
+	if c.shards != nil {
+		if shardIdx < 0 || shardIdx >= len(c.shards) {
+			return fmt.Errorf("replicated record %s/%s has no shard", c.name, change.ID)
+		}
+		shard := &c.shards[shardIdx]
+		shard.mu.Lock()
+		defer shard.mu.Unlock()
+		rows, idx = shard.storage, shard.index
+	} else {
+		mutation := c.lockMutationID(change.ID)
+		defer mutation.unlock()
+	}
+
+	if change.Existed {
+		if err := deleteIndexEntry(ctx, idx, change.ID, change.PreviousOrdinal); err != nil && !isNotFoundError(err) {
+			return fmt.Errorf("remove replicated index entry %s/%s: %w", c.name, change.ID, err)
+		}
+	}
+	entry, err := rows.Get(ctx, change.ID)
+	if err != nil {
+		if !isNotFoundError(err) {
+			return fmt.Errorf("read replicated record %s/%s: %w", c.name, change.ID, err)
+		}
+		c.noteFullTextDelete(change.ID)
+		c.noteVectorSpaceDelete(change.ID)
+		c.noteSparseDelete(change.ID)
+		c.markMetadataIndexDirty()
+		return nil
+	}
+	if err := idx.Insert(ctx, entryForIndex(c.config.Metric, entry)); err != nil {
+		return fmt.Errorf("index replicated record %s/%s: %w", c.name, change.ID, err)
+	}
+	c.noteFullTextWrite(change.ID, entry.Metadata)
+	c.noteVectorSpaceWrite(change.ID, entry.Metadata)
+	c.noteSparseWrite(change.ID, entry.Metadata)
+	c.markMetadataIndexDirty()
+	return nil
+}
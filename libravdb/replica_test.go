@@ -0,0 +1,271 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+func TestReplicaFollowsPrimaryWAL(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	primary, err := Open(WithStoragePath(filepath.Join(dir, "primary.libravdb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	primaryGraph, err := NewGraph(GraphConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primaryGraph.Close()
+	docs, err := primary.CreateCollection(ctx, "docs", WithDimension(2), WithGraph(primaryGraph))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "d1", []float32{1, 0}, map[string]interface{}{"tag": "seed"}); err != nil {
+		t.Fatal(err)
+	}
+
+	replicaPath := filepath.Join(dir, "replica.libravdb")
+	replica, err := OpenReplica(replicaPath, primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+	replicaDocs, err := replica.GetCollection("docs")
+	if err != nil {
+		t.Fatalf("seeded replica: %v", err)
+	}
+	replicaGraph, err := NewGraph(GraphConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replicaGraph.Close()
+	replicaDocs.SetGraph(replicaGraph)
+
+	// Records, an edge kind, graph edges, a config change and a new
+	// collection, all committed after the seed.
+	if err := docs.Insert(ctx, "d2", []float32{0, 1}, map[string]interface{}{"tag": "late"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "d1", []float32{0.9, 0.1}, map[string]interface{}{"tag": "updated"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.Query(ctx, "CREATE EDGE TYPE REPLICA_CITES"); err != nil {
+		t.Fatal(err)
+	}
+	d1, _ := primary.GetNodeID(ctx, "docs", "d1")
+	d2, _ := primary.GetNodeID(ctx, "docs", "d2")
+	kind := ResolveEdgeKind("REPLICA_CITES")
+	txn := primaryGraph.BeginTxn()
+	if err := txn.AddEdge(d2, d1, 1, kind); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := primary.Query(ctx, "CREATE INDEX docs_tag_idx ON docs (tag)"); err != nil {
+		t.Fatal(err)
+	}
+	notes, err := primary.CreateCollection(ctx, "notes", WithDimension(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notes.Insert(ctx, "n1", []float32{1, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := replica.CatchUp(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied == 0 {
+		t.Fatal("CatchUp applied nothing")
+	}
+	primaryLSN, _ := primary.LatestCommitLSN(ctx)
+	replicaLSN, _ := replica.LatestCommitLSN(ctx)
+	if replicaLSN != primaryLSN || replica.AppliedLSN() != primaryLSN {
+		t.Fatalf("replica at LSN %d (applied %d), primary %d", replicaLSN, replica.AppliedLSN(), primaryLSN)
+	}
+	if again, err := replica.CatchUp(ctx); err != nil || again != 0 {
+		t.Fatalf("second CatchUp applied %d: %v", again, err)
+	}
+
+	record, err := replicaDocs.Get(ctx, "d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Metadata["tag"] != "updated" {
+		t.Fatalf("replicated d1 metadata = %v", record.Metadata)
+	}
+	results, err := replicaDocs.Search(ctx, []float32{0, 1}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Results) != 1 || results.Results[0].ID != "d2" {
+		t.Fatalf("replica search = %+v, want d2", results.Results)
+	}
+	if count, _ := replicaDocs.Count(ctx); count != 2 {
+		t.Fatalf("replica docs count = %d, want 2", count)
+	}
+	if neighbors, err := replicaGraph.Neighbors(d2); err != nil || len(neighbors) != 1 || neighbors[0].Target != d1 {
+		t.Fatalf("replica graph neighbors of d2 = %v, %v", neighbors, err)
+	}
+	if defs, _ := replica.storage.(storage.EdgeKindDefinitionStore).ListEdgeKindDefinitions(); defs["REPLICA_CITES"].Kind != kind {
+		t.Fatalf("replicated edge kinds = %v", defs)
+	}
+	if indexes := replicaDocs.Config().SQLIndexes; len(indexes) != 1 || indexes[0].Name != "docs_tag_idx" {
+		t.Fatalf("replicated SQL indexes = %+v", indexes)
+	}
+	replicaNotes, err := replica.GetCollection("notes")
+	if err != nil {
+		t.Fatalf("replicated collection: %v", err)
+	}
+	if _, err := replicaNotes.Get(ctx, "n1"); err != nil {
+		t.Fatalf("replicated notes record: %v", err)
+	}
+
+	if err := replicaDocs.Insert(ctx, "local", []float32{1, 1}, nil); !errors.Is(err, ErrReplicaReadOnly) {
+		t.Fatalf("replica accepted a local write: %v", err)
+	}
+
+	if err := docs.Delete(ctx, "d2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := primary.DeleteCollection(ctx, "notes"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replica.CatchUp(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replicaDocs.Get(ctx, "d2"); err == nil {
+		t.Fatal("deleted record still present on replica")
+	}
+	if results, err := replicaDocs.Search(ctx, []float32{0, 1}, 2); err != nil || len(results.Results) != 1 {
+		t.Fatalf("replica search after delete = %+v, %v", results, err)
+	}
+	if _, err := replica.GetCollection("notes"); err == nil {
+		t.Fatal("dropped collection still present on replica")
+	}
+
+	// A reopened replica resumes from its own applied LSN.
+	if err := replica.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "d3", []float32{1, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	replica, err = OpenReplica(replicaPath, primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied, err := replica.CatchUp(ctx); err != nil || applied != 1 {
+		t.Fatalf("reopened replica applied %d: %v", applied, err)
+	}
+	replicaDocs, err = replica.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := replicaDocs.Get(ctx, "d3"); err != nil {
+		t.Fatalf("record written while the replica was closed: %v", err)
+	}
+}
+
+func TestReplicaRederivesStateAfterFailedApply(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	primary, err := Open(WithStoragePath(filepath.Join(dir, "primary.libravdb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer primary.Close()
+	docs, err := primary.CreateCollection(ctx, "docs", WithDimension(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := docs.Insert(ctx, "d1", []float32{1, 0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	replica, err := OpenReplica(filepath.Join(dir, "replica.libravdb"), primary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replica.Close()
+
+	if err := docs.Insert(ctx, "d2", []float32{0, 1}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// Apply the transaction to storage only, as a CatchUp does when the
+	// runtime half fails: the index no longer matches storage.
+	for tx, err := range primary.WALStream(ctx, replica.AppliedLSN()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err := replica.applier.ApplyWAL(ctx, tx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		replica.unapplied = append(replica.unapplied, changes...)
+	}
+	if len(replica.unapplied) == 0 {
+		t.Fatal("no replicated changes")
+	}
+
+	if applied, err := replica.CatchUp(ctx); err != nil || applied != 0 {
+		t.Fatalf("CatchUp applied %d: %v", applied, err)
+	}
+	if len(replica.unapplied) != 0 {
+		t.Fatalf("unapplied changes after CatchUp = %d", len(replica.unapplied))
+	}
+	replicaDocs, err := replica.GetCollection("docs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err := replicaDocs.Search(ctx, []float32{0, 1}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results.Results) != 1 || results.Results[0].ID != "d2" {
+		t.Fatalf("replica search after re-derivation = %+v, want d2", results.Results)
+	}
+}
+
+func TestWALStreamReportsCompactedHistory(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(filepath.Join(t.TempDir(), "stream.libravdb")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	col, err := db.CreateCollection(ctx, "docs", WithDimension(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := col.Insert(ctx, "a", []float32{1, 0}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var lsns []uint64
+	for tx, err := range db.WALStream(ctx, 0) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		lsns = append(lsns, tx.CommitLSN)
+	}
+	if len(lsns) < 2 {
+		t.Fatalf("streamed %d transactions, want create and insert", len(lsns))
+	}
+	if err := db.storage.(interface{ Compact() error }).Compact(); err != nil {
+		t.Fatal(err)
+	}
+	var streamErr error
+	for _, err := range db.WALStream(ctx, 0) {
+		streamErr = err
+	}
+	if !errors.Is(streamErr, ErrWALTruncated) {
+		t.Fatalf("WALStream below the floor: %v, want ErrWALTruncated", streamErr)
+	}
+}
@@ -8,10 +8,55 @@ import (
 	"strings"
 
 	"github.com/xDarkicex/libravdb/internal/optimizer"
+	"github.com/xDarkicex/libravdb/internal/sparse"
 )
 
 const defaultRRFK = 60.0
 
+// rrfConstant returns k when it is a usable smoothing constant and
+// defaultRRFK otherwise.
+func rrfConstant(k float64) float64 {
+	if k <= 0 || math.IsNaN(k) || math.IsInf(k, 0) {
+		return defaultRRFK
+	}
+	return k
+}
+
+// reciprocalRank is the fused-score contribution of a zero-based rank.
+func reciprocalRank(k float64, rank int) float64 {
+	return 1.0 / (k + float64(rank+1))
+}
+
+// fuseRankedResults combines independently ranked result lists by
+// reciprocal-rank fusion. Each result keeps the fields of its first
+// occurrence and takes the fused score; ties break by ID.
+func fuseRankedResults(k float64, lists [][]*SearchResult) []*SearchResult {
+	k = rrfConstant(k)
+	fused := make(map[string]*SearchResult)
+	scores := make(map[string]float64)
+	for _, list := range lists {
+		for rank, result := range list {
+			if _, ok := fused[result.ID]; !ok {
+				fused[result.ID] = result
+			}
+			scores[result.ID] += reciprocalRank(k, rank)
+		}
+	}
+	out := make([]*SearchResult, 0, len(fused))
+	for id, result := range fused {
+		result.Score = float32(scores[id])
+		out = append(out, result)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		left, right := scores[out[i].ID], scores[out[j].ID]
+		if left != right {
+			return left > right
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
 type rrfCandidate struct {
 	record   *Record
 	metadata map[string]interface{}
@@ -37,6 +82,7 @@ func (e *Executor) executeRRF(ctx context.Context, plan *optimizer.PhysicalPlan,
 	if err != nil {
 		return nil, err
 	}
+	trackSQLOperatorRows(ctx, explainKeyCandidates, len(candidateIDs))
 	if len(candidateIDs) == 0 {
 		return &SearchResults{Columns: plan.Projections}, nil
 	}
@@ -88,10 +134,7 @@ func (e *Executor) executeRRF(ctx context.Context, plan *optimizer.PhysicalPlan,
 	if len(candidates) == 0 {
 		return &SearchResults{Columns: plan.Projections}, nil
 	}
-	k := plan.RRFK
-	if k <= 0 || math.IsNaN(k) || math.IsInf(k, 0) {
-		k = defaultRRFK
-	}
+	k := rrfConstant(plan.RRFK)
 	for componentIndex, component := range plan.RRFComponents {
 		indices := make([]int, 0, len(candidates))
 		for i := range candidates {
@@ -99,6 +142,7 @@ func (e *Executor) executeRRF(ctx context.Context, plan *optimizer.PhysicalPlan,
 				indices = append(indices, i)
 			}
 		}
+		trackSQLOperatorRows(ctx, explainOperatorKey(explainKeyRRF, componentIndex), len(indices))
 		sort.SliceStable(indices, func(i, j int) bool {
 			left, right := candidates[indices[i]], candidates[indices[j]]
 			lv, rv := left.values[componentIndex], right.values[componentIndex]
@@ -111,7 +155,7 @@ func (e *Executor) executeRRF(ctx context.Context, plan *optimizer.PhysicalPlan,
 			return left.record.ID < right.record.ID
 		})
 		for rank, candidateIndex := range indices {
-			candidates[candidateIndex].score += 1.0 / (k + float64(rank+1))
+			candidates[candidateIndex].score += reciprocalRank(k, rank)
 		}
 	}
 
@@ -121,6 +165,7 @@ func (e *Executor) executeRRF(ctx context.Context, plan *optimizer.PhysicalPlan,
 		}
 		return candidates[i].record.ID < candidates[j].record.ID
 	})
+	trackSQLOperatorRows(ctx, explainKeyRRF, len(candidates))
 	if plan.Offset > 0 {
 		if plan.Offset >= len(candidates) {
 			candidates = nil
@@ -371,10 +416,30 @@ func (e *Executor) rrfComponentValue(ctx context.Context, col *Collection, rec *
 		if !ok || text == nil {
 			return 0, false, nil
 		}
-		rank := ftsRankText(recordMetaToString(text), component.TextQuery, "plain")
+		rank := col.fullTextRank(ctx, component.TextColumn, recordMetaToString(text), component.TextQuery)
 		// A zero lexical score is not a member of the lexical result list and
 		// therefore contributes no reciprocal-rank term.
 		return rank, rank > 0, nil
+	case optimizer.RRFComponentSparseInnerProduct:
+		_, dimension, declared := col.sparseVectorField(component.TextColumn)
+		if !declared {
+			return 0, false, fmt.Errorf("SPARSE_INNER_PRODUCT column %q is not a SPARSEVEC column", component.TextColumn)
+		}
+		if component.SparseQuery.Dim != dimension {
+			return 0, false, fmt.Errorf("SPARSE_INNER_PRODUCT query has %d dimensions, column %q expects %d", component.SparseQuery.Dim, component.TextColumn, dimension)
+		}
+		value, ok := recordMetadataValue(rec.Metadata, component.TextColumn)
+		if !ok || value == nil {
+			return 0, false, nil
+		}
+		vector, err := sparseVectorFromValue(value, dimension)
+		if err != nil {
+			return 0, false, nil
+		}
+		// Like a zero lexical score, a row sharing no dimension with the
+		// query is outside the sparse result list and contributes no term.
+		score, overlap := sparse.DotOverlap(vector, component.SparseQuery)
+		return float64(score), overlap, nil
 	case optimizer.RRFComponentGraphCentrality:
 		if col.graph == nil {
 			return 0, false, fmt.Errorf("GRAPH_CENTRALITY requires a graph-backed collection")
@@ -383,10 +448,18 @@ func (e *Executor) rrfComponentValue(ctx context.Context, col *Collection, rec *
 		if err != nil {
 			return 0, false, nil
 		}
+		var centrality float64
 		if snapshotLSN != 0 {
-			return col.graph.CentralityAtLSN(nodeID, snapshotLSN), true, nil
+			centrality, err = col.graph.NodeCentralityAtLSN(nodeID, snapshotLSN)
+		} else {
+			centrality, err = col.graph.NodeCentrality(nodeID)
+		}
+		if err != nil {
+			return 0, false, err
 		}
-		return col.graph.GraphCentrality(nodeID), true, nil
+		return centrality, true, nil
+	case optimizer.RRFComponentFunction:
+		return e.rrfFunctionValue(ctx, col, rec, component)
 	default:
 		return 0, false, fmt.Errorf("unknown RRF component kind %d", component.Kind)
 	}
@@ -328,7 +328,14 @@ func (e *Executor) executeScoredMultiModal(ctx context.Context, col *Collection,
 		centrality := 0.0
 		if plan.HasGraphTraversal && col.graph != nil {
 			if nodeID, err := e.db.GetNodeID(ctx, col.name, id); err == nil {
-				centrality = col.graph.GraphCentrality(nodeID)
+				if snapshotLSN != 0 {
+					centrality, err = col.graph.NodeCentralityAtLSN(nodeID, snapshotLSN)
+				} else {
+					centrality, err = col.graph.NodeCentrality(nodeID)
+				}
+				if err != nil {
+					return nil, err
+				}
 			}
 		}
 
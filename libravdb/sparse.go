@@ -0,0 +1,348 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/sparse"
+)
+
+// SparseVector is a sparse vector: strictly increasing zero-based indices
+// paired with non-zero values over a fixed dimension. Its text form is the
+// pgvector sparsevec literal, e.g. {1:0.5,7:2}/30522, with one-based indices.
+type SparseVector = sparse.Vector
+
+// ParseSparseVector decodes a pgvector-style sparsevec literal.
+func ParseSparseVector(text string) (SparseVector, error) {
+	return sparse.Parse(text)
+}
+
+// NewSparseVector builds a sparse vector from zero-based index/value pairs.
+// Zero values are dropped.
+func NewSparseVector(dimension int, entries map[uint32]float32) (SparseVector, error) {
+	return sparse.New(dimension, entries)
+}
+
+// WithSparseVector declares a sparse-vector metadata field of the given
+// dimension. Values may be supplied as a SparseVector or its text literal and
+// are stored canonically as text, so they persist through the ordinary record
+// WAL; the inverted lists used by SearchSparse are derived from them.
+func WithSparseVector(field string, dimension int) CollectionOption {
+	return func(c *CollectionConfig) error {
+		if strings.TrimSpace(field) == "" {
+			return fmt.Errorf("sparse vector field name must not be empty")
+		}
+		if dimension <= 0 {
+			return fmt.Errorf("sparse vector dimension must be positive, got %d", dimension)
+		}
+		if c.SparseVectors == nil {
+			c.SparseVectors = make(map[string]int)
+		}
+		c.SparseVectors[field] = dimension
+		return nil
+	}
+}
+
+// sparseVectorField resolves a declared sparse field case-insensitively,
+// returning the declared spelling and dimension.
+func (c *Collection) sparseVectorField(field string) (string, int, bool) {
+	if c == nil || c.config == nil {
+		return "", 0, false
+	}
+	if dimension, ok := c.config.SparseVectors[field]; ok {
+		return field, dimension, true
+	}
+	for declared, dimension := range c.config.SparseVectors {
+		if strings.EqualFold(declared, field) {
+			return declared, dimension, true
+		}
+	}
+	return "", 0, false
+}
+
+// validateSparseVectorFields checks values written to sparse-vector fields
+// against their declared dimension and replaces them with the canonical text
+// form. Missing and NULL values are left to the NOT NULL validation path.
+func (c *Collection) validateSparseVectorFields(metadata map[string]interface{}) error {
+	if c == nil || c.config == nil || len(c.config.SparseVectors) == 0 {
+		return nil
+	}
+	for name, dimension := range c.config.SparseVectors {
+		for key, value := range metadata {
+			if !strings.EqualFold(key, name) || value == nil {
+				continue
+			}
+			vector, err := sparseVectorFromValue(value, dimension)
+			if err != nil {
+				return fmt.Errorf("invalid sparse vector for column %q: %w", name, err)
+			}
+			metadata[key] = vector.String()
+			break
+		}
+	}
+	return nil
+}
+
+func sparseVectorFromValue(value interface{}, dimension int) (SparseVector, error) {
+	var vector SparseVector
+	switch v := value.(type) {
+	case SparseVector:
+		vector = v
+	case *SparseVector:
+		if v == nil {
+			return SparseVector{}, fmt.Errorf("nil sparse vector")
+		}
+		vector = *v
+	case string:
+		parsed, err := sparse.Parse(v)
+		if err != nil {
+			return SparseVector{}, err
+		}
+		vector = parsed
+	case []byte:
+		parsed, err := sparse.Parse(string(v))
+		if err != nil {
+			return SparseVector{}, err
+		}
+		vector = parsed
+	default:
+		return SparseVector{}, fmt.Errorf("unsupported sparse vector value of type %T", value)
+	}
+	if err := vector.Validate(); err != nil {
+		return SparseVector{}, err
+	}
+	if dimension > 0 && vector.Dim != dimension {
+		return SparseVector{}, fmt.Errorf("expected %d dimensions, not %d", dimension, vector.Dim)
+	}
+	return vector, nil
+}
+
+// SearchSparse returns the k records whose sparse vector in field has the
+// largest inner product with query. Scores are the raw inner products, best
+// first. Records without a value, or sharing no non-zero dimension with the
+// query, are not returned.
+func (c *Collection) SearchSparse(ctx context.Context, field string, query SparseVector, k int) (*SearchResults, error) {
+	start := time.Now()
+	declared, dimension, ok := c.sparseVectorField(field)
+	if !ok {
+		return nil, fmt.Errorf("field %q is not a declared sparse vector", field)
+	}
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	if err := query.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid sparse query: %w", err)
+	}
+	if query.Dim != dimension {
+		return nil, fmt.Errorf("sparse query has %d dimensions, field %q expects %d", query.Dim, declared, dimension)
+	}
+
+	idx, err := c.sparseIndexFor(ctx, declared, dimension)
+	if err != nil {
+		return nil, err
+	}
+	hits := idx.Search(query, k)
+	results := make([]*SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		record, err := c.Get(ctx, hit.ID)
+		if err != nil {
+			// A delete can commit between the search and this read.
+			if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		results = append(results, &SearchResult{
+			ID:       record.ID,
+			Score:    hit.Score,
+			Vector:   record.Vector,
+			Metadata: record.Metadata,
+			Version:  record.Version,
+			Ordinal:  record.Ordinal,
+		})
+	}
+	return &SearchResults{Results: results, Took: time.Since(start), Total: len(results)}, nil
+}
+
+type sparseIndexState uint8
+
+const (
+	sparseIndexUnbuilt sparseIndexState = iota
+	sparseIndexBuilding
+	sparseIndexReady
+)
+
+// sparseFieldIndex holds the inverted lists of one sparse-vector field. Like
+// a full-text index it is built from storage on first use and then
+// maintained by committed writes; writes committed while the build scans are
+// queued and replayed over the fresh lists in commit order.
+type sparseFieldIndex struct {
+	field     string
+	dimension int
+
+	// buildMu serializes builds and is never taken by writers.
+	buildMu sync.Mutex
+
+	mu      sync.Mutex
+	state   sparseIndexState
+	pending []sparseIndexWrite
+	lists   *sparse.Index
+}
+
+// sparseIndexWrite is one committed write. An empty vector removes the
+// document.
+type sparseIndexWrite struct {
+	id     string
+	vector SparseVector
+}
+
+// vectorOf extracts the field's vector from a record's complete metadata.
+// Values are validated on write; a record that predates the declaration
+// simply does not participate.
+func (x *sparseFieldIndex) vectorOf(id string, metadata map[string]interface{}) sparseIndexWrite {
+	write := sparseIndexWrite{id: id}
+	value, ok := recordMetadataValue(metadata, x.field)
+	if !ok || value == nil {
+		return write
+	}
+	if vector, err := sparseVectorFromValue(value, x.dimension); err == nil {
+		write.vector = vector
+	}
+	return write
+}
+
+func (x *sparseFieldIndex) record(write sparseIndexWrite) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	switch x.state {
+	case sparseIndexReady:
+		x.lists.Put(write.id, write.vector)
+	case sparseIndexBuilding:
+		x.pending = append(x.pending, write)
+	}
+}
+
+func (x *sparseFieldIndex) tracking() bool {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.state != sparseIndexUnbuilt
+}
+
+// ensureBuilt scans the collection once and publishes the lists.
+func (x *sparseFieldIndex) ensureBuilt(ctx context.Context, c *Collection) (*sparse.Index, error) {
+	x.mu.Lock()
+	if x.state == sparseIndexReady {
+		defer x.mu.Unlock()
+		return x.lists, nil
+	}
+	x.mu.Unlock()
+	x.buildMu.Lock()
+	defer x.buildMu.Unlock()
+
+	x.mu.Lock()
+	if x.state == sparseIndexReady {
+		defer x.mu.Unlock()
+		return x.lists, nil
+	}
+	x.state = sparseIndexBuilding
+	x.pending = nil
+	x.mu.Unlock()
+
+	builder := sparse.NewBuilder()
+	err := c.Iterate(withExpiredRecords(ctx), func(record Record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		write := x.vectorOf(record.ID, record.Metadata)
+		builder.Add(write.id, write.vector)
+		return nil
+	})
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err != nil {
+		x.state = sparseIndexUnbuilt
+		x.pending = nil
+		return nil, fmt.Errorf("build sparse index for %q: %w", x.field, err)
+	}
+	lists := builder.Build()
+	for _, write := range x.pending {
+		lists.Put(write.id, write.vector)
+	}
+	x.pending = nil
+	x.lists = lists
+	x.state = sparseIndexReady
+	return lists, nil
+}
+
+// sparseIndexFor returns the inverted lists of one declared sparse field,
+// building them on first use.
+func (c *Collection) sparseIndexFor(ctx context.Context, field string, dimension int) (*sparse.Index, error) {
+	c.sparseIndexMu.Lock()
+	if c.sparseIndexes == nil {
+		c.sparseIndexes = make(map[string]*sparseFieldIndex)
+	}
+	index := c.sparseIndexes[field]
+	if index == nil || index.dimension != dimension {
+		index = &sparseFieldIndex{field: field, dimension: dimension}
+		c.sparseIndexes[field] = index
+	}
+	c.sparseIndexMu.Unlock()
+	return index.ensureBuilt(ctx, c)
+}
+
+// resetSparseIndexes drops all derived lists after the declarations change;
+// they are rebuilt on next use.
+func (c *Collection) resetSparseIndexes() {
+	c.sparseIndexMu.Lock()
+	c.sparseIndexes = nil
+	c.sparseIndexMu.Unlock()
+}
+
+func (c *Collection) trackedSparseIndexes() []*sparseFieldIndex {
+	if c == nil {
+		return nil
+	}
+	c.sparseIndexMu.Lock()
+	defer c.sparseIndexMu.Unlock()
+	if len(c.sparseIndexes) == 0 {
+		return nil
+	}
+	indexes := make([]*sparseFieldIndex, 0, len(c.sparseIndexes))
+	for _, index := range c.sparseIndexes {
+		indexes = append(indexes, index)
+	}
+	return indexes
+}
+
+// noteSparseWrite applies a committed insert or update to every built sparse
+// index. metadata must be the record's complete metadata.
+func (c *Collection) noteSparseWrite(id string, metadata map[string]interface{}) {
+	for _, index := range c.trackedSparseIndexes() {
+		if index.tracking() {
+			index.record(index.vectorOf(id, metadata))
+		}
+	}
+}
+
+// noteSparseDelete removes a committed delete from every built sparse index.
+func (c *Collection) noteSparseDelete(id string) {
+	for _, index := range c.trackedSparseIndexes() {
+		index.record(sparseIndexWrite{id: id})
+	}
+}
+
+func cloneSparseVectorDeclarations(declarations map[string]int) map[string]int {
+	if len(declarations) == 0 {
+		return nil
+	}
+	out := make(map[string]int, len(declarations))
+	for field, dimension := range declarations {
+		out[field] = dimension
+	}
+	return out
+}
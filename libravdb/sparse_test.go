@@ -0,0 +1,267 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/sparse"
+)
+
+func randomSparseVector(rng *rand.Rand, dimension, nnz int) SparseVector {
+	entries := make(map[uint32]float32, nnz)
+	for len(entries) < nnz {
+		entries[uint32(rng.Intn(dimension))] = rng.Float32() + 0.01
+	}
+	vector, err := NewSparseVector(dimension, entries)
+	if err != nil {
+		panic(err)
+	}
+	return vector
+}
+
+func TestSearchSparseMatchesBruteForce(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(t.TempDir()+"/sparse.libravdb"), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	col, err := db.CreateCollection(ctx, "passages", WithDimension(2), WithFlat(), WithSparseVector("terms", 200))
+	if err != nil {
+		t.Fatalf("CreateCollection: %v", err)
+	}
+
+	rng := rand.New(rand.NewSource(5))
+	vectors := make(map[string]SparseVector, 400)
+	for i := 0; i < 400; i++ {
+		id := fmt.Sprintf("p%03d", i)
+		vector := randomSparseVector(rng, 200, 1+rng.Intn(10))
+		vectors[id] = vector
+		// Both the typed value and its text literal are accepted.
+		var value interface{} = vector
+		if i%2 == 1 {
+			value = vector.String()
+		}
+		if err := col.Insert(ctx, id, []float32{1, 0}, map[string]interface{}{"terms": value}); err != nil {
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+
+	stored, err := col.Get(ctx, "p000")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.Metadata["terms"] != vectors["p000"].String() {
+		t.Fatalf("stored value = %#v, want canonical %q", stored.Metadata["terms"], vectors["p000"].String())
+	}
+
+	check := func(query SparseVector) {
+		t.Helper()
+		type scored struct {
+			id    string
+			score float32
+		}
+		var want []scored
+		for id, vector := range vectors {
+			if score, overlap := sparse.DotOverlap(vector, query); overlap {
+				want = append(want, scored{id: id, score: score})
+			}
+		}
+		sort.Slice(want, func(i, j int) bool {
+			if want[i].score != want[j].score {
+				return want[i].score > want[j].score
+			}
+			return want[i].id < want[j].id
+		})
+		if len(want) > 10 {
+			want = want[:10]
+		}
+		results, err := col.SearchSparse(ctx, "terms", query, 10)
+		if err != nil {
+			t.Fatalf("SearchSparse: %v", err)
+		}
+		if len(results.Results) != len(want) {
+			t.Fatalf("got %d results, want %d", len(results.Results), len(want))
+		}
+		for i, result := range results.Results {
+			if result.ID != want[i].id {
+				t.Fatalf("rank %d = %s (%v), want %s (%v)", i, result.ID, result.Score, want[i].id, want[i].score)
+			}
+			if result.Metadata["terms"] == nil || result.Version == 0 {
+				t.Fatalf("result %s was not hydrated: %+v", result.ID, result)
+			}
+		}
+	}
+	for q := 0; q < 10; q++ {
+		check(randomSparseVector(rng, 200, 3+rng.Intn(5)))
+	}
+
+	// Committed writes are applied to the built lists in place.
+	query := randomSparseVector(rng, 200, 4)
+	results, err := col.SearchSparse(ctx, "terms", query, 1)
+	if err != nil || len(results.Results) != 1 {
+		t.Fatalf("SearchSparse before delete = %v, %v", results, err)
+	}
+	if err := col.Delete(ctx, results.Results[0].ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	delete(vectors, results.Results[0].ID)
+	check(query)
+
+	replacement := randomSparseVector(rng, 200, 6)
+	if err := col.Update(ctx, "p001", nil, map[string]interface{}{"terms": replacement}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	vectors["p001"] = replacement
+	check(replacement)
+	if err := col.Insert(ctx, "p400", []float32{1, 0}, map[string]interface{}{"terms": query}); err != nil {
+		t.Fatalf("Insert p400: %v", err)
+	}
+	vectors["p400"] = query
+	check(query)
+}
+
+func TestSparseVectorValidation(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(t.TempDir()+"/sparse_validation.libravdb"), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+	col, err := db.CreateCollection(ctx, "passages", WithDimension(1), WithFlat(), WithSparseVector("terms", 5))
+	if err != nil {
+		t.Fatalf("CreateCollection: %v", err)
+	}
+
+	for _, value := range []interface{}{"{1:1}/6", "{6:1}/5", "not sparse", 42} {
+		if err := col.Insert(ctx, "bad", []float32{1}, map[string]interface{}{"terms": value}); err == nil {
+			t.Fatalf("Insert accepted invalid sparse value %#v", value)
+		}
+	}
+	if err := col.Insert(ctx, "missing", []float32{1}, map[string]interface{}{"title": "no sparse value"}); err != nil {
+		t.Fatalf("Insert without sparse value: %v", err)
+	}
+
+	query, _ := ParseSparseVector("{1:1}/5")
+	if _, err := col.SearchSparse(ctx, "title", query, 3); err == nil {
+		t.Fatal("SearchSparse accepted an undeclared field")
+	}
+	wrongDim, _ := ParseSparseVector("{1:1}/4")
+	if _, err := col.SearchSparse(ctx, "terms", wrongDim, 3); err == nil {
+		t.Fatal("SearchSparse accepted a query of the wrong dimension")
+	}
+	results, err := col.SearchSparse(ctx, "TERMS", query, 3)
+	if err != nil || len(results.Results) != 0 {
+		t.Fatalf("SearchSparse without matches = %+v, %v", results, err)
+	}
+	if _, err := db.CreateCollection(ctx, "invalid", WithDimension(1), WithSparseVector("terms", 0)); err == nil {
+		t.Fatal("WithSparseVector accepted a zero dimension")
+	}
+}
+
+func TestSparseVectorDeclarationSurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/sparse_reopen.libravdb"
+	db, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	col, err := db.CreateCollection(ctx, "passages", WithDimension(1), WithFlat(), WithSparseVector("terms", 30522))
+	if err != nil {
+		db.Close()
+		t.Fatalf("CreateCollection: %v", err)
+	}
+	for id, literal := range map[string]string{
+		"a": "{10:1,200:0.5}/30522",
+		"b": "{10:0.25}/30522",
+		"c": "{30522:4}/30522",
+	} {
+		if err := col.Insert(ctx, id, []float32{1}, map[string]interface{}{"terms": literal}); err != nil {
+			db.Close()
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	reloaded, err := reopened.GetCollection("passages")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	if got := reloaded.Config().SparseVectors; len(got) != 1 || got["terms"] != 30522 {
+		t.Fatalf("reloaded SparseVectors = %#v", got)
+	}
+	query, _ := ParseSparseVector("{10:2,200:2}/30522")
+	results, err := reloaded.SearchSparse(ctx, "terms", query, 5)
+	if err != nil {
+		t.Fatalf("SearchSparse after reopen: %v", err)
+	}
+	if len(results.Results) != 2 || results.Results[0].ID != "a" || results.Results[0].Score != 3 || results.Results[1].ID != "b" {
+		t.Fatalf("SearchSparse after reopen = %+v", results.Results)
+	}
+	if err := reloaded.Insert(ctx, "d", []float32{1}, map[string]interface{}{"terms": "{1:1}/8"}); err == nil {
+		t.Fatal("reopened collection no longer validates the declared dimension")
+	}
+}
+
+func TestSQL_SparseVecColumnAndRRF(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(t.TempDir()+"/sparse_sql.libravdb"), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Query(ctx, `CREATE TABLE docs (id TEXT PRIMARY KEY, embedding VECTOR(3), terms SPARSEVEC(8))`); err != nil {
+		t.Fatalf("CREATE TABLE: %v", err)
+	}
+	col, err := db.GetCollection("docs")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	if got := col.Config().SparseVectors["terms"]; got != 8 {
+		t.Fatalf("SPARSEVEC(8) declared dimension = %d", got)
+	}
+	if _, err := db.Query(ctx, `INSERT INTO docs (id, embedding, terms) VALUES `+
+		`('a', '[1,0,0]', '{1:2,3:1}/8'), `+
+		`('b', '[0.9,0.1,0]', '{2:5}/8'), `+
+		`('c', '[0,1,0]', '{1:1}/8')`); err != nil {
+		t.Fatalf("INSERT: %v", err)
+	}
+	if _, err := db.Query(ctx, `INSERT INTO docs (id, embedding, terms) VALUES ('bad', '[1,0,0]', '{9:1}/8')`); err == nil {
+		t.Fatal("INSERT accepted a sparse index beyond SPARSEVEC(8)")
+	}
+
+	// Vector ranks a, b, c; the sparse signal ranks a, c and omits b, which
+	// shares no dimension with the query. c therefore overtakes b.
+	result, err := db.QueryWithParams(ctx,
+		"SELECT id, RRF(VECTOR_DISTANCE(embedding, $query_vec), SPARSE_INNER_PRODUCT(terms, '{1:1,3:1}/8')) AS relevance "+
+			"FROM docs ORDER BY relevance DESC LIMIT 3",
+		QueryParams{"query_vec": []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatalf("RRF query: %v", err)
+	}
+	var ids []string
+	for _, row := range result.Results {
+		ids = append(ids, row.ID)
+	}
+	if strings.Join(ids, ",") != "a,c,b" {
+		t.Fatalf("RRF order = %v, want [a c b]", ids)
+	}
+
+	if _, err := db.QueryWithParams(ctx,
+		"SELECT id, RRF(VECTOR_DISTANCE(embedding, $query_vec), SPARSE_INNER_PRODUCT(terms, '{1:1}/9')) AS relevance FROM docs",
+		QueryParams{"query_vec": []float32{1, 0, 0}}); err == nil {
+		t.Fatal("RRF accepted a sparse query of the wrong dimension")
+	}
+}
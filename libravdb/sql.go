@@ -83,12 +83,27 @@ func (db *Database) queryWithBoundParamsAndConfigInternal(ctx context.Context, s
 	// does not represent schema-qualified table expressions. Strip only the
 	// pg_catalog qualifier outside quoted SQL text before parsing.
 	sql = rewriteNativePgCatalogPrefix(sql)
+	// EMBED() calls are evaluated as the statement is planned, once per
+	// statement; see embed.go.
+	ctx = withEmbedScope(ctx)
 	src := []byte(sql)
 
 	// 1 & 2. Lex & Parse
 	doc := &parser.QueryDoc{}
 	if err := parser.Parse(src, doc); err != nil {
-		return nil, fmt.Errorf("parse error: %w", err)
+		if plan, handled, planErr := planOutsideGrammar(src); handled {
+			if planErr != nil {
+				return nil, planErr
+			}
+			return db.executeSQLPlan(ctx, plan)
+		}
+		if err := parseOutsideGrammar(src, doc, err); err != nil {
+			return nil, err
+		}
+	}
+	// A statement may read a view but not write to one; see sql_view.go.
+	if err := db.guardViewWrites(src, doc); err != nil {
+		return nil, err
 	}
 	if doc.Explain {
 		return db.executeSQLExplain(ctx, src, doc, boundParams, legacyParams, sessionConfig, tracker)
@@ -153,7 +168,8 @@ func (db *Database) queryWithBoundParamsAndConfigInternal(ctx context.Context, s
 
 	// CTE SELECT: preserve the existing Leiden virtual relation path, while
 	// ordinary SELECT CTEs execute through an in-memory virtual relation.  No
-	// temporary collection or catalog/WAL mutation is involved.
+	// temporary collection or catalog/WAL mutation is involved. A query that
+	// reads a view takes the same route, with the view bound as a relation.
 	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && doc.SelectStmts[root].CTEsCount > 0 {
 		cte := doc.CTEs[doc.SelectStmts[root].CTEsStart]
 		if cte.Body.Kind == parser.NodeKindComputeLeidenStmt {
@@ -161,70 +177,10 @@ func (db *Database) queryWithBoundParamsAndConfigInternal(ctx context.Context, s
 		}
 		return db.executeGenericCTE(ctx, src, doc, boundParams, legacyParams, sessionConfig)
 	}
-	// VERSIONS OF ... BETWEEN TIMESTAMP ... is a virtual temporal relation;
-	// evaluate it with the query-local row engine so its historical tuples can
-	// participate in normal WHERE/ORDER/OFFSET/LIMIT projection semantics.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && selectHasTemporalRange(doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// JSON extraction and containment are evaluated by the query-local row
-	// engine so projected JSON values and nested predicates retain their
-	// document shape instead of being flattened into scalar catalog bytes.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && virtualSelectHasJSON(src, doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// Window functions require a post-filter partition/order pass before the
-	// outer ORDER BY/LIMIT. Keep them in the query-local virtual evaluator so
-	// the physical relational plan cannot discard window scope.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && virtualSelectHasWindow(doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// ARRAY_AGG and STRING_AGG are ordinary PostgreSQL aggregate names but are
-	// intentionally parsed as FunctionExpr nodes (they are not lexer keywords).
-	// Route them through the query-local relation evaluator so grouped and
-	// nullable inputs retain their row values and the physical scalar planner
-	// cannot flatten the resulting array/string.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && virtualSelectHasCollectionAggregate(src, doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// Ordered-set aggregates (PERCENTILE_CONT, PERCENTILE_DISC, MODE) use
-	// WITHIN GROUP ordering and are evaluated by the query-local relation path;
-	// the physical aggregate planner only handles ordinary aggregates.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && virtualSelectHasOrderedSetAggregate(doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// Direct aggregates over bound parameters, such as MIN($threshold), need
-	// the row-aware virtual aggregate evaluator. The physical aggregate planner
-	// only sees catalog columns and otherwise drops MIN/MAX or treats SUM's
-	// parameter operand as a zero-valued scalar.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && virtualSelectHasParameterizedAggregate(src, doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// Aggregate-derived scalar expressions such as SUM(alpha) / SUM(beta)
-	// require the grouped virtual evaluator so each aggregate operand is
-	// materialized before the enclosing arithmetic expression is evaluated.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && virtualSelectHasNestedAggregateProjection(doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// CASE and general casts are scalar SQL expressions.  They must be
-	// evaluated after the visible row has been materialized; the physical
-	// relational planner deliberately only projects catalog columns and would
-	// otherwise drop these expression nodes.  Keep this route query-local so
-	// staged/temporal rows and typed parameters retain their normal semantics.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && virtualSelectHasScalarExpressions(src, doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
-	}
-	// Derived tables are query-local virtual relations. Execute them through
-	// the same AST evaluator as correlated subqueries before catalog binding;
-	// a parenthesized SELECT has no physical catalog identity.
-	if root := rootSelectIndex(doc); root >= 0 && root < len(doc.SelectStmts) && selectHasDerivedRelation(doc, &doc.SelectStmts[root]) {
-		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
+	if _, ok := db.readsView(src, doc); ok {
+		return db.executeGenericCTE(ctx, src, doc, boundParams, legacyParams, sessionConfig)
 	}
-	// Uncorrelated IN (SELECT ...) and EXISTS (SELECT ...) predicates are
-	// evaluated as virtual membership filters before the ordinary binder. This
-	// keeps subquery rows out of the catalog while retaining snapshot/epoch
-	// visibility through the recursive query path.
-	if len(doc.SubqueryExprs) > 0 && len(doc.SelectStmts) > 0 {
+	if db.queryLocalSelectRoute(src, doc) != "" {
 		return db.executeSubquerySelect(ctx, src, doc, boundParams, legacyParams)
 	}
 	// JSON predicates in UPDATE WHERE clauses need the same row-aware
@@ -258,7 +214,7 @@ func (db *Database) queryWithBoundParamsAndConfigInternal(ctx context.Context, s
 			if tracker != nil {
 				tracker.planCacheHits++
 			}
-			return newExecutor(db).Execute(ctx, cached)
+			return db.executeSQLPlan(ctx, cached)
 		}
 		if tracker != nil {
 			tracker.planCacheMisses++
@@ -271,7 +227,7 @@ func (db *Database) queryWithBoundParamsAndConfigInternal(ctx context.Context, s
 	}
 
 	// 4. Optimize (AST -> Physical Plan)
-	opt := optimizer.NewOptimizer(cat)
+	opt := db.newSQLOptimizer(ctx, cat)
 	var plan *optimizer.PhysicalPlan
 	var err error
 	if boundParams != nil {
@@ -287,7 +243,90 @@ func (db *Database) queryWithBoundParamsAndConfigInternal(ctx context.Context, s
 	}
 
 	// 5. Execute Physical Plan
-	return newExecutor(db).Execute(ctx, plan)
+	return db.executeSQLPlan(ctx, plan)
+}
+
+// queryLocalSelectRoute reports why a SELECT must run on the query-local row
+// evaluator instead of the physical planner, or "" when it does not. The
+// conditions are checked in dispatch order.
+func (db *Database) queryLocalSelectRoute(src []byte, doc *parser.QueryDoc) string {
+	root := rootSelectIndex(doc)
+	if root < 0 || root >= len(doc.SelectStmts) {
+		return ""
+	}
+	stmt := &doc.SelectStmts[root]
+	// VERSIONS OF ... BETWEEN TIMESTAMP ... is a virtual temporal relation;
+	// evaluate it with the query-local row engine so its historical tuples can
+	// participate in normal WHERE/ORDER/OFFSET/LIMIT projection semantics.
+	if selectHasTemporalRange(doc, stmt) {
+		return "temporal_range"
+	}
+	// JSON extraction and containment are evaluated by the query-local row
+	// engine so projected JSON values and nested predicates retain their
+	// document shape instead of being flattened into scalar catalog bytes.
+	if virtualSelectHasJSON(src, doc, stmt) {
+		return "json"
+	}
+	// Window functions require a post-filter partition/order pass before the
+	// outer ORDER BY/LIMIT. Keep them in the query-local virtual evaluator so
+	// the physical relational plan cannot discard window scope.
+	if virtualSelectHasWindow(doc, stmt) {
+		return "window"
+	}
+	// ARRAY_AGG and STRING_AGG are ordinary PostgreSQL aggregate names but are
+	// intentionally parsed as FunctionExpr nodes (they are not lexer keywords).
+	// Route them through the query-local relation evaluator so grouped and
+	// nullable inputs retain their row values and the physical scalar planner
+	// cannot flatten the resulting array/string.
+	if virtualSelectHasCollectionAggregate(src, doc, stmt) {
+		return "collection_aggregate"
+	}
+	// Ordered-set aggregates (PERCENTILE_CONT, PERCENTILE_DISC, MODE) use
+	// WITHIN GROUP ordering and are evaluated by the query-local relation path;
+	// the physical aggregate planner only handles ordinary aggregates.
+	if virtualSelectHasOrderedSetAggregate(doc, stmt) {
+		return "ordered_set_aggregate"
+	}
+	// Direct aggregates over bound parameters, such as MIN($threshold), need
+	// the row-aware virtual aggregate evaluator. The physical aggregate planner
+	// only sees catalog columns and otherwise drops MIN/MAX or treats SUM's
+	// parameter operand as a zero-valued scalar.
+	if virtualSelectHasParameterizedAggregate(src, doc, stmt) {
+		return "parameterized_aggregate"
+	}
+	// Aggregate-derived scalar expressions such as SUM(alpha) / SUM(beta)
+	// require the grouped virtual evaluator so each aggregate operand is
+	// materialized before the enclosing arithmetic expression is evaluated.
+	if virtualSelectHasNestedAggregateProjection(doc, stmt) {
+		return "nested_aggregate"
+	}
+	// Functions registered from Go are unknown to the physical planner; calls
+	// to them are evaluated per row, or per group for aggregates.
+	if db.selectCallsUserFunction(src, doc, stmt) {
+		return "user_function"
+	}
+	// CASE and general casts are scalar SQL expressions.  They must be
+	// evaluated after the visible row has been materialized; the physical
+	// relational planner deliberately only projects catalog columns and would
+	// otherwise drop these expression nodes.  Keep this route query-local so
+	// staged/temporal rows and typed parameters retain their normal semantics.
+	if virtualSelectHasScalarExpressions(src, doc, stmt) {
+		return "scalar_expression"
+	}
+	// Derived tables are query-local virtual relations. Execute them through
+	// the same AST evaluator as correlated subqueries before catalog binding;
+	// a parenthesized SELECT has no physical catalog identity.
+	if selectHasDerivedRelation(doc, stmt) {
+		return "derived_relation"
+	}
+	// Uncorrelated IN (SELECT ...) and EXISTS (SELECT ...) predicates are
+	// evaluated as virtual membership filters before the ordinary binder. This
+	// keeps subquery rows out of the catalog while retaining snapshot/epoch
+	// visibility through the recursive query path.
+	if len(doc.SubqueryExprs) > 0 {
+		return "subquery"
+	}
+	return ""
 }
 
 func rewriteNativePgCatalogPrefix(query string) string {
@@ -352,6 +391,33 @@ func rewriteNativePgCatalogPrefix(query string) string {
 	return rewritten.String()
 }
 
+// planOutsideGrammar plans the statements the parser rejects: the ALTER
+// TABLE actions beyond ADD/DROP COLUMN, and view DDL. handled is false for
+// anything else, whose parse error stands.
+func planOutsideGrammar(src []byte) (*optimizer.PhysicalPlan, bool, error) {
+	o := optimizer.NewOptimizer(nil)
+	if plan, handled, err := o.OptimizeAlterTable(src); handled {
+		return plan, true, err
+	}
+	return o.OptimizeView(src)
+}
+
+// parseOutsideGrammar parses the statement forms the grammar does not model,
+// a weightedShortestPath call and a CREATE TABLE generated vector column,
+// into doc; the optimizer plans the clause itself from src. Any other
+// statement fails with parseErr.
+func parseOutsideGrammar(src []byte, doc *parser.QueryDoc, parseErr error) error {
+	for _, parse := range []func([]byte, *parser.QueryDoc) (bool, error){
+		optimizer.ParseWeightedShortestPath,
+		optimizer.ParseGeneratedVectorColumn,
+	} {
+		if handled, err := parse(src, doc); handled {
+			return err
+		}
+	}
+	return fmt.Errorf("parse error: %w", parseErr)
+}
+
 func isSQLIdentifierByte(b byte) bool {
 	return (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') ||
 		(b >= '0' && b <= '9') || b == '_' || b == '$'
@@ -385,7 +451,7 @@ func (db *Database) executeVirtualJSONUpdate(ctx context.Context, src []byte, do
 	if err := binder.Bind(doc); err != nil {
 		return nil, fmt.Errorf("bind error: %w", err)
 	}
-	opt := optimizer.NewOptimizer(cat)
+	opt := db.newSQLOptimizer(ctx, cat)
 	var (
 		plan *optimizer.PhysicalPlan
 		err  error
@@ -0,0 +1,1495 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xDarkicex/libravdb/internal/catalog"
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// ALTER TABLE actions other than ADD/DROP COLUMN (renames, column type
+// changes, NOT NULL, DEFAULT and table constraints) are planned by the
+// optimizer into PhysicalPlan.DDLAlterActions. Each statement is executed as
+// a single storage transaction: rewritten rows, the physical collection
+// config and the new catalog generation become durable together or not at
+// all.
+
+// executeAlterTableActions executes a DDL plan carrying DDLAlterActions.
+func (db *Database) executeAlterTableActions(ctx context.Context, plan *optimizer.PhysicalPlan) (*SearchResults, error) {
+	if view, ok := db.lookupView(plan.DDLTableName); ok {
+		return nil, fmt.Errorf("%q is not a table", view.Name)
+	}
+	col, err := db.GetCollection(plan.DDLTableName)
+	if err != nil {
+		if plan.DDLIfExists {
+			return &SearchResults{}, nil
+		}
+		return nil, fmt.Errorf("ALTER TABLE: table %q not found", plan.DDLTableName)
+	}
+	if plan.DDLAlterActions[0].Kind == optimizer.AlterRenameTable {
+		err = db.renameTable(ctx, col, plan.DDLAlterActions[0].NewName)
+	} else {
+		err = db.alterTable(ctx, col, plan)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResults{}, nil
+}
+
+// tableAlteration is the working state of one ALTER TABLE statement. The
+// actions edit a private copy of the collection config; the row steps they
+// queue are applied to every record once all actions have been checked.
+type tableAlteration struct {
+	db       *Database
+	table    string
+	cfg      *CollectionConfig
+	cat      *catalog.Catalog
+	vectors  map[string]bool
+	parents  map[string]alterParent
+	steps    []alterRowStep
+	notNull  map[string]bool
+	checks   []optimizer.DDLCheckConstraint
+	uniques  map[string][]string
+	fks      [][]catalog.ForeignKeyInfo
+	renamed  map[string]string // old column name -> new, for referencing tables
+	rewrites map[string]bool
+	storedOK bool
+	// exprs carries the statement's lowered USING expressions in the fields
+	// evalConflictExpr reads.
+	exprs *optimizer.PhysicalPlan
+}
+
+// alterParent is a table referenced by ADD FOREIGN KEY. Its rows are read
+// while it is locked together with the altered collection, and it stays
+// locked until the new constraint is committed.
+type alterParent struct {
+	col     *Collection
+	name    string
+	keys    []string
+	records []Record
+}
+
+// alterRowStep is a per-row rewrite: a column rename when to is set,
+// otherwise a type conversion of column. using holds the lowered USING
+// expressions, evaluated from usingRoot; it is nil without a USING clause.
+type alterRowStep struct {
+	column    string
+	to        string
+	from      FieldType
+	target    alterType
+	using     *optimizer.PhysicalPlan
+	usingRoot int32
+}
+
+// alterTable applies every action of plan except RENAME TO as one
+// transaction.
+func (db *Database) alterTable(ctx context.Context, col *Collection, plan *optimizer.PhysicalPlan) error {
+	actions := plan.DDLAlterActions
+	cfg := col.Config()
+	if cfg.Sharded {
+		return fmt.Errorf("ALTER TABLE: sharded table %q cannot be altered", col.name)
+	}
+	engine, ok := db.storage.(storage.TransactionalEngine)
+	reader, okReader := db.storage.(interface {
+		GetCollectionWithConfig(name string) (storage.Collection, *storage.CollectionConfig, error)
+	})
+	if !ok || !okReader {
+		return fmt.Errorf("ALTER TABLE: storage engine does not support transactional schema changes")
+	}
+
+	// Tables referenced by ADD FOREIGN KEY are resolved here and read once
+	// they are locked; a self-reference is validated against the rewritten
+	// rows.
+	parents := make(map[string]alterParent)
+	for _, action := range actions {
+		if action.Kind != optimizer.AlterAddForeignKey || strings.EqualFold(action.RefTable, col.name) {
+			continue
+		}
+		if _, system := catalog.ResolveSystemTable(action.RefTable); system {
+			return fmt.Errorf("ALTER TABLE: foreign keys to system table %q can only be declared in CREATE TABLE", action.RefTable)
+		}
+		parent, err := db.GetCollection(action.RefTable)
+		if err != nil {
+			return fmt.Errorf("ALTER TABLE: referenced table %q does not exist", action.RefTable)
+		}
+		parents[strings.ToLower(action.RefTable)] = alterParent{
+			col:  parent,
+			name: parent.name,
+			keys: parent.Config().PrimaryKeyColumns,
+		}
+	}
+
+	release, err := col.acquireWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	unlockAsync, err := col.lockAsyncMutation(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to flush asynchronous index before ALTER TABLE: %w", err)
+	}
+	defer unlockAsync()
+
+	db.mu.RLock()
+	cat := db.catalog
+	names := make([]string, 0, len(db.collections))
+	for name := range db.collections {
+		names = append(names, name)
+	}
+	db.mu.RUnlock()
+
+	// The referenced tables are read-locked with the altered one, in name
+	// order as transactions lock collections, so no parent row the check
+	// relies on can be removed before the constraint commits.
+	lockOrder := make([]*Collection, 0, len(parents)+1)
+	lockOrder = append(lockOrder, col)
+	for _, parent := range parents {
+		lockOrder = append(lockOrder, parent.col)
+	}
+	sort.Slice(lockOrder, func(i, j int) bool { return lockOrder[i].name < lockOrder[j].name })
+	var readLocked []*Collection
+	unlockParents := func() {
+		for i := len(readLocked) - 1; i >= 0; i-- {
+			readLocked[i].mu.RUnlock()
+		}
+		readLocked = nil
+	}
+	defer unlockParents()
+	locked := false
+	defer func() {
+		if locked {
+			col.mu.Unlock()
+		}
+	}()
+	for _, c := range lockOrder {
+		if c == col {
+			col.mu.Lock()
+			locked = true
+			continue
+		}
+		c.mu.RLock()
+		readLocked = append(readLocked, c)
+	}
+	if col.closed {
+		return ErrCollectionClosed
+	}
+	for key, parent := range parents {
+		records := make([]Record, 0)
+		if err := parent.col.iterateLocked(ctx, func(record Record) error {
+			records = append(records, record)
+			return nil
+		}); err != nil {
+			return err
+		}
+		parent.records = records
+		parents[key] = parent
+	}
+	working := col.configLocked()
+	alt := &tableAlteration{
+		db: db, table: col.name, cfg: &working, cat: cat, parents: parents,
+		vectors: make(map[string]bool), notNull: make(map[string]bool),
+		uniques: make(map[string][]string), renamed: make(map[string]string),
+		rewrites: make(map[string]bool),
+		exprs:    &optimizer.PhysicalPlan{InsertConflictExprs: plan.DDLAlterExprs, InsertConflictCases: plan.DDLAlterExprCases},
+	}
+	alt.hydrateColumns()
+	for _, action := range actions {
+		if err := alt.apply(action); err != nil {
+			return err
+		}
+	}
+
+	puts, changed, err := alt.rewriteRows(ctx, col)
+	if err != nil {
+		return err
+	}
+
+	_, stored, err := reader.GetCollectionWithConfig(col.name)
+	if err != nil {
+		return err
+	}
+	if stored == nil {
+		return fmt.Errorf("ALTER TABLE: collection %q has no persisted configuration", col.name)
+	}
+	stored.MetadataSchema = metadataSchemaToStorage(working.MetadataSchema)
+	stored.IndexedFields = append([]string(nil), working.IndexedFields...)
+	stored.SQLIndexes = sqlIndexesToStorage(working.SQLIndexes)
+	stored.SQLIndexedFields = append([]string(nil), working.SQLIndexedFields...)
+	stored.SparseVectors = cloneSparseVectorDeclarations(working.SparseVectors)
+	stored.FullTextIndexes = fullTextIndexesToStorage(working.FullTextIndexes)
+	stored.VectorSpaces = vectorSpacesToStorage(working.VectorSpaces)
+	stored.GeneratedVector = generatedVectorToStorage(working.GeneratedVector)
+	stored.TTLField = working.TTLField
+	stored.DefaultTTL = working.DefaultTTL
+
+	data, err := alt.buildCatalog(col.name, col.name, catalogTableNames(names))
+	if err != nil {
+		return err
+	}
+	newCatalog, err := catalog.Load(data, db.quantRegistry)
+	if err != nil {
+		return fmt.Errorf("ALTER TABLE: build catalog: %w", err)
+	}
+
+	ops := append(puts,
+		storage.TxOperation{Type: storage.TxOperationCollectionConfig, Collection: col.name, Config: stored},
+		storage.TxOperation{Type: storage.TxOperationCatalog, Catalog: data},
+	)
+	if err := engine.CommitTx(ctx, ops); err != nil {
+		return err
+	}
+
+	// Storage is durable; publish the new schema and refresh derived state.
+	working.Graph = col.config.Graph
+	*col.config = working
+	// The statement has committed, so a refresh failure is logged rather
+	// than reported as a failed ALTER TABLE.
+	for _, entry := range changed {
+		err := deleteIndexEntry(ctx, col.index, entry.ID, entry.Ordinal)
+		if err == nil || isNotFoundError(err) {
+			err = col.index.Insert(ctx, entryForIndex(col.config.Metric, entry))
+		}
+		if err != nil && db.logger != nil {
+			db.logger.Printf("libravdb: ALTER TABLE %q: refresh index entry %q: %v", col.name, entry.ID, err)
+		}
+	}
+	col.jsonIndex = nil
+	col.jsonIndexBuiltAt = 0
+	col.jsonContainmentIndex = nil
+	col.jsonContainmentBuiltAt = 0
+	col.metadataIndexMu.Lock()
+	col.metadataIndex = nil
+	col.metadataIndexBuiltAt = 0
+	col.metadataIndexMu.Unlock()
+	col.resetSparseIndexes()
+	col.resetFullTextIndexes()
+	col.resetVectorSpaces()
+	col.markMetadataIndexDirty()
+	locked = false
+	col.mu.Unlock()
+
+	db.mu.Lock()
+	db.catalog = newCatalog
+	db.catalogGeneration.Add(1)
+	db.mu.Unlock()
+	// Deletes from a referenced table see the new constraint from here on.
+	unlockParents()
+
+	// Tables whose foreign keys reference a renamed column follow it.
+	if len(alt.renamed) > 0 {
+		db.updateReferencingForeignKeys(col, func(fk *catalog.ForeignKeyInfo) {
+			if !strings.EqualFold(fk.TargetTable, col.name) {
+				return
+			}
+			if renamed, ok := alt.renamed[strings.ToLower(fk.TargetColumn)]; ok {
+				fk.TargetColumn = renamed
+			}
+		})
+	}
+	return nil
+}
+
+// updateReferencingForeignKeys applies update to the foreign keys of every
+// collection other than col.
+func (db *Database) updateReferencingForeignKeys(col *Collection, update func(*catalog.ForeignKeyInfo)) {
+	db.mu.RLock()
+	others := make([]*Collection, 0, len(db.collections))
+	for _, other := range db.collections {
+		if other != col {
+			others = append(others, other)
+		}
+	}
+	db.mu.RUnlock()
+	for _, other := range others {
+		other.mu.Lock()
+		if other.config != nil {
+			for i := range other.config.ForeignKeys {
+				update(&other.config.ForeignKeys[i])
+			}
+		}
+		other.mu.Unlock()
+	}
+}
+
+// hydrateColumns completes the working schema from the catalog, which is
+// authoritative for columns added after the collection config was persisted
+// and for constraint flags declared by CREATE TABLE, and records the vector
+// columns.
+func (a *tableAlteration) hydrateColumns() {
+	if a.cat == nil {
+		return
+	}
+	table, err := a.cat.GetTable(catalog.HashIdentifier(a.table))
+	if err != nil {
+		return
+	}
+	for _, column := range a.cat.AllColumns(table) {
+		if column.Type == catalog.TypeVector {
+			a.vectors[strings.ToLower(column.Name)] = true
+			continue
+		}
+		if strings.EqualFold(column.Name, "id") {
+			continue
+		}
+		name := a.column(column.Name)
+		if name == "" {
+			if a.cfg.MetadataSchema == nil {
+				a.cfg.MetadataSchema = make(MetadataSchema)
+			}
+			name = column.Name
+			a.cfg.MetadataSchema[name] = catalogColumnFieldType(column.Type)
+		}
+		if a.flags(name) == 0 && column.Flags != 0 {
+			a.setFlags(name, column.Flags)
+		}
+	}
+}
+
+// catalogColumnFieldType maps a catalog column type back to the metadata
+// field type it was registered from.
+func catalogColumnFieldType(typ uint16) FieldType {
+	switch typ {
+	case catalog.TypeInt, catalog.TypeSmallInt:
+		return IntField
+	case catalog.TypeBigInt:
+		return BigIntField
+	case catalog.TypeFloat, catalog.TypeFloat4:
+		return FloatField
+	case catalog.TypeBool:
+		return BoolField
+	case catalog.TypeTimestamp:
+		return TimeField
+	case catalog.TypeJSON:
+		return JSONField
+	case catalog.TypeJSONB:
+		return JSONBField
+	default:
+		return StringField
+	}
+}
+
+// column returns the declared spelling of name, or "" when the table has no
+// such metadata column.
+func (a *tableAlteration) column(name string) string {
+	for existing := range a.cfg.MetadataSchema {
+		if strings.EqualFold(existing, name) {
+			return existing
+		}
+	}
+	return ""
+}
+
+// existingColumn resolves name to a metadata column that ALTER TABLE may
+// modify.
+func (a *tableAlteration) existingColumn(name string) (string, error) {
+	if strings.EqualFold(name, "id") {
+		return "", fmt.Errorf("ALTER TABLE: cannot alter the primary key column %q", name)
+	}
+	if a.vectors[strings.ToLower(name)] {
+		return "", fmt.Errorf("ALTER TABLE: cannot alter vector column %q", name)
+	}
+	column := a.column(name)
+	if column == "" {
+		return "", fmt.Errorf("ALTER TABLE: column %q of relation %q does not exist", name, a.table)
+	}
+	return column, nil
+}
+
+// referencedColumn resolves a column named by a constraint; "id" is the
+// record key and always exists.
+func (a *tableAlteration) referencedColumn(name string) (string, error) {
+	if strings.EqualFold(name, "id") {
+		return "id", nil
+	}
+	return a.existingColumn(name)
+}
+
+func (a *tableAlteration) flags(column string) uint16 {
+	for name, flags := range a.cfg.ColumnConstraints {
+		if strings.EqualFold(name, column) {
+			return flags
+		}
+	}
+	return 0
+}
+
+func (a *tableAlteration) setFlags(column string, flags uint16) {
+	for name := range a.cfg.ColumnConstraints {
+		if strings.EqualFold(name, column) {
+			delete(a.cfg.ColumnConstraints, name)
+		}
+	}
+	if flags == 0 {
+		return
+	}
+	if a.cfg.ColumnConstraints == nil {
+		a.cfg.ColumnConstraints = make(map[string]uint16)
+	}
+	a.cfg.ColumnConstraints[column] = flags
+}
+
+func (a *tableAlteration) inPrimaryKey(column string) bool {
+	for _, name := range a.cfg.PrimaryKeyColumns {
+		if strings.EqualFold(name, column) {
+			return true
+		}
+	}
+	return a.flags(column)&catalog.ColFlagPrimaryKey != 0
+}
+
+// constraintKind reports which kind of constraint owns name: "c" (CHECK),
+// "f" (FOREIGN KEY), "u" (named UNIQUE), "k" (column UNIQUE, reported as
+// <table>_<column>_key), "p" (PRIMARY KEY) or "" when there is none.
+func (a *tableAlteration) constraintKind(name string) string {
+	if strings.EqualFold(name, a.table+"_pkey") {
+		return "p"
+	}
+	if a.columnUniqueConstraint(name) != "" {
+		return "k"
+	}
+	for _, chk := range a.cfg.CheckConstraints {
+		if strings.EqualFold(chk.Name, name) {
+			return "c"
+		}
+	}
+	for _, fk := range a.cfg.ForeignKeys {
+		if strings.EqualFold(fk.Name, name) {
+			return "f"
+		}
+	}
+	for existing := range a.cfg.NamedUniqueConstraints {
+		if strings.EqualFold(existing, name) {
+			return "u"
+		}
+	}
+	return ""
+}
+
+// columnUniqueConstraint returns the column whose column-level UNIQUE
+// constraint is called name.
+func (a *tableAlteration) columnUniqueConstraint(name string) string {
+	for column, flags := range a.cfg.ColumnConstraints {
+		if flags&catalog.ColFlagUnique != 0 && strings.EqualFold(name, a.table+"_"+column+"_key") {
+			return column
+		}
+	}
+	return ""
+}
+
+// constraintName returns name, or a PostgreSQL-style generated name
+// <table>_<columns>_<suffix> made unique within the table.
+func (a *tableAlteration) constraintName(name string, columns []string, suffix string) (string, error) {
+	if name != "" {
+		if a.constraintKind(name) != "" {
+			return "", fmt.Errorf("ALTER TABLE: constraint %q for relation %q already exists", name, a.table)
+		}
+		return name, nil
+	}
+	base := a.table
+	if len(columns) > 0 {
+		base += "_" + strings.Join(columns, "_")
+	}
+	base += "_" + suffix
+	name = base
+	for i := 1; a.constraintKind(name) != ""; i++ {
+		name = fmt.Sprintf("%s%d", base, i)
+	}
+	return name, nil
+}
+
+func (a *tableAlteration) apply(action optimizer.AlterTableAction) error {
+	switch action.Kind {
+	case optimizer.AlterRenameColumn:
+		return a.renameColumn(action.Column, action.NewName)
+	case optimizer.AlterRenameConstraint:
+		return a.renameConstraint(action.Constraint, action.NewName)
+	case optimizer.AlterColumnType:
+		return a.changeType(action)
+	case optimizer.AlterSetNotNull, optimizer.AlterDropNotNull:
+		column, err := a.existingColumn(action.Column)
+		if err != nil {
+			return err
+		}
+		flags := a.flags(column)
+		if action.Kind == optimizer.AlterSetNotNull {
+			a.setFlags(column, flags|catalog.ColFlagNotNull)
+			a.notNull[column] = true
+			return nil
+		}
+		if a.inPrimaryKey(column) {
+			return fmt.Errorf("ALTER TABLE: column %q is in a primary key", column)
+		}
+		a.setFlags(column, flags&^catalog.ColFlagNotNull)
+		delete(a.notNull, column)
+		return nil
+	case optimizer.AlterSetDefault, optimizer.AlterDropDefault:
+		column, err := a.existingColumn(action.Column)
+		if err != nil {
+			return err
+		}
+		for name := range a.cfg.ColumnDefaults {
+			if strings.EqualFold(name, column) {
+				delete(a.cfg.ColumnDefaults, name)
+			}
+		}
+		if action.Kind == optimizer.AlterDropDefault {
+			a.setFlags(column, a.flags(column)&^catalog.ColFlagHasDefault)
+			return nil
+		}
+		if action.Default != "NULL" {
+			target := alterTypeForField(a.cfg.MetadataSchema[column])
+			if _, err := castAlterDatum(alterLiteral(action.Default), target, false); err != nil {
+				return fmt.Errorf("ALTER TABLE: invalid default for column %q: %w", column, err)
+			}
+		}
+		if a.cfg.ColumnDefaults == nil {
+			a.cfg.ColumnDefaults = make(map[string]string)
+		}
+		a.cfg.ColumnDefaults[column] = action.Default
+		a.setFlags(column, a.flags(column)|catalog.ColFlagHasDefault)
+		return nil
+	case optimizer.AlterAddCheck:
+		return a.addCheck(action)
+	case optimizer.AlterAddUnique:
+		return a.addUnique(action)
+	case optimizer.AlterAddForeignKey:
+		return a.addForeignKey(action)
+	case optimizer.AlterDropConstraint:
+		return a.dropConstraint(action.Constraint, action.IfExists)
+	}
+	return fmt.Errorf("ALTER TABLE: unsupported action")
+}
+
+func (a *tableAlteration) renameColumn(name, newName string) error {
+	column, err := a.existingColumn(name)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(newName, "id") || a.vectors[strings.ToLower(newName)] ||
+		(a.column(newName) != "" && !strings.EqualFold(newName, column)) {
+		return fmt.Errorf("ALTER TABLE: column %q of relation %q already exists", newName, a.table)
+	}
+	// Composite primary keys are encoded from their column names into the
+	// record key, so renaming one would orphan every existing row.
+	for _, pk := range a.cfg.PrimaryKeyColumns {
+		if strings.EqualFold(pk, column) {
+			return fmt.Errorf("ALTER TABLE: cannot rename primary key column %q", column)
+		}
+	}
+	rename := func(value string) string {
+		if strings.EqualFold(value, column) {
+			return newName
+		}
+		return value
+	}
+	renameAll := func(values []string) {
+		for i := range values {
+			values[i] = rename(values[i])
+		}
+	}
+
+	a.cfg.MetadataSchema[newName] = a.cfg.MetadataSchema[column]
+	if column != newName {
+		delete(a.cfg.MetadataSchema, column)
+	}
+	flags := a.flags(column)
+	a.setFlags(column, 0)
+	a.setFlags(newName, flags)
+	if value, ok := a.cfg.ColumnDefaults[column]; ok {
+		delete(a.cfg.ColumnDefaults, column)
+		a.cfg.ColumnDefaults[newName] = value
+	}
+	for _, columns := range a.cfg.NamedUniqueConstraints {
+		renameAll(columns)
+	}
+	for name, columns := range a.uniques {
+		renameAll(columns)
+		a.uniques[name] = columns
+	}
+	for i := range a.cfg.ForeignKeys {
+		fk := &a.cfg.ForeignKeys[i]
+		fk.SourceColumn = rename(fk.SourceColumn)
+		if strings.EqualFold(fk.TargetTable, a.table) {
+			fk.TargetColumn = rename(fk.TargetColumn)
+		}
+	}
+	for i := range a.cfg.CheckConstraints {
+		chk := &a.cfg.CheckConstraints[i]
+		chk.ColumnName = rename(chk.ColumnName)
+		chk.Expression = renameExpressionColumn(chk.Expression, column, newName)
+	}
+	for i := range a.checks {
+		a.checks[i].Expression = renameExpressionColumn(a.checks[i].Expression, column, newName)
+	}
+	renameAll(a.cfg.IndexedFields)
+	renameAll(a.cfg.SQLIndexedFields)
+	for i := range a.cfg.SQLIndexes {
+		renameAll(a.cfg.SQLIndexes[i].Columns)
+	}
+	for i := range a.cfg.JSONIndexes {
+		a.cfg.JSONIndexes[i].Column = rename(a.cfg.JSONIndexes[i].Column)
+	}
+	for i := range a.cfg.FullTextIndexes {
+		a.cfg.FullTextIndexes[i].Field = rename(a.cfg.FullTextIndexes[i].Field)
+	}
+	if dimension, ok := a.cfg.SparseVectors[column]; ok {
+		delete(a.cfg.SparseVectors, column)
+		a.cfg.SparseVectors[newName] = dimension
+	}
+	for i := range a.cfg.VectorSpaces {
+		a.cfg.VectorSpaces[i].Name = rename(a.cfg.VectorSpaces[i].Name)
+	}
+	if generated := a.cfg.GeneratedVector; generated != nil {
+		generated.Expression = renameExpressionColumn(generated.Expression, column, newName)
+	}
+	if a.cfg.TTLField != "" {
+		a.cfg.TTLField = rename(a.cfg.TTLField)
+	} else if a.cfg.DefaultTTL > 0 && strings.EqualFold(column, DefaultTTLField) {
+		// The implicit expiry column keeps its role under the new name.
+		a.cfg.TTLField = newName
+	}
+	if a.notNull[column] {
+		delete(a.notNull, column)
+		a.notNull[newName] = true
+	}
+	a.renamed[strings.ToLower(column)] = newName
+	a.steps = append(a.steps, alterRowStep{column: column, to: newName})
+	return nil
+}
+
+// renameExpressionColumn rewrites references to column in a CHECK
+// expression, leaving string literals and other identifiers untouched.
+func renameExpressionColumn(expr, column, newName string) string {
+	tokens, err := optimizer.LexSQLTokens([]byte(expr), true)
+	if err != nil {
+		return expr
+	}
+	replacement := newName
+	if !isPlainSQLIdentifier(newName) {
+		replacement = `"` + strings.ReplaceAll(newName, `"`, `""`) + `"`
+	}
+	var b strings.Builder
+	last := 0
+	for _, token := range tokens {
+		if token.IsName() && strings.EqualFold(token.Text, column) {
+			b.WriteString(expr[last:token.Start])
+			b.WriteString(replacement)
+			last = token.End
+		}
+	}
+	b.WriteString(expr[last:])
+	return b.String()
+}
+
+func isPlainSQLIdentifier(name string) bool {
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isSQLIdentifierByte(name[i]) || name[i] == '$' {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *tableAlteration) renameConstraint(name, newName string) error {
+	kind := a.constraintKind(name)
+	if kind == "" {
+		return fmt.Errorf("ALTER TABLE: constraint %q of relation %q does not exist", name, a.table)
+	}
+	if !strings.EqualFold(name, newName) && a.constraintKind(newName) != "" {
+		return fmt.Errorf("ALTER TABLE: constraint %q for relation %q already exists", newName, a.table)
+	}
+	switch kind {
+	case "p", "k":
+		return fmt.Errorf("ALTER TABLE: constraint %q is named after its columns and cannot be renamed", name)
+	case "c":
+		for i := range a.cfg.CheckConstraints {
+			if strings.EqualFold(a.cfg.CheckConstraints[i].Name, name) {
+				a.cfg.CheckConstraints[i].Name = newName
+			}
+		}
+	case "f":
+		for i := range a.cfg.ForeignKeys {
+			if strings.EqualFold(a.cfg.ForeignKeys[i].Name, name) {
+				a.cfg.ForeignKeys[i].Name = newName
+			}
+		}
+	case "u":
+		for existing, columns := range a.cfg.NamedUniqueConstraints {
+			if strings.EqualFold(existing, name) {
+				delete(a.cfg.NamedUniqueConstraints, existing)
+				a.cfg.NamedUniqueConstraints[newName] = columns
+			}
+		}
+		for i := range a.cfg.SQLIndexes {
+			if a.cfg.SQLIndexes[i].Unique && strings.EqualFold(a.cfg.SQLIndexes[i].Name, name) {
+				a.cfg.SQLIndexes[i].Name = newName
+			}
+		}
+	}
+	return nil
+}
+
+func (a *tableAlteration) changeType(action optimizer.AlterTableAction) error {
+	column, err := a.existingColumn(action.Column)
+	if err != nil {
+		return err
+	}
+	target, err := resolveAlterType(action.Type, column)
+	if err != nil {
+		return err
+	}
+	for _, pk := range a.cfg.PrimaryKeyColumns {
+		if strings.EqualFold(pk, column) {
+			return fmt.Errorf("ALTER TABLE: cannot change the type of primary key column %q", column)
+		}
+	}
+	for _, fk := range a.cfg.ForeignKeys {
+		if strings.EqualFold(fk.SourceColumn, column) || strings.EqualFold(fk.TargetTable, a.table) && strings.EqualFold(fk.TargetColumn, column) {
+			return fmt.Errorf("ALTER TABLE: cannot change the type of column %q used by foreign key %q", column, fk.Name)
+		}
+	}
+	if a.cat != nil {
+		tableHash, colHash := catalog.HashIdentifier(a.table), catalog.HashIdentifier(column)
+		for _, fk := range a.cat.AllForeignKeys() {
+			if fk.TargetTableHash == tableHash && fk.TargetColHash == colHash {
+				return fmt.Errorf("ALTER TABLE: cannot change the type of column %q referenced by a foreign key", column)
+			}
+		}
+	}
+	if _, sparse := a.cfg.SparseVectors[column]; sparse {
+		return fmt.Errorf("ALTER TABLE: cannot change the type of sparse vector column %q", column)
+	}
+	for _, space := range a.cfg.VectorSpaces {
+		if strings.EqualFold(space.Name, column) {
+			return fmt.Errorf("ALTER TABLE: cannot change the type of vector space column %q", column)
+		}
+	}
+	if generated := a.cfg.GeneratedVector; generated != nil && containsFold(generated.sourceColumns(), column) {
+		return fmt.Errorf("ALTER TABLE: cannot change the type of column %q used by generated vector column %q", column, generated.Column)
+	}
+	for _, jsonIndex := range a.cfg.JSONIndexes {
+		if strings.EqualFold(jsonIndex.Column, column) && target.field != JSONField && target.field != JSONBField {
+			return fmt.Errorf("ALTER TABLE: JSON index %q requires column %q to remain JSON or JSONB", jsonIndex.Name, column)
+		}
+	}
+	for _, fts := range a.cfg.FullTextIndexes {
+		if strings.EqualFold(fts.Field, column) && target.field != StringField {
+			return fmt.Errorf("ALTER TABLE: full-text index %q requires column %q to remain text", fts.Name, column)
+		}
+	}
+	step := alterRowStep{column: column, from: a.cfg.MetadataSchema[column], target: target}
+	if action.UsingRoot >= 0 {
+		if err := a.bindUsing(action.UsingRoot); err != nil {
+			return err
+		}
+		step.using, step.usingRoot = a.exprs, action.UsingRoot
+	}
+	a.steps = append(a.steps, step)
+	a.cfg.MetadataSchema[column] = target.field
+	a.rewrites[column] = true
+
+	// The default must survive the change; PostgreSQL rejects the statement
+	// otherwise.
+	for name, value := range a.cfg.ColumnDefaults {
+		if !strings.EqualFold(name, column) || value == "NULL" {
+			continue
+		}
+		current, err := alterStoredDatum(parseDefaultLiteral(value), step.from)
+		if err != nil {
+			return fmt.Errorf("ALTER TABLE: default for column %q cannot be cast automatically to type %s", column, target.name)
+		}
+		converted, err := castAlterDatum(current, target, false)
+		if err != nil {
+			return fmt.Errorf("ALTER TABLE: default for column %q cannot be cast automatically to type %s", column, target.name)
+		}
+		a.cfg.ColumnDefaults[name] = converted.text()
+	}
+
+	// Values may collapse (for example float to integer), so the
+	// constraints over this column are checked again after the rewrite.
+	if a.flags(column)&catalog.ColFlagNotNull != 0 {
+		a.notNull[column] = true
+	}
+	if a.flags(column)&catalog.ColFlagUnique != 0 {
+		a.uniques[column+"_key"] = []string{column}
+	}
+	for name, columns := range a.cfg.NamedUniqueConstraints {
+		for _, unique := range columns {
+			if strings.EqualFold(unique, column) {
+				a.uniques[name] = columns
+			}
+		}
+	}
+	a.checks = append(a.checks[:0], a.cfg.CheckConstraints...)
+	return nil
+}
+
+// bindUsing checks that every column a USING expression reads exists
+// before any row is rewritten.
+func (a *tableAlteration) bindUsing(root int32) error {
+	exprs := a.exprs.InsertConflictExprs
+	if root < 0 || int(root) >= len(exprs) {
+		return nil
+	}
+	expr := exprs[root]
+	var children []int32
+	switch expr.Kind {
+	case optimizer.ConflictExprColumn:
+		if !strings.EqualFold(expr.Column, "id") && a.column(expr.Column) == "" {
+			return fmt.Errorf("ALTER TABLE: column %q does not exist", expr.Column)
+		}
+	case optimizer.ConflictExprUnary, optimizer.ConflictExprCast:
+		children = []int32{expr.Left}
+	case optimizer.ConflictExprBinary, optimizer.ConflictExprFunction:
+		children = []int32{expr.Left, expr.Right}
+	case optimizer.ConflictExprJSONFunction:
+		children = []int32{expr.Left, expr.Right, expr.Third, expr.Fourth}
+	case optimizer.ConflictExprCase:
+		children = []int32{expr.CaseElse}
+		cases := a.exprs.InsertConflictCases
+		for i := expr.CaseWhenStart; i < expr.CaseWhenStart+expr.CaseWhenCount && int(i) < len(cases); i++ {
+			children = append(children, cases[i].Condition, cases[i].Value)
+		}
+	}
+	for _, child := range children {
+		if err := a.bindUsing(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *tableAlteration) addCheck(action optimizer.AlterTableAction) error {
+	if _, err := evaluateCheckExpr(action.Expression, map[string]interface{}{}); err != nil {
+		return fmt.Errorf("ALTER TABLE: invalid CHECK expression: %w", err)
+	}
+	var first []string
+	for _, name := range action.Columns {
+		if column := a.column(name); column != "" {
+			first = []string{column}
+			break
+		}
+	}
+	name, err := a.constraintName(action.Constraint, first, "check")
+	if err != nil {
+		return err
+	}
+	chk := optimizer.DDLCheckConstraint{Name: name, Expression: action.Expression}
+	a.cfg.CheckConstraints = append(a.cfg.CheckConstraints, chk)
+	a.checks = append(a.checks, chk)
+	return nil
+}
+
+func (a *tableAlteration) addUnique(action optimizer.AlterTableAction) error {
+	columns := make([]string, len(action.Columns))
+	for i, name := range action.Columns {
+		column, err := a.referencedColumn(name)
+		if err != nil {
+			return err
+		}
+		columns[i] = column
+	}
+	name, err := a.constraintName(action.Constraint, columns, "key")
+	if err != nil {
+		return err
+	}
+	for _, index := range a.cfg.SQLIndexes {
+		if strings.EqualFold(index.Name, name) {
+			return fmt.Errorf("ALTER TABLE: relation %q already exists", name)
+		}
+	}
+	if a.cfg.NamedUniqueConstraints == nil {
+		a.cfg.NamedUniqueConstraints = make(map[string][]string)
+	}
+	a.cfg.NamedUniqueConstraints[name] = columns
+	a.uniques[name] = columns
+	// The declaration is kept as a unique SQL index so it survives reopen
+	// and its columns get equality postings, as CREATE INDEX does.
+	a.cfg.SQLIndexes = append(a.cfg.SQLIndexes, SQLIndexDefinition{Name: name, Columns: append([]string(nil), columns...), Unique: true})
+	for _, column := range columns {
+		if !containsFold(a.cfg.IndexedFields, column) {
+			a.cfg.IndexedFields = append(a.cfg.IndexedFields, column)
+			if !containsFold(a.cfg.SQLIndexedFields, column) {
+				a.cfg.SQLIndexedFields = append(a.cfg.SQLIndexedFields, column)
+			}
+		}
+	}
+	return nil
+}
+
+func containsFold(values []string, value string) bool {
+	for _, existing := range values {
+		if strings.EqualFold(existing, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *tableAlteration) addForeignKey(action optimizer.AlterTableAction) error {
+	columns := make([]string, len(action.Columns))
+	for i, name := range action.Columns {
+		column, err := a.referencedColumn(name)
+		if err != nil {
+			return err
+		}
+		columns[i] = column
+	}
+	self := strings.EqualFold(action.RefTable, a.table)
+	targetTable := action.RefTable
+	targetColumns := action.RefColumns
+	var targetNames map[uint64]string
+	if self {
+		targetTable = a.table
+		targetNames = map[uint64]string{catalog.HashIdentifier("id"): "id"}
+		for name := range a.cfg.MetadataSchema {
+			targetNames[catalog.HashIdentifier(name)] = name
+		}
+		if len(targetColumns) == 0 {
+			targetColumns = a.cfg.PrimaryKeyColumns
+		}
+	} else {
+		if a.cat == nil {
+			return fmt.Errorf("ALTER TABLE: referenced table %q does not exist", action.RefTable)
+		}
+		table, err := a.cat.GetTable(catalog.HashIdentifier(action.RefTable))
+		if err != nil {
+			return fmt.Errorf("ALTER TABLE: referenced table %q does not exist", action.RefTable)
+		}
+		targetNames = make(map[uint64]string)
+		for _, column := range a.cat.AllColumns(table) {
+			targetNames[column.NameHash] = column.Name
+		}
+		if parent, ok := a.parents[strings.ToLower(action.RefTable)]; ok {
+			targetTable = parent.name
+			if len(targetColumns) == 0 {
+				targetColumns = parent.keys
+			}
+		}
+	}
+	if len(targetColumns) == 0 {
+		targetColumns = []string{"id"}
+	}
+	if len(targetColumns) != len(columns) {
+		return fmt.Errorf("ALTER TABLE: number of referencing and referenced columns for foreign key disagree")
+	}
+	resolved := make([]string, len(targetColumns))
+	for i, name := range targetColumns {
+		column := targetNames[catalog.HashIdentifier(name)]
+		if column == "" {
+			return fmt.Errorf("ALTER TABLE: column %q referenced in foreign key constraint does not exist", name)
+		}
+		resolved[i] = column
+	}
+
+	for _, column := range columns {
+		switch {
+		case action.OnDelete == catalog.OnDeleteSetNull || action.OnUpdate == catalog.OnDeleteSetNull:
+			if a.flags(column)&catalog.ColFlagNotNull != 0 {
+				return fmt.Errorf("ALTER TABLE: ON DELETE/UPDATE SET NULL requires source column %q to allow NULL", column)
+			}
+		case action.OnDelete == catalog.OnDeleteSetDefault || action.OnUpdate == catalog.OnDeleteSetDefault:
+			if _, ok := a.cfg.ColumnDefaults[column]; !ok {
+				return fmt.Errorf("ALTER TABLE: ON DELETE/UPDATE SET DEFAULT requires source column %q to have a DEFAULT value", column)
+			}
+		}
+	}
+	name, err := a.constraintName(action.Constraint, columns[:1], "fkey")
+	if err != nil {
+		return err
+	}
+	group := make([]catalog.ForeignKeyInfo, len(columns))
+	for i := range columns {
+		group[i] = catalog.ForeignKeyInfo{
+			Name:         name,
+			SourceTable:  a.table,
+			SourceColumn: columns[i],
+			TargetTable:  targetTable,
+			TargetColumn: resolved[i],
+			OnDelete:     action.OnDelete,
+			OnUpdate:     action.OnUpdate,
+		}
+	}
+	a.cfg.ForeignKeys = append(a.cfg.ForeignKeys, group...)
+	a.fks = append(a.fks, group)
+	return nil
+}
+
+func (a *tableAlteration) dropConstraint(name string, ifExists bool) error {
+	switch a.constraintKind(name) {
+	case "p":
+		return fmt.Errorf("ALTER TABLE: dropping primary key constraint %q is not supported", name)
+	case "k":
+		column := a.columnUniqueConstraint(name)
+		a.setFlags(column, a.flags(column)&^catalog.ColFlagUnique)
+		delete(a.uniques, column+"_key")
+	case "c":
+		filtered := a.cfg.CheckConstraints[:0]
+		for _, chk := range a.cfg.CheckConstraints {
+			if !strings.EqualFold(chk.Name, name) {
+				filtered = append(filtered, chk)
+			}
+		}
+		a.cfg.CheckConstraints = filtered
+		checks := a.checks[:0]
+		for _, chk := range a.checks {
+			if !strings.EqualFold(chk.Name, name) {
+				checks = append(checks, chk)
+			}
+		}
+		a.checks = checks
+	case "f":
+		filtered := a.cfg.ForeignKeys[:0]
+		for _, fk := range a.cfg.ForeignKeys {
+			if !strings.EqualFold(fk.Name, name) {
+				filtered = append(filtered, fk)
+			}
+		}
+		a.cfg.ForeignKeys = filtered
+		fks := a.fks[:0]
+		for _, group := range a.fks {
+			if !strings.EqualFold(group[0].Name, name) {
+				fks = append(fks, group)
+			}
+		}
+		a.fks = fks
+	case "u":
+		for existing, columns := range a.cfg.NamedUniqueConstraints {
+			if !strings.EqualFold(existing, name) {
+				continue
+			}
+			if len(a.cfg.PrimaryKeyColumns) > 0 && sameColumnSet(columns, a.cfg.PrimaryKeyColumns) {
+				return fmt.Errorf("ALTER TABLE: dropping primary key constraint %q is not supported", existing)
+			}
+			delete(a.cfg.NamedUniqueConstraints, existing)
+			delete(a.uniques, existing)
+		}
+		removeSQLIndexDefinition(a.cfg, name)
+	default:
+		if ifExists {
+			return nil
+		}
+		return fmt.Errorf("ALTER TABLE: constraint %q of relation %q does not exist", name, a.table)
+	}
+	return nil
+}
+
+// rewriteRows applies the queued row steps to every record and validates
+// the constraints the actions added or affected. It returns the Put
+// operations for changed records and their new entries.
+func (a *tableAlteration) rewriteRows(ctx context.Context, col *Collection) ([]storage.TxOperation, []*index.VectorEntry, error) {
+	if len(a.steps) == 0 && len(a.notNull) == 0 && len(a.checks) == 0 && len(a.uniques) == 0 && len(a.fks) == 0 {
+		return nil, nil, nil
+	}
+	type row struct {
+		entry   *index.VectorEntry
+		changed bool
+	}
+	var rows []row
+	err := col.storage.Iterate(ctx, func(entry *index.VectorEntry) error {
+		metadata := cloneMetadata(entry.Metadata)
+		changed := false
+		for _, step := range a.steps {
+			stepChanged, err := step.apply(entry.ID, metadata)
+			if err != nil {
+				return err
+			}
+			changed = changed || stepChanged
+		}
+		rewritten := *entry
+		rewritten.Metadata = metadata
+		rows = append(rows, row{entry: &rewritten, changed: changed})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	notNull := make([]string, 0, len(a.notNull))
+	for column := range a.notNull {
+		notNull = append(notNull, column)
+	}
+	sort.Strings(notNull)
+	uniqueNames := make([]string, 0, len(a.uniques))
+	for name := range a.uniques {
+		uniqueNames = append(uniqueNames, name)
+	}
+	sort.Strings(uniqueNames)
+	seen := make([]map[string]string, len(uniqueNames))
+	for i := range seen {
+		seen[i] = make(map[string]string)
+	}
+	for _, r := range rows {
+		for _, column := range notNull {
+			if value, ok := metadataValueFold(r.entry.Metadata, column); !ok || value == nil {
+				return nil, nil, fmt.Errorf("ALTER TABLE: column %q of relation %q contains null values", column, a.table)
+			}
+		}
+		for _, chk := range a.checks {
+			ok, err := evaluateCheckExpr(chk.Expression, r.entry.Metadata)
+			if err != nil {
+				return nil, nil, fmt.Errorf("ALTER TABLE: CHECK constraint evaluation error: %w", err)
+			}
+			if !ok {
+				return nil, nil, fmt.Errorf("ALTER TABLE: check constraint %q of relation %q is violated by some row", chk.Name, a.table)
+			}
+		}
+		for i, name := range uniqueNames {
+			key, ok := namedUniqueKey(r.entry.ID, r.entry.Metadata, a.uniques[name])
+			if !ok {
+				continue
+			}
+			if prior, exists := seen[i][key]; exists {
+				return nil, nil, fmt.Errorf("ALTER TABLE: UNIQUE constraint %q violated by rows %q and %q", name, prior, r.entry.ID)
+			}
+			seen[i][key] = r.entry.ID
+		}
+	}
+	for _, group := range a.fks {
+		parents := a.parents[strings.ToLower(group[0].TargetTable)].records
+		if strings.EqualFold(group[0].TargetTable, a.table) {
+			parents = make([]Record, len(rows))
+			for i, r := range rows {
+				parents[i] = Record{ID: r.entry.ID, Metadata: r.entry.Metadata}
+			}
+		}
+		keys := make(map[string]struct{}, len(parents))
+		for _, parent := range parents {
+			if key, ok := foreignKeyTuple(parent.ID, parent.Metadata, group, false); ok {
+				keys[key] = struct{}{}
+			}
+		}
+		for _, r := range rows {
+			key, ok := foreignKeyTuple(r.entry.ID, r.entry.Metadata, group, true)
+			if !ok {
+				continue
+			}
+			if _, exists := keys[key]; !exists {
+				return nil, nil, fmt.Errorf("ALTER TABLE: insert or update on table %q violates foreign key constraint %q: row %q references a missing row in %q",
+					a.table, group[0].Name, r.entry.ID, group[0].TargetTable)
+			}
+		}
+	}
+
+	var ops []storage.TxOperation
+	var changed []*index.VectorEntry
+	for _, r := range rows {
+		if !r.changed {
+			continue
+		}
+		ops = append(ops, storage.TxOperation{
+			Type:       storage.TxOperationPut,
+			Collection: a.table,
+			ID:         r.entry.ID,
+			Vector:     r.entry.Vector,
+			Metadata:   r.entry.Metadata,
+			Ordinal:    r.entry.Ordinal,
+		})
+		changed = append(changed, r.entry)
+	}
+	return ops, changed, nil
+}
+
+// foreignKeyTuple renders the source (or target) columns of a foreign key
+// group the way validateForeignKeys compares them. ok is false when any
+// component is NULL, which leaves the row unconstrained.
+func foreignKeyTuple(id string, metadata map[string]interface{}, group []catalog.ForeignKeyInfo, source bool) (string, bool) {
+	var b strings.Builder
+	for _, fk := range group {
+		column := fk.TargetColumn
+		if source {
+			column = fk.SourceColumn
+		}
+		value := fkValueFromRecord(id, metadata, catalog.HashIdentifier(column))
+		if value.Null {
+			return "", false
+		}
+		b.WriteString(value.Value)
+		b.WriteByte(0)
+	}
+	return b.String(), true
+}
+
+func metadataValueFold(metadata map[string]interface{}, column string) (interface{}, bool) {
+	if value, ok := metadata[column]; ok {
+		return value, true
+	}
+	for key, value := range metadata {
+		if strings.EqualFold(key, column) {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// usingDatum types the text result of a USING expression. The evaluator
+// renders numbers in their SQL text form; anything else stays an untyped
+// literal and is assigned with the column's input rules.
+func usingDatum(text string, isNull bool) alterDatum {
+	if isNull {
+		return alterNull(alterTextType)
+	}
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return alterDatum{typ: alterBigIntType, value: n}
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+		return alterDatum{typ: alterFloatType, value: f}
+	}
+	return alterLiteral(text)
+}
+
+// apply rewrites one record in place and reports whether it changed.
+func (s alterRowStep) apply(id string, metadata map[string]interface{}) (bool, error) {
+	if s.to != "" {
+		for key, value := range metadata {
+			if strings.EqualFold(key, s.column) {
+				delete(metadata, key)
+				metadata[s.to] = value
+				return key != s.to, nil
+			}
+		}
+		return false, nil
+	}
+	original, present := metadataValueFold(metadata, s.column)
+	var value alterDatum
+	if s.using != nil {
+		text, isNull, err := evalConflictExpr(s.using, s.usingRoot, Record{ID: id, Metadata: metadata}, VectorEntry{})
+		if err != nil {
+			return false, fmt.Errorf("ALTER TABLE: row %q: %w", id, err)
+		}
+		if value, err = castAlterDatum(usingDatum(text, isNull), s.target, false); err != nil {
+			return false, fmt.Errorf("ALTER TABLE: result of USING clause for column %q cannot be cast automatically to type %s: %w", s.column, s.target.name, err)
+		}
+	} else {
+		if !present || original == nil {
+			return false, nil
+		}
+		source, err := alterStoredDatum(original, s.from)
+		if err != nil {
+			return false, fmt.Errorf("ALTER TABLE: row %q: %w", id, err)
+		}
+		if !alterImplicitCast(source.typ, s.target) {
+			return false, fmt.Errorf("ALTER TABLE: column %q cannot be cast automatically to type %s; specify a USING expression", s.column, s.target.name)
+		}
+		if value, err = castAlterDatum(source, s.target, false); err != nil {
+			return false, fmt.Errorf("ALTER TABLE: row %q: %w", id, err)
+		}
+	}
+	for key := range metadata {
+		if strings.EqualFold(key, s.column) {
+			delete(metadata, key)
+		}
+	}
+	if value.null {
+		if present && original != nil {
+			metadata[s.column] = nil
+			return true, nil
+		}
+		return false, nil
+	}
+	_, textual := original.(string)
+	metadata[s.column] = value.stored(textual || !present || original == nil)
+	return true, nil
+}
+
+// buildCatalog returns the catalog with table oldName replaced by name and
+// its columns and constraints taken from the working config.
+func (a *tableAlteration) buildCatalog(oldName, name string, tableNames map[uint64]string) ([]byte, error) {
+	builder := catalog.NewBuilderFrom(a.cat)
+	columns := []catalog.ColumnInfo{{Name: "id", Type: catalog.TypeString, Flags: catalog.ColFlagPrimaryKey | catalog.ColFlagNotNull}}
+	listed := make(map[string]bool)
+	if a.cat != nil {
+		if table, err := a.cat.GetTable(catalog.HashIdentifier(oldName)); err == nil {
+			columns = columns[:0]
+			for _, column := range a.cat.AllColumns(table) {
+				info := catalog.ColumnInfo{Name: column.Name, Type: column.Type, Flags: column.Flags}
+				if renamed, ok := a.renamed[strings.ToLower(column.Name)]; ok {
+					info.Name = renamed
+				}
+				if current := a.column(info.Name); current != "" && column.Type != catalog.TypeVector {
+					info.Name = current
+					info.Flags = a.flags(current)
+					if a.rewrites[current] {
+						info.Type = metadataFieldToCatalogType(a.cfg.MetadataSchema[current])
+					}
+				}
+				listed[strings.ToLower(info.Name)] = true
+				columns = append(columns, info)
+			}
+		}
+	}
+	var added []string
+	for column := range a.cfg.MetadataSchema {
+		if !listed[strings.ToLower(column)] {
+			added = append(added, column)
+		}
+	}
+	sort.Strings(added)
+	for _, column := range added {
+		columns = append(columns, catalog.ColumnInfo{
+			Name:  column,
+			Type:  metadataFieldToCatalogType(a.cfg.MetadataSchema[column]),
+			Flags: a.flags(column),
+		})
+	}
+
+	builder.DropTable(oldName)
+	if oldName != name {
+		builder.RenameForeignKeyTarget(oldName, name)
+	}
+	for oldColumn, newColumn := range a.renamed {
+		builder.RenameForeignKeyTargetColumn(name, oldColumn, newColumn)
+	}
+	builder.AddTable(name, columns)
+	for _, fk := range a.cfg.ForeignKeys {
+		if tableNames != nil && tableNames[catalog.HashIdentifier(fk.TargetTable)] == "" && !strings.EqualFold(fk.TargetTable, name) {
+			return nil, fmt.Errorf("ALTER TABLE: referenced table %q does not exist", fk.TargetTable)
+		}
+		builder.AddForeignKey(fk)
+	}
+	for _, chk := range a.cfg.CheckConstraints {
+		builder.AddNamedCheckConstraint(name, chk.Name, chk.Expression, chk.ColumnName)
+	}
+	defaults := make([]string, 0, len(a.cfg.ColumnDefaults))
+	for column := range a.cfg.ColumnDefaults {
+		defaults = append(defaults, column)
+	}
+	sort.Strings(defaults)
+	for _, column := range defaults {
+		builder.AddDefaultValue(name, column, a.cfg.ColumnDefaults[column])
+	}
+	jsonIndexes := make([]catalog.JSONIndexInfo, 0, len(a.cfg.JSONIndexes))
+	for _, jsonIndex := range a.cfg.JSONIndexes {
+		jsonIndexes = append(jsonIndexes, catalog.JSONIndexInfo{
+			Name: jsonIndex.Name, Table: name, Column: jsonIndex.Column,
+			Path: jsonIndex.Path, TextResult: jsonIndex.TextResult,
+		})
+	}
+	builder.ReplaceJSONIndexesForTable(name, jsonIndexes)
+	return builder.Build(), nil
+}
+
+// renameTable executes ALTER TABLE ... RENAME TO. The physical rename and
+// the catalog that re-registers the table under its new name commit as one
+// transaction.
+func (db *Database) renameTable(ctx context.Context, col *Collection, newName string) error {
+	oldName := col.name
+	if newName == oldName {
+		return nil
+	}
+	cfg := col.Config()
+	if cfg.Sharded {
+		return fmt.Errorf("ALTER TABLE: sharded table %q cannot be renamed", oldName)
+	}
+	if cfg.GraphNamespace != "" || col.graph != nil {
+		return fmt.Errorf("ALTER TABLE: graph table %q cannot be renamed", oldName)
+	}
+	if _, system := catalog.ResolveSystemTable(newName); system {
+		return fmt.Errorf("ALTER TABLE: %q is a reserved system table name", newName)
+	}
+	if _, _, shard := parseShardName(newName); shard {
+		return fmt.Errorf("ALTER TABLE: %q is a reserved shard name", newName)
+	}
+	engine, ok := db.storage.(storage.TransactionalEngine)
+	if !ok {
+		return fmt.Errorf("ALTER TABLE: storage engine does not support transactional schema changes")
+	}
+
+	release, err := col.acquireWrite(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	unlockAsync, err := col.lockAsyncMutation(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to flush asynchronous index before ALTER TABLE: %w", err)
+	}
+	defer unlockAsync()
+
+	// The collection's own foreign keys move with it, including those that
+	// reference itself.
+	for i := range cfg.ForeignKeys {
+		cfg.ForeignKeys[i].SourceTable = newName
+		if strings.EqualFold(cfg.ForeignKeys[i].TargetTable, oldName) {
+			cfg.ForeignKeys[i].TargetTable = newName
+		}
+	}
+
+	db.mu.Lock()
+	if db.closed {
+		db.mu.Unlock()
+		return ErrDatabaseClosed
+	}
+	if db.collections[oldName] != col {
+		db.mu.Unlock()
+		return fmt.Errorf("ALTER TABLE: table %q not found", oldName)
+	}
+	names := make([]string, 0, len(db.collections))
+	for name := range db.collections {
+		if strings.EqualFold(name, newName) && name != oldName {
+			db.mu.Unlock()
+			return fmt.Errorf("ALTER TABLE: relation %q already exists", newName)
+		}
+		if name != oldName {
+			names = append(names, name)
+		}
+	}
+	names = append(names, newName)
+	alt := &tableAlteration{
+		db: db, table: oldName, cfg: &cfg, cat: db.catalog,
+		vectors: make(map[string]bool), renamed: make(map[string]string), rewrites: make(map[string]bool),
+	}
+	alt.hydrateColumns()
+	data, err := alt.buildCatalog(oldName, newName, catalogTableNames(names))
+	if err != nil {
+		db.mu.Unlock()
+		return err
+	}
+	newCatalog, err := catalog.Load(data, db.quantRegistry)
+	if err != nil {
+		db.mu.Unlock()
+		return fmt.Errorf("ALTER TABLE: build catalog: %w", err)
+	}
+	if err := engine.CommitTx(ctx, []storage.TxOperation{
+		{Type: storage.TxOperationCollectionRename, Collection: oldName, NewName: newName},
+		{Type: storage.TxOperationCatalog, Catalog: data},
+	}); err != nil {
+		db.mu.Unlock()
+		return err
+	}
+	delete(db.collections, oldName)
+	db.collections[newName] = col
+	db.catalog = newCatalog
+	db.catalogGeneration.Add(1)
+	db.mu.Unlock()
+
+	col.mu.Lock()
+	col.name = newName
+	if col.config != nil {
+		col.config.ForeignKeys = cfg.ForeignKeys
+	}
+	col.mu.Unlock()
+	db.updateReferencingForeignKeys(col, func(fk *catalog.ForeignKeyInfo) {
+		if strings.EqualFold(fk.TargetTable, oldName) {
+			fk.TargetTable = newName
+		}
+	})
+
+	// SERIAL allocation restarts from the persisted rows under either name.
+	db.autoIncrementMu.Lock()
+	delete(db.autoIncrementNext, strings.ToLower(oldName))
+	delete(db.autoIncrementNext, strings.ToLower(newName))
+	db.autoIncrementMu.Unlock()
+	return nil
+}
@@ -0,0 +1,503 @@
+package libravdb
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Value conversion for ALTER COLUMN ... TYPE. SQL INSERT stores metadata as
+// text and Go callers store native values, so a stored value is first read
+// according to the column's old type, converted with PostgreSQL's assignment
+// (or, under USING and ::, explicit) cast rules and written back in the form
+// it arrived in.
+
+type alterCategory uint8
+
+const (
+	alterText alterCategory = iota + 1
+	alterInt
+	alterFloat
+	alterBool
+	alterTime
+	alterJSON
+	alterUUID
+)
+
+// alterType is a resolved SQL column type.
+type alterType struct {
+	name     string
+	category alterCategory
+	field    FieldType
+	bits     int // integer width
+	length   int // VARCHAR/CHAR length; 0 when unbounded
+	scale    int // NUMERIC scale; -1 when unconstrained
+	date     bool
+	clock    bool // TIME: time of day only
+	jsonb    bool
+}
+
+var alterTypeAliases = map[string]string{
+	"INT":               "INTEGER",
+	"INT2":              "SMALLINT",
+	"INT4":              "INTEGER",
+	"INT8":              "BIGINT",
+	"FLOAT4":            "REAL",
+	"FLOAT8":            "DOUBLE PRECISION",
+	"FLOAT":             "DOUBLE PRECISION",
+	"DOUBLE":            "DOUBLE PRECISION",
+	"DECIMAL":           "NUMERIC",
+	"BOOL":              "BOOLEAN",
+	"STRING":            "TEXT",
+	"CHARACTER VARYING": "VARCHAR",
+	"CHARACTER":         "CHAR",
+	"TIMESTAMPTZ":       "TIMESTAMP WITH TIME ZONE",
+}
+
+// resolveAlterType resolves the canonical type name of an AlterTableAction.
+func resolveAlterType(typeName, column string) (alterType, error) {
+	base, params := typeName, ""
+	if open := strings.IndexByte(typeName, '('); open >= 0 {
+		base, params = typeName[:open], strings.TrimSuffix(typeName[open+1:], ")")
+	}
+	if alias, ok := alterTypeAliases[base]; ok {
+		base = alias
+	}
+	switch base {
+	case "SERIAL", "SMALLSERIAL", "BIGSERIAL", "SERIAL2", "SERIAL4", "SERIAL8":
+		return alterType{}, fmt.Errorf("ALTER TABLE: type %s can only be used in CREATE TABLE", strings.ToLower(base))
+	case "VECTOR", "SPARSEVEC":
+		return alterType{}, fmt.Errorf("ALTER TABLE: cannot change column %q to type %s", column, base)
+	}
+	field, ok := sqlTypeToFieldType(base)
+	if !ok {
+		return alterType{}, fmt.Errorf("ALTER TABLE: unsupported type %q for column %q", typeName, column)
+	}
+	typ := alterType{name: base, field: field, scale: -1}
+	var args []int
+	if params != "" {
+		for _, param := range strings.Split(params, ",") {
+			n, err := strconv.Atoi(param)
+			if err != nil || n < 0 {
+				return alterType{}, fmt.Errorf("ALTER TABLE: invalid modifier for type %s", base)
+			}
+			args = append(args, n)
+		}
+		typ.name = base + "(" + params + ")"
+	}
+	switch base {
+	case "SMALLINT", "INTEGER", "BIGINT":
+		typ.category = alterInt
+		typ.bits = map[string]int{"SMALLINT": 16, "INTEGER": 32, "BIGINT": 64}[base]
+	case "REAL", "DOUBLE PRECISION":
+		typ.category = alterFloat
+	case "NUMERIC":
+		typ.category = alterFloat
+		if len(args) == 2 {
+			typ.scale = args[1]
+		} else if len(args) == 1 {
+			typ.scale = 0
+		}
+	case "BOOLEAN":
+		typ.category = alterBool
+	case "TIMESTAMP", "TIMESTAMP WITH TIME ZONE":
+		typ.category = alterTime
+	case "DATE":
+		typ.category, typ.date = alterTime, true
+	case "TIME":
+		typ.category, typ.clock = alterTime, true
+	case "JSON", "JSONB":
+		typ.category, typ.jsonb = alterJSON, base == "JSONB"
+	case "UUID":
+		typ.category = alterUUID
+	case "VARCHAR", "CHAR":
+		typ.category = alterText
+		if len(args) > 0 {
+			typ.length = args[0]
+		}
+	default:
+		typ.category = alterText
+	}
+	if len(args) > 0 && typ.length == 0 && base != "NUMERIC" && typ.category != alterTime {
+		return alterType{}, fmt.Errorf("ALTER TABLE: type %s does not accept modifiers", base)
+	}
+	return typ, nil
+}
+
+// alterTypeForField returns the SQL type a metadata field type is read as.
+func alterTypeForField(field FieldType) alterType {
+	switch field {
+	case IntField:
+		return alterType{name: "INTEGER", category: alterInt, field: field, bits: 64, scale: -1}
+	case BigIntField:
+		return alterType{name: "BIGINT", category: alterInt, field: field, bits: 64, scale: -1}
+	case FloatField:
+		return alterType{name: "DOUBLE PRECISION", category: alterFloat, field: field, scale: -1}
+	case BoolField:
+		return alterType{name: "BOOLEAN", category: alterBool, field: field, scale: -1}
+	case TimeField:
+		return alterType{name: "TIMESTAMP", category: alterTime, field: field, scale: -1}
+	case JSONField:
+		return alterType{name: "JSON", category: alterJSON, field: field, scale: -1}
+	case JSONBField:
+		return alterType{name: "JSONB", category: alterJSON, field: field, scale: -1, jsonb: true}
+	default:
+		return alterType{name: "TEXT", category: alterText, field: StringField, scale: -1}
+	}
+}
+
+var (
+	alterTextType   = alterTypeForField(StringField)
+	alterBigIntType = alterTypeForField(BigIntField)
+	alterFloatType  = alterTypeForField(FloatField)
+	alterBoolType   = alterTypeForField(BoolField)
+)
+
+// alterDatum is a typed SQL value. value is a string, int64, float64, bool,
+// time.Time or a canonical JSON tree.
+type alterDatum struct {
+	typ     alterType
+	value   interface{}
+	null    bool
+	literal bool // an untyped string literal, assignable to any type
+}
+
+func alterNull(typ alterType) alterDatum { return alterDatum{typ: typ, null: true} }
+
+// alterLiteral returns an untyped string literal.
+func alterLiteral(text string) alterDatum {
+	return alterDatum{typ: alterTextType, value: text, literal: true}
+}
+
+// alterStoredDatum reads a stored metadata value as a value of the column's
+// declared type.
+func alterStoredDatum(value interface{}, field FieldType) (alterDatum, error) {
+	typ := alterTypeForField(field)
+	if value == nil {
+		return alterNull(typ), nil
+	}
+	if typ.category == alterJSON {
+		canonical, ok := decodeJSONValue(value)
+		if !ok {
+			return alterDatum{}, fmt.Errorf("invalid JSON value %v", value)
+		}
+		return alterDatum{typ: typ, value: canonical}, nil
+	}
+	if typ.category == alterText {
+		return alterDatum{typ: typ, value: alterValueText(value)}, nil
+	}
+	if text, ok := value.(string); ok {
+		return castAlterDatum(alterDatum{typ: alterTextType, value: text}, typ, true)
+	}
+	switch v := value.(type) {
+	case int:
+		return alterNumber(typ, int64(v))
+	case int8:
+		return alterNumber(typ, int64(v))
+	case int16:
+		return alterNumber(typ, int64(v))
+	case int32:
+		return alterNumber(typ, int64(v))
+	case int64:
+		return alterNumber(typ, v)
+	case uint:
+		return alterNumber(typ, float64(v))
+	case uint8:
+		return alterNumber(typ, int64(v))
+	case uint16:
+		return alterNumber(typ, int64(v))
+	case uint32:
+		return alterNumber(typ, int64(v))
+	case uint64:
+		return alterNumber(typ, float64(v))
+	case float32:
+		return alterNumber(typ, float64(v))
+	case float64:
+		return alterNumber(typ, v)
+	case bool:
+		return castAlterDatum(alterDatum{typ: alterBoolType, value: v}, typ, true)
+	case time.Time:
+		if typ.category == alterTime {
+			return alterDatum{typ: typ, value: v}, nil
+		}
+	}
+	return castAlterDatum(alterDatum{typ: alterTextType, value: alterValueText(value)}, typ, true)
+}
+
+func alterNumber(typ alterType, value interface{}) (alterDatum, error) {
+	var d alterDatum
+	switch v := value.(type) {
+	case int64:
+		d = alterDatum{typ: alterBigIntType, value: v}
+	case float64:
+		d = alterDatum{typ: alterFloatType, value: v}
+	}
+	return castAlterDatum(d, typ, true)
+}
+
+func alterValueText(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// text renders d in its SQL text form.
+func (d alterDatum) text() string {
+	switch v := d.value.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case time.Time:
+		switch {
+		case d.typ.date:
+			return v.Format("2006-01-02")
+		case d.typ.clock:
+			return v.Format("15:04:05.999999999")
+		default:
+			return v.Format(time.RFC3339Nano)
+		}
+	}
+	if d.typ.category == alterJSON {
+		if encoded, err := encodeJSONValue(d.value); err == nil {
+			return encoded
+		}
+	}
+	return fmt.Sprint(d.value)
+}
+
+// stored returns the metadata representation of d: JSON documents as their
+// canonical tree, everything else natively, or as text when textual is set
+// because the row was written through SQL.
+func (d alterDatum) stored(textual bool) interface{} {
+	if d.typ.category == alterJSON {
+		return d.value
+	}
+	if textual {
+		return d.text()
+	}
+	switch v := d.value.(type) {
+	case int64:
+		if d.typ.field == IntField && v >= math.MinInt && v <= math.MaxInt {
+			return int(v)
+		}
+		return v
+	case float64:
+		return v
+	}
+	return d.value
+}
+
+// alterImplicitCast reports whether values of type from may be assigned to
+// a column of type to without a USING clause.
+func alterImplicitCast(from, to alterType) bool {
+	switch {
+	case from.category == to.category, to.category == alterText:
+		return true
+	case (from.category == alterInt || from.category == alterFloat) && (to.category == alterInt || to.category == alterFloat):
+		return true
+	case from.category == alterText && to.category == alterUUID:
+		return true
+	}
+	return false
+}
+
+// castAlterDatum converts d to target. Explicit casts (USING, ::, CAST)
+// additionally parse text and convert between integers and booleans.
+func castAlterDatum(d alterDatum, target alterType, explicit bool) (alterDatum, error) {
+	if d.null {
+		return alterNull(target), nil
+	}
+	if d.literal {
+		explicit = true
+	} else if !explicit && !alterImplicitCast(d.typ, target) {
+		return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+	}
+	out := alterDatum{typ: target}
+	switch target.category {
+	case alterText:
+		text := d.text()
+		if target.length > 0 && len([]rune(text)) > target.length {
+			if !explicit {
+				return alterDatum{}, fmt.Errorf("value too long for type %s", alterTypeLabel(target))
+			}
+			text = string([]rune(text)[:target.length])
+		}
+		out.value = text
+	case alterUUID:
+		text := strings.ToLower(strings.TrimSpace(d.text()))
+		if !validVirtualUUID(text) {
+			return alterDatum{}, fmt.Errorf("invalid input syntax for type uuid: %q", d.text())
+		}
+		out.value = text
+	case alterInt:
+		var n int64
+		switch v := d.value.(type) {
+		case int64:
+			n = v
+		case float64:
+			if math.IsNaN(v) || math.IsInf(v, 0) || v < math.MinInt64 || v >= math.MaxInt64 {
+				return alterDatum{}, fmt.Errorf("integer out of range")
+			}
+			n = int64(math.RoundToEven(v))
+		case bool:
+			if !explicit || d.typ.category != alterBool {
+				return alterDatum{}, fmt.Errorf("cannot cast type boolean to %s", alterTypeLabel(target))
+			}
+			if v {
+				n = 1
+			}
+		case string:
+			if d.typ.category != alterText {
+				return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+			}
+			parsed, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+					return alterDatum{}, fmt.Errorf("integer out of range")
+				}
+				return alterDatum{}, fmt.Errorf("invalid input syntax for type %s: %q", alterTypeLabel(target), v)
+			}
+			n = parsed
+		default:
+			return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+		}
+		if target.bits > 0 && target.bits < 64 {
+			limit := int64(1) << (target.bits - 1)
+			if n < -limit || n >= limit {
+				if target.bits == 16 {
+					return alterDatum{}, fmt.Errorf("smallint out of range")
+				}
+				return alterDatum{}, fmt.Errorf("integer out of range")
+			}
+		}
+		out.value = n
+	case alterFloat:
+		var f float64
+		switch v := d.value.(type) {
+		case int64:
+			f = float64(v)
+		case float64:
+			f = v
+		case string:
+			if d.typ.category != alterText {
+				return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return alterDatum{}, fmt.Errorf("invalid input syntax for type %s: %q", alterTypeLabel(target), v)
+			}
+			f = parsed
+		default:
+			return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+		}
+		if target.scale >= 0 {
+			pow := math.Pow10(target.scale)
+			f = math.Round(f*pow) / pow
+		}
+		out.value = f
+	case alterBool:
+		switch v := d.value.(type) {
+		case bool:
+			out.value = v
+		case int64:
+			if !explicit || d.typ.category != alterInt {
+				return alterDatum{}, fmt.Errorf("cannot cast type %s to boolean", alterTypeLabel(d.typ))
+			}
+			out.value = v != 0
+		case string:
+			if d.typ.category != alterText {
+				return alterDatum{}, fmt.Errorf("cannot cast type %s to boolean", alterTypeLabel(d.typ))
+			}
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "t", "true", "y", "yes", "on", "1":
+				out.value = true
+			case "f", "false", "n", "no", "off", "0":
+				out.value = false
+			default:
+				return alterDatum{}, fmt.Errorf("invalid input syntax for type boolean: %q", v)
+			}
+		default:
+			return alterDatum{}, fmt.Errorf("cannot cast type %s to boolean", alterTypeLabel(d.typ))
+		}
+	case alterTime:
+		var t time.Time
+		switch v := d.value.(type) {
+		case time.Time:
+			t = v
+		case string:
+			if d.typ.category != alterText {
+				return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+			}
+			parsed, ok := parseAlterTime(strings.TrimSpace(v))
+			if !ok {
+				return alterDatum{}, fmt.Errorf("invalid input syntax for type %s: %q", alterTypeLabel(target), v)
+			}
+			t = parsed
+		default:
+			return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+		}
+		if target.date {
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		}
+		out.value = t
+	case alterJSON:
+		if d.typ.category == alterJSON {
+			out.value = d.value
+			break
+		}
+		text, ok := d.value.(string)
+		if !ok || d.typ.category != alterText {
+			return alterDatum{}, fmt.Errorf("cannot cast type %s to %s", alterTypeLabel(d.typ), alterTypeLabel(target))
+		}
+		canonical, ok := decodeJSONValue(text)
+		if !ok {
+			return alterDatum{}, fmt.Errorf("invalid input syntax for type %s: %q", alterTypeLabel(target), text)
+		}
+		out.value = canonical
+	}
+	return out, nil
+}
+
+var alterTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+	"15:04:05.999999999",
+}
+
+func parseAlterTime(text string) (time.Time, bool) {
+	for _, layout := range alterTimeLayouts {
+		if t, err := time.Parse(layout, text); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func alterTypeLabel(typ alterType) string {
+	if typ.name == "" {
+		return "unknown"
+	}
+	return strings.ToLower(typ.name)
+}
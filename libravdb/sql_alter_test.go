@@ -0,0 +1,236 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func alterMustFail(t *testing.T, db *Database, sql, want string) {
+	t.Helper()
+	_, err := db.Query(context.Background(), sql)
+	if err == nil {
+		t.Fatalf("SQL %q succeeded, want error containing %q", sql, want)
+	}
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("SQL %q: error %q does not contain %q", sql, err, want)
+	}
+}
+
+func alterField(t *testing.T, db *Database, table, id, column string) interface{} {
+	t.Helper()
+	rec, err := getColl(t, db, table).Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get(%s/%s): %v", table, id, err)
+	}
+	return rec.Metadata[column]
+}
+
+func TestAlterColumnTypeUsingIsAtomic(t *testing.T) {
+	db := openTempDB(t, "alter_type")
+	defer db.Close()
+
+	exec(t, db, `CREATE TABLE stock (id TEXT PRIMARY KEY, qty TEXT, price TEXT)`)
+	exec(t, db, `INSERT INTO stock (id, qty, price) VALUES ('a', '5', '1.50')`)
+	exec(t, db, `INSERT INTO stock (id, qty, price) VALUES ('b', 'many', '2')`)
+
+	alterMustFail(t, db, `ALTER TABLE stock ALTER COLUMN qty TYPE INTEGER`, "specify a USING expression")
+	alterMustFail(t, db, `ALTER TABLE stock ALTER COLUMN qty TYPE INTEGER USING qty::integer`, `cannot cast "many" to integer`)
+	alterMustFail(t, db, `ALTER TABLE stock ALTER COLUMN qty TYPE INTEGER USING nope::integer`, `column "nope" does not exist`)
+
+	// The failed statements rewrote nothing.
+	if got := alterField(t, db, "stock", "a", "qty"); got != "5" {
+		t.Fatalf("qty after failed ALTER = %#v, want \"5\"", got)
+	}
+	if cfg := getColl(t, db, "stock").Config(); cfg.MetadataSchema["qty"] != StringField {
+		t.Fatalf("qty type after failed ALTER = %v", cfg.MetadataSchema["qty"])
+	}
+
+	exec(t, db, `ALTER TABLE stock ALTER COLUMN qty TYPE INTEGER USING COALESCE(NULLIF(qty, 'many'), '0')::integer * 10, ALTER COLUMN price TYPE NUMERIC(10,2) USING price::numeric`)
+	cfg := getColl(t, db, "stock").Config()
+	intField, _ := sqlTypeToFieldType("INTEGER")
+	if cfg.MetadataSchema["qty"] != intField {
+		t.Fatalf("qty type = %v, want %v", cfg.MetadataSchema["qty"], intField)
+	}
+	for id, want := range map[string]string{"a": "50", "b": "0"} {
+		if got := fmt.Sprint(alterField(t, db, "stock", id, "qty")); got != want {
+			t.Fatalf("qty of %s = %s, want %s", id, got, want)
+		}
+	}
+	if got := fmt.Sprint(alterField(t, db, "stock", "a", "price")); got != "1.5" {
+		t.Fatalf("price of a = %s, want 1.5", got)
+	}
+
+	// Comparisons now follow the new integer type.
+	res, err := db.Query(context.Background(), `SELECT id FROM stock WHERE qty > 10`)
+	if err != nil {
+		t.Fatalf("query: %v", err)
+	}
+	if len(res.Results) != 1 || res.Results[0].ID != "a" {
+		t.Fatalf("qty > 10 matched %d rows", len(res.Results))
+	}
+}
+
+func TestAlterRenameFollowsForeignKeys(t *testing.T) {
+	db := openTempDB(t, "alter_rename")
+	defer db.Close()
+
+	exec(t, db, `CREATE TABLE parents (id TEXT PRIMARY KEY, code TEXT UNIQUE)`)
+	exec(t, db, `CREATE TABLE children (id TEXT PRIMARY KEY, parent_code TEXT REFERENCES parents(code))`)
+	exec(t, db, `INSERT INTO parents (id, code) VALUES ('p1', 'alpha')`)
+	exec(t, db, `INSERT INTO children (id, parent_code) VALUES ('c1', 'alpha')`)
+
+	exec(t, db, `ALTER TABLE parents RENAME COLUMN code TO slug`)
+	exec(t, db, `ALTER TABLE parents RENAME TO guardians`)
+	exec(t, db, `ALTER TABLE children RENAME COLUMN parent_code TO guardian_slug`)
+
+	if _, err := db.GetCollection("parents"); err == nil {
+		t.Fatal("table still reachable under its old name")
+	}
+	if got := alterField(t, db, "guardians", "p1", "slug"); got != "alpha" {
+		t.Fatalf("renamed column value = %#v", got)
+	}
+	if got := alterField(t, db, "children", "c1", "guardian_slug"); got != "alpha" {
+		t.Fatalf("renamed FK column value = %#v", got)
+	}
+	fks := getColl(t, db, "children").Config().ForeignKeys
+	if len(fks) != 1 || fks[0].TargetTable != "guardians" || fks[0].TargetColumn != "slug" || fks[0].SourceColumn != "guardian_slug" {
+		t.Fatalf("foreign keys = %+v", fks)
+	}
+
+	exec(t, db, `INSERT INTO children (id, guardian_slug) VALUES ('c2', 'alpha')`)
+	alterMustFail(t, db, `INSERT INTO children (id, guardian_slug) VALUES ('c3', 'beta')`, "foreign key")
+	alterMustFail(t, db, `ALTER TABLE guardians RENAME TO children`, "already exists")
+	alterMustFail(t, db, `ALTER TABLE guardians RENAME COLUMN id TO pid`, "primary key")
+}
+
+func TestAlterAddAndDropConstraints(t *testing.T) {
+	db := openTempDB(t, "alter_constraints")
+	defer db.Close()
+
+	exec(t, db, `CREATE TABLE owners (id TEXT PRIMARY KEY)`)
+	exec(t, db, `CREATE TABLE pets (id TEXT PRIMARY KEY, owner TEXT, tag TEXT, age INTEGER, CONSTRAINT age_check CHECK (age >= 0))`)
+	exec(t, db, `INSERT INTO owners (id) VALUES ('o1')`)
+	exec(t, db, `INSERT INTO pets (id, owner, tag, age) VALUES ('p1', 'o1', 'x', 3)`)
+	exec(t, db, `INSERT INTO pets (id, owner, tag, age) VALUES ('p2', 'o2', 'x', 30)`)
+
+	alterMustFail(t, db, `ALTER TABLE pets ADD CONSTRAINT pets_tag_key UNIQUE (tag)`, "violated by rows")
+	alterMustFail(t, db, `ALTER TABLE pets ADD CONSTRAINT young CHECK (age < 20)`, `check constraint "young" of relation "pets" is violated`)
+	alterMustFail(t, db, `ALTER TABLE pets ADD CONSTRAINT pets_owner_fk FOREIGN KEY (owner) REFERENCES owners(id)`, "violates foreign key constraint")
+	alterMustFail(t, db, `ALTER TABLE pets DROP CONSTRAINT missing`, `constraint "missing" of relation "pets" does not exist`)
+	exec(t, db, `ALTER TABLE pets DROP CONSTRAINT IF EXISTS missing`)
+
+	exec(t, db, `UPDATE pets SET tag = 'y', owner = 'o1', age = 10 WHERE id = 'p2'`)
+	exec(t, db, `ALTER TABLE pets ADD CONSTRAINT pets_tag_key UNIQUE (tag), ADD CONSTRAINT young CHECK (age < 20), ADD CONSTRAINT pets_owner_fk FOREIGN KEY (owner) REFERENCES owners(id) ON DELETE CASCADE`)
+
+	alterMustFail(t, db, `INSERT INTO pets (id, owner, tag, age) VALUES ('p3', 'o1', 'x', 1)`, "")
+	alterMustFail(t, db, `INSERT INTO pets (id, owner, tag, age) VALUES ('p3', 'o1', 'z', 25)`, "")
+	alterMustFail(t, db, `INSERT INTO pets (id, owner, tag, age) VALUES ('p3', 'o9', 'z', 1)`, "")
+	exec(t, db, `DELETE FROM owners WHERE id = 'o1'`)
+	if _, err := getColl(t, db, "pets").Get(context.Background(), "p1"); err == nil {
+		t.Fatal("ON DELETE CASCADE from an added foreign key did not remove the child")
+	}
+
+	exec(t, db, `ALTER TABLE pets DROP CONSTRAINT pets_tag_key, DROP CONSTRAINT young, DROP CONSTRAINT pets_owner_fk, DROP CONSTRAINT age_check`)
+	exec(t, db, `INSERT INTO pets (id, owner, tag, age) VALUES ('p4', 'o9', 'x', -1)`)
+	exec(t, db, `INSERT INTO pets (id, owner, tag, age) VALUES ('p5', 'o9', 'x', 99)`)
+	cfg := getColl(t, db, "pets").Config()
+	if len(cfg.ForeignKeys) != 0 || len(cfg.CheckConstraints) != 0 || len(cfg.NamedUniqueConstraints) != 0 {
+		t.Fatalf("constraints survived DROP CONSTRAINT: %+v %+v %+v", cfg.ForeignKeys, cfg.CheckConstraints, cfg.NamedUniqueConstraints)
+	}
+}
+
+func TestAlterAddForeignKeyHoldsReferencedRows(t *testing.T) {
+	db := openTempDB(t, "alter_fk_race")
+	defer db.Close()
+
+	for i := 0; i < 20; i++ {
+		owners, pets := fmt.Sprintf("owners%d", i), fmt.Sprintf("pets%d", i)
+		exec(t, db, `CREATE TABLE `+owners+` (id TEXT PRIMARY KEY)`)
+		exec(t, db, `CREATE TABLE `+pets+` (id TEXT PRIMARY KEY, owner TEXT)`)
+		exec(t, db, `INSERT INTO `+owners+` (id) VALUES ('o1')`)
+		exec(t, db, `INSERT INTO `+pets+` (id, owner) VALUES ('p1', 'o1')`)
+
+		deleted := make(chan error, 1)
+		go func() {
+			_, err := db.Query(context.Background(), `DELETE FROM `+owners+` WHERE id = 'o1'`)
+			deleted <- err
+		}()
+		_, alterErr := db.Query(context.Background(), `ALTER TABLE `+pets+` ADD CONSTRAINT fk FOREIGN KEY (owner) REFERENCES `+owners+`(id)`)
+		deleteErr := <-deleted
+
+		// Either statement may win, but the constraint must never be
+		// added over a parent row that is gone.
+		if alterErr == nil && deleteErr == nil {
+			t.Fatalf("iteration %d: ADD FOREIGN KEY and the parent delete both succeeded", i)
+		}
+		if alterErr == nil {
+			if _, err := getColl(t, db, owners).Get(context.Background(), "o1"); err != nil {
+				t.Fatalf("iteration %d: referenced row removed under an added foreign key: %v", i, err)
+			}
+		}
+	}
+}
+
+func TestAlterNotNullAndDefault(t *testing.T) {
+	db := openTempDB(t, "alter_notnull")
+	defer db.Close()
+
+	exec(t, db, `CREATE TABLE notes (id TEXT PRIMARY KEY, body TEXT, rank INTEGER)`)
+	exec(t, db, `INSERT INTO notes (id) VALUES ('n1')`)
+
+	alterMustFail(t, db, `ALTER TABLE notes ALTER COLUMN body SET NOT NULL`, `column "body" of relation "notes" contains null values`)
+	exec(t, db, `UPDATE notes SET body = 'hello' WHERE id = 'n1'`)
+	exec(t, db, `ALTER TABLE notes ALTER COLUMN body SET NOT NULL, ALTER COLUMN rank SET DEFAULT 7`)
+
+	alterMustFail(t, db, `INSERT INTO notes (id) VALUES ('n2')`, "")
+	exec(t, db, `INSERT INTO notes (id, body) VALUES ('n2', 'text')`)
+	if got := fmt.Sprint(alterField(t, db, "notes", "n2", "rank")); got != "7" {
+		t.Fatalf("rank default = %s, want 7", got)
+	}
+
+	exec(t, db, `ALTER TABLE notes ALTER COLUMN body DROP NOT NULL, ALTER COLUMN rank DROP DEFAULT`)
+	exec(t, db, `INSERT INTO notes (id) VALUES ('n3')`)
+	if got := alterField(t, db, "notes", "n3", "rank"); got != nil {
+		t.Fatalf("rank after DROP DEFAULT = %#v, want nil", got)
+	}
+	alterMustFail(t, db, `ALTER TABLE notes ALTER COLUMN rank SET DEFAULT 'abc'`, "invalid default")
+}
+
+func TestAlterTableSurvivesReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alter.libravdb")
+	db, err := Open(WithStoragePath(path))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	exec(t, db, `CREATE TABLE teams (id TEXT PRIMARY KEY)`)
+	exec(t, db, `CREATE TABLE players (id TEXT PRIMARY KEY, team TEXT REFERENCES teams(id), score TEXT)`)
+	exec(t, db, `INSERT INTO teams (id) VALUES ('t1')`)
+	exec(t, db, `INSERT INTO players (id, team, score) VALUES ('p1', 't1', '12')`)
+	exec(t, db, `ALTER TABLE players ALTER COLUMN score TYPE BIGINT USING score::bigint, ADD CONSTRAINT positive CHECK (score > 0)`)
+	exec(t, db, `ALTER TABLE teams RENAME TO squads`)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	db, err = Open(WithStoragePath(path))
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.GetCollection("teams"); err == nil {
+		t.Fatal("old table name reappeared after reopen")
+	}
+	if got := fmt.Sprint(alterField(t, db, "players", "p1", "score")); got != "12" {
+		t.Fatalf("score after reopen = %s", got)
+	}
+	bigint, _ := sqlTypeToFieldType("BIGINT")
+	if cfg := getColl(t, db, "players").Config(); cfg.MetadataSchema["score"] != bigint {
+		t.Fatalf("score type after reopen = %v", cfg.MetadataSchema["score"])
+	}
+	alterMustFail(t, db, `INSERT INTO players (id, team, score) VALUES ('p2', 't1', -1)`, "")
+	alterMustFail(t, db, `INSERT INTO players (id, team, score) VALUES ('p2', 'missing', 1)`, "")
+	exec(t, db, `INSERT INTO players (id, team, score) VALUES ('p2', 't1', 3)`)
+}
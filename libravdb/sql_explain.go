@@ -3,6 +3,9 @@ package libravdb
 import (
 	"context"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/xDarkicex/lexer/parser"
@@ -12,41 +15,103 @@ import (
 
 const sqlExplainColumn = "libravdb_explain"
 
-// SQLExplainColumn is the stable output column name for EXPLAIN ANALYZE.
+// SQLExplainColumn is the stable output column name for EXPLAIN and
+// EXPLAIN ANALYZE.
 const SQLExplainColumn = sqlExplainColumn
 
-// SQLExplainPlan is the stable JSON value returned by EXPLAIN ANALYZE.
-// Estimated rows are intentionally absent: the graph planner does not yet
-// have a cardinality estimator, so reporting an estimate would be misleading.
+// estimateSourceUnavailable marks plans whose operators cannot be estimated:
+// statements answered by the query-local row evaluator, and EXPLAIN ANALYZE
+// of statements the planner cannot describe without running them.
+const estimateSourceUnavailable = "unavailable"
+
+// SQLExplainPlan is the stable JSON value returned by EXPLAIN and EXPLAIN
+// ANALYZE. Plan is the operator tree; its row estimates come from the
+// analyzed statistics of each relation (see AnalyzeCollection), and
+// EstimateSource reports when a relation had no fresh snapshot so provisional
+// selectivities were used instead. Actual rows and the execution counters
+// are filled in only when Analyzed is true.
 type SQLExplainPlan struct {
-	Strategy            string `json:"strategy"`
-	Anchor              string `json:"anchor,omitempty"`
-	ActualRows          uint64 `json:"actual_rows"`
-	GraphExpansions     uint64 `json:"graph_expansions"`
-	PredicateRejections uint64 `json:"predicate_rejections"`
-	IndexHits           uint64 `json:"index_hits"`
-	ExecutionTimeNanos  uint64 `json:"execution_time_ns"`
-	PlanReused          bool   `json:"plan_reused"`
+	Strategy            string         `json:"strategy"`
+	Anchor              string         `json:"anchor,omitempty"`
+	Analyzed            bool           `json:"analyzed"`
+	EstimatedRows       uint64         `json:"estimated_rows"`
+	EstimateSource      string         `json:"estimate_source"`
+	ActualRows          uint64         `json:"actual_rows"`
+	GraphExpansions     uint64         `json:"graph_expansions"`
+	PredicateRejections uint64         `json:"predicate_rejections"`
+	IndexHits           uint64         `json:"index_hits"`
+	ExecutionTimeNanos  uint64         `json:"execution_time_ns"`
+	PlanReused          bool           `json:"plan_reused"`
+	Plan                SQLExplainNode `json:"plan"`
+}
+
+// SQLExplainNode is one operator of an EXPLAIN plan tree. Children are the
+// operator's inputs. ActualRows is absent for plain EXPLAIN and for operators
+// the executor does not count on the path it took; the root always carries
+// the statement's result rows under EXPLAIN ANALYZE.
+type SQLExplainNode struct {
+	Operator      string           `json:"operator"`
+	Relation      string           `json:"relation,omitempty"`
+	Detail        string           `json:"detail,omitempty"`
+	EstimatedRows uint64           `json:"estimated_rows"`
+	ActualRows    *uint64          `json:"actual_rows,omitempty"`
+	Children      []SQLExplainNode `json:"children,omitempty"`
+}
+
+// Executors report the rows an operator produced under these keys through
+// trackSQLOperatorRows. A statement has one base relation, so the bare keys
+// name its operators; explainOperatorKey numbers the inputs and outputs of
+// joins (scan:1 is the first join's right input) and RRF components.
+const (
+	explainKeyScan       = "scan"
+	explainKeyFilter     = "filter"
+	explainKeyJoin       = "join"
+	explainKeyGraph      = "graph"
+	explainKeyCandidates = "candidates"
+	explainKeyVector     = "vector"
+	explainKeyRRF        = "rrf"
+	explainKeyDistinct   = "distinct"
+)
+
+func explainOperatorKey(base string, n int) string {
+	return base + ":" + strconv.Itoa(n)
 }
 
 func (db *Database) executeSQLExplain(ctx context.Context, src []byte, doc *parser.QueryDoc, boundParams *optimizer.ParameterSet, legacyParams QueryParams, sessionConfig *SessionConfig, tracker *sqlQueryTracker) (*SearchResults, error) {
 	if doc == nil || !doc.Explain {
 		return nil, fmt.Errorf("invalid EXPLAIN query")
 	}
-	if !doc.ExplainAnalyze {
-		return nil, fmt.Errorf("EXPLAIN without ANALYZE is not supported; use EXPLAIN ANALYZE")
-	}
 	if doc.ExplainQueryStart >= uint32(len(src)) || doc.ExplainQueryEnd <= doc.ExplainQueryStart || doc.ExplainQueryEnd > uint32(len(src)) {
 		return nil, fmt.Errorf("invalid EXPLAIN query span")
 	}
-	strategy, anchor, graph := explainGraphShape(src, doc)
-	if !graph {
-		return nil, fmt.Errorf("EXPLAIN ANALYZE currently supports graph queries only")
+	started := time.Now()
+	innerSQL := string(src[doc.ExplainQueryStart:doc.ExplainQueryEnd])
+	tree, err := db.planSQLExplain(ctx, innerSQL, boundParams, legacyParams)
+	if err != nil {
+		if !doc.ExplainAnalyze {
+			return nil, err
+		}
+		// The statement may still be executable (the executor is more
+		// permissive than the planner description); report only its result.
+		tree = &sqlExplainTree{root: &explainNode{operator: "statement"}, strategy: "statement", source: estimateSourceUnavailable}
+	}
+	plan := SQLExplainPlan{Strategy: tree.strategy, EstimateSource: tree.source}
+	if strategy, anchor, graph := explainGraphShape(src, doc); graph {
+		plan.Strategy, plan.Anchor = strategy, anchor
+	}
+
+	if !doc.ExplainAnalyze {
+		plan.Plan = tree.root.export(nil, false)
+		plan.EstimatedRows = plan.Plan.EstimatedRows
+		return sqlExplainResults(plan, time.Since(started)), nil
 	}
 
 	before := sqlTrackerSnapshot(tracker)
-	started := time.Now()
-	innerSQL := string(src[doc.ExplainQueryStart:doc.ExplainQueryEnd])
+	if tracker != nil && tree.instrumented {
+		tracker.operatorRows = make(map[string]uint64)
+		defer func() { tracker.operatorRows = nil }()
+	}
+	started = time.Now()
 	results, err := db.queryWithBoundParamsAndConfigInternal(ctx, innerSQL, boundParams, legacyParams, sessionConfig, tracker)
 	elapsed := time.Since(started)
 	if err != nil {
@@ -60,27 +125,33 @@ func (db *Database) executeSQLExplain(ctx context.Context, src []byte, doc *pars
 			actualRows = uint64(len(results.Results))
 		}
 	}
+	var operatorRows map[string]uint64
 	if tracker != nil {
 		tracker.rowsReturned = actualRows
 		tracker.rowsReturnedOverride = true
+		operatorRows = tracker.operatorRows
 	}
-	plan := SQLExplainPlan{
-		Strategy:            strategy,
-		Anchor:              anchor,
-		ActualRows:          actualRows,
-		GraphExpansions:     after.graphExpansions - before.graphExpansions,
-		PredicateRejections: after.predicateRejections - before.predicateRejections,
-		IndexHits:           after.indexHits - before.indexHits,
-		ExecutionTimeNanos:  uint64(maxDurationNanos(elapsed)),
-		PlanReused:          after.planCacheHits > before.planCacheHits,
-	}
+	plan.Analyzed = true
+	plan.Plan = tree.root.export(operatorRows, true)
+	plan.Plan.ActualRows = &actualRows
+	plan.EstimatedRows = plan.Plan.EstimatedRows
+	plan.ActualRows = actualRows
+	plan.GraphExpansions = after.graphExpansions - before.graphExpansions
+	plan.PredicateRejections = after.predicateRejections - before.predicateRejections
+	plan.IndexHits = after.indexHits - before.indexHits
+	plan.ExecutionTimeNanos = uint64(maxDurationNanos(elapsed))
+	plan.PlanReused = after.planCacheHits > before.planCacheHits
+	return sqlExplainResults(plan, elapsed), nil
+}
+
+func sqlExplainResults(plan SQLExplainPlan, took time.Duration) *SearchResults {
 	return &SearchResults{
 		Results:     []*SearchResult{{ID: "1", Score: 1, Metadata: map[string]interface{}{sqlExplainColumn: plan}}},
-		Took:        elapsed,
+		Took:        took,
 		Total:       1,
 		Columns:     []string{sqlExplainColumn},
 		ColumnTypes: []uint16{catalog.TypeJSONB},
-	}, nil
+	}
 }
 
 type sqlTrackerSnapshotValue struct {
@@ -129,3 +200,738 @@ func explainGraphShape(src []byte, doc *parser.QueryDoc) (strategy, anchor strin
 	}
 	return "", "", false
 }
+
+// sqlExplainTree is a planned statement. instrumented is false when the
+// executor will not report per-operator rows for it: query-local routes, and
+// statements that run nested SQL through the same tracker, whose operator
+// keys would collide with the outer plan's.
+type sqlExplainTree struct {
+	root         *explainNode
+	strategy     string
+	source       string
+	instrumented bool
+}
+
+type explainNode struct {
+	operator string
+	relation string
+	detail   string
+	key      string
+	rows     float64
+	children []*explainNode
+}
+
+func (n *explainNode) export(operatorRows map[string]uint64, analyzed bool) SQLExplainNode {
+	out := SQLExplainNode{
+		Operator:      n.operator,
+		Relation:      n.relation,
+		Detail:        n.detail,
+		EstimatedRows: explainRowEstimate(n.rows),
+	}
+	for _, child := range n.children {
+		out.Children = append(out.Children, child.export(operatorRows, analyzed))
+	}
+	if !analyzed {
+		return out
+	}
+	if n.key != "" {
+		if rows, ok := operatorRows[n.key]; ok {
+			out.ActualRows = &rows
+		}
+	} else if n.operator == "sort" && len(out.Children) == 1 {
+		// Sorting preserves its input's rows.
+		out.ActualRows = out.Children[0].ActualRows
+	}
+	return out
+}
+
+// explainRowEstimate rounds to whole rows, keeping a non-zero estimate
+// visible as at least one row.
+func explainRowEstimate(rows float64) uint64 {
+	switch {
+	case math.IsNaN(rows) || rows <= 0:
+		return 0
+	case rows < 1:
+		return 1
+	case rows >= math.MaxUint64:
+		return math.MaxUint64
+	}
+	return uint64(math.Round(rows))
+}
+
+// planSQLExplain parses, binds and optimizes sql exactly as execution would,
+// without running it or touching the plan cache, and describes the physical
+// plan the executor will dispatch on.
+func (db *Database) planSQLExplain(ctx context.Context, sql string, boundParams *optimizer.ParameterSet, legacyParams QueryParams) (*sqlExplainTree, error) {
+	sql = rewriteNativePgCatalogPrefix(sql)
+	src := []byte(sql)
+	doc := &parser.QueryDoc{}
+	if err := parser.Parse(src, doc); err != nil {
+		if plan, handled, planErr := planOutsideGrammar(src); handled {
+			if planErr != nil {
+				return nil, planErr
+			}
+			return &sqlExplainTree{root: &explainNode{operator: "ddl", relation: plan.DDLTableName}, strategy: "ddl", source: estimateSourceHeuristic}, nil
+		}
+		if err := parseOutsideGrammar(src, doc, err); err != nil {
+			return nil, err
+		}
+	}
+	if doc.Explain {
+		return nil, fmt.Errorf("EXPLAIN cannot be applied to another EXPLAIN")
+	}
+	if route := db.explainQueryLocalRoute(ctx, src, doc); route != "" {
+		return &sqlExplainTree{
+			root:     &explainNode{operator: "query_local_evaluator", detail: route},
+			strategy: "query_local_evaluator",
+			source:   estimateSourceUnavailable,
+		}, nil
+	}
+
+	db.mu.RLock()
+	cat := db.catalog
+	db.mu.RUnlock()
+	if cat == nil {
+		return nil, fmt.Errorf("catalog not initialized")
+	}
+	if err := catalog.NewBinder(cat, src).Bind(doc); err != nil {
+		return nil, fmt.Errorf("bind error: %w", err)
+	}
+	opt := db.newSQLOptimizer(ctx, cat)
+	var plan *optimizer.PhysicalPlan
+	var err error
+	if boundParams != nil {
+		plan, err = opt.OptimizeWithBoundParams(doc, src, boundParams)
+	} else {
+		plan, err = opt.OptimizeWithParams(doc, src, legacyParams)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("optimize error: %w", err)
+	}
+
+	b := &sqlExplainBuilder{ctx: ctx, db: db, est: newCardinalityEstimator(ctx, db), instrumented: true}
+	switch {
+	case plan.SnapshotLSN != 0:
+		b.asOf = fmt.Sprintf("as of LSN %d", plan.SnapshotLSN)
+	case !plan.SnapshotTimestamp.IsZero():
+		b.asOf = "as of timestamp " + plan.SnapshotTimestamp.Format(time.RFC3339Nano)
+	}
+	root := b.build(plan)
+	return &sqlExplainTree{root: root, strategy: b.strategy, source: b.est.source(), instrumented: b.instrumented}, nil
+}
+
+// explainQueryLocalRoute mirrors the routes queryWithBoundParamsAndConfigInternal
+// takes before catalog binding. Those statements are evaluated row by row
+// without a physical plan, so EXPLAIN reports only the route.
+func (db *Database) explainQueryLocalRoute(ctx context.Context, src []byte, doc *parser.QueryDoc) string {
+	if _, handled, _ := db.executeLatestCommitLSNQuery(ctx, src, doc); handled {
+		return "scalar_function"
+	}
+	if _, handled, _ := db.executeSQLStatsQuery(ctx, src, doc); handled {
+		return "scalar_function"
+	}
+	if len(doc.MergeStmts) > 0 {
+		return "merge"
+	}
+	for i := range doc.DeleteStmts {
+		if doc.DeleteStmts[i].Cypher {
+			return "cypher_delete"
+		}
+	}
+	root := rootSelectIndex(doc)
+	if root >= 0 && root < len(doc.SelectStmts) && doc.SelectStmts[root].PipeWithCount > 0 {
+		return "cypher_pipe"
+	}
+	if len(doc.ComputeLeidenStmts) > 0 {
+		return "compute_leiden"
+	}
+	if len(doc.SessionSettingStmts) > 0 {
+		return "session_setting"
+	}
+	if root >= 0 && root < len(doc.SelectStmts) && doc.SelectStmts[root].CTEsCount > 0 {
+		return "cte"
+	}
+	if _, ok := db.readsView(src, doc); ok {
+		return "view"
+	}
+	if route := db.queryLocalSelectRoute(src, doc); route != "" {
+		return route
+	}
+	if len(doc.UpdateStmts) > 0 && updateHasVirtualJSONPredicate(src, doc) {
+		return "json_update"
+	}
+	if len(doc.SelectStmts) == 0 && len(doc.InsertStmts) == 0 &&
+		len(doc.InsertGraphEdgeStmts) == 0 &&
+		len(doc.UpdateStmts) == 0 && len(doc.DeleteStmts) == 0 &&
+		len(doc.CreateTableStmts) == 0 && len(doc.CreateEdgeTypeStmts) == 0 && len(doc.DropTableStmts) == 0 &&
+		len(doc.CreateIndexStmts) == 0 && len(doc.DropIndexStmts) == 0 &&
+		len(doc.AlterTableStmts) == 0 {
+		return "no_op"
+	}
+	return ""
+}
+
+// sqlExplainBuilder turns a physical plan into an operator tree. Its cases
+// follow Executor.Execute's dispatch order so the tree names the operators
+// that will actually run.
+type sqlExplainBuilder struct {
+	ctx          context.Context
+	db           *Database
+	est          *cardinalityEstimator
+	strategy     string
+	asOf         string
+	instrumented bool
+}
+
+func (b *sqlExplainBuilder) node(operator, relation, key string, rows float64, children ...*explainNode) *explainNode {
+	node := &explainNode{operator: operator, relation: relation, key: key, rows: rows, children: children}
+	if key == explainKeyScan && relation != "" {
+		node.detail = b.asOf
+	}
+	return node
+}
+
+func (b *sqlExplainBuilder) build(plan *optimizer.PhysicalPlan) *explainNode {
+	if len(plan.UnionQueries) > 0 {
+		b.strategy = "set_operation"
+		return b.setOperation(plan)
+	}
+	if plan.HasVectorOperator {
+		return b.vectorOperator(plan)
+	}
+	if catalog.IsSystemTableOID(plan.CollectionOID) || isSystemTableName(plan.CollectionName) {
+		b.strategy = "system_table_scan"
+		return &explainNode{operator: "system_table_scan", relation: plan.CollectionName}
+	}
+	if plan.Kind == optimizer.QueryKindMultiModal {
+		if plan.HasRRF {
+			b.strategy = "rrf_fusion"
+			return b.rrf(plan)
+		}
+		b.strategy = "multi_modal_search"
+		r := b.est.relation(plan.CollectionName)
+		return b.node("multi_modal_search", r.name, "", math.Min(explainTopK(plan), r.rows*r.planSelectivity(plan)))
+	}
+	if isHybridQuery(plan) {
+		b.strategy = "hybrid_vector_search"
+		return b.hybrid(plan)
+	}
+
+	switch plan.Kind {
+	case optimizer.QueryKindKNN:
+		b.strategy = "vector_ann"
+		r := b.est.relation(plan.CollectionName)
+		node := b.node("vector_ann", r.name, explainKeyVector, math.Min(explainTopK(plan), r.rows))
+		if plan.Similarity > 0 {
+			node.detail = fmt.Sprintf("similarity >= %g", plan.Similarity)
+		}
+		return node
+	case optimizer.QueryKindVectorProjection:
+		b.strategy = "exact_vector_scan"
+		r := b.est.relation(plan.CollectionName)
+		return b.finish(b.node("exact_vector_scan", r.name, explainKeyVector, r.rows), plan, r)
+	case optimizer.QueryKindGraph:
+		b.strategy = "graph_table_match"
+		return b.finish(b.graph(plan), plan, nil)
+	case optimizer.QueryKindRelational:
+		r := b.est.relation(plan.CollectionName)
+		return b.finish(b.relational(plan, r), plan, r)
+	case optimizer.QueryKindJoin:
+		if len(plan.GraphJoins) > 0 {
+			b.strategy = "graph_join_match"
+			return b.finish(b.graphJoin(plan), plan, nil)
+		}
+		if len(plan.Joins) == 0 {
+			r := b.est.relation(plan.CollectionName)
+			return b.finish(b.relational(plan, r), plan, r)
+		}
+		b.strategy = "relational_join"
+		return b.finish(b.join(plan), plan, nil)
+	case optimizer.QueryKindAggregate:
+		b.strategy = "aggregate"
+		return b.finish(b.aggregate(plan), plan, nil)
+	case optimizer.QueryKindInsert, optimizer.QueryKindInsertGraphEdge:
+		return b.insert(plan)
+	case optimizer.QueryKindUpdate:
+		r := b.est.relation(plan.CollectionName)
+		input := b.relational(plan, r)
+		b.strategy = "update"
+		return b.node("update", r.name, "", input.rows, input)
+	case optimizer.QueryKindDelete:
+		if plan.GraphEdgeDelete {
+			// Graph edges carry no statistics of their own.
+			b.strategy = "delete"
+			b.est.heuristic = true
+			return b.node("delete_graph_edges", plan.CollectionName, "", 0)
+		}
+		r := b.est.relation(plan.CollectionName)
+		input := b.relational(plan, r)
+		b.strategy = "delete"
+		return b.node("delete", r.name, "", input.rows, input)
+	case optimizer.QueryKindDDL:
+		b.strategy = "ddl"
+		return b.node("ddl", plan.DDLTableName, "", 0)
+	}
+	b.strategy = "unknown"
+	return &explainNode{operator: "unknown"}
+}
+
+// relational describes executeRelational: a primary-key probe or range scan
+// when every predicate is on id, a posting-list lookup when a full-text
+// predicate has an index, and otherwise a scan followed by a filter.
+func (b *sqlExplainBuilder) relational(plan *optimizer.PhysicalPlan, r *relationEstimate) *explainNode {
+	selectivity := r.planSelectivity(plan) * r.fullTextConjunctionSelectivity(plan.FTSPredicates)
+	filtered := r.rows * selectivity
+	if len(plan.FTSPredicates) > 0 {
+		var indexed []optimizer.FTSPredicate
+		for _, predicate := range plan.FTSPredicates {
+			if predicate.Column != "" && r.col != nil && r.col.fullTextIndexFor(predicate.Column, predicate.Config) != nil {
+				indexed = append(indexed, predicate)
+			}
+		}
+		if len(indexed) > 0 && plan.SnapshotLSN == 0 {
+			b.strategy = "full_text_scan"
+			lookup := b.node("full_text_index_scan", r.name, explainKeyScan, r.rows*r.fullTextConjunctionSelectivity(indexed))
+			if lookup.detail == "" {
+				lookup.detail = indexed[0].Column
+			}
+			return b.node("filter", "", explainKeyFilter, filtered, lookup)
+		}
+	}
+	if b.strategy == "" {
+		b.strategy = "relational_scan"
+	}
+	switch explainRelationalAccess(plan) {
+	case "primary_key_lookup":
+		b.strategy = "primary_key_lookup"
+		return b.node("primary_key_lookup", r.name, explainKeyScan, filtered)
+	case "primary_key_range_scan":
+		return b.node("primary_key_range_scan", r.name, explainKeyScan, filtered)
+	}
+	scan := b.node("seq_scan", r.name, explainKeyScan, r.rows)
+	if !planHasPredicates(plan) && len(plan.FTSPredicates) == 0 {
+		return scan
+	}
+	return b.node("filter", "", explainKeyFilter, filtered, scan)
+}
+
+// explainRelationalAccess repeats executeRelational's access-path choice.
+func explainRelationalAccess(plan *optimizer.PhysicalPlan) string {
+	if len(plan.FTSPredicates) > 0 || len(plan.PredicateAlternatives) > 0 {
+		return "seq_scan"
+	}
+	for _, predicate := range plan.Predicates {
+		if predicate.ValueIsNull || predicate.NullTest != optimizer.NullTestNone {
+			return "seq_scan"
+		}
+	}
+	if len(plan.Predicates) == 1 && plan.Predicates[0].Operator == 12 &&
+		strings.EqualFold(plan.Predicates[0].Column, "id") {
+		if plan.Predicates[0].Not {
+			return "seq_scan"
+		}
+		return "primary_key_lookup"
+	}
+	if len(plan.Predicates) == 0 {
+		return "seq_scan"
+	}
+	for _, predicate := range plan.Predicates {
+		if !strings.EqualFold(predicate.Column, "id") {
+			return "seq_scan"
+		}
+	}
+	return "primary_key_range_scan"
+}
+
+// finish adds the operators applied after the plan's rows are produced:
+// DISTINCT, ORDER BY, then OFFSET/LIMIT. base, when known, bounds DISTINCT
+// by the distinct values of the projected columns.
+func (b *sqlExplainBuilder) finish(node *explainNode, plan *optimizer.PhysicalPlan, base *relationEstimate) *explainNode {
+	if plan.Distinct {
+		rows := node.rows
+		if base != nil && len(plan.Projections) > 0 {
+			groups := 1.0
+			for _, column := range plan.Projections {
+				groups *= base.distinct(column)
+			}
+			rows = math.Min(rows, groups)
+		}
+		node = b.node("distinct", "", explainKeyDistinct, rows, node)
+	}
+	if plan.OrderBy != "" {
+		sort := b.node("sort", "", "", node.rows, node)
+		sort.detail = plan.OrderBy
+		if plan.IsDesc {
+			sort.detail += " DESC"
+		}
+		node = sort
+	}
+	if plan.Limit > 0 || plan.Offset > 0 {
+		rows := math.Max(0, node.rows-float64(max(0, plan.Offset)))
+		if plan.Limit > 0 {
+			rows = math.Min(rows, float64(plan.Limit))
+		}
+		limit := b.node("limit", "", "", rows, node)
+		limit.detail = fmt.Sprintf("limit %d offset %d", plan.Limit, max(0, plan.Offset))
+		node = limit
+	}
+	return node
+}
+
+// join describes executeJoin: nested loops over the base relation and each
+// joined relation in order, then the WHERE clause over the joined rows.
+func (b *sqlExplainBuilder) join(plan *optimizer.PhysicalPlan) *explainNode {
+	base := b.est.relation(plan.CollectionName)
+	leftAlias := plan.CollectionName
+	if plan.Joins[0].LeftAlias != "" {
+		leftAlias = plan.Joins[0].LeftAlias
+	}
+	aliases := map[string]*relationEstimate{strings.ToLower(leftAlias): base}
+	lookup := func(alias string) *relationEstimate {
+		if r, ok := aliases[strings.ToLower(alias)]; ok {
+			return r
+		}
+		return base
+	}
+
+	node := b.node("seq_scan", base.name, explainKeyScan, base.rows)
+	rows := base.rows
+	for i, join := range plan.Joins {
+		right := b.est.relation(join.CollectionName)
+		rightAlias := join.RightAlias
+		if rightAlias == "" {
+			rightAlias = join.CollectionName
+		}
+		aliases[strings.ToLower(rightAlias)] = right
+		scan := b.node("seq_scan", right.name, explainOperatorKey(explainKeyScan, i+1), right.rows)
+
+		var joined float64
+		joinType := explainJoinType(join.JoinType)
+		detail := joinType
+		if join.JoinType == uint8(parser.JoinCross) {
+			joined = rows * right.rows
+		} else {
+			left := lookup(join.LeftAlias)
+			matching := right.rows * right.conjunctionSelectivity(join.RightPredicates)
+			joined = equiJoinRows(rows, matching,
+				math.Min(left.distinct(join.LeftColumn), math.Max(rows, 1)),
+				math.Min(right.distinct(join.RightColumn), math.Max(matching, 1)))
+			detail = fmt.Sprintf("%s %s.%s = %s.%s", joinType, join.LeftAlias, join.LeftColumn, rightAlias, join.RightColumn)
+		}
+		switch join.JoinType {
+		case uint8(parser.JoinLeft):
+			joined = math.Max(joined, rows)
+		case uint8(parser.JoinRight):
+			joined = math.Max(joined, right.rows)
+		case uint8(parser.JoinFull):
+			joined = math.Max(joined, math.Max(rows, right.rows))
+		}
+		node = b.node("nested_loop_join", right.name, explainOperatorKey(explainKeyJoin, i), joined, node, scan)
+		node.detail = detail
+		rows = joined
+	}
+
+	selectivity := 1.0
+	if len(plan.PredicateAlternatives) > 0 {
+		miss := 1.0
+		for _, clause := range plan.PredicateAlternatives {
+			miss *= 1 - explainJoinedSelectivity(clause, lookup)
+		}
+		selectivity = 1 - miss
+	} else {
+		selectivity = explainJoinedSelectivity(plan.Predicates, lookup)
+	}
+	return b.node("filter", "", explainKeyFilter, rows*selectivity, node)
+}
+
+func explainJoinedSelectivity(predicates []optimizer.RelationalPredicate, lookup func(string) *relationEstimate) float64 {
+	selectivity := 1.0
+	for _, predicate := range predicates {
+		selectivity *= lookup(predicate.Alias).predicateSelectivity(predicate)
+	}
+	return selectivity
+}
+
+func explainJoinType(joinType uint8) string {
+	switch joinType {
+	case uint8(parser.JoinLeft):
+		return "left"
+	case uint8(parser.JoinRight):
+		return "right"
+	case uint8(parser.JoinFull):
+		return "full"
+	case uint8(parser.JoinCross):
+		return "cross"
+	}
+	return "inner"
+}
+
+func (b *sqlExplainBuilder) graphCollection(plan *optimizer.PhysicalPlan) string {
+	if plan.CollectionName != "" {
+		return plan.CollectionName
+	}
+	if col := newExecutor(b.db).implicitGraphCollection(plan); col != nil {
+		return col.name
+	}
+	return ""
+}
+
+// graphJoin describes JOIN MATCH: every qualifying row of the source relation
+// seeds a traversal, and each reached vertex that passes the terminal
+// predicates emits a row.
+func (b *sqlExplainBuilder) graphJoin(plan *optimizer.PhysicalPlan) *explainNode {
+	name := b.graphCollection(plan)
+	r := b.est.relation(name)
+	scan := b.node("seq_scan", r.name, explainKeyScan, r.rows)
+	seeds := func(join optimizer.GraphJoinPlan) float64 {
+		selectivity := r.conjunctionSelectivity(graphJoinSourcePredicates(plan.Predicates, join, name))
+		return r.rows * selectivity * r.labelFraction(join.SeedLabels)
+	}
+	reach := func(join optimizer.GraphJoinPlan, sources float64) float64 {
+		reached := sources * r.graphFanout(join.GraphEdges) *
+			r.conjunctionSelectivity(graphJoinTerminalPredicates(plan.Predicates, join)) *
+			r.labelFraction(join.TerminalLabels)
+		if join.PredicateMatch {
+			reached = math.Min(reached, sources)
+		}
+		if join.JoinType == 1 { // parser.JoinLeft
+			reached = math.Max(reached, sources)
+		}
+		return reached
+	}
+
+	var rows float64
+	detail := ""
+	switch {
+	case graphJoinsFormCommonNeighbor(plan.GraphJoins):
+		// Both patterns must reach the same vertex; treat the two reach sets
+		// as independent samples of the relation.
+		detail = "common_neighbor"
+		first, second := plan.GraphJoins[0], plan.GraphJoins[1]
+		rows = reach(first, seeds(first)) * reach(second, seeds(second)) / math.Max(1, r.rows)
+	case len(plan.GraphJoins) > 1 && graphJoinsFormChain(plan.GraphJoins):
+		detail = "chain"
+		rows = seeds(plan.GraphJoins[0])
+		for _, join := range plan.GraphJoins {
+			rows = reach(join, rows)
+		}
+	default:
+		for _, join := range plan.GraphJoins {
+			rows += reach(join, seeds(join))
+		}
+	}
+	node := b.node("graph_join_match", r.name, explainKeyGraph, rows, scan)
+	node.detail = detail
+	return node
+}
+
+// graph describes a standalone graph query: seeds from the highest-priority
+// source executeGraph would use, then one traversal over the shared visited
+// set, so the result is bounded by the relation.
+func (b *sqlExplainBuilder) graph(plan *optimizer.PhysicalPlan) *explainNode {
+	r := b.est.relation(b.graphCollection(plan))
+	seeds, source := b.graphSeeds(plan, r)
+	seed := b.node("graph_seed", r.name, explainKeyScan, seeds)
+	if seed.detail == "" {
+		seed.detail = source
+	}
+	fanout, sampled := r.patternFanout(plan)
+	if !sampled {
+		fanout = r.graphFanout(plan.GraphEdges)
+	}
+	rows := seeds * fanout
+	if r.rows > 0 {
+		rows = math.Min(rows, r.rows)
+	}
+	return b.node("graph_table_match", r.name, explainKeyGraph, rows, seed)
+}
+
+func (b *sqlExplainBuilder) graphSeeds(plan *optimizer.PhysicalPlan, r *relationEstimate) (float64, string) {
+	switch {
+	case plan.HasExplicitSeed:
+		return 1, "explicit_seed"
+	case plan.HasVectorAnchor:
+		return math.Min(explainTopK(plan), r.rows), "vector_anchor"
+	case len(plan.SeedLabels) > 0:
+		return r.rows * r.labelFraction(plan.SeedLabels), "label_scan"
+	case plan.SeedLabel != "":
+		return r.rows * r.labelFraction([]string{plan.SeedLabel}), "label_scan"
+	}
+	terminalAlias := ""
+	if len(plan.GraphJoins) > 0 {
+		terminalAlias = plan.GraphJoins[0].TerminalAlias
+	}
+	var sourcePredicates []optimizer.RelationalPredicate
+	for _, predicate := range plan.Predicates {
+		if predicate.Alias == "" || predicate.Alias != terminalAlias {
+			sourcePredicates = append(sourcePredicates, predicate)
+		}
+	}
+	return r.rows * r.conjunctionSelectivity(sourcePredicates), "source_rows"
+}
+
+// hybrid describes executeHybrid: the candidate set allowed by the scalar
+// and graph constraints, then the top-k vector search over it using the
+// dispatch plan dispatchHybrid selects.
+func (b *sqlExplainBuilder) hybrid(plan *optimizer.PhysicalPlan) *explainNode {
+	r := b.est.relation(plan.CollectionName)
+	candidates := r.rows * r.planSelectivity(plan)
+	if plan.HasGraphTraversal {
+		seeds, _ := b.graphSeeds(plan, r)
+		candidates = math.Min(candidates, seeds*r.graphFanout(plan.GraphEdges))
+	}
+	chosen, reason, _ := newExecutor(b.db).dispatchHybrid(b.ctx, plan)
+	if plan.RecallContract == optimizer.RecallExact {
+		chosen, reason = DispatchExactCandidateScan, ReasonExactRecallContract
+	}
+	candidateNode := b.node("hybrid_candidates", r.name, explainKeyCandidates, candidates)
+	node := b.node("vector_topk", r.name, explainKeyVector, math.Min(explainTopK(plan), candidates), candidateNode)
+	node.detail = fmt.Sprintf("%s (%s)", chosen, reason)
+	return node
+}
+
+// vectorOperator describes executeVectorOperatorSQL, which uses the index
+// only for an unfiltered ORDER BY over the index's own metric.
+func (b *sqlExplainBuilder) vectorOperator(plan *optimizer.PhysicalPlan) *explainNode {
+	r := b.est.relation(plan.CollectionName)
+	metric, _ := vectorOperatorMetric(plan.VectorOperator)
+	if plan.HasVectorOperatorOrder && !planHasPredicates(plan) && plan.Limit > 0 &&
+		r.col != nil && r.col.GetIndex() != nil && r.col.Config().Metric == metric {
+		b.strategy = "vector_ann"
+		node := b.node("vector_ann", r.name, explainKeyVector, math.Min(float64(plan.Limit+max(0, plan.Offset)), r.rows))
+		return b.finish(node, plan, r)
+	}
+	b.strategy = "exact_vector_scan"
+	scan := b.node("seq_scan", r.name, explainKeyScan, r.rows)
+	node := b.node("exact_vector_scan", r.name, explainKeyFilter, r.rows*r.planSelectivity(plan), scan)
+	return b.finish(node, plan, r)
+}
+
+// rrf describes executeRRF: one candidate set ranked independently by each
+// component, then fused by reciprocal rank.
+func (b *sqlExplainBuilder) rrf(plan *optimizer.PhysicalPlan) *explainNode {
+	r := b.est.relation(plan.CollectionName)
+	candidates := r.rows * r.conjunctionSelectivity(plan.Predicates)
+	candidateNode := b.node("rrf_candidates", r.name, explainKeyCandidates, candidates)
+	children := []*explainNode{candidateNode}
+	for i, component := range plan.RRFComponents {
+		rows := candidates
+		detail := "vector_distance"
+		switch component.Kind {
+		case optimizer.RRFComponentFTSRank:
+			detail = "fts_rank"
+			rows *= r.fullTextSelectivity(optimizer.FTSPredicate{Column: component.TextColumn, Query: component.TextQuery})
+		case optimizer.RRFComponentGraphCentrality:
+			detail = "graph_centrality"
+		case optimizer.RRFComponentSparseInnerProduct:
+			detail = "sparse_inner_product"
+			rows *= r.nonNullFraction(component.TextColumn)
+		case optimizer.RRFComponentFunction:
+			detail = strings.ToLower(component.Function)
+		}
+		rank := b.node("rrf_rank", "", explainOperatorKey(explainKeyRRF, i), rows)
+		rank.detail = detail
+		children = append(children, rank)
+	}
+	node := b.node("rrf_fusion", r.name, explainKeyRRF, candidates, children...)
+	return b.finish(node, &optimizer.PhysicalPlan{Limit: plan.Limit, Offset: plan.Offset}, nil)
+}
+
+func (b *sqlExplainBuilder) aggregate(plan *optimizer.PhysicalPlan) *explainNode {
+	var input *explainNode
+	r := b.est.relation(b.graphCollection(plan))
+	if len(plan.GraphJoins) > 0 {
+		input = b.graphJoin(plan)
+	} else {
+		input = b.relational(plan, r)
+	}
+	b.strategy = "aggregate"
+	// An ungrouped aggregate always returns one row.
+	groups := 1.0
+	if len(plan.GroupByColumns) > 0 {
+		for _, column := range plan.GroupByColumns {
+			groups *= r.distinct(column)
+		}
+		groups = math.Min(groups, input.rows)
+		if plan.HavingAggregate || plan.HavingExpr != "" {
+			groups *= estimatorOtherSelectivity
+		}
+	}
+	node := b.node("aggregate", "", "", groups, input)
+	node.detail = aggregateColumnName(plan.AggregateFunc)
+	if len(plan.GroupByColumns) > 0 {
+		node.detail += " group by " + strings.Join(plan.GroupByColumns, ", ")
+	}
+	return node
+}
+
+func (b *sqlExplainBuilder) insert(plan *optimizer.PhysicalPlan) *explainNode {
+	operator := "insert"
+	if plan.Kind == optimizer.QueryKindInsertGraphEdge {
+		operator = "insert_graph_edge"
+	}
+	b.strategy = operator
+	if plan.InsertSelectSQL != "" {
+		// INSERT ... SELECT runs its source as a nested statement.
+		b.instrumented = false
+		source, err := b.db.planSQLExplain(b.ctx, plan.InsertSelectSQL, nil, nil)
+		if err != nil {
+			b.est.heuristic = true
+			return b.node(operator, plan.CollectionName, "", 0)
+		}
+		if source.source != estimateSourceStatistics {
+			b.est.heuristic = true
+		}
+		return b.node(operator, plan.CollectionName, "", source.root.rows, source.root)
+	}
+	rows := 1.0
+	if len(plan.InsertColumns) > 0 && len(plan.InsertValues) > 0 {
+		rows = float64(len(plan.InsertValues) / len(plan.InsertColumns))
+	}
+	// The row count comes from the statement itself.
+	b.est.relation(plan.CollectionName)
+	return b.node(operator, plan.CollectionName, "", rows)
+}
+
+// setOperation plans both branches independently; each runs as its own
+// statement, so their operators are estimated but not counted.
+func (b *sqlExplainBuilder) setOperation(plan *optimizer.PhysicalPlan) *explainNode {
+	b.instrumented = false
+	var branches []*explainNode
+	for _, query := range plan.UnionQueries {
+		branch, err := b.db.planSQLExplain(b.ctx, query, nil, nil)
+		if err != nil {
+			b.est.heuristic = true
+			branch = &sqlExplainTree{root: &explainNode{operator: "statement"}}
+		} else if branch.source != estimateSourceStatistics {
+			b.est.heuristic = true
+		}
+		branches = append(branches, branch.root)
+	}
+	node := b.node("set_operation", "", "", 0, branches...)
+	if len(branches) != 2 {
+		return node
+	}
+	left, right := branches[0].rows, branches[1].rows
+	switch plan.SetOp {
+	case uint8(parser.SetOpUnion):
+		node.detail, node.rows = "union", left+right
+	case uint8(parser.SetOpIntersect):
+		node.detail, node.rows = "intersect", math.Min(left, right)
+	case uint8(parser.SetOpExcept):
+		node.detail, node.rows = "except", left
+	}
+	if plan.SetOpAll {
+		node.detail += " all"
+	}
+	return b.finish(node, &optimizer.PhysicalPlan{Limit: plan.Limit, Offset: plan.Offset}, nil)
+}
+
+// explainTopK matches the k dispatchHybrid and the vector executors use when
+// the statement has no LIMIT.
+func explainTopK(plan *optimizer.PhysicalPlan) float64 {
+	if plan.Limit <= 0 {
+		return 10
+	}
+	return float64(plan.Limit)
+}
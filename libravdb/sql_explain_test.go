@@ -2,6 +2,7 @@ package libravdb
 
 import (
 	"context"
+	"fmt"
 	"testing"
 )
 
@@ -98,3 +99,208 @@ func TestSQLExplainAnalyzeGraphJoin(t *testing.T) {
 		t.Fatalf("query-local stats leaked incorrectly: stats=%#v plan=%#v", stats, plan)
 	}
 }
+
+func explainPlanFromResults(t *testing.T, rows *SearchResults) SQLExplainPlan {
+	t.Helper()
+	if rows == nil || len(rows.Results) != 1 {
+		t.Fatalf("explain rows=%#v", rows)
+	}
+	plan, ok := rows.Results[0].Metadata[SQLExplainColumn].(SQLExplainPlan)
+	if !ok {
+		t.Fatalf("explain value type=%T", rows.Results[0].Metadata[SQLExplainColumn])
+	}
+	return plan
+}
+
+// findExplainNode returns the first operator named operator in depth-first
+// order.
+func findExplainNode(node SQLExplainNode, operator string) (SQLExplainNode, bool) {
+	if node.Operator == operator {
+		return node, true
+	}
+	for _, child := range node.Children {
+		if found, ok := findExplainNode(child, operator); ok {
+			return found, true
+		}
+	}
+	return SQLExplainNode{}, false
+}
+
+func TestSQLExplainEstimatesFromAnalyzedStatistics(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(":memory:explain-estimates"), WithMetrics(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	orders, err := db.CreateCollection(ctx, "orders", WithMetadataOnly(), WithMetadataSchema(MetadataSchema{
+		"status": StringField,
+		"amount": IntField,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		status := "closed"
+		if i%10 == 0 {
+			status = "open"
+		}
+		if err := orders.Insert(ctx, fmt.Sprintf("o%03d", i), nil, map[string]interface{}{"status": status, "amount": int64(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	explain := func(sql string) SQLExplainPlan {
+		t.Helper()
+		rows, err := db.Query(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		return explainPlanFromResults(t, rows)
+	}
+
+	provisional := explain("EXPLAIN SELECT id FROM orders WHERE status = 'open'")
+	if provisional.Analyzed || provisional.EstimateSource != estimateSourceHeuristic {
+		t.Fatalf("unanalyzed explain=%#v", provisional)
+	}
+
+	if _, err := db.AnalyzeCollection(ctx, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	equality := explain("EXPLAIN SELECT id FROM orders WHERE status = 'open'")
+	if equality.Analyzed || equality.EstimateSource != estimateSourceStatistics || equality.Strategy != "relational_scan" {
+		t.Fatalf("equality explain=%#v", equality)
+	}
+	if equality.EstimatedRows != 10 || equality.Plan.Operator != "filter" || equality.Plan.ActualRows != nil {
+		t.Fatalf("equality plan=%#v, want a 10-row filter without actuals", equality.Plan)
+	}
+	if scan, ok := findExplainNode(equality.Plan, "seq_scan"); !ok || scan.EstimatedRows != 100 || scan.Relation != "orders" {
+		t.Fatalf("equality scan=%#v", scan)
+	}
+
+	rangePlan := explain("EXPLAIN SELECT id FROM orders WHERE amount < 50 ORDER BY amount LIMIT 80")
+	if rangePlan.Plan.Operator != "limit" || rangePlan.EstimatedRows < 40 || rangePlan.EstimatedRows > 60 {
+		t.Fatalf("range plan=%#v, want about 50 rows under a limit", rangePlan.Plan)
+	}
+	if sort, ok := findExplainNode(rangePlan.Plan, "sort"); !ok || sort.EstimatedRows != rangePlan.EstimatedRows {
+		t.Fatalf("range sort=%#v", sort)
+	}
+
+	// Plain EXPLAIN never executes the statement.
+	db.ResetSQLStats()
+	insert := explain("EXPLAIN INSERT INTO orders (id, status, amount) VALUES ('o999', 'open', 1)")
+	if insert.Plan.Operator != "insert" || insert.EstimatedRows != 1 {
+		t.Fatalf("insert plan=%#v", insert.Plan)
+	}
+	if count, err := orders.Count(ctx); err != nil || count != 100 {
+		t.Fatalf("count after EXPLAIN INSERT=%d err=%v, want 100", count, err)
+	}
+	if stats := db.SQLStats(); stats.RowsExamined != 0 {
+		t.Fatalf("plain EXPLAIN examined rows: %#v", stats)
+	}
+}
+
+func TestSQLExplainAnalyzeReportsOperatorRows(t *testing.T) {
+	ctx := context.Background()
+	db, err := Open(WithStoragePath(":memory:explain-analyze-operators"), WithMetrics(false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	explainAnalyze := func(sql string) SQLExplainPlan {
+		t.Helper()
+		rows, err := db.Query(ctx, sql)
+		if err != nil {
+			t.Fatalf("%s: %v", sql, err)
+		}
+		plan := explainPlanFromResults(t, rows)
+		if !plan.Analyzed || plan.Plan.ActualRows == nil || *plan.Plan.ActualRows != plan.ActualRows {
+			t.Fatalf("%s: analyzed plan=%#v", sql, plan)
+		}
+		return plan
+	}
+	actual := func(node SQLExplainNode) int64 {
+		if node.ActualRows == nil {
+			return -1
+		}
+		return int64(*node.ActualRows)
+	}
+
+	customers, err := db.CreateCollection(ctx, "customers", WithMetadataOnly(), WithMetadataSchema(MetadataSchema{"region": StringField}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	purchases, err := db.CreateCollection(ctx, "purchases", WithMetadataOnly(), WithMetadataSchema(MetadataSchema{"customer_id": StringField}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range [][2]string{{"c1", "eu"}, {"c2", "eu"}, {"c3", "us"}} {
+		if err := customers.Insert(ctx, row[0], nil, map[string]interface{}{"region": row[1]}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i, customer := range []string{"c1", "c1", "c2", "c3", "c3", "c3"} {
+		if err := purchases.Insert(ctx, fmt.Sprintf("p%d", i), nil, map[string]interface{}{"customer_id": customer}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	join := explainAnalyze("EXPLAIN ANALYZE SELECT p.id FROM purchases p JOIN customers c ON p.customer_id = c.id WHERE c.region = 'eu'")
+	if join.Strategy != "relational_join" || join.ActualRows != 3 {
+		t.Fatalf("join explain=%#v", join)
+	}
+	joined, ok := findExplainNode(join.Plan, "nested_loop_join")
+	if !ok || actual(joined) != 6 || len(joined.Children) != 2 {
+		t.Fatalf("join operator=%#v", joined)
+	}
+	if actual(joined.Children[0]) != 6 || actual(joined.Children[1]) != 3 || joined.Children[1].Relation != "customers" {
+		t.Fatalf("join inputs=%#v", joined.Children)
+	}
+
+	if _, err := db.Query(ctx, `CREATE TABLE docs (id TEXT PRIMARY KEY, content TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		content := "graph storage"
+		if i%5 == 0 {
+			content = "zebra crossing"
+		}
+		if _, err := db.QueryWithParams(ctx, `INSERT INTO docs (id, content) VALUES ($1, $2)`, QueryParams{"1": fmt.Sprintf("d%02d", i), "2": content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Query(ctx, `CREATE INDEX docs_content_fts ON docs USING fts (content)`); err != nil {
+		t.Fatal(err)
+	}
+	const ftsQuery = "SELECT id FROM docs WHERE to_tsvector(content) @@ to_tsquery('zebra')"
+	fts := explainAnalyze("EXPLAIN ANALYZE " + ftsQuery)
+	if fts.Strategy != "full_text_scan" || fts.ActualRows != 4 {
+		t.Fatalf("fts explain=%#v", fts)
+	}
+	if lookup, ok := findExplainNode(fts.Plan, "full_text_index_scan"); !ok || actual(lookup) != 4 {
+		t.Fatalf("fts lookup=%#v", lookup)
+	}
+	// The postings built by the analyzed run now drive the estimate.
+	rows, err := db.Query(ctx, "EXPLAIN "+ftsQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if estimate := explainPlanFromResults(t, rows); estimate.EstimatedRows != 4 {
+		t.Fatalf("fts estimate=%#v", estimate.Plan)
+	}
+
+	vectors, err := db.CreateCollection(ctx, "explain_vectors", WithDimension(3), WithMetric(L2Distance))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 12; i++ {
+		if err := vectors.Insert(ctx, fmt.Sprintf("v%02d", i), []float32{float32(i), 1, 0}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ann := explainAnalyze("EXPLAIN ANALYZE SELECT id FROM explain_vectors ORDER BY embedding <-> '[0,1,0]' LIMIT 3")
+	if ann.Strategy != "vector_ann" || ann.ActualRows != 3 || ann.EstimatedRows != 3 {
+		t.Fatalf("vector explain=%#v", ann)
+	}
+	if search, ok := findExplainNode(ann.Plan, "vector_ann"); !ok || actual(search) != 3 {
+		t.Fatalf("vector operator=%#v", search)
+	}
+}
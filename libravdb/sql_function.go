@@ -0,0 +1,591 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xDarkicex/lexer/parser"
+	"github.com/xDarkicex/libravdb/internal/catalog"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
+)
+
+// Go code can register scalar and aggregate functions for use in SQL. The
+// physical planner knows nothing about them: a SELECT that calls one runs on
+// the query-local row evaluator, which resolves names against the database's
+// registry. RRF is the exception; the optimizer lowers a function call in
+// RRF(...) into a ranking signal and the RRF executor calls it per candidate.
+// Registrations are held in memory and must be repeated after each Open.
+
+// ScalarFunction is the Go implementation of a SQL scalar function. args
+// holds one value per declared argument type, already converted to that
+// type's Go representation; SQL NULL is passed as nil. The result is
+// converted to the declared return type, and nil is SQL NULL.
+type ScalarFunction func(ctx context.Context, args []interface{}) (interface{}, error)
+
+// Aggregator accumulates one group of an aggregate function. Step receives
+// the converted arguments for each input row and Result is called once after
+// the last row, including for empty groups.
+type Aggregator interface {
+	Step(args []interface{}) error
+	Result() (interface{}, error)
+}
+
+// AggregateFunction returns a fresh Aggregator for each group.
+type AggregateFunction func() Aggregator
+
+// SQLFunction describes a function registered with RegisterScalarFunction or
+// RegisterAggregateFunction.
+type SQLFunction struct {
+	Name       string
+	ArgTypes   []FieldType
+	ReturnType FieldType
+	Aggregate  bool
+}
+
+type sqlFunction struct {
+	SQLFunction
+	scalar    ScalarFunction
+	aggregate AggregateFunction
+}
+
+// builtinSQLFunctions are names the parser or executor already gives a
+// meaning. A registered function with one of these names would never be
+// called, so registration rejects them.
+var builtinSQLFunctions = map[string]struct{}{
+	"now": {}, "nullif": {}, "coalesce": {}, "rrf": {}, "fts_rank": {}, "ts_rank": {}, "ts_rank_cd": {},
+	"sparse_inner_product": {}, "vector_distance": {}, "similarity": {}, "max_sim": {}, "graph_centrality": {},
+	"count": {}, "sum": {}, "avg": {}, "min": {}, "max": {}, "vector_avg": {}, "array_agg": {}, "string_agg": {},
+	"percentile_cont": {}, "percentile_disc": {}, "mode": {},
+	"row_number": {}, "rank": {}, "dense_rank": {}, "percent_rank": {}, "cume_dist": {}, "ntile": {}, "lag": {}, "lead": {},
+	"to_tsvector": {}, "to_tsquery": {}, "plainto_tsquery": {}, "phraseto_tsquery": {}, "websearch_to_tsquery": {},
+	"jsonb_set": {}, "json_set": {}, "jsonb_insert": {}, "json_insert": {}, "jsonb_build_array": {}, "json_build_array": {},
+	"jsonb_build_object": {}, "json_build_object": {}, "jsonb_populate_record": {}, "json_populate_record": {},
+	"to_jsonb": {}, "to_json": {}, "jsonb_array_length": {}, "jsonb_typeof": {}, "json_typeof": {},
+	"jsonb_array_elements": {}, "json_array_elements": {}, "jsonb_array_elements_text": {}, "json_array_elements_text": {},
+	"graph_semijoin": {}, "libravdb_latest_commit_lsn": {}, "libravdb_sql_stats": {}, "embed": {}, "mmr": {},
+}
+
+// RegisterScalarFunction makes fn callable from SQL as name(arg, ...).
+// Function names are case-insensitive. Registering an existing name replaces
+// the earlier function.
+func (db *Database) RegisterScalarFunction(name string, argTypes []FieldType, returnType FieldType, fn ScalarFunction) error {
+	if fn == nil {
+		return fmt.Errorf("function %q has no implementation", name)
+	}
+	return db.registerSQLFunction(&sqlFunction{
+		SQLFunction: SQLFunction{Name: name, ArgTypes: argTypes, ReturnType: returnType},
+		scalar:      fn,
+	})
+}
+
+// RegisterAggregateFunction makes fn callable from SQL as an aggregate. An
+// aggregate call must be a whole projection of a SELECT, optionally with
+// GROUP BY, in the same way as ARRAY_AGG and STRING_AGG.
+func (db *Database) RegisterAggregateFunction(name string, argTypes []FieldType, returnType FieldType, fn AggregateFunction) error {
+	if fn == nil {
+		return fmt.Errorf("function %q has no implementation", name)
+	}
+	return db.registerSQLFunction(&sqlFunction{
+		SQLFunction: SQLFunction{Name: name, ArgTypes: argTypes, ReturnType: returnType, Aggregate: true},
+		aggregate:   fn,
+	})
+}
+
+func (db *Database) registerSQLFunction(fn *sqlFunction) error {
+	if !validSQLFunctionName(fn.Name) {
+		return fmt.Errorf("invalid function name %q", fn.Name)
+	}
+	key := strings.ToLower(fn.Name)
+	if _, builtin := builtinSQLFunctions[key]; builtin {
+		return fmt.Errorf("function %s is built in and cannot be registered", key)
+	}
+	for i, typ := range fn.ArgTypes {
+		if typ < StringField || typ > JSONBField {
+			return fmt.Errorf("function %s argument %d has unsupported type %s", key, i+1, typ)
+		}
+	}
+	switch fn.ReturnType {
+	case StringArrayField, IntArrayField:
+		return fmt.Errorf("function %s cannot return %s", key, fn.ReturnType)
+	}
+	if fn.ReturnType < StringField || fn.ReturnType > JSONBField {
+		return fmt.Errorf("function %s has unsupported return type %s", key, fn.ReturnType)
+	}
+	fn.Name = key
+	fn.ArgTypes = append([]FieldType(nil), fn.ArgTypes...)
+
+	db.functionsMu.Lock()
+	defer db.functionsMu.Unlock()
+	if db.functions == nil {
+		db.functions = make(map[string]*sqlFunction)
+	}
+	db.functions[key] = fn
+	return nil
+}
+
+func validSQLFunctionName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (i > 0 && c >= '0' && c <= '9') {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// ListSQLFunctions returns the registered functions sorted by name.
+func (db *Database) ListSQLFunctions() []SQLFunction {
+	db.functionsMu.RLock()
+	defer db.functionsMu.RUnlock()
+	out := make([]SQLFunction, 0, len(db.functions))
+	for _, fn := range db.functions {
+		info := fn.SQLFunction
+		info.ArgTypes = append([]FieldType(nil), info.ArgTypes...)
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// LookupSQLFunction reports the registered function called name.
+func (db *Database) LookupSQLFunction(name string) (SQLFunction, bool) {
+	fn, ok := db.lookupSQLFunction(name)
+	if !ok {
+		return SQLFunction{}, false
+	}
+	info := fn.SQLFunction
+	info.ArgTypes = append([]FieldType(nil), info.ArgTypes...)
+	return info, true
+}
+
+func (db *Database) lookupSQLFunction(name string) (*sqlFunction, bool) {
+	if db == nil {
+		return nil, false
+	}
+	db.functionsMu.RLock()
+	defer db.functionsMu.RUnlock()
+	if len(db.functions) == 0 {
+		return nil, false
+	}
+	fn, ok := db.functions[strings.ToLower(name)]
+	return fn, ok
+}
+
+// callScalar checks and converts args, calls fn and converts its
+// result to the declared return type.
+func (fn *sqlFunction) callScalar(ctx context.Context, args []interface{}) (interface{}, error) {
+	converted, err := fn.convertArgs(args)
+	if err != nil {
+		return nil, err
+	}
+	result, err := fn.scalar(ctx, converted)
+	if err != nil {
+		return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+	}
+	return fn.convertResult(result)
+}
+
+func (fn *sqlFunction) convertArgs(args []interface{}) ([]interface{}, error) {
+	if len(args) != len(fn.ArgTypes) {
+		return nil, fmt.Errorf("function %s expects %d argument(s), got %d", fn.Name, len(fn.ArgTypes), len(args))
+	}
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		value, err := sqlFunctionValue(arg, fn.ArgTypes[i])
+		if err != nil {
+			return nil, fmt.Errorf("function %s argument %d: %w", fn.Name, i+1, err)
+		}
+		converted[i] = value
+	}
+	return converted, nil
+}
+
+func (fn *sqlFunction) convertResult(result interface{}) (interface{}, error) {
+	value, err := sqlFunctionValue(result, fn.ReturnType)
+	if err != nil {
+		return nil, fmt.Errorf("function %s result: %w", fn.Name, err)
+	}
+	return value, nil
+}
+
+// sqlFunctionValue converts an evaluator value to the Go representation of
+// typ: string, int64, float64, bool, time.Time, []string, []int64,
+// []float32, or a decoded JSON value.
+func sqlFunctionValue(value interface{}, typ FieldType) (interface{}, error) {
+	value = materializeSQLJSONValue(value)
+	if value == nil {
+		return nil, nil
+	}
+	switch typ {
+	case StringField:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case float64:
+			return strconv.FormatFloat(v, 'g', -1, 64), nil
+		case float32:
+			return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+		case time.Time:
+			return v.Format(time.RFC3339Nano), nil
+		}
+		return recordMetaToString(value), nil
+	case IntField, BigIntField:
+		if n, ok := toInt64(value); ok {
+			return n, nil
+		}
+		if f, ok := toFloat(value); ok && f == math.Trunc(f) && math.Abs(f) <= math.MaxInt64 {
+			return int64(f), nil
+		}
+	case FloatField:
+		if f, ok := toFloat(value); ok {
+			return f, nil
+		}
+		switch v := value.(type) {
+		case int32:
+			return float64(v), nil
+		case uint64:
+			return float64(v), nil
+		}
+	case BoolField:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if parsed, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return parsed, nil
+			}
+		}
+	case TimeField:
+		switch v := value.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999", "2006-01-02"} {
+				if parsed, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+					return parsed, nil
+				}
+			}
+		}
+	case FloatArrayField:
+		if vector := vectorValue(value); vector != nil {
+			return vector, nil
+		}
+		switch v := value.(type) {
+		case string:
+			if vector := parseVectorLiteral(strings.TrimSpace(v)); len(vector) > 0 {
+				return vector, nil
+			}
+		case []interface{}:
+			vector := make([]float32, len(v))
+			for i, item := range v {
+				f, ok := toFloat(item)
+				if !ok {
+					return nil, fmt.Errorf("cannot convert %v to %s", value, typ)
+				}
+				vector[i] = float32(f)
+			}
+			return vector, nil
+		}
+	case StringArrayField:
+		switch v := value.(type) {
+		case []string:
+			return v, nil
+		case []interface{}:
+			out := make([]string, len(v))
+			for i, item := range v {
+				out[i] = recordMetaToString(item)
+			}
+			return out, nil
+		}
+	case IntArrayField:
+		switch v := value.(type) {
+		case []int64:
+			return v, nil
+		case []interface{}:
+			out := make([]int64, len(v))
+			for i, item := range v {
+				n, ok := toInt64(item)
+				if !ok {
+					return nil, fmt.Errorf("cannot convert %v to %s", value, typ)
+				}
+				out[i] = n
+			}
+			return out, nil
+		}
+	case JSONField, JSONBField:
+		if text, ok := value.(string); ok {
+			if decoded, valid := decodeJSONValue(text); valid {
+				return decoded, nil
+			}
+		}
+		return value, nil
+	}
+	return nil, fmt.Errorf("cannot convert %v to %s", value, typ)
+}
+
+// sqlFunctionCatalogType is the result column type of a registered function.
+func sqlFunctionCatalogType(fn *sqlFunction) uint16 {
+	if fn.ReturnType == FloatArrayField {
+		return catalog.TypeVector
+	}
+	return metadataFieldTypeToCatalogType(fn.ReturnType)
+}
+
+// sqlFunctionTypeOID is the PostgreSQL type OID reported by pg_proc.
+func sqlFunctionTypeOID(typ FieldType) int64 {
+	switch typ {
+	case StringArrayField:
+		return 1009 // _text
+	case IntArrayField:
+		return 1007 // _int4
+	case FloatArrayField:
+		return int64(catalog.ColumnTypeToPGOID(catalog.TypeVector))
+	}
+	return int64(catalog.ColumnTypeToPGOID(metadataFieldTypeToCatalogType(typ)))
+}
+
+// functionCall resolves a FunctionExpr naming a registered function.
+func (db *Database) functionCall(src []byte, doc *parser.QueryDoc, ref parser.NodeRef) (*sqlFunction, bool) {
+	if doc == nil || ref.Kind != parser.NodeKindFunctionExpr || ref.ID < 0 || int(ref.ID) >= len(doc.FunctionExprs) {
+		return nil, false
+	}
+	fn := &doc.FunctionExprs[ref.ID]
+	if fn.HasWindow || fn.NameEnd > uint32(len(src)) || fn.NameStart > fn.NameEnd {
+		return nil, false
+	}
+	return db.lookupSQLFunction(sourceSpan(src, fn.NameStart, fn.NameEnd))
+}
+
+// aggregateFunctionCall resolves a FunctionExpr naming a registered aggregate.
+func (db *Database) aggregateFunctionCall(src []byte, doc *parser.QueryDoc, ref parser.NodeRef) (*sqlFunction, bool) {
+	fn, ok := db.functionCall(src, doc, ref)
+	if !ok || !fn.Aggregate {
+		return nil, false
+	}
+	return fn, true
+}
+
+// virtualFunctionIsAggregate reports whether a FunctionExpr is evaluated once
+// per group: ARRAY_AGG, STRING_AGG or a registered aggregate.
+func (db *Database) virtualFunctionIsAggregate(src []byte, doc *parser.QueryDoc, ref parser.NodeRef) bool {
+	if virtualFunctionNameIsCollectionAggregate(src, doc, ref) {
+		return true
+	}
+	_, ok := db.aggregateFunctionCall(src, doc, ref)
+	return ok
+}
+
+func (db *Database) virtualSelectHasUserAggregate(src []byte, doc *parser.QueryDoc, stmt *parser.SelectStmt) bool {
+	if doc == nil || stmt == nil {
+		return false
+	}
+	for i := int32(0); i < stmt.ProjectionsCount; i++ {
+		if stmt.ProjectionsStart+i >= 0 && int(stmt.ProjectionsStart+i) < len(doc.Projections) {
+			if _, ok := db.aggregateFunctionCall(src, doc, doc.Projections[stmt.ProjectionsStart+i].Expr); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// selectCallsUserFunction reports whether a SELECT calls a registered
+// function outside RRF(...), whose arguments the optimizer lowers itself.
+func (db *Database) selectCallsUserFunction(src []byte, doc *parser.QueryDoc, stmt *parser.SelectStmt) bool {
+	if doc == nil || stmt == nil {
+		return false
+	}
+	db.functionsMu.RLock()
+	registered := len(db.functions) > 0
+	db.functionsMu.RUnlock()
+	if !registered {
+		return false
+	}
+	for i := int32(0); i < stmt.ProjectionsCount; i++ {
+		if stmt.ProjectionsStart+i >= 0 && int(stmt.ProjectionsStart+i) < len(doc.Projections) && db.nodeCallsUserFunction(src, doc, doc.Projections[stmt.ProjectionsStart+i].Expr) {
+			return true
+		}
+	}
+	if db.nodeCallsUserFunction(src, doc, stmt.WhereExpr) || db.nodeCallsUserFunction(src, doc, stmt.HavingExpr) || db.nodeCallsUserFunction(src, doc, stmt.OrderBy) {
+		return true
+	}
+	for _, term := range stmt.OrderTerms {
+		if db.nodeCallsUserFunction(src, doc, term.Expr) {
+			return true
+		}
+	}
+	for _, ref := range stmt.GroupBy {
+		if db.nodeCallsUserFunction(src, doc, ref) {
+			return true
+		}
+	}
+	for i := range stmt.Joins {
+		if db.nodeCallsUserFunction(src, doc, stmt.Joins[i].OnExpr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (db *Database) nodeCallsUserFunction(src []byte, doc *parser.QueryDoc, ref parser.NodeRef) bool {
+	if doc == nil || ref.Kind == parser.NodeKindUnknown || ref.ID < 0 {
+		return false
+	}
+	switch ref.Kind {
+	case parser.NodeKindFunctionExpr:
+		if int(ref.ID) >= len(doc.FunctionExprs) {
+			return false
+		}
+		fn := doc.FunctionExprs[ref.ID]
+		if fn.NameEnd > uint32(len(src)) || strings.EqualFold(sourceSpan(src, fn.NameStart, fn.NameEnd), "rrf") {
+			return false
+		}
+		if _, ok := db.functionCall(src, doc, ref); ok {
+			return true
+		}
+		for i := int32(0); i < fn.ArgsCount; i++ {
+			if fn.ArgsStart+i >= 0 && int(fn.ArgsStart+i) < len(doc.FunctionArgs) && db.nodeCallsUserFunction(src, doc, doc.FunctionArgs[fn.ArgsStart+i]) {
+				return true
+			}
+		}
+	case parser.NodeKindBinaryExpr:
+		if int(ref.ID) < len(doc.BinaryExprs) {
+			be := doc.BinaryExprs[ref.ID]
+			return db.nodeCallsUserFunction(src, doc, be.Left) || db.nodeCallsUserFunction(src, doc, be.Right)
+		}
+	case parser.NodeKindUnaryExpr:
+		if int(ref.ID) < len(doc.UnaryExprs) {
+			return db.nodeCallsUserFunction(src, doc, doc.UnaryExprs[ref.ID].Expr)
+		}
+	case parser.NodeKindCastExpr:
+		if int(ref.ID) < len(doc.CastExprs) {
+			return db.nodeCallsUserFunction(src, doc, doc.CastExprs[ref.ID].Expr)
+		}
+	case parser.NodeKindCaseExpr:
+		if int(ref.ID) < len(doc.CaseExprs) {
+			ce := doc.CaseExprs[ref.ID]
+			for i := int32(0); i < ce.WhensCount; i++ {
+				when := doc.CaseWhens[ce.WhensStart+i]
+				if db.nodeCallsUserFunction(src, doc, when.Condition) || db.nodeCallsUserFunction(src, doc, when.Value) {
+					return true
+				}
+			}
+			return ce.HasElse && db.nodeCallsUserFunction(src, doc, ce.Else)
+		}
+	case parser.NodeKindBetweenExpr:
+		if int(ref.ID) < len(doc.BetweenExprs) {
+			between := doc.BetweenExprs[ref.ID]
+			return db.nodeCallsUserFunction(src, doc, between.Expr) || db.nodeCallsUserFunction(src, doc, between.Lower) || db.nodeCallsUserFunction(src, doc, between.Upper)
+		}
+	case parser.NodeKindInExpr:
+		if int(ref.ID) < len(doc.InExprs) {
+			in := doc.InExprs[ref.ID]
+			if db.nodeCallsUserFunction(src, doc, in.Expr) {
+				return true
+			}
+			for i := int32(0); i < in.ListCount; i++ {
+				if db.nodeCallsUserFunction(src, doc, doc.Nodes[in.ListStart+i]) {
+					return true
+				}
+			}
+		}
+	case parser.NodeKindAggregateExpr:
+		if int(ref.ID) < len(doc.AggregateExprs) {
+			return db.nodeCallsUserFunction(src, doc, doc.AggregateExprs[ref.ID].Expr)
+		}
+	}
+	return false
+}
+
+// evaluateUserAggregate runs a registered aggregate over one group of rows.
+func (db *Database) evaluateUserAggregate(ctx context.Context, src []byte, doc *parser.QueryDoc, fn *sqlFunction, ref parser.NodeRef, rows []virtualSQLRow, params *optimizer.ParameterSet, legacy QueryParams) (interface{}, error) {
+	call := doc.FunctionExprs[ref.ID]
+	aggregator := fn.aggregate()
+	if aggregator == nil {
+		return nil, fmt.Errorf("function %s returned no aggregator", fn.Name)
+	}
+	args := make([]interface{}, call.ArgsCount)
+	for _, row := range rows {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for i := int32(0); i < call.ArgsCount; i++ {
+			if call.ArgsStart+i < 0 || int(call.ArgsStart+i) >= len(doc.FunctionArgs) {
+				return nil, fmt.Errorf("invalid function argument reference")
+			}
+			value, ok, err := db.virtualExprValue(ctx, src, doc, doc.FunctionArgs[call.ArgsStart+i], row, params, legacy)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				value = nil
+			}
+			args[i] = value
+		}
+		converted, err := fn.convertArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		if err := aggregator.Step(converted); err != nil {
+			return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+		}
+	}
+	result, err := aggregator.Result()
+	if err != nil {
+		return nil, fmt.Errorf("function %s: %w", fn.Name, err)
+	}
+	return fn.convertResult(result)
+}
+
+// rrfFunctionValue evaluates a registered-function RRF signal for one
+// candidate. A NULL or non-numeric result leaves the candidate out of that
+// signal's ranking, like a zero lexical score.
+func (e *Executor) rrfFunctionValue(ctx context.Context, col *Collection, rec *Record, component optimizer.RRFComponent) (float64, bool, error) {
+	fn, ok := e.db.lookupSQLFunction(component.Function)
+	if !ok {
+		return 0, false, fmt.Errorf("function %s does not exist", strings.ToLower(component.Function))
+	}
+	if fn.Aggregate {
+		return 0, false, fmt.Errorf("aggregate function %s cannot be an RRF signal", fn.Name)
+	}
+	args := make([]interface{}, len(component.FunctionArgs))
+	for i, arg := range component.FunctionArgs {
+		if !arg.IsColumn {
+			args[i] = virtualScalarInterface(arg.Value)
+			continue
+		}
+		switch {
+		case strings.EqualFold(arg.Column, "id"):
+			args[i] = rec.ID
+		case e.isVectorColumn(col.name, arg.Column) || strings.EqualFold(arg.Column, "embedding") || strings.EqualFold(arg.Column, "vector"):
+			if value, ok := recordMetadataValue(rec.Metadata, arg.Column); ok && vectorValue(value) != nil {
+				args[i] = value
+			} else if len(rec.Vector) > 0 {
+				args[i] = rec.Vector
+			}
+		default:
+			args[i], _ = recordMetadataValue(rec.Metadata, arg.Column)
+		}
+	}
+	result, err := fn.callScalar(ctx, args)
+	if err != nil {
+		return 0, false, err
+	}
+	if result == nil {
+		return 0, false, nil
+	}
+	score, ok := toFloat(result)
+	if !ok {
+		return 0, false, fmt.Errorf("RRF signal %s must return a number", fn.Name)
+	}
+	return score, !math.IsNaN(score), nil
+}
@@ -0,0 +1,219 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xDarkicex/libravdb/internal/catalog"
+)
+
+type productAggregator struct {
+	product float64
+	seen    bool
+}
+
+func (a *productAggregator) Step(args []interface{}) error {
+	if args[0] == nil {
+		return nil
+	}
+	if !a.seen {
+		a.product, a.seen = 1, true
+	}
+	a.product *= args[0].(float64)
+	return nil
+}
+
+func (a *productAggregator) Result() (interface{}, error) {
+	if !a.seen {
+		return nil, nil
+	}
+	return a.product, nil
+}
+
+func registerTestFunctions(t *testing.T, db *Database) {
+	t.Helper()
+	if err := db.RegisterScalarFunction("title_len", []FieldType{StringField}, IntField, func(_ context.Context, args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return len(args[0].(string)), nil
+	}); err != nil {
+		t.Fatalf("RegisterScalarFunction title_len: %v", err)
+	}
+	if err := db.RegisterScalarFunction("Boost", []FieldType{BigIntField, FloatField}, FloatField, func(_ context.Context, args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return float64(args[0].(int64)) * args[1].(float64), nil
+	}); err != nil {
+		t.Fatalf("RegisterScalarFunction boost: %v", err)
+	}
+	if err := db.RegisterAggregateFunction("product", []FieldType{FloatField}, FloatField, func() Aggregator {
+		return &productAggregator{}
+	}); err != nil {
+		t.Fatalf("RegisterAggregateFunction product: %v", err)
+	}
+}
+
+func TestScalarFunctionInProjectionWhereAndOrderBy(t *testing.T) {
+	db := openTempDB(t, "udf_scalar")
+	defer db.Close()
+	registerTestFunctions(t, db)
+
+	exec(t, db, `CREATE TABLE docs (id TEXT PRIMARY KEY, title TEXT, views BIGINT)`)
+	exec(t, db, `INSERT INTO docs (id, title, views) VALUES ('a', 'go', 30)`)
+	exec(t, db, `INSERT INTO docs (id, title, views) VALUES ('b', 'vectors', 10)`)
+	exec(t, db, `INSERT INTO docs (id, title, views) VALUES ('c', 'graphs', 20)`)
+	exec(t, db, `INSERT INTO docs (id, views) VALUES ('d', 40)`)
+
+	results, err := db.Query(context.Background(), `SELECT id, TITLE_LEN(title) AS n, boost(views, 0.5) AS b FROM docs WHERE title_len(title) > 2 ORDER BY boost(views, 0.5) DESC`)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	var got []string
+	for _, row := range results.Results {
+		got = append(got, fmt.Sprintf("%s:%v:%v", row.ID, row.Metadata["n"], row.Metadata["b"]))
+	}
+	if strings.Join(got, ",") != "c:6:10,b:7:5" {
+		t.Fatalf("rows = %v, want [c:6:10 b:7:5]", got)
+	}
+	if len(results.ColumnTypes) != 3 || results.ColumnTypes[1] != catalog.TypeInt || results.ColumnTypes[2] != catalog.TypeFloat {
+		t.Fatalf("column types = %v", results.ColumnTypes)
+	}
+
+	// A missing column reaches the function as NULL.
+	results, err = db.Query(context.Background(), `SELECT id, title_len(title) AS n FROM docs WHERE id = 'd'`)
+	if err != nil {
+		t.Fatalf("Query NULL argument: %v", err)
+	}
+	if len(results.Results) != 1 || results.Results[0].Metadata["n"] != nil {
+		t.Fatalf("NULL argument rows = %#v", results.Results)
+	}
+
+	if err := db.RegisterScalarFunction("title_len", []FieldType{StringField}, IntField, func(context.Context, []interface{}) (interface{}, error) {
+		return nil, errors.New("boom")
+	}); err != nil {
+		t.Fatalf("re-register title_len: %v", err)
+	}
+	alterMustFail(t, db, `SELECT title_len(title) FROM docs`, "function title_len: boom")
+	alterMustFail(t, db, `SELECT boost(views) FROM docs`, "expects 2 argument(s), got 1")
+	alterMustFail(t, db, `SELECT id FROM docs WHERE product(views) > 1`, "must be a top-level projection")
+}
+
+func TestAggregateFunction(t *testing.T) {
+	db := openTempDB(t, "udf_aggregate")
+	defer db.Close()
+	registerTestFunctions(t, db)
+
+	exec(t, db, `CREATE TABLE items (id TEXT PRIMARY KEY, category TEXT, price FLOAT)`)
+	exec(t, db, `INSERT INTO items (id, category, price) VALUES ('a', 'x', 2)`)
+	exec(t, db, `INSERT INTO items (id, category, price) VALUES ('b', 'x', 3)`)
+	exec(t, db, `INSERT INTO items (id, category, price) VALUES ('c', 'y', 4)`)
+	exec(t, db, `INSERT INTO items (id, category) VALUES ('d', 'z')`)
+
+	results, err := db.Query(context.Background(), `SELECT product(price) AS p FROM items`)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if len(results.Results) != 1 || results.Results[0].Metadata["p"] != float64(24) {
+		t.Fatalf("product = %#v, want 24", results.Results)
+	}
+
+	results, err = db.Query(context.Background(), `SELECT category, product(price) AS p FROM items GROUP BY category ORDER BY category`)
+	if err != nil {
+		t.Fatalf("grouped Query: %v", err)
+	}
+	var got []string
+	for _, row := range results.Results {
+		got = append(got, fmt.Sprintf("%v=%v", row.Metadata["category"], row.Metadata["p"]))
+	}
+	if strings.Join(got, ",") != "x=6,y=4,z=<nil>" {
+		t.Fatalf("grouped product = %v", got)
+	}
+}
+
+func TestRRFWithRegisteredFunction(t *testing.T) {
+	ctx := context.Background()
+	db := openTempDB(t, "udf_rrf")
+	defer db.Close()
+	if err := db.RegisterScalarFunction("popularity", []FieldType{BigIntField}, FloatField, func(_ context.Context, args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return nil, nil
+		}
+		return float64(args[0].(int64)), nil
+	}); err != nil {
+		t.Fatalf("RegisterScalarFunction: %v", err)
+	}
+
+	exec(t, db, `CREATE TABLE docs (id TEXT PRIMARY KEY, embedding VECTOR(3), views BIGINT)`)
+	exec(t, db, `INSERT INTO docs (id, embedding, views) VALUES ('a', '[1,0,0]', 5)`)
+	exec(t, db, `INSERT INTO docs (id, embedding) VALUES ('b', '[0.9,0.1,0]')`)
+	exec(t, db, `INSERT INTO docs (id, embedding, views) VALUES ('c', '[0,1,0]', 3)`)
+
+	// Vector ranks a, b, c; popularity ranks a, c and omits b, whose views
+	// are NULL. c therefore overtakes b.
+	result, err := db.QueryWithParams(ctx,
+		"SELECT id, RRF(VECTOR_DISTANCE(embedding, $q), popularity(views)) AS relevance FROM docs ORDER BY relevance DESC LIMIT 3",
+		QueryParams{"q": []float32{1, 0, 0}})
+	if err != nil {
+		t.Fatalf("RRF query: %v", err)
+	}
+	var ids []string
+	for _, row := range result.Results {
+		ids = append(ids, row.ID)
+	}
+	if strings.Join(ids, ",") != "a,c,b" {
+		t.Fatalf("RRF order = %v, want [a c b]", ids)
+	}
+
+	if _, err := db.QueryWithParams(ctx,
+		"SELECT id, RRF(VECTOR_DISTANCE(embedding, $q), missing_fn(views)) AS relevance FROM docs",
+		QueryParams{"q": []float32{1, 0, 0}}); err == nil || !strings.Contains(err.Error(), "function missing_fn does not exist") {
+		t.Fatalf("unregistered RRF signal error = %v", err)
+	}
+}
+
+func TestRegisterFunctionValidationAndPgProc(t *testing.T) {
+	db := openTempDB(t, "udf_catalog")
+	defer db.Close()
+	scalar := func(context.Context, []interface{}) (interface{}, error) { return nil, nil }
+
+	for _, tc := range []struct {
+		err  error
+		want string
+	}{
+		{db.RegisterScalarFunction("1abc", nil, IntField, scalar), "invalid function name"},
+		{db.RegisterScalarFunction("count", nil, IntField, scalar), "built in"},
+		{db.RegisterScalarFunction("f", nil, IntField, nil), "no implementation"},
+		{db.RegisterScalarFunction("f", nil, StringArrayField, scalar), "cannot return"},
+		{db.RegisterScalarFunction("f", []FieldType{FieldType(99)}, IntField, scalar), "argument 1 has unsupported type"},
+		{db.RegisterAggregateFunction("g", nil, IntField, nil), "no implementation"},
+	} {
+		if tc.err == nil || !strings.Contains(tc.err.Error(), tc.want) {
+			t.Fatalf("registration error = %v, want %q", tc.err, tc.want)
+		}
+	}
+	if _, ok := db.LookupSQLFunction("f"); ok {
+		t.Fatal("rejected function was registered")
+	}
+
+	registerTestFunctions(t, db)
+	if info, ok := db.LookupSQLFunction("BOOST"); !ok || info.Name != "boost" || len(info.ArgTypes) != 2 || info.ReturnType != FloatField {
+		t.Fatalf("LookupSQLFunction(BOOST) = %#v, %v", info, ok)
+	}
+	results, err := db.Query(context.Background(), `SELECT proname, prokind, pronargs, prorettype, proargtypes FROM pg_catalog.pg_proc ORDER BY proname`)
+	if err != nil {
+		t.Fatalf("Query pg_proc: %v", err)
+	}
+	var got []string
+	for _, row := range results.Results {
+		m := row.Metadata
+		got = append(got, fmt.Sprintf("%v/%v/%v/%v/%v", m["proname"], m["prokind"], m["pronargs"], m["prorettype"], m["proargtypes"]))
+	}
+	if strings.Join(got, " ") != "boost/f/2/701/20 701 product/a/1/701/701 title_len/f/1/23/25" {
+		t.Fatalf("pg_proc rows = %q", got)
+	}
+}
@@ -303,7 +303,7 @@ func (db *Database) executeGraphSemijoinSubquery(ctx context.Context, sql string
 	if err := catalog.NewBinder(cat, src).Bind(doc); err != nil {
 		return nil, fmt.Errorf("bind graph semijoin subquery: %w", err)
 	}
-	plan, err := optimizer.NewOptimizer(cat).OptimizeWithBoundParams(doc, src, params)
+	plan, err := db.newSQLOptimizer(ctx, cat).OptimizeWithBoundParams(doc, src, params)
 	if err != nil {
 		return nil, fmt.Errorf("optimize graph semijoin subquery: %w", err)
 	}
@@ -316,6 +316,15 @@ func parseSQL(sql string) (*parser.QueryDoc, error) {
 	src := []byte(sql)
 	doc := &parser.QueryDoc{}
 	if err := parser.Parse(src, doc); err != nil {
+		// ALTER TABLE actions and view DDL the grammar does not model are
+		// planned by the optimizer and execute through
+		// queryWithSessionConfig like any other single statement.
+		if _, handled, planErr := planOutsideGrammar(src); handled {
+			if planErr != nil {
+				return nil, planErr
+			}
+			return &parser.QueryDoc{}, nil
+		}
 		return nil, fmt.Errorf("parse error: %w", err)
 	}
 	// Count standalone statements. ComputeLeidenStmts referenced by a
@@ -86,6 +86,9 @@ type sqlQueryTracker struct {
 	predicateRejections  uint64
 	rowsReturned         uint64
 	rowsReturnedOverride bool
+	// operatorRows is allocated only by EXPLAIN ANALYZE; it collects the
+	// rows each plan operator produced, keyed as in sql_explain.go.
+	operatorRows map[string]uint64
 }
 
 type sqlQueryTrackerContextKey struct{}
@@ -122,6 +125,14 @@ func trackSQLPredicateRejection(ctx context.Context, count int) {
 	}
 }
 
+// trackSQLOperatorRows records rows produced by one plan operator. Ordinary
+// queries pay only the context lookup.
+func trackSQLOperatorRows(ctx context.Context, operator string, rows int) {
+	if tracker := sqlTrackerFromContext(ctx); tracker != nil && tracker.operatorRows != nil && rows >= 0 {
+		tracker.operatorRows[operator] += uint64(rows)
+	}
+}
+
 func recordMatchesPredicatesTracked(ctx context.Context, record Record, predicates []optimizer.RelationalPredicate) bool {
 	if recordMatchesPredicates(record, predicates) {
 		return true
@@ -0,0 +1,817 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xDarkicex/lexer/parser"
+	"github.com/xDarkicex/libravdb/internal/catalog"
+	"github.com/xDarkicex/libravdb/internal/optimizer"
+	"github.com/xDarkicex/libravdb/internal/storage"
+)
+
+// Views are outside the parser's grammar. Their DDL is planned by the
+// optimizer (OptimizeView) when parsing fails, and definitions are kept in
+// the durable view registry (storage.ViewStore).
+//
+// A plain view is resolved on the parsed query, never on its text: a
+// statement whose AST names a view as a relation runs on the query-local
+// evaluator, which binds that relation to the rows of the view's own parsed
+// definition, evaluated once per statement. A materialized view is an
+// ordinary collection filled from its defining query; it is read like any
+// table, and REFRESH MATERIALIZED VIEW replaces its rows in a single
+// transaction.
+
+// View describes a view created with CREATE VIEW or CREATE MATERIALIZED VIEW.
+type View struct {
+	Name         string
+	Query        string
+	Materialized bool
+}
+
+// viewKey is the registry key of a relation name: lower-cased, without the
+// public schema qualifier.
+func viewKey(name string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), "public.")
+}
+
+// loadViews reads the durable view registry into the runtime cache.
+func (db *Database) loadViews() error {
+	store, ok := db.storage.(storage.ViewStore)
+	if !ok {
+		return nil
+	}
+	definitions, err := store.ListViews()
+	if err != nil {
+		return fmt.Errorf("load SQL views: %w", err)
+	}
+	views := make(map[string]View, len(definitions))
+	for name, definition := range definitions {
+		views[viewKey(name)] = View{Name: name, Query: definition.Query, Materialized: definition.Materialized}
+	}
+	db.viewsMu.Lock()
+	db.views = views
+	db.viewsMu.Unlock()
+	return nil
+}
+
+// ListViews returns the registered views sorted by name.
+func (db *Database) ListViews() []View {
+	db.viewsMu.RLock()
+	views := make([]View, 0, len(db.views))
+	for _, view := range db.views {
+		views = append(views, view)
+	}
+	db.viewsMu.RUnlock()
+	sort.Slice(views, func(i, j int) bool { return views[i].Name < views[j].Name })
+	return views
+}
+
+func (db *Database) lookupView(name string) (View, bool) {
+	db.viewsMu.RLock()
+	view, ok := db.views[viewKey(name)]
+	db.viewsMu.RUnlock()
+	return view, ok
+}
+
+// viewSnapshot returns a copy of the view cache keyed by viewKey.
+func (db *Database) viewSnapshot() map[string]View {
+	db.viewsMu.RLock()
+	views := make(map[string]View, len(db.views))
+	for key, view := range db.views {
+		views[key] = view
+	}
+	db.viewsMu.RUnlock()
+	return views
+}
+
+func (db *Database) cacheView(view View) {
+	db.viewsMu.Lock()
+	if db.views == nil {
+		db.views = make(map[string]View)
+	}
+	db.views[viewKey(view.Name)] = view
+	db.viewsMu.Unlock()
+}
+
+func (db *Database) uncacheView(name string) {
+	db.viewsMu.Lock()
+	delete(db.views, viewKey(name))
+	db.viewsMu.Unlock()
+}
+
+// parseViewQuery parses a view's defining query. Each statement that reads
+// the view parses its own copy, because evaluation annotates the AST.
+func parseViewQuery(query string) ([]byte, *parser.QueryDoc, error) {
+	src := []byte(strings.TrimRight(strings.TrimSpace(query), "; \t\r\n"))
+	doc := &parser.QueryDoc{}
+	if err := parser.Parse(src, doc); err != nil {
+		return nil, nil, err
+	}
+	return src, doc, nil
+}
+
+// viewRelations returns the keys of the views in views that doc reads as a
+// FROM or JOIN relation, in AST order. Names bound by the query's own CTEs
+// shadow views.
+func viewRelations(src []byte, doc *parser.QueryDoc, views map[string]View) []string {
+	if len(views) == 0 || doc == nil {
+		return nil
+	}
+	ctes := make(map[string]bool, len(doc.CTEs))
+	for i := range doc.CTEs {
+		ctes[virtualCTEName(sourceSpan(src, doc.CTEs[i].NameStart, doc.CTEs[i].NameEnd))] = true
+	}
+	var keys []string
+	relation := func(start, end uint32) {
+		name := sourceSpan(src, start, end)
+		if name == "" || ctes[virtualCTEName(name)] {
+			return
+		}
+		if _, ok := views[viewKey(name)]; ok {
+			keys = append(keys, viewKey(name))
+		}
+	}
+	for i := range doc.TableExprs {
+		t := &doc.TableExprs[i]
+		if !t.IsDerived && !t.IsFunction && !t.TemporalRange {
+			relation(t.Start, t.End)
+		}
+	}
+	for i := range doc.SelectStmts {
+		for j := range doc.SelectStmts[i].Joins {
+			join := &doc.SelectStmts[i].Joins[j]
+			if join.Derived.Kind == parser.NodeKindUnknown && !join.IsFunction && join.MatchPath.Kind != parser.NodeKindMatchPath {
+				relation(join.TableStart, join.TableEnd)
+			}
+		}
+	}
+	return keys
+}
+
+// viewDependencies returns the keys of the views query reads. A definition
+// that no longer parses reads nothing.
+func viewDependencies(query string, views map[string]View) []string {
+	src, doc, err := parseViewQuery(query)
+	if err != nil {
+		return nil
+	}
+	return viewRelations(src, doc, views)
+}
+
+// checkViewCycle reports a plain view that reaches itself through the views
+// it reads. state marks views in progress (1) and done (2).
+func checkViewCycle(key string, views map[string]View, state map[string]uint8) error {
+	switch state[key] {
+	case 1:
+		return fmt.Errorf("view %q is defined in terms of itself", views[key].Name)
+	case 2:
+		return nil
+	}
+	state[key] = 1
+	for _, ref := range viewDependencies(views[key].Query, views) {
+		if views[ref].Materialized {
+			continue
+		}
+		if err := checkViewCycle(ref, views, state); err != nil {
+			return err
+		}
+	}
+	state[key] = 2
+	return nil
+}
+
+// readsView returns the first plain view that doc reads. Such a statement is
+// evaluated by the query-local evaluator, which resolves the view.
+func (db *Database) readsView(src []byte, doc *parser.QueryDoc) (View, bool) {
+	db.viewsMu.RLock()
+	defer db.viewsMu.RUnlock()
+	for _, key := range viewRelations(src, doc, db.views) {
+		if view := db.views[key]; !view.Materialized {
+			return view, true
+		}
+	}
+	return View{}, false
+}
+
+// ReadsView reports whether a parsed query reads a plain view. Protocol
+// adapters use it to describe such a query like other query-local
+// statements instead of binding it against the catalog.
+func (db *Database) ReadsView(src []byte, doc *parser.QueryDoc) bool {
+	_, ok := db.readsView(src, doc)
+	return ok
+}
+
+// viewScope tracks the plain views one statement has evaluated. rows holds
+// each view's result for reuse by later references; active holds the views
+// being evaluated, so a cycle is reported instead of recursing.
+type viewScope struct {
+	rows   map[string][]virtualSQLRow
+	active map[string]bool
+}
+
+type viewScopeContextKey struct{}
+
+func withViewScope(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(viewScopeContextKey{}).(*viewScope); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, viewScopeContextKey{}, &viewScope{
+		rows:   make(map[string][]virtualSQLRow),
+		active: make(map[string]bool),
+	})
+}
+
+// viewRows evaluates a plain view's definition for the statement reading it.
+// The definition sees neither the statement's CTEs nor its column pruning;
+// its own WITH clause is scoped to it.
+func (db *Database) viewRows(ctx context.Context, view View) ([]virtualSQLRow, error) {
+	ctx = withViewScope(ctx)
+	scope := ctx.Value(viewScopeContextKey{}).(*viewScope)
+	key := viewKey(view.Name)
+	if rows, ok := scope.rows[key]; ok {
+		return rows, nil
+	}
+	if scope.active[key] {
+		return nil, fmt.Errorf("view %q is defined in terms of itself", view.Name)
+	}
+	scope.active[key] = true
+	defer delete(scope.active, key)
+
+	src, doc, err := parseViewQuery(view.Query)
+	if err != nil {
+		return nil, fmt.Errorf("view %q: parse error: %w", view.Name, err)
+	}
+	root := rootSelectIndex(doc)
+	if root < 0 || root >= len(doc.SelectStmts) {
+		return nil, fmt.Errorf("view %q is not defined by a SELECT query", view.Name)
+	}
+	stmt := &doc.SelectStmts[root]
+	ctx = context.WithValue(ctx, virtualColumnRequirementsContextKey{}, virtualColumnRequirements(nil))
+	ctx = withVirtualCTEs(ctx, make(virtualCTEEnv, stmt.CTEsCount), virtualSessionConfigFromContext(ctx))
+	if err := db.evaluateVirtualCTEs(ctx, src, doc, stmt, nil, nil); err != nil {
+		return nil, fmt.Errorf("view %q: %w", view.Name, err)
+	}
+	rows, columns, err := db.evaluateVirtualSelectRows(ctx, src, doc, stmt, nil, nil, nil)
+	if err == nil {
+		rows, err = db.applyVirtualSelectClauses(ctx, src, doc, stmt, rows, columns, nil, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("view %q: %w", view.Name, err)
+	}
+	scope.rows[key] = rows
+	return rows, nil
+}
+
+// executeViewDDL executes a view statement planned by OptimizeView.
+func (db *Database) executeViewDDL(ctx context.Context, plan *optimizer.PhysicalPlan) (*SearchResults, error) {
+	statement := optimizer.ViewStatementName(plan)
+	store, ok := db.storage.(storage.ViewStore)
+	if !ok {
+		return nil, fmt.Errorf("%s: views are not supported by this storage engine", statement)
+	}
+	db.viewDDLMu.Lock()
+	defer db.viewDDLMu.Unlock()
+	var err error
+	switch {
+	case plan.DDLKind == 6 && plan.DDLViewMaterialized:
+		err = db.createMaterializedView(ctx, store, plan)
+	case plan.DDLKind == 6:
+		err = db.createView(store, plan)
+	case plan.DDLKind == 8:
+		err = db.refreshMaterializedView(ctx, plan)
+	default:
+		err = db.dropViews(ctx, plan)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResults{}, nil
+}
+
+func (db *Database) createView(store storage.ViewStore, plan *optimizer.PhysicalPlan) error {
+	name := plan.DDLViewNames[0]
+	if existing, ok := db.lookupView(name); ok {
+		if existing.Materialized {
+			return fmt.Errorf("CREATE VIEW: %q is a materialized view", existing.Name)
+		}
+		if !plan.DDLViewOrReplace {
+			return fmt.Errorf("CREATE VIEW: relation %q already exists", existing.Name)
+		}
+		name = existing.Name
+	} else if _, err := db.GetCollection(name); err == nil {
+		return fmt.Errorf("CREATE VIEW: relation %q already exists", name)
+	}
+	view := View{Name: name, Query: plan.DDLViewQuery}
+	views := db.viewSnapshot()
+	views[viewKey(name)] = view
+	if err := checkViewCycle(viewKey(name), views, make(map[string]uint8)); err != nil {
+		return fmt.Errorf("CREATE VIEW: %w", err)
+	}
+	if err := store.PutView(view.Name, storage.ViewDefinition{Query: view.Query}); err != nil {
+		return fmt.Errorf("CREATE VIEW: %w", err)
+	}
+	db.cacheView(view)
+	return nil
+}
+
+// dropViews drops every named view after checking that all of them exist
+// and that no remaining view is defined in terms of one of them. The views
+// and the tables backing the materialized ones are removed in one storage
+// transaction.
+func (db *Database) dropViews(ctx context.Context, plan *optimizer.PhysicalPlan) error {
+	statement := optimizer.ViewStatementName(plan)
+	views := db.viewSnapshot()
+	dropped := make(map[string]bool, len(plan.DDLViewNames))
+	var drop []View
+	for _, name := range plan.DDLViewNames {
+		key := viewKey(name)
+		view, ok := views[key]
+		switch {
+		case !ok && plan.DDLIfExists:
+			continue
+		case !ok && plan.DDLViewMaterialized:
+			return newSQLError(SQLErrorUndefinedTable, "42P01", fmt.Errorf("%s: materialized view %q does not exist", statement, name))
+		case !ok:
+			return newSQLError(SQLErrorUndefinedTable, "42P01", fmt.Errorf("%s: view %q does not exist", statement, name))
+		case view.Materialized && !plan.DDLViewMaterialized:
+			return fmt.Errorf("%s: %q is a materialized view; use DROP MATERIALIZED VIEW", statement, view.Name)
+		case !view.Materialized && plan.DDLViewMaterialized:
+			return fmt.Errorf("%s: %q is not a materialized view; use DROP VIEW", statement, view.Name)
+		}
+		if !dropped[key] {
+			dropped[key] = true
+			drop = append(drop, view)
+		}
+	}
+	for key, view := range views {
+		if dropped[key] {
+			continue
+		}
+		for _, ref := range viewDependencies(view.Query, views) {
+			if dropped[ref] {
+				return fmt.Errorf("%s: cannot drop %q because view %q depends on it", statement, views[ref].Name, view.Name)
+			}
+		}
+	}
+	engine, ok := db.storage.(storage.TransactionalEngine)
+	if !ok {
+		return fmt.Errorf("%s: storage engine does not support transactional schema changes", statement)
+	}
+	ops := make([]storage.TxOperation, 0, 2*len(drop))
+	for _, view := range drop {
+		ops = append(ops, storage.TxOperation{Type: storage.TxOperationViewDrop, Collection: view.Name})
+		if !view.Materialized {
+			continue
+		}
+		if refs := db.foreignKeyReferrers(view.Name); len(refs) > 0 {
+			return fmt.Errorf("%s: cannot drop %q: foreign key constraints in %v reference it", statement, view.Name, refs)
+		}
+		ops = append(ops, storage.TxOperation{Type: storage.TxOperationCollectionDelete, Collection: view.Name})
+	}
+	// The views and their backing tables leave the registry and the file
+	// in one commit, so a failure drops none of them.
+	if err := engine.CommitTx(ctx, ops); err != nil {
+		return fmt.Errorf("%s: %w", statement, err)
+	}
+	for _, view := range drop {
+		db.uncacheView(view.Name)
+		if !view.Materialized {
+			continue
+		}
+		db.mu.Lock()
+		col := db.collections[view.Name]
+		delete(db.collections, view.Name)
+		db.mu.Unlock()
+		if col == nil {
+			continue
+		}
+		if err := col.Close(); err != nil && db.logger != nil {
+			db.logger.Printf("libravdb: %s: close dropped table %q: %v", statement, view.Name, err)
+		}
+	}
+	return nil
+}
+
+// guardViewWrites rejects INSERT, UPDATE, DELETE, ALTER TABLE and DROP
+// TABLE naming a view, CREATE TABLE reusing a view's name, and any
+// statement other than a query that reads a plain view.
+func (db *Database) guardViewWrites(src []byte, doc *parser.QueryDoc) error {
+	db.viewsMu.RLock()
+	empty := len(db.views) == 0
+	db.viewsMu.RUnlock()
+	if empty {
+		return nil
+	}
+	type target struct {
+		verb       string
+		start, end uint32
+	}
+	var targets []target
+	for i := range doc.InsertStmts {
+		targets = append(targets, target{"insert into", doc.InsertStmts[i].TableStart, doc.InsertStmts[i].TableEnd})
+	}
+	for i := range doc.UpdateStmts {
+		targets = append(targets, target{"update", doc.UpdateStmts[i].TableStart, doc.UpdateStmts[i].TableEnd})
+	}
+	for i := range doc.DeleteStmts {
+		if !doc.DeleteStmts[i].Cypher {
+			targets = append(targets, target{"delete from", doc.DeleteStmts[i].TableStart, doc.DeleteStmts[i].TableEnd})
+		}
+	}
+	for i := range doc.AlterTableStmts {
+		targets = append(targets, target{"alter", doc.AlterTableStmts[i].TableStart, doc.AlterTableStmts[i].TableEnd})
+	}
+	for i := range doc.DropTableStmts {
+		targets = append(targets, target{"drop", doc.DropTableStmts[i].TableStart, doc.DropTableStmts[i].TableEnd})
+	}
+	for i := range doc.CreateTableStmts {
+		targets = append(targets, target{"create", doc.CreateTableStmts[i].TableStart, doc.CreateTableStmts[i].TableEnd})
+	}
+	for _, target := range targets {
+		view, ok := db.lookupView(sourceSpan(src, target.start, target.end))
+		if !ok {
+			continue
+		}
+		switch {
+		case target.verb == "create":
+			return fmt.Errorf("CREATE TABLE: relation %q already exists", view.Name)
+		case target.verb == "drop" && view.Materialized:
+			return fmt.Errorf("DROP TABLE: %q is not a table; use DROP MATERIALIZED VIEW", view.Name)
+		case target.verb == "drop":
+			return fmt.Errorf("DROP TABLE: %q is not a table; use DROP VIEW", view.Name)
+		case target.verb == "alter":
+			return fmt.Errorf("%q is not a table", view.Name)
+		case view.Materialized:
+			return fmt.Errorf("cannot change materialized view %q", view.Name)
+		default:
+			return fmt.Errorf("cannot %s view %q", target.verb, view.Name)
+		}
+	}
+	if len(targets) > 0 || len(doc.MergeStmts) > 0 {
+		if view, ok := db.readsView(src, doc); ok {
+			return fmt.Errorf("view %q can only be read by a SELECT query", view.Name)
+		}
+	}
+	return nil
+}
+
+func quoteViewIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// materializedColumn is one column of a materialized view's table. The id
+// column becomes the record ID and has no type.
+type materializedColumn struct {
+	name     string
+	sqlType  string
+	declared uint16
+	vector   int
+	id       bool
+}
+
+// materializedColumns derives the table columns of a materialized view from
+// its query result. Catalog types reported by the executor win; otherwise
+// the type is inferred from the first non-NULL value.
+func materializedColumns(results *SearchResults) ([]materializedColumn, error) {
+	names := results.Columns
+	if len(names) == 0 {
+		seen := make(map[string]bool)
+		for _, row := range results.Results {
+			for key := range row.Metadata {
+				if !seen[key] && !strings.EqualFold(key, "id") {
+					seen[key] = true
+					names = append(names, key)
+				}
+			}
+		}
+		sort.Strings(names)
+		names = append([]string{"id"}, names...)
+	}
+	seen := make(map[string]bool, len(names))
+	columns := make([]materializedColumn, 0, len(names))
+	vectors := 0
+	for i, name := range names {
+		key := strings.ToLower(name)
+		if seen[key] {
+			return nil, fmt.Errorf("column %q specified more than once", name)
+		}
+		seen[key] = true
+		column := materializedColumn{name: name, id: key == "id"}
+		if column.id {
+			columns = append(columns, column)
+			continue
+		}
+		if i < len(results.ColumnTypes) {
+			column.declared = results.ColumnTypes[i]
+		}
+		var sample interface{}
+		for _, row := range results.Results {
+			if sample = materializedValue(row, name, column.declared); sample != nil {
+				break
+			}
+		}
+		if err := column.inferType(sample); err != nil {
+			return nil, err
+		}
+		if column.vector > 0 {
+			vectors++
+		}
+		columns = append(columns, column)
+	}
+	if vectors > 1 {
+		return nil, fmt.Errorf("a materialized view can have at most one vector column")
+	}
+	return columns, nil
+}
+
+func (c *materializedColumn) inferType(sample interface{}) error {
+	if vector, ok := materializedVector(sample, c.declared); ok || c.declared == catalog.TypeVector {
+		if len(vector) == 0 {
+			return fmt.Errorf("cannot determine the dimension of vector column %q", c.name)
+		}
+		c.vector = len(vector)
+		c.sqlType = fmt.Sprintf("VECTOR(%d)", len(vector))
+		return nil
+	}
+	switch c.declared {
+	case catalog.TypeInt:
+		c.sqlType = "INTEGER"
+	case catalog.TypeSmallInt:
+		c.sqlType = "SMALLINT"
+	case catalog.TypeBigInt, catalog.TypeOID:
+		c.sqlType = "BIGINT"
+	case catalog.TypeFloat:
+		c.sqlType = "FLOAT"
+	case catalog.TypeFloat4:
+		c.sqlType = "REAL"
+	case catalog.TypeBool:
+		c.sqlType = "BOOLEAN"
+	case catalog.TypeTimestamp:
+		c.sqlType = "TIMESTAMPTZ"
+	case catalog.TypeJSON:
+		c.sqlType = "JSON"
+	case catalog.TypeJSONB:
+		c.sqlType = "JSONB"
+	case catalog.TypeUUID:
+		c.sqlType = "UUID"
+	}
+	if c.sqlType != "" {
+		return nil
+	}
+	switch sample.(type) {
+	case bool:
+		c.sqlType = "BOOLEAN"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		c.sqlType = "BIGINT"
+	case float32, float64:
+		c.sqlType = "FLOAT"
+	case time.Time:
+		c.sqlType = "TIMESTAMPTZ"
+	case map[string]interface{}, []interface{}:
+		c.sqlType = "JSONB"
+	default:
+		c.sqlType = "TEXT"
+	}
+	return nil
+}
+
+// materializedValue returns the value of column in a query result row the
+// way protocol adapters read it.
+func materializedValue(row *SearchResult, column string, declared uint16) interface{} {
+	if row == nil {
+		return nil
+	}
+	if strings.EqualFold(column, "id") {
+		return row.ID
+	}
+	value, ok := metadataColumnValue(row.Metadata, column)
+	if !ok {
+		switch {
+		case declared == catalog.TypeVector && len(row.Vector) > 0:
+			return row.Vector
+		case strings.EqualFold(column, "score"):
+			return row.Score
+		}
+		return nil
+	}
+	return materializeSQLJSONValue(value)
+}
+
+// materializedVector converts a vector value. Vector columns are projected
+// in their text form, so a string is parsed only for a declared vector.
+func materializedVector(value interface{}, declared uint16) ([]float32, bool) {
+	switch v := value.(type) {
+	case []float32:
+		return v, true
+	case []float64:
+		vector := make([]float32, len(v))
+		for i, f := range v {
+			vector[i] = float32(f)
+		}
+		return vector, true
+	case string:
+		if declared == catalog.TypeVector {
+			vector := parseVectorLiteral(v)
+			return vector, vector != nil
+		}
+	}
+	return nil, false
+}
+
+// materializedRecords converts query rows to the records of a materialized
+// view's table. Without an id column, rows are numbered from 1.
+func materializedRecords(results *SearchResults, columns []materializedColumn) ([]Record, error) {
+	hasID := false
+	for _, column := range columns {
+		hasID = hasID || column.id
+	}
+	records := make([]Record, 0, len(results.Results))
+	seen := make(map[string]bool, len(results.Results))
+	for i, row := range results.Results {
+		record := Record{ID: strconv.Itoa(i + 1), Metadata: make(map[string]interface{}, len(columns))}
+		for _, column := range columns {
+			value := materializedValue(row, column.name, column.declared)
+			switch {
+			case column.id:
+				if value == nil || value == "" {
+					return nil, fmt.Errorf("row %d has a NULL id", i+1)
+				}
+				record.ID = fmt.Sprint(value)
+			case column.vector > 0:
+				vector, ok := materializedVector(value, column.declared)
+				if !ok || len(vector) != column.vector {
+					return nil, fmt.Errorf("vector column %q: row %d does not have %d dimensions", column.name, i+1, column.vector)
+				}
+				record.Vector = cloneVector(vector)
+			case value != nil:
+				record.Metadata[column.name] = value
+			}
+		}
+		if hasID {
+			if seen[record.ID] {
+				return nil, fmt.Errorf("duplicate id %q", record.ID)
+			}
+			seen[record.ID] = true
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// materializedTableSQL is the CREATE TABLE statement for a materialized
+// view's columns.
+func materializedTableSQL(name string, columns []materializedColumn) string {
+	var b strings.Builder
+	b.WriteString("CREATE TABLE ")
+	b.WriteString(quoteViewIdentifier(name))
+	b.WriteString(` ("id" TEXT PRIMARY KEY`)
+	for _, column := range columns {
+		if column.id {
+			continue
+		}
+		b.WriteString(", ")
+		b.WriteString(quoteViewIdentifier(column.name))
+		b.WriteByte(' ')
+		b.WriteString(column.sqlType)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+func (db *Database) createMaterializedView(ctx context.Context, store storage.ViewStore, plan *optimizer.PhysicalPlan) error {
+	name := plan.DDLViewNames[0]
+	_, isView := db.lookupView(name)
+	_, collectionErr := db.GetCollection(name)
+	if isView || collectionErr == nil {
+		if plan.DDLViewIfNotExists {
+			return nil
+		}
+		return fmt.Errorf("CREATE MATERIALIZED VIEW: relation %q already exists", name)
+	}
+	results, err := db.queryWithContext(ctx, plan.DDLViewQuery, nil)
+	if err != nil {
+		return fmt.Errorf("CREATE MATERIALIZED VIEW: %w", err)
+	}
+	columns, err := materializedColumns(results)
+	if err != nil {
+		return fmt.Errorf("CREATE MATERIALIZED VIEW: %w", err)
+	}
+	var records []Record
+	if plan.DDLViewWithData {
+		if records, err = materializedRecords(results, columns); err != nil {
+			return fmt.Errorf("CREATE MATERIALIZED VIEW: %w", err)
+		}
+	}
+	if _, err := db.queryWithContext(ctx, materializedTableSQL(name, columns), nil); err != nil {
+		return fmt.Errorf("CREATE MATERIALIZED VIEW: %w", err)
+	}
+	err = db.WithTx(ctx, func(tx Tx) error {
+		for _, record := range records {
+			if err := tx.Insert(ctx, name, record.ID, record.Vector, record.Metadata); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err == nil {
+		err = store.PutView(name, storage.ViewDefinition{Query: plan.DDLViewQuery, Materialized: true})
+	}
+	if err != nil {
+		_, _ = db.queryWithContext(ctx, "DROP TABLE "+quoteViewIdentifier(name), nil)
+		return fmt.Errorf("CREATE MATERIALIZED VIEW: %w", err)
+	}
+	db.cacheView(View{Name: name, Query: plan.DDLViewQuery, Materialized: true})
+	return nil
+}
+
+// refreshMaterializedView re-runs the view's query and, in one transaction,
+// deletes the rows whose id is gone and upserts the rest.
+func (db *Database) refreshMaterializedView(ctx context.Context, plan *optimizer.PhysicalPlan) error {
+	view, ok := db.lookupView(plan.DDLViewNames[0])
+	if !ok {
+		return newSQLError(SQLErrorUndefinedTable, "42P01", fmt.Errorf("REFRESH MATERIALIZED VIEW: materialized view %q does not exist", plan.DDLViewNames[0]))
+	}
+	if !view.Materialized {
+		return fmt.Errorf("REFRESH MATERIALIZED VIEW: %q is not a materialized view", view.Name)
+	}
+	col, err := db.GetCollection(view.Name)
+	if err != nil {
+		return fmt.Errorf("REFRESH MATERIALIZED VIEW: %w", err)
+	}
+	var records []Record
+	if plan.DDLViewWithData {
+		results, err := db.queryWithContext(ctx, view.Query, nil)
+		if err != nil {
+			return fmt.Errorf("REFRESH MATERIALIZED VIEW: %w", err)
+		}
+		columns, err := materializedColumns(results)
+		if err == nil {
+			err = checkMaterializedColumns(col, columns)
+		}
+		if err == nil {
+			records, err = materializedRecords(results, columns)
+		}
+		if err != nil {
+			return fmt.Errorf("REFRESH MATERIALIZED VIEW %q: %w", view.Name, err)
+		}
+	}
+	existing, err := col.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("REFRESH MATERIALIZED VIEW: %w", err)
+	}
+	keep := make(map[string]bool, len(records))
+	for _, record := range records {
+		keep[record.ID] = true
+	}
+	return db.WithTx(ctx, func(tx Tx) error {
+		for _, record := range existing {
+			if !keep[record.ID] {
+				if err := tx.Delete(ctx, view.Name, record.ID); err != nil {
+					return err
+				}
+			}
+		}
+		for _, record := range records {
+			if err := tx.Upsert(ctx, view.Name, record.ID, record.Vector, record.Metadata); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// checkMaterializedColumns reports whether the query still produces the
+// columns the materialized view's table was created with.
+func checkMaterializedColumns(col *Collection, columns []materializedColumn) error {
+	cfg := col.Config()
+	metadata := 0
+	for name := range cfg.MetadataSchema {
+		if !strings.EqualFold(name, "id") {
+			metadata++
+		}
+	}
+	for _, column := range columns {
+		if column.id {
+			continue
+		}
+		_, stored := cfg.MetadataSchema[column.name]
+		if stored {
+			metadata--
+		}
+		switch {
+		case column.vector > 0 && column.vector != cfg.Dimension:
+			return fmt.Errorf("vector column %q now has %d dimensions, the table has %d; drop and re-create the view", column.name, column.vector, cfg.Dimension)
+		case column.vector == 0 && !stored:
+			return fmt.Errorf("column %q is not in the table; drop and re-create the view", column.name)
+		}
+	}
+	if metadata > 0 {
+		return fmt.Errorf("the query no longer produces every column of the table; drop and re-create the view")
+	}
+	return nil
+}
@@ -0,0 +1,243 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/xDarkicex/lexer/parser"
+)
+
+func viewIDs(t *testing.T, db *Database, sql string) string {
+	t.Helper()
+	results, err := db.Query(context.Background(), sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	ids := make([]string, 0, len(results.Results))
+	for _, row := range results.Results {
+		ids = append(ids, row.ID)
+	}
+	sort.Strings(ids)
+	return strings.Join(ids, ",")
+}
+
+func TestViewQueriesFollowBaseTable(t *testing.T) {
+	db := openTempDB(t, "views_basic")
+	defer db.Close()
+
+	exec(t, db, `CREATE TABLE orders (id TEXT PRIMARY KEY, customer TEXT, total BIGINT)`)
+	exec(t, db, `INSERT INTO orders (id, customer, total) VALUES ('o1', 'ann', 40)`)
+	exec(t, db, `INSERT INTO orders (id, customer, total) VALUES ('o2', 'bob', 250)`)
+	exec(t, db, `CREATE VIEW large_orders AS SELECT id, customer, total FROM orders WHERE total > 100`)
+	exec(t, db, `CREATE VIEW bob_large AS SELECT id FROM public.large_orders WHERE customer = 'bob';`)
+
+	if got := viewIDs(t, db, `SELECT id FROM large_orders`); got != "o2" {
+		t.Fatalf("large_orders = %q, want o2", got)
+	}
+	exec(t, db, `INSERT INTO orders (id, customer, total) VALUES ('o3', 'bob', 900)`)
+	if got := viewIDs(t, db, `SELECT id FROM bob_large`); got != "o2,o3" {
+		t.Fatalf("bob_large after insert = %q, want o2,o3", got)
+	}
+	// A WITH clause in the query itself keeps working next to the view CTEs.
+	if got := viewIDs(t, db, `WITH mine AS (SELECT id FROM large_orders WHERE customer = 'bob') SELECT id FROM mine`); got != "o2,o3" {
+		t.Fatalf("query-local CTE over a view = %q, want o2,o3", got)
+	}
+
+	exec(t, db, `CREATE OR REPLACE VIEW large_orders AS SELECT id, customer, total FROM orders WHERE total > 500`)
+	if got := viewIDs(t, db, `SELECT id FROM bob_large`); got != "o3" {
+		t.Fatalf("bob_large after replace = %q, want o3", got)
+	}
+
+	alterMustFail(t, db, `CREATE VIEW large_orders AS SELECT id FROM orders`, `relation "large_orders" already exists`)
+	alterMustFail(t, db, `CREATE VIEW orders AS SELECT id FROM orders`, `relation "orders" already exists`)
+	alterMustFail(t, db, `CREATE VIEW v (a) AS SELECT id FROM orders`, "column lists are not supported")
+	alterMustFail(t, db, `CREATE OR REPLACE VIEW large_orders AS SELECT id FROM bob_large`, "defined in terms of itself")
+	alterMustFail(t, db, `INSERT INTO large_orders (id) VALUES ('x')`, `cannot insert into view "large_orders"`)
+	alterMustFail(t, db, `DELETE FROM large_orders WHERE id = 'o3'`, `cannot delete from view "large_orders"`)
+	alterMustFail(t, db, `DROP TABLE large_orders`, "use DROP VIEW")
+	alterMustFail(t, db, `DROP VIEW large_orders`, `view "bob_large" depends on it`)
+	alterMustFail(t, db, `DROP VIEW missing`, `view "missing" does not exist`)
+
+	exec(t, db, `DROP VIEW IF EXISTS missing, bob_large, large_orders`)
+	if views := db.ListViews(); len(views) != 0 {
+		t.Fatalf("views after DROP VIEW = %#v", views)
+	}
+	if _, err := db.Query(context.Background(), `SELECT id FROM large_orders`); err == nil {
+		t.Fatal("dropped view is still queryable")
+	}
+}
+
+func TestViewRelationsComeFromTheParsedQuery(t *testing.T) {
+	views := map[string]View{
+		"recent": {Name: "recent", Query: "SELECT id FROM docs WHERE age < 7"},
+		"top":    {Name: "top", Query: "WITH r AS (SELECT id FROM recent) SELECT id FROM r"},
+		"mv":     {Name: "mv", Query: "SELECT id FROM docs", Materialized: true},
+	}
+	for _, tc := range []struct {
+		sql, want string
+	}{
+		{sql: "SELECT id FROM recent", want: "recent"},
+		{sql: "SELECT a.id FROM docs a JOIN recent b ON a.id = b.id", want: "recent"},
+		{sql: "WITH x AS (SELECT id FROM top) SELECT id FROM x, mv", want: "top,mv"},
+		// Shadowed by the query's own CTE, a column and a string.
+		{sql: "WITH recent AS (SELECT 1) SELECT * FROM recent", want: ""},
+		{sql: "SELECT recent FROM docs", want: ""},
+		{sql: "SELECT id FROM docs WHERE note = 'FROM recent'", want: ""},
+	} {
+		src := []byte(tc.sql)
+		doc := &parser.QueryDoc{}
+		if err := parser.Parse(src, doc); err != nil {
+			t.Fatalf("parse %q: %v", tc.sql, err)
+		}
+		got := viewRelations(src, doc, views)
+		sort.Strings(got)
+		want := strings.Split(tc.want, ",")
+		if tc.want == "" {
+			want = nil
+		}
+		sort.Strings(want)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Fatalf("relations of %q = %v, want %v", tc.sql, got, want)
+		}
+	}
+	if got := viewDependencies(views["top"].Query, views); len(got) != 1 || got[0] != "recent" {
+		t.Fatalf("top depends on %v, want [recent]", got)
+	}
+}
+
+func TestViewReadsAreLimitedToQueries(t *testing.T) {
+	db := openTempDB(t, "views_writes")
+	defer db.Close()
+
+	exec(t, db, `CREATE TABLE docs (id TEXT PRIMARY KEY, age BIGINT)`)
+	exec(t, db, `INSERT INTO docs (id, age) VALUES ('a', 3)`)
+	exec(t, db, `INSERT INTO docs (id, age) VALUES ('b', 30)`)
+	exec(t, db, `CREATE VIEW recent AS SELECT id, age FROM docs WHERE age < 7`)
+	exec(t, db, `CREATE TABLE log (id TEXT PRIMARY KEY)`)
+
+	// The view is evaluated once per statement however often it is read.
+	if got := viewIDs(t, db, `SELECT a.id FROM recent a JOIN recent b ON a.id = b.id`); got != "a" {
+		t.Fatalf("self-join of recent = %q, want a", got)
+	}
+	alterMustFail(t, db, `INSERT INTO log (id) SELECT id FROM recent`, `view "recent" can only be read by a SELECT query`)
+	alterMustFail(t, db, `CREATE TABLE recent (id TEXT PRIMARY KEY)`, `relation "recent" already exists`)
+	alterMustFail(t, db, `ALTER TABLE recent RENAME TO old`, `"recent" is not a table`)
+	alterMustFail(t, db, `CREATE VIEW broken AS SELECT FROM WHERE`, "CREATE VIEW: parse error")
+}
+
+func TestMaterializedViewRefreshAndReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "matview.libravdb")
+	db, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	exec(t, db, `CREATE TABLE docs (id TEXT PRIMARY KEY, embedding VECTOR(3), topic TEXT, views BIGINT)`)
+	exec(t, db, `INSERT INTO docs (id, embedding, topic, views) VALUES ('a', '[1,0,0]', 'go', 10)`)
+	exec(t, db, `INSERT INTO docs (id, embedding, topic, views) VALUES ('b', '[0,1,0]', 'go', 300)`)
+	exec(t, db, `INSERT INTO docs (id, embedding, topic, views) VALUES ('c', '[0,0,1]', 'sql', 500)`)
+	exec(t, db, `CREATE MATERIALIZED VIEW popular AS SELECT id, embedding, topic FROM docs WHERE views >= 100`)
+
+	if got := viewIDs(t, db, `SELECT id FROM popular`); got != "b,c" {
+		t.Fatalf("popular = %q, want b,c", got)
+	}
+	// The materialized view is a vector table of its own.
+	nearest, err := db.Query(context.Background(), `SELECT id FROM popular ORDER BY embedding <-> '[0,0.9,0.1]' LIMIT 1`)
+	if err != nil {
+		t.Fatalf("vector search on materialized view: %v", err)
+	}
+	if len(nearest.Results) != 1 || nearest.Results[0].ID != "b" {
+		t.Fatalf("nearest = %#v, want b", nearest.Results)
+	}
+
+	exec(t, db, `UPDATE docs SET views = 1 WHERE id = 'b'`)
+	exec(t, db, `INSERT INTO docs (id, embedding, topic, views) VALUES ('d', '[1,1,0]', 'sql', 700)`)
+	if got := viewIDs(t, db, `SELECT id FROM popular`); got != "b,c" {
+		t.Fatalf("popular before refresh = %q, want b,c", got)
+	}
+	exec(t, db, `REFRESH MATERIALIZED VIEW popular`)
+	if got := viewIDs(t, db, `SELECT id FROM popular`); got != "c,d" {
+		t.Fatalf("popular after refresh = %q, want c,d", got)
+	}
+	if got := fmt.Sprint(alterField(t, db, "popular", "d", "topic")); got != "sql" {
+		t.Fatalf("popular/d topic = %s", got)
+	}
+
+	alterMustFail(t, db, `INSERT INTO popular (id, topic) VALUES ('x', 'go')`, `cannot change materialized view "popular"`)
+	alterMustFail(t, db, `DROP VIEW popular`, "use DROP MATERIALIZED VIEW")
+	alterMustFail(t, db, `CREATE OR REPLACE MATERIALIZED VIEW popular AS SELECT id FROM docs`, "OR REPLACE is not supported")
+	exec(t, db, `CREATE MATERIALIZED VIEW IF NOT EXISTS popular AS SELECT id FROM docs`)
+	exec(t, db, `CREATE MATERIALIZED VIEW sql_docs AS SELECT id, topic FROM docs WHERE topic = 'sql' WITH NO DATA`)
+	if got := viewIDs(t, db, `SELECT id FROM sql_docs`); got != "" {
+		t.Fatalf("sql_docs WITH NO DATA = %q, want empty", got)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	db, err = Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer db.Close()
+	views := db.ListViews()
+	if len(views) != 2 || views[0].Name != "popular" || !views[0].Materialized || views[1].Name != "sql_docs" {
+		t.Fatalf("views after reopen = %#v", views)
+	}
+	exec(t, db, `REFRESH MATERIALIZED VIEW sql_docs`)
+	if got := viewIDs(t, db, `SELECT id FROM sql_docs`); got != "c,d" {
+		t.Fatalf("sql_docs after refresh = %q, want c,d", got)
+	}
+	exec(t, db, `DROP MATERIALIZED VIEW popular, sql_docs`)
+	if _, err := db.GetCollection("popular"); err == nil {
+		t.Fatal("materialized view table survived DROP MATERIALIZED VIEW")
+	}
+	if views := db.ListViews(); len(views) != 0 {
+		t.Fatalf("views after drop = %#v", views)
+	}
+}
+
+func TestDropMaterializedViewsIsAtomic(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dropviews.libravdb")
+	db, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	exec(t, db, `CREATE TABLE docs (id TEXT PRIMARY KEY, topic TEXT)`)
+	exec(t, db, `INSERT INTO docs (id, topic) VALUES ('a', 'go')`)
+	exec(t, db, `CREATE MATERIALIZED VIEW go_docs AS SELECT id, topic FROM docs WHERE topic = 'go'`)
+	exec(t, db, `CREATE MATERIALIZED VIEW all_docs AS SELECT id, topic FROM docs`)
+	exec(t, db, `CREATE TABLE pins (id TEXT PRIMARY KEY, doc_id TEXT REFERENCES all_docs(id))`)
+
+	// all_docs cannot go, so go_docs must stay as well.
+	alterMustFail(t, db, `DROP MATERIALIZED VIEW go_docs, all_docs`, "foreign key constraints")
+	if views := db.ListViews(); len(views) != 2 {
+		t.Fatalf("views after failed drop = %#v", views)
+	}
+	if got := viewIDs(t, db, `SELECT id FROM go_docs`); got != "a" {
+		t.Fatalf("go_docs after failed drop = %q, want a", got)
+	}
+
+	exec(t, db, `DROP TABLE pins`)
+	exec(t, db, `DROP MATERIALIZED VIEW go_docs, all_docs`)
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	db, err = Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Reopen: %v", err)
+	}
+	defer db.Close()
+	if views := db.ListViews(); len(views) != 0 {
+		t.Fatalf("views after reopen = %#v", views)
+	}
+	for _, name := range []string{"go_docs", "all_docs"} {
+		if _, err := db.GetCollection(name); err == nil {
+			t.Fatalf("materialized view table %q survived DROP MATERIALIZED VIEW", name)
+		}
+	}
+}
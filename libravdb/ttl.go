@@ -0,0 +1,362 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/graph"
+)
+
+const (
+	// DefaultTTLField is the metadata column WithDefaultTTL stamps when no
+	// WithTTLField is configured.
+	DefaultTTLField = "expires_at"
+	// EdgeExpiryProperty is the edge property that bounds an edge's lifetime.
+	// Its value is a number of Unix seconds or an RFC 3339 timestamp.
+	EdgeExpiryProperty = graph.EdgeExpiryProperty
+
+	defaultTTLReapInterval = time.Minute
+	ttlReapBatchSize       = 256
+)
+
+// WithTTLField makes field the collection's expiry column. A record whose
+// field holds a time at or before now is hidden from reads and deleted by the
+// reaper. The value may be a time.Time, an RFC 3339 string or a number of
+// Unix seconds; a missing or NULL value never expires.
+func WithTTLField(field string) CollectionOption {
+	return func(c *CollectionConfig) error {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			return fmt.Errorf("TTL field cannot be empty")
+		}
+		c.TTLField = field
+		return nil
+	}
+}
+
+// WithDefaultTTL stamps now+d into the expiry column of every inserted or
+// upserted record that does not set it. The column is the WithTTLField
+// field, or DefaultTTLField when none is configured.
+func WithDefaultTTL(d time.Duration) CollectionOption {
+	return func(c *CollectionConfig) error {
+		if d <= 0 {
+			return fmt.Errorf("default TTL must be positive")
+		}
+		c.DefaultTTL = d
+		return nil
+	}
+}
+
+// WithTTLReapInterval sets how often the background reaper deletes expired
+// records and edges. Zero disables the reaper; ReapExpired can still be
+// called directly. The default is one minute.
+func WithTTLReapInterval(d time.Duration) Option {
+	return func(c *Config) error {
+		if d < 0 {
+			return fmt.Errorf("TTL reap interval must be non-negative")
+		}
+		c.TTLReapInterval = d
+		return nil
+	}
+}
+
+// ReapStats reports what one ReapExpired pass deleted.
+type ReapStats struct {
+	Records int
+	Edges   int
+}
+
+// ttlBypassKey marks a context whose reads must see expired records that are
+// still stored: the reaper itself, and index builds that have to cover every
+// physical row.
+type ttlBypassKey struct{}
+
+func withExpiredRecords(ctx context.Context) context.Context {
+	return context.WithValue(ctx, ttlBypassKey{}, true)
+}
+
+func expiredRecordsVisible(ctx context.Context) bool {
+	visible, _ := ctx.Value(ttlBypassKey{}).(bool)
+	return visible
+}
+
+// ttlField returns the collection's expiry column, or "" when the
+// collection has no TTL.
+func (c *Collection) ttlField() string {
+	if c == nil || c.config == nil {
+		return ""
+	}
+	if c.config.TTLField != "" {
+		return c.config.TTLField
+	}
+	if c.config.DefaultTTL > 0 {
+		return DefaultTTLField
+	}
+	return ""
+}
+
+// applyDefaultTTL stamps the default expiry into a write image. A present
+// key, including an explicit NULL, is the caller's choice and is kept.
+func (c *Collection) applyDefaultTTL(metadata map[string]interface{}) {
+	if c.config == nil || c.config.DefaultTTL <= 0 {
+		return
+	}
+	field := c.ttlField()
+	if _, ok := metadata[field]; ok {
+		return
+	}
+	metadata[field] = time.Now().Add(c.config.DefaultTTL).UTC().Format(time.RFC3339Nano)
+}
+
+// recordExpiry interprets an expiry column value.
+func recordExpiry(value interface{}) (time.Time, bool) {
+	switch v := value.(type) {
+	case nil:
+		return time.Time{}, false
+	case time.Time:
+		return v, true
+	case string:
+		text := strings.TrimSpace(v)
+		if t, ok := parseAlterTime(text); ok {
+			return t, true
+		}
+	}
+	seconds, ok := toFloat(value)
+	if !ok || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return time.Time{}, false
+	}
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*float64(time.Second))), true
+}
+
+func (c *Collection) recordExpired(ctx context.Context, metadata map[string]interface{}, now time.Time) bool {
+	field := c.ttlField()
+	if field == "" || expiredRecordsVisible(ctx) {
+		return false
+	}
+	expiry, ok := recordExpiry(metadata[field])
+	return ok && !expiry.After(now)
+}
+
+// liveRecords drops expired records from a read result in place.
+func (c *Collection) liveRecords(ctx context.Context, records []Record) []Record {
+	if c.ttlField() == "" || expiredRecordsVisible(ctx) {
+		return records
+	}
+	now := time.Now()
+	live := records[:0]
+	for _, record := range records {
+		if !c.recordExpired(ctx, record.Metadata, now) {
+			live = append(live, record)
+		}
+	}
+	return live
+}
+
+// liveSearchResults drops expired records from search results in place.
+func (c *Collection) liveSearchResults(ctx context.Context, results []*SearchResult) []*SearchResult {
+	if c.ttlField() == "" || expiredRecordsVisible(ctx) {
+		return results
+	}
+	now := time.Now()
+	live := results[:0]
+	for _, result := range results {
+		if result == nil || !c.recordExpired(ctx, result.Metadata, now) {
+			live = append(live, result)
+		}
+	}
+	return live
+}
+
+// ReapExpired deletes every expired record and edge. Records are deleted
+// through ordinary transactions, so foreign key actions, graph node drops and
+// temporal history behave exactly as for an explicit DELETE. A record
+// rewritten after it was scanned is left for the next pass.
+func (db *Database) ReapExpired(ctx context.Context) (ReapStats, error) {
+	var stats ReapStats
+	db.mu.RLock()
+	if db.closed {
+		db.mu.RUnlock()
+		return stats, ErrDatabaseClosed
+	}
+	collections := make([]*Collection, 0, len(db.collections))
+	for _, col := range db.collections {
+		collections = append(collections, col)
+	}
+	db.mu.RUnlock()
+	sort.Slice(collections, func(i, j int) bool { return collections[i].name < collections[j].name })
+
+	ctx = withExpiredRecords(ctx)
+	var firstErr error
+	for _, col := range collections {
+		n, err := db.reapExpiredRecords(ctx, col)
+		stats.Records += n
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("reap collection %q: %w", col.name, err)
+		}
+	}
+	seen := make(map[Graph]struct{})
+	for _, col := range collections {
+		g := col.GetGraph()
+		if g == nil {
+			continue
+		}
+		shared := g
+		if wrapped, ok := g.(*collectionGraph); ok {
+			shared = wrapped.Graph
+		}
+		if _, ok := seen[shared]; ok {
+			continue
+		}
+		seen[shared] = struct{}{}
+		n, err := reapExpiredEdges(ctx, g)
+		stats.Edges += n
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("reap graph edges of %q: %w", col.name, err)
+		}
+	}
+	return stats, firstErr
+}
+
+func (db *Database) reapExpiredRecords(ctx context.Context, col *Collection) (int, error) {
+	field := col.ttlField()
+	if field == "" {
+		return 0, nil
+	}
+	type expiredRecord struct {
+		id      string
+		version uint64
+	}
+	now := time.Now()
+	var expired []expiredRecord
+	err := col.Iterate(ctx, func(record Record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if expiry, ok := recordExpiry(record.Metadata[field]); ok && !expiry.After(now) {
+			expired = append(expired, expiredRecord{id: record.ID, version: record.Version})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	deleteOne := func(record expiredRecord) error {
+		return db.WithTx(ctx, func(tx Tx) error {
+			return tx.DeleteIfVersion(ctx, col.name, record.id, record.version)
+		})
+	}
+	reaped := 0
+	var firstErr error
+	for start := 0; start < len(expired); start += ttlReapBatchSize {
+		batch := expired[start:min(start+ttlReapBatchSize, len(expired))]
+		err := db.WithTx(ctx, func(tx Tx) error {
+			for _, record := range batch {
+				if err := tx.DeleteIfVersion(ctx, col.name, record.id, record.version); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err == nil {
+			reaped += len(batch)
+			continue
+		}
+		if ctx.Err() != nil {
+			return reaped, ctx.Err()
+		}
+		// One record rewritten or deleted since the scan fails the whole
+		// batch; retry individually so the rest are still reaped.
+		for _, record := range batch {
+			switch err := deleteOne(record); {
+			case err == nil:
+				reaped++
+			case errors.Is(err, ErrVersionConflict), errors.Is(err, ErrRecordNotFound):
+			default:
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+		}
+	}
+	return reaped, firstErr
+}
+
+func reapExpiredEdges(ctx context.Context, g Graph) (int, error) {
+	lister, ok := g.(interface {
+		ExpiredEdges(time.Time) ([]graph.ExpiredEdge, error)
+	})
+	if !ok {
+		return 0, nil
+	}
+	expired, err := lister.ExpiredEdges(time.Now())
+	if err != nil || len(expired) == 0 {
+		return 0, err
+	}
+	txn := g.BeginTxn()
+	for _, edge := range expired {
+		if err := txn.RemoveEdge(edge.Src, edge.Tgt, edge.Kind); err != nil {
+			_ = txn.Rollback()
+			return 0, fmt.Errorf("staging expired edge delete: %w", err)
+		}
+	}
+	if err := txn.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing expired edge delete: %w", err)
+	}
+	return len(expired), nil
+}
+
+// ttlReaper runs ReapExpired on a fixed interval until stopped.
+type ttlReaper struct {
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+func (db *Database) startTTLReaper(interval time.Duration) {
+	r := &ttlReaper{stop: make(chan struct{}), done: make(chan struct{})}
+	db.ttlReaper = r
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-r.stop:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			_, err := db.ReapExpired(ctx)
+			cancel()
+			if err != nil && !errors.Is(err, ErrDatabaseClosed) && !errors.Is(err, context.Canceled) && db.logger != nil {
+				db.logger.Printf("libravdb: ttl reaper: %v", err)
+			}
+		}
+	}()
+}
+
+// stopTTLReaper stops the background reaper and waits for an in-flight pass.
+// It must be called without db.mu held, since a pass takes the read lock.
+func (db *Database) stopTTLReaper() {
+	r := db.ttlReaper
+	if r == nil {
+		return
+	}
+	r.once.Do(func() { close(r.stop) })
+	<-r.done
+}
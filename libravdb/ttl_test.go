@@ -0,0 +1,217 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func openTTLTestDB(t *testing.T, path string, opts ...Option) *Database {
+	t.Helper()
+	db, err := Open(append([]Option{WithStoragePath(path), WithMetrics(false), WithTTLReapInterval(0)}, opts...)...)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return db
+}
+
+func TestTTLHidesAndReapsExpiredRecords(t *testing.T) {
+	ctx := context.Background()
+	db := openTTLTestDB(t, ":memory:ttl_records")
+	defer db.Close()
+	g, err := NewGraph(GraphConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer g.Close()
+	col, err := db.CreateCollection(ctx, "memories", WithDimension(3), WithFlat(), WithGraph(g), WithDefaultTTL(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	past := time.Now().Add(-time.Minute).UTC().Format(time.RFC3339)
+	for _, record := range []struct {
+		id       string
+		vector   []float32
+		metadata map[string]interface{}
+	}{
+		{"fresh", []float32{1, 0, 0}, map[string]interface{}{"topic": "plans"}},
+		{"stale", []float32{1, 0.1, 0}, map[string]interface{}{"topic": "plans", DefaultTTLField: past}},
+		{"pinned", []float32{0, 1, 0}, map[string]interface{}{"topic": "facts", DefaultTTLField: nil}},
+	} {
+		if err := col.Insert(ctx, record.id, record.vector, record.metadata); err != nil {
+			t.Fatalf("Insert(%s): %v", record.id, err)
+		}
+	}
+
+	fresh, err := col.Get(ctx, "fresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expiry, ok := recordExpiry(fresh.Metadata[DefaultTTLField]); !ok || expiry.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("default TTL stamped %v", fresh.Metadata[DefaultTTLField])
+	}
+	if _, err := col.Get(ctx, "stale"); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("Get(stale) error = %v, want ErrRecordNotFound", err)
+	}
+	if got := viewIDs(t, db, "SELECT id FROM memories"); got != "fresh,pinned" {
+		t.Fatalf("SELECT ids = %s", got)
+	}
+	results, err := col.Search(ctx, []float32{1, 0, 0}, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results.Results {
+		if result.ID == "stale" {
+			t.Fatal("search returned an expired record")
+		}
+	}
+
+	node := func(id string) uint64 {
+		nodeID, err := db.GetNodeID(ctx, "memories", id)
+		if err != nil {
+			t.Fatalf("GetNodeID(%s): %v", id, err)
+		}
+		return nodeID
+	}
+	txn := g.BeginTxn()
+	if err := txn.AddEdge(node("fresh"), node("stale"), 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.AddEdgeWithProperties(node("fresh"), node("pinned"), 1, 1, map[string]interface{}{EdgeExpiryProperty: past}); err != nil {
+		t.Fatal(err)
+	}
+	if err := txn.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if edges, err := g.Neighbors(node("fresh")); err != nil || len(edges) != 1 {
+		t.Fatalf("live neighbors before reaping = %v, %v; want only the edge to stale", edges, err)
+	}
+
+	stats, err := db.ReapExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats != (ReapStats{Records: 1, Edges: 1}) {
+		t.Fatalf("ReapExpired = %+v, want 1 record and 1 edge", stats)
+	}
+	if _, err := col.Get(withExpiredRecords(ctx), "stale"); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("reaped record is still stored: %v", err)
+	}
+	if edges, err := g.Neighbors(node("fresh")); err != nil || len(edges) != 0 {
+		t.Fatalf("neighbors after reaping = %v, %v; want none", edges, err)
+	}
+	if count, err := col.Count(ctx); err != nil || count != 2 {
+		t.Fatalf("Count after reaping = %d, %v; want 2", count, err)
+	}
+	if stats, err := db.ReapExpired(ctx); err != nil || stats != (ReapStats{}) {
+		t.Fatalf("second ReapExpired = %+v, %v; want nothing", stats, err)
+	}
+}
+
+func TestReapExpiredCascadesForeignKeys(t *testing.T) {
+	ctx := context.Background()
+	db := openTTLTestDB(t, ":memory:ttl_cascade")
+	defer db.Close()
+	sessions, err := db.CreateCollection(ctx, "sessions", WithMetadataOnly(), WithTTLField("ends_at"),
+		WithMetadataSchema(MetadataSchema{"ends_at": StringField}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, "CREATE TABLE messages (id TEXT PRIMARY KEY, session_id TEXT REFERENCES sessions(id) ON DELETE CASCADE)")
+	if err := sessions.Insert(ctx, "s1", nil, map[string]interface{}{"ends_at": time.Now().Add(-time.Second).UTC().Format(time.RFC3339Nano)}); err != nil {
+		t.Fatal(err)
+	}
+	if err := sessions.Insert(ctx, "s2", nil, map[string]interface{}{"ends_at": float64(time.Now().Add(time.Hour).Unix())}); err != nil {
+		t.Fatal(err)
+	}
+	exec(t, db, "INSERT INTO messages (id, session_id) VALUES ('m1', 's2')")
+	exec(t, db, "INSERT INTO messages (id, session_id) VALUES ('m2', 's2')")
+	messages := getColl(t, db, "messages")
+	if err := messages.Insert(withExpiredRecords(ctx), "m0", nil, map[string]interface{}{"session_id": "s1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := db.ReapExpired(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Records != 1 {
+		t.Fatalf("ReapExpired records = %d, want 1", stats.Records)
+	}
+	if got := viewIDs(t, db, "SELECT id FROM sessions"); got != "s2" {
+		t.Fatalf("sessions = %s, want s2", got)
+	}
+	if got := viewIDs(t, db, "SELECT id FROM messages"); got != "m1,m2" {
+		t.Fatalf("messages = %s, want the expired session's message cascaded away", got)
+	}
+}
+
+func TestTTLConfigurationSurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/ttl.libravdb"
+	db := openTTLTestDB(t, path)
+	if _, err := db.CreateCollection(ctx, "context", WithMetadataOnly(), WithTTLField("valid_until"), WithDefaultTTL(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db = openTTLTestDB(t, path)
+	defer db.Close()
+	col := getColl(t, db, "context")
+	if col.config.TTLField != "valid_until" || col.config.DefaultTTL != 2*time.Hour {
+		t.Fatalf("reopened TTL config = %q, %v", col.config.TTLField, col.config.DefaultTTL)
+	}
+	if err := col.Insert(ctx, "c1", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	record, err := col.Get(ctx, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := recordExpiry(record.Metadata["valid_until"]); !ok {
+		t.Fatalf("reopened collection did not stamp valid_until: %v", record.Metadata)
+	}
+}
+
+func TestBackgroundReaperDeletesExpiredRecords(t *testing.T) {
+	ctx := context.Background()
+	db := openTTLTestDB(t, ":memory:ttl_background", WithTTLReapInterval(10*time.Millisecond))
+	defer db.Close()
+	col, err := db.CreateCollection(ctx, "scratch", WithMetadataOnly(), WithDefaultTTL(time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := col.Insert(ctx, "tmp", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		count, err := col.Count(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background reaper did not delete the expired record")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTTLOptionValidation(t *testing.T) {
+	var config CollectionConfig
+	if err := WithTTLField(" ")(&config); err == nil {
+		t.Fatal("WithTTLField accepted an empty field")
+	}
+	if err := WithDefaultTTL(0)(&config); err == nil {
+		t.Fatal("WithDefaultTTL accepted a zero duration")
+	}
+	if err := WithTTLReapInterval(-time.Second)(&Config{}); err == nil {
+		t.Fatal("WithTTLReapInterval accepted a negative interval")
+	}
+}
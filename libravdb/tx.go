@@ -152,6 +152,9 @@ type txMutation struct {
 	expectedVersion    uint64
 	kind               txMutationKind
 	hasExpectedVersion bool
+	// regenerate hands id to the collection's asynchronous indexer once the
+	// transaction commits, to embed its generated vector again.
+	regenerate bool
 }
 
 type transaction struct {
@@ -275,6 +278,10 @@ func (db *Database) WithTxReceipt(ctx context.Context, fn func(tx ReceiptTx) err
 
 func (tx *transaction) Insert(ctx context.Context, collection, id string, vector []float32, metadata map[string]interface{}) error {
 	ctx = withTransactionContext(ctx, tx)
+	vector, err := tx.generateStagedVector(ctx, collection, vector, metadata)
+	if err != nil {
+		return err
+	}
 	if err := tx.validateStage(ctx, collection, id, vector, true); err != nil {
 		return err
 	}
@@ -303,6 +310,10 @@ func (tx *transaction) Insert(ctx context.Context, collection, id string, vector
 
 func (tx *transaction) InsertOwned(ctx context.Context, collection, id string, vector []float32, metadata map[string]interface{}) error {
 	ctx = withTransactionContext(ctx, tx)
+	vector, err := tx.generateStagedVector(ctx, collection, vector, metadata)
+	if err != nil {
+		return err
+	}
 	if err := tx.validateStage(ctx, collection, id, vector, true); err != nil {
 		return err
 	}
@@ -339,6 +350,10 @@ func (tx *transaction) UpdateOwned(ctx context.Context, collection, id string, v
 
 func (tx *transaction) Upsert(ctx context.Context, collection, id string, vector []float32, metadata map[string]interface{}) error {
 	ctx = withTransactionContext(ctx, tx)
+	vector, err := tx.generateStagedVector(ctx, collection, vector, metadata)
+	if err != nil {
+		return err
+	}
 	if err := tx.validateStage(ctx, collection, id, vector, true); err != nil {
 		return err
 	}
@@ -365,6 +380,20 @@ func (tx *transaction) Upsert(ctx context.Context, collection, id string, vector
 	})
 }
 
+// generateStagedVector embeds the generated vector of an insert that omits
+// it, before staging validation requires a vector. An unknown collection is
+// left for validateStage to report.
+func (tx *transaction) generateStagedVector(ctx context.Context, collection string, vector []float32, metadata map[string]interface{}) ([]float32, error) {
+	if vector != nil {
+		return vector, nil
+	}
+	coll, err := tx.db.GetCollection(collection)
+	if err != nil {
+		return nil, nil
+	}
+	return coll.generateVector(ctx, vector, metadata)
+}
+
 // prepareInsertMetadata creates the transaction-owned metadata image and
 // applies schema defaults and CHECK constraints before any FK/UNIQUE check or
 // mutation-log append. The caller's map is never mutated.
@@ -410,9 +439,6 @@ func (tx *transaction) Rename(ctx context.Context, collection, oldID, newID stri
 	if _, err := tx.currentRecord(ctx, collection, newID); err == nil {
 		return fmt.Errorf("%w: record %s already exists in collection %s", ErrTxConflict, newID, collection)
 	}
-	if vector == nil {
-		vector = old.Vector
-	}
 	renameMetadata := cloneMetadata(old.Metadata)
 	for key, value := range metadata {
 		if renameMetadata == nil {
@@ -420,6 +446,13 @@ func (tx *transaction) Rename(ctx context.Context, collection, oldID, newID stri
 		}
 		renameMetadata[key] = cloneMetadataValue(value)
 	}
+	vector, regenerate, err := coll.regenerateVector(ctx, vector, old.Metadata, renameMetadata)
+	if err != nil {
+		return err
+	}
+	if vector == nil {
+		vector = old.Vector
+	}
 	if err := coll.validateNotNullConstraints(renameMetadata); err != nil {
 		return err
 	}
@@ -437,7 +470,7 @@ func (tx *transaction) Rename(ctx context.Context, collection, oldID, newID stri
 		return err
 	}
 	graphNodeID, _ := tx.db.GetNodeID(ctx, collection, oldID)
-	if err := tx.append(txMutation{kind: txMutationRename, collection: collection, id: newID, oldID: oldID, graphNodeID: graphNodeID, vector: cloneVector(vector), metadata: cloneMetadata(renameMetadata)}); err != nil {
+	if err := tx.append(txMutation{kind: txMutationRename, collection: collection, id: newID, oldID: oldID, graphNodeID: graphNodeID, vector: cloneVector(vector), metadata: cloneMetadata(renameMetadata), regenerate: regenerate}); err != nil {
 		return err
 	}
 	for _, cascade := range cascades {
@@ -601,6 +634,10 @@ func (tx *transaction) update(ctx context.Context, collection, id string, vector
 		}
 		merged[k] = cloneMetadataValue(v)
 	}
+	vector, regenerate, err := coll.regenerateVector(ctx, vector, existing.Metadata, merged)
+	if err != nil {
+		return err
+	}
 	if err := coll.validateNotNullConstraints(merged); err != nil {
 		return err
 	}
@@ -621,6 +658,12 @@ func (tx *transaction) update(ctx context.Context, collection, id string, vector
 	if err := coll.validateJSONFields(preparedDelta); err != nil {
 		return err
 	}
+	if err := coll.validateSparseVectorFields(preparedDelta); err != nil {
+		return err
+	}
+	if err := coll.validateVectorSpaceFields(preparedDelta); err != nil {
+		return err
+	}
 	if err := tx.append(txMutation{
 		kind:               txMutationUpdate,
 		collection:         collection,
@@ -629,6 +672,7 @@ func (tx *transaction) update(ctx context.Context, collection, id string, vector
 		metadata:           preparedDelta,
 		hasExpectedVersion: hasExpectedVersion,
 		expectedVersion:    expectedVersion,
+		regenerate:         regenerate,
 	}); err != nil {
 		return err
 	}
@@ -701,6 +745,10 @@ func (tx *transaction) updateOwned(ctx context.Context, collection, id string, v
 		}
 		merged[k] = cloneMetadataValue(v)
 	}
+	vector, regenerate, err := coll.regenerateVector(ctx, vector, existing.Metadata, merged)
+	if err != nil {
+		return err
+	}
 	if err := coll.validateNotNullConstraints(merged); err != nil {
 		return err
 	}
@@ -717,6 +765,12 @@ func (tx *transaction) updateOwned(ctx context.Context, collection, id string, v
 	if err := coll.validateJSONFields(preparedDelta); err != nil {
 		return err
 	}
+	if err := coll.validateSparseVectorFields(preparedDelta); err != nil {
+		return err
+	}
+	if err := coll.validateVectorSpaceFields(preparedDelta); err != nil {
+		return err
+	}
 	return tx.append(txMutation{
 		kind:               txMutationUpdate,
 		collection:         collection,
@@ -725,6 +779,7 @@ func (tx *transaction) updateOwned(ctx context.Context, collection, id string, v
 		metadata:           preparedDelta,
 		hasExpectedVersion: hasExpectedVersion,
 		expectedVersion:    expectedVersion,
+		regenerate:         regenerate,
 	})
 }
 
@@ -1059,6 +1114,14 @@ func (tx *transaction) commitWithReceipt(ctx context.Context, receipt *CommitRec
 	tx.mu.Lock()
 	tx.committed = true
 	tx.mu.Unlock()
+	for _, op := range ops {
+		if !op.regenerate {
+			continue
+		}
+		if coll, err := tx.db.GetCollection(op.collection); err == nil && coll.asyncIndex != nil {
+			coll.asyncIndex.regenerate(op.id)
+		}
+	}
 
 	if tx.db.metrics != nil {
 		tx.db.metrics.TxCommits.Inc()
@@ -1310,6 +1373,19 @@ func (db *Database) commitTxWithGraphReceipt(ctx context.Context, ops []txMutati
 	}
 	defer closeIndexes(newIndexes)
 
+	// The committed writes reach the full-text postings below.
+	for _, name := range names {
+		collection := collections[name]
+		if parentName, _, isShard := parseShardName(name); isShard {
+			if parent, err := db.GetCollection(parentName); err == nil {
+				collection = parent
+			}
+		}
+		if collection != nil {
+			defer collection.beginFullTextWrite()()
+		}
+	}
+
 	// Append graph ops to the same WAL transaction.
 	combinedOps := preparedOps
 	if len(graphOps) > 0 {
@@ -1353,6 +1429,27 @@ func (db *Database) commitTxWithGraphReceipt(ctx context.Context, ops []txMutati
 		}
 		collection.markMetadataIndexDirty()
 	}
+	for _, op := range preparedOps {
+		collection := collections[op.Collection]
+		if parentName, _, isShard := parseShardName(op.Collection); isShard {
+			if parent, err := db.GetCollection(parentName); err == nil {
+				collection = parent
+			}
+		}
+		if collection == nil {
+			continue
+		}
+		switch op.Type {
+		case storage.TxOperationPut:
+			collection.noteFullTextWrite(op.ID, op.Metadata)
+			collection.noteVectorSpaceWrite(op.ID, op.Metadata)
+			collection.noteSparseWrite(op.ID, op.Metadata)
+		case storage.TxOperationDelete:
+			collection.noteFullTextDelete(op.ID)
+			collection.noteVectorSpaceDelete(op.ID)
+			collection.noteSparseDelete(op.ID)
+		}
+	}
 
 	hasCAS := false
 	for _, op := range preparedOps {
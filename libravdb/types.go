@@ -38,10 +38,13 @@ type SearchResult struct {
 // are record IDs in traversal order; EdgeTypes and EdgeWeights are aligned
 // with the hop between Nodes[i] and Nodes[i+1]. It is used when a MATCH path
 // variable is projected (for example `p = (...)` followed by SELECT p).
+// Cost is the accumulated edge cost of a weightedShortestPath() path and is
+// zero for hop-counted paths.
 type GraphPath struct {
 	Nodes       []string  `json:"nodes"`
 	EdgeTypes   []string  `json:"edge_types,omitempty"`
 	EdgeWeights []float32 `json:"edge_weights,omitempty"`
+	Cost        float64   `json:"cost,omitempty"`
 }
 
 // SearchResults represents the complete search response.
@@ -70,20 +73,21 @@ type DatabaseStats struct {
 
 // CollectionStats represents collection-specific statistics
 type CollectionStats struct {
-	MemoryStats          *CollectionMemoryStats `json:"memory_stats,omitempty"`
-	OptimizationStatus   *OptimizationStatus    `json:"optimization_status,omitempty"`
-	RawVectorStoreStats  *RawVectorStoreStats   `json:"raw_vector_store_stats,omitempty"`
-	IndexType            string                 `json:"index_type"`
-	Name                 string                 `json:"name"`
-	MemoryUsage          int64                  `json:"memory_usage"`
-	Dimension            int                    `json:"dimension"`
-	VectorCount          int                    `json:"vector_count"`
-	LiveRecordCount      int                    `json:"live_record_count"`
-	OrdinalUtilization   float64                `json:"ordinal_utilization"`
-	NextOrdinal          uint32                 `json:"next_ordinal"`
-	HasQuantization      bool                   `json:"has_quantization"`
-	HasMemoryLimit       bool                   `json:"has_memory_limit"`
-	MemoryMappingEnabled bool                   `json:"memory_mapping_enabled"`
+	MemoryStats           *CollectionMemoryStats `json:"memory_stats,omitempty"`
+	OptimizationStatus    *OptimizationStatus    `json:"optimization_status,omitempty"`
+	RawVectorStoreStats   *RawVectorStoreStats   `json:"raw_vector_store_stats,omitempty"`
+	Int8ConstructionStats *Int8ConstructionStats `json:"int8_construction_stats,omitempty"`
+	IndexType             string                 `json:"index_type"`
+	Name                  string                 `json:"name"`
+	MemoryUsage           int64                  `json:"memory_usage"`
+	Dimension             int                    `json:"dimension"`
+	VectorCount           int                    `json:"vector_count"`
+	LiveRecordCount       int                    `json:"live_record_count"`
+	OrdinalUtilization    float64                `json:"ordinal_utilization"`
+	NextOrdinal           uint32                 `json:"next_ordinal"`
+	HasQuantization       bool                   `json:"has_quantization"`
+	HasMemoryLimit        bool                   `json:"has_memory_limit"`
+	MemoryMappingEnabled  bool                   `json:"memory_mapping_enabled"`
 }
 
 type RawVectorStoreStats struct {
@@ -101,6 +105,16 @@ type RawVectorStoreStats struct {
 	CapacityUtilization float64 `json:"capacity_utilization"`
 }
 
+// Int8ConstructionStats reports how often HNSW int8 bounds settled a beam
+// candidate without an FP32 distance. FallbackRate is Fallbacks / Checks.
+type Int8ConstructionStats struct {
+	Trained      bool    `json:"trained"`
+	Checks       int64   `json:"checks"`
+	Pruned       int64   `json:"pruned"`
+	Fallbacks    int64   `json:"fallbacks"`
+	FallbackRate float64 `json:"fallback_rate"`
+}
+
 func (it IndexType) String() string {
 	switch it {
 	case HNSW:
@@ -109,6 +123,8 @@ func (it IndexType) String() string {
 		return "IVF-PQ"
 	case Flat:
 		return "Flat"
+	case DiskANN:
+		return "DiskANN"
 	default:
 		return "Unknown"
 	}
@@ -247,6 +263,54 @@ type OptimizationOptions struct {
 	ForceIndexTypeSwitch bool `json:"force_index_type_switch"`
 }
 
+// RetrainOptions configures online IVF-PQ retraining
+type RetrainOptions struct {
+	// SampleSize is the number of canonical vectors used to measure drift and
+	// to train. Zero selects max(64*NClusters, 4096), capped at the record count.
+	SampleSize int `json:"sample_size"`
+
+	// MaxSizeSkew is the largest cluster size over the mean size tolerated
+	// before clusters are rebalanced. Zero selects 4.
+	MaxSizeSkew float64 `json:"max_size_skew"`
+
+	// MaxErrorGrowth is the ratio of sampled to trained error tolerated
+	// before the index is retrained. Zero selects 1.5.
+	MaxErrorGrowth float64 `json:"max_error_growth"`
+
+	// Force retrains regardless of the measured drift
+	Force bool `json:"force"`
+
+	// RebalanceOnly splits oversized clusters into undersized slots instead
+	// of retraining, keeping the fine quantizer and unaffected codes
+	RebalanceOnly bool `json:"rebalance_only"`
+}
+
+// IndexDrift describes how far an IVF-PQ index has drifted from the state it
+// was trained on. Trained errors are zero when no baseline is known.
+type IndexDrift struct {
+	Generation               uint64  `json:"generation"`
+	Clusters                 int     `json:"clusters"`
+	Vectors                  int     `json:"vectors"`
+	EmptyClusters            int     `json:"empty_clusters"`
+	MinClusterSize           int     `json:"min_cluster_size"`
+	MaxClusterSize           int     `json:"max_cluster_size"`
+	SizeSkew                 float64 `json:"size_skew"`
+	CoarseError              float64 `json:"coarse_error"`
+	QuantizationError        float64 `json:"quantization_error"`
+	TrainedCoarseError       float64 `json:"trained_coarse_error"`
+	TrainedQuantizationError float64 `json:"trained_quantization_error"`
+	ErrorGrowth              float64 `json:"error_growth"`
+}
+
+// RetrainResult reports the drift measured before and after RetrainIndex and
+// the action taken
+type RetrainResult struct {
+	Before    *IndexDrift `json:"before"`
+	After     *IndexDrift `json:"after,omitempty"`
+	Retrained bool        `json:"retrained"`
+	Splits    int         `json:"splits"`
+}
+
 // OptimizationStatus represents the current optimization state of a collection
 type OptimizationStatus struct {
 	LastOptimization         time.Time `json:"last_optimization"`
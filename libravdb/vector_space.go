@@ -0,0 +1,670 @@
+package libravdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xDarkicex/libravdb/internal/index"
+	"github.com/xDarkicex/libravdb/internal/storage"
+	"github.com/xDarkicex/libravdb/internal/util"
+)
+
+// VectorSpaceDefinition declares a named dense vector space. A record's
+// vector in the space is carried in its metadata under Name, next to the
+// collection's primary vector, and is searched through a dedicated HNSW
+// graph. Zero HNSW parameters inherit the collection's M, EfConstruction and
+// EfSearch when the collection is created.
+//
+// The declaration is persisted with the collection. The vectors themselves
+// are stored canonically as pgvector text, so they persist through the
+// ordinary record WAL; the graph is derived from them on first use after open
+// and is maintained by every committed write afterwards.
+type VectorSpaceDefinition struct {
+	Name           string         `json:"name"`
+	Dimension      int            `json:"dimension"`
+	Metric         DistanceMetric `json:"metric"`
+	M              int            `json:"m,omitempty"`
+	EfConstruction int            `json:"ef_construction,omitempty"`
+	EfSearch       int            `json:"ef_search,omitempty"`
+}
+
+// VectorSpaceOption configures the index of one vector space.
+type VectorSpaceOption func(*VectorSpaceDefinition) error
+
+// WithSpaceHNSW sets the HNSW parameters of a vector space's graph.
+func WithSpaceHNSW(m, efConstruction, efSearch int) VectorSpaceOption {
+	return func(d *VectorSpaceDefinition) error {
+		if m <= 0 || efConstruction <= 0 || efSearch <= 0 {
+			return fmt.Errorf("HNSW parameters must be positive")
+		}
+		d.M = m
+		d.EfConstruction = efConstruction
+		d.EfSearch = efSearch
+		return nil
+	}
+}
+
+// WithVectorSpace declares a named vector space of the given dimension and
+// metric. Values are written as metadata under name and may be supplied as
+// []float32, []float64, []interface{} of numbers, or a pgvector text literal
+// such as "[0.1,0.2,0.3]". A collection that only searches named spaces can
+// combine this with WithMetadataOnly.
+func WithVectorSpace(name string, dimension int, metric DistanceMetric, opts ...VectorSpaceOption) CollectionOption {
+	return func(c *CollectionConfig) error {
+		definition := VectorSpaceDefinition{Name: strings.TrimSpace(name), Dimension: dimension, Metric: metric}
+		if definition.Name == "" {
+			return fmt.Errorf("vector space name must not be empty")
+		}
+		if strings.EqualFold(definition.Name, "id") {
+			return fmt.Errorf("vector space name %q is reserved", definition.Name)
+		}
+		if dimension <= 0 {
+			return fmt.Errorf("vector space %q: dimension must be positive, got %d", definition.Name, dimension)
+		}
+		switch metric {
+		case L2Distance, InnerProduct, CosineDistance:
+		default:
+			return fmt.Errorf("vector space %q: unsupported distance metric %d", definition.Name, metric)
+		}
+		for _, opt := range opts {
+			if err := opt(&definition); err != nil {
+				return fmt.Errorf("vector space %q: %w", definition.Name, err)
+			}
+		}
+		for _, existing := range c.VectorSpaces {
+			if strings.EqualFold(existing.Name, definition.Name) {
+				return fmt.Errorf("vector space %q is declared more than once", definition.Name)
+			}
+		}
+		c.VectorSpaces = append(c.VectorSpaces, definition)
+		return nil
+	}
+}
+
+// resolveVectorSpaces fills inherited HNSW parameters once every option has
+// been applied and rejects spaces that collide with sparse-vector fields.
+func (config *CollectionConfig) resolveVectorSpaces() error {
+	for i := range config.VectorSpaces {
+		space := &config.VectorSpaces[i]
+		for field := range config.SparseVectors {
+			if strings.EqualFold(field, space.Name) {
+				return fmt.Errorf("vector space %q is also declared as a sparse vector field", space.Name)
+			}
+		}
+		if space.M == 0 {
+			space.M = config.M
+		}
+		if space.EfConstruction == 0 {
+			space.EfConstruction = config.EfConstruction
+		}
+		if space.EfSearch == 0 {
+			space.EfSearch = config.EfSearch
+		}
+	}
+	return nil
+}
+
+func vectorSpacesToStorage(spaces []VectorSpaceDefinition) []storage.VectorSpaceDefinition {
+	if len(spaces) == 0 {
+		return nil
+	}
+	converted := make([]storage.VectorSpaceDefinition, len(spaces))
+	for i, space := range spaces {
+		converted[i] = storage.VectorSpaceDefinition{
+			Name: space.Name, Dimension: space.Dimension, Metric: int(space.Metric),
+			M: space.M, EfConstruction: space.EfConstruction, EfSearch: space.EfSearch,
+		}
+	}
+	return converted
+}
+
+func vectorSpacesFromStorage(spaces []storage.VectorSpaceDefinition) []VectorSpaceDefinition {
+	if len(spaces) == 0 {
+		return nil
+	}
+	converted := make([]VectorSpaceDefinition, len(spaces))
+	for i, space := range spaces {
+		converted[i] = VectorSpaceDefinition{
+			Name: space.Name, Dimension: space.Dimension, Metric: DistanceMetric(space.Metric),
+			M: space.M, EfConstruction: space.EfConstruction, EfSearch: space.EfSearch,
+		}
+	}
+	return converted
+}
+
+// vectorSpace resolves a declared vector space case-insensitively.
+func (c *Collection) vectorSpace(name string) (VectorSpaceDefinition, bool) {
+	if c == nil || c.config == nil {
+		return VectorSpaceDefinition{}, false
+	}
+	for _, space := range c.config.VectorSpaces {
+		if strings.EqualFold(space.Name, name) {
+			return space, true
+		}
+	}
+	return VectorSpaceDefinition{}, false
+}
+
+// validateVectorSpaceFields checks values written to vector space fields
+// against their declared dimension and replaces them with the canonical text
+// form. Missing and NULL values are left to the NOT NULL validation path.
+func (c *Collection) validateVectorSpaceFields(metadata map[string]interface{}) error {
+	if c == nil || c.config == nil || len(c.config.VectorSpaces) == 0 {
+		return nil
+	}
+	for _, space := range c.config.VectorSpaces {
+		for key, value := range metadata {
+			if !strings.EqualFold(key, space.Name) || value == nil {
+				continue
+			}
+			vector, err := vectorSpaceValue(value, space.Dimension)
+			if err != nil {
+				return fmt.Errorf("invalid vector for space %q: %w", space.Name, err)
+			}
+			metadata[key] = formatConflictVector(vector)
+			break
+		}
+	}
+	return nil
+}
+
+func vectorSpaceValue(value interface{}, dimension int) ([]float32, error) {
+	var vector []float32
+	switch v := value.(type) {
+	case []float32:
+		vector = append([]float32(nil), v...)
+	case []float64:
+		vector = make([]float32, len(v))
+		for i, f := range v {
+			vector[i] = float32(f)
+		}
+	case []interface{}:
+		vector = make([]float32, len(v))
+		for i, element := range v {
+			f, ok := toFloat(element)
+			if !ok {
+				return nil, fmt.Errorf("element %d has unsupported type %T", i, element)
+			}
+			vector[i] = float32(f)
+		}
+	case string:
+		if vector = parseVectorLiteral(strings.TrimSpace(v)); vector == nil {
+			return nil, fmt.Errorf("malformed vector literal %q", v)
+		}
+	case []byte:
+		if vector = parseVectorLiteral(strings.TrimSpace(string(v))); vector == nil {
+			return nil, fmt.Errorf("malformed vector literal %q", v)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported vector value of type %T", value)
+	}
+	if len(vector) != dimension {
+		return nil, fmt.Errorf("expected %d dimensions, not %d", dimension, len(vector))
+	}
+	for _, f := range vector {
+		if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+			return nil, fmt.Errorf("vector contains a non-finite value")
+		}
+	}
+	return vector, nil
+}
+
+// recordSpaceVector returns the record's vector in space, or nil when the
+// record has none.
+func recordSpaceVector(metadata map[string]interface{}, space VectorSpaceDefinition) []float32 {
+	value, ok := recordMetadataValue(metadata, space.Name)
+	if !ok || value == nil {
+		return nil
+	}
+	vector, err := vectorSpaceValue(value, space.Dimension)
+	if err != nil {
+		return nil
+	}
+	return vector
+}
+
+// vectorSpaceIndex is the in-memory HNSW graph behind one declared vector
+// space. It follows the full-text index lifecycle: built from storage on
+// first use, with writes committed during the build queued and replayed over
+// the fresh graph so none is lost or applied twice.
+type vectorSpaceIndex struct {
+	definition VectorSpaceDefinition
+	config     index.HNSWConfig
+
+	// buildMu serializes builds and is never taken by writers.
+	buildMu sync.Mutex
+
+	mu      sync.RWMutex
+	state   fullTextIndexState
+	retired bool
+	pending []vectorSpaceWrite
+	graph   index.Index
+	members map[string]struct{}
+}
+
+// vectorSpaceWrite is one committed write. A nil vector removes the record.
+type vectorSpaceWrite struct {
+	id     string
+	vector []float32
+}
+
+func newVectorSpaceIndex(config *CollectionConfig, definition VectorSpaceDefinition) *vectorSpaceIndex {
+	return &vectorSpaceIndex{
+		definition: definition,
+		config: index.HNSWConfig{
+			Dimension:      definition.Dimension,
+			M:              definition.M,
+			EfConstruction: definition.EfConstruction,
+			EfSearch:       definition.EfSearch,
+			ML:             1.0 / math.Log(float64(max(definition.M, 2))),
+			Metric:         util.DistanceMetric(definition.Metric),
+			RawVectorStore: config.RawVectorStore,
+			RawStoreCap:    config.RawStoreCap,
+		},
+	}
+}
+
+// apply publishes one write to graph. The caller owns graph and members.
+func (x *vectorSpaceIndex) apply(ctx context.Context, graph index.Index, members map[string]struct{}, write vectorSpaceWrite) error {
+	if _, ok := members[write.id]; ok {
+		if err := graph.Delete(ctx, write.id); err != nil && !isNotFoundError(err) {
+			return err
+		}
+		delete(members, write.id)
+	}
+	if write.vector == nil {
+		return nil
+	}
+	entry := &index.VectorEntry{ID: write.id, Vector: vectorForIndex(x.definition.Metric, write.vector)}
+	if err := graph.Insert(ctx, entry); err != nil {
+		return err
+	}
+	members[write.id] = struct{}{}
+	return nil
+}
+
+func (x *vectorSpaceIndex) record(write vectorSpaceWrite) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	switch x.state {
+	case fullTextIndexReady:
+		// The write is already durable; a graph that cannot absorb it is
+		// discarded and rebuilt from storage on next use.
+		if err := x.apply(context.Background(), x.graph, x.members, write); err != nil {
+			x.releaseLocked()
+		}
+	case fullTextIndexBuilding:
+		x.pending = append(x.pending, write)
+	}
+}
+
+func (x *vectorSpaceIndex) tracking() bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return x.state != fullTextIndexUnbuilt
+}
+
+// releaseLocked drops the graph so the next use rebuilds it. The caller
+// must hold x.mu.
+func (x *vectorSpaceIndex) releaseLocked() {
+	if x.graph != nil {
+		_ = x.graph.Close()
+	}
+	x.graph = nil
+	x.members = nil
+	x.pending = nil
+	x.state = fullTextIndexUnbuilt
+}
+
+// retire releases the graph of a space whose declarations changed or whose
+// collection closed. A build still in flight discards its result.
+func (x *vectorSpaceIndex) retire() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.retired = true
+	x.releaseLocked()
+}
+
+// ensureBuilt scans the collection once and publishes the graph.
+func (x *vectorSpaceIndex) ensureBuilt(ctx context.Context, c *Collection) error {
+	x.mu.RLock()
+	ready := x.state == fullTextIndexReady
+	x.mu.RUnlock()
+	if ready {
+		return nil
+	}
+	x.buildMu.Lock()
+	defer x.buildMu.Unlock()
+
+	x.mu.Lock()
+	if x.state == fullTextIndexReady {
+		x.mu.Unlock()
+		return nil
+	}
+	if x.retired {
+		x.mu.Unlock()
+		return ErrCollectionClosed
+	}
+	x.state = fullTextIndexBuilding
+	x.pending = nil
+	x.mu.Unlock()
+
+	config := x.config
+	graph, err := index.NewHNSW(&config)
+	if err != nil {
+		x.mu.Lock()
+		x.state = fullTextIndexUnbuilt
+		x.mu.Unlock()
+		return fmt.Errorf("build vector space %q: %w", x.definition.Name, err)
+	}
+	members := make(map[string]struct{})
+	err = c.Iterate(withExpiredRecords(ctx), func(record Record) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		vector := recordSpaceVector(record.Metadata, x.definition)
+		if vector == nil {
+			return nil
+		}
+		return x.apply(ctx, graph, members, vectorSpaceWrite{id: record.ID, vector: vector})
+	})
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err == nil {
+		for _, write := range x.pending {
+			if err = x.apply(ctx, graph, members, write); err != nil {
+				break
+			}
+		}
+	}
+	x.pending = nil
+	if err != nil || x.retired {
+		_ = graph.Close()
+		if x.state == fullTextIndexBuilding {
+			x.state = fullTextIndexUnbuilt
+		}
+		if err == nil {
+			return ErrCollectionClosed
+		}
+		return fmt.Errorf("build vector space %q: %w", x.definition.Name, err)
+	}
+	x.graph = graph
+	x.members = members
+	x.state = fullTextIndexReady
+	return nil
+}
+
+// search returns up to k record IDs nearest to query, with raw index
+// distances in Score.
+func (x *vectorSpaceIndex) search(ctx context.Context, query []float32, k, ef int) ([]*index.SearchResult, int, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if x.graph == nil || len(x.members) == 0 {
+		return nil, 0, nil
+	}
+	k = min(k, len(x.members), 4096)
+	indexQuery := vectorForIndex(x.definition.Metric, query)
+	var hits []*index.SearchResult
+	var err error
+	if searchable, ok := x.graph.(interface {
+		SearchWithEf(context.Context, []float32, int, int, index.GraphFilter) ([]*index.SearchResult, error)
+	}); ok {
+		hits, err = searchable.SearchWithEf(ctx, indexQuery, k, ef, nil)
+	} else {
+		hits, err = x.graph.Search(ctx, indexQuery, k, nil)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	return hits, len(x.members), nil
+}
+
+// vectorSpaceIndexFor returns the graph of a declared space.
+func (c *Collection) vectorSpaceIndexFor(definition VectorSpaceDefinition) *vectorSpaceIndex {
+	c.vectorSpaceMu.Lock()
+	defer c.vectorSpaceMu.Unlock()
+	if c.vectorSpaces == nil {
+		c.vectorSpaces = make(map[string]*vectorSpaceIndex)
+	}
+	key := strings.ToLower(definition.Name)
+	space := c.vectorSpaces[key]
+	if space == nil || space.definition != definition {
+		if space != nil {
+			space.retire()
+		}
+		space = newVectorSpaceIndex(c.config, definition)
+		c.vectorSpaces[key] = space
+	}
+	return space
+}
+
+// resetVectorSpaces releases every derived graph after the declarations
+// change or the collection closes; they are rebuilt on next use.
+func (c *Collection) resetVectorSpaces() {
+	c.vectorSpaceMu.Lock()
+	spaces := c.vectorSpaces
+	c.vectorSpaces = nil
+	c.vectorSpaceMu.Unlock()
+	for _, space := range spaces {
+		space.retire()
+	}
+}
+
+func (c *Collection) trackedVectorSpaces() []*vectorSpaceIndex {
+	if c == nil {
+		return nil
+	}
+	c.vectorSpaceMu.Lock()
+	defer c.vectorSpaceMu.Unlock()
+	if len(c.vectorSpaces) == 0 {
+		return nil
+	}
+	spaces := make([]*vectorSpaceIndex, 0, len(c.vectorSpaces))
+	for _, space := range c.vectorSpaces {
+		spaces = append(spaces, space)
+	}
+	return spaces
+}
+
+// noteVectorSpaceWrite applies a committed insert or update to every built
+// vector space graph. metadata must be the record's complete metadata.
+func (c *Collection) noteVectorSpaceWrite(id string, metadata map[string]interface{}) {
+	for _, space := range c.trackedVectorSpaces() {
+		if space.tracking() {
+			space.record(vectorSpaceWrite{id: id, vector: recordSpaceVector(metadata, space.definition)})
+		}
+	}
+}
+
+// noteVectorSpaceDelete removes a committed delete from every built vector
+// space graph.
+func (c *Collection) noteVectorSpaceDelete(id string) {
+	for _, space := range c.trackedVectorSpaces() {
+		space.record(vectorSpaceWrite{id: id})
+	}
+}
+
+// SearchSpace returns the k records whose vector in the named space is
+// nearest to vector, best first. Scores follow the space's metric with the
+// same "higher is better" semantics as Search. Records without a vector in
+// the space are not returned.
+func (c *Collection) SearchSpace(ctx context.Context, space string, vector []float32, k int) (*SearchResults, error) {
+	start := time.Now()
+	definition, ok := c.vectorSpace(space)
+	if !ok {
+		return nil, fmt.Errorf("vector space %q is not declared", space)
+	}
+	if k <= 0 {
+		return nil, fmt.Errorf("k must be positive, got %d", k)
+	}
+	results, err := c.searchVectorSpace(ctx, definition, vector, k, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResults{Results: results, Took: time.Since(start), Total: len(results)}, nil
+}
+
+// searchVectorSpace returns the top-k records of one space. When allow is
+// non-nil only those records qualify: small allow-sets are scored exactly,
+// larger ones run an oversampled graph search that an exact scan repairs if
+// filtering leaves it short, mirroring searchPrefiltered.
+func (c *Collection) searchVectorSpace(ctx context.Context, definition VectorSpaceDefinition, query []float32, k, ef int, allow map[string]Record) ([]*SearchResult, error) {
+	if len(query) != definition.Dimension {
+		return nil, fmt.Errorf("query vector has %d dimensions, vector space %q expects %d", len(query), definition.Name, definition.Dimension)
+	}
+	if allow != nil {
+		if len(allow) == 0 {
+			return []*SearchResult{}, nil
+		}
+		if len(allow) <= exactCandidateCap/10 {
+			return scoreVectorSpaceCandidates(definition, query, k, allow), nil
+		}
+	}
+
+	space := c.vectorSpaceIndexFor(definition)
+	if err := space.ensureBuilt(ctx, c); err != nil {
+		return nil, err
+	}
+	fetch := k
+	if allow != nil {
+		total := max(c.countRecords(), 1)
+		fetch = int(math.Ceil(float64(k) * float64(total) / float64(len(allow)) * 2))
+	}
+	hits, members, err := space.search(ctx, query, fetch, max(ef, definition.EfSearch))
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*SearchResult, 0, min(k, len(hits)))
+	for _, hit := range hits {
+		if len(results) == k {
+			break
+		}
+		var record Record
+		if allow != nil {
+			var ok bool
+			if record, ok = allow[hit.ID]; !ok {
+				continue
+			}
+		} else if record, err = c.Get(ctx, hit.ID); err != nil {
+			// A concurrent delete can remove a record after the graph was
+			// read; its delete hook retires the node.
+			if isNotFoundError(err) || errors.Is(err, ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		results = append(results, &SearchResult{
+			ID:       record.ID,
+			Score:    publicScore(definition.Metric, hit.Score),
+			Vector:   record.Vector,
+			Metadata: record.Metadata,
+			Version:  record.Version,
+			Ordinal:  record.Ordinal,
+		})
+	}
+	if allow != nil && len(results) < k && len(hits) < members {
+		return scoreVectorSpaceCandidates(definition, query, k, allow), nil
+	}
+	return results, nil
+}
+
+// scoreVectorSpaceCandidates computes the exact top-k of one space over the
+// given records using the same public score as the graph search.
+func scoreVectorSpaceCandidates(definition VectorSpaceDefinition, query []float32, k int, candidates map[string]Record) []*SearchResult {
+	records := make([]Record, 0, len(candidates))
+	for _, record := range candidates {
+		if vector := recordSpaceVector(record.Metadata, definition); vector != nil {
+			records = append(records, Record{ID: record.ID, Vector: vector})
+		}
+	}
+	scored := scoreRecordsTopK(definition.Metric, records, query, k)
+	for _, result := range scored.Results {
+		record := candidates[result.ID]
+		result.Vector = record.Vector
+		result.Metadata = record.Metadata
+		result.Version = record.Version
+		result.Ordinal = record.Ordinal
+	}
+	return scored.Results
+}
+
+// spaceQuery is one WithSpaceVector component of a QueryBuilder.
+type spaceQuery struct {
+	space  string
+	vector []float32
+}
+
+// searchSpaces runs a query that names at least one vector space. A lone
+// space is searched directly; several vectors, including a primary
+// WithVector, are each ranked to a fixed depth and fused by RRF. Metadata
+// and graph filters restrict every component to the same records.
+func (qb *QueryBuilder) searchSpaces() (*SearchResults, error) {
+	start := time.Now()
+	definitions := make([]VectorSpaceDefinition, len(qb.spaceVectors))
+	for i, component := range qb.spaceVectors {
+		definition, ok := qb.collection.vectorSpace(component.space)
+		if !ok {
+			return nil, fmt.Errorf("vector space %q is not declared", component.space)
+		}
+		definitions[i] = definition
+	}
+
+	filters := qb.optimizeFilters()
+	var prefilter *queryPrefilter
+	var allow map[string]Record
+	if len(filters) > 0 || qb.graphFilter != nil {
+		var err error
+		if prefilter, err = qb.planPrefilter(filters); err != nil {
+			return nil, err
+		}
+		// Vector spaces are stored in metadata, so the qualifying records
+		// are loaded for their exact scoring anyway.
+		if allow, err = qb.qualifyingRecords(prefilter, filters); err != nil {
+			return nil, err
+		}
+	}
+
+	fused := len(qb.spaceVectors) > 1 || qb.vector != nil
+	depth := qb.limit
+	if fused {
+		depth = max(qb.limit, min(qb.limit*4, 4096))
+	}
+	lists := make([][]*SearchResult, 0, len(qb.spaceVectors)+1)
+	if qb.vector != nil {
+		primary := *qb
+		primary.limit = depth
+		primary.thresholdSet = false
+		primary.spaceVectors = nil
+		var result *SearchResults
+		var err error
+		if prefilter != nil {
+			result, err = primary.searchPrefiltered(prefilter, filters)
+		} else {
+			result, err = primary.search()
+		}
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, result.Results)
+	}
+	for i, component := range qb.spaceVectors {
+		results, err := qb.collection.searchVectorSpace(qb.ctx, definitions[i], component.vector, depth, qb.efSearch, allow)
+		if err != nil {
+			return nil, err
+		}
+		lists = append(lists, results)
+	}
+
+	results := lists[0]
+	if fused {
+		results = fuseRankedResults(qb.rrfK, lists)
+	}
+	return &SearchResults{Results: results, Took: time.Since(start), Total: len(results)}, nil
+}
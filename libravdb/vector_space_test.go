@@ -0,0 +1,348 @@
+package libravdb
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func randomDenseVector(rng *rand.Rand, dimension int) []float32 {
+	vector := make([]float32, dimension)
+	for i := range vector {
+		vector[i] = rng.Float32()*2 - 1
+	}
+	return vector
+}
+
+// rankSpaceBruteForce orders ids by exact similarity to query under metric,
+// best first.
+func rankSpaceBruteForce(vectors map[string][]float32, query []float32, metric DistanceMetric) []string {
+	type scored struct {
+		id    string
+		score float64
+	}
+	ranked := make([]scored, 0, len(vectors))
+	for id, vector := range vectors {
+		var dot, queryNorm, vectorNorm, l2 float64
+		for i := range vector {
+			dot += float64(vector[i]) * float64(query[i])
+			queryNorm += float64(query[i]) * float64(query[i])
+			vectorNorm += float64(vector[i]) * float64(vector[i])
+			diff := float64(vector[i]) - float64(query[i])
+			l2 += diff * diff
+		}
+		score := dot
+		switch metric {
+		case CosineDistance:
+			score = dot / math.Sqrt(queryNorm*vectorNorm)
+		case L2Distance:
+			score = -l2
+		}
+		ranked = append(ranked, scored{id: id, score: score})
+	}
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].score > ranked[j].score })
+	ids := make([]string, len(ranked))
+	for i, entry := range ranked {
+		ids[i] = entry.id
+	}
+	return ids
+}
+
+func openVectorSpaceCollection(t *testing.T, path string) (*Database, *Collection) {
+	t.Helper()
+	db, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	col, err := db.CreateCollection(context.Background(), "products",
+		WithMetadataOnly(),
+		WithVectorSpace("title", 8, CosineDistance),
+		WithVectorSpace("body", 16, L2Distance, WithSpaceHNSW(16, 100, 64)),
+		WithVectorSpace("image", 4, InnerProduct),
+	)
+	if err != nil {
+		db.Close()
+		t.Fatalf("CreateCollection: %v", err)
+	}
+	return db, col
+}
+
+func rankedResultIDs(results *SearchResults) []string {
+	ids := make([]string, len(results.Results))
+	for i, result := range results.Results {
+		ids[i] = result.ID
+	}
+	return ids
+}
+
+func TestSearchSpaceMatchesBruteForce(t *testing.T) {
+	ctx := context.Background()
+	db, col := openVectorSpaceCollection(t, t.TempDir()+"/spaces.libravdb")
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(13))
+	spaces := map[string]DistanceMetric{"title": CosineDistance, "body": L2Distance, "image": InnerProduct}
+	dimensions := map[string]int{"title": 8, "body": 16, "image": 4}
+	vectors := map[string]map[string][]float32{"title": {}, "body": {}, "image": {}}
+	for i := 0; i < 300; i++ {
+		id := fmt.Sprintf("p%03d", i)
+		metadata := map[string]interface{}{"sku": id}
+		for space, dimension := range dimensions {
+			vector := randomDenseVector(rng, dimension)
+			vectors[space][id] = vector
+			// Typed slices and text literals are all accepted.
+			switch i % 3 {
+			case 0:
+				metadata[space] = vector
+			case 1:
+				widened := make([]float64, len(vector))
+				for j, v := range vector {
+					widened[j] = float64(v)
+				}
+				metadata[space] = widened
+			default:
+				metadata[space] = formatConflictVector(vector)
+			}
+		}
+		if err := col.Insert(ctx, id, nil, metadata); err != nil {
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+
+	stored, err := col.Get(ctx, "p001")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if want := formatConflictVector(vectors["title"]["p001"]); stored.Metadata["title"] != want {
+		t.Fatalf("stored title = %#v, want canonical %q", stored.Metadata["title"], want)
+	}
+
+	check := func(space string) {
+		t.Helper()
+		query := randomDenseVector(rng, dimensions[space])
+		results, err := col.SearchSpace(ctx, space, query, 10)
+		if err != nil {
+			t.Fatalf("SearchSpace(%s): %v", space, err)
+		}
+		want := rankSpaceBruteForce(vectors[space], query, spaces[space])[:10]
+		if got := rankedResultIDs(results); fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("SearchSpace(%s) = %v, want %v", space, got, want)
+		}
+		for i := 1; i < len(results.Results); i++ {
+			if results.Results[i].Score > results.Results[i-1].Score {
+				t.Fatalf("SearchSpace(%s) scores are not descending: %+v", space, results.Results)
+			}
+		}
+	}
+	for space := range spaces {
+		check(space)
+	}
+
+	// Committed writes after the graphs are built keep them current.
+	moved := randomDenseVector(rng, 8)
+	if err := col.Update(ctx, "p010", nil, map[string]interface{}{"title": moved}); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	vectors["title"]["p010"] = moved
+	if err := col.Delete(ctx, "p020"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for space := range vectors {
+		delete(vectors[space], "p020")
+	}
+	results, err := col.SearchSpace(ctx, "title", moved, 1)
+	if err != nil || len(results.Results) != 1 || results.Results[0].ID != "p010" {
+		t.Fatalf("SearchSpace after update = %+v, %v", results, err)
+	}
+	for space := range spaces {
+		check(space)
+	}
+}
+
+func TestQueryBuilderFusesVectorSpaces(t *testing.T) {
+	ctx := context.Background()
+	db, col := openVectorSpaceCollection(t, t.TempDir()+"/spaces_fusion.libravdb")
+	defer db.Close()
+
+	rng := rand.New(rand.NewSource(29))
+	titles := make(map[string][]float32)
+	bodies := make(map[string][]float32)
+	for i := 0; i < 200; i++ {
+		id := fmt.Sprintf("p%03d", i)
+		titles[id] = randomDenseVector(rng, 8)
+		bodies[id] = randomDenseVector(rng, 16)
+		category := "book"
+		if i%4 == 0 {
+			category = "film"
+		}
+		metadata := map[string]interface{}{"category": category, "title": titles[id], "body": bodies[id]}
+		if err := col.Insert(ctx, id, nil, metadata); err != nil {
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+	titleQuery := randomDenseVector(rng, 8)
+	bodyQuery := randomDenseVector(rng, 16)
+
+	// A lone space vector searches that space directly.
+	single, err := col.Query(ctx).WithSpaceVector("title", titleQuery).Limit(5).Execute()
+	if err != nil {
+		t.Fatalf("single-space query: %v", err)
+	}
+	if got, want := rankedResultIDs(single), rankSpaceBruteForce(titles, titleQuery, CosineDistance)[:5]; fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("single-space query = %v, want %v", got, want)
+	}
+
+	// Two spaces are fused by RRF over each space's top limit*4 ranks.
+	const limit = 5
+	expected := func(titleRank, bodyRank []string) []string {
+		scores := make(map[string]float64)
+		for _, ranking := range [][]string{titleRank, bodyRank} {
+			for rank, id := range ranking[:min(limit*4, len(ranking))] {
+				scores[id] += reciprocalRank(defaultRRFK, rank)
+			}
+		}
+		ids := make([]string, 0, len(scores))
+		for id := range scores {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			if scores[ids[i]] != scores[ids[j]] {
+				return scores[ids[i]] > scores[ids[j]]
+			}
+			return ids[i] < ids[j]
+		})
+		return ids[:limit]
+	}
+	fused, err := col.Query(ctx).
+		WithSpaceVector("title", titleQuery).
+		WithSpaceVector("body", bodyQuery).
+		Limit(limit).
+		Execute()
+	if err != nil {
+		t.Fatalf("fused query: %v", err)
+	}
+	want := expected(rankSpaceBruteForce(titles, titleQuery, CosineDistance), rankSpaceBruteForce(bodies, bodyQuery, L2Distance))
+	if got := rankedResultIDs(fused); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("fused query = %v, want %v", got, want)
+	}
+
+	// Filters restrict every component to the qualifying records.
+	films := func(vectors map[string][]float32) map[string][]float32 {
+		filtered := make(map[string][]float32)
+		for id, vector := range vectors {
+			var n int
+			fmt.Sscanf(id, "p%d", &n)
+			if n%4 == 0 {
+				filtered[id] = vector
+			}
+		}
+		return filtered
+	}
+	filtered, err := col.Query(ctx).
+		WithSpaceVector("title", titleQuery).
+		WithSpaceVector("body", bodyQuery).
+		Eq("category", "film").
+		Limit(limit).
+		Execute()
+	if err != nil {
+		t.Fatalf("filtered fused query: %v", err)
+	}
+	want = expected(rankSpaceBruteForce(films(titles), titleQuery, CosineDistance), rankSpaceBruteForce(films(bodies), bodyQuery, L2Distance))
+	if got := rankedResultIDs(filtered); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("filtered fused query = %v, want %v", got, want)
+	}
+	for _, result := range filtered.Results {
+		if result.Metadata["category"] != "film" {
+			t.Fatalf("filtered fused query returned %s with category %v", result.ID, result.Metadata["category"])
+		}
+	}
+
+	if _, err := col.Query(ctx).WithSpaceVector("audio", titleQuery).Limit(limit).Execute(); err == nil {
+		t.Fatal("query accepted an undeclared vector space")
+	}
+}
+
+func TestVectorSpaceValidation(t *testing.T) {
+	ctx := context.Background()
+	db, col := openVectorSpaceCollection(t, t.TempDir()+"/spaces_validation.libravdb")
+	defer db.Close()
+
+	for _, value := range []interface{}{[]float32{1, 2}, "[1,2,3]", "not a vector", 42, []interface{}{1, "x", 3, 4}} {
+		if err := col.Insert(ctx, "bad", nil, map[string]interface{}{"image": value}); err == nil {
+			t.Fatalf("Insert accepted invalid image vector %#v", value)
+		}
+	}
+	if err := col.Insert(ctx, "partial", nil, map[string]interface{}{"image": []interface{}{1, 0.5, "0.25", 0}}); err != nil {
+		t.Fatalf("Insert with numeric interface slice: %v", err)
+	}
+	results, err := col.SearchSpace(ctx, "TITLE", make([]float32, 8), 3)
+	if err != nil || len(results.Results) != 0 {
+		t.Fatalf("SearchSpace over a space without vectors = %+v, %v", results, err)
+	}
+	if _, err := col.SearchSpace(ctx, "audio", []float32{1}, 3); err == nil {
+		t.Fatal("SearchSpace accepted an undeclared space")
+	}
+	if _, err := col.SearchSpace(ctx, "image", []float32{1, 2}, 3); err == nil {
+		t.Fatal("SearchSpace accepted a query of the wrong dimension")
+	}
+
+	if _, err := db.CreateCollection(ctx, "dup", WithVectorSpace("a", 2, L2Distance), WithVectorSpace("A", 2, L2Distance)); err == nil {
+		t.Fatal("WithVectorSpace accepted a duplicate space")
+	}
+	if _, err := db.CreateCollection(ctx, "zero", WithVectorSpace("a", 0, L2Distance)); err == nil {
+		t.Fatal("WithVectorSpace accepted a zero dimension")
+	}
+	if _, err := db.CreateCollection(ctx, "clash", WithVectorSpace("terms", 2, L2Distance), WithSparseVector("terms", 10)); err == nil {
+		t.Fatal("WithVectorSpace accepted a space named like a sparse vector field")
+	}
+}
+
+func TestVectorSpaceDeclarationSurvivesReopen(t *testing.T) {
+	ctx := context.Background()
+	path := t.TempDir() + "/spaces_reopen.libravdb"
+	db, col := openVectorSpaceCollection(t, path)
+	for id, image := range map[string][]float32{
+		"a": {1, 0, 0, 0},
+		"b": {0.5, 0.5, 0, 0},
+		"c": {0, 0, 1, 0},
+	} {
+		if err := col.Insert(ctx, id, nil, map[string]interface{}{"image": image}); err != nil {
+			db.Close()
+			t.Fatalf("Insert %s: %v", id, err)
+		}
+	}
+	want := col.Config().VectorSpaces
+	if err := db.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	reopened, err := Open(WithStoragePath(path), WithMetrics(false))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer reopened.Close()
+	reloaded, err := reopened.GetCollection("products")
+	if err != nil {
+		t.Fatalf("GetCollection: %v", err)
+	}
+	got := reloaded.Config().VectorSpaces
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("reloaded VectorSpaces = %+v, want %+v", got, want)
+	}
+	if got[0].M != ProductionHNSWM || got[1].M != 16 || got[1].EfSearch != 64 {
+		t.Fatalf("reloaded HNSW parameters = %+v", got)
+	}
+	results, err := reloaded.SearchSpace(ctx, "image", []float32{1, 0.2, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("SearchSpace after reopen: %v", err)
+	}
+	if ids := rankedResultIDs(results); fmt.Sprint(ids) != "[a b]" {
+		t.Fatalf("SearchSpace after reopen = %v", ids)
+	}
+	if err := reloaded.Insert(ctx, "d", nil, map[string]interface{}{"image": []float32{1}}); err == nil {
+		t.Fatal("reopened collection no longer validates the declared dimension")
+	}
+}
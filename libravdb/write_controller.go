@@ -20,7 +20,7 @@ type writeController struct {
 // index Insert method acquires its own internal mutex:
 //
 //	Flat.BatchInsert    — idx.mu.Lock()
-//	HNSW.BatchInsert    — h.mu.Lock()
+//	HNSW.BatchInsert    — h.epochMu.Lock()
 //	IVFPQ.Insert        — per-cluster cluster.mutex.Lock()
 //
 // IVFPQ is the least parallel: it locks individual clusters but is not batch-atomic.
@@ -62,6 +62,13 @@ func NewSCRAMCredentialWithIterations(username, password string, iterations int)
 	return internalpgwire.NewSCRAMCredentialWithIterations(username, password, iterations)
 }
 
+// ParseSCRAMVerifier decodes a PostgreSQL SCRAM-SHA-256 verifier
+// (SCRAM-SHA-256$<iterations>:<salt>$<StoredKey>:<ServerKey>) for username.
+// SCRAMCredential.Verifier produces the same format.
+func ParseSCRAMVerifier(username, verifier string) (SCRAMCredential, error) {
+	return internalpgwire.ParseSCRAMVerifier(username, verifier)
+}
+
 // Serve starts a PostgreSQL wire-protocol server and blocks until ctx is
 // cancelled or the server is closed.
 func Serve(ctx context.Context, db *libravdb.Database, config ServerConfig) error {